@@ -9,6 +9,7 @@ import (
 
 	"github.com/bynil/btcd/btcutil"
 	"github.com/bynil/btcd/database"
+	"github.com/bynil/btcd/wire"
 )
 
 // maybeAcceptBlock potentially accepts a block into the block chain and, if
@@ -62,17 +63,30 @@ func (b *BlockChain) maybeAcceptBlock(block *btcutil.Block, flags BehaviorFlags)
 
 	// Create a new block node for the block and add it to the node index. Even
 	// if the block ultimately gets connected to the main chain, it starts out
-	// on a side chain.
-	blockHeader := &block.MsgBlock().Header
-	newNode := newBlockNode(blockHeader, prevNode)
-	newNode.status = statusDataStored
-
-	b.index.AddNode(newNode)
+	// on a side chain.  The node already exists when the header of the block
+	// was processed before.
+	newNode := b.index.LookupNode(block.Hash())
+	if newNode == nil {
+		blockHeader := &block.MsgBlock().Header
+		newNode = newBlockNode(blockHeader, prevNode)
+		newNode.status = statusDataStored
+		b.index.AddNode(newNode)
+	} else {
+		b.index.SetStatusFlags(newNode, statusDataStored)
+	}
 	err = b.index.flushToDB()
 	if err != nil {
 		return false, err
 	}
 
+	// The blocks of the main chain before the base of a loaded UTXO set
+	// snapshot are already part of the main chain.  They are connected by
+	// the background validation of the chain instead.
+	if b.bestChain.Contains(newNode) {
+		b.notifySnapshotBlockStored()
+		return false, nil
+	}
+
 	// Connect the passed block to the chain while respecting proper chain
 	// selection according to the chain with the most proof of work.  This
 	// also handles validation of the transaction scripts.
@@ -92,3 +106,46 @@ func (b *BlockChain) maybeAcceptBlock(block *btcutil.Block, flags BehaviorFlags)
 
 	return isMainChain, nil
 }
+
+// maybeAcceptBlockHeader potentially accepts a block header into the block
+// index.  It performs several validation checks which depend on its position
+// within the block chain before adding it.  Headers which are already known are
+// ignored.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) maybeAcceptBlockHeader(header *wire.BlockHeader,
+	flags BehaviorFlags) error {
+
+	blockHash := header.BlockHash()
+	if node := b.index.LookupNode(&blockHash); node != nil {
+		if b.index.NodeStatus(node).KnownInvalid() {
+			str := fmt.Sprintf("block %s is known to be invalid",
+				blockHash)
+			return ruleError(ErrInvalidAncestorBlock, str)
+		}
+		return nil
+	}
+
+	prevHash := &header.PrevBlock
+	prevNode := b.index.LookupNode(prevHash)
+	if prevNode == nil {
+		str := fmt.Sprintf("previous block %s is unknown", prevHash)
+		return ruleError(ErrPreviousBlockUnknown, str)
+	} else if b.index.NodeStatus(prevNode).KnownInvalid() {
+		str := fmt.Sprintf("previous block %s is known to be invalid", prevHash)
+		return ruleError(ErrInvalidAncestorBlock, str)
+	}
+
+	// The header must pass all of the validation rules which depend on its
+	// position within the block chain.
+	err := CheckBlockHeaderContext(header, prevNode, flags, b, false)
+	if err != nil {
+		return err
+	}
+
+	// Only the header of the block is known, so its data is not stored.
+	newNode := newBlockNode(header, prevNode)
+	b.index.AddNode(newNode)
+
+	return b.index.flushToDB()
+}
@@ -0,0 +1,1039 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	"github.com/bynil/btcd/database"
+	"github.com/bynil/btcd/txscript"
+	"github.com/bynil/btcd/wire"
+)
+
+// -----------------------------------------------------------------------------
+// A UTXO set snapshot is serialized in the same format Bitcoin Core uses, so
+// snapshots can be exchanged between implementations.
+//
+// The serialized format is:
+//
+//   <metadata><coins grouped by txid>
+//
+//   Field                Type             Size
+//   magic                []byte           5
+//   version              uint16           2
+//   network magic        uint32           4
+//   base block hash      chainhash.Hash   chainhash.HashSize
+//   number of coins      uint64           8
+//
+// Each group of coins is serialized as:
+//
+//   Field                Type             Size
+//   txid                 chainhash.Hash   chainhash.HashSize
+//   number of coins      CompactSize      variable
+//   coins                []coin           variable
+//
+// Each coin is serialized as its output index as a CompactSize followed by the
+// same serialization utxo entries use in the utxo set bucket.  All integers of
+// the metadata are little endian.
+// -----------------------------------------------------------------------------
+
+const (
+	// snapshotVersion is the version of the UTXO set snapshot format.
+	snapshotVersion = 2
+
+	// snapshotWriteBatchSize is the number of coins loaded from a UTXO set
+	// snapshot that are written to the database in a single transaction.
+	snapshotWriteBatchSize = 100000
+
+	// snapshotBlockWaitInterval is the maximum time the background
+	// validation of the chain before the base of a UTXO set snapshot waits
+	// for the next block to be downloaded before checking for it again.
+	snapshotBlockWaitInterval = time.Minute
+)
+
+// snapshotMagic is the magic the serialization of a UTXO set snapshot starts
+// with.
+var snapshotMagic = [5]byte{'u', 't', 'x', 'o', 0xff}
+
+// snapshotStatus describes the validation status of the chain before the base
+// of a loaded UTXO set snapshot.
+type snapshotStatus byte
+
+const (
+	// snapshotValidating indicates the chain before the snapshot base is
+	// being validated in the background.
+	snapshotValidating snapshotStatus = iota
+
+	// snapshotValidated indicates the chain before the snapshot base was
+	// validated and results in the UTXO set of the snapshot.
+	snapshotValidated
+
+	// snapshotInvalid indicates the chain before the snapshot base is
+	// invalid or does not result in the UTXO set of the snapshot.
+	snapshotInvalid
+)
+
+// snapshotChainstate houses the state of the UTXO set snapshot the chain state
+// was loaded from.
+type snapshotChainstate struct {
+	// baseNode is the block node of the block the snapshot was taken at.
+	baseNode *blockNode
+
+	// hashSerialized is the hash_serialized_3 hash of the snapshot.
+	hashSerialized chainhash.Hash
+
+	// status is the validation status of the chain before the snapshot
+	// base.
+	status snapshotStatus
+
+	// bgCache is the utxo cache used to validate the chain before the
+	// snapshot base in the background and bgTip is the block node up to
+	// which the chain is validated.  They are only set while the chain is
+	// being validated.
+	bgCache *utxoCache
+	bgTip   *blockNode
+
+	// blockStored is signaled when a block before the snapshot base is
+	// downloaded.
+	blockStored chan struct{}
+}
+
+// UtxoSnapshotStats describes a UTXO set snapshot that was dumped or loaded.
+type UtxoSnapshotStats struct {
+	// BaseHash and BaseHeight identify the block the snapshot was taken at.
+	BaseHash   chainhash.Hash
+	BaseHeight int32
+
+	// NumCoins is the number of coins in the snapshot.
+	NumCoins uint64
+
+	// HashSerialized is the hash_serialized_3 hash of the snapshot.
+	HashSerialized chainhash.Hash
+
+	// ChainTxCount is the total number of transactions in the chain up to
+	// and including the snapshot base block.
+	ChainTxCount uint64
+}
+
+// utxoSetHasher computes the hash_serialized_3 hash of a UTXO set.  Coins must
+// be added in the order of their outpoints as used by the keys of the utxo set
+// bucket.
+type utxoSetHasher struct {
+	h   hash.Hash
+	buf [8]byte
+}
+
+// newUtxoSetHasher returns a new hasher for the hash_serialized_3 hash of a
+// UTXO set.
+func newUtxoSetHasher() *utxoSetHasher {
+	return &utxoSetHasher{h: sha256.New()}
+}
+
+// add adds the passed coin to the hash.
+func (u *utxoSetHasher) add(outpoint wire.OutPoint, entry *UtxoEntry) {
+	writeUtxoForHash(u.h, &u.buf, outpoint, entry)
+}
+
+// sum returns the hash of the coins added so far.
+func (u *utxoSetHasher) sum() chainhash.Hash {
+	return chainhash.Hash(sha256.Sum256(u.h.Sum(nil)))
+}
+
+// outpointFromKey returns the outpoint the passed key of the utxo set bucket
+// is for.
+func outpointFromKey(key []byte) (wire.OutPoint, error) {
+	var outpoint wire.OutPoint
+	if len(key) <= chainhash.HashSize {
+		return outpoint, errDeserialize("unexpected end of utxo key")
+	}
+	copy(outpoint.Hash[:], key[:chainhash.HashSize])
+	index, bytesRead := deserializeVLQ(key[chainhash.HashSize:])
+	if chainhash.HashSize+bytesRead != len(key) {
+		return outpoint, errDeserialize("malformed utxo key")
+	}
+	outpoint.Index = uint32(index)
+
+	return outpoint, nil
+}
+
+// dbFetchSnapshotState uses an existing database transaction to fetch the
+// state of a loaded UTXO set snapshot.  It returns nil when no snapshot was
+// loaded.
+func dbFetchSnapshotState(dbTx database.Tx) (*chainhash.Hash,
+	*chainhash.Hash, snapshotStatus, error) {
+
+	serialized := dbTx.Metadata().Get(snapshotStateKeyName)
+	if serialized == nil {
+		return nil, nil, 0, nil
+	}
+	if len(serialized) != 2*chainhash.HashSize+1 {
+		return nil, nil, 0, database.Error{
+			ErrorCode: database.ErrCorruption,
+			Description: "corrupt utxo snapshot state: unexpected " +
+				"size",
+		}
+	}
+
+	var baseHash, hashSerialized chainhash.Hash
+	copy(baseHash[:], serialized[:chainhash.HashSize])
+	copy(hashSerialized[:], serialized[chainhash.HashSize:])
+	status := snapshotStatus(serialized[2*chainhash.HashSize])
+
+	return &baseHash, &hashSerialized, status, nil
+}
+
+// dbPutSnapshotState uses an existing database transaction to store the state
+// of a loaded UTXO set snapshot.
+func dbPutSnapshotState(dbTx database.Tx, s *snapshotChainstate) error {
+	serialized := make([]byte, 2*chainhash.HashSize+1)
+	copy(serialized, s.baseNode.hash[:])
+	copy(serialized[chainhash.HashSize:], s.hashSerialized[:])
+	serialized[2*chainhash.HashSize] = byte(s.status)
+
+	return dbTx.Metadata().Put(snapshotStateKeyName, serialized)
+}
+
+// initSnapshotChainstate loads the state of the UTXO set snapshot the chain
+// state was loaded from, if any.  The chain state is then backed by the utxo
+// set loaded from the snapshot, while the original utxo set is used to
+// validate the chain before the snapshot base in the background.
+//
+// This function MUST be called with the block index loaded.
+func (b *BlockChain) initSnapshotChainstate(dbTx database.Tx) error {
+	baseHash, hashSerialized, status, err := dbFetchSnapshotState(dbTx)
+	if err != nil || baseHash == nil {
+		return err
+	}
+
+	baseNode := b.index.LookupNode(baseHash)
+	if baseNode == nil {
+		return AssertError(fmt.Sprintf("initSnapshotChainstate: cannot "+
+			"find snapshot base block %s in block index", baseHash))
+	}
+	b.utxoCache.bucketName = snapshotUtxoSetBucketName
+	b.snapshot = &snapshotChainstate{
+		baseNode:       baseNode,
+		hashSerialized: *hashSerialized,
+		status:         status,
+	}
+
+	switch status {
+	case snapshotValidating:
+	case snapshotInvalid:
+		log.Errorf("The chain before the UTXO set snapshot base block "+
+			"%v is invalid -- the chain state must be resynced",
+			baseHash)
+		return nil
+	default:
+		return nil
+	}
+
+	bgHash, err := chainhash.NewHash(
+		dbTx.Metadata().Get(bgUtxoStateConsistencyKeyName),
+	)
+	if err != nil {
+		return err
+	}
+	bgTip := b.index.LookupNode(bgHash)
+	if bgTip == nil || baseNode.Ancestor(bgTip.height) != bgTip {
+		return AssertError(fmt.Sprintf("initSnapshotChainstate: "+
+			"background utxo state is consistent with block %s "+
+			"which is not an ancestor of the snapshot base", bgHash))
+	}
+	b.snapshot.bgTip = bgTip
+	b.snapshot.bgCache = b.newBackgroundUtxoCache(bgTip)
+	b.snapshot.blockStored = make(chan struct{}, 1)
+
+	log.Infof("Validating the chain from height %d up to the UTXO set "+
+		"snapshot base block %v (height %d) in the background",
+		bgTip.height+1, baseNode.hash, baseNode.height)
+
+	return nil
+}
+
+// newBackgroundUtxoCache returns the utxo cache used to validate the chain
+// before the base of a UTXO set snapshot.  It is backed by the original utxo
+// set, which is consistent with the passed block node.
+func (b *BlockChain) newBackgroundUtxoCache(tip *blockNode) *utxoCache {
+	cache := newUtxoCache(b.db, b.utxoCache.maxTotalMemoryUsage)
+	cache.stateKeyName = bgUtxoStateConsistencyKeyName
+	cache.lastFlushHash = tip.hash
+	cache.lastFlushTime = time.Now()
+
+	return cache
+}
+
+// DumpUtxoSnapshot serializes the UTXO set at the tip of the main chain to the
+// passed writer.  The chain is not extended while the snapshot is written.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) DumpUtxoSnapshot(w io.Writer) (*UtxoSnapshotStats, error) {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	// Flush the utxo cache so the utxo set bucket contains the utxo set at
+	// the tip.
+	bestState := b.BestSnapshot()
+	err := b.db.Update(func(dbTx database.Tx) error {
+		return b.utxoCache.flush(dbTx, FlushRequired, bestState)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &UtxoSnapshotStats{
+		BaseHash:     bestState.Hash,
+		BaseHeight:   bestState.Height,
+		ChainTxCount: bestState.TotalTxns,
+	}
+	err = b.db.View(func(dbTx database.Tx) error {
+		utxoBucket := dbTx.Metadata().Bucket(b.utxoCache.bucketName)
+
+		// The number of coins is part of the metadata, so count them
+		// first.
+		cursor := utxoBucket.Cursor()
+		for ok := cursor.First(); ok; ok = cursor.Next() {
+			stats.NumCoins++
+		}
+
+		bw := bufio.NewWriter(w)
+		var buf [8]byte
+		bw.Write(snapshotMagic[:])
+		binary.LittleEndian.PutUint16(buf[:2], snapshotVersion)
+		bw.Write(buf[:2])
+		binary.LittleEndian.PutUint32(buf[:4], uint32(b.chainParams.Net))
+		bw.Write(buf[:4])
+		bw.Write(bestState.Hash[:])
+		binary.LittleEndian.PutUint64(buf[:], stats.NumCoins)
+		bw.Write(buf[:])
+
+		// Coins are written grouped by the transaction that created
+		// them, so the coins of a transaction are collected until the
+		// coins of the next transaction are reached.
+		type coin struct {
+			index      uint32
+			serialized []byte
+		}
+		var txHash chainhash.Hash
+		var coins []coin
+		writeCoins := func() error {
+			if len(coins) == 0 {
+				return nil
+			}
+			bw.Write(txHash[:])
+			err := wire.WriteVarInt(bw, 0, uint64(len(coins)))
+			if err != nil {
+				return err
+			}
+			for _, c := range coins {
+				err := wire.WriteVarInt(bw, 0, uint64(c.index))
+				if err != nil {
+					return err
+				}
+				if _, err := bw.Write(c.serialized); err != nil {
+					return err
+				}
+			}
+			coins = coins[:0]
+			return nil
+		}
+
+		hasher := newUtxoSetHasher()
+		cursor = utxoBucket.Cursor()
+		for ok := cursor.First(); ok; ok = cursor.Next() {
+			outpoint, err := outpointFromKey(cursor.Key())
+			if err != nil {
+				return err
+			}
+			serialized := cursor.Value()
+			entry, err := deserializeUtxoEntry(serialized)
+			if err != nil {
+				return err
+			}
+			hasher.add(outpoint, entry)
+
+			if outpoint.Hash != txHash {
+				if err := writeCoins(); err != nil {
+					return err
+				}
+				txHash = outpoint.Hash
+			}
+			coins = append(coins, coin{
+				index:      outpoint.Index,
+				serialized: append([]byte(nil), serialized...),
+			})
+		}
+		if err := writeCoins(); err != nil {
+			return err
+		}
+		stats.HashSerialized = hasher.sum()
+
+		return bw.Flush()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return stats, nil
+}
+
+// readVLQBytes reads a variable-length quantity from the passed reader and
+// appends its serialization to the passed buffer.
+func readVLQBytes(r io.ByteReader, buf []byte) ([]byte, error) {
+	for i := 0; i < 10; i++ {
+		val, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, val)
+		if val&0x80 == 0 {
+			return buf, nil
+		}
+	}
+
+	return nil, errDeserialize("variable-length quantity too long")
+}
+
+// readSnapshotCoin reads the serialized utxo entry of a coin of a UTXO set
+// snapshot from the passed reader.
+func readSnapshotCoin(r *bufio.Reader) ([]byte, error) {
+	// Read the header code and the compressed amount.
+	serialized, err := readVLQBytes(r, nil)
+	if err != nil {
+		return nil, err
+	}
+	serialized, err = readVLQBytes(r, serialized)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the compressed script, which starts with its size or type.
+	scriptStart := len(serialized)
+	serialized, err = readVLQBytes(r, serialized)
+	if err != nil {
+		return nil, err
+	}
+	const maxScriptSize = txscript.MaxScriptSize + numSpecialScripts
+	scriptSize := decodeCompressedScriptSize(serialized[scriptStart:])
+	remaining := scriptSize - (len(serialized) - scriptStart)
+	if remaining < 0 || remaining > maxScriptSize {
+		return nil, errDeserialize("invalid compressed script size")
+	}
+	script := make([]byte, remaining)
+	if _, err := io.ReadFull(r, script); err != nil {
+		return nil, err
+	}
+
+	return append(serialized, script...), nil
+}
+
+// findAssumeUtxo returns the UTXO set snapshot pinned in the chain parameters
+// for the block with the passed hash, or nil if there is none.
+func (b *BlockChain) findAssumeUtxo(hash *chainhash.Hash) *chaincfg.AssumeUtxoData {
+	for i := range b.chainParams.AssumeUtxo {
+		data := &b.chainParams.AssumeUtxo[i]
+		if data.BlockHash.IsEqual(hash) {
+			return data
+		}
+	}
+
+	return nil
+}
+
+// checkSnapshotActivation returns an error if a UTXO set snapshot with the
+// passed base block can't be loaded.  The header of the base block must be
+// known, and the base block must be a descendant of the tip of the main chain.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) checkSnapshotActivation(baseHash *chainhash.Hash) (*blockNode, error) {
+	if b.snapshot != nil {
+		return nil, errors.New("a UTXO set snapshot is already loaded")
+	}
+	if b.indexManager != nil {
+		return nil, errors.New("a UTXO set snapshot can't be loaded " +
+			"while optional indexes are enabled")
+	}
+
+	baseNode := b.index.LookupNode(baseHash)
+	if baseNode == nil {
+		return nil, fmt.Errorf("the header of the snapshot base block "+
+			"%v is not known -- wait for the headers to sync", baseHash)
+	}
+	if b.index.NodeStatus(baseNode).KnownInvalid() {
+		return nil, fmt.Errorf("the snapshot base block %v is known to "+
+			"be invalid", baseHash)
+	}
+
+	tip := b.bestChain.Tip()
+	if tip.height >= baseNode.height {
+		return nil, fmt.Errorf("the chain tip at height %d is not "+
+			"before the snapshot base block at height %d",
+			tip.height, baseNode.height)
+	}
+	if baseNode.Ancestor(tip.height) != tip {
+		return nil, fmt.Errorf("the snapshot base block %v does not "+
+			"extend the chain tip %v", baseHash, tip.hash)
+	}
+
+	return baseNode, nil
+}
+
+// LoadUtxoSnapshot loads a UTXO set snapshot from the passed reader and makes
+// the chain state continue from the snapshot base block.  The snapshot must be
+// pinned in the chain parameters, and the header of its base block must be
+// known.
+//
+// The chain before the snapshot base is validated in the background once its
+// blocks are downloaded, see SnapshotBlocksNeeded.  When the resulting UTXO set
+// does not match the snapshot, the chain state is considered invalid.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) LoadUtxoSnapshot(r io.Reader) (*UtxoSnapshotStats, error) {
+	b.snapshotLoadLock.Lock()
+	defer b.snapshotLoadLock.Unlock()
+
+	br := bufio.NewReader(r)
+
+	// Read and check the metadata.
+	var magic [5]byte
+	if _, err := io.ReadFull(br, magic[:]); err != nil {
+		return nil, fmt.Errorf("unable to read snapshot metadata: %v",
+			err)
+	}
+	if magic != snapshotMagic {
+		return nil, errors.New("invalid snapshot magic")
+	}
+	var metadata [2 + 4 + chainhash.HashSize + 8]byte
+	if _, err := io.ReadFull(br, metadata[:]); err != nil {
+		return nil, fmt.Errorf("unable to read snapshot metadata: %v",
+			err)
+	}
+	version := binary.LittleEndian.Uint16(metadata[:2])
+	if version != snapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d",
+			version)
+	}
+	net := wire.BitcoinNet(binary.LittleEndian.Uint32(metadata[2:6]))
+	if net != b.chainParams.Net {
+		return nil, fmt.Errorf("snapshot is for network %v, not %v",
+			net, b.chainParams.Net)
+	}
+	var baseHash chainhash.Hash
+	copy(baseHash[:], metadata[6:6+chainhash.HashSize])
+	numCoins := binary.LittleEndian.Uint64(metadata[6+chainhash.HashSize:])
+
+	data := b.findAssumeUtxo(&baseHash)
+	if data == nil {
+		return nil, fmt.Errorf("no UTXO set snapshot is known for "+
+			"block %v", baseHash)
+	}
+
+	b.chainLock.RLock()
+	_, err := b.checkSnapshotActivation(&baseHash)
+	b.chainLock.RUnlock()
+	if err != nil {
+		return nil, err
+	}
+
+	log.Infof("Loading %d coins of the UTXO set snapshot at block %v "+
+		"(height %d)", numCoins, baseHash, data.Height)
+
+	// Start over with an empty bucket in case a previous attempt to load
+	// a snapshot was aborted.
+	err = b.db.Update(func(dbTx database.Tx) error {
+		meta := dbTx.Metadata()
+		if meta.Bucket(snapshotUtxoSetBucketName) != nil {
+			err := meta.DeleteBucket(snapshotUtxoSetBucketName)
+			if err != nil {
+				return err
+			}
+		}
+		_, err := meta.CreateBucket(snapshotUtxoSetBucketName)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	hashSerialized, err := b.loadSnapshotCoins(br, numCoins, data.Height)
+	if err == nil && hashSerialized != *data.HashSerialized {
+		err = fmt.Errorf("snapshot has hash %v instead of the expected "+
+			"%v", hashSerialized, data.HashSerialized)
+	}
+	if err != nil {
+		dbErr := b.db.Update(func(dbTx database.Tx) error {
+			return dbTx.Metadata().DeleteBucket(
+				snapshotUtxoSetBucketName,
+			)
+		})
+		if dbErr != nil {
+			log.Errorf("Unable to remove the snapshot utxo set: %v",
+				dbErr)
+		}
+		return nil, err
+	}
+
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	baseNode, err := b.checkSnapshotActivation(&baseHash)
+	if err != nil {
+		return nil, err
+	}
+	if err := b.activateSnapshot(baseNode, data); err != nil {
+		return nil, err
+	}
+
+	log.Infof("Loaded the UTXO set snapshot at block %v (height %d)",
+		baseHash, baseNode.height)
+
+	return &UtxoSnapshotStats{
+		BaseHash:       baseHash,
+		BaseHeight:     baseNode.height,
+		NumCoins:       numCoins,
+		HashSerialized: hashSerialized,
+		ChainTxCount:   data.ChainTxCount,
+	}, nil
+}
+
+// loadSnapshotCoins reads the passed number of coins of a UTXO set snapshot
+// from the passed reader into the snapshot utxo set bucket and returns the
+// hash_serialized_3 hash of the coins.  The coins must be ordered by their
+// outpoints, like they are in the utxo set bucket.
+func (b *BlockChain) loadSnapshotCoins(r *bufio.Reader, numCoins uint64,
+	baseHeight int32) (chainhash.Hash, error) {
+
+	type coin struct {
+		key        []byte
+		serialized []byte
+	}
+	batch := make([]coin, 0, snapshotWriteBatchSize)
+	writeBatch := func() error {
+		err := b.db.Update(func(dbTx database.Tx) error {
+			bucket := dbTx.Metadata().Bucket(snapshotUtxoSetBucketName)
+			for _, c := range batch {
+				if err := bucket.Put(c.key, c.serialized); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		batch = batch[:0]
+		return err
+	}
+
+	hasher := newUtxoSetHasher()
+	var prevKey []byte
+	var coinsRead uint64
+	for coinsRead < numCoins {
+		var txHash chainhash.Hash
+		if _, err := io.ReadFull(r, txHash[:]); err != nil {
+			return chainhash.Hash{}, err
+		}
+		numTxCoins, err := wire.ReadVarInt(r, 0)
+		if err != nil {
+			return chainhash.Hash{}, err
+		}
+		if numTxCoins == 0 || numTxCoins > numCoins-coinsRead {
+			return chainhash.Hash{}, fmt.Errorf("invalid number of "+
+				"coins %d for transaction %v", numTxCoins, txHash)
+		}
+
+		for i := uint64(0); i < numTxCoins; i++ {
+			index, err := wire.ReadVarInt(r, 0)
+			if err != nil {
+				return chainhash.Hash{}, err
+			}
+			if index >= MaxOutputsPerBlock {
+				return chainhash.Hash{}, fmt.Errorf("invalid "+
+					"output index %d of transaction %v",
+					index, txHash)
+			}
+			serialized, err := readSnapshotCoin(r)
+			if err != nil {
+				return chainhash.Hash{}, err
+			}
+			entry, err := deserializeUtxoEntry(serialized)
+			if err != nil {
+				return chainhash.Hash{}, err
+			}
+			outpoint := wire.OutPoint{Hash: txHash, Index: uint32(index)}
+			if entry.BlockHeight() > baseHeight {
+				return chainhash.Hash{}, fmt.Errorf("coin %v "+
+					"has height %d after the snapshot base",
+					outpoint, entry.BlockHeight())
+			}
+			if entry.Amount() < 0 ||
+				entry.Amount() > btcutil.MaxSatoshi {
+
+				return chainhash.Hash{}, fmt.Errorf("coin %v "+
+					"has invalid amount %d", outpoint,
+					entry.Amount())
+			}
+
+			// The hash is computed over the coins in the order of
+			// their keys, so they must be in that order.
+			key := outpointKey(outpoint)
+			keyCopy := append([]byte(nil), *key...)
+			recycleOutpointKey(key)
+			if prevKey != nil && bytes.Compare(prevKey, keyCopy) >= 0 {
+				return chainhash.Hash{}, fmt.Errorf("coin %v "+
+					"is out of order", outpoint)
+			}
+			prevKey = keyCopy
+
+			hasher.add(outpoint, entry)
+			batch = append(batch, coin{
+				key:        keyCopy,
+				serialized: serialized,
+			})
+			if len(batch) == snapshotWriteBatchSize {
+				if err := writeBatch(); err != nil {
+					return chainhash.Hash{}, err
+				}
+			}
+		}
+		coinsRead += numTxCoins
+
+		if interruptRequested(b.interrupt) {
+			return chainhash.Hash{}, errInterruptRequested
+		}
+	}
+	if err := writeBatch(); err != nil {
+		return chainhash.Hash{}, err
+	}
+
+	// The snapshot must not contain more data than the coins.
+	if _, err := r.ReadByte(); err != io.EOF {
+		return chainhash.Hash{}, errors.New("snapshot contains more " +
+			"coins than indicated by its metadata")
+	}
+
+	return hasher.sum(), nil
+}
+
+// activateSnapshot makes the chain state continue from the base block of the
+// UTXO set snapshot that was loaded into the snapshot utxo set bucket.  The
+// original utxo set is kept to validate the chain before the snapshot base in
+// the background.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) activateSnapshot(baseNode *blockNode,
+	data *chaincfg.AssumeUtxoData) error {
+
+	// Flush the utxo cache so the original utxo set is consistent with the
+	// tip it is validated from in the background.
+	tip := b.bestChain.Tip()
+	err := b.db.Update(func(dbTx database.Tx) error {
+		return b.utxoCache.flush(dbTx, FlushRequired, b.BestSnapshot())
+	})
+	if err != nil {
+		return err
+	}
+
+	s := &snapshotChainstate{
+		baseNode:       baseNode,
+		hashSerialized: *data.HashSerialized,
+		status:         snapshotValidating,
+		bgTip:          tip,
+		blockStored:    make(chan struct{}, 1),
+	}
+	state := newBestState(baseNode, 0, 0, 0, data.ChainTxCount,
+		CalcPastMedianTime(baseNode))
+	err = b.db.Update(func(dbTx database.Tx) error {
+		// Add the blocks up to the snapshot base to the block index
+		// which tracks the main chain.
+		for n := baseNode; n != tip; n = n.parent {
+			err := dbPutBlockIndex(dbTx, &n.hash, n.height)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := dbTx.Metadata().Put(bgUtxoStateConsistencyKeyName,
+			tip.hash[:])
+		if err != nil {
+			return err
+		}
+		err = dbPutUtxoStateConsistency(dbTx, &baseNode.hash)
+		if err != nil {
+			return err
+		}
+		err = dbPutBestState(dbTx, state, baseNode.workSum)
+		if err != nil {
+			return err
+		}
+		return dbPutSnapshotState(dbTx, s)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.bgCache = b.newBackgroundUtxoCache(tip)
+	b.utxoCache.bucketName = snapshotUtxoSetBucketName
+	b.utxoCache.lastFlushHash = baseNode.hash
+	b.snapshot = s
+
+	b.bestChain.SetTip(baseNode)
+	b.stateLock.Lock()
+	b.stateSnapshot = state
+	b.stateLock.Unlock()
+
+	go b.validateSnapshotChain()
+
+	return nil
+}
+
+// notifySnapshotBlockStored signals the background validation of the chain
+// before the base of a UTXO set snapshot that a block was downloaded.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) notifySnapshotBlockStored() {
+	if b.snapshot == nil || b.snapshot.blockStored == nil {
+		return
+	}
+
+	select {
+	case b.snapshot.blockStored <- struct{}{}:
+	default:
+	}
+}
+
+// SnapshotBlocksNeeded returns the hashes of the blocks that have to be
+// downloaded next to validate the chain before the base of a loaded UTXO set
+// snapshot, in order of their height.  Only blocks up to the passed number of
+// blocks past the block the chain is validated up to are returned.  It returns
+// nil when no chain is validated in the background.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) SnapshotBlocksNeeded(window int32) []*chainhash.Hash {
+	b.chainLock.RLock()
+	defer b.chainLock.RUnlock()
+
+	s := b.snapshot
+	if s == nil || s.status != snapshotValidating {
+		return nil
+	}
+
+	endHeight := s.bgTip.height + window
+	if endHeight > s.baseNode.height {
+		endHeight = s.baseNode.height
+	}
+	var hashes []*chainhash.Hash
+	for height := s.bgTip.height + 1; height <= endHeight; height++ {
+		node := s.baseNode.Ancestor(height)
+		if !b.index.NodeStatus(node).HaveData() {
+			hashes = append(hashes, &node.hash)
+		}
+	}
+
+	return hashes
+}
+
+// validateSnapshotChain validates the chain before the base of a loaded UTXO
+// set snapshot as its blocks are downloaded, until the snapshot base is
+// reached.
+func (b *BlockChain) validateSnapshotChain() {
+	for !interruptRequested(b.interrupt) {
+		b.chainLock.RLock()
+		s := b.snapshot
+		validating := s.status == snapshotValidating
+		b.chainLock.RUnlock()
+		if !validating {
+			return
+		}
+
+		connected, err := b.connectSnapshotChainBlock()
+		if err != nil {
+			log.Errorf("Background validation of the chain before "+
+				"the UTXO set snapshot base failed: %v", err)
+			return
+		}
+		if connected {
+			continue
+		}
+
+		// Wait for the next block to be downloaded.
+		select {
+		case <-s.blockStored:
+		case <-time.After(snapshotBlockWaitInterval):
+		case <-b.interrupt:
+		}
+	}
+}
+
+// connectSnapshotChainBlock validates the next block of the chain before the
+// base of a loaded UTXO set snapshot and connects it to the utxo set used to
+// validate the chain in the background.  It returns whether a block was
+// connected, which is not the case when the next block is not downloaded yet.
+// The validation is finished once the snapshot base is connected.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) connectSnapshotChainBlock() (bool, error) {
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	s := b.snapshot
+	if s.bgTip == s.baseNode {
+		return false, b.finishSnapshotValidation()
+	}
+
+	node := s.baseNode.Ancestor(s.bgTip.height + 1)
+	if !b.index.NodeStatus(node).HaveData() {
+		return false, nil
+	}
+	var block *btcutil.Block
+	err := b.db.View(func(dbTx database.Tx) error {
+		var err error
+		block, err = dbFetchBlockByNode(dbTx, node)
+		return err
+	})
+	if err != nil {
+		return false, err
+	}
+
+	view := NewUtxoViewpoint()
+	view.SetBestHash(&s.bgTip.hash)
+	err = b.checkConnectBlock(node, block, view, s.bgCache, nil)
+	if err != nil {
+		if _, ok := err.(RuleError); ok {
+			b.index.SetStatusFlags(node, statusValidateFailed)
+			if writeErr := b.index.flushToDB(); writeErr != nil {
+				log.Warnf("Error flushing block index changes "+
+					"to disk: %v", writeErr)
+			}
+			return false, b.invalidateSnapshot(err)
+		}
+		return false, err
+	}
+
+	stxos := make([]SpentTxOut, 0, countSpentOutputs(block))
+	err = s.bgCache.connectTransactions(block, &stxos)
+	if err != nil {
+		return false, err
+	}
+	b.index.SetStatusFlags(node, statusValid)
+	err = b.index.flushToDB()
+	if err != nil {
+		return false, err
+	}
+
+	// Store the spend journal of the block so it can be disconnected once
+	// the chain is validated.
+	err = b.db.Update(func(dbTx database.Tx) error {
+		err := dbPutSpendJournalEntry(dbTx, &node.hash, stxos)
+		if err != nil {
+			return err
+		}
+		return s.bgCache.flush(dbTx, FlushIfNeeded, &BestState{
+			Hash:   node.hash,
+			Height: node.height,
+		})
+	})
+	if err != nil {
+		return false, err
+	}
+	s.bgTip = node
+
+	return true, nil
+}
+
+// finishSnapshotValidation checks the utxo set resulting from the validation
+// of the chain up to the base of a loaded UTXO set snapshot matches the
+// snapshot.  The original utxo set is no longer needed afterwards.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) finishSnapshotValidation() error {
+	s := b.snapshot
+	bestState := &BestState{Hash: s.baseNode.hash, Height: s.baseNode.height}
+	err := b.db.Update(func(dbTx database.Tx) error {
+		return s.bgCache.flush(dbTx, FlushRequired, bestState)
+	})
+	if err != nil {
+		return err
+	}
+
+	var hashSerialized chainhash.Hash
+	err = b.db.View(func(dbTx database.Tx) error {
+		hasher := newUtxoSetHasher()
+		cursor := dbTx.Metadata().Bucket(utxoSetBucketName).Cursor()
+		for ok := cursor.First(); ok; ok = cursor.Next() {
+			outpoint, err := outpointFromKey(cursor.Key())
+			if err != nil {
+				return err
+			}
+			entry, err := deserializeUtxoEntry(cursor.Value())
+			if err != nil {
+				return err
+			}
+			hasher.add(outpoint, entry)
+		}
+		hashSerialized = hasher.sum()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if hashSerialized != s.hashSerialized {
+		return b.invalidateSnapshot(fmt.Errorf("the UTXO set at the "+
+			"snapshot base has hash %v instead of %v",
+			hashSerialized, s.hashSerialized))
+	}
+
+	s.status = snapshotValidated
+	err = b.db.Update(func(dbTx database.Tx) error {
+		meta := dbTx.Metadata()
+		if err := meta.DeleteBucket(utxoSetBucketName); err != nil {
+			return err
+		}
+		if _, err := meta.CreateBucket(utxoSetBucketName); err != nil {
+			return err
+		}
+		if err := meta.Delete(bgUtxoStateConsistencyKeyName); err != nil {
+			return err
+		}
+		return dbPutSnapshotState(dbTx, s)
+	})
+	if err != nil {
+		return err
+	}
+	s.bgCache = nil
+
+	log.Infof("Validated the chain up to the UTXO set snapshot base "+
+		"block %v (height %d)", s.baseNode.hash, s.baseNode.height)
+
+	return nil
+}
+
+// invalidateSnapshot marks the chain state loaded from a UTXO set snapshot as
+// invalid since the chain before the snapshot base failed to validate with the
+// passed error.
+//
+// This function MUST be called with the chain state lock held (for writes).
+func (b *BlockChain) invalidateSnapshot(validateErr error) error {
+	s := b.snapshot
+	s.status = snapshotInvalid
+	err := b.db.Update(func(dbTx database.Tx) error {
+		return dbPutSnapshotState(dbTx, s)
+	})
+	if err != nil {
+		return err
+	}
+
+	return fmt.Errorf("the chain before the UTXO set snapshot base "+
+		"block %v is invalid, so the chain state must be resynced: %v",
+		s.baseNode.hash, validateErr)
+}
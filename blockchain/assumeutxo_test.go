@@ -0,0 +1,221 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bynil/btcd/blockchain/internal/testhelper"
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+)
+
+// snapshotStatusOf returns the status of the UTXO set snapshot loaded into the
+// passed chain.
+func snapshotStatusOf(chain *BlockChain) snapshotStatus {
+	chain.chainLock.RLock()
+	defer chain.chainLock.RUnlock()
+
+	return chain.snapshot.status
+}
+
+// TestUtxoSnapshot ensures a UTXO set snapshot dumped from one chain can be
+// loaded into another chain which only knows the headers, that the chain is
+// extended from the snapshot base, and that the chain before the base is
+// validated in the background as its blocks arrive.
+func TestUtxoSnapshot(t *testing.T) {
+	src, params, tearDownSrc := utxoCacheTestChain("TestUtxoSnapshot-src")
+
+	const numBlocks = 10
+	genesis := btcutil.NewBlock(params.GenesisBlock)
+	_, spendableOuts, err := addBlocks(
+		numBlocks, src, genesis, []*testhelper.SpendableOut{},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := make([]*btcutil.Block, 0, numBlocks)
+	for height := int32(1); height <= numBlocks; height++ {
+		block, err := src.BlockByHeight(height)
+		if err != nil {
+			t.Fatal(err)
+		}
+		blocks = append(blocks, block)
+	}
+	base := blocks[numBlocks-1]
+
+	var snapshot bytes.Buffer
+	stats, err := src.DumpUtxoSnapshot(&snapshot)
+	if err != nil {
+		t.Fatalf("DumpUtxoSnapshot: unexpected error: %v", err)
+	}
+	if stats.BaseHash != *base.Hash() || stats.BaseHeight != numBlocks {
+		t.Fatalf("got snapshot base %v (height %d), want %v (height %d)",
+			stats.BaseHash, stats.BaseHeight, base.Hash(), numBlocks)
+	}
+	if stats.NumCoins == 0 {
+		t.Fatal("snapshot contains no coins")
+	}
+
+	// The teardown of a test chain removes the directory of all test
+	// databases, so the source chain is torn down before the destination
+	// chain is created.
+	tearDownSrc()
+
+	dst, _, tearDownDst := utxoCacheTestChain("TestUtxoSnapshot-dst")
+	defer tearDownDst()
+
+	// load loads the snapshot pinned with the passed hash into the
+	// destination chain.
+	load := func(hashSerialized chainhash.Hash) error {
+		dst.chainParams.AssumeUtxo = []chaincfg.AssumeUtxoData{{
+			Height:         stats.BaseHeight,
+			BlockHash:      &stats.BaseHash,
+			HashSerialized: &hashSerialized,
+			ChainTxCount:   stats.ChainTxCount,
+		}}
+		_, err := dst.LoadUtxoSnapshot(bytes.NewReader(snapshot.Bytes()))
+		return err
+	}
+
+	// The snapshot can't be loaded before the header of its base block is
+	// known.
+	if err := load(stats.HashSerialized); err == nil {
+		t.Fatal("snapshot with an unknown base block loaded")
+	}
+	for _, block := range blocks {
+		err := dst.ProcessBlockHeader(&block.MsgBlock().Header, BFNone)
+		if err != nil {
+			t.Fatalf("ProcessBlockHeader: unexpected error: %v", err)
+		}
+	}
+
+	// A snapshot which does not match the pinned hash is rejected.
+	if err := load(chainhash.Hash{0x01}); err == nil {
+		t.Fatal("snapshot not matching the pinned hash loaded")
+	}
+	if tip := dst.BestSnapshot(); tip.Hash != *params.GenesisHash {
+		t.Fatalf("got tip %v after a rejected snapshot, want %v",
+			tip.Hash, params.GenesisHash)
+	}
+
+	if err := load(stats.HashSerialized); err != nil {
+		t.Fatalf("LoadUtxoSnapshot: unexpected error: %v", err)
+	}
+	if tip := dst.BestSnapshot(); tip.Hash != stats.BaseHash {
+		t.Fatalf("got tip %v after loading the snapshot, want %v",
+			tip.Hash, stats.BaseHash)
+	}
+	if err := load(stats.HashSerialized); err == nil {
+		t.Fatal("second snapshot loaded")
+	}
+
+	// The chain is extended from the snapshot base by a block spending
+	// coins from the snapshot.
+	next, _, err := addBlock(dst, base, spendableOuts[numBlocks-1])
+	if err != nil {
+		t.Fatalf("unable to extend the chain from the snapshot: %v", err)
+	}
+	if tip := dst.BestSnapshot(); tip.Hash != *next.Hash() {
+		t.Fatalf("got tip %v, want %v", tip.Hash, next.Hash())
+	}
+
+	// The blocks before the snapshot base are requested and validated in
+	// the background as they arrive.
+	needed := dst.SnapshotBlocksNeeded(numBlocks)
+	if len(needed) != numBlocks {
+		t.Fatalf("got %d blocks needed, want %d", len(needed), numBlocks)
+	}
+	for _, block := range blocks {
+		if _, _, err := dst.ProcessBlock(block, BFNone); err != nil {
+			t.Fatalf("ProcessBlock: unexpected error: %v", err)
+		}
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for snapshotStatusOf(dst) == snapshotValidating {
+		if time.Now().After(deadline) {
+			t.Fatal("background validation did not finish")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if status := snapshotStatusOf(dst); status != snapshotValidated {
+		t.Fatalf("got snapshot status %d, want %d", status,
+			snapshotValidated)
+	}
+	if needed := dst.SnapshotBlocksNeeded(numBlocks); len(needed) != 0 {
+		t.Fatalf("got %d blocks needed after the validation, want none",
+			len(needed))
+	}
+	if tip := dst.BestSnapshot(); tip.Hash != *next.Hash() {
+		t.Fatalf("got tip %v after the validation, want %v", tip.Hash,
+			next.Hash())
+	}
+}
+
+// TestAssumeUtxoParams ensures the UTXO set snapshots pinned in the parameters
+// of the networks are found when a snapshot is loaded, so only the header of
+// its base block is missing, while a snapshot at any other block is rejected.
+func TestAssumeUtxoParams(t *testing.T) {
+	// snapshotHeader returns the metadata of a snapshot of the passed
+	// network with the passed base block.
+	snapshotHeader := func(net uint32, baseHash *chainhash.Hash) []byte {
+		var buf bytes.Buffer
+		buf.Write(snapshotMagic[:])
+		binary.Write(&buf, binary.LittleEndian, uint16(snapshotVersion))
+		binary.Write(&buf, binary.LittleEndian, net)
+		buf.Write(baseHash[:])
+		binary.Write(&buf, binary.LittleEndian, uint64(0))
+		return buf.Bytes()
+	}
+
+	for _, params := range []chaincfg.Params{
+		chaincfg.MainNetParams, chaincfg.TestNet3Params,
+		chaincfg.SigNetParams,
+	} {
+		params := params
+		t.Run(params.Name, func(t *testing.T) {
+			chain, tearDown, err := chainSetup(
+				"TestAssumeUtxoParams", &params,
+			)
+			if err != nil {
+				t.Fatalf("failed to setup chain instance: %v", err)
+			}
+			defer tearDown()
+
+			if len(params.AssumeUtxo) == 0 {
+				t.Fatal("no UTXO set snapshots")
+			}
+			for _, data := range params.AssumeUtxo {
+				snapshot := snapshotHeader(uint32(params.Net),
+					data.BlockHash)
+				_, err := chain.LoadUtxoSnapshot(
+					bytes.NewReader(snapshot),
+				)
+				if err == nil || !strings.Contains(err.Error(),
+					"is not known") {
+
+					t.Fatalf("loading the snapshot at height "+
+						"%d: got error %v, want unknown "+
+						"base header", data.Height, err)
+				}
+			}
+
+			snapshot := snapshotHeader(uint32(params.Net),
+				params.GenesisHash)
+			_, err = chain.LoadUtxoSnapshot(bytes.NewReader(snapshot))
+			if err == nil || !strings.Contains(err.Error(),
+				"no UTXO set snapshot is known") {
+
+				t.Fatalf("loading an unknown snapshot: got error "+
+					"%v, want unknown snapshot", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/bynil/btcd/chaincfg/chainhash"
+)
+
+// assumeValidMinBuriedTime is the minimum amount of work, expressed as the
+// time it takes to mine it at the difficulty of the best known header, the
+// best known header chain must build on top of a block for its scripts to be
+// assumed valid.  It makes sure the block isn't near the tip of the chain,
+// where blocks are mined on top of it with scripts verified by the network.
+const assumeValidMinBuriedTime = 2 * 7 * 24 * time.Hour
+
+// proofEquivalentTime returns the time it takes to mine the difference of
+// work between the two passed nodes at the difficulty of the passed tip node.
+// The result is negative when the from node has more work than the to node.
+func proofEquivalentTime(to, from, tip *blockNode,
+	targetTimePerBlock time.Duration) time.Duration {
+
+	tipWork := CalcWork(tip.bits)
+	if tipWork.Sign() <= 0 {
+		return 0
+	}
+	workDiff := new(big.Int).Sub(to.workSum, from.workSum)
+	workDiff.Mul(workDiff, big.NewInt(int64(targetTimePerBlock/time.Second)))
+	workDiff.Quo(workDiff, tipWork)
+
+	// Clamp the result to the range of a duration.
+	maxSeconds := big.NewInt(int64(math.MaxInt64 / time.Second))
+	if workDiff.CmpAbs(maxSeconds) > 0 {
+		if workDiff.Sign() < 0 {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	}
+	return time.Duration(workDiff.Int64()) * time.Second
+}
+
+// isAssumedValid returns whether the scripts of the block of the passed node
+// are assumed to be valid, so they don't need to be verified.  That's the
+// case when:
+//
+//   - The AssumeValid block is known and the node is one of its ancestors or
+//     the block itself
+//   - The best known header chain contains the AssumeValid block
+//   - The best known header chain has at least the minimum chain work of the
+//     chain parameters
+//   - The best known header chain builds at least two weeks worth of work on
+//     top of the node
+//
+// All the other validation rules are still applied to the block.
+//
+// This function MUST be called with the chain state lock held (for reads).
+func (b *BlockChain) isAssumedValid(node *blockNode) bool {
+	if b.assumeValid == nil {
+		return false
+	}
+
+	assumeValidNode := b.index.LookupNode(b.assumeValid)
+	if assumeValidNode == nil ||
+		assumeValidNode.Ancestor(node.height) != node {
+
+		return false
+	}
+
+	bestHeader := b.index.BestHeader()
+	if bestHeader == nil ||
+		bestHeader.Ancestor(assumeValidNode.height) != assumeValidNode {
+
+		return false
+	}
+
+	minChainWork := b.chainParams.MinimumChainWork
+	if minChainWork != nil && bestHeader.workSum.Cmp(minChainWork) < 0 {
+		return false
+	}
+
+	buriedTime := proofEquivalentTime(bestHeader, node, bestHeader,
+		b.chainParams.TargetTimePerBlock)
+	return buriedTime >= assumeValidMinBuriedTime
+}
+
+// AssumeValid returns the hash of the block whose scripts and those of its
+// ancestors are assumed to be valid, or nil when every script is verified.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) AssumeValid() *chainhash.Hash {
+	return b.assumeValid
+}
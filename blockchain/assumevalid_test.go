@@ -0,0 +1,109 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/bynil/btcd/chaincfg"
+)
+
+// TestIsAssumedValid ensures the scripts of blocks are only assumed to be
+// valid for ancestors of the assumed valid block which are buried deeply
+// enough in a header chain with enough work.
+func TestIsAssumedValid(t *testing.T) {
+	params := chaincfg.RegressionNetParams
+	chain := newFakeChain(&params)
+
+	// Create a header chain of 3000 blocks with the same difficulty, so
+	// two weeks of work are 2016 blocks.
+	const numNodes = 3000
+	nodes := make([]*blockNode, numNodes)
+	tip := chain.bestChain.Tip()
+	for i := range nodes {
+		timestamp := time.Unix(tip.timestamp, 0).Add(10 * time.Minute)
+		nodes[i] = newFakeNode(tip, 1, params.PowLimitBits, timestamp)
+		chain.index.AddNode(nodes[i])
+		tip = nodes[i]
+	}
+	if chain.index.BestHeader() != tip {
+		t.Fatalf("BestHeader: got height %d, want %d",
+			chain.index.BestHeader().height, tip.height)
+	}
+
+	// Nothing is assumed valid without an assumed valid block.
+	if chain.isAssumedValid(nodes[10]) {
+		t.Fatalf("isAssumedValid: block assumed valid without an " +
+			"assumed valid block")
+	}
+
+	// A fork of the header chain which doesn't contain the assumed valid
+	// block.
+	fork := newFakeNode(nodes[100], 2, params.PowLimitBits,
+		time.Unix(nodes[100].timestamp, 0).Add(time.Second))
+	chain.index.AddNode(fork)
+
+	// The assumed valid block itself is buried one block less than two
+	// weeks.
+	assumeValid := nodes[numNodes-2016]
+	chain.assumeValid = &assumeValid.hash
+	tests := []struct {
+		name string
+		node *blockNode
+		want bool
+	}{
+		{"ancestor", nodes[100], true},
+		{"buried two weeks", nodes[numNodes-2017], true},
+		{"assumed valid block", nodes[numNodes-2016], false},
+		{"descendant", nodes[numNodes-1], false},
+		{"fork", fork, false},
+	}
+	for _, test := range tests {
+		if got := chain.isAssumedValid(test.node); got != test.want {
+			t.Errorf("isAssumedValid (%s): got %v, want %v",
+				test.name, got, test.want)
+		}
+	}
+
+	// The best header chain must have the minimum chain work.
+	params.MinimumChainWork = new(big.Int).Add(tip.workSum, big.NewInt(1))
+	if chain.isAssumedValid(nodes[100]) {
+		t.Fatalf("isAssumedValid: block assumed valid without the " +
+			"minimum chain work")
+	}
+	params.MinimumChainWork = tip.workSum
+	if !chain.isAssumedValid(nodes[100]) {
+		t.Fatalf("isAssumedValid: block not assumed valid with the " +
+			"minimum chain work")
+	}
+
+	// Once the best header is known to be invalid, its parent becomes the
+	// best header, so the ancestors which were buried exactly two weeks
+	// no longer are.
+	chain.index.SetStatusFlags(tip, statusValidateFailed)
+	if chain.index.BestHeader() != tip.parent {
+		t.Fatalf("BestHeader: got height %d, want %d",
+			chain.index.BestHeader().height, tip.parent.height)
+	}
+	if chain.isAssumedValid(nodes[numNodes-2017]) {
+		t.Fatalf("isAssumedValid: block assumed valid while buried " +
+			"less than two weeks")
+	}
+
+	// The best header chain must contain the assumed valid block, which
+	// isn't the case once the fork has the most work.
+	forkTip := fork
+	for forkTip.workSum.Cmp(tip.parent.workSum) <= 0 {
+		forkTip = newFakeNode(forkTip, 2, params.PowLimitBits,
+			time.Unix(forkTip.timestamp, 0).Add(10*time.Minute))
+		chain.index.AddNode(forkTip)
+	}
+	if chain.isAssumedValid(nodes[100]) {
+		t.Fatalf("isAssumedValid: block assumed valid with a best " +
+			"header chain not containing the assumed valid block")
+	}
+}
@@ -363,6 +363,11 @@ type blockIndex struct {
 	sync.RWMutex
 	index map[chainhash.Hash]*blockNode
 	dirty map[*blockNode]struct{}
+
+	// bestHeader is the node with the most cumulative work in the index
+	// which isn't known to be invalid, whether its block data is known or
+	// not.
+	bestHeader *blockNode
 }
 
 // newBlockIndex returns a new empty instance of a block index.  The index will
@@ -415,6 +420,32 @@ func (bi *blockIndex) AddNode(node *blockNode) {
 // This function is NOT safe for concurrent access.
 func (bi *blockIndex) addNode(node *blockNode) {
 	bi.index[node.hash] = node
+	bi.maybeUpdateBestHeader(node)
+}
+
+// maybeUpdateBestHeader sets the best header to the provided node when it has
+// more cumulative work than the current one and isn't known to be invalid.
+//
+// This function MUST be called with the block index lock held (for writes).
+func (bi *blockIndex) maybeUpdateBestHeader(node *blockNode) {
+	if node.status.KnownInvalid() {
+		return
+	}
+	if bi.bestHeader == nil || node.workSum.Cmp(bi.bestHeader.workSum) > 0 {
+		bi.bestHeader = node
+	}
+}
+
+// BestHeader returns the node with the most cumulative work in the index which
+// isn't known to be invalid.  Since headers are added to the index before
+// their blocks are downloaded, it can be ahead of the tip of the best chain.
+//
+// This function is safe for concurrent access.
+func (bi *blockIndex) BestHeader() *blockNode {
+	bi.RLock()
+	bestHeader := bi.bestHeader
+	bi.RUnlock()
+	return bestHeader
 }
 
 // NodeStatus provides concurrent-safe access to the status field of a node.
@@ -436,6 +467,15 @@ func (bi *blockIndex) SetStatusFlags(node *blockNode, flags blockStatus) {
 	bi.Lock()
 	node.status |= flags
 	bi.dirty[node] = struct{}{}
+
+	// Find the new best header when the current one is now known to be
+	// invalid.
+	if node == bi.bestHeader && node.status.KnownInvalid() {
+		bi.bestHeader = nil
+		for _, n := range bi.index {
+			bi.maybeUpdateBestHeader(n)
+		}
+	}
 	bi.Unlock()
 }
 
@@ -447,6 +487,7 @@ func (bi *blockIndex) UnsetStatusFlags(node *blockNode, flags blockStatus) {
 	bi.Lock()
 	node.status &^= flags
 	bi.dirty[node] = struct{}{}
+	bi.maybeUpdateBestHeader(node)
 	bi.Unlock()
 }
 
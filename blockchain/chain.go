@@ -102,6 +102,13 @@ type BlockChain struct {
 	sigCache            *txscript.SigCache
 	indexManager        IndexManager
 	hashCache           *txscript.HashCache
+	interrupt           <-chan struct{}
+
+	// assumeValid is the hash of the block whose scripts and those of its
+	// ancestors are assumed to be valid, or nil when all scripts are
+	// verified.  See isAssumedValid for the conditions under which script
+	// verification is skipped.
+	assumeValid *chainhash.Hash
 
 	// The following fields are calculated based upon the provided chain
 	// parameters.  They are also set when the instance is created and
@@ -135,6 +142,13 @@ type BlockChain struct {
 	// It is protected by the chain lock.
 	utxoCache *utxoCache
 
+	// snapshot tracks the UTXO set snapshot the chain state was loaded from,
+	// if any, along with the state used to validate the chain before it in
+	// the background.  It is protected by the chain lock, while
+	// snapshotLoadLock prevents loading multiple snapshots at once.
+	snapshot         *snapshotChainstate
+	snapshotLoadLock sync.Mutex
+
 	// These fields are related to handling of orphan blocks.  They are
 	// protected by a combination of the chain lock and the orphan lock.
 	orphanLock   sync.RWMutex
@@ -782,7 +796,8 @@ func (b *BlockChain) disconnectBlock(node *blockNode, block *btcutil.Block, view
 		// Update the utxo set using the state of the utxo view.  This
 		// entails restoring all of the utxos spent and removing the new
 		// ones created by the block.
-		err = dbPutUtxoView(dbTx, view)
+		utxoBucket := dbTx.Metadata().Bucket(b.utxoCache.bucketName)
+		err = dbPutUtxoView(dbTx, utxoBucket, view)
 		if err != nil {
 			return err
 		}
@@ -870,6 +885,20 @@ func countSpentOutputs(block *btcutil.Block) int {
 //
 // This function MUST be called with the chain state lock held (for writes).
 func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error {
+	// The blocks up to the base of a loaded UTXO set snapshot can't be
+	// disconnected before the chain up to it is validated in the
+	// background, since there is no spend journal for them before then.
+	if s := b.snapshot; s != nil && s.status != snapshotValidated &&
+		detachNodes.Len() > 0 {
+
+		forkNode := detachNodes.Back().Value.(*blockNode).parent
+		if forkNode.height < s.baseNode.height {
+			return fmt.Errorf("unable to reorganize the chain to "+
+				"before the UTXO set snapshot base block %v",
+				s.baseNode.hash)
+		}
+	}
+
 	// Check first that the detach and the attach nodes are valid and they
 	// pass verification.
 	detachBlocks, attachBlocks, detachSpentTxOuts,
@@ -905,7 +934,7 @@ func (b *BlockChain) reorganizeChain(detachNodes, attachNodes *list.List) error
 		// Update the view to unspend all of the spent txos and remove
 		// the utxos created by the block.
 		err = view.disconnectTransactions(
-			b.db, block, detachSpentTxOuts[i],
+			b.utxoCache, block, detachSpentTxOuts[i],
 		)
 		if err != nil {
 			return err
@@ -1064,7 +1093,7 @@ func (b *BlockChain) verifyReorganizationValidity(detachNodes, attachNodes *list
 		detachBlocks = append(detachBlocks, block)
 		detachSpentTxOuts = append(detachSpentTxOuts, stxos)
 
-		err = view.disconnectTransactions(b.db, block, stxos)
+		err = view.disconnectTransactions(b.utxoCache, block, stxos)
 		if err != nil {
 			return nil, nil, nil, err
 		}
@@ -1122,7 +1151,7 @@ func (b *BlockChain) verifyReorganizationValidity(detachNodes, attachNodes *list
 		// In the case the block is determined to be invalid due to a
 		// rule violation, mark it as invalid and mark all of its
 		// descendants as having an invalid ancestor.
-		err = b.checkConnectBlock(n, block, view, nil)
+		err = b.checkConnectBlock(n, block, view, b.utxoCache, nil)
 		if err != nil {
 			if _, ok := err.(RuleError); ok {
 				b.index.SetStatusFlags(n, statusValidateFailed)
@@ -1191,7 +1220,7 @@ func (b *BlockChain) connectBestChain(node *blockNode, block *btcutil.Block, fla
 			// expensive memory allocation done by fetch input utxos.
 			view := NewUtxoViewpoint()
 			view.SetBestHash(parentHash)
-			err := b.checkConnectBlock(node, block, view, nil)
+			err := b.checkConnectBlock(node, block, view, b.utxoCache, nil)
 			if err == nil {
 				b.index.SetStatusFlags(node, statusValid)
 			} else if _, ok := err.(RuleError); ok {
@@ -2098,6 +2127,13 @@ type Config struct {
 	// checkpoints.
 	Checkpoints []chaincfg.Checkpoint
 
+	// AssumeValid overrides the AssumeValid block of ChainParams, whose
+	// scripts and those of its ancestors are assumed to be valid.  The zero
+	// hash disables the optimization so every script is verified.
+	//
+	// This field can be nil to use the AssumeValid block of ChainParams.
+	AssumeValid *chainhash.Hash
+
 	// TimeSource defines the median time source to use for things such as
 	// block processing and determining whether or not the chain is current.
 	//
@@ -2170,6 +2206,14 @@ func New(config *Config) (*BlockChain, error) {
 	}
 
 	params := config.ChainParams
+	assumeValid := params.AssumeValid
+	if config.AssumeValid != nil {
+		assumeValid = config.AssumeValid
+	}
+	if assumeValid != nil && *assumeValid == zeroHash {
+		assumeValid = nil
+	}
+
 	targetTimespan := int64(params.TargetTimespan / time.Second)
 	targetTimePerBlock := int64(params.TargetTimePerBlock / time.Second)
 	adjustmentFactor := params.RetargetAdjustmentFactor
@@ -2181,6 +2225,8 @@ func New(config *Config) (*BlockChain, error) {
 		timeSource:          config.TimeSource,
 		sigCache:            config.SigCache,
 		indexManager:        config.IndexManager,
+		interrupt:           config.Interrupt,
+		assumeValid:         assumeValid,
 		minRetargetTimespan: targetTimespan / adjustmentFactor,
 		maxRetargetTimespan: targetTimespan * adjustmentFactor,
 		blocksPerRetarget:   int32(targetTimespan / targetTimePerBlock),
@@ -2245,6 +2291,12 @@ func New(config *Config) (*BlockChain, error) {
 		bestNode.height, bestNode.hash, b.stateSnapshot.TotalTxns,
 		bestNode.workSum)
 
+	// Resume the background validation of the chain before the base of a
+	// loaded UTXO set snapshot.
+	if b.snapshot != nil && b.snapshot.status == snapshotValidating {
+		go b.validateSnapshotChain()
+	}
+
 	return &b, nil
 }
 
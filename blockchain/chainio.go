@@ -71,6 +71,20 @@ var (
 	// unspent transaction output set.
 	utxoSetBucketName = []byte("utxosetv2")
 
+	// snapshotUtxoSetBucketName is the name of the db bucket used to house
+	// the unspent transaction output set once it was loaded from a UTXO set
+	// snapshot.
+	snapshotUtxoSetBucketName = []byte("utxosetsnapshot")
+
+	// snapshotStateKeyName is the name of the db key used to store the
+	// state of a loaded UTXO set snapshot.
+	snapshotStateKeyName = []byte("utxosnapshotstate")
+
+	// bgUtxoStateConsistencyKeyName is the name of the db key used to store
+	// the consistency status of the utxo state used to validate the chain
+	// before the base of a loaded UTXO set snapshot in the background.
+	bgUtxoStateConsistencyKeyName = []byte("bgutxostateconsistency")
+
 	// byteOrder is the preferred byte order used for serializing numeric
 	// fields for storage in the database.
 	byteOrder = binary.LittleEndian
@@ -716,12 +730,14 @@ func deserializeUtxoEntry(serialized []byte) (*UtxoEntry, error) {
 //
 // When there are no entries for the provided hash, nil will be returned for the
 // both the entry and the error.
-func dbFetchUtxoEntryByHash(dbTx database.Tx, hash *chainhash.Hash) (*UtxoEntry, error) {
+func dbFetchUtxoEntryByHash(dbTx database.Tx, utxoBucket database.Bucket,
+	hash *chainhash.Hash) (*UtxoEntry, error) {
+
 	// Attempt to find an entry by seeking for the hash along with a zero
 	// index.  Due to the fact the keys are serialized as <hash><index>,
 	// where the index uses an MSB encoding, if there are any entries for
 	// the hash at all, one will be found.
-	cursor := dbTx.Metadata().Bucket(utxoSetBucketName).Cursor()
+	cursor := utxoBucket.Cursor()
 	key := outpointKey(wire.OutPoint{Hash: *hash, Index: 0})
 	ok := cursor.Seek(*key)
 	recycleOutpointKey(key)
@@ -790,13 +806,14 @@ func dbFetchUtxoEntry(dbTx database.Tx, utxoBucket database.Bucket,
 // in the database based on the provided utxo view contents and state.  In
 // particular, only the entries that have been marked as modified are written
 // to the database.
-func dbPutUtxoView(dbTx database.Tx, view *UtxoViewpoint) error {
+func dbPutUtxoView(dbTx database.Tx, utxoBucket database.Bucket,
+	view *UtxoViewpoint) error {
+
 	// Return early if the view is nil.
 	if view == nil {
 		return nil
 	}
 
-	utxoBucket := dbTx.Metadata().Bucket(utxoSetBucketName)
 	for outpoint, entry := range view.entries {
 		// No need to update the database if the entry was not modified.
 		if entry == nil || !entry.isModified() {
@@ -1270,23 +1287,44 @@ func (b *BlockChain) initChainState() error {
 		}
 		b.bestChain.SetTip(tip)
 
-		// Load the raw block bytes for the best block.
-		blockBytes, err := dbTx.FetchBlock(&state.hash)
+		// Load the state of the UTXO set snapshot the chain state was
+		// loaded from, if any.
+		err = b.initSnapshotChainstate(dbTx)
 		if err != nil {
 			return err
 		}
+
+		// Load the raw block bytes for the best block.  Only the header
+		// of the base block of a UTXO set snapshot is known right after
+		// loading the snapshot.
 		var block wire.MsgBlock
-		err = block.Deserialize(bytes.NewReader(blockBytes))
-		if err != nil {
-			return err
+		var blockBytes []byte
+		if tip.status.HaveData() {
+			blockBytes, err = dbTx.FetchBlock(&state.hash)
+			if err != nil {
+				return err
+			}
+			err = block.Deserialize(bytes.NewReader(blockBytes))
+			if err != nil {
+				return err
+			}
 		}
 
 		// As a final consistency check, we'll run through all the
 		// nodes which are ancestors of the current chain tip, and mark
 		// them as valid if they aren't already marked as such.  This
 		// is a safe assumption as all the block before the current tip
-		// are valid by definition.
+		// are valid by definition, except for the blocks before the
+		// base of a UTXO set snapshot which are validated in the
+		// background.
 		for iterNode := tip; iterNode != nil; iterNode = iterNode.parent {
+			if b.snapshot != nil &&
+				b.snapshot.status != snapshotValidated &&
+				iterNode == b.snapshot.baseNode {
+
+				break
+			}
+
 			// If this isn't already marked as valid in the index, then
 			// we'll mark it as valid now to ensure consistency once
 			// we're up and running.
@@ -0,0 +1,217 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockchain
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/bynil/btcd/blockchain/internal/muhash"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	"github.com/bynil/btcd/database"
+	"github.com/bynil/btcd/wire"
+)
+
+// UtxoSetHashType identifies the hash of the UTXO set computed along with its
+// statistics.
+type UtxoSetHashType int
+
+const (
+	// UtxoSetHashNone indicates no hash of the UTXO set is computed.
+	UtxoSetHashNone UtxoSetHashType = iota
+
+	// UtxoSetHashSerialized indicates the hash_serialized_3 hash of the
+	// UTXO set is computed.  It is the double SHA256 hash of the coins
+	// serialized with SerializeUtxoForHash in the order of their outpoints.
+	UtxoSetHashSerialized
+
+	// UtxoSetHashMuHash indicates the MuHash3072 hash of the set of coins
+	// serialized with SerializeUtxoForHash is computed.
+	UtxoSetHashMuHash
+)
+
+// String returns the UtxoSetHashType as the name used by the gettxoutsetinfo
+// RPC.
+func (t UtxoSetHashType) String() string {
+	switch t {
+	case UtxoSetHashNone:
+		return "none"
+	case UtxoSetHashSerialized:
+		return "hash_serialized_3"
+	case UtxoSetHashMuHash:
+		return "muhash"
+	}
+
+	return fmt.Sprintf("Unknown UtxoSetHashType (%d)", int(t))
+}
+
+// UtxoSetStats houses the statistics of the UTXO set at a block.
+type UtxoSetStats struct {
+	// Hash and Height identify the block the statistics are for.
+	Hash   chainhash.Hash
+	Height int32
+
+	// Transactions is the number of transactions with unspent outputs.
+	Transactions int64
+
+	// TxOuts is the number of unspent outputs.
+	TxOuts int64
+
+	// BogoSize is a database independent metric for the size of the UTXO
+	// set, see UtxoBogoSize.
+	BogoSize int64
+
+	// TotalAmount is the total amount of the unspent outputs in satoshi.
+	TotalAmount int64
+
+	// HashSerialized is the hash_serialized_3 hash of the UTXO set when it
+	// was requested.
+	HashSerialized chainhash.Hash
+
+	// MuHash is the MuHash3072 hash of the UTXO set when it was requested.
+	MuHash chainhash.Hash
+}
+
+// UtxoBogoSize returns the contribution of an unspent output with the passed
+// public key script to the bogo size of the UTXO set.  It is the size of the
+// outpoint, height, coinbase flag, amount and script, which makes it
+// independent of how the UTXO set is stored.
+func UtxoBogoSize(pkScript []byte) int64 {
+	return chainhash.HashSize + 4 + 4 + 8 + 2 + int64(len(pkScript))
+}
+
+// writeUtxoForHash writes the serialization of the passed coin used by the
+// hashes of the UTXO set to the passed writer.  The buffer is used to encode
+// the numeric fields.
+func writeUtxoForHash(w io.Writer, buf *[8]byte, outpoint wire.OutPoint,
+	entry *UtxoEntry) {
+
+	w.Write(outpoint.Hash[:])
+	binary.LittleEndian.PutUint32(buf[:4], outpoint.Index)
+	w.Write(buf[:4])
+
+	code := uint32(entry.BlockHeight()) << 1
+	if entry.IsCoinBase() {
+		code |= 0x01
+	}
+	binary.LittleEndian.PutUint32(buf[:4], code)
+	w.Write(buf[:4])
+
+	binary.LittleEndian.PutUint64(buf[:], uint64(entry.Amount()))
+	w.Write(buf[:])
+	_ = wire.WriteVarBytes(w, 0, entry.PkScript())
+}
+
+// SerializeUtxoForHash returns the serialization of the passed coin used by the
+// hashes of the UTXO set.  It is the outpoint, followed by the block height
+// shifted left by one with the coinbase flag in the lowest bit, and the output.
+func SerializeUtxoForHash(outpoint wire.OutPoint, entry *UtxoEntry) []byte {
+	var w bytes.Buffer
+	var buf [8]byte
+	writeUtxoForHash(&w, &buf, outpoint, entry)
+	return w.Bytes()
+}
+
+// ForEachUtxo calls the passed function with each unspent output of the UTXO
+// set at the tip of the main chain, in the order of the hashes of their
+// transactions, and returns the tip.  The iteration stops when the function
+// returns an error, which is returned.  This requires iterating the whole UTXO
+// set, which takes a while, but the chain is not held up while doing so.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ForEachUtxo(fn func(outpoint wire.OutPoint,
+	entry *UtxoEntry) error) (*BestState, error) {
+
+	// Flush the utxo cache so the utxo set bucket contains the utxo set at
+	// the tip, and start a read-only transaction before the chain can be
+	// extended so the utxo set is read as of the tip.
+	b.chainLock.Lock()
+	bestState := b.BestSnapshot()
+	err := b.db.Update(func(dbTx database.Tx) error {
+		return b.utxoCache.flush(dbTx, FlushRequired, bestState)
+	})
+	if err != nil {
+		b.chainLock.Unlock()
+		return nil, err
+	}
+	dbTx, err := b.db.Begin(false)
+	bucketName := b.utxoCache.bucketName
+	b.chainLock.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	defer dbTx.Rollback()
+
+	cursor := dbTx.Metadata().Bucket(bucketName).Cursor()
+	for ok := cursor.First(); ok; ok = cursor.Next() {
+		outpoint, err := outpointFromKey(cursor.Key())
+		if err != nil {
+			return nil, err
+		}
+		entry, err := deserializeUtxoEntry(cursor.Value())
+		if err != nil {
+			return nil, err
+		}
+		if err := fn(outpoint, entry); err != nil {
+			return nil, err
+		}
+
+		if interruptRequested(b.interrupt) {
+			return nil, errInterruptRequested
+		}
+	}
+
+	return bestState, nil
+}
+
+// FetchUtxoSetStats returns the statistics of the UTXO set at the tip of the
+// main chain along with the requested hash of it.  This requires iterating the
+// whole UTXO set, which takes a while, but the chain is not held up while
+// doing so.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) FetchUtxoSetStats(hashType UtxoSetHashType) (*UtxoSetStats, error) {
+	stats := &UtxoSetStats{}
+	hasher := newUtxoSetHasher()
+	muHash := muhash.New()
+	var prevHash chainhash.Hash
+	bestState, err := b.ForEachUtxo(func(outpoint wire.OutPoint,
+		entry *UtxoEntry) error {
+
+		// The coins of a transaction are next to each other since the
+		// keys start with the transaction hash.
+		if stats.TxOuts == 0 || outpoint.Hash != prevHash {
+			stats.Transactions++
+			prevHash = outpoint.Hash
+		}
+		stats.TxOuts++
+		stats.BogoSize += UtxoBogoSize(entry.PkScript())
+		stats.TotalAmount += entry.Amount()
+
+		switch hashType {
+		case UtxoSetHashSerialized:
+			hasher.add(outpoint, entry)
+		case UtxoSetHashMuHash:
+			muHash.Add(SerializeUtxoForHash(outpoint, entry))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Hash = bestState.Hash
+	stats.Height = bestState.Height
+	switch hashType {
+	case UtxoSetHashSerialized:
+		stats.HashSerialized = hasher.sum()
+	case UtxoSetHashMuHash:
+		stats.MuHash = muHash.Finalize()
+	}
+
+	return stats, nil
+}
@@ -0,0 +1,413 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"fmt"
+
+	"github.com/bynil/btcd/blockchain"
+	"github.com/bynil/btcd/blockchain/internal/muhash"
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	"github.com/bynil/btcd/database"
+	"github.com/bynil/btcd/txscript"
+	"github.com/bynil/btcd/wire"
+)
+
+const (
+	// coinStatsIndexName is the human-readable name for the index.
+	coinStatsIndexName = "coin statistics index"
+
+	// coinStatsEntrySize is the size of a coin statistics index entry.
+	// It consists of the height of the block, the number of unspent
+	// outputs, their bogo size and total amount, and the MuHash of the
+	// UTXO set.
+	coinStatsEntrySize = 4 + 8 + 8 + 8 + chainhash.HashSize
+)
+
+var (
+	// coinStatsIndexKey is the key of the coin statistics index and the db
+	// bucket used to house it.
+	coinStatsIndexKey = []byte("coinstatsbyhashidx")
+
+	// coinStatsMuHashKey is the key of the MuHash state of the UTXO set at
+	// the tip of the index in the coin statistics index bucket.  It is
+	// shorter than the block hash keys of the entries, so it can't collide
+	// with them.
+	coinStatsMuHashKey = []byte("muhash")
+
+	// bip30UnspendableBlocks are the blocks with coinbase transactions
+	// whose outputs were overwritten by later coinbase transactions with
+	// the same hash before BIP0030 was activated.  Their outputs are not
+	// part of the UTXO set.
+	bip30UnspendableBlocks = map[int32]string{
+		91722: "00000000000271a2dc26e7667f8419f2e15416dc6955e5a6c6cdf3f2574dd08e",
+		91812: "00000000000af0aed4792b1acee3d966af36cf5def14935db8de83d6f9306f2f",
+	}
+)
+
+// -----------------------------------------------------------------------------
+// The coin statistics index consists of an entry for every block in the main
+// chain which records the statistics of the UTXO set after the block is
+// connected, along with the MuHash state of the UTXO set at the tip of the
+// index which is updated as blocks are connected and disconnected.
+//
+// The serialized format for keys and values in the index bucket is:
+//
+//   <block hash> = <height><txouts><bogo size><total amount><muhash>
+//
+//   Field           Type              Size
+//   block hash      chainhash.Hash    32
+//   height          uint32            4
+//   txouts          uint64            8
+//   bogo size       uint64            8
+//   total amount    uint64            8
+//   muhash          chainhash.Hash    32
+//   -----
+//   Total: 60 bytes
+//
+//   muhash = <MuHash state>
+//
+//   Field           Type              Size
+//   MuHash state    []byte            muhash.SerializeSize
+// -----------------------------------------------------------------------------
+
+// serializeCoinStats returns the serialized coin statistics index entry for the
+// passed statistics.
+func serializeCoinStats(stats *blockchain.UtxoSetStats) []byte {
+	serialized := make([]byte, coinStatsEntrySize)
+	byteOrder.PutUint32(serialized[0:4], uint32(stats.Height))
+	byteOrder.PutUint64(serialized[4:12], uint64(stats.TxOuts))
+	byteOrder.PutUint64(serialized[12:20], uint64(stats.BogoSize))
+	byteOrder.PutUint64(serialized[20:28], uint64(stats.TotalAmount))
+	copy(serialized[28:], stats.MuHash[:])
+	return serialized
+}
+
+// deserializeCoinStats decodes the passed serialized coin statistics index entry
+// of the block with the passed hash.
+func deserializeCoinStats(hash *chainhash.Hash,
+	serialized []byte) (*blockchain.UtxoSetStats, error) {
+
+	if len(serialized) != coinStatsEntrySize {
+		return nil, errDeserialize(fmt.Sprintf("unexpected coin "+
+			"statistics entry size %d for block %v",
+			len(serialized), hash))
+	}
+
+	stats := &blockchain.UtxoSetStats{
+		Hash:        *hash,
+		Height:      int32(byteOrder.Uint32(serialized[0:4])),
+		TxOuts:      int64(byteOrder.Uint64(serialized[4:12])),
+		BogoSize:    int64(byteOrder.Uint64(serialized[12:20])),
+		TotalAmount: int64(byteOrder.Uint64(serialized[20:28])),
+	}
+	copy(stats.MuHash[:], serialized[28:])
+	return stats, nil
+}
+
+// dbFetchCoinStats fetches the coin statistics index entry of the block with the
+// passed hash.
+func dbFetchCoinStats(bucket internalBucket,
+	hash *chainhash.Hash) (*blockchain.UtxoSetStats, error) {
+
+	serialized := bucket.Get(hash[:])
+	if serialized == nil {
+		return nil, fmt.Errorf("no coin statistics for block %v", hash)
+	}
+
+	return deserializeCoinStats(hash, serialized)
+}
+
+// dbFetchCoinStatsMuHash fetches the MuHash state of the UTXO set at the tip of
+// the coin statistics index.
+func dbFetchCoinStatsMuHash(bucket internalBucket) (*muhash.MuHash, error) {
+	serialized := bucket.Get(coinStatsMuHashKey)
+	if serialized == nil {
+		return nil, AssertError("missing coin statistics MuHash state")
+	}
+
+	state, err := muhash.Deserialize(serialized)
+	if err != nil {
+		return nil, errDeserialize(err.Error())
+	}
+	return state, nil
+}
+
+// isBIP30Unspendable returns whether the outputs of the coinbase transaction of
+// the passed block are not part of the UTXO set since they were overwritten by
+// a later coinbase transaction with the same hash.
+func isBIP30Unspendable(block *btcutil.Block) bool {
+	hash, ok := bip30UnspendableBlocks[block.Height()]
+	return ok && block.Hash().String() == hash
+}
+
+// updateCoinStats updates the passed statistics and MuHash state of the UTXO set
+// with the outputs created and spent by the passed block.  The changes are
+// reverted instead when disconnect is set.
+func updateCoinStats(stats *blockchain.UtxoSetStats, state *muhash.MuHash,
+	block *btcutil.Block, stxos []blockchain.SpentTxOut,
+	disconnect bool) error {
+
+	// update adds the passed coin to the UTXO set, or removes it when
+	// remove is set.
+	update := func(outpoint wire.OutPoint, entry *blockchain.UtxoEntry,
+		remove bool) {
+
+		serialized := blockchain.SerializeUtxoForHash(outpoint, entry)
+		sign := int64(1)
+		if remove {
+			state.Remove(serialized)
+			sign = -1
+		} else {
+			state.Add(serialized)
+		}
+		stats.TxOuts += sign
+		stats.BogoSize += sign * blockchain.UtxoBogoSize(entry.PkScript())
+		stats.TotalAmount += sign * entry.Amount()
+	}
+
+	var stxoIndex int
+	for txIdx, tx := range block.Transactions() {
+		isCoinBase := txIdx == 0
+		if !isCoinBase {
+			for _, txIn := range tx.MsgTx().TxIn {
+				if stxoIndex >= len(stxos) {
+					return AssertError(fmt.Sprintf("missing "+
+						"spent outputs of block %v",
+						block.Hash()))
+				}
+				stxo := &stxos[stxoIndex]
+				stxoIndex++
+
+				txOut := wire.NewTxOut(stxo.Amount, stxo.PkScript)
+				entry := blockchain.NewUtxoEntry(
+					txOut, stxo.Height, stxo.IsCoinBase,
+				)
+				update(txIn.PreviousOutPoint, entry, !disconnect)
+			}
+		}
+
+		if isCoinBase && isBIP30Unspendable(block) {
+			continue
+		}
+		for i, txOut := range tx.MsgTx().TxOut {
+			// Unspendable outputs are not part of the UTXO set.
+			if txscript.IsUnspendable(txOut.PkScript) {
+				continue
+			}
+
+			outpoint := wire.OutPoint{Hash: *tx.Hash(), Index: uint32(i)}
+			entry := blockchain.NewUtxoEntry(
+				txOut, block.Height(), isCoinBase,
+			)
+			update(outpoint, entry, disconnect)
+		}
+	}
+	if stxoIndex != len(stxos) {
+		return AssertError(fmt.Sprintf("got %d spent outputs for block "+
+			"%v, want %d", len(stxos), block.Hash(), stxoIndex))
+	}
+
+	return nil
+}
+
+// dbConnectCoinStats adds the coin statistics index entry of the passed block
+// and updates the MuHash state of the UTXO set to include the block.
+func dbConnectCoinStats(bucket internalBucket, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	state, err := dbFetchCoinStatsMuHash(bucket)
+	if err != nil {
+		return err
+	}
+
+	// The outputs of the genesis block are not part of the UTXO set, so it
+	// has empty statistics.
+	stats := &blockchain.UtxoSetStats{}
+	if block.Height() != 0 {
+		prevHash := &block.MsgBlock().Header.PrevBlock
+		stats, err = dbFetchCoinStats(bucket, prevHash)
+		if err != nil {
+			return err
+		}
+		err = updateCoinStats(stats, state, block, stxos, false)
+		if err != nil {
+			return err
+		}
+	}
+	stats.Hash = *block.Hash()
+	stats.Height = block.Height()
+	stats.MuHash = state.Finalize()
+
+	if err := bucket.Put(coinStatsMuHashKey, state.Serialize()); err != nil {
+		return err
+	}
+	return bucket.Put(block.Hash()[:], serializeCoinStats(stats))
+}
+
+// dbDisconnectCoinStats removes the coin statistics index entry of the passed
+// block and updates the MuHash state of the UTXO set to exclude the block.
+func dbDisconnectCoinStats(bucket internalBucket, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	state, err := dbFetchCoinStatsMuHash(bucket)
+	if err != nil {
+		return err
+	}
+	stats, err := dbFetchCoinStats(bucket, block.Hash())
+	if err != nil {
+		return err
+	}
+	err = updateCoinStats(stats, state, block, stxos, true)
+	if err != nil {
+		return err
+	}
+
+	// The resulting UTXO set must match the one recorded for the previous
+	// block.
+	prevHash := &block.MsgBlock().Header.PrevBlock
+	prevStats, err := dbFetchCoinStats(bucket, prevHash)
+	if err != nil {
+		return err
+	}
+	if muHash := state.Finalize(); muHash != prevStats.MuHash {
+		return AssertError(fmt.Sprintf("MuHash %v after disconnecting "+
+			"block %v does not match the MuHash %v of block %v",
+			muHash, block.Hash(), prevStats.MuHash, prevHash))
+	}
+
+	if err := bucket.Put(coinStatsMuHashKey, state.Serialize()); err != nil {
+		return err
+	}
+	return bucket.Delete(block.Hash()[:])
+}
+
+// CoinStatsIndex implements an index of the statistics of the UTXO set, along
+// with its MuHash, after every block in the main chain.
+type CoinStatsIndex struct {
+	db database.DB
+}
+
+// Ensure the CoinStatsIndex type implements the Indexer interface.
+var _ Indexer = (*CoinStatsIndex)(nil)
+
+// Ensure the CoinStatsIndex type implements the NeedsInputser interface.
+var _ NeedsInputser = (*CoinStatsIndex)(nil)
+
+// NeedsInputs signals that the index requires the referenced inputs in order
+// to properly create the index.
+//
+// This implements the NeedsInputser interface.
+func (idx *CoinStatsIndex) NeedsInputs() bool {
+	return true
+}
+
+// Init initializes the coin statistics index.
+//
+// This is part of the Indexer interface.
+func (idx *CoinStatsIndex) Init() error {
+	return nil // Nothing to do.
+}
+
+// Key returns the database key to use for the index as a byte slice.
+//
+// This is part of the Indexer interface.
+func (idx *CoinStatsIndex) Key() []byte {
+	return coinStatsIndexKey
+}
+
+// Name returns the human-readable name of the index.
+//
+// This is part of the Indexer interface.
+func (idx *CoinStatsIndex) Name() string {
+	return coinStatsIndexName
+}
+
+// Create is invoked when the indexer manager determines the index needs to be
+// created for the first time.  It creates the bucket for the index along with
+// the MuHash state of the empty UTXO set.
+//
+// This is part of the Indexer interface.
+func (idx *CoinStatsIndex) Create(dbTx database.Tx) error {
+	bucket, err := dbTx.Metadata().CreateBucket(coinStatsIndexKey)
+	if err != nil {
+		return err
+	}
+	return bucket.Put(coinStatsMuHashKey, muhash.New().Serialize())
+}
+
+// ConnectBlock is invoked by the index manager when a new block has been
+// connected to the main chain.  This indexer adds the statistics of the UTXO
+// set after the block.
+//
+// This is part of the Indexer interface.
+func (idx *CoinStatsIndex) ConnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	bucket := dbTx.Metadata().Bucket(coinStatsIndexKey)
+	return dbConnectCoinStats(bucket, block, stxos)
+}
+
+// DisconnectBlock is invoked by the index manager when a block has been
+// disconnected from the main chain.  This indexer removes the statistics of the
+// UTXO set after the block.
+//
+// This is part of the Indexer interface.
+func (idx *CoinStatsIndex) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
+	stxos []blockchain.SpentTxOut) error {
+
+	bucket := dbTx.Metadata().Bucket(coinStatsIndexKey)
+	return dbDisconnectCoinStats(bucket, block, stxos)
+}
+
+// CoinStatsByBlockHash returns the statistics of the UTXO set after the main
+// chain block with the passed hash.  Only the MuHash of the UTXO set is
+// available, and the number of transactions with unspent outputs is not
+// tracked.  An error is returned when the block is not indexed.
+//
+// This function is safe for concurrent access.
+func (idx *CoinStatsIndex) CoinStatsByBlockHash(
+	hash *chainhash.Hash) (*blockchain.UtxoSetStats, error) {
+
+	var stats *blockchain.UtxoSetStats
+	err := idx.db.View(func(dbTx database.Tx) error {
+		bucket := dbTx.Metadata().Bucket(coinStatsIndexKey)
+		var err error
+		stats, err = dbFetchCoinStats(bucket, hash)
+		return err
+	})
+	return stats, err
+}
+
+// NewCoinStatsIndex returns a new instance of an indexer that is used to keep
+// the statistics of the UTXO set after every block in the main chain, so they
+// can be queried at any height without iterating the UTXO set.
+//
+// It implements the Indexer interface which plugs into the IndexManager that in
+// turn is used by the blockchain package.  This allows the index to be
+// seamlessly maintained along with the chain.
+func NewCoinStatsIndex(db database.DB) *CoinStatsIndex {
+	return &CoinStatsIndex{db: db}
+}
+
+// DropCoinStatsIndex drops the coin statistics index from the provided database
+// if it exists.
+func DropCoinStatsIndex(db database.DB, interrupt <-chan struct{}) error {
+	return dropIndex(db, coinStatsIndexKey, coinStatsIndexName, interrupt)
+}
+
+// CoinStatsIndexInitialized returns true if the coin statistics index has been
+// created previously.
+func CoinStatsIndexInitialized(db database.DB) bool {
+	var exists bool
+	db.View(func(dbTx database.Tx) error {
+		bucket := dbTx.Metadata().Bucket(coinStatsIndexKey)
+		exists = bucket != nil
+		return nil
+	})
+
+	return exists
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package indexers
+
+import (
+	"testing"
+
+	"github.com/bynil/btcd/blockchain"
+	"github.com/bynil/btcd/blockchain/internal/muhash"
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg"
+	"github.com/bynil/btcd/wire"
+)
+
+// coinStatsBucket provides a mock coin statistics index database bucket by
+// implementing the internalBucket interface.
+type coinStatsBucket map[string][]byte
+
+// Get returns the value associated with the key from the mock bucket.
+//
+// This is part of the internalBucket interface.
+func (b coinStatsBucket) Get(key []byte) []byte {
+	return b[string(key)]
+}
+
+// Put stores the provided key/value pair to the mock bucket.
+//
+// This is part of the internalBucket interface.
+func (b coinStatsBucket) Put(key []byte, value []byte) error {
+	b[string(key)] = value
+	return nil
+}
+
+// Delete removes the provided key from the mock bucket.
+//
+// This is part of the internalBucket interface.
+func (b coinStatsBucket) Delete(key []byte) error {
+	delete(b, string(key))
+	return nil
+}
+
+// TestCoinStatsIndex ensures the coin statistics index tracks the UTXO set as
+// blocks are connected and disconnected.
+func TestCoinStatsIndex(t *testing.T) {
+	t.Parallel()
+
+	p2pkh := []byte{
+		0x76, 0xa9, 0x14, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+		0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10, 0x11,
+		0x12, 0x13, 0x14, 0x88, 0xac,
+	}
+	nullData := []byte{0x6a, 0x01, 0x01}
+
+	// newBlock returns a block at the passed height on top of the passed
+	// block with the passed transactions after a coinbase transaction
+	// paying to the passed outputs.
+	newBlock := func(prev *btcutil.Block, height int32,
+		coinbaseOuts []*wire.TxOut, txns ...*wire.MsgTx) *btcutil.Block {
+
+		coinbase := wire.NewMsgTx(1)
+		coinbase.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+			SignatureScript:  []byte{0x01, byte(height)},
+		})
+		coinbase.TxOut = coinbaseOuts
+
+		msgBlock := &wire.MsgBlock{
+			Header: wire.BlockHeader{PrevBlock: *prev.Hash()},
+		}
+		msgBlock.AddTransaction(coinbase)
+		for _, tx := range txns {
+			msgBlock.AddTransaction(tx)
+		}
+		block := btcutil.NewBlock(msgBlock)
+		block.SetHeight(height)
+		return block
+	}
+
+	genesis := btcutil.NewBlock(chaincfg.RegressionNetParams.GenesisBlock)
+	genesis.SetHeight(0)
+	block1 := newBlock(genesis, 1, []*wire.TxOut{
+		wire.NewTxOut(5000, p2pkh), wire.NewTxOut(0, nullData),
+	})
+	coinbase1 := block1.Transactions()[0]
+
+	spend := wire.NewMsgTx(1)
+	spend.AddTxIn(wire.NewTxIn(
+		&wire.OutPoint{Hash: *coinbase1.Hash(), Index: 0}, nil, nil,
+	))
+	spend.AddTxOut(wire.NewTxOut(4000, p2pkh))
+	block2 := newBlock(block1, 2, []*wire.TxOut{
+		wire.NewTxOut(1000, p2pkh),
+	}, spend)
+	stxos2 := []blockchain.SpentTxOut{{
+		Amount:     5000,
+		PkScript:   p2pkh,
+		Height:     1,
+		IsCoinBase: true,
+	}}
+
+	bucket := coinStatsBucket{}
+	bucket.Put(coinStatsMuHashKey, muhash.New().Serialize())
+
+	// expectStats ensures the index entry of the passed block has the
+	// passed statistics and the MuHash of the passed coins.
+	expectStats := func(block *btcutil.Block, txOuts, totalAmount int64,
+		coins map[wire.OutPoint]*blockchain.UtxoEntry) {
+
+		t.Helper()
+
+		stats, err := dbFetchCoinStats(bucket, block.Hash())
+		if err != nil {
+			t.Fatalf("dbFetchCoinStats: unexpected error: %v", err)
+		}
+		wantMuHash := muhash.New()
+		var bogoSize int64
+		for outpoint, entry := range coins {
+			wantMuHash.Add(blockchain.SerializeUtxoForHash(
+				outpoint, entry,
+			))
+			bogoSize += blockchain.UtxoBogoSize(entry.PkScript())
+		}
+		if stats.Height != block.Height() || stats.TxOuts != txOuts ||
+			stats.TotalAmount != totalAmount ||
+			stats.BogoSize != bogoSize {
+
+			t.Fatalf("got stats %+v at height %d, want %d txouts "+
+				"with total amount %d and bogo size %d", stats,
+				block.Height(), txOuts, totalAmount, bogoSize)
+		}
+		if want := wantMuHash.Finalize(); stats.MuHash != want {
+			t.Fatalf("got MuHash %v at height %d, want %v",
+				stats.MuHash, block.Height(), want)
+		}
+	}
+
+	if err := dbConnectCoinStats(bucket, genesis, nil); err != nil {
+		t.Fatalf("dbConnectCoinStats: unexpected error: %v", err)
+	}
+	expectStats(genesis, 0, 0, nil)
+
+	// The unspendable output of the coinbase is not part of the UTXO set.
+	if err := dbConnectCoinStats(bucket, block1, nil); err != nil {
+		t.Fatalf("dbConnectCoinStats: unexpected error: %v", err)
+	}
+	coins1 := map[wire.OutPoint]*blockchain.UtxoEntry{
+		{Hash: *coinbase1.Hash(), Index: 0}: blockchain.NewUtxoEntry(
+			wire.NewTxOut(5000, p2pkh), 1, true,
+		),
+	}
+	expectStats(block1, 1, 5000, coins1)
+
+	// The output spent by the second block is removed from the UTXO set.
+	if err := dbConnectCoinStats(bucket, block2, nil); err == nil {
+		t.Fatal("block connected without its spent outputs")
+	}
+	if err := dbConnectCoinStats(bucket, block2, stxos2); err != nil {
+		t.Fatalf("dbConnectCoinStats: unexpected error: %v", err)
+	}
+	coinbase2 := block2.Transactions()[0]
+	expectStats(block2, 2, 5000, map[wire.OutPoint]*blockchain.UtxoEntry{
+		{Hash: *coinbase2.Hash(), Index: 0}: blockchain.NewUtxoEntry(
+			wire.NewTxOut(1000, p2pkh), 2, true,
+		),
+		{Hash: spend.TxHash(), Index: 0}: blockchain.NewUtxoEntry(
+			wire.NewTxOut(4000, p2pkh), 2, false,
+		),
+	})
+
+	// Disconnecting the second block restores the UTXO set of the first
+	// block, so connecting another block on top of it starts from there.
+	if err := dbDisconnectCoinStats(bucket, block2, stxos2); err != nil {
+		t.Fatalf("dbDisconnectCoinStats: unexpected error: %v", err)
+	}
+	if bucket.Get(block2.Hash()[:]) != nil {
+		t.Fatal("entry of the disconnected block not removed")
+	}
+	block2b := newBlock(block1, 2, []*wire.TxOut{
+		wire.NewTxOut(2000, p2pkh),
+	})
+	if err := dbConnectCoinStats(bucket, block2b, nil); err != nil {
+		t.Fatalf("dbConnectCoinStats: unexpected error: %v", err)
+	}
+	coins1[wire.OutPoint{Hash: *block2b.Transactions()[0].Hash()}] =
+		blockchain.NewUtxoEntry(wire.NewTxOut(2000, p2pkh), 2, true)
+	expectStats(block2b, 2, 7000, coins1)
+}
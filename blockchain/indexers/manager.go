@@ -533,6 +533,59 @@ func (m *Manager) DisconnectBlock(dbTx database.Tx, block *btcutil.Block,
 	return nil
 }
 
+// IndexTip identifies the block an enabled index is synced to.
+type IndexTip struct {
+	// Name is the name the getindexinfo RPC reports the index under.  It
+	// matches the name Bitcoin Core uses for the equivalent index.
+	Name   string
+	Hash   chainhash.Hash
+	Height int32
+}
+
+// rpcIndexName returns the name the getindexinfo RPC reports the provided
+// index under.  The indexes Bitcoin Core also provides use the names it reports
+// for them so clients can query either implementation, and any other index
+// falls back to its human-readable name.
+func rpcIndexName(indexer Indexer) string {
+	switch indexer.(type) {
+	case *TxIndex:
+		return "txindex"
+	case *CfIndex:
+		return "basic block filter index"
+	case *CoinStatsIndex:
+		return "coinstatsindex"
+	case *AddrIndex:
+		// Bitcoin Core has no address index, so use the name of the
+		// option which enables it.
+		return "addrindex"
+	}
+	return indexer.Name()
+}
+
+// IndexTips returns the current tip of each of the enabled indexes in the order
+// they were enabled.  The height of an index which does not have any entries
+// yet is -1.
+//
+// This function is safe for concurrent access.
+func (m *Manager) IndexTips() ([]IndexTip, error) {
+	tips := make([]IndexTip, 0, len(m.enabledIndexes))
+	err := m.db.View(func(dbTx database.Tx) error {
+		for _, indexer := range m.enabledIndexes {
+			hash, height, err := dbFetchIndexerTip(dbTx, indexer.Key())
+			if err != nil {
+				return err
+			}
+			tips = append(tips, IndexTip{
+				Name:   rpcIndexName(indexer),
+				Hash:   *hash,
+				Height: height,
+			})
+		}
+		return nil
+	})
+	return tips, err
+}
+
 // NewManager returns a new index manager with the provided indexes enabled.
 //
 // The manager returned satisfies the blockchain.IndexManager interface and thus
@@ -0,0 +1,155 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package muhash implements the MuHash3072 rolling set hash used by the coin
+statistics of Bitcoin Core.
+
+Each element is hashed to a number modulo the prime 2^3072 - 1103717 by
+expanding its SHA256 hash with ChaCha20.  The hash of a set is the product of
+the numbers of its elements, so elements can be added and removed in any order
+and the hash only depends on the resulting set.  Removing an element multiplies
+a separate denominator which is only divided out when the hash is finalized,
+since computing the inverse is expensive.
+*/
+package muhash
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	"golang.org/x/crypto/chacha20"
+)
+
+const (
+	// numBytes is the size of a serialized number modulo the prime.
+	numBytes = 384
+
+	// SerializeSize is the size of a serialized MuHash.
+	SerializeSize = 2 * numBytes
+)
+
+// prime is the modulus 2^3072 - 1103717 of the numbers the elements are
+// hashed to.
+var prime = func() *big.Int {
+	p := new(big.Int).Lsh(big.NewInt(1), 8*numBytes)
+	return p.Sub(p, big.NewInt(1103717))
+}()
+
+// MuHash is a MuHash3072 hash of a set of elements.  The zero value is not
+// usable; use New or Deserialize.
+type MuHash struct {
+	numerator   *big.Int
+	denominator *big.Int
+}
+
+// New returns the hash of the empty set.
+func New() *MuHash {
+	return &MuHash{
+		numerator:   big.NewInt(1),
+		denominator: big.NewInt(1),
+	}
+}
+
+// reverse reverses the passed bytes in place, converting between the little
+// endian serialization of numbers and the big endian one used by big.Int.
+func reverse(b []byte) []byte {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+	return b
+}
+
+// toNum hashes the passed element to a number modulo the prime.
+func toNum(data []byte) *big.Int {
+	key := sha256.Sum256(data)
+	var nonce [chacha20.NonceSize]byte
+	cipher, err := chacha20.NewUnauthenticatedCipher(key[:], nonce[:])
+	if err != nil {
+		// The key and nonce are always of the correct size.
+		panic(err)
+	}
+	var stream [numBytes]byte
+	cipher.XORKeyStream(stream[:], stream[:])
+
+	return new(big.Int).SetBytes(reverse(stream[:]))
+}
+
+// Add adds the passed element to the set.
+func (h *MuHash) Add(data []byte) {
+	h.numerator.Mul(h.numerator, toNum(data))
+	h.numerator.Mod(h.numerator, prime)
+}
+
+// Remove removes the passed element from the set.
+func (h *MuHash) Remove(data []byte) {
+	h.denominator.Mul(h.denominator, toNum(data))
+	h.denominator.Mod(h.denominator, prime)
+}
+
+// Combine adds the elements of the passed set to the set.
+func (h *MuHash) Combine(other *MuHash) {
+	h.numerator.Mul(h.numerator, other.numerator)
+	h.numerator.Mod(h.numerator, prime)
+	h.denominator.Mul(h.denominator, other.denominator)
+	h.denominator.Mod(h.denominator, prime)
+}
+
+// normalize divides the numerator by the denominator so the denominator is
+// one.
+func (h *MuHash) normalize() {
+	if h.denominator.Cmp(big.NewInt(1)) == 0 {
+		return
+	}
+
+	inverse := new(big.Int).ModInverse(h.denominator, prime)
+	h.numerator.Mul(h.numerator, inverse)
+	h.numerator.Mod(h.numerator, prime)
+	h.denominator.SetInt64(1)
+}
+
+// Finalize returns the hash of the set.  It is the SHA256 hash of the little
+// endian serialization of the product of the numbers of the elements.
+func (h *MuHash) Finalize() chainhash.Hash {
+	h.normalize()
+
+	var num [numBytes]byte
+	h.numerator.FillBytes(num[:])
+	return chainhash.Hash(sha256.Sum256(reverse(num[:])))
+}
+
+// Serialize returns the state of the hash, which is the little endian
+// serialization of its numerator followed by that of its denominator.
+func (h *MuHash) Serialize() []byte {
+	serialized := make([]byte, SerializeSize)
+	h.numerator.FillBytes(serialized[:numBytes])
+	h.denominator.FillBytes(serialized[numBytes:])
+	reverse(serialized[:numBytes])
+	reverse(serialized[numBytes:])
+
+	return serialized
+}
+
+// Deserialize returns the hash with the passed state as returned by Serialize.
+func Deserialize(serialized []byte) (*MuHash, error) {
+	if len(serialized) != SerializeSize {
+		return nil, errors.New("invalid serialized MuHash size")
+	}
+
+	b := make([]byte, SerializeSize)
+	copy(b, serialized)
+	h := &MuHash{
+		numerator:   new(big.Int).SetBytes(reverse(b[:numBytes])),
+		denominator: new(big.Int).SetBytes(reverse(b[numBytes:])),
+	}
+	if h.numerator.Cmp(prime) >= 0 || h.denominator.Cmp(prime) >= 0 ||
+		h.denominator.Sign() == 0 {
+
+		return nil, errors.New("invalid serialized MuHash")
+	}
+
+	return h, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package muhash
+
+import (
+	"testing"
+
+	"github.com/bynil/btcd/chaincfg/chainhash"
+)
+
+// element returns the 32 byte element with the passed first byte, as used by
+// the MuHash3072 test vectors of Bitcoin Core.
+func element(i byte) []byte {
+	e := make([]byte, 32)
+	e[0] = i
+	return e
+}
+
+// TestMuHash ensures the hash matches the Bitcoin Core test vector and only
+// depends on the resulting set.
+func TestMuHash(t *testing.T) {
+	want, err := chainhash.NewHashFromStr("10d312b100cbd32ada024a6646e40d" +
+		"3482fcff103668d2625f10002a607d5863")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := New()
+	h.Add(element(0))
+	h.Add(element(1))
+	h.Remove(element(2))
+	if got := h.Finalize(); got != *want {
+		t.Fatalf("got hash %v, want %v", got, want)
+	}
+
+	// Adding and removing elements in a different order, with extra
+	// elements that are removed again, results in the same hash.
+	h = New()
+	h.Remove(element(2))
+	h.Add(element(3))
+	h.Add(element(1))
+	h.Remove(element(3))
+	h.Add(element(0))
+	if got := h.Finalize(); got != *want {
+		t.Fatalf("got hash %v for a reordered set, want %v", got, want)
+	}
+
+	// Combining the hashes of two sets results in the hash of their union.
+	a, b := New(), New()
+	a.Add(element(0))
+	b.Add(element(1))
+	b.Remove(element(2))
+	a.Combine(b)
+	if got := a.Finalize(); got != *want {
+		t.Fatalf("got hash %v for combined sets, want %v", got, want)
+	}
+
+	// The state survives a serialization round trip, also before it is
+	// finalized.
+	h = New()
+	h.Add(element(0))
+	h.Remove(element(2))
+	restored, err := Deserialize(h.Serialize())
+	if err != nil {
+		t.Fatalf("Deserialize: unexpected error: %v", err)
+	}
+	restored.Add(element(1))
+	if got := restored.Finalize(); got != *want {
+		t.Fatalf("got hash %v after deserializing, want %v", got, want)
+	}
+
+	if _, err := Deserialize(make([]byte, SerializeSize)); err == nil {
+		t.Fatal("state with a zero denominator deserialized")
+	}
+}
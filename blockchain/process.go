@@ -11,6 +11,7 @@ import (
 	"github.com/bynil/btcd/btcutil"
 	"github.com/bynil/btcd/chaincfg/chainhash"
 	"github.com/bynil/btcd/database"
+	"github.com/bynil/btcd/wire"
 )
 
 // BehaviorFlags is a bitmask defining tweaks to the normal behavior when
@@ -39,8 +40,9 @@ const (
 // This function is safe for concurrent access.
 func (b *BlockChain) blockExists(hash *chainhash.Hash) (bool, error) {
 	// Check block index first (could be main chain or side chain blocks).
-	if b.index.HaveBlock(hash) {
-		return true, nil
+	// Only the header of the block is known when its data is not stored.
+	if node := b.index.LookupNode(hash); node != nil {
+		return b.index.NodeStatus(node).HaveData(), nil
 	}
 
 	// Check in the database.
@@ -210,12 +212,18 @@ func (b *BlockChain) ProcessBlock(block *btcutil.Block, flags BehaviorFlags) (bo
 		}
 	}
 
-	// Handle orphan blocks.
+	// Handle orphan blocks.  The blocks of the main chain before the base
+	// of a loaded UTXO set snapshot are downloaded in any order, so they
+	// are never orphans.
 	prevHash := &blockHeader.PrevBlock
 	prevHashExists, err := b.blockExists(prevHash)
 	if err != nil {
 		return false, false, err
 	}
+	if !prevHashExists {
+		prevNode := b.index.LookupNode(prevHash)
+		prevHashExists = prevNode != nil && b.bestChain.Contains(prevNode)
+	}
 	if !prevHashExists {
 		log.Infof("Adding orphan block %v with parent %v", blockHash, prevHash)
 		b.addOrphanBlock(block)
@@ -242,3 +250,28 @@ func (b *BlockChain) ProcessBlock(block *btcutil.Block, flags BehaviorFlags) (bo
 
 	return isMainChain, false, nil
 }
+
+// ProcessBlockHeader is the main workhorse for handling insertion of new block
+// headers into the block index.  It includes functionality such as rejecting
+// invalid headers and insertion into the block index.  The block of a header
+// is processed with ProcessBlock once it is downloaded.
+//
+// Knowing the headers of the main chain is required to load a UTXO set
+// snapshot, since the header of its base block must be known.
+//
+// This function is safe for concurrent access.
+func (b *BlockChain) ProcessBlockHeader(header *wire.BlockHeader,
+	flags BehaviorFlags) error {
+
+	b.chainLock.Lock()
+	defer b.chainLock.Unlock()
+
+	// Perform preliminary sanity checks on the header.
+	err := CheckBlockHeaderSanity(header, b.chainParams.PowLimit,
+		b.timeSource, flags)
+	if err != nil {
+		return err
+	}
+
+	return b.maybeAcceptBlockHeader(header, flags)
+}
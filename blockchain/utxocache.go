@@ -210,6 +210,13 @@ const (
 type utxoCache struct {
 	db database.DB
 
+	// bucketName is the name of the db bucket which houses the utxo set the
+	// cache is backed by, and stateKeyName is the name of the db key used to
+	// store the hash of the block the utxo set in the bucket is consistent
+	// with.
+	bucketName   []byte
+	stateKeyName []byte
+
 	// maxTotalMemoryUsage is the maximum memory usage in bytes that the state
 	// should contain in normal circumstances.
 	maxTotalMemoryUsage uint64
@@ -240,6 +247,8 @@ func newUtxoCache(db database.DB, maxTotalMemoryUsage uint64) *utxoCache {
 
 	return &utxoCache{
 		db:                  db,
+		bucketName:          utxoSetBucketName,
+		stateKeyName:        utxoStateConsistencyKeyName,
 		maxTotalMemoryUsage: maxTotalMemoryUsage,
 		cachedEntries: mapSlice{
 			maps:                []map[wire.OutPoint]*UtxoEntry{m},
@@ -297,7 +306,7 @@ func (s *utxoCache) fetchEntries(outpoints []wire.OutPoint) ([]*UtxoEntry, error
 	// Fetch the missing outpoints in the cache from the database.
 	dbEntries := make([]*UtxoEntry, len(missingOps))
 	err := s.db.View(func(dbTx database.Tx) error {
-		utxoBucket := dbTx.Metadata().Bucket(utxoSetBucketName)
+		utxoBucket := dbTx.Metadata().Bucket(s.bucketName)
 
 		for i := range missingOps {
 			entry, err := dbFetchUtxoEntry(dbTx, utxoBucket, missingOps[i])
@@ -503,7 +512,7 @@ func (s *utxoCache) writeCache(dbTx database.Tx, bestState *BestState) error {
 	// Update commits and flushes the cache to the database.
 	// NOTE: The database has its own cache which gets atomically written
 	// to leveldb.
-	utxoBucket := dbTx.Metadata().Bucket(utxoSetBucketName)
+	utxoBucket := dbTx.Metadata().Bucket(s.bucketName)
 	for i := range s.cachedEntries.maps {
 		for outpoint, entry := range s.cachedEntries.maps[i] {
 			switch {
@@ -533,7 +542,7 @@ func (s *utxoCache) writeCache(dbTx database.Tx, bestState *BestState) error {
 
 	// When done, store the best state hash in the database to indicate the state
 	// is consistent until that hash.
-	err := dbPutUtxoStateConsistency(dbTx, &bestState.Hash)
+	err := dbTx.Metadata().Put(s.stateKeyName, bestState.Hash[:])
 	if err != nil {
 		return err
 	}
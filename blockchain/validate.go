@@ -180,17 +180,32 @@ func IsFinalizedTransaction(tx *btcutil.Tx, blockHeight int32, blockTime time.Ti
 // two blocks that violate the BIP0030 rule which prevents transactions from
 // overwriting old ones.
 func isBIP0030Node(node *blockNode) bool {
-	if node.height == 91842 && node.hash.IsEqual(block91842Hash) {
+	return isBIP0030Block(node.height, &node.hash)
+}
+
+// isBIP0030Block returns whether or not the block with the passed height and
+// hash is one of the two blocks that violate the BIP0030 rule.
+func isBIP0030Block(height int32, hash *chainhash.Hash) bool {
+	if height == 91842 && hash.IsEqual(block91842Hash) {
 		return true
 	}
 
-	if node.height == 91880 && node.hash.IsEqual(block91880Hash) {
+	if height == 91880 && hash.IsEqual(block91880Hash) {
 		return true
 	}
 
 	return false
 }
 
+// IsBIP0030Block returns whether or not the passed block is one of the two
+// blocks that violate the BIP0030 rule which prevents transactions from
+// overwriting old ones.  The coinbases of these blocks duplicate earlier
+// coinbases, so their outputs did not add to the utxo set.  The height of the
+// block must be set.
+func IsBIP0030Block(block *btcutil.Block) bool {
+	return isBIP0030Block(block.Height(), block.Hash())
+}
+
 // CalcBlockSubsidy returns the subsidy amount a block at the provided height
 // should have. This is mainly used for determining how much the coinbase for
 // newly generated blocks awards as well as validating the coinbase for blocks
@@ -921,7 +936,9 @@ func (b *BlockChain) checkBlockContext(block *btcutil.Block, prevNode *blockNode
 // http://r6.ca/blog/20120206T005236Z.html.
 //
 // This function MUST be called with the chain state lock held (for reads).
-func (b *BlockChain) checkBIP0030(node *blockNode, block *btcutil.Block, view *UtxoViewpoint) error {
+func (b *BlockChain) checkBIP0030(node *blockNode, block *btcutil.Block,
+	view *UtxoViewpoint, cache *utxoCache) error {
+
 	// Fetch utxos for all of the transaction outputs in this block.
 	// Typically, there will not be any utxos for any of the outputs.
 	fetch := make([]wire.OutPoint, 0, len(block.Transactions()))
@@ -932,7 +949,7 @@ func (b *BlockChain) checkBIP0030(node *blockNode, block *btcutil.Block, view *U
 			fetch = append(fetch, prevOut)
 		}
 	}
-	err := view.fetchUtxos(b.utxoCache, fetch)
+	err := view.fetchUtxos(cache, fetch)
 	if err != nil {
 		return err
 	}
@@ -1076,8 +1093,14 @@ func CheckTransactionInputs(tx *btcutil.Tx, txHeight int32, utxoView *UtxoViewpo
 // connects to the end of the current main chain and then calls this function
 // with that node.
 //
+// The utxos referenced by the block which are not in the view are loaded from
+// the passed utxo cache, which is the cache of the main chain state except when
+// validating the blocks before a loaded UTXO set snapshot in the background.
+//
 // This function MUST be called with the chain state lock held (for writes).
-func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, view *UtxoViewpoint, stxos *[]SpentTxOut) error {
+func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block,
+	view *UtxoViewpoint, cache *utxoCache, stxos *[]SpentTxOut) error {
+
 	// If the side chain blocks end up in the database, a call to
 	// CheckBlockSanity should be done here in case a previous version
 	// allowed a block that is no longer valid.  However, since the
@@ -1116,7 +1139,7 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, vi
 	// BIP0030 check is expensive since it involves a ton of cache misses in
 	// the utxoset.
 	if !isBIP0030Node(node) && (node.height < b.chainParams.BIP0034Height) {
-		err := b.checkBIP0030(node, block, view)
+		err := b.checkBIP0030(node, block, view, cache)
 		if err != nil {
 			return err
 		}
@@ -1127,7 +1150,7 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, vi
 	//
 	// These utxo entries are needed for verification of things such as
 	// transaction inputs, counting pay-to-script-hashes, and scripts.
-	err := view.fetchInputUtxos(b.utxoCache, block)
+	err := view.fetchInputUtxos(cache, block)
 	if err != nil {
 		return err
 	}
@@ -1244,6 +1267,14 @@ func (b *BlockChain) checkConnectBlock(node *blockNode, block *btcutil.Block, vi
 		runScripts = false
 	}
 
+	// Likewise, don't run scripts of the ancestors of the assumed valid
+	// block once it's buried deeply enough in a header chain with enough
+	// work.  This doesn't depend on checkpoints, so it also applies when
+	// they are disabled.
+	if runScripts && b.isAssumedValid(node) {
+		runScripts = false
+	}
+
 	// Blocks created after the BIP0016 activation time need to have the
 	// pay-to-script-hash checks enabled.
 	var scriptFlags txscript.ScriptFlags
@@ -1378,7 +1409,7 @@ func (b *BlockChain) CheckConnectBlockTemplate(block *btcutil.Block) error {
 	view := NewUtxoViewpoint()
 	view.SetBestHash(&tip.hash)
 	newNode := newBlockNode(&header, tip)
-	return b.checkConnectBlock(newNode, block, view, nil)
+	return b.checkConnectBlock(newNode, block, view, b.utxoCache, nil)
 }
 
 // ChainParams returns the Blockchain's configured chaincfg.Params.
@@ -169,6 +169,14 @@ func btcdMain(serverChan chan<- *server) error {
 
 		return nil
 	}
+	if cfg.DropCoinStatsIndex {
+		if err := indexers.DropCoinStatsIndex(db, interrupt); err != nil {
+			btcdLog.Errorf("%v", err)
+			return err
+		}
+
+		return nil
+	}
 
 	// Check if the database had previously been pruned.  If it had been, it's
 	// not possible to newly generate the tx index and addr index.
@@ -202,6 +210,16 @@ func btcdMain(serverChan chan<- *server) error {
 		btcdLog.Errorf("%v", err)
 		return err
 	}
+	// The coin statistics index can be kept up to date on a pruned node,
+	// but it can't be built since the spent outputs of the pruned blocks
+	// are no longer available.
+	if beenPruned && cfg.CoinStatsIndex && !indexers.CoinStatsIndexInitialized(db) {
+		err = fmt.Errorf("--coinstatsindex cannot be enabled as the node has been "+
+			"previously pruned. You must delete the files in the datadir: \"%s\" "+
+			"and sync from the beginning to enable the desired index", cfg.DataDir)
+		btcdLog.Errorf("%v", err)
+		return err
+	}
 	// If we've previously been pruned and the cfindex isn't present, it means that the
 	// user wants to enable the cfindex after the node has already synced up and been
 	// pruned.
@@ -0,0 +1,389 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcec
+
+import (
+	"crypto/rand"
+	"errors"
+
+	"github.com/bynil/btcd/chaincfg/chainhash"
+)
+
+// EllswiftEncodingLen is the length of an ElligatorSwift encoded public key
+// as defined by BIP0324.
+const EllswiftEncodingLen = 64
+
+var (
+	// ErrEllswiftNoSolution is returned when an ElligatorSwift encoding
+	// for a given x coordinate could not be found after many attempts.
+	// This is practically impossible for valid x coordinates.
+	ErrEllswiftNoSolution = errors.New("no ElligatorSwift encoding found")
+
+	// ErrEllswiftInvalidX is returned when an x coordinate that is not on
+	// the curve is passed to XElligatorSwift.
+	ErrEllswiftInvalidX = errors.New("x coordinate is not on the curve")
+
+	// ellswiftXonlyECDHTag is the BIP0340-style tag used to derive the
+	// BIP0324 shared secret from the x-only ECDH result.
+	ellswiftXonlyECDHTag = []byte("bip324_ellswift_xonly_ecdh")
+
+	// feMinus3Sqrt is the square root of -3 in the field, computed as
+	// (-3)^((p+1)/4) to match the root selected by the reference
+	// implementation.
+	feMinus3Sqrt = func() FieldVal {
+		var minus3, root FieldVal
+		minus3.SetInt(3).Negate(1).Normalize()
+		root.SquareRootVal(&minus3)
+		root.Normalize()
+		return root
+	}()
+
+	// feSeven is the curve constant b = 7.
+	feSeven = func() FieldVal {
+		var seven FieldVal
+		seven.SetInt(7)
+		return seven
+	}()
+)
+
+// The following helpers perform the field arithmetic needed by the
+// ElligatorSwift mapping.  They always return normalized values so the
+// magnitude preconditions of FieldVal never have to be tracked by callers.
+
+func feAdd(a, b *FieldVal) FieldVal {
+	var r FieldVal
+	r.Set(a).Add(b).Normalize()
+	return r
+}
+
+func feNeg(a *FieldVal) FieldVal {
+	var r FieldVal
+	r.NegateVal(a, 1).Normalize()
+	return r
+}
+
+func feSub(a, b *FieldVal) FieldVal {
+	nb := feNeg(b)
+	return feAdd(a, &nb)
+}
+
+func feMul(a, b *FieldVal) FieldVal {
+	var r FieldVal
+	r.Mul2(a, b).Normalize()
+	return r
+}
+
+func feSqr(a *FieldVal) FieldVal {
+	var r FieldVal
+	r.SquareVal(a).Normalize()
+	return r
+}
+
+func feInv(a *FieldVal) FieldVal {
+	var r FieldVal
+	r.Set(a).Inverse().Normalize()
+	return r
+}
+
+func feHalf(a *FieldVal) FieldVal {
+	var two FieldVal
+	two.SetInt(2)
+	twoInv := feInv(&two)
+	return feMul(a, &twoInv)
+}
+
+// feSqrt returns the square root of a along with whether it exists.
+func feSqrt(a *FieldVal) (FieldVal, bool) {
+	var r FieldVal
+	ok := r.SquareRootVal(a)
+	r.Normalize()
+	return r, ok
+}
+
+// curveRHS returns x^3 + 7.
+func curveRHS(x *FieldVal) FieldVal {
+	x2 := feSqr(x)
+	x3 := feMul(&x2, x)
+	return feAdd(&x3, &feSeven)
+}
+
+// isValidX returns whether x is the x coordinate of a point on the curve.
+func isValidX(x *FieldVal) bool {
+	rhs := curveRHS(x)
+	_, ok := feSqrt(&rhs)
+	return ok
+}
+
+// XSwiftEC decodes the field elements (u, t) of an ElligatorSwift encoding
+// to the x coordinate of a point on the curve as defined by BIP0324.  Every
+// pair of field elements maps to a valid x coordinate.
+func XSwiftEC(uIn, tIn *FieldVal) *FieldVal {
+	var u, t FieldVal
+	u.Set(uIn).Normalize()
+	t.Set(tIn).Normalize()
+
+	// Map the exceptional inputs to ones that don't cause a division by
+	// zero.
+	if u.IsZero() {
+		u.SetInt(1)
+	}
+	if t.IsZero() {
+		t.SetInt(1)
+	}
+	u3b := curveRHS(&u)
+	t2 := feSqr(&t)
+	if sum := feAdd(&u3b, &t2); sum.IsZero() {
+		t = feAdd(&t, &t)
+		t2 = feSqr(&t)
+	}
+
+	// X = (u^3 + 7 - t^2) / (2*t)
+	// Y = (X + t) / (sqrt(-3) * u)
+	twoT := feAdd(&t, &t)
+	twoTInv := feInv(&twoT)
+	num := feSub(&u3b, &t2)
+	bigX := feMul(&num, &twoTInv)
+
+	xPlusT := feAdd(&bigX, &t)
+	den := feMul(&feMinus3Sqrt, &u)
+	denInv := feInv(&den)
+	bigY := feMul(&xPlusT, &denInv)
+
+	// Candidate x1 = u + 4*Y^2.
+	y2 := feSqr(&bigY)
+	fourY2 := feAdd(&y2, &y2)
+	fourY2 = feAdd(&fourY2, &fourY2)
+	x1 := feAdd(&u, &fourY2)
+	if isValidX(&x1) {
+		return &x1
+	}
+
+	// Candidate x2 = (-X/Y - u) / 2.
+	yInv := feInv(&bigY)
+	xOverY := feMul(&bigX, &yInv)
+	negXOverY := feNeg(&xOverY)
+	x2 := feSub(&negXOverY, &u)
+	x2 = feHalf(&x2)
+	if isValidX(&x2) {
+		return &x2
+	}
+
+	// Candidate x3 = (X/Y - u) / 2.  One of the candidates is always
+	// valid, so this must be it.
+	x3 := feSub(&xOverY, &u)
+	x3 = feHalf(&x3)
+	return &x3
+}
+
+// XSwiftECInv returns a field element t such that XSwiftEC(u, t) = x, or nil
+// when no such t exists for the given case.  The case, in the range [0, 8),
+// selects which of the up to eight preimages is returned.
+func XSwiftECInv(xIn, uIn *FieldVal, caseNum int) *FieldVal {
+	var x, u FieldVal
+	x.Set(xIn).Normalize()
+	u.Set(uIn).Normalize()
+
+	u2 := feSqr(&u)
+	u3b := curveRHS(&u)
+
+	var s, v FieldVal
+	if caseNum&2 == 0 {
+		// If -x-u is a valid x coordinate, the x1 or x2 formula of
+		// XSwiftEC would be used instead of x3, so fail.
+		negX := feNeg(&x)
+		negXMinusU := feSub(&negX, &u)
+		if isValidX(&negXMinusU) {
+			return nil
+		}
+
+		// s = -(u^3 + 7) / (u^2 + u*v + v^2)
+		v = x
+		uv := feMul(&u, &v)
+		v2 := feSqr(&v)
+		den := feAdd(&u2, &uv)
+		den = feAdd(&den, &v2)
+		if den.IsZero() {
+			return nil
+		}
+		denInv := feInv(&den)
+		negU3b := feNeg(&u3b)
+		s = feMul(&negU3b, &denInv)
+	} else {
+		// s = x - u
+		s = feSub(&x, &u)
+		if s.IsZero() {
+			return nil
+		}
+
+		// r = sqrt(-s * (4*(u^3 + 7) + 3*s*u^2))
+		fourU3b := feAdd(&u3b, &u3b)
+		fourU3b = feAdd(&fourU3b, &fourU3b)
+		su2 := feMul(&s, &u2)
+		threeSU2 := feAdd(&su2, &su2)
+		threeSU2 = feAdd(&threeSU2, &su2)
+		inner := feAdd(&fourU3b, &threeSU2)
+		negS := feNeg(&s)
+		radicand := feMul(&negS, &inner)
+		r, ok := feSqrt(&radicand)
+		if !ok {
+			return nil
+		}
+		if caseNum&1 != 0 && r.IsZero() {
+			return nil
+		}
+
+		// v = (r/s - u) / 2
+		sInv := feInv(&s)
+		rOverS := feMul(&r, &sInv)
+		v = feSub(&rOverS, &u)
+		v = feHalf(&v)
+	}
+
+	w, ok := feSqrt(&s)
+	if !ok {
+		return nil
+	}
+
+	// Compute u * (1 -/+ sqrt(-3)) / 2 + v depending on the case and
+	// multiply it by -/+ w.
+	var one FieldVal
+	one.SetInt(1)
+	var factor FieldVal
+	if caseNum&1 == 0 {
+		factor = feSub(&one, &feMinus3Sqrt)
+	} else {
+		factor = feAdd(&one, &feMinus3Sqrt)
+	}
+	factor = feHalf(&factor)
+	t := feMul(&u, &factor)
+	t = feAdd(&t, &v)
+	t = feMul(&w, &t)
+
+	switch caseNum & 5 {
+	case 0, 5:
+		t = feNeg(&t)
+	}
+	return &t
+}
+
+// XElligatorSwift returns a random ElligatorSwift encoding (u, t) of the
+// passed x coordinate.
+func XElligatorSwift(x *FieldVal) (*FieldVal, *FieldVal, error) {
+	if !isValidX(x) {
+		return nil, nil, ErrEllswiftInvalidX
+	}
+
+	var buf [33]byte
+	for i := 0; i < 1024; i++ {
+		if _, err := rand.Read(buf[:]); err != nil {
+			return nil, nil, err
+		}
+
+		var u FieldVal
+		u.SetByteSlice(buf[:32])
+		u.Normalize()
+		if u.IsZero() {
+			continue
+		}
+
+		t := XSwiftECInv(x, &u, int(buf[32]&7))
+		if t != nil {
+			return &u, t, nil
+		}
+	}
+
+	return nil, nil, ErrEllswiftNoSolution
+}
+
+// EllswiftEncode returns a random 64-byte ElligatorSwift encoding of the
+// passed public key.  The encoding only commits to the x coordinate of the
+// key.
+func EllswiftEncode(pubKey *PublicKey) ([EllswiftEncodingLen]byte, error) {
+	var enc [EllswiftEncodingLen]byte
+
+	x := pubKey.X()
+	var xf FieldVal
+	xf.SetByteSlice(x.Bytes())
+	xf.Normalize()
+
+	u, t, err := XElligatorSwift(&xf)
+	if err != nil {
+		return enc, err
+	}
+	u.PutBytesUnchecked(enc[:32])
+	t.PutBytesUnchecked(enc[32:])
+	return enc, nil
+}
+
+// EllswiftDecode returns the x coordinate of the public key encoded by the
+// passed 64-byte ElligatorSwift encoding.  Every encoding is valid.
+func EllswiftDecode(enc [EllswiftEncodingLen]byte) *FieldVal {
+	var u, t FieldVal
+	u.SetByteSlice(enc[:32])
+	t.SetByteSlice(enc[32:])
+	return XSwiftEC(&u, &t)
+}
+
+// EllswiftCreate generates a new private key along with a random
+// ElligatorSwift encoding of its public key.
+func EllswiftCreate() (*PrivateKey, [EllswiftEncodingLen]byte, error) {
+	privKey, err := NewPrivateKey()
+	if err != nil {
+		return nil, [EllswiftEncodingLen]byte{}, err
+	}
+
+	enc, err := EllswiftEncode(privKey.PubKey())
+	if err != nil {
+		return nil, [EllswiftEncodingLen]byte{}, err
+	}
+
+	return privKey, enc, nil
+}
+
+// EllswiftECDHXOnly performs an x-only ECDH between the passed private key
+// and the public key encoded by the passed ElligatorSwift encoding and returns
+// the x coordinate of the resulting point.
+func EllswiftECDHXOnly(ellswiftTheirs [EllswiftEncodingLen]byte,
+	privKey *PrivateKey) [32]byte {
+
+	x := EllswiftDecode(ellswiftTheirs)
+
+	// The decoded x coordinate is always valid, so the y coordinate
+	// always exists.  Its parity doesn't matter since only the x
+	// coordinate of the result is used.
+	var y FieldVal
+	DecompressY(x, false, &y)
+	y.Normalize()
+
+	var one FieldVal
+	one.SetInt(1)
+	point := MakeJacobianPoint(x, &y, &one)
+
+	var result JacobianPoint
+	ScalarMultNonConst(&privKey.Key, &point, &result)
+	result.ToAffine()
+
+	var out [32]byte
+	result.X.PutBytesUnchecked(out[:])
+	return out
+}
+
+// V2Ecdh derives the BIP0324 shared secret from the passed private key and the
+// ElligatorSwift encodings of both parties.  The initiating flag indicates
+// whether the local party initiated the connection, which determines the
+// order in which the encodings are hashed.
+func V2Ecdh(privKey *PrivateKey, ellswiftTheirs,
+	ellswiftOurs [EllswiftEncodingLen]byte,
+	initiating bool) (*chainhash.Hash, error) {
+
+	ecdhX := EllswiftECDHXOnly(ellswiftTheirs, privKey)
+
+	if initiating {
+		return chainhash.TaggedHash(ellswiftXonlyECDHTag,
+			ellswiftOurs[:], ellswiftTheirs[:], ecdhX[:]), nil
+	}
+	return chainhash.TaggedHash(ellswiftXonlyECDHTag, ellswiftTheirs[:],
+		ellswiftOurs[:], ecdhX[:]), nil
+}
@@ -0,0 +1,295 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcec
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"testing"
+)
+
+// TestEllswiftDecodeVectors ensures decoding ElligatorSwift encodings matches
+// the BIP0324 test vectors from ellswift_decode_test_vectors.csv, along with
+// encodings whose field elements are at least the field prime, which decode
+// like their reductions.
+func TestEllswiftDecodeVectors(t *testing.T) {
+	const (
+		zero  = "0000000000000000000000000000000000000000000000000000000000000000"
+		prime = "fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f"
+	)
+	tests := []struct {
+		enc string
+		x   string
+	}{{
+		enc: zero + zero,
+		x:   "edd1fd3e327ce90cc7a3542614289aee9682003e9cf7dcc9cf2ca9743be5aa0c",
+	}, {
+		enc: zero + "01d3475bf7655b0fb2d852921035b2ef607f49069b97454e6795251062741771",
+		x:   "b5da00b73cd6560520e7c364086e7cd23a34bf60d0e707be9fc34d4cd5fdfa2c",
+	}, {
+		enc: zero + "82277c4a71f9d22e66ece523f8fa08741a7c0912c66a69ce68514bfd3515b49f",
+		x:   "f482f2e241753ad0fb89150d8491dc1e34ff0b8acfbb442cfe999e2e5e6fd1d2",
+	}, {
+		enc: zero + "8421cc930e77c9f514b6915c3dbe2a94c6d8f690b5b739864ba6789fb8a55dd0",
+		x:   "9f59c40275f5085a006f05dae77eb98c6fd0db1ab4a72ac47eae90a4fc9e57e0",
+	}, {
+		enc: zero + "d19c182d2759cd99824228d94799f8c6557c38a1c0d6779b9d4b729c6f1ccc42",
+		x:   "70720db7e238d04121f5b1afd8cc5ad9d18944c6bdc94881f502b7a3af3aecff",
+	}, {
+		enc: "0a2d2ba93507f1df233770c2a797962cc61f6d15da14ecd47d8d27ae1cd5f853" + zero,
+		x:   "532167c11200b08c0e84a354e74dcc40f8b25f4fe686e30869526366278a0688",
+	}, {
+		enc: "0ffde9ca81d751e9cdaffc1a50779245320b28996dbaf32f822f20117c22fbd6" +
+			"c74d99efceaa550f1ad1c0f43f46e7ff1ee3bd0162b7bf55f2965da9c3450646",
+		x: "74e880b3ffd18fe3cddf7902522551ddf97fa4a35a3cfda8197f947081a57b8f",
+	}, {
+		enc: "a4a94dfce69b4a2a0a099313d10f9f7e7d649d60501c9e1d274c300e0d89aafa" +
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffff8faf88d5",
+		x: "0c71defa3fafd74cb835102acd81490963f6b72d889495e06561375bd65f6ffc",
+	}, {
+		enc: zero + prime,
+		x:   "edd1fd3e327ce90cc7a3542614289aee9682003e9cf7dcc9cf2ca9743be5aa0c",
+	}, {
+		enc: prime + zero,
+		x:   "edd1fd3e327ce90cc7a3542614289aee9682003e9cf7dcc9cf2ca9743be5aa0c",
+	}, {
+		enc: prime + "01d3475bf7655b0fb2d852921035b2ef607f49069b97454e6795251062741771",
+		x:   "b5da00b73cd6560520e7c364086e7cd23a34bf60d0e707be9fc34d4cd5fdfa2c",
+	}, {
+		enc: "0a2d2ba93507f1df233770c2a797962cc61f6d15da14ecd47d8d27ae1cd5f853" + prime,
+		x:   "532167c11200b08c0e84a354e74dcc40f8b25f4fe686e30869526366278a0688",
+	}}
+
+	for i, test := range tests {
+		var enc [EllswiftEncodingLen]byte
+		copy(enc[:], hexToBytes(test.enc))
+
+		x := EllswiftDecode(enc)
+		var xBytes [32]byte
+		x.PutBytes(&xBytes)
+		if got := hex.EncodeToString(xBytes[:]); got != test.x {
+			t.Fatalf("#%d: decoded x mismatch - got %s, want %s", i,
+				got, test.x)
+		}
+	}
+}
+
+// TestXSwiftECInvVectors ensures the preimages returned for each case match
+// the BIP0324 test vectors from xswiftec_inv_test_vectors.csv and decode to
+// the expected x coordinate.  An empty preimage means the case has none.
+func TestXSwiftECInvVectors(t *testing.T) {
+	tests := []struct {
+		u     string
+		x     string
+		cases [8]string
+	}{{
+		u: "05ff6bdad900fc3261bc7fe34e2fb0f569f06e091ae437d3a52e9da0cbfb9590",
+		x: "80cdf63774ec7022c89a5a8558e373a279170285e0ab27412dbce510bdfe23fc",
+		cases: [8]string{
+			"", "",
+			"45654798ece071ba79286d04f7f3eb1c3f1d17dd883610f2ad2efd82a287466b",
+			"0aeaa886f6b76c7158452418cbf5033adc5747e9e9b5d3b2303db96936528557",
+			"", "",
+			"ba9ab867131f8e4586d792fb080c14e3c0e2e82277c9ef0d52d1027c5d78b5c4",
+			"f51557790948938ea7badbe7340afcc523a8b816164a2c4dcfc24695c9ad76d8",
+		},
+	}, {
+		u: "1737a85f4c8d146cec96e3ffdca76d9903dcf3bd53061868d478c78c63c2aa9e",
+		x: "39e48dd150d2f429be088dfd5b61882e7e8407483702ae9a5ab35927b15f85ea",
+		cases: [8]string{
+			"1be8cc0b04be0c681d0c6a68f733f82c6c896e0c8a262fcd392918e303a7abf4",
+			"605b5814bf9b8cb066667c9e5480d22dc5b6c92f14b4af3ee0a9eb83b03685e3",
+			"", "",
+			"e41733f4fb41f397e2f3959708cc07d3937691f375d9d032c6d6e71bfc58503b",
+			"9fa4a7eb4064734f99998361ab7f2dd23a4936d0eb4b50c11f56147b4fc9764c",
+			"", "",
+		},
+	}}
+
+	for i, test := range tests {
+		u, x := hexToFieldVal(test.u), hexToFieldVal(test.x)
+		for c, want := range test.cases {
+			tVal := XSwiftECInv(x, u, c)
+			if tVal == nil {
+				if want != "" {
+					t.Fatalf("#%d case %d: no preimage, want %s",
+						i, c, want)
+				}
+				continue
+			}
+
+			var tBytes [32]byte
+			tVal.PutBytes(&tBytes)
+			if got := hex.EncodeToString(tBytes[:]); got != want {
+				t.Fatalf("#%d case %d: got preimage %s, want %q",
+					i, c, got, want)
+			}
+			if decoded := XSwiftEC(u, tVal); !decoded.Equals(x) {
+				t.Fatalf("#%d case %d: preimage decodes to %v, "+
+					"want %v", i, c, decoded, x)
+			}
+		}
+	}
+}
+
+// TestEllswiftRoundTrip ensures that encoding a public key with
+// ElligatorSwift and decoding it again results in the same x coordinate.
+func TestEllswiftRoundTrip(t *testing.T) {
+	for i := 0; i < 32; i++ {
+		privKey, enc, err := EllswiftCreate()
+		if err != nil {
+			t.Fatalf("EllswiftCreate: unexpected error: %v", err)
+		}
+
+		x := EllswiftDecode(enc)
+		var want FieldVal
+		want.SetByteSlice(privKey.PubKey().X().Bytes())
+		if !x.Equals(&want) {
+			t.Fatalf("#%d: decoded x mismatch - got %v, want %v",
+				i, x, &want)
+		}
+	}
+}
+
+// TestEllswiftDecodeAlwaysValid ensures arbitrary encodings, including the
+// exceptional ones, decode to a valid x coordinate.
+func TestEllswiftDecodeAlwaysValid(t *testing.T) {
+	var encodings [][EllswiftEncodingLen]byte
+
+	// All zero and all 0xff encodings exercise the exceptional cases and
+	// values that overflow the field prime.
+	var zero, ff [EllswiftEncodingLen]byte
+	for i := range ff {
+		ff[i] = 0xff
+	}
+	encodings = append(encodings, zero, ff)
+
+	for i := 0; i < 32; i++ {
+		var enc [EllswiftEncodingLen]byte
+		if _, err := rand.Read(enc[:]); err != nil {
+			t.Fatalf("unable to read random bytes: %v", err)
+		}
+		encodings = append(encodings, enc)
+	}
+
+	for i, enc := range encodings {
+		x := EllswiftDecode(enc)
+		if !isValidX(x) {
+			t.Fatalf("#%d: decoded x %v is not on the curve", i, x)
+		}
+	}
+}
+
+// TestV2Ecdh ensures both sides of a BIP0324 key exchange derive the same
+// shared secret.
+func TestV2Ecdh(t *testing.T) {
+	privA, ellA, err := EllswiftCreate()
+	if err != nil {
+		t.Fatalf("EllswiftCreate: unexpected error: %v", err)
+	}
+	privB, ellB, err := EllswiftCreate()
+	if err != nil {
+		t.Fatalf("EllswiftCreate: unexpected error: %v", err)
+	}
+
+	secretA, err := V2Ecdh(privA, ellB, ellA, true)
+	if err != nil {
+		t.Fatalf("V2Ecdh: unexpected error: %v", err)
+	}
+	secretB, err := V2Ecdh(privB, ellA, ellB, false)
+	if err != nil {
+		t.Fatalf("V2Ecdh: unexpected error: %v", err)
+	}
+	if *secretA != *secretB {
+		t.Fatalf("shared secrets mismatch - %v != %v", secretA, secretB)
+	}
+
+	// Both sides claiming to be the initiator must not agree.
+	secretC, err := V2Ecdh(privB, ellA, ellB, true)
+	if err != nil {
+		t.Fatalf("V2Ecdh: unexpected error: %v", err)
+	}
+	if *secretA == *secretC {
+		t.Fatal("shared secret does not commit to the initiator")
+	}
+}
+
+// TestV2EcdhVectors ensures the shared secrets derived from the private key and
+// ElligatorSwift encodings of the BIP0324 test vectors from
+// packet_encoding_test_vectors.csv match, along with the x coordinates of the
+// encoded public keys and of the x-only ECDH.
+func TestV2EcdhVectors(t *testing.T) {
+	tests := []struct {
+		privKey      string
+		ellswiftOurs string
+		ellswiftThem string
+		initiating   bool
+		xOurs        string
+		xTheirs      string
+		xShared      string
+		secret       string
+	}{{
+		privKey: "61062ea5071d800bbfd59e2e8b53d47d194b095ae5a4df04936b49772ef0d4d7",
+		ellswiftOurs: "ec0adff257bbfe500c188c80b4fdd640f6b45a482bbc15fc7cef5931deff0aa1" +
+			"86f6eb9bba7b85dc4dcc28b28722de1e3d9108b985e2967045668f66098e475b",
+		ellswiftThem: "a4a94dfce69b4a2a0a099313d10f9f7e7d649d60501c9e1d274c300e0d89aafa" +
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffff8faf88d5",
+		initiating: true,
+		xOurs:      "19e965bc20fc40614e33f2f82d4eeff81b5e7516b12a5c6c0d6053527eba0923",
+		xTheirs:    "0c71defa3fafd74cb835102acd81490963f6b72d889495e06561375bd65f6ffc",
+		xShared:    "4eb2bf85bd00939468ea2abb25b63bc642e3d1eb8b967fb90caa2d89e716050e",
+		secret:     "c6992a117f5edbea70c3f511d32d26b9798be4b81a62eaee1a5acaa8459a3592",
+	}, {
+		privKey: "1f9c581b35231838f0f17cf0c979835baccb7f3abbbb96ffcc318ab71e6e126f",
+		ellswiftOurs: "a1855e10e94e00baa23041d916e259f7044e491da6171269694763f018c7e636" +
+			"93d29575dcb464ac816baa1be353ba12e3876cba7628bd0bd8e755e721eb0140",
+		ellswiftThem: "fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f" +
+			"0000000000000000000000000000000000000000000000000000000000000000",
+		initiating: false,
+		xOurs:      "45b6f1f684fd9f2b16e2651ddc47156c0695c8c5cd2c0c9df6d79a1056c61120",
+		xTheirs:    "edd1fd3e327ce90cc7a3542614289aee9682003e9cf7dcc9cf2ca9743be5aa0c",
+		xShared:    "c40eb6190caf399c9007254ad5e5fa20d64af2b41696599c59b2191d16992955",
+		secret:     "a0138f564f74d0ad70bc337dacc9d0bf1d2349364caf1188a1e6e8ddb3b7b184",
+	}}
+
+	for i, test := range tests {
+		privKey, _ := PrivKeyFromBytes(hexToBytes(test.privKey))
+		var ours, theirs [EllswiftEncodingLen]byte
+		copy(ours[:], hexToBytes(test.ellswiftOurs))
+		copy(theirs[:], hexToBytes(test.ellswiftThem))
+
+		var xBytes [32]byte
+		EllswiftDecode(ours).PutBytes(&xBytes)
+		if got := hex.EncodeToString(xBytes[:]); got != test.xOurs {
+			t.Fatalf("#%d: got our x %s, want %s", i, got, test.xOurs)
+		}
+		if got := hex.EncodeToString(privKey.PubKey().X().Bytes()); got !=
+			test.xOurs {
+
+			t.Fatalf("#%d: got public key x %s, want %s", i, got,
+				test.xOurs)
+		}
+		EllswiftDecode(theirs).PutBytes(&xBytes)
+		if got := hex.EncodeToString(xBytes[:]); got != test.xTheirs {
+			t.Fatalf("#%d: got their x %s, want %s", i, got,
+				test.xTheirs)
+		}
+
+		xShared := EllswiftECDHXOnly(theirs, privKey)
+		if got := hex.EncodeToString(xShared[:]); got != test.xShared {
+			t.Fatalf("#%d: got shared x %s, want %s", i, got,
+				test.xShared)
+		}
+
+		secret, err := V2Ecdh(privKey, theirs, ours, test.initiating)
+		if err != nil {
+			t.Fatalf("#%d: V2Ecdh: unexpected error: %v", i, err)
+		}
+		if got := hex.EncodeToString(secret[:]); got != test.secret {
+			t.Fatalf("#%d: got shared secret %s, want %s", i, got,
+				test.secret)
+		}
+	}
+}
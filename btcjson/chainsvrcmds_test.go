@@ -43,6 +43,91 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"addnode","params":["127.0.0.1","remove"],"id":1}`,
 			unmarshalled: &btcjson.AddNodeCmd{Addr: "127.0.0.1", SubCmd: btcjson.ANRemove},
 		},
+		{
+			name: "analyzepsbt",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("analyzepsbt", "cHNidP8B")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewAnalyzePsbtCmd("cHNidP8B")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"analyzepsbt","params":["cHNidP8B"],"id":1}`,
+			unmarshalled: &btcjson.AnalyzePsbtCmd{Psbt: "cHNidP8B"},
+		},
+		{
+			name: "combinepsbt",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("combinepsbt", []string{"cHNidP8B", "cHNidP8C"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewCombinePsbtCmd([]string{"cHNidP8B", "cHNidP8C"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"combinepsbt","params":[["cHNidP8B","cHNidP8C"]],"id":1}`,
+			unmarshalled: &btcjson.CombinePsbtCmd{
+				Psbts: []string{"cHNidP8B", "cHNidP8C"},
+			},
+		},
+		{
+			name: "combinerawtransaction",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("combinerawtransaction", []string{"0100", "0200"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewCombineRawTransactionCmd([]string{"0100", "0200"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"combinerawtransaction","params":[["0100","0200"]],"id":1}`,
+			unmarshalled: &btcjson.CombineRawTransactionCmd{
+				Txs: []string{"0100", "0200"},
+			},
+		},
+		{
+			name: "createpsbt",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("createpsbt", `[{"txid":"123","vout":1}]`,
+					`[{"456":0.0123},{"data":"00"}]`)
+			},
+			staticCmd: func() interface{} {
+				txInputs := []btcjson.CreatePsbtInput{
+					{Txid: "123", Vout: 1},
+				}
+				outputs := []btcjson.PsbtOutput{
+					{"456": .0123}, {"data": "00"},
+				}
+				return btcjson.NewCreatePsbtCmd(txInputs, outputs, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"createpsbt","params":[[{"txid":"123","vout":1}],[{"456":0.0123},{"data":"00"}]],"id":1}`,
+			unmarshalled: &btcjson.CreatePsbtCmd{
+				Inputs: []btcjson.CreatePsbtInput{{Txid: "123", Vout: 1}},
+				Outputs: []btcjson.PsbtOutput{
+					{"456": .0123}, {"data": "00"},
+				},
+			},
+		},
+		{
+			name: "createpsbt optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("createpsbt",
+					`[{"txid":"123","vout":1,"sequence":7}]`,
+					`[{"456":0.0123}]`, int64(500000), true)
+			},
+			staticCmd: func() interface{} {
+				txInputs := []btcjson.CreatePsbtInput{
+					{Txid: "123", Vout: 1, Sequence: btcjson.Uint32(7)},
+				}
+				outputs := []btcjson.PsbtOutput{{"456": .0123}}
+				return btcjson.NewCreatePsbtCmd(txInputs, outputs,
+					btcjson.Int64(500000), btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"createpsbt","params":[[{"txid":"123","vout":1,"sequence":7}],[{"456":0.0123}],500000,true],"id":1}`,
+			unmarshalled: &btcjson.CreatePsbtCmd{
+				Inputs: []btcjson.CreatePsbtInput{
+					{Txid: "123", Vout: 1, Sequence: btcjson.Uint32(7)},
+				},
+				Outputs:     []btcjson.PsbtOutput{{"456": .0123}},
+				LockTime:    btcjson.Int64(500000),
+				Replaceable: btcjson.Bool(true),
+			},
+		},
 		{
 			name: "createrawtransaction",
 			newCmd: func() (interface{}, error) {
@@ -97,6 +182,34 @@ func TestChainSvrCmds(t *testing.T) {
 				LockTime: btcjson.Int64(12312333333),
 			},
 		},
+		{
+			name: "finalizepsbt",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("finalizepsbt", "cHNidP8B")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewFinalizePsbtCmd("cHNidP8B", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"finalizepsbt","params":["cHNidP8B"],"id":1}`,
+			unmarshalled: &btcjson.FinalizePsbtCmd{
+				Psbt:    "cHNidP8B",
+				Extract: btcjson.Bool(true),
+			},
+		},
+		{
+			name: "finalizepsbt optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("finalizepsbt", "cHNidP8B", false)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewFinalizePsbtCmd("cHNidP8B", btcjson.Bool(false))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"finalizepsbt","params":["cHNidP8B",false],"id":1}`,
+			unmarshalled: &btcjson.FinalizePsbtCmd{
+				Psbt:    "cHNidP8B",
+				Extract: btcjson.Bool(false),
+			},
+		},
 		{
 			name: "fundrawtransaction - empty opts",
 			newCmd: func() (i interface{}, e error) {
@@ -199,6 +312,17 @@ func TestChainSvrCmds(t *testing.T) {
 				}(),
 			},
 		},
+		{
+			name: "decodepsbt",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("decodepsbt", "cHNidP8B")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewDecodePsbtCmd("cHNidP8B")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"decodepsbt","params":["cHNidP8B"],"id":1}`,
+			unmarshalled: &btcjson.DecodePsbtCmd{Psbt: "cHNidP8B"},
+		},
 		{
 			name: "decoderawtransaction",
 			newCmd: func() (interface{}, error) {
@@ -689,6 +813,30 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"gethashespersec","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetHashesPerSecCmd{},
 		},
+		{
+			name: "getindexinfo",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getindexinfo")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetIndexInfoCmd(nil)
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getindexinfo","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetIndexInfoCmd{},
+		},
+		{
+			name: "getindexinfo optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getindexinfo", "txindex")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetIndexInfoCmd(btcjson.String("txindex"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getindexinfo","params":["txindex"],"id":1}`,
+			unmarshalled: &btcjson.GetIndexInfoCmd{
+				IndexName: btcjson.String("txindex"),
+			},
+		},
 		{
 			name: "getinfo",
 			newCmd: func() (interface{}, error) {
@@ -700,6 +848,64 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"getinfo","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetInfoCmd{},
 		},
+		{
+			name: "getmempoolancestors",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getmempoolancestors", "txhash")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetMempoolAncestorsCmd("txhash", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempoolancestors","params":["txhash"],"id":1}`,
+			unmarshalled: &btcjson.GetMempoolAncestorsCmd{
+				TxID:    "txhash",
+				Verbose: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getmempoolancestors verbose",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getmempoolancestors", "txhash", true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetMempoolAncestorsCmd("txhash",
+					btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempoolancestors","params":["txhash",true],"id":1}`,
+			unmarshalled: &btcjson.GetMempoolAncestorsCmd{
+				TxID:    "txhash",
+				Verbose: btcjson.Bool(true),
+			},
+		},
+		{
+			name: "getmempooldescendants",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getmempooldescendants", "txhash")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetMempoolDescendantsCmd("txhash", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempooldescendants","params":["txhash"],"id":1}`,
+			unmarshalled: &btcjson.GetMempoolDescendantsCmd{
+				TxID:    "txhash",
+				Verbose: btcjson.Bool(false),
+			},
+		},
+		{
+			name: "getmempooldescendants verbose",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getmempooldescendants", "txhash", true)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetMempoolDescendantsCmd("txhash",
+					btcjson.Bool(true))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"getmempooldescendants","params":["txhash",true],"id":1}`,
+			unmarshalled: &btcjson.GetMempoolDescendantsCmd{
+				TxID:    "txhash",
+				Verbose: btcjson.Bool(true),
+			},
+		},
 		{
 			name: "getmempoolentry",
 			newCmd: func() (interface{}, error) {
@@ -836,6 +1042,17 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"getpeerinfo","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetPeerInfoCmd{},
 		},
+		{
+			name: "getprioritisedtransactions",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("getprioritisedtransactions")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetPrioritisedTransactionsCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"getprioritisedtransactions","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetPrioritisedTransactionsCmd{},
+		},
 		{
 			name: "getrawmempool",
 			newCmd: func() (interface{}, error) {
@@ -958,8 +1175,31 @@ func TestChainSvrCmds(t *testing.T) {
 			staticCmd: func() interface{} {
 				return btcjson.NewGetTxOutSetInfoCmd()
 			},
-			marshalled:   `{"jsonrpc":"1.0","method":"gettxoutsetinfo","params":[],"id":1}`,
-			unmarshalled: &btcjson.GetTxOutSetInfoCmd{},
+			marshalled: `{"jsonrpc":"1.0","method":"gettxoutsetinfo","params":[],"id":1}`,
+			unmarshalled: &btcjson.GetTxOutSetInfoCmd{
+				HashType: btcjson.String("hash_serialized_3"),
+				UseIndex: btcjson.Bool(true),
+			},
+		},
+		{
+			name: "gettxoutsetinfo optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("gettxoutsetinfo", "muhash",
+					btcjson.HashOrHeight{Value: 123}, false)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewGetTxOutSetInfoCmdWithOptions(
+					btcjson.String("muhash"),
+					&btcjson.HashOrHeight{Value: 123},
+					btcjson.Bool(false),
+				)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"gettxoutsetinfo","params":["muhash",123,false],"id":1}`,
+			unmarshalled: &btcjson.GetTxOutSetInfoCmd{
+				HashType:     btcjson.String("muhash"),
+				HashOrHeight: &btcjson.HashOrHeight{Value: 123},
+				UseIndex:     btcjson.Bool(false),
+			},
 		},
 		{
 			name: "getwork",
@@ -1050,6 +1290,21 @@ func TestChainSvrCmds(t *testing.T) {
 				BlockHash: "0123",
 			},
 		},
+		{
+			name: "prioritisetransaction",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("prioritisetransaction", "0123",
+					0.0, -1000)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewPrioritiseTransactionCmd("0123", -1000)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"prioritisetransaction","params":["0123",0,-1000],"id":1}`,
+			unmarshalled: &btcjson.PrioritiseTransactionCmd{
+				TxID:     "0123",
+				FeeDelta: -1000,
+			},
+		},
 		{
 			name: "reconsiderblock",
 			newCmd: func() (interface{}, error) {
@@ -1312,6 +1567,58 @@ func TestChainSvrCmds(t *testing.T) {
 				Message: "Hey",
 			},
 		},
+		{
+			name: "signrawtransactionwithkey",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("signrawtransactionwithkey", "001122", []string{"cV"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSignRawTransactionWithKeyCmd("001122", []string{"cV"}, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"signrawtransactionwithkey","params":["001122",["cV"]],"id":1}`,
+			unmarshalled: &btcjson.SignRawTransactionWithKeyCmd{
+				RawTx:       "001122",
+				PrivKeys:    []string{"cV"},
+				Inputs:      nil,
+				SigHashType: btcjson.String("DEFAULT"),
+			},
+		},
+		{
+			name: "signrawtransactionwithkey optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("signrawtransactionwithkey", "001122", []string{"cV"},
+					`[{"txid":"123","vout":1,"scriptPubKey":"00","witnessScript":"01","amount":0.5}]`,
+					"ALL|ANYONECANPAY")
+			},
+			staticCmd: func() interface{} {
+				txInputs := []btcjson.RawTxWitnessInput{
+					{
+						Txid:          "123",
+						Vout:          1,
+						ScriptPubKey:  "00",
+						WitnessScript: btcjson.String("01"),
+						Amount:        btcjson.Float64(0.5),
+					},
+				}
+				return btcjson.NewSignRawTransactionWithKeyCmd("001122", []string{"cV"},
+					&txInputs, btcjson.String("ALL|ANYONECANPAY"))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"signrawtransactionwithkey","params":["001122",["cV"],[{"txid":"123","vout":1,"scriptPubKey":"00","witnessScript":"01","amount":0.5}],"ALL|ANYONECANPAY"],"id":1}`,
+			unmarshalled: &btcjson.SignRawTransactionWithKeyCmd{
+				RawTx:    "001122",
+				PrivKeys: []string{"cV"},
+				Inputs: &[]btcjson.RawTxWitnessInput{
+					{
+						Txid:          "123",
+						Vout:          1,
+						ScriptPubKey:  "00",
+						WitnessScript: btcjson.String("01"),
+						Amount:        btcjson.Float64(0.5),
+					},
+				},
+				SigHashType: btcjson.String("ALL|ANYONECANPAY"),
+			},
+		},
 		{
 			name: "stop",
 			newCmd: func() (interface{}, error) {
@@ -1356,6 +1663,61 @@ func TestChainSvrCmds(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "submitpackage",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("submitpackage", []string{"rawhex1", "rawhex2"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSubmitPackageCmd([]string{"rawhex1", "rawhex2"}, nil, nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"submitpackage","params":[["rawhex1","rawhex2"]],"id":1}`,
+			unmarshalled: &btcjson.SubmitPackageCmd{
+				RawTxns:       []string{"rawhex1", "rawhex2"},
+				MaxFeeRate:    btcjson.Float64(0.1),
+				MaxBurnAmount: btcjson.Float64(0),
+			},
+		},
+		{
+			name: "submitpackage optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("submitpackage", []string{"rawhex1", "rawhex2"}, 0.5, 0.001)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSubmitPackageCmd([]string{"rawhex1", "rawhex2"},
+					btcjson.Float64(0.5), btcjson.Float64(0.001))
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"submitpackage","params":[["rawhex1","rawhex2"],0.5,0.001],"id":1}`,
+			unmarshalled: &btcjson.SubmitPackageCmd{
+				RawTxns:       []string{"rawhex1", "rawhex2"},
+				MaxFeeRate:    btcjson.Float64(0.5),
+				MaxBurnAmount: btcjson.Float64(0.001),
+			},
+		},
+		{
+			name: "joinpsbts",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("joinpsbts", []string{"cHNidP8B", "cHNidP8C"})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewJoinPsbtsCmd([]string{"cHNidP8B", "cHNidP8C"})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"joinpsbts","params":[["cHNidP8B","cHNidP8C"]],"id":1}`,
+			unmarshalled: &btcjson.JoinPsbtsCmd{
+				Psbts: []string{"cHNidP8B", "cHNidP8C"},
+			},
+		},
+		{
+			name: "utxoupdatepsbt",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("utxoupdatepsbt", "cHNidP8B")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewUtxoUpdatePsbtCmd("cHNidP8B")
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"utxoupdatepsbt","params":["cHNidP8B"],"id":1}`,
+			unmarshalled: &btcjson.UtxoUpdatePsbtCmd{Psbt: "cHNidP8B"},
+		},
 		{
 			name: "uptime",
 			newCmd: func() (interface{}, error) {
@@ -1473,6 +1835,119 @@ func TestChainSvrCmds(t *testing.T) {
 			marshalled:   `{"jsonrpc":"1.0","method":"getzmqnotifications","params":[],"id":1}`,
 			unmarshalled: &btcjson.GetZmqNotificationsCmd{},
 		},
+		{
+			name: "importmempool",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("importmempool", "mempool.dat")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewImportMempoolCmd("mempool.dat", nil)
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importmempool","params":["mempool.dat"],"id":1}`,
+			unmarshalled: &btcjson.ImportMempoolCmd{
+				FilePath: "mempool.dat",
+			},
+		},
+		{
+			name: "importmempool optional",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("importmempool", "mempool.dat",
+					`{"use_current_time":false}`)
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewImportMempoolCmd("mempool.dat",
+					&btcjson.ImportMempoolOptions{
+						UseCurrentTime: btcjson.Bool(false),
+					})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"importmempool","params":["mempool.dat",{"use_current_time":false}],"id":1}`,
+			unmarshalled: &btcjson.ImportMempoolCmd{
+				FilePath: "mempool.dat",
+				Options: &btcjson.ImportMempoolOptions{
+					UseCurrentTime: btcjson.Bool(false),
+				},
+			},
+		},
+		{
+			name: "dumptxoutset",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("dumptxoutset", "utxo.dat")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewDumpTxOutSetCmd("utxo.dat")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"dumptxoutset","params":["utxo.dat"],"id":1}`,
+			unmarshalled: &btcjson.DumpTxOutSetCmd{
+				Path: "utxo.dat",
+			},
+		},
+		{
+			name: "loadtxoutset",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("loadtxoutset", "utxo.dat")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewLoadTxOutSetCmd("utxo.dat")
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"loadtxoutset","params":["utxo.dat"],"id":1}`,
+			unmarshalled: &btcjson.LoadTxOutSetCmd{
+				Path: "utxo.dat",
+			},
+		},
+		{
+			name: "savemempool",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("savemempool")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewSaveMempoolCmd()
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"savemempool","params":[],"id":1}`,
+			unmarshalled: &btcjson.SaveMempoolCmd{},
+		},
+		{
+			name: "scantxoutset status",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("scantxoutset", "status")
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewScanTxOutSetCmd("status", nil)
+			},
+			marshalled:   `{"jsonrpc":"1.0","method":"scantxoutset","params":["status"],"id":1}`,
+			unmarshalled: &btcjson.ScanTxOutSetCmd{Action: "status"},
+		},
+		{
+			name: "scantxoutset start",
+			newCmd: func() (interface{}, error) {
+				return btcjson.NewCmd("scantxoutset", "start", []btcjson.ScanObject{
+					{Desc: "addr(mkmZxiEcEd8ZqjQWVZuC6so5dFMKEFpN2j)"},
+					{
+						Desc:  "pkh(tpub/*)",
+						Range: &btcjson.DescriptorRange{Value: []int{1, 10}},
+					},
+				})
+			},
+			staticCmd: func() interface{} {
+				return btcjson.NewScanTxOutSetCmd("start", &[]btcjson.ScanObject{
+					{Desc: "addr(mkmZxiEcEd8ZqjQWVZuC6so5dFMKEFpN2j)"},
+					{
+						Desc:  "pkh(tpub/*)",
+						Range: &btcjson.DescriptorRange{Value: []int{1, 10}},
+					},
+				})
+			},
+			marshalled: `{"jsonrpc":"1.0","method":"scantxoutset","params":["start",["addr(mkmZxiEcEd8ZqjQWVZuC6so5dFMKEFpN2j)",{"desc":"pkh(tpub/*)","range":[1,10]}]],"id":1}`,
+			unmarshalled: &btcjson.ScanTxOutSetCmd{
+				Action: "start",
+				ScanObjects: &[]btcjson.ScanObject{
+					{Desc: "addr(mkmZxiEcEd8ZqjQWVZuC6so5dFMKEFpN2j)"},
+					{
+						Desc:  "pkh(tpub/*)",
+						Range: &btcjson.DescriptorRange{Value: []int{1, 10}},
+					},
+				},
+			},
+		},
 		{
 			name: "testmempoolaccept",
 			newCmd: func() (interface{}, error) {
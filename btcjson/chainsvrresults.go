@@ -61,9 +61,15 @@ type GetBlockStatsResult struct {
 	TotalOut           int64   `json:"total_out"`
 	TotalSize          int64   `json:"total_size"`
 	TotalWeight        int64   `json:"total_weight"`
+	TotalFee           int64   `json:"totalfee"`
 	Txs                int64   `json:"txs"`
 	UTXOIncrease       int64   `json:"utxo_increase"`
 	UTXOSizeIncrease   int64   `json:"utxo_size_inc"`
+
+	// UTXOIncreaseActual and UTXOSizeIncreaseActual exclude unspendable
+	// outputs, which are never added to the utxo set.
+	UTXOIncreaseActual     int64 `json:"utxo_increase_actual"`
+	UTXOSizeIncreaseActual int64 `json:"utxo_size_inc_actual"`
 }
 
 // GetBlockVerboseResult models the data from the getblock command when the
@@ -249,6 +255,13 @@ type GetBlockFilterResult struct {
 	Header string `json:"header"` // the hex-encoded filter header
 }
 
+// GetIndexInfoResult models the objects included in the getindexinfo response.
+// In the actual result, these objects are keyed by the name of the index.
+type GetIndexInfoResult struct {
+	Synced          bool  `json:"synced"`
+	BestBlockHeight int32 `json:"best_block_height"`
+}
+
 // GetBlockTemplateResultTx models the transactions field of the
 // getblocktemplate command.
 type GetBlockTemplateResultTx struct {
@@ -322,29 +335,76 @@ type MempoolFees struct {
 // GetMempoolEntryResult models the data returned from the getmempoolentry
 // command.
 type GetMempoolEntryResult struct {
-	VSize           int32       `json:"vsize"`
-	Size            int32       `json:"size"`
-	Weight          int64       `json:"weight"`
-	Fee             float64     `json:"fee"`
-	ModifiedFee     float64     `json:"modifiedfee"`
-	Time            int64       `json:"time"`
-	Height          int64       `json:"height"`
-	DescendantCount int64       `json:"descendantcount"`
-	DescendantSize  int64       `json:"descendantsize"`
-	DescendantFees  float64     `json:"descendantfees"`
-	AncestorCount   int64       `json:"ancestorcount"`
-	AncestorSize    int64       `json:"ancestorsize"`
-	AncestorFees    float64     `json:"ancestorfees"`
-	WTxId           string      `json:"wtxid"`
-	Fees            MempoolFees `json:"fees"`
-	Depends         []string    `json:"depends"`
+	VSize             int32       `json:"vsize"`
+	Size              int32       `json:"size"`
+	Weight            int64       `json:"weight"`
+	Fee               float64     `json:"fee"`
+	ModifiedFee       float64     `json:"modifiedfee"`
+	Time              int64       `json:"time"`
+	Height            int64       `json:"height"`
+	DescendantCount   int64       `json:"descendantcount"`
+	DescendantSize    int64       `json:"descendantsize"`
+	DescendantFees    float64     `json:"descendantfees"`
+	AncestorCount     int64       `json:"ancestorcount"`
+	AncestorSize      int64       `json:"ancestorsize"`
+	AncestorFees      float64     `json:"ancestorfees"`
+	WTxId             string      `json:"wtxid"`
+	Fees              MempoolFees `json:"fees"`
+	Depends           []string    `json:"depends"`
+	SpentBy           []string    `json:"spentby"`
+	BIP125Replaceable bool        `json:"bip125-replaceable"`
 }
 
 // GetMempoolInfoResult models the data returned from the getmempoolinfo
 // command.
+//
+// Bytes is the total serialized size of the transactions while Usage is their
+// total virtual size as defined by BIP0141, which is what MaxMempool limits.
+// Unlike Bitcoin Core, Usage does not report the memory used by the mempool.
 type GetMempoolInfoResult struct {
-	Size  int64 `json:"size"`
-	Bytes int64 `json:"bytes"`
+	Loaded        bool    `json:"loaded"`
+	Size          int64   `json:"size"`
+	Bytes         int64   `json:"bytes"`
+	Usage         int64   `json:"usage"`
+	MaxMempool    int64   `json:"maxmempool"`
+	MempoolMinFee float64 `json:"mempoolminfee"`
+	MinRelayTxFee float64 `json:"minrelaytxfee"`
+}
+
+// PrioritisedTransactionResult models the data returned for each transaction
+// from the getprioritisedtransactions command.  ModifiedFee is only set when
+// the transaction is in the mempool.
+type PrioritisedTransactionResult struct {
+	FeeDelta    int64  `json:"fee_delta"`
+	InMempool   bool   `json:"in_mempool"`
+	ModifiedFee *int64 `json:"modified_fee,omitempty"`
+}
+
+// ImportMempoolResult models the data returned from the importmempool
+// command.
+type ImportMempoolResult struct{}
+
+// SaveMempoolResult models the data returned from the savemempool command.
+type SaveMempoolResult struct {
+	FileName string `json:"filename"`
+}
+
+// DumpTxOutSetResult models the data returned from the dumptxoutset command.
+type DumpTxOutSetResult struct {
+	CoinsWritten uint64 `json:"coins_written"`
+	BaseHash     string `json:"base_hash"`
+	BaseHeight   int32  `json:"base_height"`
+	Path         string `json:"path"`
+	TxOutSetHash string `json:"txoutset_hash"`
+	NChainTx     uint64 `json:"nchaintx"`
+}
+
+// LoadTxOutSetResult models the data returned from the loadtxoutset command.
+type LoadTxOutSetResult struct {
+	CoinsLoaded uint64 `json:"coins_loaded"`
+	TipHash     string `json:"tip_hash"`
+	BaseHeight  int32  `json:"base_height"`
+	Path        string `json:"path"`
 }
 
 // NetworksResult models the networks data from the getnetworkinfo command.
@@ -498,15 +558,47 @@ type GetTxOutResult struct {
 }
 
 // GetTxOutSetInfoResult models the data from the gettxoutsetinfo command.
+// Only the hash of the UTXO set of the requested type is set.  The number of
+// transactions and the disk size are not set when the statistics are taken
+// from the coin statistics index.
 type GetTxOutSetInfoResult struct {
-	Height         int64          `json:"height"`
-	BestBlock      chainhash.Hash `json:"bestblock"`
-	Transactions   int64          `json:"transactions"`
-	TxOuts         int64          `json:"txouts"`
-	BogoSize       int64          `json:"bogosize"`
+	Height       int64          `json:"height"`
+	BestBlock    chainhash.Hash `json:"bestblock"`
+	Transactions int64          `json:"transactions,omitempty"`
+	TxOuts       int64          `json:"txouts"`
+	BogoSize     int64          `json:"bogosize"`
+
+	// HashSerialized is the hash_serialized_2 hash of the UTXO set, which
+	// is only returned by versions of Bitcoin Core before 26.0.
 	HashSerialized chainhash.Hash `json:"hash_serialized_2"`
-	DiskSize       int64          `json:"disk_size"`
-	TotalAmount    btcutil.Amount `json:"total_amount"`
+
+	// HashSerialized3 is the hash_serialized_3 hash of the UTXO set.
+	HashSerialized3 *chainhash.Hash `json:"hash_serialized_3,omitempty"`
+
+	MuHash      *chainhash.Hash `json:"muhash,omitempty"`
+	DiskSize    int64           `json:"disk_size,omitempty"`
+	TotalAmount btcutil.Amount  `json:"total_amount"`
+}
+
+// MarshalJSON marshals the result of the gettxoutsetinfo JSON-RPC call with
+// the total amount in BTC.  The hash_serialized_2 hash is left out when it is
+// not set.
+func (g GetTxOutSetInfoResult) MarshalJSON() ([]byte, error) {
+	type Alias GetTxOutSetInfoResult
+
+	var hashSerialized *chainhash.Hash
+	if g.HashSerialized != (chainhash.Hash{}) {
+		hashSerialized = &g.HashSerialized
+	}
+	return json.Marshal(&struct {
+		HashSerialized *chainhash.Hash `json:"hash_serialized_2,omitempty"`
+		TotalAmount    float64         `json:"total_amount"`
+		Alias
+	}{
+		HashSerialized: hashSerialized,
+		TotalAmount:    g.TotalAmount.ToBTC(),
+		Alias:          Alias(g),
+	})
 }
 
 // UnmarshalJSON unmarshals the result of the gettxoutsetinfo JSON-RPC call
@@ -517,9 +609,7 @@ func (g *GetTxOutSetInfoResult) UnmarshalJSON(data []byte) error {
 	// Step 2: Create an anonymous struct with raw replacements for the special
 	// fields.
 	aux := &struct {
-		BestBlock      string  `json:"bestblock"`
-		HashSerialized string  `json:"hash_serialized_2"`
-		TotalAmount    float64 `json:"total_amount"`
+		TotalAmount float64 `json:"total_amount"`
 		*Alias
 	}{
 		Alias: (*Alias)(g),
@@ -531,20 +621,6 @@ func (g *GetTxOutSetInfoResult) UnmarshalJSON(data []byte) error {
 	}
 
 	// Step 4: Convert the raw fields to the desired types
-	blockHash, err := chainhash.NewHashFromStr(aux.BestBlock)
-	if err != nil {
-		return err
-	}
-
-	g.BestBlock = *blockHash
-
-	serializedHash, err := chainhash.NewHashFromStr(aux.HashSerialized)
-	if err != nil {
-		return err
-	}
-
-	g.HashSerialized = *serializedHash
-
 	amount, err := btcutil.NewAmount(aux.TotalAmount)
 	if err != nil {
 		return err
@@ -798,6 +874,14 @@ type SearchRawTransactionsResult struct {
 	Blocktime     int64        `json:"blocktime,omitempty"`
 }
 
+// SignRawTransactionWithKeyResult models the data from the
+// signrawtransactionwithkey command.
+type SignRawTransactionWithKeyResult struct {
+	Hex      string                    `json:"hex"`
+	Complete bool                      `json:"complete"`
+	Errors   []SignRawTransactionError `json:"errors,omitempty"`
+}
+
 // TxRawDecodeResult models the data from the decoderawtransaction command.
 type TxRawDecodeResult struct {
 	Txid     string `json:"txid"`
@@ -889,6 +973,190 @@ type GetDescriptorInfoResult struct {
 // DeriveAddressesResult models the data from the deriveaddresses command.
 type DeriveAddressesResult []string
 
+// ScanTxOutSetUnspent models an unspent transaction output found by the
+// scantxoutset command.
+type ScanTxOutSetUnspent struct {
+	TxID         string  `json:"txid"`
+	Vout         uint32  `json:"vout"`
+	ScriptPubKey string  `json:"scriptPubKey"`
+	Desc         string  `json:"desc"`
+	Amount       float64 `json:"amount"`
+	Coinbase     bool    `json:"coinbase"`
+	Height       int32   `json:"height"`
+}
+
+// ScanTxOutSetResult models the data from the scantxoutset command when a scan
+// is started.
+type ScanTxOutSetResult struct {
+	Success     bool                  `json:"success"`
+	TxOuts      int64                 `json:"txouts"`
+	Height      int32                 `json:"height"`
+	BestBlock   string                `json:"bestblock"`
+	Unspents    []ScanTxOutSetUnspent `json:"unspents"`
+	TotalAmount float64               `json:"total_amount"`
+}
+
+// ScanTxOutSetStatusResult models the data from the scantxoutset command when
+// the status of a running scan is requested.
+type ScanTxOutSetStatusResult struct {
+	Progress int `json:"progress"`
+}
+
+// PsbtScript models a redeem or witness script of a PSBT returned by the
+// decodepsbt command.
+type PsbtScript struct {
+	Asm  string `json:"asm"`
+	Hex  string `json:"hex"`
+	Type string `json:"type"`
+}
+
+// PsbtWitnessUtxo models the witness UTXO of a PSBT input returned by the
+// decodepsbt command.
+type PsbtWitnessUtxo struct {
+	Amount       float64            `json:"amount"`
+	ScriptPubKey ScriptPubKeyResult `json:"scriptPubKey"`
+}
+
+// PsbtBip32Deriv models a BIP0032 derivation path of a public key of a PSBT
+// returned by the decodepsbt command.
+type PsbtBip32Deriv struct {
+	PubKey            string `json:"pubkey"`
+	MasterFingerprint string `json:"master_fingerprint"`
+	Path              string `json:"path"`
+}
+
+// PsbtGlobalXPub models an extended public key of a PSBT returned by the
+// decodepsbt command.
+type PsbtGlobalXPub struct {
+	XPub              string `json:"xpub"`
+	MasterFingerprint string `json:"master_fingerprint"`
+	Path              string `json:"path"`
+}
+
+// PsbtTaprootScriptSig models a taproot script path signature of a PSBT
+// input returned by the decodepsbt command.
+type PsbtTaprootScriptSig struct {
+	PubKey   string `json:"pubkey"`
+	LeafHash string `json:"leaf_hash"`
+	Sig      string `json:"sig"`
+}
+
+// PsbtTaprootScript models a taproot leaf script of a PSBT input returned by
+// the decodepsbt command.
+type PsbtTaprootScript struct {
+	Script        string   `json:"script"`
+	LeafVer       uint8    `json:"leaf_ver"`
+	ControlBlocks []string `json:"control_blocks"`
+}
+
+// PsbtTaprootBip32Deriv models a BIP0032 derivation path of a taproot public
+// key of a PSBT returned by the decodepsbt command.
+type PsbtTaprootBip32Deriv struct {
+	PubKey            string   `json:"pubkey"`
+	MasterFingerprint string   `json:"master_fingerprint"`
+	Path              string   `json:"path"`
+	LeafHashes        []string `json:"leaf_hashes"`
+}
+
+// PsbtTaprootLeaf models a leaf of the taproot tree of a PSBT output returned
+// by the decodepsbt command.
+type PsbtTaprootLeaf struct {
+	Depth   uint8  `json:"depth"`
+	LeafVer uint8  `json:"leaf_ver"`
+	Script  string `json:"script"`
+}
+
+// DecodePsbtInput models an input of a PSBT returned by the decodepsbt
+// command.
+type DecodePsbtInput struct {
+	NonWitnessUtxo         *TxRawDecodeResult      `json:"non_witness_utxo,omitempty"`
+	WitnessUtxo            *PsbtWitnessUtxo        `json:"witness_utxo,omitempty"`
+	PartialSignatures      map[string]string       `json:"partial_signatures,omitempty"`
+	Sighash                string                  `json:"sighash,omitempty"`
+	RedeemScript           *PsbtScript             `json:"redeem_script,omitempty"`
+	WitnessScript          *PsbtScript             `json:"witness_script,omitempty"`
+	Bip32Derivs            []PsbtBip32Deriv        `json:"bip32_derivs,omitempty"`
+	FinalScriptSig         *ScriptSig              `json:"final_scriptSig,omitempty"`
+	FinalScriptWitness     []string                `json:"final_scriptwitness,omitempty"`
+	TaprootKeyPathSig      string                  `json:"taproot_key_path_sig,omitempty"`
+	TaprootScriptPathSigs  []PsbtTaprootScriptSig  `json:"taproot_script_path_sigs,omitempty"`
+	TaprootScripts         []PsbtTaprootScript     `json:"taproot_scripts,omitempty"`
+	TaprootBip32Derivs     []PsbtTaprootBip32Deriv `json:"taproot_bip32_derivs,omitempty"`
+	TaprootInternalKey     string                  `json:"taproot_internal_key,omitempty"`
+	TaprootMerkleRoot      string                  `json:"taproot_merkle_root,omitempty"`
+	RequiredTimeLockTime   uint32                  `json:"required_time_locktime,omitempty"`
+	RequiredHeightLockTime uint32                  `json:"required_height_locktime,omitempty"`
+	Unknown                map[string]string       `json:"unknown,omitempty"`
+}
+
+// DecodePsbtOutput models an output of a PSBT returned by the decodepsbt
+// command.
+type DecodePsbtOutput struct {
+	RedeemScript       *PsbtScript             `json:"redeem_script,omitempty"`
+	WitnessScript      *PsbtScript             `json:"witness_script,omitempty"`
+	Bip32Derivs        []PsbtBip32Deriv        `json:"bip32_derivs,omitempty"`
+	TaprootInternalKey string                  `json:"taproot_internal_key,omitempty"`
+	TaprootTree        []PsbtTaprootLeaf       `json:"taproot_tree,omitempty"`
+	TaprootBip32Derivs []PsbtTaprootBip32Deriv `json:"taproot_bip32_derivs,omitempty"`
+	Unknown            map[string]string       `json:"unknown,omitempty"`
+}
+
+// DecodePsbtResult models the data from the decodepsbt command.  The fee is
+// only set when the UTXOs of all inputs are known, and the fallback lock time
+// and modifiable flags only exist in version 2 PSBTs.
+type DecodePsbtResult struct {
+	Tx               TxRawDecodeResult  `json:"tx"`
+	GlobalXPubs      []PsbtGlobalXPub   `json:"global_xpubs"`
+	PsbtVersion      uint32             `json:"psbt_version"`
+	FallbackLockTime *uint32            `json:"fallback_locktime,omitempty"`
+	TxModifiable     *uint8             `json:"tx_modifiable,omitempty"`
+	Unknown          map[string]string  `json:"unknown"`
+	Inputs           []DecodePsbtInput  `json:"inputs"`
+	Outputs          []DecodePsbtOutput `json:"outputs"`
+	Fee              *float64           `json:"fee,omitempty"`
+}
+
+// AnalyzePsbtMissing models the data an input of a PSBT is missing before it
+// can be finalized, as returned by the analyzepsbt command.  Public keys and
+// signatures are identified by the hash160 of the public key, a redeem script
+// by its hash160 and a witness script by its sha256.
+type AnalyzePsbtMissing struct {
+	PubKeys       []string `json:"pubkeys,omitempty"`
+	Signatures    []string `json:"signatures,omitempty"`
+	RedeemScript  string   `json:"redeemscript,omitempty"`
+	WitnessScript string   `json:"witnessscript,omitempty"`
+}
+
+// AnalyzePsbtInput models the analysis of an input of a PSBT returned by the
+// analyzepsbt command.
+type AnalyzePsbtInput struct {
+	HasUtxo bool                `json:"has_utxo"`
+	IsFinal bool                `json:"is_final"`
+	Missing *AnalyzePsbtMissing `json:"missing,omitempty"`
+	Next    string              `json:"next,omitempty"`
+}
+
+// AnalyzePsbtResult models the data from the analyzepsbt command.  The size,
+// fee rate and fee are only estimated when the UTXOs of all inputs are known
+// and all inputs can be signed for.
+type AnalyzePsbtResult struct {
+	Inputs           []AnalyzePsbtInput `json:"inputs,omitempty"`
+	EstimatedVSize   *int64             `json:"estimated_vsize,omitempty"`
+	EstimatedFeeRate *float64           `json:"estimated_feerate,omitempty"` // In BTC/kvB
+	Fee              *float64           `json:"fee,omitempty"`
+	Next             string             `json:"next"`
+	Error            string             `json:"error,omitempty"`
+}
+
+// FinalizePsbtResult models the data from the finalizepsbt command.  The
+// PSBT is only returned when it isn't extracted, and the transaction is only
+// returned when it is complete and extracted.
+type FinalizePsbtResult struct {
+	Psbt     string `json:"psbt,omitempty"`
+	Hex      string `json:"hex,omitempty"`
+	Complete bool   `json:"complete"`
+}
+
 // LoadWalletResult models the data from the loadwallet command
 type LoadWalletResult struct {
 	Name    string `json:"name"`
@@ -956,6 +1224,46 @@ type TestMempoolAcceptFees struct {
 	EffectiveIncludes []string `json:"effective-includes"`
 }
 
+// SubmitPackageResult models the data from the submitpackage command.
+type SubmitPackageResult struct {
+	// PackageMsg is "success" when every transaction of the package was
+	// accepted to or already in the mempool, and "transaction failed"
+	// otherwise.
+	PackageMsg string `json:"package_msg"`
+
+	// TxResults holds the result of each transaction of the package,
+	// keyed by its wtxid in hex.
+	TxResults map[string]SubmitPackageTxResult `json:"tx-results"`
+
+	// ReplacedTransactions holds the txids in hex of the transactions
+	// removed from the mempool since they were replaced by transactions
+	// of the package.
+	ReplacedTransactions []string `json:"replaced-transactions"`
+}
+
+// SubmitPackageTxResult models the result of a single transaction from the
+// submitpackage command.
+type SubmitPackageTxResult struct {
+	// Txid is the transaction hash in hex.
+	Txid string `json:"txid"`
+
+	// OtherWtxid is the wtxid in hex of a different transaction with the
+	// same txid that is already in the mempool, if any.
+	OtherWtxid string `json:"other-wtxid,omitempty"`
+
+	// Vsize is the virtual transaction size as defined in BIP 141 (only
+	// present when the transaction is in the mempool).
+	Vsize int32 `json:"vsize,omitempty"`
+
+	// Fees specifies the transaction fees (only present when the
+	// transaction is in the mempool).
+	Fees *TestMempoolAcceptFees `json:"fees,omitempty"`
+
+	// Error is the reason the transaction was rejected (only present when
+	// the transaction is not in the mempool).
+	Error string `json:"error,omitempty"`
+}
+
 // GetTxSpendingPrevOutResult defines a single item returned from the
 // gettxspendingprevout command.
 type GetTxSpendingPrevOutResult struct {
@@ -125,3 +125,74 @@ func TestGetWalletInfoResult(t *testing.T) {
 		}
 	}
 }
+
+// TestSignRawTransactionResult ensures the errors of the signrawtransaction
+// results only include the witness of the input when it has one and survive a
+// marshalling round trip.
+func TestSignRawTransactionResult(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		result   SignRawTransactionResult
+		expected string
+	}{
+		{
+			name: "error without witness",
+			result: SignRawTransactionResult{
+				Hex: "001122",
+				Errors: []SignRawTransactionError{{
+					TxID:      "123",
+					Vout:      1,
+					ScriptSig: "00",
+					Sequence:  4294967295,
+					Error:     "Operation not valid with the current stack size",
+				}},
+			},
+			expected: `{"hex":"001122","complete":false,"errors":[{"txid":"123","vout":1,"scriptSig":"00","sequence":4294967295,"error":"Operation not valid with the current stack size"}]}`,
+		},
+		{
+			name: "error with witness",
+			result: SignRawTransactionResult{
+				Hex: "001122",
+				Errors: []SignRawTransactionError{{
+					TxID:      "123",
+					Vout:      1,
+					Witness:   []string{"", "3044", "5221"},
+					ScriptSig: "",
+					Sequence:  4294967295,
+					Error:     "Signature must be zero for failed CHECK(MULTI)SIG operation",
+				}},
+			},
+			expected: `{"hex":"001122","complete":false,"errors":[{"txid":"123","vout":1,"witness":["","3044","5221"],"scriptSig":"","sequence":4294967295,"error":"Signature must be zero for failed CHECK(MULTI)SIG operation"}]}`,
+		},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		marshalled, err := json.Marshal(test.result)
+		if err != nil {
+			t.Errorf("Test #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+		if string(marshalled) != test.expected {
+			t.Errorf("Test #%d (%s) unexpected marshalled data - "+
+				"got %s, want %s", i, test.name, marshalled,
+				test.expected)
+			continue
+		}
+
+		var out SignRawTransactionResult
+		if err := json.Unmarshal(marshalled, &out); err != nil {
+			t.Errorf("Test #%d (%s) unexpected error: %v", i,
+				test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(out, test.result) {
+			t.Errorf("Test #%d (%s) unexpected unmarshalled data - "+
+				"got %v, want %v", i, test.name, spew.Sdump(out),
+				spew.Sdump(test.result))
+		}
+	}
+}
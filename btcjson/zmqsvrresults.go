@@ -13,7 +13,7 @@ type GetZmqNotificationResult []struct {
 }
 
 func (z *GetZmqNotificationResult) MarshalJSON() ([]byte, error) {
-	var out []map[string]interface{}
+	out := make([]map[string]interface{}, 0, len(*z))
 	for _, notif := range *z {
 		out = append(out,
 			map[string]interface{}{
@@ -0,0 +1,241 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bip39
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/bynil/btcd/btcutil/hdkeychain"
+	"github.com/bynil/btcd/chaincfg"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// MinEntropyBits is the minimum number of bits of entropy encoded by a
+	// mnemonic, which is encoded by 12 words.
+	MinEntropyBits = 128
+
+	// MaxEntropyBits is the maximum number of bits of entropy encoded by a
+	// mnemonic, which is encoded by 24 words.
+	MaxEntropyBits = 256
+
+	// SeedLen is the length in bytes of the seeds derived from mnemonics.
+	SeedLen = 64 // 512 bits
+
+	// bitsPerWord is the number of bits encoded by each word of a
+	// mnemonic.
+	bitsPerWord = 11
+
+	// seedIterations is the number of PBKDF2 iterations used to derive
+	// seeds from mnemonics.
+	seedIterations = 2048
+
+	// seedSaltPrefix is the prefix of the passphrase forming the PBKDF2
+	// salt used to derive seeds from mnemonics.
+	seedSaltPrefix = "mnemonic"
+)
+
+var (
+	// ErrInvalidEntropyLength describes an error in which the entropy
+	// isn't between 128 and 256 bits long or isn't a multiple of 32 bits.
+	ErrInvalidEntropyLength = errors.New("entropy must be between 128 " +
+		"and 256 bits long and a multiple of 32 bits")
+
+	// ErrInvalidMnemonicLength describes an error in which a mnemonic
+	// doesn't have 12, 15, 18, 21 or 24 words.
+	ErrInvalidMnemonicLength = errors.New("mnemonic must have 12, 15, " +
+		"18, 21 or 24 words")
+
+	// ErrUnknownWord describes an error in which a word of a mnemonic
+	// isn't in the wordlist.
+	ErrUnknownWord = errors.New("word is not in the wordlist")
+
+	// ErrInvalidChecksum describes an error in which the checksum encoded
+	// by the last word of a mnemonic doesn't match its entropy.
+	ErrInvalidChecksum = errors.New("invalid mnemonic checksum")
+)
+
+// isSeparator returns whether the passed rune separates the words of a
+// mnemonic, which is the case for all white space including the ideographic
+// space used by Japanese mnemonics.
+func isSeparator(r rune) bool {
+	return unicode.IsSpace(r)
+}
+
+// splitMnemonic returns the NFKD normalized words of the passed mnemonic.
+func splitMnemonic(mnemonic string) []string {
+	return strings.FieldsFunc(norm.NFKD.String(mnemonic), isSeparator)
+}
+
+// checksumBits returns the number of checksum bits appended to entropy of
+// the passed length in bytes, which is one bit per 32 bits of entropy.
+func checksumBits(entropyLen int) int {
+	return entropyLen * 8 / 32
+}
+
+// validEntropyLen returns whether entropy of the passed length in bytes can
+// be encoded in a mnemonic.
+func validEntropyLen(entropyLen int) bool {
+	bits := entropyLen * 8
+	return bits >= MinEntropyBits && bits <= MaxEntropyBits && bits%32 == 0
+}
+
+// NewEntropy returns cryptographically secure random entropy with the passed
+// number of bits, which must be between 128 and 256 and a multiple of 32.
+// The entropy is encoded in a mnemonic with NewMnemonic.
+func NewEntropy(bits int) ([]byte, error) {
+	if bits%8 != 0 || !validEntropyLen(bits/8) {
+		return nil, ErrInvalidEntropyLength
+	}
+
+	entropy := make([]byte, bits/8)
+	if _, err := rand.Read(entropy); err != nil {
+		return nil, err
+	}
+	return entropy, nil
+}
+
+// NewMnemonic returns the mnemonic encoding the passed entropy with words of
+// the passed wordlist.  The entropy must be between 128 and 256 bits long and
+// a multiple of 32 bits, and is encoded along with a checksum in 12 to 24
+// words.
+func NewMnemonic(entropy []byte, wordlist *Wordlist) (string, error) {
+	if !validEntropyLen(len(entropy)) {
+		return "", ErrInvalidEntropyLength
+	}
+
+	// The checksum is the first bits of the SHA256 hash of the entropy,
+	// and is appended to it.  Every 11 bits of the result then encode a
+	// word.
+	checksum := sha256.Sum256(entropy)
+	data := append(append([]byte{}, entropy...), checksum[0])
+	numWords := (len(entropy)*8 + checksumBits(len(entropy))) / bitsPerWord
+
+	words := make([]string, numWords)
+	for i := range words {
+		var index int
+		for bit := i * bitsPerWord; bit < (i+1)*bitsPerWord; bit++ {
+			b := data[bit/8] >> (7 - bit%8) & 1
+			index = index<<1 | int(b)
+		}
+		words[i] = wordlist.Word(index)
+	}
+	return strings.Join(words, wordlist.separator), nil
+}
+
+// EntropyFromMnemonic returns the entropy encoded by the passed mnemonic
+// using words of the passed wordlist.  An error is returned when the mnemonic
+// doesn't have a valid number of words, when a word isn't in the wordlist, or
+// when the checksum doesn't match.
+func EntropyFromMnemonic(mnemonic string, wordlist *Wordlist) ([]byte, error) {
+	words := splitMnemonic(mnemonic)
+	if len(words)%3 != 0 || len(words) < MinEntropyBits/32*3 ||
+		len(words) > MaxEntropyBits/32*3 {
+
+		return nil, ErrInvalidMnemonicLength
+	}
+
+	// Every word encodes 11 bits of the entropy followed by its checksum,
+	// which has one bit per three words.
+	totalBits := len(words) * bitsPerWord
+	data := make([]byte, (totalBits+7)/8)
+	for i, word := range words {
+		index, ok := wordlist.Index(word)
+		if !ok {
+			return nil, fmt.Errorf("%w: '%s'", ErrUnknownWord, word)
+		}
+		for j := 0; j < bitsPerWord; j++ {
+			bit := i*bitsPerWord + j
+			b := byte(index>>(bitsPerWord-1-j)) & 1
+			data[bit/8] |= b << (7 - bit%8)
+		}
+	}
+
+	csBits := len(words) / 3
+	entropy := data[:(totalBits-csBits)/8]
+	checksum := sha256.Sum256(entropy)
+	mask := byte(0xff) << (8 - csBits)
+	if checksum[0]&mask != data[len(entropy)]&mask {
+		return nil, ErrInvalidChecksum
+	}
+	return entropy, nil
+}
+
+// ValidateMnemonic returns an error when the passed mnemonic isn't a valid
+// mnemonic using words of the passed wordlist, as described by
+// EntropyFromMnemonic.
+func ValidateMnemonic(mnemonic string, wordlist *Wordlist) error {
+	_, err := EntropyFromMnemonic(mnemonic, wordlist)
+	return err
+}
+
+// DetectWordlist returns the first bundled wordlist, in the order returned
+// by Wordlists, which contains every word of the passed mnemonic.  Since the
+// simplified and traditional Chinese wordlists share many words, mnemonics
+// using only shared words are detected as simplified Chinese ones.
+func DetectWordlist(mnemonic string) (*Wordlist, error) {
+	words := splitMnemonic(mnemonic)
+	if len(words) == 0 {
+		return nil, ErrInvalidMnemonicLength
+	}
+
+next:
+	for _, wordlist := range Wordlists() {
+		for _, word := range words {
+			if _, ok := wordlist.Index(word); !ok {
+				continue next
+			}
+		}
+		return wordlist, nil
+	}
+	return nil, fmt.Errorf("%w: no wordlist contains every word of the "+
+		"mnemonic", ErrUnknownWord)
+}
+
+// NewSeed returns the 64 byte seed derived from the passed mnemonic and
+// passphrase as described by BIP0039, using PBKDF2 with HMAC-SHA512 and 2048
+// iterations.  The mnemonic and passphrase are normalized to NFKD first.
+//
+// The mnemonic isn't validated, since BIP0039 allows deriving seeds from any
+// sentence.  Use NewSeedWithChecksum to only derive seeds from valid
+// mnemonics.
+func NewSeed(mnemonic, passphrase string) []byte {
+	password := []byte(norm.NFKD.String(mnemonic))
+	salt := []byte(norm.NFKD.String(seedSaltPrefix + passphrase))
+	return pbkdf2.Key(password, salt, seedIterations, SeedLen, sha512.New)
+}
+
+// NewSeedWithChecksum returns the 64 byte seed derived from the passed
+// mnemonic and passphrase like NewSeed, but returns an error when the
+// mnemonic isn't a valid mnemonic using words of the passed wordlist.
+func NewSeedWithChecksum(mnemonic, passphrase string,
+	wordlist *Wordlist) ([]byte, error) {
+
+	if err := ValidateMnemonic(mnemonic, wordlist); err != nil {
+		return nil, err
+	}
+	return NewSeed(mnemonic, passphrase), nil
+}
+
+// NewMaster returns the master extended private key for the passed network
+// derived from the seed of the passed mnemonic and passphrase.  An error is
+// returned when the mnemonic isn't a valid mnemonic using words of the passed
+// wordlist.
+func NewMaster(mnemonic, passphrase string, wordlist *Wordlist,
+	net *chaincfg.Params) (*hdkeychain.ExtendedKey, error) {
+
+	seed, err := NewSeedWithChecksum(mnemonic, passphrase, wordlist)
+	if err != nil {
+		return nil, err
+	}
+	return hdkeychain.NewMaster(seed, net)
+}
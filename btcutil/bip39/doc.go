@@ -0,0 +1,41 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package bip39 provides an API for mnemonic codes of hierarchical deterministic
+wallet seeds (BIP0039).
+
+# Overview
+
+A mnemonic is a sentence of 12 to 24 words encoding 128 to 256 bits of entropy
+along with a checksum, which is easier to write down and read back than the
+seed of a wallet.  The seed of the wallet is then derived from the mnemonic
+and an optional passphrase.
+
+# Mnemonics
+
+Random entropy is created with the NewEntropy function and encoded in a
+mnemonic with the NewMnemonic function.  The EntropyFromMnemonic function
+decodes the entropy back and validates the checksum, and the ValidateMnemonic
+function only validates mnemonics.
+
+# Wordlists
+
+Mnemonics use the words of a wordlist.  The official wordlists of BIP0039 for
+English, Chinese (Simplified and Traditional), Czech, French, Italian,
+Japanese, Korean and Spanish are provided, and other wordlists can be created
+with the NewWordlist function.  The DetectWordlist function returns the
+wordlist used by a mnemonic when it isn't known.
+
+Words are matched after being normalized to NFKD, and mnemonics and
+passphrases are normalized to NFKD before seeds are derived from them.
+
+# Seeds and Master Keys
+
+The NewSeed function derives the 64 byte seed of a mnemonic and passphrase,
+which is passed to hdkeychain.NewMaster to create the master node of a wallet.
+The NewMaster function of this package validates a mnemonic and directly
+returns the master node of its wallet for a network.
+*/
+package bip39
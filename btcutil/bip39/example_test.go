@@ -0,0 +1,76 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bip39_test
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bynil/btcd/btcutil/bip39"
+	"github.com/bynil/btcd/btcutil/hdkeychain"
+	"github.com/bynil/btcd/chaincfg"
+)
+
+// This example demonstrates how to create a new mnemonic from random entropy.
+func ExampleNewMnemonic() {
+	// Generate random entropy encoded by 24 words.
+	entropy, err := bip39.NewEntropy(bip39.MaxEntropyBits)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	mnemonic, err := bip39.NewMnemonic(entropy, bip39.English)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	// Show the number of words of the mnemonic, which would be written
+	// down by the user.
+	fmt.Println("Words:", len(strings.Fields(mnemonic)))
+
+	// Output:
+	// Words: 24
+}
+
+// This example demonstrates how to derive the master node of the wallet of a
+// mnemonic, and then the first receiving address of its first BIP0084
+// account.
+func ExampleNewMaster() {
+	mnemonic := "abandon abandon abandon abandon abandon abandon " +
+		"abandon abandon abandon abandon abandon about"
+
+	net := &chaincfg.MainNetParams
+	master, err := bip39.NewMaster(mnemonic, "", bip39.English, net)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	account, origin, err := hdkeychain.DeriveAccount(master,
+		hdkeychain.PurposeBIP84, 0, net)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	key, err := account.DerivePath(hdkeychain.Path{0, 0})
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+	addr, err := key.PurposeAddress(hdkeychain.PurposeBIP84, net)
+	if err != nil {
+		fmt.Println(err)
+		return
+	}
+
+	fmt.Println("Account:", origin)
+	fmt.Println("Address:", addr)
+
+	// Output:
+	// Account: [73c5da0a/84'/0'/0']
+	// Address: bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu
+}
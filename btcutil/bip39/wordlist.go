@@ -0,0 +1,124 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bip39
+
+import (
+	"embed"
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// wordlistLen is the number of words of a wordlist, which is the number of
+// values of the 11 bits each word of a mnemonic encodes.
+const wordlistLen = 1 << bitsPerWord
+
+// The official wordlists of BIP0039, one word per line, as published at
+// https://github.com/bitcoin/bips/tree/master/bip-0039.
+//
+//go:embed wordlists/*.txt
+var wordlistFiles embed.FS
+
+// Wordlist is a list of 2048 words used to encode mnemonics, along with the
+// separator joining the words of a mnemonic.
+type Wordlist struct {
+	language  string
+	separator string
+	words     []string
+	indexes   map[string]uint16
+}
+
+// NewWordlist returns a wordlist for the named language from the passed
+// 2048 unique words.  Mnemonics created with the wordlist join their words
+// with the passed separator, which is a space for most languages.
+//
+// Words are looked up after being normalized to NFKD, so the same words are
+// matched whichever normalization form they are written in.
+func NewWordlist(language string, words []string, separator string) (*Wordlist, error) {
+	if len(words) != wordlistLen {
+		return nil, fmt.Errorf("wordlist has %d words instead of %d",
+			len(words), wordlistLen)
+	}
+
+	w := &Wordlist{
+		language:  language,
+		separator: separator,
+		words:     make([]string, wordlistLen),
+		indexes:   make(map[string]uint16, wordlistLen),
+	}
+	for i, word := range words {
+		word = norm.NFKD.String(word)
+		if word == "" || strings.ContainsFunc(word, isSeparator) {
+			return nil, fmt.Errorf("invalid word '%s' in wordlist",
+				word)
+		}
+		if _, ok := w.indexes[word]; ok {
+			return nil, fmt.Errorf("duplicate word '%s' in wordlist",
+				word)
+		}
+		w.words[i] = word
+		w.indexes[word] = uint16(i)
+	}
+	return w, nil
+}
+
+// Language returns the language of the wordlist.
+func (w *Wordlist) Language() string {
+	return w.language
+}
+
+// Word returns the word at the passed index of the wordlist, which must be
+// less than 2048.
+func (w *Wordlist) Word(index int) string {
+	return w.words[index]
+}
+
+// Index returns the index of the passed word in the wordlist, and whether
+// the word is in the wordlist at all.
+func (w *Wordlist) Index(word string) (int, bool) {
+	index, ok := w.indexes[norm.NFKD.String(word)]
+	return int(index), ok
+}
+
+// mustLoadWordlist returns the embedded official wordlist with the passed
+// file name, and panics when it isn't valid.
+func mustLoadWordlist(language, name, separator string) *Wordlist {
+	data, err := wordlistFiles.ReadFile("wordlists/" + name + ".txt")
+	if err != nil {
+		panic(err)
+	}
+	words := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	w, err := NewWordlist(language, words, separator)
+	if err != nil {
+		panic(fmt.Sprintf("%s wordlist: %v", language, err))
+	}
+	return w
+}
+
+// The official wordlists of BIP0039.
+var (
+	ChineseSimplified  = mustLoadWordlist("Chinese (Simplified)", "chinese_simplified", " ")
+	ChineseTraditional = mustLoadWordlist("Chinese (Traditional)", "chinese_traditional", " ")
+	Czech              = mustLoadWordlist("Czech", "czech", " ")
+	English            = mustLoadWordlist("English", "english", " ")
+	French             = mustLoadWordlist("French", "french", " ")
+	Italian            = mustLoadWordlist("Italian", "italian", " ")
+	Korean             = mustLoadWordlist("Korean", "korean", " ")
+	Spanish            = mustLoadWordlist("Spanish", "spanish", " ")
+
+	// Japanese mnemonics are joined with ideographic spaces (U+3000)
+	// as recommended by BIP0039.
+	Japanese = mustLoadWordlist("Japanese", "japanese", "\u3000")
+)
+
+// Wordlists returns the official wordlists of BIP0039 bundled with the
+// package.
+func Wordlists() []*Wordlist {
+	return []*Wordlist{
+		English, ChineseSimplified, ChineseTraditional, Czech, French,
+		Italian, Japanese, Korean, Spanish,
+	}
+}
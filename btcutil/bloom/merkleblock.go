@@ -5,6 +5,9 @@
 package bloom
 
 import (
+	"errors"
+	"fmt"
+
 	"github.com/bynil/btcd/blockchain"
 	"github.com/bynil/btcd/btcutil"
 	"github.com/bynil/btcd/chaincfg/chainhash"
@@ -78,9 +81,12 @@ func (m *merkleBlock) traverseAndBuild(height, pos uint32) {
 	}
 }
 
-// NewMerkleBlock returns a new *wire.MsgMerkleBlock and an array of the matched
-// transaction index numbers based on the passed block and filter.
-func NewMerkleBlock(block *btcutil.Block, filter *Filter) (*wire.MsgMerkleBlock, []uint32) {
+// newMerkleBlock returns a new *wire.MsgMerkleBlock and an array of the
+// matched transaction index numbers based on the passed block and match
+// function.
+func newMerkleBlock(block *btcutil.Block,
+	match func(*btcutil.Tx) bool) (*wire.MsgMerkleBlock, []uint32) {
+
 	numTx := uint32(len(block.Transactions()))
 	mBlock := merkleBlock{
 		numTx:       numTx,
@@ -88,10 +94,10 @@ func NewMerkleBlock(block *btcutil.Block, filter *Filter) (*wire.MsgMerkleBlock,
 		matchedBits: make([]byte, 0, numTx),
 	}
 
-	// Find and keep track of any transactions that match the filter.
+	// Find and keep track of any transactions that match.
 	var matchedIndices []uint32
 	for txIndex, tx := range block.Transactions() {
-		if filter.MatchTxAndUpdate(tx) {
+		if match(tx) {
 			mBlock.matchedBits = append(mBlock.matchedBits, 0x01)
 			matchedIndices = append(matchedIndices, uint32(txIndex))
 		} else {
@@ -124,3 +130,160 @@ func NewMerkleBlock(block *btcutil.Block, filter *Filter) (*wire.MsgMerkleBlock,
 	}
 	return &msgMerkleBlock, matchedIndices
 }
+
+// NewMerkleBlock returns a new *wire.MsgMerkleBlock and an array of the matched
+// transaction index numbers based on the passed block and filter.
+func NewMerkleBlock(block *btcutil.Block, filter *Filter) (*wire.MsgMerkleBlock, []uint32) {
+	return newMerkleBlock(block, filter.MatchTxAndUpdate)
+}
+
+// NewMerkleBlockWithTxHashes returns a new *wire.MsgMerkleBlock which proves
+// the inclusion of the transactions with the passed hashes in the block along
+// with an array of the matched transaction index numbers.  This is the format
+// of the proofs produced by the gettxoutproof RPC.
+func NewMerkleBlockWithTxHashes(block *btcutil.Block,
+	txHashes []*chainhash.Hash) (*wire.MsgMerkleBlock, []uint32) {
+
+	matches := make(map[chainhash.Hash]struct{}, len(txHashes))
+	for _, hash := range txHashes {
+		matches[*hash] = struct{}{}
+	}
+	return newMerkleBlock(block, func(tx *btcutil.Tx) bool {
+		_, ok := matches[*tx.Hash()]
+		return ok
+	})
+}
+
+// maxMerkleBlockTxns is the maximum number of transactions a merkle block may
+// claim the block has.  It is the maximum block weight divided by the weight
+// of the smallest possible transaction.
+const maxMerkleBlockTxns = blockchain.MaxBlockWeight /
+	(blockchain.WitnessScaleFactor * 60)
+
+// merkleBlockExtractor houses the state needed to extract the matched
+// transactions from a partial merkle tree.
+type merkleBlockExtractor struct {
+	numTx          uint32
+	hashes         []*chainhash.Hash
+	flags          []byte
+	bitsUsed       uint32
+	hashesUsed     uint32
+	matchedHashes  []*chainhash.Hash
+	matchedIndices []uint32
+}
+
+// calcTreeWidth calculates and returns the number of nodes (width) or a
+// merkle tree at the given depth-first height.
+func (m *merkleBlockExtractor) calcTreeWidth(height uint32) uint32 {
+	return (m.numTx + (1 << height) - 1) >> height
+}
+
+// traverseAndExtract recursively walks the partial merkle tree in the same
+// depth-first order it was built, recording the matched leaves, and returns
+// the hash of the sub-tree at the given height and position.
+func (m *merkleBlockExtractor) traverseAndExtract(height,
+	pos uint32) (*chainhash.Hash, error) {
+
+	if m.bitsUsed >= uint32(len(m.flags))*8 {
+		return nil, errors.New("merkle block overflowed the flag bits")
+	}
+	isParent := m.flags[m.bitsUsed/8]&(1<<(m.bitsUsed%8)) != 0
+	m.bitsUsed++
+
+	// Leaves and nodes which are not a parent of a matched leaf consume the
+	// next hash.
+	if height == 0 || !isParent {
+		if m.hashesUsed >= uint32(len(m.hashes)) {
+			return nil, errors.New("merkle block overflowed the hashes")
+		}
+		hash := m.hashes[m.hashesUsed]
+		m.hashesUsed++
+
+		if height == 0 && isParent {
+			m.matchedHashes = append(m.matchedHashes, hash)
+			m.matchedIndices = append(m.matchedIndices, pos)
+		}
+		return hash, nil
+	}
+
+	// Otherwise, descend into the children.
+	left, err := m.traverseAndExtract(height-1, pos*2)
+	if err != nil {
+		return nil, err
+	}
+	right := left
+	if pos*2+1 < m.calcTreeWidth(height-1) {
+		right, err = m.traverseAndExtract(height-1, pos*2+1)
+		if err != nil {
+			return nil, err
+		}
+
+		// Identical children would allow the same root to be committed
+		// to by different transaction lists (CVE-2012-2459).
+		if right.IsEqual(left) {
+			return nil, errors.New("merkle block has identical " +
+				"sibling hashes")
+		}
+	}
+	res := blockchain.HashMerkleBranches(left, right)
+	return &res, nil
+}
+
+// ExtractMatches verifies the partial merkle tree of the passed merkle block
+// against the merkle root in its header and returns the hashes and index
+// numbers of the transactions it proves are included in the block.  An error
+// is returned when the merkle block is malformed or does not commit to the
+// merkle root of the header.
+func ExtractMatches(mBlock *wire.MsgMerkleBlock) ([]*chainhash.Hash, []uint32, error) {
+	numTx := mBlock.Transactions
+	switch {
+	case numTx == 0:
+		return nil, nil, errors.New("merkle block has no transactions")
+
+	case numTx > maxMerkleBlockTxns:
+		return nil, nil, fmt.Errorf("merkle block claims %d "+
+			"transactions which is more than the max of %d", numTx,
+			maxMerkleBlockTxns)
+
+	case uint32(len(mBlock.Hashes)) > numTx:
+		return nil, nil, fmt.Errorf("merkle block has %d hashes for "+
+			"%d transactions", len(mBlock.Hashes), numTx)
+
+	case len(mBlock.Flags)*8 < len(mBlock.Hashes):
+		return nil, nil, fmt.Errorf("merkle block has %d flag bits "+
+			"for %d hashes", len(mBlock.Flags)*8, len(mBlock.Hashes))
+	}
+
+	m := merkleBlockExtractor{
+		numTx:  numTx,
+		hashes: mBlock.Hashes,
+		flags:  mBlock.Flags,
+	}
+
+	// Calculate the number of merkle branches (height) in the tree.
+	height := uint32(0)
+	for m.calcTreeWidth(height) > 1 {
+		height++
+	}
+
+	root, err := m.traverseAndExtract(height, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// All of the flag bytes and hashes must have been consumed.
+	if (m.bitsUsed+7)/8 != uint32(len(m.flags)) {
+		return nil, nil, errors.New("merkle block has unused flag bytes")
+	}
+	if m.hashesUsed != uint32(len(m.hashes)) {
+		return nil, nil, errors.New("merkle block has unused hashes")
+	}
+
+	if !root.IsEqual(&mBlock.Header.MerkleRoot) {
+		return nil, nil, fmt.Errorf("merkle block root %v does not "+
+			"match the header merkle root %v", root,
+			mBlock.Header.MerkleRoot)
+	}
+
+	return m.matchedHashes, m.matchedIndices, nil
+}
@@ -7,8 +7,10 @@ package bloom_test
 import (
 	"bytes"
 	"encoding/hex"
+	"reflect"
 	"testing"
 
+	"github.com/bynil/btcd/blockchain"
 	"github.com/bynil/btcd/btcutil"
 	"github.com/bynil/btcd/btcutil/bloom"
 	"github.com/bynil/btcd/chaincfg/chainhash"
@@ -72,3 +74,147 @@ func TestMerkleBlock3(t *testing.T) {
 		return
 	}
 }
+
+// merkleTestBlock returns a block with the passed number of unique
+// transactions and a valid merkle root.
+func merkleTestBlock(numTxns int) *btcutil.Block {
+	var msgBlock wire.MsgBlock
+	for i := 0; i < numTxns; i++ {
+		tx := wire.NewMsgTx(wire.TxVersion)
+		tx.AddTxIn(wire.NewTxIn(&wire.OutPoint{Index: uint32(i)}, nil,
+			nil))
+		tx.AddTxOut(wire.NewTxOut(int64(i), nil))
+		msgBlock.AddTransaction(tx)
+	}
+	block := btcutil.NewBlock(&msgBlock)
+	msgBlock.Header.MerkleRoot = blockchain.CalcMerkleRoot(
+		block.Transactions(), false,
+	)
+	return btcutil.NewBlock(&msgBlock)
+}
+
+// TestMerkleBlockWithTxHashes ensures merkle blocks created for a set of
+// transaction hashes prove exactly those transactions.
+func TestMerkleBlockWithTxHashes(t *testing.T) {
+	for _, numTxns := range []int{1, 2, 3, 7, 16, 33} {
+		block := merkleTestBlock(numTxns)
+		txns := block.Transactions()
+
+		// Select the first, last and every third transaction.
+		var txHashes []*chainhash.Hash
+		var wantIndices []uint32
+		for i, tx := range txns {
+			if i == 0 || i == numTxns-1 || i%3 == 0 {
+				txHashes = append(txHashes, tx.Hash())
+				wantIndices = append(wantIndices, uint32(i))
+			}
+		}
+
+		mBlock, indices := bloom.NewMerkleBlockWithTxHashes(block,
+			txHashes)
+		if !reflect.DeepEqual(indices, wantIndices) {
+			t.Fatalf("%d txns: got matched indices %v, want %v",
+				numTxns, indices, wantIndices)
+		}
+
+		// Ensure the proof survives a round trip through the wire
+		// encoding used by gettxoutproof.
+		var buf bytes.Buffer
+		err := mBlock.BtcEncode(&buf, wire.ProtocolVersion,
+			wire.LatestEncoding)
+		if err != nil {
+			t.Fatalf("%d txns: BtcEncode failed: %v", numTxns, err)
+		}
+		var decoded wire.MsgMerkleBlock
+		err = decoded.BtcDecode(&buf, wire.ProtocolVersion,
+			wire.LatestEncoding)
+		if err != nil {
+			t.Fatalf("%d txns: BtcDecode failed: %v", numTxns, err)
+		}
+
+		hashes, indices, err := bloom.ExtractMatches(&decoded)
+		if err != nil {
+			t.Fatalf("%d txns: ExtractMatches failed: %v", numTxns,
+				err)
+		}
+		if !reflect.DeepEqual(hashes, txHashes) {
+			t.Fatalf("%d txns: got matched hashes %v, want %v",
+				numTxns, hashes, txHashes)
+		}
+		if !reflect.DeepEqual(indices, wantIndices) {
+			t.Fatalf("%d txns: got extracted indices %v, want %v",
+				numTxns, indices, wantIndices)
+		}
+	}
+}
+
+// TestExtractMatchesInvalid ensures malformed or tampered merkle blocks are
+// rejected.
+func TestExtractMatchesInvalid(t *testing.T) {
+	block := merkleTestBlock(5)
+	txHashes := []*chainhash.Hash{block.Transactions()[2].Hash()}
+
+	tests := []struct {
+		name   string
+		modify func(*wire.MsgMerkleBlock)
+	}{
+		{
+			name: "wrong merkle root",
+			modify: func(mBlock *wire.MsgMerkleBlock) {
+				mBlock.Header.MerkleRoot[0] ^= 0x01
+			},
+		},
+		{
+			name: "tampered hash",
+			modify: func(mBlock *wire.MsgMerkleBlock) {
+				hash := *mBlock.Hashes[0]
+				hash[0] ^= 0x01
+				mBlock.Hashes[0] = &hash
+			},
+		},
+		{
+			name: "no transactions",
+			modify: func(mBlock *wire.MsgMerkleBlock) {
+				mBlock.Transactions = 0
+			},
+		},
+		{
+			name: "more hashes than transactions",
+			modify: func(mBlock *wire.MsgMerkleBlock) {
+				mBlock.Transactions = 1
+			},
+		},
+		{
+			name: "unused hash",
+			modify: func(mBlock *wire.MsgMerkleBlock) {
+				mBlock.Hashes = append(mBlock.Hashes,
+					mBlock.Hashes[0])
+			},
+		},
+		{
+			name: "unused flag byte",
+			modify: func(mBlock *wire.MsgMerkleBlock) {
+				mBlock.Flags = append(mBlock.Flags, 0x00)
+			},
+		},
+		{
+			name: "missing flag bits",
+			modify: func(mBlock *wire.MsgMerkleBlock) {
+				mBlock.Flags = nil
+			},
+		},
+	}
+
+	for _, test := range tests {
+		mBlock, _ := bloom.NewMerkleBlockWithTxHashes(block, txHashes)
+		if _, _, err := bloom.ExtractMatches(mBlock); err != nil {
+			t.Fatalf("%s: unmodified merkle block rejected: %v",
+				test.name, err)
+		}
+
+		test.modify(mBlock)
+		if _, _, err := bloom.ExtractMatches(mBlock); err == nil {
+			t.Errorf("%s: invalid merkle block accepted", test.name)
+		}
+	}
+}
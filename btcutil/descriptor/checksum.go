@@ -0,0 +1,131 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package descriptor
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// inputCharset is the set of characters a descriptor may consist of.
+	// The position of a character is used to expand it into symbols for
+	// the checksum.
+	inputCharset = "0123456789()[],'/*abcdefgh@:$%{}" +
+		"IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~" +
+		"ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+
+	// checksumCharset is the set of characters the checksum is encoded
+	// with.
+	checksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+	// checksumLength is the number of characters of a checksum.
+	checksumLength = 8
+)
+
+// polyMod updates the passed checksum of a descriptor with the passed symbol.
+// The checksum is the remainder of the symbols as a polynomial over GF(32)
+// divided by a generator chosen to detect errors in descriptors, see BIP0380.
+func polyMod(c uint64, val int) uint64 {
+	c0 := c >> 35
+	c = ((c & 0x7ffffffff) << 5) ^ uint64(val)
+	if c0&1 != 0 {
+		c ^= 0xf5dee51989
+	}
+	if c0&2 != 0 {
+		c ^= 0xa9fdca3312
+	}
+	if c0&4 != 0 {
+		c ^= 0x1bab10e32d
+	}
+	if c0&8 != 0 {
+		c ^= 0x3706b1677a
+	}
+	if c0&16 != 0 {
+		c ^= 0x644d626ffd
+	}
+	return c
+}
+
+// Checksum returns the checksum of the passed descriptor, which must not
+// include a checksum itself.
+func Checksum(desc string) (string, error) {
+	c := uint64(1)
+	cls, clsCount := 0, 0
+	for i := 0; i < len(desc); i++ {
+		pos := strings.IndexByte(inputCharset, desc[i])
+		if pos == -1 {
+			return "", fmt.Errorf("invalid character %q in descriptor",
+				desc[i])
+		}
+
+		// Emit a symbol for the position inside the group, for every
+		// character.
+		c = polyMod(c, pos&31)
+
+		// Accumulate the group numbers and emit a symbol for every
+		// three characters.
+		cls = cls*3 + pos>>5
+		clsCount++
+		if clsCount == 3 {
+			c = polyMod(c, cls)
+			cls, clsCount = 0, 0
+		}
+	}
+	if clsCount > 0 {
+		c = polyMod(c, cls)
+	}
+
+	// Shift further to determine the checksum.
+	for i := 0; i < checksumLength; i++ {
+		c = polyMod(c, 0)
+	}
+	c ^= 1
+
+	var checksum [checksumLength]byte
+	for i := range checksum {
+		checksum[i] = checksumCharset[(c>>(5*(7-i)))&31]
+	}
+	return string(checksum[:]), nil
+}
+
+// AddChecksum returns the passed descriptor followed by its checksum.
+func AddChecksum(desc string) (string, error) {
+	checksum, err := Checksum(desc)
+	if err != nil {
+		return "", err
+	}
+	return desc + "#" + checksum, nil
+}
+
+// SplitChecksum splits the checksum off the passed descriptor and verifies it.
+// The checksum is empty when the descriptor does not have one.
+func SplitChecksum(desc string) (string, string, error) {
+	pos := strings.IndexByte(desc, '#')
+	if pos == -1 {
+		if _, err := Checksum(desc); err != nil {
+			return "", "", err
+		}
+		return desc, "", nil
+	}
+
+	body, checksum := desc[:pos], desc[pos+1:]
+	if strings.IndexByte(checksum, '#') != -1 {
+		return "", "", fmt.Errorf("multiple '#' symbols in descriptor")
+	}
+	if len(checksum) != checksumLength {
+		return "", "", fmt.Errorf("expected %d character checksum, not "+
+			"%d characters", checksumLength, len(checksum))
+	}
+	want, err := Checksum(body)
+	if err != nil {
+		return "", "", err
+	}
+	if checksum != want {
+		return "", "", fmt.Errorf("provided checksum '%s' does not "+
+			"match computed checksum '%s'", checksum, want)
+	}
+	return body, checksum, nil
+}
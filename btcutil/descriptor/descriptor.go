@@ -0,0 +1,467 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package descriptor
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg"
+	"github.com/bynil/btcd/txscript"
+)
+
+const (
+	// maxBareMultiSigKeys is the maximum number of keys of a multisig
+	// script which is not wrapped in P2SH or P2WSH.
+	maxBareMultiSigKeys = 3
+
+	// maxTapscriptMultiSigKeys is the maximum number of keys of a multisig
+	// script inside a taproot script tree.
+	maxTapscriptMultiSigKeys = 999
+
+	// maxTapTreeDepth is the maximum depth of a taproot script tree.
+	maxTapTreeDepth = 128
+)
+
+var (
+	// ErrNoAddress is returned when an output script of a descriptor does
+	// not have a corresponding address.
+	ErrNoAddress = errors.New("descriptor does not have a corresponding " +
+		"address")
+)
+
+// context is the script context a descriptor expression is parsed in, which
+// determines the expressions and keys allowed.
+type context uint8
+
+const (
+	// ctxTop is the top level of a descriptor.
+	ctxTop context = iota
+
+	// ctxP2SH is the inside of a sh() expression.
+	ctxP2SH
+
+	// ctxP2WSH is the inside of a wsh() expression.
+	ctxP2WSH
+
+	// ctxP2TR is a leaf of the script tree of a tr() expression.
+	ctxP2TR
+)
+
+// Descriptor is a parsed output script descriptor.  It describes an output
+// script, or a range of output scripts when it contains ranged extended keys.
+type Descriptor struct {
+	root   node
+	params *chaincfg.Params
+}
+
+// Parse parses the passed descriptor for the passed network.  The checksum of
+// the descriptor is verified when it is present.
+func Parse(desc string, params *chaincfg.Params) (*Descriptor, error) {
+	body, _, err := SplitChecksum(desc)
+	if err != nil {
+		return nil, err
+	}
+	root, err := parseExpr(body, ctxTop, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Descriptor{root: root, params: params}, nil
+}
+
+// String returns the descriptor with public keys only, followed by its
+// checksum.
+func (d *Descriptor) String() string {
+	// The descriptor only consists of characters which can be part of a
+	// descriptor, so computing the checksum can't fail.
+	desc, _ := AddChecksum(d.root.String())
+	return desc
+}
+
+// IsRange returns whether the descriptor contains ranged keys, so it describes
+// a range of output scripts.
+func (d *Descriptor) IsRange() bool {
+	for _, k := range d.root.keys() {
+		if k.isRange() {
+			return true
+		}
+	}
+	return false
+}
+
+// IsSolvable returns whether the descriptor contains the information needed to
+// spend its outputs given the private keys, which is not the case for addr()
+// and raw() descriptors.
+func (d *Descriptor) IsSolvable() bool {
+	switch d.root.(type) {
+	case *addrNode, *rawNode:
+		return false
+	}
+	return true
+}
+
+// HasPrivateKeys returns whether any key of the descriptor is a private key.
+func (d *Descriptor) HasPrivateKeys() bool {
+	for _, k := range d.root.keys() {
+		if k.hasPrivateKey() {
+			return true
+		}
+	}
+	return false
+}
+
+// Scripts returns the output scripts of the descriptor at the passed index of
+// the range.  The index is ignored when the descriptor is not ranged.  Only
+// combo() descriptors have more than one output script.
+func (d *Descriptor) Scripts(index uint32) ([][]byte, error) {
+	return d.root.scripts(index)
+}
+
+// Addresses returns the addresses of the output scripts of the descriptor at
+// the passed index of the range.  ErrNoAddress is returned when an output
+// script does not have an address, except for the P2PK script of a combo()
+// descriptor which is skipped.
+func (d *Descriptor) Addresses(index uint32) ([]btcutil.Address, error) {
+	if n, ok := d.root.(*addrNode); ok {
+		return []btcutil.Address{n.addr}, nil
+	}
+
+	scripts, err := d.Scripts(index)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]btcutil.Address, 0, len(scripts))
+	for _, script := range scripts {
+		class, scriptAddrs, _, err := txscript.ExtractPkScriptAddrs(
+			script, d.params,
+		)
+		if err != nil {
+			return nil, err
+		}
+		switch class {
+		case txscript.PubKeyHashTy, txscript.ScriptHashTy,
+			txscript.WitnessV0PubKeyHashTy,
+			txscript.WitnessV0ScriptHashTy,
+			txscript.WitnessV1TaprootTy:
+
+			if len(scriptAddrs) == 1 {
+				addrs = append(addrs, scriptAddrs[0])
+				continue
+			}
+
+		case txscript.PubKeyTy:
+			if len(scripts) > 1 {
+				continue
+			}
+		}
+		return nil, ErrNoAddress
+	}
+	return addrs, nil
+}
+
+// splitArgs splits the passed arguments of an expression at the commas which
+// are not nested in parentheses, brackets or braces.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unexpected '%c' in '%s'",
+					s[i], s)
+			}
+		case ',':
+			if depth == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced brackets in '%s'", s)
+	}
+	return append(args, s[start:]), nil
+}
+
+// splitCall splits the passed expression of the form NAME(ARGS) into its name
+// and arguments.
+func splitCall(s string) (string, []string, error) {
+	open := strings.IndexByte(s, '(')
+	if open == -1 || !strings.HasSuffix(s, ")") {
+		return "", nil, fmt.Errorf("'%s' is not a valid descriptor "+
+			"function", s)
+	}
+	args, err := splitArgs(s[open+1 : len(s)-1])
+	if err != nil {
+		return "", nil, err
+	}
+	return s[:open], args, nil
+}
+
+// parseExpr parses the passed script expression in the passed context.
+func parseExpr(s string, ctx context, params *chaincfg.Params) (node, error) {
+	name, args, err := splitCall(s)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keys are x-only inside taproot script trees, and uncompressed keys
+	// are only allowed outside of segwit scripts.
+	xOnly := ctx == ctxP2TR
+	allowUncompressed := ctx == ctxTop || ctx == ctxP2SH
+
+	// singleArg returns the single argument of the expression.
+	singleArg := func() (string, error) {
+		if len(args) != 1 {
+			return "", fmt.Errorf("%s() expects a single argument, "+
+				"got %d", name, len(args))
+		}
+		return args[0], nil
+	}
+
+	switch name {
+	case "pk":
+		arg, err := singleArg()
+		if err != nil {
+			return nil, err
+		}
+		k, err := parseKey(arg, xOnly, allowUncompressed, params)
+		if err != nil {
+			return nil, err
+		}
+		return &pkNode{key: k, xOnly: xOnly}, nil
+
+	case "pkh":
+		if ctx == ctxP2TR {
+			return nil, errors.New("can only have pkh() at top " +
+				"level, in sh(), or in wsh()")
+		}
+		arg, err := singleArg()
+		if err != nil {
+			return nil, err
+		}
+		k, err := parseKey(arg, false, allowUncompressed, params)
+		if err != nil {
+			return nil, err
+		}
+		return &pkhNode{key: k}, nil
+
+	case "wpkh":
+		if ctx != ctxTop && ctx != ctxP2SH {
+			return nil, errors.New("can only have wpkh() at top " +
+				"level or inside sh()")
+		}
+		arg, err := singleArg()
+		if err != nil {
+			return nil, err
+		}
+		k, err := parseKey(arg, false, false, params)
+		if err != nil {
+			return nil, err
+		}
+		return &wpkhNode{key: k}, nil
+
+	case "combo":
+		if ctx != ctxTop {
+			return nil, errors.New("can only have combo() at top " +
+				"level")
+		}
+		arg, err := singleArg()
+		if err != nil {
+			return nil, err
+		}
+		k, err := parseKey(arg, false, true, params)
+		if err != nil {
+			return nil, err
+		}
+		return &comboNode{key: k}, nil
+
+	case "multi", "sortedmulti", "multi_a", "sortedmulti_a":
+		tapscript := strings.HasSuffix(name, "_a")
+		if tapscript && ctx != ctxP2TR {
+			return nil, fmt.Errorf("can only have %s() in tr()",
+				name)
+		}
+		if !tapscript && ctx == ctxP2TR {
+			return nil, fmt.Errorf("can only have %s() at top "+
+				"level, in sh(), or in wsh()", name)
+		}
+		if len(args) < 2 {
+			return nil, fmt.Errorf("%s() requires a threshold and "+
+				"at least one key", name)
+		}
+		threshold, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("multi threshold '%s' is not "+
+				"valid", args[0])
+		}
+
+		numKeys := len(args) - 1
+		maxKeys := txscript.MaxPubKeysPerMultiSig
+		switch ctx {
+		case ctxTop:
+			maxKeys = maxBareMultiSigKeys
+		case ctxP2TR:
+			maxKeys = maxTapscriptMultiSigKeys
+		}
+		if numKeys > maxKeys {
+			return nil, fmt.Errorf("cannot have %d keys in %s(), "+
+				"only at most %d keys", numKeys, name, maxKeys)
+		}
+		if threshold < 1 || int(threshold) > numKeys {
+			return nil, fmt.Errorf("multi threshold %d out of "+
+				"range 1..%d", threshold, numKeys)
+		}
+
+		keys := make([]*key, 0, numKeys)
+		for _, arg := range args[1:] {
+			k, err := parseKey(arg, xOnly, allowUncompressed, params)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, k)
+		}
+		return &multiNode{
+			threshold: int(threshold),
+			multiKeys: keys,
+			sorted:    strings.HasPrefix(name, "sorted"),
+			tapscript: tapscript,
+		}, nil
+
+	case "sh":
+		if ctx != ctxTop {
+			return nil, errors.New("can only have sh() at top level")
+		}
+		arg, err := singleArg()
+		if err != nil {
+			return nil, err
+		}
+		sub, err := parseExpr(arg, ctxP2SH, params)
+		if err != nil {
+			return nil, err
+		}
+		return &shNode{sub: sub}, nil
+
+	case "wsh":
+		if ctx != ctxTop && ctx != ctxP2SH {
+			return nil, errors.New("can only have wsh() at top " +
+				"level or inside sh()")
+		}
+		arg, err := singleArg()
+		if err != nil {
+			return nil, err
+		}
+		sub, err := parseExpr(arg, ctxP2WSH, params)
+		if err != nil {
+			return nil, err
+		}
+		return &wshNode{sub: sub}, nil
+
+	case "tr":
+		if ctx != ctxTop {
+			return nil, errors.New("can only have tr() at top level")
+		}
+		if len(args) != 1 && len(args) != 2 {
+			return nil, fmt.Errorf("tr() expects a key and an "+
+				"optional script tree, got %d arguments",
+				len(args))
+		}
+		k, err := parseKey(args[0], true, false, params)
+		if err != nil {
+			return nil, err
+		}
+		n := &trNode{internalKey: k}
+		if len(args) == 2 {
+			n.tree, err = parseTapTree(args[1], 0, params)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return n, nil
+
+	case "addr":
+		if ctx != ctxTop {
+			return nil, errors.New("can only have addr() at top " +
+				"level")
+		}
+		arg, err := singleArg()
+		if err != nil {
+			return nil, err
+		}
+		addr, err := btcutil.DecodeAddress(arg, params)
+		if err != nil || !addr.IsForNet(params) {
+			return nil, fmt.Errorf("address '%s' is not valid", arg)
+		}
+		script, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return nil, err
+		}
+		return &addrNode{addr: addr, script: script}, nil
+
+	case "raw":
+		if ctx != ctxTop {
+			return nil, errors.New("can only have raw() at top level")
+		}
+		arg, err := singleArg()
+		if err != nil {
+			return nil, err
+		}
+		script, err := hex.DecodeString(arg)
+		if err != nil {
+			return nil, fmt.Errorf("raw script '%s' is not hex", arg)
+		}
+		return &rawNode{script: script}, nil
+	}
+
+	return nil, fmt.Errorf("'%s' is not a valid descriptor function", name)
+}
+
+// parseTapTree parses the passed taproot script tree at the passed depth.
+// Branches are enclosed in braces, and leaves are script expressions.
+func parseTapTree(s string, depth int, params *chaincfg.Params) (*tapTree, error) {
+	if depth > maxTapTreeDepth {
+		return nil, fmt.Errorf("max script tree depth of %d exceeded",
+			maxTapTreeDepth)
+	}
+
+	if !strings.HasPrefix(s, "{") {
+		leaf, err := parseExpr(s, ctxP2TR, params)
+		if err != nil {
+			return nil, err
+		}
+		return &tapTree{leaf: leaf}, nil
+	}
+
+	if !strings.HasSuffix(s, "}") {
+		return nil, fmt.Errorf("script tree '%s' is missing a closing "+
+			"'}'", s)
+	}
+	branches, err := splitArgs(s[1 : len(s)-1])
+	if err != nil {
+		return nil, err
+	}
+	if len(branches) != 2 {
+		return nil, fmt.Errorf("script tree branch '%s' does not have "+
+			"two subtrees", s)
+	}
+	left, err := parseTapTree(branches[0], depth+1, params)
+	if err != nil {
+		return nil, err
+	}
+	right, err := parseTapTree(branches[1], depth+1, params)
+	if err != nil {
+		return nil, err
+	}
+	return &tapTree{left: left, right: right}, nil
+}
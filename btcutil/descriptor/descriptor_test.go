@@ -0,0 +1,299 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package descriptor
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/btcutil/hdkeychain"
+	"github.com/bynil/btcd/chaincfg"
+)
+
+// TestChecksum ensures descriptor checksums are computed and verified as
+// specified by the test vectors of BIP0380.
+func TestChecksum(t *testing.T) {
+	checksum, err := Checksum("raw(deadbeef)")
+	if err != nil {
+		t.Fatalf("Checksum: unexpected error: %v", err)
+	}
+	if checksum != "89f8spxm" {
+		t.Fatalf("got checksum %s, want 89f8spxm", checksum)
+	}
+
+	body, checksum, err := SplitChecksum("raw(deadbeef)#89f8spxm")
+	if err != nil {
+		t.Fatalf("SplitChecksum: unexpected error: %v", err)
+	}
+	if body != "raw(deadbeef)" || checksum != "89f8spxm" {
+		t.Fatalf("got %s and checksum %s", body, checksum)
+	}
+
+	invalid := []string{
+		"raw(deadbeef)#",
+		"raw(deadbeef)#89f8spxmx",
+		"raw(deadbeef)#89f8spx",
+		"raw(deadbeef)#89f8spxn",
+		"raw(deedbeef)#89f8spxm",
+		"raw(deadbeef)##9f8spxm",
+		"raw(Ü)#00000000",
+	}
+	for _, desc := range invalid {
+		if _, _, err := SplitChecksum(desc); err == nil {
+			t.Errorf("SplitChecksum(%q): expected an error", desc)
+		}
+	}
+}
+
+// TestDescriptors ensures descriptors are parsed into the expected output
+// scripts and encoded again with public keys only.
+func TestDescriptors(t *testing.T) {
+	tests := []struct {
+		name        string
+		desc        string
+		want        string
+		scripts     []string
+		notSolvable bool
+		hasPrivKeys bool
+	}{{
+		name:    "pk",
+		desc:    "pk(0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798)",
+		want:    "pk(0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798)#gn28ywm7",
+		scripts: []string{"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac"},
+	}, {
+		name:    "pkh with checksum",
+		desc:    "pkh(02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5)#8fhd9pwu",
+		want:    "pkh(02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5)#8fhd9pwu",
+		scripts: []string{"76a91406afd46bcdfd22ef94ac122aa11f241244a37ecc88ac"},
+	}, {
+		name:    "sh(wpkh)",
+		desc:    "sh(wpkh(03fff97bd5755eeea420453a14355235d382f6472f8568a18b2f057a1460297556))",
+		want:    "sh(wpkh(03fff97bd5755eeea420453a14355235d382f6472f8568a18b2f057a1460297556))#qkrrc7je",
+		scripts: []string{"a914cc6ffbc0bf31af759451068f90ba7a0272b6b33287"},
+	}, {
+		name:    "tr key path only",
+		desc:    "tr(a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd)",
+		want:    "tr(a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd)#dh4fyxrd",
+		scripts: []string{"512077aab6e066f8a7419c5ab714c12c67d25007ed55a43cadcacb4d7a970a093f11"},
+	}, {
+		name:        "tr with a private key",
+		desc:        "tr(L4rK1yDtCWekvXuE6oXD9jCYfFNV2cWRpVuPLBcCU2z8TrisoyY1)",
+		want:        "tr(a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd)#dh4fyxrd",
+		scripts:     []string{"512077aab6e066f8a7419c5ab714c12c67d25007ed55a43cadcacb4d7a970a093f11"},
+		hasPrivKeys: true,
+	}, {
+		name:    "tr with a script tree",
+		desc:    "tr(a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd,pk(669b8afcec803a0d323e9a17f3ea8e68e8abe5a278020a929adbec52421adbd0))",
+		want:    "tr(a34b99f22c790c4e36b2b3c2c35a36db06226e41c692fc82b8b56ac1c540c5bd,pk(669b8afcec803a0d323e9a17f3ea8e68e8abe5a278020a929adbec52421adbd0))#eqx7gr08",
+		scripts: []string{"512017cf18db381d836d8923b1bdb246cfcd818da1a9f0e6e7907f187f0b2f937754"},
+	}, {
+		name: "combo",
+		desc: "combo(0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798)",
+		want: "combo(0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798)#lq9sf04s",
+		scripts: []string{
+			"210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798ac",
+			"76a914751e76e8199196d454941c45d1b3a323f1433bd688ac",
+			"0014751e76e8199196d454941c45d1b3a323f1433bd6",
+			"a914bcfeb728b584253d5f3f70bcb780e9ef218a68f487",
+		},
+	}, {
+		name: "sortedmulti sorts the keys",
+		desc: "sortedmulti(1,03acd484e2f0c7f65309ad178a9f559abde09796974c57e714c35f110dfc27ccbe,022f01e5e15cca351daff3843fb70f3c2f0a1bdd05e5af888a67784ef3e10a2a01)",
+		want: "sortedmulti(1,03acd484e2f0c7f65309ad178a9f559abde09796974c57e714c35f110dfc27ccbe,022f01e5e15cca351daff3843fb70f3c2f0a1bdd05e5af888a67784ef3e10a2a01)#8dlsxf35",
+		scripts: []string{"5121022f01e5e15cca351daff3843fb70f3c2f0a1bdd05e5af888a67784ef3e10a2a01" +
+			"2103acd484e2f0c7f65309ad178a9f559abde09796974c57e714c35f110dfc27ccbe52ae"},
+	}, {
+		name:        "addr",
+		desc:        "addr(bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4)",
+		want:        "addr(bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4)#uyjndxcw",
+		scripts:     []string{"0014751e76e8199196d454941c45d1b3a323f1433bd6"},
+		notSolvable: true,
+	}, {
+		name:        "raw",
+		desc:        "raw(deadbeef)",
+		want:        "raw(deadbeef)#89f8spxm",
+		scripts:     []string{"deadbeef"},
+		notSolvable: true,
+	}}
+
+	for _, test := range tests {
+		desc, err := Parse(test.desc, &chaincfg.MainNetParams)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if got := desc.String(); got != test.want {
+			t.Errorf("%s: got descriptor %s, want %s", test.name,
+				got, test.want)
+		}
+		if desc.IsRange() {
+			t.Errorf("%s: descriptor is ranged", test.name)
+		}
+		if desc.IsSolvable() == test.notSolvable {
+			t.Errorf("%s: got solvable %v, want %v", test.name,
+				desc.IsSolvable(), !test.notSolvable)
+		}
+		if desc.HasPrivateKeys() != test.hasPrivKeys {
+			t.Errorf("%s: got private keys %v, want %v", test.name,
+				desc.HasPrivateKeys(), test.hasPrivKeys)
+		}
+
+		scripts, err := desc.Scripts(0)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if len(scripts) != len(test.scripts) {
+			t.Errorf("%s: got %d scripts, want %d", test.name,
+				len(scripts), len(test.scripts))
+			continue
+		}
+		for i, script := range scripts {
+			if got := hex.EncodeToString(script); got != test.scripts[i] {
+				t.Errorf("%s: got script #%d %s, want %s",
+					test.name, i, got, test.scripts[i])
+			}
+		}
+	}
+}
+
+// TestRangedDescriptor ensures the output scripts of descriptors with ranged
+// extended keys are derived as the keys at the index of the range.
+func TestRangedDescriptor(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	xpub, err := master.Neuter()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// wantAddr returns the P2WPKH address of the key derived from the
+	// passed key with the passed path.
+	wantAddr := func(k *hdkeychain.ExtendedKey, path ...uint32) string {
+		for _, step := range path {
+			k, err = k.Derive(step)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		pubKey, err := k.ECPubKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := btcutil.NewAddressWitnessPubKeyHash(
+			btcutil.Hash160(pubKey.SerializeCompressed()),
+			&chaincfg.MainNetParams,
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return addr.String()
+	}
+
+	// The origin and the apostrophes of the path are kept.
+	desc, err := Parse("wpkh([d34db33f/84'/0'/0']"+xpub.String()+"/1/*)",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if !desc.IsRange() || desc.HasPrivateKeys() {
+		t.Fatalf("got ranged %v and private keys %v", desc.IsRange(),
+			desc.HasPrivateKeys())
+	}
+	want := "wpkh([d34db33f/84'/0'/0']" + xpub.String() + "/1/*)"
+	if got := desc.String(); !strings.HasPrefix(got, want+"#") {
+		t.Fatalf("got descriptor %s, want %s", got, want)
+	}
+	for i := uint32(0); i < 3; i++ {
+		addrs, err := desc.Addresses(i)
+		if err != nil {
+			t.Fatalf("Addresses(%d): unexpected error: %v", i, err)
+		}
+		if len(addrs) != 1 || addrs[0].String() != wantAddr(xpub, 1, i) {
+			t.Fatalf("got addresses %v at index %d, want %s", addrs,
+				i, wantAddr(xpub, 1, i))
+		}
+	}
+
+	// Hardened derivation requires the private key, which is not part of
+	// the encoded descriptor.
+	const h = hdkeychain.HardenedKeyStart
+	desc, err = Parse("wpkh("+master.String()+"/1h/*h)",
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if !desc.HasPrivateKeys() {
+		t.Fatal("descriptor with an extended private key has no " +
+			"private keys")
+	}
+	want = "wpkh(" + xpub.String() + "/1h/*h)"
+	if got := desc.String(); !strings.HasPrefix(got, want+"#") {
+		t.Fatalf("got descriptor %s, want %s", got, want)
+	}
+	addrs, err := desc.Addresses(2)
+	if err != nil {
+		t.Fatalf("Addresses: unexpected error: %v", err)
+	}
+	if addrs[0].String() != wantAddr(master, 1+h, 2+h) {
+		t.Fatalf("got address %v, want %s", addrs[0],
+			wantAddr(master, 1+h, 2+h))
+	}
+
+	desc, err = Parse(desc.String(), &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if _, err := desc.Scripts(0); err == nil {
+		t.Fatal("derived a hardened key without private keys")
+	}
+}
+
+// TestParseErrors ensures invalid descriptors are rejected.
+func TestParseErrors(t *testing.T) {
+	const (
+		compressed   = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+		uncompressed = "0479be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798" +
+			"483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8"
+		xOnly = "79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	)
+
+	tests := []struct {
+		name string
+		desc string
+	}{
+		{"unknown function", "foo(" + compressed + ")"},
+		{"missing parenthesis", "pk(" + compressed},
+		{"bad checksum", "pk(" + compressed + ")#00000000"},
+		{"x-only key outside tr", "pk(" + xOnly + ")"},
+		{"uncompressed key in wpkh", "wpkh(" + uncompressed + ")"},
+		{"uncompressed key in wsh", "wsh(pk(" + uncompressed + "))"},
+		{"uncompressed key in tr", "tr(" + uncompressed + ")"},
+		{"sh inside sh", "sh(sh(pk(" + compressed + ")))"},
+		{"wpkh inside wsh", "wsh(wpkh(" + compressed + "))"},
+		{"combo inside sh", "sh(combo(" + compressed + "))"},
+		{"tr inside sh", "sh(tr(" + xOnly + "))"},
+		{"multi in a script tree", "tr(" + xOnly + ",multi(1," + xOnly + "))"},
+		{"multi_a outside tr", "wsh(multi_a(1," + compressed + "))"},
+		{"zero threshold", "wsh(multi(0," + compressed + "))"},
+		{"threshold above keys", "wsh(multi(2," + compressed + "))"},
+		{"too many bare multisig keys", "multi(1," + strings.Repeat(compressed+",", 3) + compressed + ")"},
+		{"branch with one subtree", "tr(" + xOnly + ",{pk(" + xOnly + ")})"},
+		{"bad fingerprint", "pk([d34db3/0]" + compressed + ")"},
+		{"address of another network", "addr(tb1qw508d6qejxtdg4y5r3zarvary0c5xw7kxpjzsx)"},
+		{"raw not hex", "raw(xyz)"},
+	}
+	for _, test := range tests {
+		if _, err := Parse(test.desc, &chaincfg.MainNetParams); err == nil {
+			t.Errorf("%s: expected an error for %s", test.name,
+				test.desc)
+		}
+	}
+}
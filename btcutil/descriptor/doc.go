@@ -0,0 +1,40 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package descriptor implements output script descriptors (BIP0380 through
+BIP0386), a language for describing the output scripts of a wallet.
+
+# Overview
+
+A descriptor such as wpkh([d34db33f/84h/0h/0h]xpub.../0/*) describes how output
+scripts are constructed from keys.  Parse parses a descriptor, verifying its
+checksum when present, into a Descriptor which derives the output scripts and
+addresses it describes.
+
+The following script expressions are supported:
+
+  - pk(KEY), pkh(KEY), wpkh(KEY) and combo(KEY)
+  - sh(SCRIPT) and wsh(SCRIPT)
+  - multi(k,KEY,...) and sortedmulti(k,KEY,...)
+  - tr(KEY) and tr(KEY,TREE), where the leaves of the script tree are pk(KEY),
+    multi_a(k,KEY,...) or sortedmulti_a(k,KEY,...)
+  - addr(ADDR) and raw(HEX)
+
+# Keys
+
+Keys are hex encoded public keys, private keys in WIF, or extended keys
+followed by a derivation path, optionally preceded by the origin of the key in
+brackets.  A derivation path ending in /* or /*h makes the descriptor ranged,
+so it describes the output scripts at each index of a range.  Hardened
+derivation steps are written with an h or an apostrophe, which is kept when
+the descriptor is encoded again.
+
+# Checksums
+
+Descriptors may be followed by a # and an 8 character checksum which detects
+errors in them.  Checksum, AddChecksum and SplitChecksum compute and verify
+them.
+*/
+package descriptor
@@ -0,0 +1,350 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package descriptor
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bynil/btcd/btcec/v2"
+	"github.com/bynil/btcd/btcec/v2/schnorr"
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/btcutil/hdkeychain"
+	"github.com/bynil/btcd/chaincfg"
+)
+
+// pubKeyBytesLenUncompressed is the length of a serialized uncompressed public
+// key.
+const pubKeyBytesLenUncompressed = 65
+
+// wildcard describes whether a key is ranged, and how the index of the range
+// is derived when it is.
+type wildcard uint8
+
+const (
+	// wildcardNone indicates the key is not ranged.
+	wildcardNone wildcard = iota
+
+	// wildcardUnhardened indicates the key is ranged with an unhardened
+	// last derivation step, written as /*.
+	wildcardUnhardened
+
+	// wildcardHardened indicates the key is ranged with a hardened last
+	// derivation step, written as /*' or /*h.
+	wildcardHardened
+)
+
+// keyOrigin is the origin of a key, which is the fingerprint of the master key
+// it is derived from along with the derivation path from the master key.
+type keyOrigin struct {
+	fingerprint [4]byte
+	path        []uint32
+}
+
+// key is a key expression of a descriptor.  It is either a fixed public key,
+// given in hex or as a private key in WIF, or an extended key along with a
+// derivation path which might end in a wildcard.
+type key struct {
+	origin *keyOrigin
+
+	// pubKey is the public key of a fixed key.  privKey is set when the key
+	// was given as a private key.  compressed and xOnly describe how the
+	// key was serialized.
+	pubKey     *btcec.PublicKey
+	privKey    *btcec.PrivateKey
+	compressed bool
+	xOnly      bool
+
+	// extKey is the extended key the key is derived from using path, and
+	// the index of the range when the key has a wildcard.
+	extKey   *hdkeychain.ExtendedKey
+	path     []uint32
+	wildcard wildcard
+
+	// apostrophe is whether hardened derivation steps were written with an
+	// apostrophe rather than an h, which is kept when the key is encoded.
+	apostrophe bool
+}
+
+// parsePath parses the passed derivation path elements.  The returned bool is
+// whether any hardened step is written with an apostrophe.
+func parsePath(elems []string) ([]uint32, bool, error) {
+	path := make([]uint32, 0, len(elems))
+	apostrophe := false
+	for _, elem := range elems {
+		hardened := false
+		if strings.HasSuffix(elem, "'") || strings.HasSuffix(elem, "h") {
+			hardened = true
+			apostrophe = apostrophe || strings.HasSuffix(elem, "'")
+			elem = elem[:len(elem)-1]
+		}
+		step, err := strconv.ParseUint(elem, 10, 32)
+		if err != nil || step >= hdkeychain.HardenedKeyStart {
+			return nil, false, fmt.Errorf("key path value '%s' is "+
+				"out of range", elem)
+		}
+		if hardened {
+			step += hdkeychain.HardenedKeyStart
+		}
+		path = append(path, uint32(step))
+	}
+	return path, apostrophe, nil
+}
+
+// formatPath returns the passed derivation path, with each step preceded by a
+// slash, marking hardened steps with an apostrophe or an h.
+func formatPath(path []uint32, apostrophe bool) string {
+	var b strings.Builder
+	for _, step := range path {
+		b.WriteByte('/')
+		if step >= hdkeychain.HardenedKeyStart {
+			b.WriteString(strconv.FormatUint(
+				uint64(step-hdkeychain.HardenedKeyStart), 10))
+			if apostrophe {
+				b.WriteByte('\'')
+			} else {
+				b.WriteByte('h')
+			}
+			continue
+		}
+		b.WriteString(strconv.FormatUint(uint64(step), 10))
+	}
+	return b.String()
+}
+
+// parseKey parses the passed key expression.  Uncompressed keys are rejected
+// unless allowed, and keys are x-only inside taproot descriptors.
+func parseKey(s string, xOnly, allowUncompressed bool,
+	params *chaincfg.Params) (*key, error) {
+
+	k := &key{}
+
+	// Parse the key origin, which is the fingerprint of the master key
+	// followed by the derivation path in brackets.
+	if strings.HasPrefix(s, "[") {
+		end := strings.IndexByte(s, ']')
+		if end == -1 {
+			return nil, fmt.Errorf("key origin start '[' character " +
+				"without end ']' character")
+		}
+		elems := strings.Split(s[1:end], "/")
+		if len(elems[0]) != 8 {
+			return nil, fmt.Errorf("fingerprint is not 4 bytes (%d "+
+				"characters instead of 8 characters)",
+				len(elems[0]))
+		}
+		fingerprint, err := hex.DecodeString(elems[0])
+		if err != nil {
+			return nil, fmt.Errorf("fingerprint '%s' is not hex",
+				elems[0])
+		}
+		path, apostrophe, err := parsePath(elems[1:])
+		if err != nil {
+			return nil, err
+		}
+		k.origin = &keyOrigin{path: path}
+		copy(k.origin.fingerprint[:], fingerprint)
+		k.apostrophe = apostrophe
+		s = s[end+1:]
+	}
+	if strings.ContainsAny(s, "[]") {
+		return nil, fmt.Errorf("multiple key origins in key '%s'", s)
+	}
+
+	// Parse the key as a hex encoded public key.
+	if b, err := hex.DecodeString(s); err == nil && s != "" {
+		switch {
+		case len(b) == schnorr.PubKeyBytesLen && xOnly:
+			pubKey, err := schnorr.ParsePubKey(b)
+			if err != nil {
+				return nil, fmt.Errorf("pubkey '%s' is invalid", s)
+			}
+			k.pubKey = pubKey
+			k.xOnly = true
+			k.compressed = true
+
+		case len(b) == btcec.PubKeyBytesLenCompressed ||
+			len(b) == pubKeyBytesLenUncompressed:
+
+			pubKey, err := btcec.ParsePubKey(b)
+			if err != nil {
+				return nil, fmt.Errorf("pubkey '%s' is invalid", s)
+			}
+			k.pubKey = pubKey
+			k.compressed = len(b) == btcec.PubKeyBytesLenCompressed
+
+		default:
+			return nil, fmt.Errorf("pubkey '%s' is invalid", s)
+		}
+		if !k.compressed && !allowUncompressed {
+			return nil, fmt.Errorf("uncompressed keys are not " +
+				"allowed")
+		}
+		return k, nil
+	}
+
+	// Parse the key as a private key in WIF.
+	elems := strings.Split(s, "/")
+	if len(elems) == 1 {
+		if wif, err := btcutil.DecodeWIF(s); err == nil {
+			if !wif.IsForNet(params) {
+				return nil, fmt.Errorf("private key '%s' is not "+
+					"for network %s", s, params.Name)
+			}
+			k.privKey = wif.PrivKey
+			k.pubKey = wif.PrivKey.PubKey()
+			k.compressed = wif.CompressPubKey
+			k.xOnly = xOnly
+			if !k.compressed && !allowUncompressed {
+				return nil, fmt.Errorf("uncompressed keys " +
+					"are not allowed")
+			}
+			return k, nil
+		}
+	}
+
+	// Parse the key as an extended key followed by a derivation path.
+	extKey, err := hdkeychain.NewKeyFromString(elems[0])
+	if err != nil {
+		return nil, fmt.Errorf("key '%s' is not valid", s)
+	}
+	if !extKey.IsForNet(params) {
+		return nil, fmt.Errorf("extended key '%s' is not for network %s",
+			elems[0], params.Name)
+	}
+	k.extKey = extKey
+
+	elems = elems[1:]
+	if n := len(elems); n > 0 {
+		switch elems[n-1] {
+		case "*":
+			k.wildcard = wildcardUnhardened
+			elems = elems[:n-1]
+		case "*'", "*h":
+			k.wildcard = wildcardHardened
+			k.apostrophe = k.apostrophe || elems[n-1] == "*'"
+			elems = elems[:n-1]
+		}
+	}
+	path, apostrophe, err := parsePath(elems)
+	if err != nil {
+		return nil, err
+	}
+	k.path = path
+	k.apostrophe = k.apostrophe || apostrophe
+
+	return k, nil
+}
+
+// isRange returns whether the key is ranged.
+func (k *key) isRange() bool {
+	return k.wildcard != wildcardNone
+}
+
+// hasPrivateKey returns whether the key was given as a private key.
+func (k *key) hasPrivateKey() bool {
+	if k.extKey != nil {
+		return k.extKey.IsPrivate()
+	}
+	return k.privKey != nil
+}
+
+// derive returns the public key at the passed index of the range of a ranged
+// key, or the public key of a key which is not ranged.
+func (k *key) derive(index uint32) (*btcec.PublicKey, error) {
+	if k.extKey == nil {
+		return k.pubKey, nil
+	}
+
+	extKey := k.extKey
+	path := k.path
+	switch k.wildcard {
+	case wildcardUnhardened:
+		path = append(path[:len(path):len(path)], index)
+	case wildcardHardened:
+		path = append(path[:len(path):len(path)],
+			index+hdkeychain.HardenedKeyStart)
+	}
+	for _, step := range path {
+		var err error
+		extKey, err = extKey.Derive(step)
+		if err == hdkeychain.ErrDeriveHardFromPublic {
+			return nil, fmt.Errorf("cannot derive hardened key %s "+
+				"without private keys", formatPath(path,
+				k.apostrophe))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return extKey.ECPubKey()
+}
+
+// serialize returns the serialization of the passed public key derived from
+// the key.  Keys are serialized as x-only public keys inside taproot scripts.
+func (k *key) serialize(pubKey *btcec.PublicKey, xOnly bool) []byte {
+	switch {
+	case xOnly:
+		return schnorr.SerializePubKey(pubKey)
+	case k.compressed || k.extKey != nil:
+		return pubKey.SerializeCompressed()
+	default:
+		return pubKey.SerializeUncompressed()
+	}
+}
+
+// isCompressed returns whether the public keys derived from the key are
+// serialized in compressed form.
+func (k *key) isCompressed() bool {
+	return k.compressed || k.extKey != nil
+}
+
+// String returns the key expression with public keys only.
+func (k *key) String() string {
+	var b strings.Builder
+	if k.origin != nil {
+		b.WriteByte('[')
+		b.WriteString(hex.EncodeToString(k.origin.fingerprint[:]))
+		b.WriteString(formatPath(k.origin.path, k.apostrophe))
+		b.WriteByte(']')
+	}
+
+	if k.extKey == nil {
+		switch {
+		case k.xOnly:
+			b.WriteString(hex.EncodeToString(
+				schnorr.SerializePubKey(k.pubKey)))
+		case k.compressed:
+			b.WriteString(hex.EncodeToString(
+				k.pubKey.SerializeCompressed()))
+		default:
+			b.WriteString(hex.EncodeToString(
+				k.pubKey.SerializeUncompressed()))
+		}
+		return b.String()
+	}
+
+	extKey := k.extKey
+	if extKey.IsPrivate() {
+		// Neutering only fails for unknown networks, which is ruled
+		// out when the key is parsed.
+		extKey, _ = extKey.Neuter()
+	}
+	b.WriteString(extKey.String())
+	b.WriteString(formatPath(k.path, k.apostrophe))
+	switch k.wildcard {
+	case wildcardUnhardened:
+		b.WriteString("/*")
+	case wildcardHardened:
+		if k.apostrophe {
+			b.WriteString("/*'")
+		} else {
+			b.WriteString("/*h")
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,422 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package descriptor
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/txscript"
+)
+
+// node is a script expression of a descriptor.
+type node interface {
+	// scripts returns the scripts of the expression at the passed index
+	// of the range of ranged keys.  Only combo() has more than one script.
+	scripts(index uint32) ([][]byte, error)
+
+	// keys returns the keys of the expression and its subexpressions.
+	keys() []*key
+
+	// String returns the expression with public keys only.
+	String() string
+}
+
+// script returns the single script of the passed expression at the passed
+// index.
+func script(n node, index uint32) ([]byte, error) {
+	scripts, err := n.scripts(index)
+	if err != nil {
+		return nil, err
+	}
+	return scripts[0], nil
+}
+
+// pkNode is a pk(KEY) expression, paying to the key.
+type pkNode struct {
+	key   *key
+	xOnly bool
+}
+
+func (n *pkNode) scripts(index uint32) ([][]byte, error) {
+	pubKey, err := n.key.derive(index)
+	if err != nil {
+		return nil, err
+	}
+	s, err := txscript.NewScriptBuilder().
+		AddData(n.key.serialize(pubKey, n.xOnly)).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+	return [][]byte{s}, err
+}
+
+func (n *pkNode) keys() []*key { return []*key{n.key} }
+
+func (n *pkNode) String() string { return "pk(" + n.key.String() + ")" }
+
+// pkhNode is a pkh(KEY) expression, paying to the hash of the key.
+type pkhNode struct {
+	key *key
+}
+
+func (n *pkhNode) scripts(index uint32) ([][]byte, error) {
+	pubKey, err := n.key.derive(index)
+	if err != nil {
+		return nil, err
+	}
+	s, err := payToPubKeyHash(n.key.serialize(pubKey, false))
+	return [][]byte{s}, err
+}
+
+func (n *pkhNode) keys() []*key { return []*key{n.key} }
+
+func (n *pkhNode) String() string { return "pkh(" + n.key.String() + ")" }
+
+// wpkhNode is a wpkh(KEY) expression, paying to the witness hash of the key.
+type wpkhNode struct {
+	key *key
+}
+
+func (n *wpkhNode) scripts(index uint32) ([][]byte, error) {
+	pubKey, err := n.key.derive(index)
+	if err != nil {
+		return nil, err
+	}
+	s, err := payToWitnessPubKeyHash(n.key.serialize(pubKey, false))
+	return [][]byte{s}, err
+}
+
+func (n *wpkhNode) keys() []*key { return []*key{n.key} }
+
+func (n *wpkhNode) String() string { return "wpkh(" + n.key.String() + ")" }
+
+// comboNode is a combo(KEY) expression, paying to the key in all standard ways:
+// P2PK and P2PKH, and P2WPKH and P2SH-P2WPKH for compressed keys.
+type comboNode struct {
+	key *key
+}
+
+func (n *comboNode) scripts(index uint32) ([][]byte, error) {
+	pubKey, err := n.key.derive(index)
+	if err != nil {
+		return nil, err
+	}
+	serialized := n.key.serialize(pubKey, false)
+
+	p2pk, err := txscript.NewScriptBuilder().AddData(serialized).
+		AddOp(txscript.OP_CHECKSIG).Script()
+	if err != nil {
+		return nil, err
+	}
+	p2pkh, err := payToPubKeyHash(serialized)
+	if err != nil {
+		return nil, err
+	}
+	if !n.key.isCompressed() {
+		return [][]byte{p2pk, p2pkh}, nil
+	}
+
+	p2wpkh, err := payToWitnessPubKeyHash(serialized)
+	if err != nil {
+		return nil, err
+	}
+	p2shP2wpkh, err := payToScriptHash(p2wpkh)
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{p2pk, p2pkh, p2wpkh, p2shP2wpkh}, nil
+}
+
+func (n *comboNode) keys() []*key { return []*key{n.key} }
+
+func (n *comboNode) String() string { return "combo(" + n.key.String() + ")" }
+
+// multiNode is a multi(), sortedmulti(), multi_a() or sortedmulti_a()
+// expression, requiring signatures of a threshold of the keys.  The _a variants
+// are the multisig scripts of tapscript, which use OP_CHECKSIGADD.
+type multiNode struct {
+	threshold int
+	multiKeys []*key
+	sorted    bool
+	tapscript bool
+}
+
+func (n *multiNode) scripts(index uint32) ([][]byte, error) {
+	pubKeys := make([][]byte, 0, len(n.multiKeys))
+	for _, k := range n.multiKeys {
+		pubKey, err := k.derive(index)
+		if err != nil {
+			return nil, err
+		}
+		pubKeys = append(pubKeys, k.serialize(pubKey, n.tapscript))
+	}
+	if n.sorted {
+		sort.Slice(pubKeys, func(i, j int) bool {
+			return bytes.Compare(pubKeys[i], pubKeys[j]) < 0
+		})
+	}
+
+	if n.tapscript {
+		// Tapscripts are not limited in size, so the script is not
+		// assembled with a script builder, which would limit it to the
+		// maximum size of legacy scripts.
+		s := make([]byte, 0, len(pubKeys)*34+4)
+		for i, pubKey := range pubKeys {
+			s = append(s, txscript.OP_DATA_32)
+			s = append(s, pubKey...)
+			if i == 0 {
+				s = append(s, txscript.OP_CHECKSIG)
+			} else {
+				s = append(s, txscript.OP_CHECKSIGADD)
+			}
+		}
+		threshold, err := txscript.NewScriptBuilder().
+			AddInt64(int64(n.threshold)).Script()
+		if err != nil {
+			return nil, err
+		}
+		s = append(s, threshold...)
+		s = append(s, txscript.OP_NUMEQUAL)
+		return [][]byte{s}, nil
+	}
+
+	builder := txscript.NewScriptBuilder().AddInt64(int64(n.threshold))
+	for _, pubKey := range pubKeys {
+		builder.AddData(pubKey)
+	}
+	s, err := builder.AddInt64(int64(len(pubKeys))).
+		AddOp(txscript.OP_CHECKMULTISIG).
+		Script()
+	return [][]byte{s}, err
+}
+
+func (n *multiNode) keys() []*key { return n.multiKeys }
+
+func (n *multiNode) String() string {
+	name := "multi"
+	if n.sorted {
+		name = "sortedmulti"
+	}
+	if n.tapscript {
+		name += "_a"
+	}
+
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('(')
+	b.WriteString(strconv.Itoa(n.threshold))
+	for _, k := range n.multiKeys {
+		b.WriteByte(',')
+		b.WriteString(k.String())
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// shNode is a sh(SCRIPT) expression, paying to the hash of the script.
+type shNode struct {
+	sub node
+}
+
+func (n *shNode) scripts(index uint32) ([][]byte, error) {
+	redeemScript, err := script(n.sub, index)
+	if err != nil {
+		return nil, err
+	}
+	if len(redeemScript) > txscript.MaxScriptElementSize {
+		return nil, fmt.Errorf("P2SH script is too large, %d bytes is "+
+			"larger than %d bytes", len(redeemScript),
+			txscript.MaxScriptElementSize)
+	}
+	s, err := payToScriptHash(redeemScript)
+	return [][]byte{s}, err
+}
+
+func (n *shNode) keys() []*key { return n.sub.keys() }
+
+func (n *shNode) String() string { return "sh(" + n.sub.String() + ")" }
+
+// wshNode is a wsh(SCRIPT) expression, paying to the witness hash of the
+// script.
+type wshNode struct {
+	sub node
+}
+
+func (n *wshNode) scripts(index uint32) ([][]byte, error) {
+	witnessScript, err := script(n.sub, index)
+	if err != nil {
+		return nil, err
+	}
+	s, err := payToWitnessScriptHash(witnessScript)
+	return [][]byte{s}, err
+}
+
+func (n *wshNode) keys() []*key { return n.sub.keys() }
+
+func (n *wshNode) String() string { return "wsh(" + n.sub.String() + ")" }
+
+// tapTree is a taproot script tree.  It is either a leaf with a script
+// expression, or a branch with two subtrees.
+type tapTree struct {
+	leaf        node
+	left, right *tapTree
+}
+
+// tapNode returns the node of the tree at the passed index of the range of
+// ranged keys.
+func (t *tapTree) tapNode(index uint32) (txscript.TapNode, error) {
+	if t.leaf != nil {
+		s, err := script(t.leaf, index)
+		if err != nil {
+			return nil, err
+		}
+		return txscript.NewBaseTapLeaf(s), nil
+	}
+
+	left, err := t.left.tapNode(index)
+	if err != nil {
+		return nil, err
+	}
+	right, err := t.right.tapNode(index)
+	if err != nil {
+		return nil, err
+	}
+	return txscript.NewTapBranch(left, right), nil
+}
+
+// keys returns the keys of the script expressions of the leaves of the tree.
+func (t *tapTree) keys() []*key {
+	if t.leaf != nil {
+		return t.leaf.keys()
+	}
+	return append(t.left.keys(), t.right.keys()...)
+}
+
+// String returns the tree with public keys only.  Branches are enclosed in
+// braces.
+func (t *tapTree) String() string {
+	if t.leaf != nil {
+		return t.leaf.String()
+	}
+	return "{" + t.left.String() + "," + t.right.String() + "}"
+}
+
+// trNode is a tr(KEY) or tr(KEY,TREE) expression, paying to the taproot output
+// key tweaked from the internal key with the root of the optional script tree.
+type trNode struct {
+	internalKey *key
+	tree        *tapTree
+}
+
+func (n *trNode) scripts(index uint32) ([][]byte, error) {
+	internalKey, err := n.internalKey.derive(index)
+	if err != nil {
+		return nil, err
+	}
+
+	var scriptRoot []byte
+	if n.tree != nil {
+		root, err := n.tree.tapNode(index)
+		if err != nil {
+			return nil, err
+		}
+		rootHash := root.TapHash()
+		scriptRoot = rootHash[:]
+	}
+	outputKey := txscript.ComputeTaprootOutputKey(internalKey, scriptRoot)
+
+	s, err := txscript.PayToTaprootScript(outputKey)
+	return [][]byte{s}, err
+}
+
+func (n *trNode) keys() []*key {
+	keys := []*key{n.internalKey}
+	if n.tree != nil {
+		keys = append(keys, n.tree.keys()...)
+	}
+	return keys
+}
+
+func (n *trNode) String() string {
+	if n.tree == nil {
+		return "tr(" + n.internalKey.String() + ")"
+	}
+	return "tr(" + n.internalKey.String() + "," + n.tree.String() + ")"
+}
+
+// addrNode is an addr(ADDR) expression, paying to the address.
+type addrNode struct {
+	addr   btcutil.Address
+	script []byte
+}
+
+func (n *addrNode) scripts(uint32) ([][]byte, error) {
+	return [][]byte{n.script}, nil
+}
+
+func (n *addrNode) keys() []*key { return nil }
+
+func (n *addrNode) String() string { return "addr(" + n.addr.String() + ")" }
+
+// rawNode is a raw(HEX) expression, paying to the script.
+type rawNode struct {
+	script []byte
+}
+
+func (n *rawNode) scripts(uint32) ([][]byte, error) {
+	return [][]byte{n.script}, nil
+}
+
+func (n *rawNode) keys() []*key { return nil }
+
+func (n *rawNode) String() string {
+	return "raw(" + hex.EncodeToString(n.script) + ")"
+}
+
+// payToPubKeyHash returns a P2PKH script paying to the passed serialized public
+// key.
+func payToPubKeyHash(pubKey []byte) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_DUP).
+		AddOp(txscript.OP_HASH160).
+		AddData(btcutil.Hash160(pubKey)).
+		AddOp(txscript.OP_EQUALVERIFY).
+		AddOp(txscript.OP_CHECKSIG).
+		Script()
+}
+
+// payToWitnessPubKeyHash returns a P2WPKH script paying to the passed
+// serialized public key.
+func payToWitnessPubKeyHash(pubKey []byte) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_0).
+		AddData(btcutil.Hash160(pubKey)).
+		Script()
+}
+
+// payToScriptHash returns a P2SH script paying to the passed redeem script.
+func payToScriptHash(redeemScript []byte) ([]byte, error) {
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_HASH160).
+		AddData(btcutil.Hash160(redeemScript)).
+		AddOp(txscript.OP_EQUAL).
+		Script()
+}
+
+// payToWitnessScriptHash returns a P2WSH script paying to the passed witness
+// script.
+func payToWitnessScriptHash(witnessScript []byte) ([]byte, error) {
+	scriptHash := sha256.Sum256(witnessScript)
+	return txscript.NewScriptBuilder().
+		AddOp(txscript.OP_0).
+		AddData(scriptHash[:]).
+		Script()
+}
@@ -0,0 +1,247 @@
+// Copyright (c) 2014-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+// References:
+//   [BIP44]: BIP0044 - Multi-Account Hierarchy for Deterministic Wallets
+//   https://github.com/bitcoin/bips/blob/master/bip-0044.mediawiki
+//
+//   [BIP49]: BIP0049 - Derivation scheme for P2WPKH-nested-in-P2SH accounts
+//   https://github.com/bitcoin/bips/blob/master/bip-0049.mediawiki
+//
+//   [BIP84]: BIP0084 - Derivation scheme for P2WPKH based accounts
+//   https://github.com/bitcoin/bips/blob/master/bip-0084.mediawiki
+//
+//   [BIP86]: BIP0086 - Key Derivation for Single Key P2TR Outputs
+//   https://github.com/bitcoin/bips/blob/master/bip-0086.mediawiki
+//
+//   [SLIP132]: SLIP-0132 - Registered HD version bytes for BIP-0032
+//   https://github.com/satoshilabs/slips/blob/master/slip-0132.md
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bynil/btcd/btcec/v2"
+	"github.com/bynil/btcd/btcec/v2/schnorr"
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+)
+
+// Purpose is the purpose index of a BIP0043 derivation path, which is the
+// first hardened index of the path and determines the kind of addresses the
+// keys below it are used for.
+type Purpose uint32
+
+const (
+	// PurposeBIP44 is the purpose of BIP0044 accounts, which use
+	// pay-to-pubkey-hash addresses.
+	PurposeBIP44 Purpose = 44
+
+	// PurposeBIP49 is the purpose of BIP0049 accounts, which use
+	// pay-to-witness-pubkey-hash addresses nested in pay-to-script-hash
+	// addresses.
+	PurposeBIP49 Purpose = 49
+
+	// PurposeBIP84 is the purpose of BIP0084 accounts, which use native
+	// pay-to-witness-pubkey-hash addresses.
+	PurposeBIP84 Purpose = 84
+
+	// PurposeBIP86 is the purpose of BIP0086 accounts, which use
+	// pay-to-taproot addresses committing to the key alone.
+	PurposeBIP86 Purpose = 86
+)
+
+var (
+	// ErrUnknownPurpose describes an error in which the caller passed a
+	// purpose which isn't one of the supported BIP0044, BIP0049, BIP0084
+	// and BIP0086 ones.
+	ErrUnknownPurpose = errors.New("unknown derivation purpose")
+
+	// ErrNoSLIP132Version describes an error in which there are no
+	// registered SLIP-0132 version bytes for a purpose on a network.
+	ErrNoSLIP132Version = errors.New("no SLIP-0132 version bytes for " +
+		"purpose on network")
+)
+
+// SLIP-0132 version bytes of extended keys for BIP0049 and BIP0084 accounts.
+// BIP0044 and BIP0086 accounts use the version bytes of the network.
+var (
+	// yprvID and ypubID are the version bytes of BIP0049 extended keys on
+	// the main network.
+	yprvID = [4]byte{0x04, 0x9d, 0x78, 0x78}
+	ypubID = [4]byte{0x04, 0x9d, 0x7c, 0xb2}
+
+	// zprvID and zpubID are the version bytes of BIP0084 extended keys on
+	// the main network.
+	zprvID = [4]byte{0x04, 0xb2, 0x43, 0x0c}
+	zpubID = [4]byte{0x04, 0xb2, 0x47, 0x46}
+
+	// uprvID and upubID are the version bytes of BIP0049 extended keys on
+	// the test networks.
+	uprvID = [4]byte{0x04, 0x4a, 0x4e, 0x28}
+	upubID = [4]byte{0x04, 0x4a, 0x52, 0x62}
+
+	// vprvID and vpubID are the version bytes of BIP0084 extended keys on
+	// the test networks.
+	vprvID = [4]byte{0x04, 0x5f, 0x18, 0xbc}
+	vpubID = [4]byte{0x04, 0x5f, 0x1c, 0xf6}
+
+	// mainNetHDPrivateKeyID and testNetHDPrivateKeyID are the xprv and
+	// tprv version bytes which tell apart the main and test networks.
+	mainNetHDPrivateKeyID = chaincfg.MainNetParams.HDPrivateKeyID
+	testNetHDPrivateKeyID = chaincfg.TestNet3Params.HDPrivateKeyID
+)
+
+func init() {
+	// Register the SLIP-0132 version bytes so private extended keys using
+	// them can be neutered.
+	for _, ids := range [][2][4]byte{
+		{ypubID, yprvID},
+		{zpubID, zprvID},
+		{upubID, uprvID},
+		{vpubID, vprvID},
+	} {
+		if err := chaincfg.RegisterHDKeyID(ids[0][:], ids[1][:]); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Versions returns the version bytes of the private and public extended
+// keys of accounts with the purpose on the passed network, which are the
+// SLIP-0132 ones for BIP0049 and BIP0084 accounts and the ones of the network
+// otherwise.  BIP0049 and BIP0084 accounts are only supported on networks
+// using the xprv or tprv version bytes.
+func (p Purpose) Versions(net *chaincfg.Params) (priv, pub [4]byte, err error) {
+	switch p {
+	case PurposeBIP44, PurposeBIP86:
+		return net.HDPrivateKeyID, net.HDPublicKeyID, nil
+
+	case PurposeBIP49:
+		switch net.HDPrivateKeyID {
+		case mainNetHDPrivateKeyID:
+			return yprvID, ypubID, nil
+		case testNetHDPrivateKeyID:
+			return uprvID, upubID, nil
+		}
+
+	case PurposeBIP84:
+		switch net.HDPrivateKeyID {
+		case mainNetHDPrivateKeyID:
+			return zprvID, zpubID, nil
+		case testNetHDPrivateKeyID:
+			return vprvID, vpubID, nil
+		}
+
+	default:
+		return priv, pub, fmt.Errorf("%w: %d", ErrUnknownPurpose, p)
+	}
+
+	return priv, pub, fmt.Errorf("%w: purpose %d on %s",
+		ErrNoSLIP132Version, p, net.Name)
+}
+
+// AccountPath returns the derivation path m/purpose'/coinType'/account' of
+// the account with the passed purpose, coin type and account number.  The
+// account number must be less than HardenedKeyStart.
+func AccountPath(purpose Purpose, coinType, account uint32) Path {
+	return Path{
+		uint32(purpose) + HardenedKeyStart,
+		coinType + HardenedKeyStart,
+		account + HardenedKeyStart,
+	}
+}
+
+// DeriveAccount derives the extended private key of the account with the
+// passed purpose and account number from the master key, using the coin type
+// of the passed network.  The returned key uses the version bytes returned by
+// Purpose.Versions, so it serializes to a zprv for BIP0084 accounts on the
+// main network for example, and its origin is returned along with it.
+//
+// The external and internal chains of the account are derived from the
+// returned key with the indexes 0 and 1, and the keys of the addresses from
+// those.
+func DeriveAccount(master *ExtendedKey, purpose Purpose, account uint32,
+	net *chaincfg.Params) (*ExtendedKey, *KeyOrigin, error) {
+
+	priv, _, err := purpose.Versions(net)
+	if err != nil {
+		return nil, nil, err
+	}
+	path := AccountPath(purpose, net.HDCoinType, account)
+	key, err := master.DerivePath(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err = key.CloneWithVersion(priv[:])
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, NewKeyOrigin(master, path), nil
+}
+
+// PurposeAddress converts the extended key to the kind of address used by
+// accounts with the passed purpose for the passed network, which is a
+// pay-to-pubkey-hash address for BIP0044, a pay-to-witness-pubkey-hash
+// address nested in a pay-to-script-hash address for BIP0049, a native
+// pay-to-witness-pubkey-hash address for BIP0084, and a pay-to-taproot
+// address without a script path for BIP0086.
+func (k *ExtendedKey) PurposeAddress(purpose Purpose,
+	net *chaincfg.Params) (btcutil.Address, error) {
+
+	pubKey := k.pubKeyBytes()
+	switch purpose {
+	case PurposeBIP44:
+		return k.Address(net)
+
+	case PurposeBIP49:
+		// The redeem script is the version 0 witness program
+		// OP_0 OP_DATA_20 <hash160(pubkey)>.
+		pkHash := btcutil.Hash160(pubKey)
+		redeemScript := append([]byte{0x00, 0x14}, pkHash...)
+		return btcutil.NewAddressScriptHash(redeemScript, net)
+
+	case PurposeBIP84:
+		pkHash := btcutil.Hash160(pubKey)
+		return btcutil.NewAddressWitnessPubKeyHash(pkHash, net)
+
+	case PurposeBIP86:
+		internalKey, err := btcec.ParsePubKey(pubKey)
+		if err != nil {
+			return nil, err
+		}
+		outputKey := bip86OutputKey(internalKey)
+		return btcutil.NewAddressTaproot(
+			schnorr.SerializePubKey(outputKey), net,
+		)
+	}
+
+	return nil, fmt.Errorf("%w: %d", ErrUnknownPurpose, purpose)
+}
+
+// bip86OutputKey returns the taproot output key committing to the passed
+// internal key without a script path, as described by BIP0086.  It's the
+// same as txscript.ComputeTaprootKeyNoScript, which can't be imported here.
+func bip86OutputKey(internalKey *btcec.PublicKey) *btcec.PublicKey {
+	// The output key is internalKey + tapTweak*G, where the internal key
+	// is taken with an even y coordinate and the tweak commits to it
+	// alone.
+	xOnlyKey := schnorr.SerializePubKey(internalKey)
+	internalKey, _ = schnorr.ParsePubKey(xOnlyKey)
+	tapTweakHash := chainhash.TaggedHash(chainhash.TagTapTweak, xOnlyKey)
+
+	var tweakScalar btcec.ModNScalar
+	tweakScalar.SetBytes((*[32]byte)(tapTweakHash))
+
+	var internalPoint, tPoint, outputPoint btcec.JacobianPoint
+	internalKey.AsJacobian(&internalPoint)
+	btcec.ScalarBaseMultNonConst(&tweakScalar, &tPoint)
+	btcec.AddNonConst(&internalPoint, &tPoint, &outputPoint)
+	outputPoint.ToAffine()
+
+	return btcec.NewPublicKey(&outputPoint.X, &outputPoint.Y)
+}
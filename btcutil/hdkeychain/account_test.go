@@ -0,0 +1,176 @@
+// Copyright (c) 2014-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/bynil/btcd/chaincfg"
+)
+
+// TestDeriveAccount ensures accounts are derived with the expected version
+// bytes and addresses, using the test vectors of BIP0044, BIP0049, BIP0084
+// and BIP0086.
+func TestDeriveAccount(t *testing.T) {
+	// The seed of the mnemonic "abandon abandon abandon abandon abandon
+	// abandon abandon abandon abandon abandon abandon about" used by the
+	// test vectors.
+	seed, _ := hex.DecodeString("5eb00bbddcf069084889a8ab9155568165f5c453ccb85e70811aaed6f6da5fc19a5ac40b389cd370d086206dec8aa6c43daea6690f20ad3d8d48b2d2ce9e38e4")
+
+	tests := []struct {
+		name       string
+		purpose    Purpose
+		net        *chaincfg.Params
+		accountPub string
+		origin     string
+		addresses  []string
+	}{
+		{
+			name:    "bip44 mainnet",
+			purpose: PurposeBIP44,
+			net:     &chaincfg.MainNetParams,
+			origin:  "[73c5da0a/44'/0'/0']",
+			addresses: []string{
+				"1LqBGSKuX5yYUonjxT5qGfpUsXKYYWeabA",
+				"1Ak8PffB2meyfYnbXZR9EGfLfFZVpzJvQP",
+			},
+		},
+		{
+			name:    "bip49 testnet",
+			purpose: PurposeBIP49,
+			net:     &chaincfg.TestNet3Params,
+			origin:  "[73c5da0a/49'/1'/0']",
+			addresses: []string{
+				"2Mww8dCYPUpKHofjgcXcBCEGmniw9CoaiD2",
+			},
+		},
+		{
+			name:       "bip84 mainnet",
+			purpose:    PurposeBIP84,
+			net:        &chaincfg.MainNetParams,
+			accountPub: "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs",
+			origin:     "[73c5da0a/84'/0'/0']",
+			addresses: []string{
+				"bc1qcr8te4kr609gcawutmrza0j4xv80jy8z306fyu",
+				"bc1qnjg0jd8228aq7egyzacy8cys3knf9xvrerkf9g",
+			},
+		},
+		{
+			name:       "bip86 mainnet",
+			purpose:    PurposeBIP86,
+			net:        &chaincfg.MainNetParams,
+			accountPub: "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ",
+			origin:     "[73c5da0a/86'/0'/0']",
+			addresses: []string{
+				"bc1p5cyxnuxmeuwuvkwfem96lqzszd02n6xdcjrs20cac6yqjjwudpxqkedrcr",
+				"bc1p4qhjn9zdvkux4e44uhx8tc55attvtyu358kutcqkudyccelu0was9fqzwh",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		master, err := NewMaster(seed, test.net)
+		if err != nil {
+			t.Fatalf("%s: NewMaster: unexpected error: %v", test.name, err)
+		}
+		account, origin, err := DeriveAccount(master, test.purpose, 0,
+			test.net)
+		if err != nil {
+			t.Errorf("%s: DeriveAccount: unexpected error: %v",
+				test.name, err)
+			continue
+		}
+		if origin.String() != test.origin {
+			t.Errorf("%s: mismatched origin -- got %s, want %s",
+				test.name, origin, test.origin)
+		}
+
+		// The account key must be neutered to the public version bytes
+		// of the purpose.
+		accountPub, err := account.Neuter()
+		if err != nil {
+			t.Errorf("%s: Neuter: unexpected error: %v", test.name, err)
+			continue
+		}
+		_, pub, _ := test.purpose.Versions(test.net)
+		if !bytes.Equal(accountPub.Version(), pub[:]) {
+			t.Errorf("%s: mismatched public version -- got %x, "+
+				"want %x", test.name, accountPub.Version(), pub)
+		}
+		if test.accountPub != "" && accountPub.String() != test.accountPub {
+			t.Errorf("%s: mismatched account key -- got %s, want %s",
+				test.name, accountPub, test.accountPub)
+		}
+
+		external, err := accountPub.Derive(0)
+		if err != nil {
+			t.Errorf("%s: Derive: unexpected error: %v", test.name, err)
+			continue
+		}
+		for i, want := range test.addresses {
+			key, err := external.Derive(uint32(i))
+			if err != nil {
+				t.Errorf("%s: Derive: unexpected error: %v",
+					test.name, err)
+				continue
+			}
+			addr, err := key.PurposeAddress(test.purpose, test.net)
+			if err != nil {
+				t.Errorf("%s: PurposeAddress: unexpected error: %v",
+					test.name, err)
+				continue
+			}
+			if addr.EncodeAddress() != want {
+				t.Errorf("%s: mismatched address %d -- got %s, "+
+					"want %s", test.name, i,
+					addr.EncodeAddress(), want)
+			}
+		}
+	}
+}
+
+// TestPurposeVersions ensures the version bytes of accounts are the SLIP-0132
+// ones where expected, and that unknown purposes are rejected.
+func TestPurposeVersions(t *testing.T) {
+	tests := []struct {
+		purpose Purpose
+		net     *chaincfg.Params
+		prv     string
+		pub     string
+		wantErr error
+	}{
+		{PurposeBIP44, &chaincfg.MainNetParams, "0488ade4", "0488b21e", nil},
+		{PurposeBIP49, &chaincfg.MainNetParams, "049d7878", "049d7cb2", nil},
+		{PurposeBIP84, &chaincfg.MainNetParams, "04b2430c", "04b24746", nil},
+		{PurposeBIP86, &chaincfg.MainNetParams, "0488ade4", "0488b21e", nil},
+		{PurposeBIP49, &chaincfg.RegressionNetParams, "044a4e28", "044a5262", nil},
+		{PurposeBIP84, &chaincfg.TestNet3Params, "045f18bc", "045f1cf6", nil},
+		{PurposeBIP86, &chaincfg.SigNetParams, "04358394", "043587cf", nil},
+		{Purpose(45), &chaincfg.MainNetParams, "", "", ErrUnknownPurpose},
+	}
+
+	for _, test := range tests {
+		prv, pub, err := test.purpose.Versions(test.net)
+		if !errors.Is(err, test.wantErr) {
+			t.Errorf("Versions(%d, %s): unexpected error -- got %v, "+
+				"want %v", test.purpose, test.net.Name, err,
+				test.wantErr)
+			continue
+		}
+		if test.wantErr != nil {
+			continue
+		}
+		if hex.EncodeToString(prv[:]) != test.prv ||
+			hex.EncodeToString(pub[:]) != test.pub {
+
+			t.Errorf("Versions(%d, %s): got %x/%x, want %s/%s",
+				test.purpose, test.net.Name, prv, pub, test.prv,
+				test.pub)
+		}
+	}
+}
@@ -46,6 +46,23 @@ the HardenedKeyStart constant + the hardened key number as the index to the
 Derive function.  This provides the ability to cascade the keys into a tree and
 hence generate the hierarchical deterministic key chains.
 
+# Derivation Paths
+
+Derivation paths such as m/84'/0'/0'/1/5 are parsed with ParsePath, and every
+child along a path is derived at once with the DerivePath function.  Paths
+ending in a wildcard, such as m/84'/0'/0'/1/*, are parsed with ParseRangePath.
+The origin of a derived key, which is the fingerprint of its master key along
+with its path, is described by the KeyOrigin type and written as
+[d34db33f/84'/0'/0'] like in output descriptors.
+
+# Standard Accounts
+
+The DeriveAccount function derives the account key of a BIP0044, BIP0049,
+BIP0084 or BIP0086 account from a master key.  The account key uses the
+SLIP-0132 version bytes of the purpose, so BIP0084 account keys on the main
+network are serialized as zprv and zpub for example.  The PurposeAddress
+function converts keys below an account to the matching kind of address.
+
 # Normal vs Hardened Derived Extended Keys
 
 A private extended key can be used to derive both hardened and non-hardened
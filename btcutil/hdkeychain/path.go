@@ -0,0 +1,261 @@
+// Copyright (c) 2014-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bynil/btcd/btcutil"
+)
+
+var (
+	// ErrInvalidPath describes an error in which a derivation path or key
+	// origin could not be parsed.
+	ErrInvalidPath = errors.New("invalid derivation path")
+)
+
+// Path is a BIP0032 derivation path, which is the list of child indexes
+// leading from an extended key to one of its descendants.  Indexes starting
+// at HardenedKeyStart are hardened.
+type Path []uint32
+
+// parsePathIndex parses a single child index of a derivation path, which is
+// hardened when it ends in an apostrophe or an h.
+func parsePathIndex(elem string) (uint32, error) {
+	hardened := false
+	if strings.HasSuffix(elem, "'") || strings.HasSuffix(elem, "h") ||
+		strings.HasSuffix(elem, "H") {
+
+		hardened = true
+		elem = elem[:len(elem)-1]
+	}
+
+	// ParseUint accepts a leading plus sign, which isn't allowed in
+	// derivation paths.
+	if elem == "" || elem[0] < '0' || elem[0] > '9' {
+		return 0, fmt.Errorf("%w: index '%s' is not a number",
+			ErrInvalidPath, elem)
+	}
+	index, err := strconv.ParseUint(elem, 10, 32)
+	if err != nil || index >= HardenedKeyStart {
+		return 0, fmt.Errorf("%w: index '%s' is out of range",
+			ErrInvalidPath, elem)
+	}
+	if hardened {
+		index += HardenedKeyStart
+	}
+	return uint32(index), nil
+}
+
+// splitPath returns the elements of the passed derivation path, without the
+// leading m which denotes the master key.
+func splitPath(path string) []string {
+	if path == "m" || path == "" {
+		return nil
+	}
+	path = strings.TrimPrefix(path, "m/")
+	return strings.Split(path, "/")
+}
+
+// ParsePath parses a derivation path in the form m/84'/0'/0'/1/5.  The
+// leading m is optional, and hardened indexes can be marked with an
+// apostrophe or an h.  Paths ending in a wildcard are parsed by
+// ParseRangePath.
+func ParsePath(path string) (Path, error) {
+	elems := splitPath(path)
+	p := make(Path, 0, len(elems))
+	for _, elem := range elems {
+		index, err := parsePathIndex(elem)
+		if err != nil {
+			return nil, err
+		}
+		p = append(p, index)
+	}
+	return p, nil
+}
+
+// String returns the derivation path in the form m/84'/0'/0'/1/5.
+func (p Path) String() string {
+	var b strings.Builder
+	b.WriteString("m")
+	for _, index := range p {
+		b.WriteByte('/')
+		writePathIndex(&b, index)
+	}
+	return b.String()
+}
+
+// writePathIndex writes the passed child index, marking hardened indexes
+// with an apostrophe.
+func writePathIndex(b *strings.Builder, index uint32) {
+	if index >= HardenedKeyStart {
+		b.WriteString(strconv.FormatUint(
+			uint64(index-HardenedKeyStart), 10))
+		b.WriteByte('\'')
+		return
+	}
+	b.WriteString(strconv.FormatUint(uint64(index), 10))
+}
+
+// Child returns a new derivation path extending this one with the passed
+// child index.  The path itself is not modified.
+func (p Path) Child(index uint32) Path {
+	child := make(Path, len(p), len(p)+1)
+	copy(child, p)
+	return append(child, index)
+}
+
+// RangePath is a derivation path ending in a wildcard, such as
+// m/84'/0'/0'/1/*, which stands for the children of a key at any index.
+type RangePath struct {
+	// Path is the derivation path of the parent of the keys in the range.
+	Path Path
+
+	// Hardened is whether the keys in the range are hardened children,
+	// which is written as /*' or /*h.
+	Hardened bool
+}
+
+// ParseRangePath parses a derivation path ending in a wildcard, in the form
+// m/84'/0'/0'/1/*.  The leading m is optional, and hardened indexes and
+// wildcards can be marked with an apostrophe or an h.
+func ParseRangePath(path string) (*RangePath, error) {
+	elems := splitPath(path)
+	if len(elems) == 0 {
+		return nil, fmt.Errorf("%w: path doesn't end in a wildcard",
+			ErrInvalidPath)
+	}
+
+	var hardened bool
+	switch elems[len(elems)-1] {
+	case "*":
+	case "*'", "*h", "*H":
+		hardened = true
+	default:
+		return nil, fmt.Errorf("%w: path doesn't end in a wildcard",
+			ErrInvalidPath)
+	}
+
+	p, err := ParsePath(strings.Join(elems[:len(elems)-1], "/"))
+	if err != nil {
+		return nil, err
+	}
+	return &RangePath{Path: p, Hardened: hardened}, nil
+}
+
+// String returns the derivation path in the form m/84'/0'/0'/1/*.
+func (r *RangePath) String() string {
+	s := r.Path.String() + "/*"
+	if r.Hardened {
+		s += "'"
+	}
+	return s
+}
+
+// Index returns the derivation path of the key at the passed index of the
+// range, which must be less than HardenedKeyStart.
+func (r *RangePath) Index(index uint32) Path {
+	if r.Hardened {
+		index += HardenedKeyStart
+	}
+	return r.Path.Child(index)
+}
+
+// DerivePath returns the descendant extended key at the passed derivation
+// path, deriving each child in turn as described by Derive.  The same
+// extended key is returned for an empty path.
+func (k *ExtendedKey) DerivePath(path Path) (*ExtendedKey, error) {
+	key := k
+	for _, index := range path {
+		var err error
+		key, err = key.Derive(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
+
+// Fingerprint returns the fingerprint of the extended key, which is the
+// first four bytes of the hash160 of its public key interpreted as a big
+// endian integer.  It's the parent fingerprint of the children of the key.
+func (k *ExtendedKey) Fingerprint() uint32 {
+	return binary.BigEndian.Uint32(btcutil.Hash160(k.pubKeyBytes())[:4])
+}
+
+// KeyOrigin describes where a key comes from, which is the fingerprint of
+// the master key it is derived from along with the derivation path from the
+// master key.  It is written as [d34db33f/84'/0'/0'] in output descriptors.
+type KeyOrigin struct {
+	// Fingerprint is the fingerprint of the master key, as returned by
+	// its Fingerprint method.
+	Fingerprint uint32
+
+	// Path is the derivation path of the key from the master key.
+	Path Path
+}
+
+// NewKeyOrigin returns the origin of the key derived from the passed master
+// key at the passed derivation path.
+func NewKeyOrigin(master *ExtendedKey, path Path) *KeyOrigin {
+	return &KeyOrigin{
+		Fingerprint: master.Fingerprint(),
+		Path:        path,
+	}
+}
+
+// ParseKeyOrigin parses a key origin in the form [d34db33f/84'/0'/0'], where
+// the brackets are optional and the fingerprint is hex encoded.
+func ParseKeyOrigin(origin string) (*KeyOrigin, error) {
+	if strings.HasPrefix(origin, "[") {
+		if !strings.HasSuffix(origin, "]") {
+			return nil, fmt.Errorf("%w: key origin is missing the "+
+				"closing bracket", ErrInvalidPath)
+		}
+		origin = origin[1 : len(origin)-1]
+	}
+
+	fingerprintHex, pathStr, _ := strings.Cut(origin, "/")
+	fingerprint, err := hex.DecodeString(fingerprintHex)
+	if err != nil || len(fingerprint) != 4 {
+		return nil, fmt.Errorf("%w: fingerprint '%s' is not 4 bytes "+
+			"of hex", ErrInvalidPath, fingerprintHex)
+	}
+	path, err := ParsePath(pathStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &KeyOrigin{
+		Fingerprint: binary.BigEndian.Uint32(fingerprint),
+		Path:        path,
+	}, nil
+}
+
+// Child returns the origin of the child at the passed index of the key with
+// this origin.
+func (o *KeyOrigin) Child(index uint32) *KeyOrigin {
+	return &KeyOrigin{
+		Fingerprint: o.Fingerprint,
+		Path:        o.Path.Child(index),
+	}
+}
+
+// String returns the key origin in the form [d34db33f/84'/0'/0'].
+func (o *KeyOrigin) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "[%08x", o.Fingerprint)
+	for _, index := range o.Path {
+		b.WriteByte('/')
+		writePathIndex(&b, index)
+	}
+	b.WriteByte(']')
+	return b.String()
+}
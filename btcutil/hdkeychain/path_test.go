@@ -0,0 +1,255 @@
+// Copyright (c) 2014-2016 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestParsePath ensures derivation paths are parsed and formatted as
+// expected, and that invalid ones are rejected.
+func TestParsePath(t *testing.T) {
+	const h = HardenedKeyStart
+	tests := []struct {
+		name    string
+		path    string
+		want    Path
+		str     string
+		wantErr bool
+	}{
+		{
+			name: "master",
+			path: "m",
+			want: Path{},
+			str:  "m",
+		},
+		{
+			name: "empty",
+			path: "",
+			want: Path{},
+			str:  "m",
+		},
+		{
+			name: "apostrophes",
+			path: "m/84'/0'/0'/1/5",
+			want: Path{84 + h, 0 + h, 0 + h, 1, 5},
+			str:  "m/84'/0'/0'/1/5",
+		},
+		{
+			name: "h markers without m",
+			path: "44h/1H/2147483647h/0",
+			want: Path{44 + h, 1 + h, 2147483647 + h, 0},
+			str:  "m/44'/1'/2147483647'/0",
+		},
+		{
+			name:    "empty index",
+			path:    "m/84'//0",
+			wantErr: true,
+		},
+		{
+			name:    "trailing slash",
+			path:    "m/84'/",
+			wantErr: true,
+		},
+		{
+			name:    "index out of range",
+			path:    "m/2147483648",
+			wantErr: true,
+		},
+		{
+			name:    "plus sign",
+			path:    "m/+1",
+			wantErr: true,
+		},
+		{
+			name:    "wildcard",
+			path:    "m/84'/0'/0'/1/*",
+			wantErr: true,
+		},
+		{
+			name:    "double hardened marker",
+			path:    "m/84''",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		path, err := ParsePath(test.path)
+		if test.wantErr {
+			if !errors.Is(err, ErrInvalidPath) {
+				t.Errorf("%s: unexpected error -- got %v, want %v",
+					test.name, err, ErrInvalidPath)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(path, test.want) {
+			t.Errorf("%s: mismatched path -- got %v, want %v",
+				test.name, []uint32(path), []uint32(test.want))
+			continue
+		}
+		if str := path.String(); str != test.str {
+			t.Errorf("%s: mismatched string -- got %s, want %s",
+				test.name, str, test.str)
+		}
+	}
+}
+
+// TestParseRangePath ensures derivation paths ending in a wildcard are parsed
+// and formatted as expected.
+func TestParseRangePath(t *testing.T) {
+	const h = HardenedKeyStart
+
+	r, err := ParseRangePath("m/84'/0'/0'/1/*")
+	if err != nil {
+		t.Fatalf("ParseRangePath: unexpected error: %v", err)
+	}
+	if r.Hardened || r.String() != "m/84'/0'/0'/1/*" {
+		t.Fatalf("ParseRangePath: unexpected range %s", r)
+	}
+	want := Path{84 + h, 0 + h, 0 + h, 1, 7}
+	if got := r.Index(7); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Index: got %v, want %v", got, want)
+	}
+
+	// Index must not modify the path of the range.
+	r.Index(8)
+	if got := r.Index(7); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Index: got %v, want %v", got, want)
+	}
+
+	r, err = ParseRangePath("0h/*h")
+	if err != nil {
+		t.Fatalf("ParseRangePath: unexpected error: %v", err)
+	}
+	if !r.Hardened || r.String() != "m/0'/*'" {
+		t.Fatalf("ParseRangePath: unexpected range %s", r)
+	}
+	want = Path{0 + h, 3 + h}
+	if got := r.Index(3); !reflect.DeepEqual(got, want) {
+		t.Fatalf("Index: got %v, want %v", got, want)
+	}
+
+	for _, path := range []string{"m", "m/0", "m/*/0", "m/0/**"} {
+		_, err := ParseRangePath(path)
+		if !errors.Is(err, ErrInvalidPath) {
+			t.Errorf("ParseRangePath(%q): unexpected error -- got %v, "+
+				"want %v", path, err, ErrInvalidPath)
+		}
+	}
+}
+
+// TestDerivePath ensures deriving a path is the same as deriving each of its
+// indexes in turn, and that key origins are computed and formatted as
+// expected.
+func TestDerivePath(t *testing.T) {
+	// BIP0032 test vector 1.
+	master, err := NewKeyFromString("xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi")
+	if err != nil {
+		t.Fatalf("NewKeyFromString: unexpected error: %v", err)
+	}
+
+	path, err := ParsePath("m/0'/1/2'/2/1000000000")
+	if err != nil {
+		t.Fatalf("ParsePath: unexpected error: %v", err)
+	}
+	key, err := master.DerivePath(path)
+	if err != nil {
+		t.Fatalf("DerivePath: unexpected error: %v", err)
+	}
+	want := "xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76"
+	if key.String() != want {
+		t.Fatalf("DerivePath: got %s, want %s", key, want)
+	}
+
+	same, err := master.DerivePath(nil)
+	if err != nil || same != master {
+		t.Fatalf("DerivePath: empty path didn't return the same key")
+	}
+
+	// The fingerprint of the master key is the parent fingerprint of its
+	// children.
+	child, err := master.Derive(HardenedKeyStart)
+	if err != nil {
+		t.Fatalf("Derive: unexpected error: %v", err)
+	}
+	if master.Fingerprint() != 0x3442193e ||
+		child.ParentFingerprint() != master.Fingerprint() {
+
+		t.Fatalf("Fingerprint: got %08x, want 3442193e",
+			master.Fingerprint())
+	}
+
+	origin := NewKeyOrigin(master, path)
+	if got := origin.String(); got != "[3442193e/0'/1/2'/2/1000000000]" {
+		t.Fatalf("KeyOrigin.String: got %s", got)
+	}
+	if got := origin.Child(3).String(); got != "[3442193e/0'/1/2'/2/1000000000/3]" {
+		t.Fatalf("KeyOrigin.Child: got %s", got)
+	}
+}
+
+// TestParseKeyOrigin ensures key origins are parsed as expected, and that
+// invalid ones are rejected.
+func TestParseKeyOrigin(t *testing.T) {
+	const h = HardenedKeyStart
+	tests := []struct {
+		origin  string
+		want    *KeyOrigin
+		wantErr bool
+	}{
+		{
+			origin: "[d34db33f/84'/0'/0']",
+			want: &KeyOrigin{
+				Fingerprint: 0xd34db33f,
+				Path:        Path{84 + h, 0 + h, 0 + h},
+			},
+		},
+		{
+			origin: "d34db33f/44h/1h/0h/0/1",
+			want: &KeyOrigin{
+				Fingerprint: 0xd34db33f,
+				Path:        Path{44 + h, 1 + h, 0 + h, 0, 1},
+			},
+		},
+		{
+			origin: "[00000000]",
+			want: &KeyOrigin{
+				Fingerprint: 0,
+				Path:        Path{},
+			},
+		},
+		{origin: "[d34db33f/84'", wantErr: true},
+		{origin: "[d34db3/84']", wantErr: true},
+		{origin: "[d34db33g/84']", wantErr: true},
+		{origin: "[d34db33f/84'/x]", wantErr: true},
+	}
+
+	for _, test := range tests {
+		origin, err := ParseKeyOrigin(test.origin)
+		if test.wantErr {
+			if !errors.Is(err, ErrInvalidPath) {
+				t.Errorf("ParseKeyOrigin(%q): unexpected error -- "+
+					"got %v, want %v", test.origin, err,
+					ErrInvalidPath)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseKeyOrigin(%q): unexpected error: %v",
+				test.origin, err)
+			continue
+		}
+		if !reflect.DeepEqual(origin, test.want) {
+			t.Errorf("ParseKeyOrigin(%q): got %v, want %v",
+				test.origin, origin, test.want)
+		}
+	}
+}
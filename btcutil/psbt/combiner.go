@@ -0,0 +1,267 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt
+
+// The Combiner merges multiple PSBTs for the same unsigned transaction into
+// one, taking the union of the key-value pairs of all of them.
+
+import (
+	"bytes"
+	"errors"
+)
+
+// ErrDifferentTransactions indicates that the PSBTs passed to the Combiner
+// don't all describe the same unsigned transaction.
+var ErrDifferentTransactions = errors.New("PSBTs not compatible " +
+	"(different transactions)")
+
+// Combine merges the passed PSBTs, which must all describe the same unsigned
+// transaction, into a new packet.  Fields missing from the first packet are
+// taken from the others in order, and keyed fields such as partial
+// signatures and derivation paths are merged.  When two packets have
+// different values for the same key, the value of the earlier packet is
+// kept.  None of the passed packets are modified.  Referencing the PSBT BIP,
+// this function serves the role of the Combiner.
+func Combine(packets ...*Packet) (*Packet, error) {
+	if len(packets) == 0 {
+		return nil, ErrInvalidPsbtFormat
+	}
+
+	// Start out with a deep copy of the first packet by round tripping
+	// it through its serialization.
+	var b bytes.Buffer
+	if err := packets[0].Serialize(&b); err != nil {
+		return nil, err
+	}
+	combined, err := NewFromRawBytes(&b, false)
+	if err != nil {
+		return nil, err
+	}
+
+	txHash := combined.UnsignedTx.TxHash()
+	for _, p := range packets[1:] {
+		if p.Version != combined.Version || p.UnsignedTx.TxHash() != txHash {
+			return nil, ErrDifferentTransactions
+		}
+
+		for _, xPub := range p.XPubs {
+			if !hasXPub(combined.XPubs, xPub.ExtendedKey) {
+				combined.XPubs = append(combined.XPubs, xPub)
+			}
+		}
+		combined.Unknowns = mergeUnknowns(combined.Unknowns, p.Unknowns)
+
+		// A version 2 PSBT only stays modifiable if all of the
+		// combined packets are, while any signature with
+		// SIGHASH_SINGLE has to be reported.
+		combined.TxModifiable &= p.TxModifiable | HasSigHashSingle
+		combined.TxModifiable |= p.TxModifiable & HasSigHashSingle
+		if combined.FallbackLockTime == nil {
+			combined.FallbackLockTime = p.FallbackLockTime
+		}
+
+		for i := range p.Inputs {
+			combined.Inputs[i].merge(&p.Inputs[i])
+		}
+		for i := range p.Outputs {
+			combined.Outputs[i].merge(&p.Outputs[i])
+		}
+	}
+
+	return combined, nil
+}
+
+// merge adds the fields of the passed input which are missing from this one.
+func (pi *PInput) merge(o *PInput) {
+	if pi.NonWitnessUtxo == nil {
+		pi.NonWitnessUtxo = o.NonWitnessUtxo
+	}
+	if pi.WitnessUtxo == nil {
+		pi.WitnessUtxo = o.WitnessUtxo
+	}
+	for _, sig := range o.PartialSigs {
+		if !hasPartialSig(pi.PartialSigs, sig.PubKey) {
+			pi.PartialSigs = append(pi.PartialSigs, sig)
+		}
+	}
+	if pi.SighashType == 0 {
+		pi.SighashType = o.SighashType
+	}
+	if pi.RedeemScript == nil {
+		pi.RedeemScript = o.RedeemScript
+	}
+	if pi.WitnessScript == nil {
+		pi.WitnessScript = o.WitnessScript
+	}
+	pi.Bip32Derivation = mergeBip32Derivations(
+		pi.Bip32Derivation, o.Bip32Derivation,
+	)
+	if pi.FinalScriptSig == nil {
+		pi.FinalScriptSig = o.FinalScriptSig
+	}
+	if pi.FinalScriptWitness == nil {
+		pi.FinalScriptWitness = o.FinalScriptWitness
+	}
+	if pi.TaprootKeySpendSig == nil {
+		pi.TaprootKeySpendSig = o.TaprootKeySpendSig
+	}
+	for _, sig := range o.TaprootScriptSpendSig {
+		if !hasTaprootScriptSpendSig(pi.TaprootScriptSpendSig, sig) {
+			pi.TaprootScriptSpendSig = append(
+				pi.TaprootScriptSpendSig, sig,
+			)
+		}
+	}
+	for _, leaf := range o.TaprootLeafScript {
+		if !hasTaprootLeafScript(pi.TaprootLeafScript, leaf) {
+			pi.TaprootLeafScript = append(pi.TaprootLeafScript, leaf)
+		}
+	}
+	pi.TaprootBip32Derivation = mergeTaprootBip32Derivations(
+		pi.TaprootBip32Derivation, o.TaprootBip32Derivation,
+	)
+	if pi.TaprootInternalKey == nil {
+		pi.TaprootInternalKey = o.TaprootInternalKey
+	}
+	if pi.TaprootMerkleRoot == nil {
+		pi.TaprootMerkleRoot = o.TaprootMerkleRoot
+	}
+	pi.Unknowns = mergeUnknowns(pi.Unknowns, o.Unknowns)
+	if pi.RequiredTimeLockTime == 0 {
+		pi.RequiredTimeLockTime = o.RequiredTimeLockTime
+	}
+	if pi.RequiredHeightLockTime == 0 {
+		pi.RequiredHeightLockTime = o.RequiredHeightLockTime
+	}
+}
+
+// merge adds the fields of the passed output which are missing from this
+// one.
+func (po *POutput) merge(o *POutput) {
+	if po.RedeemScript == nil {
+		po.RedeemScript = o.RedeemScript
+	}
+	if po.WitnessScript == nil {
+		po.WitnessScript = o.WitnessScript
+	}
+	po.Bip32Derivation = mergeBip32Derivations(
+		po.Bip32Derivation, o.Bip32Derivation,
+	)
+	if po.TaprootInternalKey == nil {
+		po.TaprootInternalKey = o.TaprootInternalKey
+	}
+	if po.TaprootTapTree == nil {
+		po.TaprootTapTree = o.TaprootTapTree
+	}
+	po.TaprootBip32Derivation = mergeTaprootBip32Derivations(
+		po.TaprootBip32Derivation, o.TaprootBip32Derivation,
+	)
+	po.Unknowns = mergeUnknowns(po.Unknowns, o.Unknowns)
+}
+
+// hasXPub returns whether the list contains the passed extended key.
+func hasXPub(xPubs []XPub, extendedKey []byte) bool {
+	for _, x := range xPubs {
+		if bytes.Equal(x.ExtendedKey, extendedKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPartialSig returns whether the list contains a signature of the passed
+// public key.
+func hasPartialSig(sigs []*PartialSig, pubKey []byte) bool {
+	for _, s := range sigs {
+		if bytes.Equal(s.PubKey, pubKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasTaprootScriptSpendSig returns whether the list contains a signature of
+// the same key for the same leaf as the passed one.
+func hasTaprootScriptSpendSig(sigs []*TaprootScriptSpendSig,
+	sig *TaprootScriptSpendSig) bool {
+
+	for _, s := range sigs {
+		if bytes.Equal(s.XOnlyPubKey, sig.XOnlyPubKey) &&
+			bytes.Equal(s.LeafHash, sig.LeafHash) {
+
+			return true
+		}
+	}
+	return false
+}
+
+// hasTaprootLeafScript returns whether the list contains a leaf script with
+// the same control block as the passed one.
+func hasTaprootLeafScript(leaves []*TaprootTapLeafScript,
+	leaf *TaprootTapLeafScript) bool {
+
+	for _, l := range leaves {
+		if bytes.Equal(l.ControlBlock, leaf.ControlBlock) {
+			return true
+		}
+	}
+	return false
+}
+
+// mergeBip32Derivations returns the derivations of a followed by those of b
+// for public keys which aren't in a.
+func mergeBip32Derivations(a, b []*Bip32Derivation) []*Bip32Derivation {
+	for _, d := range b {
+		found := false
+		for _, e := range a {
+			if bytes.Equal(e.PubKey, d.PubKey) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, d)
+		}
+	}
+	return a
+}
+
+// mergeTaprootBip32Derivations returns the derivations of a followed by those
+// of b for public keys which aren't in a.
+func mergeTaprootBip32Derivations(a,
+	b []*TaprootBip32Derivation) []*TaprootBip32Derivation {
+
+	for _, d := range b {
+		found := false
+		for _, e := range a {
+			if bytes.Equal(e.XOnlyPubKey, d.XOnlyPubKey) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, d)
+		}
+	}
+	return a
+}
+
+// mergeUnknowns returns the unknowns of a followed by those of b with keys
+// which aren't in a.
+func mergeUnknowns(a, b []*Unknown) []*Unknown {
+	for _, u := range b {
+		found := false
+		for _, e := range a {
+			if bytes.Equal(e.Key, u.Key) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, u)
+		}
+	}
+	return a
+}
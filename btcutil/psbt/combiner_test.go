@@ -0,0 +1,98 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bynil/btcd/btcec/v2"
+	"github.com/bynil/btcd/txscript"
+	"github.com/bynil/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// TestCombine ensures the Combiner merges the fields of PSBTs for the same
+// transaction and rejects PSBTs for different transactions.
+func TestCombine(t *testing.T) {
+	newPacket := func() *Packet {
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(testTxIn(0))
+		tx.AddTxIn(testTxIn(1))
+		tx.AddTxOut(testTxOut(1000))
+		p, err := NewFromUnsignedTx(tx)
+		require.NoError(t, err)
+		return p
+	}
+	_, pubKey1 := btcec.PrivKeyFromBytes(bytes.Repeat([]byte{0x01}, 32))
+	_, pubKey2 := btcec.PrivKeyFromBytes(bytes.Repeat([]byte{0x02}, 32))
+	redeemScript := []byte{txscript.OP_TRUE}
+
+	// Each packet carries a different part of the data of the expected
+	// combined packet, and both have the same derivation of the first
+	// key.
+	update := func(u *Updater, first, second bool) {
+		path := []uint32{0x80000054, 0}
+		if first {
+			require.NoError(t, u.AddInBip32Derivation(
+				1, path, pubKey1.SerializeCompressed(), 0,
+			))
+			require.NoError(t, u.AddInWitnessUtxo(testTxOut(600), 0))
+			require.NoError(t, u.AddOutBip32Derivation(
+				1, path, pubKey1.SerializeCompressed(), 0,
+			))
+		}
+		if second {
+			if !first {
+				require.NoError(t, u.AddInBip32Derivation(
+					1, path, pubKey1.SerializeCompressed(),
+					0,
+				))
+			}
+			require.NoError(t, u.AddInBip32Derivation(
+				2, path, pubKey2.SerializeCompressed(), 0,
+			))
+			require.NoError(t, u.AddInRedeemScript(redeemScript, 1))
+			require.NoError(t, u.AddInSighashType(
+				txscript.SigHashAll, 1,
+			))
+			u.Upsbt.Unknowns = append(u.Upsbt.Unknowns, &Unknown{
+				Key: []byte{0xfc, 0x01}, Value: []byte{0x02},
+			})
+		}
+	}
+
+	a, b, expected := newPacket(), newPacket(), newPacket()
+	update(&Updater{Upsbt: a}, true, false)
+	update(&Updater{Upsbt: b}, false, true)
+	update(&Updater{Upsbt: expected}, true, true)
+	aBytes, bBytes := serializePacket(t, a), serializePacket(t, b)
+
+	combined, err := Combine(a, b)
+	require.NoError(t, err)
+	require.Equal(t, serializePacket(t, expected),
+		serializePacket(t, combined))
+
+	// The order of the packets doesn't matter without conflicting
+	// values, and the passed packets aren't modified.
+	combined, err = Combine(b, a)
+	require.NoError(t, err)
+	require.Equal(t, serializePacket(t, expected),
+		serializePacket(t, combined))
+	require.Equal(t, aBytes, serializePacket(t, a))
+	require.Equal(t, bBytes, serializePacket(t, b))
+
+	// A packet for a different transaction can't be combined.
+	other := newPacket()
+	other.UnsignedTx.TxOut[0].Value++
+	_, err = Combine(a, other)
+	require.ErrorIs(t, err, ErrDifferentTransactions)
+
+	// Neither can a packet of a different version.
+	v2 := newPacket()
+	require.NoError(t, v2.ConvertToV2())
+	_, err = Combine(a, v2)
+	require.ErrorIs(t, err, ErrDifferentTransactions)
+}
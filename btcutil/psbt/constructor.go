@@ -0,0 +1,223 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt
+
+// The Constructor role of BIP370 adds inputs and outputs to a version 2 PSBT
+// as long as its modifiable flags permit it.  The lock time of the transaction
+// is determined by the lock times its inputs require, rather than set by the
+// Creator.
+
+import (
+	"github.com/bynil/btcd/txscript"
+	"github.com/bynil/btcd/wire"
+)
+
+// AddInput adds the passed transaction input to a version 2 packet along with
+// its PSBT fields, which may include the lock times it requires.  An error is
+// returned if the inputs of the packet aren't modifiable, if the outpoint is
+// already spent by the packet, or if the lock time of the transaction can't be
+// determined with the new input.  The lock time may also not change when any
+// input is already signed.
+func (p *Packet) AddInput(txIn *wire.TxIn, pInput PInput) error {
+	if p.Version != 2 || p.TxModifiable&InputsModifiable == 0 {
+		return ErrInputsNotModifiable
+	}
+	if len(txIn.SignatureScript) != 0 || len(txIn.Witness) != 0 {
+		return ErrInvalidRawTxSigned
+	}
+	if !pInput.IsSane() {
+		return ErrInvalidPsbtFormat
+	}
+	for _, in := range p.UnsignedTx.TxIn {
+		if in.PreviousOutPoint == txIn.PreviousOutPoint {
+			return ErrInvalidPsbtFormat
+		}
+	}
+
+	inputs := append(p.Inputs[:len(p.Inputs):len(p.Inputs)], pInput)
+	lockTime, err := determineLockTime(inputs, p.FallbackLockTime)
+	if err != nil {
+		return err
+	}
+	if lockTime != p.UnsignedTx.LockTime && p.hasSignatures() {
+		return ErrLockTimeConflict
+	}
+
+	p.Inputs = inputs
+	p.UnsignedTx.AddTxIn(txIn)
+	p.UnsignedTx.LockTime = lockTime
+
+	return nil
+}
+
+// AddOutput adds the passed transaction output to a version 2 packet along
+// with its PSBT fields.  An error is returned if the outputs of the packet
+// aren't modifiable.
+func (p *Packet) AddOutput(txOut *wire.TxOut, pOutput POutput) error {
+	if p.Version != 2 || p.TxModifiable&OutputsModifiable == 0 {
+		return ErrOutputsNotModifiable
+	}
+
+	p.Outputs = append(p.Outputs, pOutput)
+	p.UnsignedTx.AddTxOut(txOut)
+
+	return nil
+}
+
+// DetermineLockTime returns the lock time of the transaction of the packet.
+// For a version 2 packet, this is the greatest of the lock times its inputs
+// require of the kind all of them support, preferring height based lock
+// times, or the fallback lock time when no input requires one.
+// ErrLockTimeConflict is returned when no kind of lock time is supported by
+// all the inputs.
+func (p *Packet) DetermineLockTime() (uint32, error) {
+	if p.Version != 2 {
+		return p.UnsignedTx.LockTime, nil
+	}
+
+	return determineLockTime(p.Inputs, p.FallbackLockTime)
+}
+
+// determineLockTime returns the lock time of a transaction with the passed
+// inputs and fallback lock time following BIP370.
+func determineLockTime(inputs []PInput, fallback *uint32) (uint32, error) {
+	var (
+		required           bool
+		heightOk, timeOk   = true, true
+		maxHeight, maxTime uint32
+	)
+	for _, in := range inputs {
+		// Inputs which don't require a lock time support both kinds.
+		if in.RequiredHeightLockTime == 0 && in.RequiredTimeLockTime == 0 {
+			continue
+		}
+		required = true
+
+		if in.RequiredHeightLockTime == 0 {
+			heightOk = false
+		}
+		if in.RequiredTimeLockTime == 0 {
+			timeOk = false
+		}
+		if in.RequiredHeightLockTime > maxHeight {
+			maxHeight = in.RequiredHeightLockTime
+		}
+		if in.RequiredTimeLockTime > maxTime {
+			maxTime = in.RequiredTimeLockTime
+		}
+	}
+
+	switch {
+	case !required && fallback != nil:
+		return *fallback, nil
+
+	case !required:
+		return 0, nil
+
+	case heightOk:
+		return maxHeight, nil
+
+	case timeOk:
+		return maxTime, nil
+	}
+
+	return 0, ErrLockTimeConflict
+}
+
+// hasSignatures returns whether any input of the packet is signed or
+// finalized.
+func (p *Packet) hasSignatures() bool {
+	for _, in := range p.Inputs {
+		if len(in.PartialSigs) != 0 || in.TaprootKeySpendSig != nil ||
+			len(in.TaprootScriptSpendSig) != 0 ||
+			in.FinalScriptSig != nil || in.FinalScriptWitness != nil {
+
+			return true
+		}
+	}
+
+	return false
+}
+
+// updateModifiable clears the modifiable flags of a version 2 packet which
+// are incompatible with a new signature of the passed sighash type, as
+// required of the Signer role by BIP370.
+func (p *Packet) updateModifiable(hashType txscript.SigHashType) {
+	if p.Version != 2 {
+		return
+	}
+
+	if hashType&txscript.SigHashAnyOneCanPay == 0 {
+		p.TxModifiable &^= InputsModifiable
+	}
+
+	switch hashType &^ txscript.SigHashAnyOneCanPay {
+	case txscript.SigHashNone:
+	case txscript.SigHashSingle:
+		p.TxModifiable &^= OutputsModifiable
+		p.TxModifiable |= HasSigHashSingle
+	default:
+		p.TxModifiable &^= OutputsModifiable
+	}
+}
+
+// ConvertToV2 converts a version 0 packet to version 2 in place.  The lock
+// time of its transaction becomes the fallback lock time of the packet when it
+// isn't otherwise determined, and none of its inputs or outputs become
+// modifiable.
+func (p *Packet) ConvertToV2() error {
+	switch p.Version {
+	case 0:
+	case 2:
+		return nil
+	default:
+		return ErrUnsupportedVersion
+	}
+
+	fallback := p.FallbackLockTime
+	lockTime, err := determineLockTime(p.Inputs, fallback)
+	if err != nil {
+		return err
+	}
+	if fallback == nil && lockTime != p.UnsignedTx.LockTime {
+		txLockTime := p.UnsignedTx.LockTime
+		fallback = &txLockTime
+
+		lockTime, err = determineLockTime(p.Inputs, fallback)
+		if err != nil {
+			return err
+		}
+	}
+
+	p.Version = 2
+	p.FallbackLockTime = fallback
+	p.UnsignedTx.LockTime = lockTime
+
+	return nil
+}
+
+// ConvertToV0 converts a version 2 packet to version 0 in place, which embeds
+// its transaction with the lock time determined by its inputs.  The fields
+// specific to version 2 are kept, so converting back to version 2 recovers the
+// original packet, but they are not serialized while the packet is version 0.
+func (p *Packet) ConvertToV0() error {
+	switch p.Version {
+	case 0:
+		return nil
+	case 2:
+	default:
+		return ErrUnsupportedVersion
+	}
+
+	lockTime, err := p.DetermineLockTime()
+	if err != nil {
+		return err
+	}
+
+	p.Version = 0
+	p.UnsignedTx.LockTime = lockTime
+
+	return nil
+}
@@ -0,0 +1,387 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	"github.com/bynil/btcd/txscript"
+	"github.com/bynil/btcd/wire"
+	"github.com/stretchr/testify/require"
+)
+
+// testTxIn returns an unsigned transaction input spending the output of the
+// passed index of a dummy transaction.
+func testTxIn(index uint32) *wire.TxIn {
+	return &wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{
+			Hash:  chainhash.Hash{0x01, 0x02, 0x03},
+			Index: index,
+		},
+		Sequence: wire.MaxTxInSequenceNum,
+	}
+}
+
+// testTxOut returns a transaction output paying the passed amount to a dummy
+// P2WPKH script.
+func testTxOut(value int64) *wire.TxOut {
+	script := append([]byte{txscript.OP_0, txscript.OP_DATA_20},
+		bytes.Repeat([]byte{0xaa}, 20)...)
+	return wire.NewTxOut(value, script)
+}
+
+// serializePacket returns the serialization of the passed packet.
+func serializePacket(t *testing.T, p *Packet) []byte {
+	var b bytes.Buffer
+	require.NoError(t, p.Serialize(&b))
+	return b.Bytes()
+}
+
+// TestPsbtV2Constructor ensures inputs and outputs can be added to a version 2
+// packet and that the packet survives a serialization round trip.
+func TestPsbtV2Constructor(t *testing.T) {
+	fallback := uint32(100)
+	p, err := NewV2(2, &fallback, InputsModifiable|OutputsModifiable)
+	require.NoError(t, err)
+
+	lockTime, err := p.DetermineLockTime()
+	require.NoError(t, err)
+	require.Equal(t, fallback, lockTime)
+
+	// The first input requires a height based lock time, and the second
+	// one supports both kinds, so the greatest height is used.
+	err = p.AddInput(testTxIn(0), PInput{RequiredHeightLockTime: 1000})
+	require.NoError(t, err)
+	require.Equal(t, uint32(1000), p.UnsignedTx.LockTime)
+
+	txIn := testTxIn(1)
+	txIn.Sequence = 10
+	err = p.AddInput(txIn, PInput{
+		RequiredHeightLockTime: 2000,
+		RequiredTimeLockTime:   600000000,
+		WitnessUtxo:            testTxOut(50000),
+	})
+	require.NoError(t, err)
+	require.Equal(t, uint32(2000), p.UnsignedTx.LockTime)
+
+	// An input which only supports time based lock times can't be added.
+	err = p.AddInput(testTxIn(2), PInput{RequiredTimeLockTime: 600000000})
+	require.ErrorIs(t, err, ErrLockTimeConflict)
+	require.Len(t, p.Inputs, 2)
+
+	// An outpoint can't be spent twice.
+	err = p.AddInput(testTxIn(1), PInput{})
+	require.ErrorIs(t, err, ErrInvalidPsbtFormat)
+
+	require.NoError(t, p.AddOutput(testTxOut(40000), POutput{}))
+	require.NoError(t, p.SanityCheck())
+
+	// Parsing the serialized packet must recover it exactly.
+	serialized := serializePacket(t, p)
+	parsed, err := NewFromRawBytes(bytes.NewReader(serialized), false)
+	require.NoError(t, err)
+	require.Equal(t, uint32(2), parsed.Version)
+	require.Equal(t, p.UnsignedTx, parsed.UnsignedTx)
+	require.Equal(t, p.FallbackLockTime, parsed.FallbackLockTime)
+	require.Equal(t, p.TxModifiable, parsed.TxModifiable)
+	require.Equal(t, p.Inputs[0].RequiredHeightLockTime,
+		parsed.Inputs[0].RequiredHeightLockTime)
+	require.Equal(t, p.Inputs[1].RequiredTimeLockTime,
+		parsed.Inputs[1].RequiredTimeLockTime)
+	require.Equal(t, p.Inputs[1].WitnessUtxo, parsed.Inputs[1].WitnessUtxo)
+	require.Equal(t, serialized, serializePacket(t, parsed))
+}
+
+// TestPsbtV2Modifiable ensures the modifiable flags of a version 2 packet are
+// enforced and updated by signatures.
+func TestPsbtV2Modifiable(t *testing.T) {
+	// A version 0 packet is never modifiable.
+	p, err := New(nil, nil, 2, 0, nil)
+	require.NoError(t, err)
+	require.ErrorIs(t, p.AddInput(testTxIn(0), PInput{}),
+		ErrInputsNotModifiable)
+	require.ErrorIs(t, p.AddOutput(testTxOut(1000), POutput{}),
+		ErrOutputsNotModifiable)
+
+	p, err = NewV2(2, nil, OutputsModifiable)
+	require.NoError(t, err)
+	require.ErrorIs(t, p.AddInput(testTxIn(0), PInput{}),
+		ErrInputsNotModifiable)
+	require.NoError(t, p.AddOutput(testTxOut(1000), POutput{}))
+
+	tests := []struct {
+		hashType txscript.SigHashType
+		flags    ModifiableFlags
+	}{
+		{txscript.SigHashAll, 0},
+		{txscript.SigHashNone, OutputsModifiable},
+		{txscript.SigHashSingle, HasSigHashSingle},
+		{
+			txscript.SigHashAll | txscript.SigHashAnyOneCanPay,
+			InputsModifiable,
+		},
+		{
+			txscript.SigHashNone | txscript.SigHashAnyOneCanPay,
+			InputsModifiable | OutputsModifiable,
+		},
+		{
+			txscript.SigHashSingle | txscript.SigHashAnyOneCanPay,
+			InputsModifiable | HasSigHashSingle,
+		},
+	}
+	for _, test := range tests {
+		p, err := NewV2(2, nil, InputsModifiable|OutputsModifiable)
+		require.NoError(t, err)
+		p.updateModifiable(test.hashType)
+		require.Equal(t, test.flags, p.TxModifiable, "%v", test.hashType)
+	}
+
+	// Once an input is signed, an input which changes the lock time can't
+	// be added anymore.
+	p, err = NewV2(2, nil, InputsModifiable)
+	require.NoError(t, err)
+	require.NoError(t, p.AddInput(testTxIn(0), PInput{
+		RequiredHeightLockTime: 1000,
+		PartialSigs:            []*PartialSig{{}},
+	}))
+	err = p.AddInput(testTxIn(1), PInput{RequiredHeightLockTime: 2000})
+	require.ErrorIs(t, err, ErrLockTimeConflict)
+	require.NoError(t, p.AddInput(testTxIn(1), PInput{
+		RequiredHeightLockTime: 500,
+	}))
+}
+
+// TestDetermineLockTime ensures the lock time of a version 2 packet is
+// determined as specified by BIP370.
+func TestDetermineLockTime(t *testing.T) {
+	fallback := uint32(42)
+	tests := []struct {
+		name     string
+		fallback *uint32
+		inputs   []PInput
+		lockTime uint32
+		err      error
+	}{{
+		name: "no inputs",
+	}, {
+		name:     "fallback",
+		fallback: &fallback,
+		inputs:   []PInput{{}},
+		lockTime: fallback,
+	}, {
+		name:     "greatest height",
+		fallback: &fallback,
+		inputs: []PInput{
+			{RequiredHeightLockTime: 10},
+			{},
+			{RequiredHeightLockTime: 20},
+		},
+		lockTime: 20,
+	}, {
+		name: "height preferred",
+		inputs: []PInput{{
+			RequiredHeightLockTime: 10,
+			RequiredTimeLockTime:   500000010,
+		}, {
+			RequiredHeightLockTime: 5,
+			RequiredTimeLockTime:   500000020,
+		}},
+		lockTime: 10,
+	}, {
+		name: "time supported by all",
+		inputs: []PInput{{
+			RequiredHeightLockTime: 10,
+			RequiredTimeLockTime:   500000010,
+		}, {
+			RequiredTimeLockTime: 500000020,
+		}},
+		lockTime: 500000020,
+	}, {
+		name: "conflict",
+		inputs: []PInput{
+			{RequiredHeightLockTime: 10},
+			{RequiredTimeLockTime: 500000020},
+		},
+		err: ErrLockTimeConflict,
+	}}
+
+	for _, test := range tests {
+		lockTime, err := determineLockTime(test.inputs, test.fallback)
+		require.ErrorIs(t, err, test.err, test.name)
+		require.Equal(t, test.lockTime, lockTime, test.name)
+	}
+}
+
+// TestPsbtVersionConversion ensures packets are converted between version 0
+// and version 2 without losing any information.
+func TestPsbtVersionConversion(t *testing.T) {
+	// Convert a version 0 packet to version 2 and back.
+	p, err := New(
+		[]*wire.OutPoint{&testTxIn(0).PreviousOutPoint},
+		[]*wire.TxOut{testTxOut(1000)}, 2, 1234,
+		[]uint32{wire.MaxTxInSequenceNum - 1},
+	)
+	require.NoError(t, err)
+	p.Inputs[0].WitnessUtxo = testTxOut(2000)
+	v0 := serializePacket(t, p)
+
+	require.NoError(t, p.ConvertToV2())
+	require.Equal(t, uint32(2), p.Version)
+	require.Equal(t, uint32(1234), *p.FallbackLockTime)
+
+	parsed, err := NewFromRawBytes(
+		bytes.NewReader(serializePacket(t, p)), false,
+	)
+	require.NoError(t, err)
+	require.Equal(t, p.UnsignedTx, parsed.UnsignedTx)
+	require.NoError(t, parsed.ConvertToV0())
+	require.Equal(t, v0, serializePacket(t, parsed))
+
+	// Convert a version 2 packet with required lock times to version 0
+	// and back.
+	p, err = NewV2(2, nil, InputsModifiable|OutputsModifiable)
+	require.NoError(t, err)
+	err = p.AddInput(testTxIn(0), PInput{RequiredTimeLockTime: 600000000})
+	require.NoError(t, err)
+	require.NoError(t, p.AddOutput(testTxOut(1000), POutput{}))
+	v2 := serializePacket(t, p)
+
+	require.NoError(t, p.ConvertToV0())
+	require.Equal(t, uint32(600000000), p.UnsignedTx.LockTime)
+	parsed, err = NewFromRawBytes(
+		bytes.NewReader(serializePacket(t, p)), false,
+	)
+	require.NoError(t, err)
+	require.Equal(t, p.UnsignedTx.TxHash(), parsed.UnsignedTx.TxHash())
+
+	require.NoError(t, p.ConvertToV2())
+	require.Equal(t, v2, serializePacket(t, p))
+}
+
+// TestReadInvalidPsbtV2 ensures serializations which violate the version
+// rules of BIP370 are rejected.
+func TestReadInvalidPsbtV2(t *testing.T) {
+	u32 := func(v uint32) []byte {
+		return []byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)}
+	}
+	txid := make([]byte, 32)
+
+	type kv struct {
+		keyType uint8
+		value   []byte
+	}
+	v2Globals := []kv{
+		{uint8(TxVersionType), u32(2)},
+		{uint8(InputCountType), []byte{1}},
+		{uint8(OutputCountType), []byte{0}},
+		{uint8(VersionType), u32(2)},
+	}
+	validInput := []kv{
+		{uint8(PreviousTxidType), txid},
+		{uint8(OutputIndexType), u32(0)},
+	}
+
+	tests := []struct {
+		name    string
+		globals []kv
+		input   []kv
+		err     error
+	}{{
+		name: "missing input count",
+		globals: []kv{
+			{uint8(TxVersionType), u32(2)},
+			{uint8(OutputCountType), []byte{0}},
+			{uint8(VersionType), u32(2)},
+		},
+		input: validInput,
+		err:   ErrInvalidPsbtFormat,
+	}, {
+		name: "unsupported version",
+		globals: []kv{
+			{uint8(TxVersionType), u32(2)},
+			{uint8(InputCountType), []byte{1}},
+			{uint8(OutputCountType), []byte{0}},
+			{uint8(VersionType), u32(3)},
+		},
+		input: validInput,
+		err:   ErrUnsupportedVersion,
+	}, {
+		name:    "missing output index",
+		globals: v2Globals,
+		input:   []kv{{uint8(PreviousTxidType), txid}},
+		err:     ErrInvalidPsbtFormat,
+	}, {
+		name:    "short txid",
+		globals: v2Globals,
+		input: []kv{
+			{uint8(PreviousTxidType), txid[:31]},
+			{uint8(OutputIndexType), u32(0)},
+		},
+		err: ErrInvalidKeyData,
+	}, {
+		name:    "height as required time lock time",
+		globals: v2Globals,
+		input: append(validInput[:2:2], kv{
+			uint8(RequiredTimeLockTimeType), u32(100),
+		}),
+		err: ErrInvalidKeyData,
+	}, {
+		name:    "time as required height lock time",
+		globals: v2Globals,
+		input: append(validInput[:2:2], kv{
+			uint8(RequiredHeightLockTimeType), u32(500000000),
+		}),
+		err: ErrInvalidKeyData,
+	}}
+
+	for _, test := range tests {
+		var b bytes.Buffer
+		b.Write(psbtMagic[:])
+		for _, field := range test.globals {
+			err := serializeKVPairWithType(
+				&b, field.keyType, nil, field.value,
+			)
+			require.NoError(t, err)
+		}
+		b.WriteByte(0)
+		for _, field := range test.input {
+			err := serializeKVPairWithType(
+				&b, field.keyType, nil, field.value,
+			)
+			require.NoError(t, err)
+		}
+		b.WriteByte(0)
+
+		_, err := NewFromRawBytes(&b, false)
+		require.ErrorIs(t, err, test.err, test.name)
+	}
+
+	// A version 0 packet must not contain the fields of version 2, so
+	// splice them into its input section.
+	p, err := New(
+		[]*wire.OutPoint{&testTxIn(0).PreviousOutPoint},
+		[]*wire.TxOut{testTxOut(1000)}, 2, 0,
+		[]uint32{wire.MaxTxInSequenceNum},
+	)
+	require.NoError(t, err)
+
+	var b, tx bytes.Buffer
+	require.NoError(t, p.UnsignedTx.SerializeNoWitness(&tx))
+	b.Write(psbtMagic[:])
+	require.NoError(t, serializeKVPairWithType(
+		&b, uint8(UnsignedTxType), nil, tx.Bytes(),
+	))
+	b.WriteByte(0)
+	require.NoError(t, p.Inputs[0].serializeV2Fields(
+		&b, p.UnsignedTx.TxIn[0],
+	))
+	b.WriteByte(0)
+	b.WriteByte(0)
+
+	_, err = NewFromRawBytes(&b, false)
+	require.ErrorIs(t, err, ErrInvalidPsbtFormat)
+}
@@ -61,3 +61,31 @@ func New(inputs []*wire.OutPoint,
 		Unknowns:   nil,
 	}, nil
 }
+
+// NewV2 creates a new version 2 PSBT packet (BIP370) without any inputs or
+// outputs, which are added with AddInput and AddOutput as permitted by the
+// passed modifiable flags.  The fallback lock time is used as the lock time of
+// the transaction when none of its inputs require one, and may be nil to use
+// a lock time of zero.  Referencing the PSBT BIP, this function serves the
+// role of the Creator.
+func NewV2(version int32, fallbackLockTime *uint32,
+	modifiable ModifiableFlags) (*Packet, error) {
+
+	if version < MinTxVersion {
+		return nil, ErrInvalidPsbtFormat
+	}
+
+	unsignedTx := wire.NewMsgTx(version)
+	if fallbackLockTime != nil {
+		unsignedTx.LockTime = *fallbackLockTime
+	}
+
+	return &Packet{
+		Version:          2,
+		UnsignedTx:       unsignedTx,
+		Inputs:           []PInput{},
+		Outputs:          []POutput{},
+		FallbackLockTime: fallbackLockTime,
+		TxModifiable:     modifiable,
+	}, nil
+}
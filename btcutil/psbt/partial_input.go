@@ -6,6 +6,7 @@ import (
 	"io"
 	"sort"
 
+	"github.com/bynil/btcd/chaincfg/chainhash"
 	"github.com/bynil/btcd/txscript"
 	"github.com/bynil/btcd/wire"
 )
@@ -29,6 +30,12 @@ type PInput struct {
 	TaprootInternalKey     []byte
 	TaprootMerkleRoot      []byte
 	Unknowns               []*Unknown
+
+	// RequiredTimeLockTime and RequiredHeightLockTime are the minimum
+	// time and height based lock times the input of a version 2 PSBT
+	// requires, or zero if it doesn't require one.
+	RequiredTimeLockTime   uint32
+	RequiredHeightLockTime uint32
 }
 
 // NewPsbtInput creates an instance of PsbtInput given either a nonWitnessUtxo
@@ -60,11 +67,24 @@ func (pi *PInput) IsSane() bool {
 	// only one is set anymore.
 	// See https://github.com/bitcoin/bitcoin/pull/19215.
 
+	// The required lock times must be of their kind.
+	if pi.RequiredTimeLockTime != 0 &&
+		pi.RequiredTimeLockTime < txscript.LockTimeThreshold {
+
+		return false
+	}
+	if pi.RequiredHeightLockTime >= txscript.LockTimeThreshold {
+		return false
+	}
+
 	return true
 }
 
 // deserialize attempts to deserialize a new PInput from the passed io.Reader.
-func (pi *PInput) deserialize(r io.Reader) error {
+// The input of a version 2 packet also describes the transaction input which
+// is decoded into the passed txIn, which must be nil for version 0.
+func (pi *PInput) deserialize(r io.Reader, txIn *wire.TxIn) error {
+	var hasTxid, hasIndex, hasSequence bool
 	for {
 		keyCode, keyData, err := getKey(r)
 		if err != nil {
@@ -81,6 +101,17 @@ func (pi *PInput) deserialize(r io.Reader) error {
 			return err
 		}
 
+		// The fields of version 2 packets have no key data, so keys of
+		// their types with key data are unknown ones.
+		if keyData != nil && InputType(keyCode) >= PreviousTxidType &&
+			InputType(keyCode) <= RequiredHeightLockTimeType {
+
+			if err := pi.addUnknown(keyCode, keyData, value); err != nil {
+				return err
+			}
+			continue
+		}
+
 		switch InputType(keyCode) {
 
 		case NonWitnessUtxoType:
@@ -363,38 +394,109 @@ func (pi *PInput) deserialize(r io.Reader) error {
 
 			pi.TaprootMerkleRoot = value
 
+		case PreviousTxidType:
+			if txIn == nil {
+				return ErrInvalidPsbtFormat
+			}
+			if hasTxid {
+				return ErrDuplicateKey
+			}
+			if len(value) != chainhash.HashSize {
+				return ErrInvalidKeyData
+			}
+
+			copy(txIn.PreviousOutPoint.Hash[:], value)
+			hasTxid = true
+
+		case OutputIndexType, SequenceType:
+			if txIn == nil {
+				return ErrInvalidPsbtFormat
+			}
+			field, present := &txIn.PreviousOutPoint.Index, &hasIndex
+			if InputType(keyCode) == SequenceType {
+				field, present = &txIn.Sequence, &hasSequence
+			}
+			if *present {
+				return ErrDuplicateKey
+			}
+			if len(value) != 4 {
+				return ErrInvalidKeyData
+			}
+
+			*field = binary.LittleEndian.Uint32(value)
+			*present = true
+
+		case RequiredTimeLockTimeType, RequiredHeightLockTimeType:
+			if txIn == nil {
+				return ErrInvalidPsbtFormat
+			}
+			field := &pi.RequiredTimeLockTime
+			if InputType(keyCode) == RequiredHeightLockTimeType {
+				field = &pi.RequiredHeightLockTime
+			}
+			if *field != 0 {
+				return ErrDuplicateKey
+			}
+			if len(value) != 4 {
+				return ErrInvalidKeyData
+			}
+
+			*field = binary.LittleEndian.Uint32(value)
+			if *field == 0 || !pi.IsSane() {
+				return ErrInvalidKeyData
+			}
+
 		default:
 			// A fall through case for any proprietary types.
-			keyCodeAndData := append(
-				[]byte{byte(keyCode)}, keyData...,
-			)
-			newUnknown := &Unknown{
-				Key:   keyCodeAndData,
-				Value: value,
+			if err := pi.addUnknown(keyCode, keyData, value); err != nil {
+				return err
 			}
+		}
+	}
 
-			// Duplicate key+keyData are not allowed.
-			for _, x := range pi.Unknowns {
-				if bytes.Equal(x.Key, newUnknown.Key) &&
-					bytes.Equal(x.Value, newUnknown.Value) {
+	// The input of a version 2 packet must identify the output it spends.
+	if txIn != nil && (!hasTxid || !hasIndex) {
+		return ErrInvalidPsbtFormat
+	}
 
-					return ErrDuplicateKey
-				}
-			}
+	return nil
+}
+
+// addUnknown adds a key-value pair of an unknown type to the input.
+func (pi *PInput) addUnknown(keyCode int, keyData, value []byte) error {
+	keyCodeAndData := append([]byte{byte(keyCode)}, keyData...)
+	newUnknown := &Unknown{
+		Key:   keyCodeAndData,
+		Value: value,
+	}
+
+	// Duplicate key+keyData are not allowed.
+	for _, x := range pi.Unknowns {
+		if bytes.Equal(x.Key, newUnknown.Key) &&
+			bytes.Equal(x.Value, newUnknown.Value) {
 
-			pi.Unknowns = append(pi.Unknowns, newUnknown)
+			return ErrDuplicateKey
 		}
 	}
 
+	pi.Unknowns = append(pi.Unknowns, newUnknown)
 	return nil
 }
 
 // serialize attempts to serialize the target PInput into the passed io.Writer.
-func (pi *PInput) serialize(w io.Writer) error {
+// The input of a version 2 packet also describes the passed transaction input,
+// which must be nil for version 0.
+func (pi *PInput) serialize(w io.Writer, txIn *wire.TxIn) error {
 	if !pi.IsSane() {
 		return ErrInvalidPsbtFormat
 	}
 
+	if txIn != nil {
+		if err := pi.serializeV2Fields(w, txIn); err != nil {
+			return err
+		}
+	}
+
 	if pi.NonWitnessUtxo != nil {
 		var buf bytes.Buffer
 		err := pi.NonWitnessUtxo.Serialize(&buf)
@@ -603,3 +705,53 @@ func (pi *PInput) serialize(w io.Writer) error {
 
 	return nil
 }
+
+// serializeV2Fields writes the fields which describe the passed transaction
+// input of a version 2 packet, along with the lock times it requires.
+func (pi *PInput) serializeV2Fields(w io.Writer, txIn *wire.TxIn) error {
+	err := serializeKVPairWithType(
+		w, uint8(PreviousTxidType), nil, txIn.PreviousOutPoint.Hash[:],
+	)
+	if err != nil {
+		return err
+	}
+
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], txIn.PreviousOutPoint.Index)
+	err = serializeKVPairWithType(w, uint8(OutputIndexType), nil, buf[:])
+	if err != nil {
+		return err
+	}
+
+	if txIn.Sequence != wire.MaxTxInSequenceNum {
+		binary.LittleEndian.PutUint32(buf[:], txIn.Sequence)
+		err := serializeKVPairWithType(
+			w, uint8(SequenceType), nil, buf[:],
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if pi.RequiredTimeLockTime != 0 {
+		binary.LittleEndian.PutUint32(buf[:], pi.RequiredTimeLockTime)
+		err := serializeKVPairWithType(
+			w, uint8(RequiredTimeLockTimeType), nil, buf[:],
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	if pi.RequiredHeightLockTime != 0 {
+		binary.LittleEndian.PutUint32(buf[:], pi.RequiredHeightLockTime)
+		err := serializeKVPairWithType(
+			w, uint8(RequiredHeightLockTimeType), nil, buf[:],
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
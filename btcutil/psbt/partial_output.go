@@ -2,6 +2,7 @@ package psbt
 
 import (
 	"bytes"
+	"encoding/binary"
 	"io"
 	"sort"
 
@@ -33,7 +34,10 @@ func NewPsbtOutput(redeemScript []byte, witnessScript []byte,
 }
 
 // deserialize attempts to recode a new POutput from the passed io.Reader.
-func (po *POutput) deserialize(r io.Reader) error {
+// The output of a version 2 packet also describes the transaction output which
+// is decoded into the passed txOut, which must be nil for version 0.
+func (po *POutput) deserialize(r io.Reader, txOut *wire.TxOut) error {
+	var hasAmount, hasScript bool
 	for {
 		keyCode, keyData, err := getKey(r)
 		if err != nil {
@@ -51,6 +55,17 @@ func (po *POutput) deserialize(r io.Reader) error {
 			return err
 		}
 
+		// The fields of version 2 packets have no key data, so keys of
+		// their types with key data are unknown ones.
+		if keyData != nil && (OutputType(keyCode) == AmountType ||
+			OutputType(keyCode) == ScriptType) {
+
+			if err := po.addUnknown(keyCode, keyData, value); err != nil {
+				return err
+			}
+			continue
+		}
+
 		switch OutputType(keyCode) {
 
 		case RedeemScriptOutputType:
@@ -144,35 +159,90 @@ func (po *POutput) deserialize(r io.Reader) error {
 				po.TaprootBip32Derivation, taprootDerivation,
 			)
 
+		case AmountType:
+			if txOut == nil {
+				return ErrInvalidPsbtFormat
+			}
+			if hasAmount {
+				return ErrDuplicateKey
+			}
+			if len(value) != 8 {
+				return ErrInvalidKeyData
+			}
+
+			txOut.Value = int64(binary.LittleEndian.Uint64(value))
+			hasAmount = true
+
+		case ScriptType:
+			if txOut == nil {
+				return ErrInvalidPsbtFormat
+			}
+			if hasScript {
+				return ErrDuplicateKey
+			}
+
+			txOut.PkScript = value
+			hasScript = true
+
 		default:
 			// A fall through case for any proprietary types.
-			keyCodeAndData := append(
-				[]byte{byte(keyCode)}, keyData...,
-			)
-			newUnknown := &Unknown{
-				Key:   keyCodeAndData,
-				Value: value,
+			if err := po.addUnknown(keyCode, keyData, value); err != nil {
+				return err
 			}
+		}
+	}
 
-			// Duplicate key+keyData are not allowed.
-			for _, x := range po.Unknowns {
-				if bytes.Equal(x.Key, newUnknown.Key) &&
-					bytes.Equal(x.Value, newUnknown.Value) {
+	// The output of a version 2 packet must have an amount and a script.
+	if txOut != nil && (!hasAmount || !hasScript) {
+		return ErrInvalidPsbtFormat
+	}
 
-					return ErrDuplicateKey
-				}
-			}
+	return nil
+}
 
-			po.Unknowns = append(po.Unknowns, newUnknown)
+// addUnknown adds a key-value pair of an unknown type to the output.
+func (po *POutput) addUnknown(keyCode int, keyData, value []byte) error {
+	keyCodeAndData := append([]byte{byte(keyCode)}, keyData...)
+	newUnknown := &Unknown{
+		Key:   keyCodeAndData,
+		Value: value,
+	}
+
+	// Duplicate key+keyData are not allowed.
+	for _, x := range po.Unknowns {
+		if bytes.Equal(x.Key, newUnknown.Key) &&
+			bytes.Equal(x.Value, newUnknown.Value) {
+
+			return ErrDuplicateKey
 		}
 	}
 
+	po.Unknowns = append(po.Unknowns, newUnknown)
 	return nil
 }
 
 // serialize attempts to write out the target POutput into the passed
-// io.Writer.
-func (po *POutput) serialize(w io.Writer) error {
+// io.Writer. The output of a version 2 packet also describes the passed
+// transaction output, which must be nil for version 0.
+func (po *POutput) serialize(w io.Writer, txOut *wire.TxOut) error {
+	if txOut != nil {
+		var amount [8]byte
+		binary.LittleEndian.PutUint64(amount[:], uint64(txOut.Value))
+		err := serializeKVPairWithType(
+			w, uint8(AmountType), nil, amount[:],
+		)
+		if err != nil {
+			return err
+		}
+
+		err = serializeKVPairWithType(
+			w, uint8(ScriptType), nil, txOut.PkScript,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
 	if po.RedeemScript != nil {
 		err := serializeKVPairWithType(
 			w, uint8(RedeemScriptOutputType), nil, po.RedeemScript,
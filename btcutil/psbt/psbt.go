@@ -10,6 +10,7 @@ package psbt
 import (
 	"bytes"
 	"encoding/base64"
+	"encoding/binary"
 	"errors"
 	"io"
 
@@ -109,6 +110,47 @@ var (
 	// script witness given is not supported by this codebase, or is
 	// otherwise not valid.
 	ErrUnsupportedScriptType = errors.New("Unsupported script type")
+
+	// ErrUnsupportedVersion indicates that a passed Psbt serialization has
+	// a version other than 0 or 2, which are the versions defined by
+	// BIP174 and BIP370.
+	ErrUnsupportedVersion = errors.New("Unsupported PSBT version")
+
+	// ErrLockTimeConflict indicates that the lock time of the transaction
+	// of a version 2 PSBT can't be determined, because its inputs require
+	// both height and time based lock times, or that adding an input
+	// would change the lock time of a transaction which is already signed.
+	ErrLockTimeConflict = errors.New("Conflicting lock time requirements")
+
+	// ErrInputsNotModifiable indicates that an input can't be added to a
+	// PSBT, since it isn't version 2 or its inputs aren't modifiable.
+	ErrInputsNotModifiable = errors.New("PSBT inputs are not modifiable")
+
+	// ErrOutputsNotModifiable indicates that an output can't be added to a
+	// PSBT, since it isn't version 2 or its outputs aren't modifiable.
+	ErrOutputsNotModifiable = errors.New("PSBT outputs are not " +
+		"modifiable")
+)
+
+// ModifiableFlags is the bit field of the PSBT_GLOBAL_TX_MODIFIABLE field of
+// a version 2 PSBT, which reports whether inputs and outputs may still be
+// added to the packet.
+type ModifiableFlags uint8
+
+const (
+	// InputsModifiable indicates that inputs may be added to the packet.
+	// It is cleared by a signature which doesn't use SIGHASH_ANYONECANPAY.
+	InputsModifiable ModifiableFlags = 1 << 0
+
+	// OutputsModifiable indicates that outputs may be added to the
+	// packet. It is cleared by a signature which doesn't use
+	// SIGHASH_NONE.
+	OutputsModifiable ModifiableFlags = 1 << 1
+
+	// HasSigHashSingle indicates that the packet has a signature using
+	// SIGHASH_SINGLE, so the input and output of the same index must stay
+	// paired.
+	HasSigHashSingle ModifiableFlags = 1 << 2
 )
 
 // Unknown is a struct encapsulating a key-value pair for which the key type is
@@ -123,8 +165,18 @@ type Unknown struct {
 // key-value pair lists, 1 global, defining the unsigned transaction structure
 // with N inputs and M outputs.  These key-value pairs can contain scripts,
 // signatures, key derivations and other transaction-defining data.
+//
+// Both version 0 (BIP174) and version 2 (BIP370) packets are represented by
+// their unsigned transaction.  A version 2 packet serializes the transaction
+// as fields of its global, input and output sections rather than embedding it,
+// and determines its lock time from the lock times its inputs require.
 type Packet struct {
-	// UnsignedTx is the decoded unsigned transaction for this PSBT.
+	// Version is the version of this PSBT, either 0 or 2.
+	Version uint32
+
+	// UnsignedTx is the decoded unsigned transaction for this PSBT.  The
+	// lock time of the transaction of a version 2 PSBT is the one
+	// determined by DetermineLockTime.
 	UnsignedTx *wire.MsgTx // Deserialization of unsigned tx
 
 	// Inputs contains all the information needed to properly sign this
@@ -144,6 +196,15 @@ type Packet struct {
 
 	// Unknowns are the set of custom types (global only) within this PSBT.
 	Unknowns []*Unknown
+
+	// FallbackLockTime is the lock time of the transaction of a version 2
+	// PSBT when none of its inputs require one.  A nil value means a
+	// fallback lock time of zero which isn't serialized.
+	FallbackLockTime *uint32
+
+	// TxModifiable reports whether inputs and outputs may be added to a
+	// version 2 PSBT.
+	TxModifiable ModifiableFlags
 }
 
 // validateUnsignedTx returns true if the transaction is unsigned.  Note that
@@ -206,44 +267,22 @@ func NewFromRawBytes(r io.Reader, b64 bool) (*Packet, error) {
 		return nil, ErrInvalidMagicBytes
 	}
 
-	// Next we parse the GLOBAL section.  There is currently only 1 known
-	// key type, UnsignedTx.  We insist this exists first; unknowns are
-	// allowed, but only after.
-	keyCode, keyData, err := getKey(r)
-	if err != nil {
-		return nil, err
-	}
-	if GlobalType(keyCode) != UnsignedTxType || keyData != nil {
-		return nil, ErrInvalidPsbtFormat
-	}
-
-	// Now that we've verified the global type is present, we'll decode it
-	// into a proper unsigned transaction, and validate it.
-	value, err := wire.ReadVarBytes(
-		r, 0, MaxPsbtValueLength, "PSBT value",
-	)
-	if err != nil {
-		return nil, err
-	}
-	msgTx := wire.NewMsgTx(2)
-
-	// BIP-0174 states: "The transaction must be in the old serialization
-	// format (without witnesses)."
-	err = msgTx.DeserializeNoWitness(bytes.NewReader(value))
-	if err != nil {
-		return nil, err
-	}
-	if !validateUnsignedTX(msgTx) {
-		return nil, ErrInvalidRawTxSigned
-	}
-
-	// Next we parse any unknowns that may be present, making sure that we
-	// break at the separator.
+	// Next we parse the GLOBAL section.  A version 0 packet must start
+	// with the unsigned transaction, while a version 2 packet describes
+	// the transaction with fields of the global, input and output sections
+	// instead.
 	var (
+		msgTx        *wire.MsgTx
 		xPubSlice    []XPub
 		unknownSlice []*Unknown
+		version      uint32
+		txVersion    *int32
+		fallback     *uint32
+		inputCount   *uint64
+		outputCount  *uint64
+		modifiable   *ModifiableFlags
 	)
-	for {
+	for i := 0; ; i++ {
 		keyint, keydata, err := getKey(r)
 		if err != nil {
 			return nil, ErrInvalidPsbtFormat
@@ -259,7 +298,37 @@ func NewFromRawBytes(r io.Reader, b64 bool) (*Packet, error) {
 			return nil, err
 		}
 
-		switch GlobalType(keyint) {
+		// The fields of version 2 packets have no key data, so keys of
+		// their types with key data are unknown ones, which are kept
+		// like proprietary ones.
+		globalType := GlobalType(keyint)
+		if keydata != nil && globalType >= TxVersionType &&
+			globalType <= TxModifiableType {
+
+			globalType = ProprietaryGlobalType
+		}
+
+		switch globalType {
+		case UnsignedTxType:
+			if i != 0 || keydata != nil {
+				return nil, ErrInvalidPsbtFormat
+			}
+
+			// Now that we've verified the global type is present,
+			// we'll decode it into a proper unsigned transaction,
+			// and validate it.
+			msgTx = wire.NewMsgTx(2)
+
+			// BIP-0174 states: "The transaction must be in the old
+			// serialization format (without witnesses)."
+			err = msgTx.DeserializeNoWitness(bytes.NewReader(value))
+			if err != nil {
+				return nil, err
+			}
+			if !validateUnsignedTX(msgTx) {
+				return nil, ErrInvalidRawTxSigned
+			}
+
 		case XPubType:
 			xPub, err := ReadXPub(keydata, value)
 			if err != nil {
@@ -268,13 +337,64 @@ func NewFromRawBytes(r io.Reader, b64 bool) (*Packet, error) {
 
 			// Duplicate keys are not allowed
 			for _, x := range xPubSlice {
-				if bytes.Equal(x.ExtendedKey, keyData) {
+				if bytes.Equal(x.ExtendedKey, keydata) {
 					return nil, ErrDuplicateKey
 				}
 			}
 
 			xPubSlice = append(xPubSlice, *xPub)
 
+		case TxVersionType:
+			if txVersion != nil {
+				return nil, ErrDuplicateKey
+			}
+			if len(value) != 4 {
+				return nil, ErrInvalidKeyData
+			}
+			v := int32(binary.LittleEndian.Uint32(value))
+			txVersion = &v
+
+		case FallbackLockTimeType:
+			if fallback != nil {
+				return nil, ErrDuplicateKey
+			}
+			if len(value) != 4 {
+				return nil, ErrInvalidKeyData
+			}
+			v := binary.LittleEndian.Uint32(value)
+			fallback = &v
+
+		case InputCountType, OutputCountType:
+			count := &inputCount
+			if globalType == OutputCountType {
+				count = &outputCount
+			}
+			if *count != nil {
+				return nil, ErrDuplicateKey
+			}
+			valueReader := bytes.NewReader(value)
+			v, err := wire.ReadVarInt(valueReader, 0)
+			if err != nil || valueReader.Len() != 0 {
+				return nil, ErrInvalidKeyData
+			}
+			*count = &v
+
+		case TxModifiableType:
+			if modifiable != nil {
+				return nil, ErrDuplicateKey
+			}
+			if len(value) != 1 {
+				return nil, ErrInvalidKeyData
+			}
+			v := ModifiableFlags(value[0])
+			modifiable = &v
+
+		case VersionType:
+			if keydata != nil || len(value) != 4 {
+				return nil, ErrInvalidKeyData
+			}
+			version = binary.LittleEndian.Uint32(value)
+
 		default:
 			keyintanddata := []byte{byte(keyint)}
 			keyintanddata = append(keyintanddata, keydata...)
@@ -287,42 +407,102 @@ func NewFromRawBytes(r io.Reader, b64 bool) (*Packet, error) {
 		}
 	}
 
-	// Next we parse the INPUT section.
-	inSlice := make([]PInput, len(msgTx.TxIn))
-	for i := range msgTx.TxIn {
+	// Each version requires its own set of global fields, and excludes
+	// those of the other version.
+	switch version {
+	case 0:
+		if msgTx == nil || txVersion != nil || fallback != nil ||
+			inputCount != nil || outputCount != nil ||
+			modifiable != nil {
+
+			return nil, ErrInvalidPsbtFormat
+		}
+
+	case 2:
+		if msgTx != nil || txVersion == nil || inputCount == nil ||
+			outputCount == nil {
+
+			return nil, ErrInvalidPsbtFormat
+		}
+		msgTx = wire.NewMsgTx(*txVersion)
+
+	default:
+		return nil, ErrUnsupportedVersion
+	}
+
+	// Next we parse the INPUT section.  The transaction of a version 2
+	// packet is instead built from the fields of its inputs and outputs.
+	numInputs := uint64(len(msgTx.TxIn))
+	numOutputs := uint64(len(msgTx.TxOut))
+	if version == 2 {
+		numInputs, numOutputs = *inputCount, *outputCount
+	}
+	inSlice := make([]PInput, 0, len(msgTx.TxIn))
+	for i := uint64(0); i < numInputs; i++ {
+		var txIn *wire.TxIn
+		if version == 2 {
+			txIn = &wire.TxIn{Sequence: wire.MaxTxInSequenceNum}
+		}
+
 		input := PInput{}
-		err = input.deserialize(r)
+		err := input.deserialize(r, txIn)
 		if err != nil {
 			return nil, err
 		}
+		if txIn != nil {
+			msgTx.AddTxIn(txIn)
+		}
 
-		inSlice[i] = input
+		inSlice = append(inSlice, input)
 	}
 
 	// Next we parse the OUTPUT section.
-	outSlice := make([]POutput, len(msgTx.TxOut))
-	for i := range msgTx.TxOut {
+	outSlice := make([]POutput, 0, len(msgTx.TxOut))
+	for i := uint64(0); i < numOutputs; i++ {
+		var txOut *wire.TxOut
+		if version == 2 {
+			txOut = &wire.TxOut{}
+		}
+
 		output := POutput{}
-		err = output.deserialize(r)
+		err := output.deserialize(r, txOut)
 		if err != nil {
 			return nil, err
 		}
+		if txOut != nil {
+			msgTx.AddTxOut(txOut)
+		}
 
-		outSlice[i] = output
+		outSlice = append(outSlice, output)
 	}
 
 	// Populate the new Packet object.
 	newPsbt := Packet{
-		UnsignedTx: msgTx,
-		Inputs:     inSlice,
-		Outputs:    outSlice,
-		XPubs:      xPubSlice,
-		Unknowns:   unknownSlice,
+		Version:          version,
+		UnsignedTx:       msgTx,
+		Inputs:           inSlice,
+		Outputs:          outSlice,
+		XPubs:            xPubSlice,
+		Unknowns:         unknownSlice,
+		FallbackLockTime: fallback,
+	}
+	if modifiable != nil {
+		newPsbt.TxModifiable = *modifiable
+	}
+
+	// The lock time of the transaction of a version 2 packet is determined
+	// by the lock times its inputs require.
+	if version == 2 {
+		lockTime, err := newPsbt.DetermineLockTime()
+		if err != nil {
+			return nil, err
+		}
+		newPsbt.UnsignedTx.LockTime = lockTime
 	}
 
 	// Extended sanity checking is applied here to make sure the
 	// externally-passed Packet follows all the rules.
-	if err = newPsbt.SanityCheck(); err != nil {
+	if err := newPsbt.SanityCheck(); err != nil {
 		return nil, err
 	}
 
@@ -338,22 +518,25 @@ func (p *Packet) Serialize(w io.Writer) error {
 		return err
 	}
 
-	// Next we prep to write out the unsigned transaction by first
-	// serializing it into an intermediate buffer.
-	serializedTx := bytes.NewBuffer(
-		make([]byte, 0, p.UnsignedTx.SerializeSize()),
-	)
-	if err := p.UnsignedTx.SerializeNoWitness(serializedTx); err != nil {
-		return err
-	}
+	// A version 0 packet embeds the unsigned transaction, so we prep to
+	// write it out by first serializing it into an intermediate buffer.
+	if p.Version == 0 {
+		serializedTx := bytes.NewBuffer(
+			make([]byte, 0, p.UnsignedTx.SerializeSize()),
+		)
+		err := p.UnsignedTx.SerializeNoWitness(serializedTx)
+		if err != nil {
+			return err
+		}
 
-	// Now that we have the serialized transaction, we'll write it out to
-	// the proper global type.
-	err := serializeKVPairWithType(
-		w, uint8(UnsignedTxType), nil, serializedTx.Bytes(),
-	)
-	if err != nil {
-		return err
+		// Now that we have the serialized transaction, we'll write it
+		// out to the proper global type.
+		err = serializeKVPairWithType(
+			w, uint8(UnsignedTxType), nil, serializedTx.Bytes(),
+		)
+		if err != nil {
+			return err
+		}
 	}
 
 	// Serialize the global xPubs.
@@ -369,6 +552,25 @@ func (p *Packet) Serialize(w io.Writer) error {
 		}
 	}
 
+	// A version 2 packet instead describes the transaction with global
+	// fields for its version, lock time and number of inputs and outputs.
+	if p.Version == 2 {
+		if err := p.serializeV2Globals(w); err != nil {
+			return err
+		}
+	}
+
+	if p.Version != 0 {
+		var version [4]byte
+		binary.LittleEndian.PutUint32(version[:], p.Version)
+		err := serializeKVPairWithType(
+			w, uint8(VersionType), nil, version[:],
+		)
+		if err != nil {
+			return err
+		}
+	}
+
 	// Unknown is a special case; we don't have a key type, only a key and
 	// a value field
 	for _, kv := range p.Unknowns {
@@ -385,8 +587,13 @@ func (p *Packet) Serialize(w io.Writer) error {
 		return err
 	}
 
-	for _, pInput := range p.Inputs {
-		err := pInput.serialize(w)
+	for i, pInput := range p.Inputs {
+		var txIn *wire.TxIn
+		if p.Version == 2 {
+			txIn = p.UnsignedTx.TxIn[i]
+		}
+
+		err := pInput.serialize(w, txIn)
 		if err != nil {
 			return err
 		}
@@ -396,8 +603,13 @@ func (p *Packet) Serialize(w io.Writer) error {
 		}
 	}
 
-	for _, pOutput := range p.Outputs {
-		err := pOutput.serialize(w)
+	for i, pOutput := range p.Outputs {
+		var txOut *wire.TxOut
+		if p.Version == 2 {
+			txOut = p.UnsignedTx.TxOut[i]
+		}
+
+		err := pOutput.serialize(w, txOut)
 		if err != nil {
 			return err
 		}
@@ -410,6 +622,60 @@ func (p *Packet) Serialize(w io.Writer) error {
 	return nil
 }
 
+// serializeV2Globals writes the global fields which describe the transaction
+// of a version 2 packet.
+func (p *Packet) serializeV2Globals(w io.Writer) error {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], uint32(p.UnsignedTx.Version))
+	err := serializeKVPairWithType(w, uint8(TxVersionType), nil, buf[:])
+	if err != nil {
+		return err
+	}
+
+	if p.FallbackLockTime != nil {
+		binary.LittleEndian.PutUint32(buf[:], *p.FallbackLockTime)
+		err := serializeKVPairWithType(
+			w, uint8(FallbackLockTimeType), nil, buf[:],
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	var count bytes.Buffer
+	err = wire.WriteVarInt(&count, 0, uint64(len(p.UnsignedTx.TxIn)))
+	if err != nil {
+		return err
+	}
+	err = serializeKVPairWithType(
+		w, uint8(InputCountType), nil, count.Bytes(),
+	)
+	if err != nil {
+		return err
+	}
+
+	count.Reset()
+	err = wire.WriteVarInt(&count, 0, uint64(len(p.UnsignedTx.TxOut)))
+	if err != nil {
+		return err
+	}
+	err = serializeKVPairWithType(
+		w, uint8(OutputCountType), nil, count.Bytes(),
+	)
+	if err != nil {
+		return err
+	}
+
+	if p.TxModifiable != 0 {
+		return serializeKVPairWithType(
+			w, uint8(TxModifiableType), nil,
+			[]byte{byte(p.TxModifiable)},
+		)
+	}
+
+	return nil
+}
+
 // B64Encode returns the base64 encoding of the serialization of
 // the current PSBT, or an error if the encoding fails.
 func (p *Packet) B64Encode() (string, error) {
@@ -441,6 +707,21 @@ func (p *Packet) SanityCheck() error {
 		return ErrInvalidRawTxSigned
 	}
 
+	switch p.Version {
+	case 0:
+	case 2:
+		if len(p.Inputs) != len(p.UnsignedTx.TxIn) ||
+			len(p.Outputs) != len(p.UnsignedTx.TxOut) {
+
+			return ErrInvalidPsbtFormat
+		}
+		if _, err := p.DetermineLockTime(); err != nil {
+			return err
+		}
+	default:
+		return ErrUnsupportedVersion
+	}
+
 	for _, tin := range p.Inputs {
 		if !tin.IsSane() {
 			return ErrInvalidPsbtFormat
@@ -30,6 +30,30 @@ const (
 	// extended public key.
 	XPubType GlobalType = 1
 
+	// TxVersionType is an empty key ({0x02}) which houses the version of
+	// the transaction described by a version 2 PSBT, as a 32-bit little
+	// endian signed integer.
+	TxVersionType GlobalType = 2
+
+	// FallbackLockTimeType is an empty key ({0x03}) which houses the lock
+	// time of the transaction described by a version 2 PSBT to use when
+	// none of its inputs require one, as a 32-bit little endian unsigned
+	// integer. If omitted, the fallback lock time is zero.
+	FallbackLockTimeType GlobalType = 3
+
+	// InputCountType is an empty key ({0x04}) which houses the number of
+	// inputs of a version 2 PSBT as a compact size unsigned integer.
+	InputCountType GlobalType = 4
+
+	// OutputCountType is an empty key ({0x05}) which houses the number of
+	// outputs of a version 2 PSBT as a compact size unsigned integer.
+	OutputCountType GlobalType = 5
+
+	// TxModifiableType is an empty key ({0x06}) which houses the bit field
+	// of the ModifiableFlags of a version 2 PSBT as an 8-bit unsigned
+	// integer. If omitted, nothing is modifiable.
+	TxModifiableType GlobalType = 6
+
 	// VersionType houses the global version number of this PSBT. There is
 	// no key (only contains the byte type), then the value if omitted, is
 	// assumed to be zero.
@@ -114,6 +138,32 @@ const (
 	// scripts necessary for the input to pass validation.
 	FinalScriptWitnessType InputType = 8
 
+	// PreviousTxidType is an empty key ({0x0e}) which houses the 32-byte
+	// hash of the transaction the input of a version 2 PSBT spends from.
+	PreviousTxidType InputType = 0x0e
+
+	// OutputIndexType is an empty key ({0x0f}) which houses the index of
+	// the output the input of a version 2 PSBT spends, as a 32-bit little
+	// endian unsigned integer.
+	OutputIndexType InputType = 0x0f
+
+	// SequenceType is an empty key ({0x10}) which houses the sequence
+	// number of the input of a version 2 PSBT, as a 32-bit little endian
+	// unsigned integer. If omitted, the sequence number is
+	// wire.MaxTxInSequenceNum.
+	SequenceType InputType = 0x10
+
+	// RequiredTimeLockTimeType is an empty key ({0x11}) which houses the
+	// minimum time based lock time the input of a version 2 PSBT requires,
+	// as a 32-bit little endian unsigned integer of at least 500000000.
+	RequiredTimeLockTimeType InputType = 0x11
+
+	// RequiredHeightLockTimeType is an empty key ({0x12}) which houses the
+	// minimum height based lock time the input of a version 2 PSBT
+	// requires, as a 32-bit little endian unsigned integer greater than
+	// zero and below 500000000.
+	RequiredHeightLockTimeType InputType = 0x12
+
 	// TaprootKeySpendSignatureType is an empty key ({0x13}). The value is
 	// a 64-byte Schnorr signature or a 65-byte Schnorr signature with the
 	// one byte sighash type appended to it.
@@ -184,6 +234,15 @@ const (
 	// Public keys are those needed to spend this output.
 	Bip32DerivationOutputType OutputType = 2
 
+	// AmountType is an empty key ({0x03}) which houses the value of the
+	// output of a version 2 PSBT in satoshis, as a 64-bit little endian
+	// signed integer.
+	AmountType OutputType = 3
+
+	// ScriptType is an empty key ({0x04}) which houses the script of the
+	// output of a version 2 PSBT.
+	ScriptType OutputType = 4
+
 	// TaprootInternalKeyOutputType is an empty key ({0x05}). The value is
 	// an x-only pubkey denoting the internal public key used for
 	// constructing a taproot key.
@@ -216,6 +216,10 @@ func (u *Updater) addPartialSignature(inIndex int, sig []byte,
 		u.Upsbt.Inputs[inIndex].PartialSigs, &partialSig,
 	)
 
+	// The inputs and outputs a signature commits to may no longer be
+	// modified by the Constructor of a version 2 packet.
+	u.Upsbt.updateModifiable(txscript.SigHashType(sig[len(sig)-1]))
+
 	if err := u.Upsbt.SanityCheck(); err != nil {
 		return err
 	}
@@ -5,6 +5,7 @@
 package chaincfg
 
 import (
+	"bytes"
 	"encoding/binary"
 	"encoding/hex"
 	"errors"
@@ -45,6 +46,13 @@ var (
 	// have for the signet test network. It is the value 0x0377ae << 216.
 	sigNetPowLimit = new(big.Int).Lsh(new(big.Int).SetInt64(0x0377ae), 216)
 
+	// mainMinimumChainWork is the minimum total work of the best known
+	// header chain of the main network required to use its AssumeValid
+	// block, which is a lower bound of the work of the main chain up to
+	// that block.
+	mainMinimumChainWork, _ = new(big.Int).SetString(
+		"44a50fe819c39ad624021859", 16)
+
 	// DefaultSignetChallenge is the byte representation of the signet
 	// challenge for the default (public, Taproot enabled) signet network.
 	// This is the binary equivalent of the bitcoin script
@@ -79,6 +87,26 @@ type Checkpoint struct {
 	Hash   *chainhash.Hash
 }
 
+// AssumeUtxoData identifies a known good snapshot of the UTXO set at a block in
+// the block chain.  A snapshot matching it may be loaded in order to sync the
+// chain from the snapshot base block while the blocks before it are validated
+// in the background.
+type AssumeUtxoData struct {
+	// Height is the height of the block the snapshot was taken at.
+	Height int32
+
+	// BlockHash is the hash of the block the snapshot was taken at.
+	BlockHash *chainhash.Hash
+
+	// HashSerialized is the hash of the serialized UTXO set as reported by
+	// the hash_serialized_3 field of gettxoutsetinfo.
+	HashSerialized *chainhash.Hash
+
+	// ChainTxCount is the total number of transactions in the chain up to
+	// and including the snapshot base block.
+	ChainTxCount uint64
+}
+
 // EffectiveAlwaysActiveHeight returns the effective activation height for the
 // deployment. If AlwaysActiveHeight is unset (i.e. zero), it returns
 // the maximum uint32 value to indicate that it does not force activation.
@@ -260,6 +288,27 @@ type Params struct {
 	// Checkpoints ordered from oldest to newest.
 	Checkpoints []Checkpoint
 
+	// AssumeValid is the hash of a block whose scripts, along with those of
+	// its ancestors, are assumed to be valid so they aren't verified while
+	// syncing.  The rest of the validation rules still apply to those
+	// blocks.  It's only used once the best known header chain has at least
+	// MinimumChainWork and builds enough work on top of the blocks.
+	//
+	// It can be nil to verify every script.
+	AssumeValid *chainhash.Hash
+
+	// MinimumChainWork is the minimum total work the best known header
+	// chain must have for the AssumeValid block to be used.  It prevents
+	// skipping script verification while only a low work header chain is
+	// known.
+	//
+	// It can be nil to not require any minimum work.
+	MinimumChainWork *big.Int
+
+	// AssumeUtxo lists the UTXO set snapshots which may be loaded, ordered
+	// from oldest to newest.
+	AssumeUtxo []AssumeUtxoData
+
 	// These fields are related to voting on consensus rule changes as
 	// defined by BIP0009.
 	//
@@ -372,6 +421,27 @@ var MainNetParams = Params{
 		{810000, newHashFromStr("000000000000000000028028ca82b6aa81ce789e4eb9e0321b74c3cbaf405dd1")},
 	},
 
+	// The scripts of the blocks up to the final checkpoint are assumed
+	// to be valid.
+	AssumeValid:      newHashFromStr("000000000000000000028028ca82b6aa81ce789e4eb9e0321b74c3cbaf405dd1"),
+	MinimumChainWork: mainMinimumChainWork,
+
+	// UTXO set snapshots ordered from oldest to newest.
+	AssumeUtxo: []AssumeUtxoData{
+		{
+			Height:         840000,
+			BlockHash:      newHashFromStr("0000000000000000000320283a032748cef8227873ff4872689bf23f1cda83a5"),
+			HashSerialized: newHashFromStr("a2a5521b1b5ab65f67818e5e8eccabb7171a517f9e2382208f77687310768f96"),
+			ChainTxCount:   991032194,
+		},
+		{
+			Height:         880000,
+			BlockHash:      newHashFromStr("000000000000000000010b17283c3c400507969a9c2afd1dcf2082ec5cca2880"),
+			HashSerialized: newHashFromStr("dbd190983eaf433ef7c15f78a278ae42c00ef52e0fd2a54953782175fbadcea9"),
+			ChainTxCount:   1145604538,
+		},
+	},
+
 	// Consensus rule change deployments.
 	//
 	// The miner confirmation window is defined as:
@@ -637,6 +707,16 @@ var TestNet3Params = Params{
 		{2344474, newHashFromStr("0000000000000004877fa2d36316398528de4f347df2f8a96f76613a298ce060")},
 	},
 
+	// UTXO set snapshots ordered from oldest to newest.
+	AssumeUtxo: []AssumeUtxoData{
+		{
+			Height:         2500000,
+			BlockHash:      newHashFromStr("0000000000000093bcb68c03a9a168ae252572d348a2eaeba2cdf9231d73206f"),
+			HashSerialized: newHashFromStr("f841584909f68e47897952345234e37fcd9128cd818f41ee6c3ca68db8071be7"),
+			ChainTxCount:   66484552,
+		},
+	},
+
 	// Consensus rule change deployments.
 	//
 	// The miner confirmation window is defined as:
@@ -982,6 +1062,17 @@ var SigNetParams = CustomSignetParams(
 	DefaultSignetChallenge, DefaultSignetDNSSeeds,
 )
 
+// sigNetAssumeUtxo lists the UTXO set snapshots of the default public signet
+// network ordered from oldest to newest.
+var sigNetAssumeUtxo = []AssumeUtxoData{
+	{
+		Height:         160000,
+		BlockHash:      newHashFromStr("0000003ca3c99aff040f2563c2ad8f8ec88bd0fd6b8f0895cfaf1ef90353a62c"),
+		HashSerialized: newHashFromStr("fe0a44309b74d6b5883d246cb419c6221bcccf0b308c9b59b7d70783dbdf928a"),
+		ChainTxCount:   2289496,
+	},
+}
+
 // CustomSignetParams creates network parameters for a custom signet network
 // from a challenge. The challenge is the binary compiled version of the block
 // challenge script.
@@ -997,6 +1088,13 @@ func CustomSignetParams(challenge []byte, dnsSeeds []DNSSeed) Params {
 	// We use little endian encoding of the hash prefix to be in line with
 	// the other wire network identities.
 	net := binary.LittleEndian.Uint32(hashDouble[0:4])
+
+	// The UTXO set snapshots are only known for the default signet.
+	var assumeUtxo []AssumeUtxoData
+	if bytes.Equal(challenge, DefaultSignetChallenge) {
+		assumeUtxo = sigNetAssumeUtxo
+	}
+
 	return Params{
 		Name:        "signet",
 		Net:         wire.BitcoinNet(net),
@@ -1023,6 +1121,9 @@ func CustomSignetParams(challenge []byte, dnsSeeds []DNSSeed) Params {
 		// Checkpoints ordered from oldest to newest.
 		Checkpoints: nil,
 
+		// UTXO set snapshots ordered from oldest to newest.
+		AssumeUtxo: assumeUtxo,
+
 		// Consensus rule change deployments.
 		//
 		// The miner confirmation window is defined as:
@@ -113,6 +113,33 @@ func TestSigNetMagic(t *testing.T) {
 	require.Equal(t, wire.SigNet, SigNetParams.Net)
 }
 
+// TestAssumeUtxo ensures the UTXO set snapshots of the networks are complete
+// and ordered from oldest to newest, and that a custom signet has none.
+func TestAssumeUtxo(t *testing.T) {
+	t.Parallel()
+
+	for _, params := range []*Params{
+		&MainNetParams, &TestNet3Params, &SigNetParams,
+	} {
+		require.NotEmpty(t, params.AssumeUtxo, params.Name)
+		for i, data := range params.AssumeUtxo {
+			require.NotNil(t, data.BlockHash, params.Name)
+			require.NotNil(t, data.HashSerialized, params.Name)
+			require.NotZero(t, data.ChainTxCount, params.Name)
+			if i > 0 {
+				prev := params.AssumeUtxo[i-1]
+				require.Greater(t, data.Height, prev.Height,
+					params.Name)
+				require.Greater(t, data.ChainTxCount,
+					prev.ChainTxCount, params.Name)
+			}
+		}
+	}
+
+	customSigNet := CustomSignetParams([]byte{0x51}, nil)
+	require.Empty(t, customSigNet.AssumeUtxo)
+}
+
 // compactToBig is a copy of the blockchain.CompactToBig function. We copy it
 // here so we don't run into a circular dependency just because of a test.
 func compactToBig(compact uint32) *big.Int {
@@ -33,6 +33,7 @@ import (
 	"github.com/bynil/btcd/mempool"
 	"github.com/bynil/btcd/peer"
 	"github.com/bynil/btcd/wire"
+	"github.com/bynil/btcd/zmq"
 	flags "github.com/jessevdk/go-flags"
 )
 
@@ -62,6 +63,7 @@ const (
 	blockMaxWeightMax            = blockchain.MaxBlockWeight - 4000
 	defaultGenerate              = false
 	defaultMaxOrphanTransactions = 100
+	defaultMaxMempool            = mempool.DefaultMaxPoolSize / 1000000
 	defaultMaxOrphanTxSize       = 100000
 	defaultSigCacheMaxSize       = 100000
 	defaultUtxoCacheMaxSizeMiB   = 250
@@ -103,6 +105,7 @@ type config struct {
 	AddrIndex            bool          `long:"addrindex" description:"Maintain a full address-based transaction index which makes the searchrawtransactions RPC available"`
 	AgentBlacklist       []string      `long:"agentblacklist" description:"A comma separated list of user-agent substrings which will cause btcd to reject any peers whose user-agent contains any of the blacklisted substrings."`
 	AgentWhitelist       []string      `long:"agentwhitelist" description:"A comma separated list of user-agent substrings which will cause btcd to require all peers' user-agents to contain one of the whitelisted substrings. The blacklist is applied before the whitelist, and an empty whitelist will allow all agents that do not fail the blacklist."`
+	AssumeValid          string        `long:"assumevalid" description:"Hash of a block whose scripts, along with those of its ancestors, are assumed to be valid.  Set to 0 to verify all scripts.  Defaults to a block of the active network"`
 	BanDuration          time.Duration `long:"banduration" description:"How long to ban misbehaving peers.  Valid time units are {s, m, h}.  Minimum 1 second"`
 	BanThreshold         uint32        `long:"banthreshold" description:"Maximum allowed ban score before disconnecting and banning misbehaving peers."`
 	BlockMaxSize         uint32        `long:"blockmaxsize" description:"Maximum block size in bytes to be used when creating a block"`
@@ -111,6 +114,7 @@ type config struct {
 	BlockMinWeight       uint32        `long:"blockminweight" description:"Minimum block weight to be used when creating a block"`
 	BlockPrioritySize    uint32        `long:"blockprioritysize" description:"Size in bytes for high-priority/low-fee transactions when creating a block"`
 	BlocksOnly           bool          `long:"blocksonly" description:"Do not accept transactions from remote peers."`
+	CoinStatsIndex       bool          `long:"coinstatsindex" description:"Maintain an index of the statistics of the UTXO set after every block which makes the gettxoutsetinfo RPC return instantly at any height"`
 	ConfigFile           string        `short:"C" long:"configfile" description:"Path to configuration file"`
 	ConnectPeers         []string      `long:"connect" description:"Connect only to the specified peers at startup"`
 	CPUProfile           string        `long:"cpuprofile" description:"Write CPU profile to the specified file"`
@@ -121,12 +125,18 @@ type config struct {
 	DebugLevel           string        `short:"d" long:"debuglevel" description:"Logging level for all subsystems {trace, debug, info, warn, error, critical} -- You may also specify <subsystem>=<level>,<subsystem2>=<level>,... to set the log level for individual subsystems -- Use show to list available subsystems"`
 	DropAddrIndex        bool          `long:"dropaddrindex" description:"Deletes the address-based transaction index from the database on start up and then exits."`
 	DropCfIndex          bool          `long:"dropcfindex" description:"Deletes the index used for committed filtering (CF) support from the database on start up and then exits."`
+	DropCoinStatsIndex   bool          `long:"dropcoinstatsindex" description:"Deletes the index of the statistics of the UTXO set from the database on start up and then exits."`
 	DropTxIndex          bool          `long:"droptxindex" description:"Deletes the hash-based transaction index from the database on start up and then exits."`
 	ExternalIPs          []string      `long:"externalip" description:"Add an ip to the list of local addresses we claim to listen on to peers"`
 	Generate             bool          `long:"generate" description:"Generate (mine) bitcoins using the CPU"`
+	LimitAncestorCount   int           `long:"limitancestorcount" description:"Do not accept transactions with more than this many unconfirmed ancestors in the memory pool, including the transaction itself"`
+	LimitAncestorSize    int64         `long:"limitancestorsize" description:"Do not accept transactions whose total virtual size with their unconfirmed ancestors in the memory pool exceeds this many kilobytes"`
+	LimitDescendantCount int           `long:"limitdescendantcount" description:"Do not accept transactions if an unconfirmed ancestor in the memory pool would have more than this many descendants, including the ancestor itself"`
+	LimitDescendantSize  int64         `long:"limitdescendantsize" description:"Do not accept transactions if the total virtual size of an unconfirmed ancestor in the memory pool with its descendants would exceed this many kilobytes"`
 	FreeTxRelayLimit     float64       `long:"limitfreerelay" description:"Limit relay of transactions with no transaction fee to the given amount in thousands of bytes per minute"`
 	Listeners            []string      `long:"listen" description:"Add an interface/port to listen for connections (default all interfaces port: 8333, testnet: 18333)"`
 	LogDir               string        `long:"logdir" description:"Directory to log output."`
+	MaxMempool           int64         `long:"maxmempool" description:"Max size of the memory pool in megabytes -- Transactions with the lowest fee rates are evicted once it is exceeded and the minimum fee rate required to enter it is raised"`
 	MaxOrphanTxs         int           `long:"maxorphantx" description:"Max number of orphan transactions to keep in memory"`
 	MaxPeers             int           `long:"maxpeers" description:"Max number of inbound and outbound peers"`
 	MiningAddrs          []string      `long:"miningaddr" description:"Add the specified payment address to the list of addresses to use for generated blocks -- At least one address is required if the generate option is set"`
@@ -138,6 +148,7 @@ type config struct {
 	DisableListen        bool          `long:"nolisten" description:"Disable listening for incoming connections -- NOTE: Listening is automatically disabled if the --connect or --proxy options are used without also specifying listen interfaces via --listen"`
 	NoOnion              bool          `long:"noonion" description:"Disable connecting to tor hidden services"`
 	NoPeerBloomFilters   bool          `long:"nopeerbloomfilters" description:"Disable bloom filtering support"`
+	NoPersistMempool     bool          `long:"nopersistmempool" description:"Do not save the memory pool on shutdown and load it on startup"`
 	NoRelayPriority      bool          `long:"norelaypriority" description:"Do not require free or low-fee transactions to have high priority for relaying"`
 	NoWinService         bool          `long:"nowinservice" description:"Do not start as a background service on Windows -- NOTE: This flag only works on the command line, not in the config file"`
 	DisableRPC           bool          `long:"norpc" description:"Disable built-in RPC server -- NOTE: The RPC server is disabled by default if no rpcuser/rpcpass or rpclimituser/rpclimitpass is specified"`
@@ -179,12 +190,20 @@ type config struct {
 	TxIndex              bool          `long:"txindex" description:"Maintain a full hash-based transaction index which makes all transactions available via the getrawtransaction RPC"`
 	UserAgentComments    []string      `long:"uacomment" description:"Comment to add to the user agent -- See BIP 14 for more information."`
 	Upnp                 bool          `long:"upnp" description:"Use UPnP to map our listening port outside of NAT"`
+	V2Transport          bool          `long:"v2transport" description:"Support the BIP0324 v2 encrypted transport protocol for peer connections -- Outbound connections fall back to the v1 protocol for peers that don't support it"`
 	ShowVersion          bool          `short:"V" long:"version" description:"Display version information and exit"`
 	Whitelists           []string      `long:"whitelist" description:"Add an IP network or IP that will not be banned. (eg. 192.168.1.0/24 or ::1)"`
+	ZMQPubHashBlock      string        `long:"zmqpubhashblock" description:"Publish the hash of each connected block on the ZeroMQ endpoint (eg. tcp://127.0.0.1:28332)"`
+	ZMQPubHashTx         string        `long:"zmqpubhashtx" description:"Publish the hash of each mempool and block transaction on the ZeroMQ endpoint (eg. tcp://127.0.0.1:28332)"`
+	ZMQPubHWM            int           `long:"zmqpubhwm" description:"Max number of ZeroMQ messages queued for each subscriber before further messages are dropped"`
+	ZMQPubRawBlock       string        `long:"zmqpubrawblock" description:"Publish each connected block on the ZeroMQ endpoint (eg. tcp://127.0.0.1:28332)"`
+	ZMQPubRawTx          string        `long:"zmqpubrawtx" description:"Publish each mempool and block transaction on the ZeroMQ endpoint (eg. tcp://127.0.0.1:28332)"`
+	ZMQPubSequence       string        `long:"zmqpubsequence" description:"Publish block connections and disconnections along with mempool additions and removals on the ZeroMQ endpoint (eg. tcp://127.0.0.1:28332)"`
 	lookup               func(string) ([]net.IP, error)
 	oniondial            func(string, string, time.Duration) (net.Conn, error)
 	dial                 func(string, string, time.Duration) (net.Conn, error)
 	addCheckpoints       []chaincfg.Checkpoint
+	assumeValid          *chainhash.Hash
 	miningAddrs          []btcutil.Address
 	minRelayTxFee        btcutil.Amount
 	whitelists           []*net.IPNet
@@ -436,12 +455,18 @@ func loadConfig() (*config, []string, error) {
 		BlockMinWeight:       defaultBlockMinWeight,
 		BlockMaxWeight:       defaultBlockMaxWeight,
 		BlockPrioritySize:    mempool.DefaultBlockPrioritySize,
+		LimitAncestorCount:   mempool.DefaultMaxAncestorCount,
+		LimitAncestorSize:    mempool.DefaultMaxAncestorSize / 1000,
+		LimitDescendantCount: mempool.DefaultMaxDescendantCount,
+		LimitDescendantSize:  mempool.DefaultMaxDescendantSize / 1000,
+		MaxMempool:           defaultMaxMempool,
 		MaxOrphanTxs:         defaultMaxOrphanTransactions,
 		SigCacheMaxSize:      defaultSigCacheMaxSize,
 		UtxoCacheMaxSizeMiB:  defaultUtxoCacheMaxSizeMiB,
 		Generate:             defaultGenerate,
 		TxIndex:              defaultTxIndex,
 		AddrIndex:            defaultAddrIndex,
+		ZMQPubHWM:            zmq.DefaultHighWaterMark,
 	}
 
 	// Service options which are only added on Windows.
@@ -865,6 +890,42 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// The memory pool must be able to hold transactions.
+	if cfg.MaxMempool <= 0 {
+		str := "%s: The maxmempool option must be greater than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.MaxMempool)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The ancestor and descendant limits must allow transactions without
+	// unconfirmed relatives.
+	if cfg.LimitAncestorCount <= 0 || cfg.LimitAncestorSize <= 0 ||
+		cfg.LimitDescendantCount <= 0 || cfg.LimitDescendantSize <= 0 {
+
+		str := "%s: The limitancestorcount, limitancestorsize, " +
+			"limitdescendantcount and limitdescendantsize options " +
+			"must be greater than 0 -- parsed [%d, %d, %d, %d]"
+		err := fmt.Errorf(str, funcName, cfg.LimitAncestorCount,
+			cfg.LimitAncestorSize, cfg.LimitDescendantCount,
+			cfg.LimitDescendantSize)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
+	// The ZeroMQ high water mark must be positive.
+	if cfg.ZMQPubHWM <= 0 {
+		str := "%s: The zmqpubhwm option must be greater than 0 " +
+			"-- parsed [%d]"
+		err := fmt.Errorf(str, funcName, cfg.ZMQPubHWM)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// Limit the block priority and minimum block sizes to max block size.
 	cfg.BlockPrioritySize = minUint32(cfg.BlockPrioritySize, cfg.BlockMaxSize)
 	cfg.BlockMinSize = minUint32(cfg.BlockMinSize, cfg.BlockMaxSize)
@@ -919,6 +980,16 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// --coinstatsindex and --dropcoinstatsindex do not mix.
+	if cfg.CoinStatsIndex && cfg.DropCoinStatsIndex {
+		err := fmt.Errorf("%s: the --coinstatsindex and "+
+			"--dropcoinstatsindex options may not be activated at "+
+			"the same time", funcName)
+		fmt.Fprintln(os.Stderr, err)
+		fmt.Fprintln(os.Stderr, usageMessage)
+		return nil, nil, err
+	}
+
 	// --addrindex and --droptxindex do not mix.
 	if cfg.AddrIndex && cfg.DropTxIndex {
 		err := fmt.Errorf("%s: the --addrindex and --droptxindex "+
@@ -1029,6 +1100,22 @@ func loadConfig() (*config, []string, error) {
 		return nil, nil, err
 	}
 
+	// Parse the assumed valid block, where 0 disables it.
+	if cfg.AssumeValid != "" {
+		cfg.assumeValid = &chainhash.Hash{}
+		if cfg.AssumeValid != "0" {
+			cfg.assumeValid, err = chainhash.NewHashFromStr(
+				cfg.AssumeValid)
+			if err != nil {
+				str := "%s: Error parsing assumevalid hash: %v"
+				err := fmt.Errorf(str, funcName, err)
+				fmt.Fprintln(os.Stderr, err)
+				fmt.Fprintln(os.Stderr, usageMessage)
+				return nil, nil, err
+			}
+		}
+	}
+
 	// Tor stream isolation requires either proxy or onion proxy to be set.
 	if cfg.TorIsolation && cfg.Proxy == "" && cfg.OnionProxy == "" {
 		str := "%s: Tor stream isolation requires either proxy or " +
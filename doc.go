@@ -29,6 +29,10 @@ Application Options:
 	    --addrindex             Maintain a full address-based transaction index
 	                            which makes the searchrawtransactions RPC
 	                            available
+	    --assumevalid=          Hash of a block whose scripts, along with those
+	                            of its ancestors, are assumed to be valid.  Set
+	                            to 0 to verify all scripts.  Defaults to a block
+	                            of the active network
 	    --banduration=          How long to ban misbehaving peers.  Valid time
 	                            units are {s, m, h}.  Minimum 1 second (default:
 	                            24h0m0s)
@@ -46,6 +50,10 @@ Application Options:
 	                            transactions when creating a block (default:
 	                            50000)
 	    --blocksonly            Do not accept transactions from remote peers.
+	    --coinstatsindex        Maintain an index of the statistics of the UTXO
+	                            set after every block which makes the
+	                            gettxoutsetinfo RPC return instantly at any
+	                            height
 	-C, --configfile=           Path to configuration file
 	    --connect=              Connect only to the specified peers at startup
 	    --cpuprofile=           Write CPU profile to the specified file
@@ -64,11 +72,29 @@ Application Options:
 	    --dropcfindex           Deletes the index used for committed filtering
 	                            (CF) support from the database on start up and
 	                            then exits.
+	    --dropcoinstatsindex    Deletes the index of the statistics of the UTXO
+	                            set from the database on start up and then
+	                            exits.
 	    --droptxindex           Deletes the hash-based transaction index from the
 	                            database on start up and then exits.
 	    --externalip=           Add an ip to the list of local addresses we claim
 	                            to listen on to peers
 	    --generate              Generate (mine) bitcoins using the CPU
+	    --limitancestorcount=   Do not accept transactions with more than this
+	                            many unconfirmed ancestors in the memory pool,
+	                            including the transaction itself (default: 25)
+	    --limitancestorsize=    Do not accept transactions whose total virtual
+	                            size with their unconfirmed ancestors in the
+	                            memory pool exceeds this many kilobytes
+	                            (default: 101)
+	    --limitdescendantcount= Do not accept transactions if an unconfirmed
+	                            ancestor in the memory pool would have more than
+	                            this many descendants, including the ancestor
+	                            itself (default: 25)
+	    --limitdescendantsize=  Do not accept transactions if the total virtual
+	                            size of an unconfirmed ancestor in the memory
+	                            pool with its descendants would exceed this many
+	                            kilobytes (default: 101)
 	    --limitfreerelay=       Limit relay of transactions with no transaction
 	                            fee to the given amount in thousands of bytes per
 	                            minute (default: 15)
@@ -76,6 +102,11 @@ Application Options:
 	                            (default all interfaces port: 8333, testnet:
 	                            18333, signet: 38333)
 	    --logdir=               Directory to log output
+	    --maxmempool=           Max size of the memory pool in megabytes --
+	                            Transactions with the lowest fee rates are
+	                            evicted once it is exceeded and the minimum fee
+	                            rate required to enter it is raised (default:
+	                            300)
 	    --maxorphantx=          Max number of orphan transactions to keep in
 	                            memory (default: 100)
 	    --maxpeers=             Max number of inbound and outbound peers
@@ -97,6 +128,8 @@ Application Options:
 	                            also specifying listen interfaces via --listen
 	    --noonion               Disable connecting to tor hidden services
 	    --nopeerbloomfilters    Disable bloom filtering support
+	    --nopersistmempool      Do not save the memory pool on shutdown and load
+	                            it on startup
 	    --norelaypriority       Do not require free or low-fee transactions to
 	                            have high priority for relaying
 	    --norpc                 Disable built-in RPC server -- NOTE: The RPC
@@ -151,9 +184,28 @@ Application Options:
 	    --uacomment=            Comment to add to the user agent -- See BIP 14
 	                            for more information.
 	    --upnp                  Use UPnP to map our listening port outside of NAT
+	    --v2transport           Support the BIP0324 v2 encrypted transport
+	                            protocol for peer connections -- Outbound
+	                            connections fall back to the v1 protocol for
+	                            peers that don't support it
 	-V, --version               Display version information and exit
 	    --whitelist=            Add an IP network or IP that will not be banned.
 	                            (eg. 192.168.1.0/24 or ::1)
+	    --zmqpubhashblock=      Publish the hash of each connected block on the
+	                            ZeroMQ endpoint (eg. tcp://127.0.0.1:28332)
+	    --zmqpubhashtx=         Publish the hash of each mempool and block
+	                            transaction on the ZeroMQ endpoint
+	                            (eg. tcp://127.0.0.1:28332)
+	    --zmqpubhwm=            Max number of ZeroMQ messages queued for each
+	                            subscriber before further messages are dropped
+	                            (1000)
+	    --zmqpubrawblock=       Publish each connected block on the ZeroMQ
+	                            endpoint (eg. tcp://127.0.0.1:28332)
+	    --zmqpubrawtx=          Publish each mempool and block transaction on
+	                            the ZeroMQ endpoint (eg. tcp://127.0.0.1:28332)
+	    --zmqpubsequence=       Publish block connections and disconnections
+	                            along with mempool additions and removals on the
+	                            ZeroMQ endpoint (eg. tcp://127.0.0.1:28332)
 
 Help Options:
 
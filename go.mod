@@ -1,8 +1,10 @@
 module github.com/bynil/btcd
 
 require (
+	github.com/aead/siphash v1.0.1
 	github.com/bynil/btcd/btcec/v2 v2.3.400
 	github.com/bynil/btcd/btcutil v1.1.600
+	github.com/bynil/btcd/btcutil/psbt v1.1.9
 	github.com/bynil/btcd/chaincfg/chainhash v1.1.1000
 	github.com/btcsuite/btclog v0.0.0-20170628155309-84c8d2346e9f
 	github.com/btcsuite/go-socks v0.0.0-20170105172521-4720035b7bfd
@@ -22,7 +24,6 @@ require (
 )
 
 require (
-	github.com/aead/siphash v1.0.1 // indirect
 	github.com/decred/dcrd/crypto/blake256 v1.0.0 // indirect
 	github.com/golang/snappy v0.0.4 // indirect
 	github.com/kkdai/bstream v0.0.0-20161212061736-f391b8402d23 // indirect
@@ -32,6 +33,16 @@ require (
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
+// The btcec, btcutil, psbt and chainhash modules are developed in this repository
+// and are resolved from the local tree so changes to them are picked up
+// without waiting for a tagged release.
+replace (
+	github.com/bynil/btcd/btcec/v2 => ./btcec
+	github.com/bynil/btcd/btcutil => ./btcutil
+	github.com/bynil/btcd/btcutil/psbt => ./btcutil/psbt
+	github.com/bynil/btcd/chaincfg/chainhash => ./chaincfg/chainhash
+)
+
 // The retract statements below fixes an accidental push of the tags of a btcd
 // fork.
 retract (
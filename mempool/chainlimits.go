@@ -0,0 +1,237 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"fmt"
+	"maps"
+
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	"github.com/bynil/btcd/wire"
+)
+
+const (
+	// DefaultMaxAncestorCount is the default maximum number of unconfirmed
+	// ancestors of a transaction in the pool, including the transaction
+	// itself.
+	DefaultMaxAncestorCount = 25
+
+	// DefaultMaxAncestorSize is the default maximum total virtual size of
+	// a transaction and its unconfirmed ancestors in the pool.
+	DefaultMaxAncestorSize = 101000
+
+	// DefaultMaxDescendantCount is the default maximum number of
+	// unconfirmed descendants of a transaction in the pool, including the
+	// transaction itself.
+	DefaultMaxDescendantCount = 25
+
+	// DefaultMaxDescendantSize is the default maximum total virtual size
+	// of a transaction and its unconfirmed descendants in the pool.
+	DefaultMaxDescendantSize = 101000
+
+	// carveOutMaxVsize is the maximum virtual size of a transaction which
+	// may exceed the descendant limits of its only unconfirmed ancestor by
+	// one transaction as described by validateChainLimits.
+	carveOutMaxVsize = 10000
+)
+
+// chainTotals is the number and total virtual size of a set of unconfirmed
+// transactions.
+type chainTotals struct {
+	count int64
+	size  int64
+}
+
+// chainTxSize returns the virtual size of the passed unconfirmed transaction,
+// which is either in the pool or in the package being validated.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) chainTxSize(tx *btcutil.Tx) int64 {
+	if txD, exists := mp.pool[*tx.Hash()]; exists {
+		return txD.vsize
+	}
+	return GetTxVirtualSize(tx)
+}
+
+// chainAncestors returns all of the unconfirmed ancestors of the passed
+// transaction like txAncestors, including the ones which are part of the
+// package the transaction is validated with rather than in the pool.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) chainAncestors(tx *btcutil.Tx,
+	pkg *packageContext) map[chainhash.Hash]*btcutil.Tx {
+
+	if pkg == nil {
+		return mp.txAncestors(tx, nil)
+	}
+
+	ancestors := make(map[chainhash.Hash]*btcutil.Tx)
+	for hash, parent := range mp.unconfirmedParents(tx, pkg) {
+		if _, ok := ancestors[hash]; ok {
+			continue
+		}
+		ancestors[hash] = parent
+
+		// Transactions in the pool can't spend the transactions of
+		// the package.
+		if _, ok := mp.pool[hash]; ok {
+			maps.Copy(ancestors, mp.txAncestors(parent, nil))
+			continue
+		}
+		maps.Copy(ancestors, mp.chainAncestors(parent, pkg))
+	}
+
+	return ancestors
+}
+
+// validateChainLimits checks that the passed transaction doesn't exceed the
+// ancestor and descendant limits of the policy, which bound the chains of
+// unconfirmed transactions in the pool.  That is, the transaction along with
+// its unconfirmed ancestors must not exceed the maximum ancestor count and
+// size, and none of its ancestors may end up exceeding the maximum descendant
+// count and size.  The passed conflicts are the transactions replaced by the
+// transaction, which no longer count towards the limits.
+//
+// Like Bitcoin Core, a transaction with a single unconfirmed ancestor and a
+// virtual size of at most 10 kvB may exceed the descendant limits of that
+// ancestor by one transaction and 10 kvB.  This CPFP carve-out allows either
+// party of a two-party contract to bump the fee of its transaction through an
+// output of its own even when the other party filled up the descendant limits.
+// It doesn't apply to TRUC transactions, which are limited by their own
+// policy, nor to transactions validated as part of a package.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) validateChainLimits(tx *btcutil.Tx, txSize int64,
+	conflicts map[chainhash.Hash]*btcutil.Tx, pkg *packageContext) error {
+
+	policy := &mp.cfg.Policy
+	txHash := tx.Hash()
+
+	ancestors := mp.chainAncestors(tx, pkg)
+	ancestorCount := len(ancestors) + 1
+	if policy.MaxAncestorCount > 0 &&
+		ancestorCount > policy.MaxAncestorCount {
+
+		str := fmt.Sprintf("transaction %v has too many unconfirmed "+
+			"ancestors: %d [limit: %d]", txHash, ancestorCount,
+			policy.MaxAncestorCount)
+		return txRuleError(wire.RejectNonstandard, str)
+	}
+
+	ancestorSize := txSize
+	for _, ancestor := range ancestors {
+		ancestorSize += mp.chainTxSize(ancestor)
+	}
+	if policy.MaxAncestorSize > 0 && ancestorSize > policy.MaxAncestorSize {
+		str := fmt.Sprintf("transaction %v exceeds the ancestor size "+
+			"limit: %d [limit: %d]", txHash, ancestorSize,
+			policy.MaxAncestorSize)
+		return txRuleError(wire.RejectNonstandard, str)
+	}
+
+	err := mp.validateDescendantLimits(
+		tx, txSize, ancestors, conflicts, pkg,
+		int64(policy.MaxDescendantCount), policy.MaxDescendantSize,
+	)
+	if err == nil || pkg != nil || len(ancestors) != 1 ||
+		txSize > carveOutMaxVsize ||
+		tx.MsgTx().Version == TrucVersion {
+
+		return err
+	}
+
+	// Apply the CPFP carve-out by allowing one more descendant of up to
+	// 10 kvB.
+	maxCount := int64(policy.MaxDescendantCount)
+	if maxCount > 0 {
+		maxCount++
+	}
+	maxSize := policy.MaxDescendantSize
+	if maxSize > 0 {
+		maxSize += carveOutMaxVsize
+	}
+	carveOutErr := mp.validateDescendantLimits(
+		tx, txSize, ancestors, conflicts, pkg, maxCount, maxSize,
+	)
+	if carveOutErr != nil {
+		return err
+	}
+
+	log.Debugf("Accepting transaction %v beyond the descendant limits of "+
+		"its parent as a CPFP carve-out", txHash)
+
+	return nil
+}
+
+// validateDescendantLimits checks that none of the passed unconfirmed
+// ancestors of the passed transaction ends up exceeding the passed maximum
+// descendant count and size once the transaction is added to the pool along
+// with the other transactions of its package, if any, and the passed
+// conflicts are removed.  A maximum of zero disables the respective check.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) validateDescendantLimits(tx *btcutil.Tx, txSize int64,
+	ancestors, conflicts map[chainhash.Hash]*btcutil.Tx,
+	pkg *packageContext, maxCount, maxSize int64) error {
+
+	if maxCount <= 0 && maxSize <= 0 {
+		return nil
+	}
+
+	// Determine how the descendants of each ancestor change.  They grow by
+	// the transaction itself and the transactions of its package, which
+	// are added to the pool along with it, and shrink by the conflicts.
+	changes := make(map[chainhash.Hash]chainTotals, len(ancestors))
+	update := func(ancestors map[chainhash.Hash]*btcutil.Tx, count,
+		size int64) {
+
+		for hash := range ancestors {
+			change := changes[hash]
+			change.count += count
+			change.size += size
+			changes[hash] = change
+		}
+	}
+	update(ancestors, 1, txSize)
+	if pkg != nil {
+		for _, pkgTx := range pkg.txns {
+			update(mp.chainAncestors(pkgTx, pkg), 1,
+				GetTxVirtualSize(pkgTx))
+		}
+	}
+	for hash, conflict := range conflicts {
+		update(mp.txAncestors(conflict, nil), -1, -mp.pool[hash].vsize)
+	}
+
+	for hash, ancestor := range ancestors {
+		// Transactions of the package don't have any descendants in
+		// the pool yet.
+		totals := chainTotals{count: 1, size: mp.chainTxSize(ancestor)}
+		if txD, exists := mp.pool[hash]; exists {
+			totals.count = txD.descendantCount
+			totals.size = txD.descendantSize
+		}
+		totals.count += changes[hash].count
+		totals.size += changes[hash].size
+
+		if maxCount > 0 && totals.count > maxCount {
+			str := fmt.Sprintf("transaction %v exceeds the "+
+				"descendant count limit of unconfirmed "+
+				"ancestor %v: %d [limit: %d]", tx.Hash(), hash,
+				totals.count, maxCount)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+		if maxSize > 0 && totals.size > maxSize {
+			str := fmt.Sprintf("transaction %v exceeds the "+
+				"descendant size limit of unconfirmed "+
+				"ancestor %v: %d [limit: %d]", tx.Hash(), hash,
+				totals.size, maxSize)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bynil/btcd/chaincfg"
+	"github.com/bynil/btcd/wire"
+)
+
+// checkRelativeTotals ensures the ancestor and descendant totals tracked for
+// each transaction in the pool match its ancestors and descendants that are
+// in the pool.
+func checkRelativeTotals(t *testing.T, txPool *TxPool) {
+	t.Helper()
+
+	txPool.mtx.RLock()
+	defer txPool.mtx.RUnlock()
+
+	for hash, txD := range txPool.pool {
+		want := [3]int64{1, txD.modifiedFee(), txD.vsize}
+		for ancestorHash := range txPool.txAncestors(txD.Tx, nil) {
+			ancestor := txPool.pool[ancestorHash]
+			want[0]++
+			want[1] += ancestor.modifiedFee()
+			want[2] += ancestor.vsize
+		}
+		got := [3]int64{txD.ancestorCount, txD.ancestorFee,
+			txD.ancestorSize}
+		if got != want {
+			t.Fatalf("got ancestor count, fee and size %v for %v, "+
+				"want %v", got, hash, want)
+		}
+
+		want = [3]int64{1, txD.modifiedFee(), txD.vsize}
+		for descendantHash := range txPool.txDescendants(txD.Tx, nil) {
+			descendant := txPool.pool[descendantHash]
+			want[0]++
+			want[1] += descendant.modifiedFee()
+			want[2] += descendant.vsize
+		}
+		got = [3]int64{txD.descendantCount, txD.descendantFee,
+			txD.descendantSize}
+		if got != want {
+			t.Fatalf("got descendant count, fee and size %v for "+
+				"%v, want %v", got, hash, want)
+		}
+	}
+}
+
+// TestChainLimits ensures transactions exceeding the ancestor and descendant
+// limits are rejected, that replaced transactions don't count towards the
+// limits, and that the CPFP carve-out allows a single extra child.
+func TestChainLimits(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	txPool := harness.txPool
+	txPool.cfg.Policy.MaxAncestorCount = 3
+	txPool.cfg.Policy.MaxDescendantCount = 3
+
+	// expectRejected ensures the transaction spending the passed outputs
+	// is rejected with an error containing the passed reason.
+	expectRejected := func(inputs []spendableOutput, reason string) {
+		t.Helper()
+
+		tx, err := harness.CreateSignedTx(inputs, 1, 1000, false)
+		if err != nil {
+			t.Fatalf("unable to create transaction: %v", err)
+		}
+		_, err = txPool.ProcessTransaction(tx, false, false, 0)
+		if err == nil {
+			t.Fatalf("expected transaction %v to be rejected",
+				tx.Hash())
+		}
+		rerr, ok := err.(RuleError)
+		if !ok {
+			t.Fatalf("expected a rule error, got %v", err)
+		}
+		txErr, ok := rerr.Err.(TxRuleError)
+		if !ok || txErr.RejectCode != wire.RejectNonstandard ||
+			!strings.Contains(err.Error(), reason) {
+
+			t.Fatalf("got error %v, want a non-standard error "+
+				"about the %s", err, reason)
+		}
+		testPoolMembership(ctx, tx, false, false)
+	}
+
+	// A chain of three transactions fits within the ancestor limit while
+	// a fourth transaction doesn't.
+	coinbase := ctx.addCoinbaseTx(3)
+	a := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 0)}, 1, 1000,
+		false, false,
+	)
+	b := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(a, 0)}, 1, 1000, false,
+		false,
+	)
+	c := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(b, 0)}, 1, 1000, false,
+		false,
+	)
+	expectRejected(
+		[]spendableOutput{txOutToSpendableOut(c, 0)},
+		"unconfirmed ancestors",
+	)
+
+	// A second child of a transaction with an unconfirmed parent would
+	// make the parent exceed the descendant limit.  The carve-out doesn't
+	// apply since the child would have two unconfirmed ancestors.
+	g := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 1)}, 1, 1000,
+		false, false,
+	)
+	p := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(g, 0)}, 2, 1000, false,
+		false,
+	)
+	x := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(p, 0)}, 1, 1000, true,
+		false,
+	)
+	expectRejected(
+		[]spendableOutput{txOutToSpendableOut(p, 1)},
+		"descendant count limit",
+	)
+
+	// A replacement of the child doesn't count towards the limit along
+	// with the child it replaces.
+	xReplacement := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(p, 0)}, 1, 5000, false,
+		false,
+	)
+	testPoolMembership(ctx, x, false, false)
+	checkRelativeTotals(t, txPool)
+
+	// A transaction with a single unconfirmed parent may exceed the
+	// descendant limit of the parent by one transaction, but not more.
+	q := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 2)}, 4, 1000,
+		false, false,
+	)
+	for i := uint32(0); i < 3; i++ {
+		ctx.addSignedTx(
+			[]spendableOutput{txOutToSpendableOut(q, i)}, 1, 1000,
+			false, false,
+		)
+	}
+	expectRejected(
+		[]spendableOutput{txOutToSpendableOut(q, 3)},
+		"descendant count limit",
+	)
+	checkRelativeTotals(t, txPool)
+
+	// Confirming the first transaction of a chain updates the ancestor
+	// totals of its descendants.
+	txPool.RemoveConfirmedTransaction(g)
+	checkRelativeTotals(t, txPool)
+	txPool.mtx.RLock()
+	if got := txPool.pool[*xReplacement.Hash()].ancestorCount; got != 2 {
+		t.Fatalf("got ancestor count %d after confirming the "+
+			"grandparent, want 2", got)
+	}
+	txPool.mtx.RUnlock()
+}
+
+// TestRelativeTotalsReorg ensures the ancestor and descendant totals are
+// updated when a transaction is added to the pool while its descendants are
+// already in the pool, which happens when the block including it is
+// disconnected.
+func TestRelativeTotalsReorg(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	txPool := harness.txPool
+
+	// Add a transaction to the pool along with its unconfirmed parent
+	// and a child spending a confirmed transaction.
+	coinbase := ctx.addCoinbaseTx(2)
+	a := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 0)}, 1, 1000,
+		false, false,
+	)
+	b, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 1)}, 1, 1000,
+		false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	harness.chain.utxos.AddTxOuts(b, harness.chain.BestHeight())
+	c := ctx.addSignedTx(
+		[]spendableOutput{
+			txOutToSpendableOut(a, 0), txOutToSpendableOut(b, 0),
+		}, 1, 1000, false, false,
+	)
+	d := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(c, 0)}, 1, 1000, false,
+		false,
+	)
+
+	// Disconnect the confirmed transaction and add it back to the pool.
+	harness.chain.utxos.RemoveEntry(wire.OutPoint{Hash: *b.Hash()})
+	_, err = txPool.ProcessTransaction(b, false, false, 0)
+	if err != nil {
+		t.Fatalf("unable to add disconnected transaction: %v", err)
+	}
+	checkRelativeTotals(t, txPool)
+
+	txPool.mtx.RLock()
+	defer txPool.mtx.RUnlock()
+	if got := txPool.pool[*d.Hash()].ancestorCount; got != 4 {
+		t.Fatalf("got ancestor count %d, want 4", got)
+	}
+	if got := txPool.pool[*b.Hash()].descendantFee; got != 3000 {
+		t.Fatalf("got descendant fee %d, want 3000", got)
+	}
+}
@@ -56,11 +56,17 @@ be an exhaustive list.
     5. Max signature operations per transaction
     6. Max orphan transaction size
     7. Max number of orphan transactions allowed
+    8. Max number and size of the unconfirmed ancestors and descendants of a
+    transaction, with a carve-out for fee bumping children (CPFP)
   - Additional metadata tracking for each transaction
     1. Timestamp when the transaction was added to the pool
     2. Most recent block height when the transaction was added to the pool
     3. The fee the transaction pays
     4. The starting priority for the transaction
+    5. The number, fee and size of its unconfirmed ancestors and descendants
+    6. The fee delta the transaction is prioritized with, which is added to
+    its fee for fee checks, eviction and mining, and is kept for
+    transactions which aren't in the pool yet
   - Manual control of transaction removal
     1. Recursive removal of all dependent transactions
 
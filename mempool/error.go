@@ -42,6 +42,29 @@ func (e TxRuleError) Error() string {
 	return e.Description
 }
 
+// PackageRuleError identifies a violation of the package policy, such as a
+// package that isn't sorted topologically, as opposed to a rule violation of
+// one of the transactions in the package.  The Reason field holds the short
+// reject reason used by the reference implementation for the violation, such
+// as "package-not-sorted".
+type PackageRuleError struct {
+	Reason      string // Reject reason of the reference implementation
+	Description string // Human readable description of the issue
+}
+
+// Error satisfies the error interface and prints human-readable errors.
+func (e PackageRuleError) Error() string {
+	return e.Description
+}
+
+// packageRuleError creates an underlying PackageRuleError with the given
+// reason and description and returns a RuleError that encapsulates it.
+func packageRuleError(reason, desc string) RuleError {
+	return RuleError{
+		Err: PackageRuleError{Reason: reason, Description: desc},
+	}
+}
+
 // txRuleError creates an underlying TxRuleError with the given a set of
 // arguments and returns a RuleError that encapsulates it.
 func txRuleError(c wire.RejectCode, desc string) RuleError {
@@ -24,6 +24,36 @@ type TxMempool interface {
 	// populated btcjson result.
 	RawMempoolVerbose() map[string]*btcjson.GetRawMempoolVerboseResult
 
+	// MempoolEntry returns the transaction with the passed hash in the
+	// main pool as a fully populated btcjson result, which includes the
+	// totals of its unconfirmed ancestors and descendants.
+	MempoolEntry(txHash *chainhash.Hash) (*btcjson.GetMempoolEntryResult,
+		error)
+
+	// MempoolAncestors returns the unconfirmed ancestors of the
+	// transaction with the passed hash in the main pool as fully
+	// populated btcjson results keyed by their hash.
+	MempoolAncestors(txHash *chainhash.Hash) (
+		map[string]*btcjson.GetMempoolEntryResult, error)
+
+	// MempoolDescendants returns the unconfirmed descendants of the
+	// transaction with the passed hash in the main pool as fully
+	// populated btcjson results keyed by their hash.
+	MempoolDescendants(txHash *chainhash.Hash) (
+		map[string]*btcjson.GetMempoolEntryResult, error)
+
+	// PrioritiseTransaction adds the passed fee delta in satoshi to the
+	// fee delta of the transaction with the passed hash, which is applied
+	// to its fee by the fee checks, eviction and block template
+	// generation.  It is recorded even when the transaction is not in the
+	// main pool yet.
+	PrioritiseTransaction(txHash *chainhash.Hash, feeDelta int64)
+
+	// PrioritisedTransactions returns the fee deltas recorded by
+	// PrioritiseTransaction as fully populated btcjson results keyed by
+	// the hash of their transaction.
+	PrioritisedTransactions() map[string]*btcjson.PrioritisedTransactionResult
+
 	// Count returns the number of transactions in the main pool. It does
 	// not include the orphan pool.
 	Count() int
@@ -64,8 +94,44 @@ type TxMempool interface {
 	// actions based on it.
 	CheckMempoolAcceptance(tx *btcutil.Tx) (*MempoolAcceptResult, error)
 
+	// CheckPackageAcceptance behaves similarly to bitcoind's
+	// `testmempoolaccept` RPC method for more than one transaction. It
+	// checks whether the passed package of transactions would be accepted
+	// to the mempool without adding any of them.
+	CheckPackageAcceptance(txns []*btcutil.Tx) (*PackageAcceptResult,
+		error)
+
+	// ProcessPackage validates the passed package, which consists of a
+	// child transaction along with its parents, and adds its transactions
+	// to the mempool, evaluating the transactions which don't pay a
+	// sufficient fee on their own at the fee rate of the package.
+	ProcessPackage(txns []*btcutil.Tx,
+		maxFeeRate btcutil.Amount) (*PackageAcceptResult, error)
+
 	// CheckSpend checks whether the passed outpoint is already spent by
 	// a transaction in the mempool. If that's the case the spending
 	// transaction will be returned, if not nil will be returned.
 	CheckSpend(op wire.OutPoint) *btcutil.Tx
+
+	// MinFeeRate returns the minimum fee rate in satoshi/kB a transaction
+	// must pay to be accepted into the mempool.
+	MinFeeRate() btcutil.Amount
+
+	// TotalSize returns the total virtual size in bytes of the
+	// transactions in the main pool.
+	TotalSize() int64
+
+	// DumpToFile writes the transactions in the main pool to the file at
+	// the passed path in a format compatible with the mempool.dat file
+	// used by Bitcoin Core.
+	DumpToFile(path string) error
+
+	// LoadFromFile loads the transactions from the mempool dump file at
+	// the passed path, validating them as if they were newly received.
+	LoadFromFile(path string, useCurrentTime bool,
+		interrupt <-chan struct{}) (*LoadStats, error)
+
+	// LoadTried returns whether an attempt to load the pool from a dump
+	// has finished.
+	LoadTried() bool
 }
@@ -5,10 +5,12 @@
 package mempool
 
 import (
+	"container/heap"
 	"container/list"
 	"fmt"
 	"maps"
 	"math"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -54,6 +56,20 @@ const (
 	// Transactions smaller than 65 non-witness bytes are not relayed to
 	// mitigate CVE-2017-12842.
 	MinStandardTxNonWitnessSize = 65
+
+	// DefaultMaxPoolSize is the default maximum total virtual size in
+	// bytes of the transactions in the main pool.
+	DefaultMaxPoolSize = 300 * 1000 * 1000
+
+	// rollingFeeHalfLife is the half-life of the rolling minimum fee rate
+	// once a block has been connected since it was last raised.  It is
+	// shortened when the pool is well below its maximum size so the fee
+	// rate drops faster when there is plenty of room.
+	rollingFeeHalfLife = time.Hour * 12
+
+	// rollingFeeUpdateInterval is the minimum amount of time in between
+	// updates of the decayed rolling minimum fee rate.
+	rollingFeeUpdateInterval = 10 // seconds
 )
 
 // Tag represents an identifier to use for tagging orphan transactions.  The
@@ -109,6 +125,23 @@ type Config struct {
 	// FeeEstimator provides a feeEstimator. If it is not nil, the mempool
 	// records all new transactions it observes into the feeEstimator.
 	FeeEstimator *FeeEstimator
+
+	// OnTxAdded defines an optional function to be called when a
+	// transaction is added to the main pool along with the mempool
+	// sequence number assigned to the event.
+	//
+	// This function is called with the mempool lock held, so it MUST NOT
+	// call back into the mempool.
+	OnTxAdded func(tx *btcutil.Tx, mempoolSeq uint64)
+
+	// OnTxRemoved defines an optional function to be called when a
+	// transaction is removed from the main pool for any reason other than
+	// being included in a block along with the mempool sequence number
+	// assigned to the event.
+	//
+	// This function is called with the mempool lock held, so it MUST NOT
+	// call back into the mempool.
+	OnTxRemoved func(tx *btcutil.Tx, mempoolSeq uint64)
 }
 
 // Policy houses the policy (configuration parameters) which is used to
@@ -154,6 +187,33 @@ type Policy struct {
 	// transactions using the Replace-By-Fee (RBF) signaling policy into
 	// the mempool.
 	RejectReplacement bool
+
+	// MaxPoolSize is the maximum total virtual size in bytes of the
+	// transactions in the main pool.  Once it is exceeded, the
+	// transactions with the lowest descendant fee rate are evicted along
+	// with their descendants and the minimum fee rate required to enter
+	// the pool is raised accordingly.  A value of zero disables the limit.
+	MaxPoolSize int64
+
+	// MaxAncestorCount is the maximum number of unconfirmed ancestors a
+	// transaction in the main pool may have, including the transaction
+	// itself.  A value of zero disables the limit.
+	MaxAncestorCount int
+
+	// MaxAncestorSize is the maximum total virtual size in bytes of a
+	// transaction in the main pool and its unconfirmed ancestors.  A value
+	// of zero disables the limit.
+	MaxAncestorSize int64
+
+	// MaxDescendantCount is the maximum number of unconfirmed descendants
+	// a transaction in the main pool may have, including the transaction
+	// itself.  A value of zero disables the limit.
+	MaxDescendantCount int
+
+	// MaxDescendantSize is the maximum total virtual size in bytes of a
+	// transaction in the main pool and its unconfirmed descendants.  A
+	// value of zero disables the limit.
+	MaxDescendantSize int64
 }
 
 // TxDesc is a descriptor containing a transaction in the mempool along with
@@ -164,6 +224,91 @@ type TxDesc struct {
 	// StartingPriority is the priority of the transaction when it was added
 	// to the pool.
 	StartingPriority float64
+
+	// vsize is the virtual size of the transaction.
+	vsize int64
+
+	// descendantCount, descendantFee and descendantSize are the number,
+	// total modified fee and total virtual size of the transaction and all
+	// of its descendants in the pool.
+	descendantCount int64
+	descendantFee   int64
+	descendantSize  int64
+
+	// ancestorCount, ancestorFee and ancestorSize are the number, total
+	// modified fee and total virtual size of the transaction and all of
+	// its ancestors in the pool.
+	ancestorCount int64
+	ancestorFee   int64
+	ancestorSize  int64
+
+	// evictionScore is the descendant score used to select transactions
+	// for eviction and evictionIndex is the position of the transaction in
+	// the eviction queue.
+	evictionScore float64
+	evictionIndex int
+}
+
+// modifiedFee returns the fee of the transaction plus the fee delta it was
+// prioritized with, which is used in place of the fee it actually pays when
+// checking and prioritizing it.
+func (txD *TxDesc) modifiedFee() int64 {
+	return txD.Fee + txD.FeeDelta
+}
+
+// descendantScore returns the greater of the modified fee rate of the
+// transaction and the modified fee rate of the package formed by it and its
+// descendants in the pool.
+func (txD *TxDesc) descendantScore() float64 {
+	return math.Max(float64(txD.modifiedFee())/float64(txD.vsize),
+		float64(txD.descendantFee)/float64(txD.descendantSize))
+}
+
+// evictionQueue implements heap.Interface to order the transactions in the
+// main pool by their descendant score so the transaction to evict next is
+// always at the root.  Ties are broken by evicting the most recently added
+// transaction first.
+type evictionQueue []*TxDesc
+
+// Len returns the number of transactions in the queue.  It is part of the
+// heap.Interface implementation.
+func (q evictionQueue) Len() int { return len(q) }
+
+// Less returns whether the transaction at index i should be evicted before
+// the transaction at index j.  It is part of the heap.Interface
+// implementation.
+func (q evictionQueue) Less(i, j int) bool {
+	if q[i].evictionScore == q[j].evictionScore {
+		return q[i].Added.After(q[j].Added)
+	}
+	return q[i].evictionScore < q[j].evictionScore
+}
+
+// Swap swaps the transactions at the passed indices in the queue.  It is part
+// of the heap.Interface implementation.
+func (q evictionQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].evictionIndex = i
+	q[j].evictionIndex = j
+}
+
+// Push adds the passed transaction to the queue.  It is part of the
+// heap.Interface implementation.
+func (q *evictionQueue) Push(x interface{}) {
+	txD := x.(*TxDesc)
+	txD.evictionIndex = len(*q)
+	*q = append(*q, txD)
+}
+
+// Pop removes the last transaction from the queue.  It is part of the
+// heap.Interface implementation.
+func (q *evictionQueue) Pop() interface{} {
+	old := *q
+	n := len(old)
+	txD := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return txD
 }
 
 // orphanTx is normal transaction that references an ancestor transaction
@@ -181,6 +326,7 @@ type orphanTx struct {
 type TxPool struct {
 	// The following variables must only be used atomically.
 	lastUpdated int64 // last time pool was updated
+	loadTried   int32 // whether loading the pool from a dump finished
 
 	mtx           sync.RWMutex
 	cfg           Config
@@ -191,6 +337,35 @@ type TxPool struct {
 	pennyTotal    float64 // exponentially decaying total for penny spends.
 	lastPennyUnix int64   // unix time of last ``penny spend''
 
+	// sequence is incremented each time a transaction is added to or
+	// removed from the main pool, including removals due to inclusion in
+	// a block.
+	sequence uint64
+
+	// feeDeltas holds the fee deltas transactions were prioritized with by
+	// PrioritiseTransaction, including transactions which are not in the
+	// main pool yet.  A delta is applied once its transaction is added to
+	// the pool and is cleared once the transaction is included in a block.
+	feeDeltas map[chainhash.Hash]int64
+
+	// totalSize is the total virtual size of the transactions in the main
+	// pool.
+	totalSize int64
+
+	// evictionQueue orders the transactions in the main pool by their
+	// descendant score to select the transactions to evict once the pool
+	// exceeds its maximum size.
+	evictionQueue evictionQueue
+
+	// rollingMinFeeRate is the minimum fee rate in satoshi/kB required to
+	// enter the pool after transactions were evicted due to the pool
+	// exceeding its maximum size.  It decays once a block has been
+	// connected since it was last raised, as tracked by blockSinceFeeBump,
+	// and lastRollingFeeUpdate is the unix time it was last updated.
+	rollingMinFeeRate    float64
+	lastRollingFeeUpdate int64
+	blockSinceFeeBump    bool
+
 	// nextExpireScan is the time after which the orphan pool will be
 	// scanned in order to evict orphans.  This is NOT a hard deadline as
 	// the scan will only run when an orphan is added to the pool as opposed
@@ -471,18 +646,35 @@ func (mp *TxPool) HaveTransaction(hash *chainhash.Hash) bool {
 	return haveTx
 }
 
+// nextSequence returns the mempool sequence number to assign to an addition
+// or removal and increments it.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) nextSequence() uint64 {
+	seq := mp.sequence
+	mp.sequence++
+	return seq
+}
+
 // removeTransaction is the internal function which implements the public
-// RemoveTransaction.  See the comment for RemoveTransaction for more details.
+// RemoveTransaction and RemoveConfirmedTransaction.  See the comment for
+// RemoveTransaction for more details.  The confirmed flag indicates the
+// transaction is being removed due to its inclusion in a block.
 //
 // This function MUST be called with the mempool lock held (for writes).
-func (mp *TxPool) removeTransaction(tx *btcutil.Tx, removeRedeemers bool) {
+func (mp *TxPool) removeTransaction(tx *btcutil.Tx, removeRedeemers,
+	confirmed bool) {
+
 	txHash := tx.Hash()
+	if confirmed {
+		delete(mp.feeDeltas, *txHash)
+	}
 	if removeRedeemers {
 		// Remove any transactions which rely on this one.
 		for i := uint32(0); i < uint32(len(tx.MsgTx().TxOut)); i++ {
 			prevOut := wire.OutPoint{Hash: *txHash, Index: i}
 			if txRedeemer, exists := mp.outpoints[prevOut]; exists {
-				mp.removeTransaction(txRedeemer, true)
+				mp.removeTransaction(txRedeemer, true, false)
 			}
 		}
 	}
@@ -495,12 +687,38 @@ func (mp *TxPool) removeTransaction(tx *btcutil.Tx, removeRedeemers bool) {
 			mp.cfg.AddrIndex.RemoveUnconfirmedTx(txHash)
 		}
 
+		// The relatives of the transaction are determined before it is
+		// removed since the transaction connects them.
+		ancestors := mp.txAncestors(tx, nil)
+		descendants := mp.txDescendants(tx, nil)
+		heap.Remove(&mp.evictionQueue, txDesc.evictionIndex)
+
 		// Mark the referenced outpoints as unspent by the pool.
 		for _, txIn := range txDesc.Tx.MsgTx().TxIn {
 			delete(mp.outpoints, txIn.PreviousOutPoint)
 		}
 		delete(mp.pool, *txHash)
+		mp.totalSize -= txDesc.vsize
+
+		// The transaction no longer contributes to the descendant
+		// totals of its ancestors.  When it still has descendants,
+		// which is the case when it is removed due to its inclusion in
+		// a block, they may also no longer descend from its ancestors,
+		// so the totals of all its relatives are recomputed instead.
+		if len(descendants) == 0 {
+			mp.updateAncestorScores(
+				ancestors, -1, -txDesc.modifiedFee(),
+				-txDesc.vsize,
+			)
+		} else {
+			mp.updateRelativeTotals(ancestors, descendants)
+		}
 		atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
+
+		seq := mp.nextSequence()
+		if !confirmed && mp.cfg.OnTxRemoved != nil {
+			mp.cfg.OnTxRemoved(tx, seq)
+		}
 	}
 }
 
@@ -513,7 +731,43 @@ func (mp *TxPool) removeTransaction(tx *btcutil.Tx, removeRedeemers bool) {
 func (mp *TxPool) RemoveTransaction(tx *btcutil.Tx, removeRedeemers bool) {
 	// Protect concurrent access.
 	mp.mtx.Lock()
-	mp.removeTransaction(tx, removeRedeemers)
+	mp.removeTransaction(tx, removeRedeemers, false)
+	mp.mtx.Unlock()
+}
+
+// RemoveConfirmedTransaction removes the passed transaction from the mempool
+// due to its inclusion in a block.  It differs from RemoveTransaction in that
+// transactions redeeming its outputs remain in the pool and the OnTxRemoved
+// callback is not invoked since the transaction was mined rather than
+// evicted.  The fee delta the transaction was prioritized with, if any, is
+// cleared even when it is not in the pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) RemoveConfirmedTransaction(tx *btcutil.Tx) {
+	// Protect concurrent access.
+	mp.mtx.Lock()
+	mp.removeTransaction(tx, false, true)
+	mp.mtx.Unlock()
+}
+
+// blockConnected is the internal function which implements the public
+// BlockConnected.  See the comment for BlockConnected for more details.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) blockConnected(now int64) {
+	mp.updateMinFeeRate(now)
+	mp.blockSinceFeeBump = true
+}
+
+// BlockConnected informs the pool that a block has been connected to the main
+// chain.  It must be called once per connected block, after its transactions
+// were removed from the pool, and allows the rolling minimum fee rate to
+// decay.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) BlockConnected() {
+	mp.mtx.Lock()
+	mp.blockConnected(time.Now().Unix())
 	mp.mtx.Unlock()
 }
 
@@ -530,7 +784,7 @@ func (mp *TxPool) RemoveDoubleSpends(tx *btcutil.Tx) {
 	for _, txIn := range tx.MsgTx().TxIn {
 		if txRedeemer, ok := mp.outpoints[txIn.PreviousOutPoint]; ok {
 			if !txRedeemer.Hash().IsEqual(tx.Hash()) {
-				mp.removeTransaction(txRedeemer, true)
+				mp.removeTransaction(txRedeemer, true, false)
 			}
 		}
 	}
@@ -545,21 +799,47 @@ func (mp *TxPool) RemoveDoubleSpends(tx *btcutil.Tx) {
 func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint, tx *btcutil.Tx, height int32, fee int64) *TxDesc {
 	// Add the transaction to the pool and mark the referenced outpoints
 	// as spent by the pool.
+	txSize := GetTxVirtualSize(tx)
 	txD := &TxDesc{
 		TxDesc: mining.TxDesc{
 			Tx:       tx,
 			Added:    time.Now(),
 			Height:   height,
 			Fee:      fee,
-			FeePerKB: fee * 1000 / GetTxVirtualSize(tx),
+			FeePerKB: fee * 1000 / txSize,
+			FeeDelta: mp.feeDeltas[*tx.Hash()],
 		},
 		StartingPriority: mining.CalcPriority(tx.MsgTx(), utxoView, height),
+		vsize:            txSize,
+		descendantCount:  1,
+		descendantSize:   txSize,
 	}
+	txD.descendantFee = txD.modifiedFee()
+	txD.evictionScore = txD.descendantScore()
 
 	mp.pool[*tx.Hash()] = txD
+	mp.totalSize += txSize
+	heap.Push(&mp.evictionQueue, txD)
 	for _, txIn := range tx.MsgTx().TxIn {
 		mp.outpoints[txIn.PreviousOutPoint] = tx
 	}
+
+	// The transaction contributes to the descendant totals of its
+	// ancestors.  It already has descendants in the pool when it is added
+	// back after the block including it was disconnected, in which case
+	// the totals of all its relatives are recomputed instead since it now
+	// connects them.
+	ancestors := mp.txAncestors(tx, nil)
+	mp.updateAncestorTotals(txD, ancestors)
+	descendants := mp.txDescendants(tx, nil)
+	if len(descendants) == 0 {
+		mp.updateAncestorScores(
+			ancestors, 1, txD.modifiedFee(), txSize,
+		)
+	} else {
+		mp.updateDescendantTotals(txD, nil)
+		mp.updateRelativeTotals(ancestors, descendants)
+	}
 	atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
 
 	// Add unconfirmed address index entries associated with the transaction
@@ -573,6 +853,11 @@ func (mp *TxPool) addTransaction(utxoView *blockchain.UtxoViewpoint, tx *btcutil
 		mp.cfg.FeeEstimator.ObserveTransaction(txD)
 	}
 
+	seq := mp.nextSequence()
+	if mp.cfg.OnTxAdded != nil {
+		mp.cfg.OnTxAdded(tx, seq)
+	}
+
 	return txD
 }
 
@@ -628,6 +913,11 @@ func (mp *TxPool) checkPoolDoubleSpend(tx *btcutil.Tx) (bool, error) {
 func (mp *TxPool) signalsReplacement(tx *btcutil.Tx,
 	cache map[chainhash.Hash]struct{}) bool {
 
+	// TRUC transactions are always replaceable.
+	if tx.MsgTx().Version == TrucVersion {
+		return true
+	}
+
 	// If a cache was not provided, we'll initialize one now to use for the
 	// recursive calls.
 	if cache == nil {
@@ -834,15 +1124,21 @@ func (mp *TxPool) FetchTransaction(txHash *chainhash.Hash) (*btcutil.Tx, error)
 // validateReplacement determines whether a transaction is deemed as a valid
 // replacement of all of its conflicts according to the RBF policy. If it is
 // valid, no error is returned. Otherwise, an error is returned indicating what
-// went wrong.
+// went wrong.  The sibling is optional and is considered a conflict when set,
+// which is the case when the transaction evicts the existing child of its TRUC
+// parent.
 //
 // This function MUST be called with the mempool lock held (for reads).
-func (mp *TxPool) validateReplacement(tx *btcutil.Tx,
-	txFee int64) (map[chainhash.Hash]*btcutil.Tx, error) {
+func (mp *TxPool) validateReplacement(tx *btcutil.Tx, txFee int64,
+	sibling *btcutil.Tx) (map[chainhash.Hash]*btcutil.Tx, error) {
 
 	// First, we'll make sure the set of conflicting transactions doesn't
 	// exceed the maximum allowed.
 	conflicts := mp.txConflicts(tx)
+	if sibling != nil {
+		conflicts[*sibling.Hash()] = sibling
+		maps.Copy(conflicts, mp.txDescendants(sibling, nil))
+	}
 	if len(conflicts) > MaxReplacementEvictions {
 		str := fmt.Sprintf("%v: replacement transaction evicts more "+
 			"transactions than permitted: max is %v, evicts %v",
@@ -864,7 +1160,9 @@ func (mp *TxPool) validateReplacement(tx *btcutil.Tx,
 
 	// The replacement should have a higher fee rate than each of the
 	// conflicting transactions and a higher absolute fee than the fee sum
-	// of all the conflicting transactions.
+	// of all the conflicting transactions.  The modified fees of the
+	// conflicting transactions are compared against, just like the passed
+	// fee of the replacement.
 	//
 	// We usually don't want to accept replacements with lower fee rates
 	// than what they replaced as that would lower the fee rate of the next
@@ -877,15 +1175,16 @@ func (mp *TxPool) validateReplacement(tx *btcutil.Tx,
 		conflictsParents = make(map[chainhash.Hash]struct{})
 	)
 	for hash, conflict := range conflicts {
-		if txFeeRate <= mp.pool[hash].FeePerKB {
+		conflictFee := mp.pool[hash].modifiedFee()
+		conflictFeeRate := conflictFee * 1000 / mp.pool[hash].vsize
+		if txFeeRate <= conflictFeeRate {
 			str := fmt.Sprintf("%v: replacement transaction has an "+
 				"insufficient fee rate: needs more than %v, "+
-				"has %v", tx.Hash(), mp.pool[hash].FeePerKB,
-				txFeeRate)
+				"has %v", tx.Hash(), conflictFeeRate, txFeeRate)
 			return nil, txRuleError(wire.RejectInsufficientFee, str)
 		}
 
-		conflictsFee += mp.pool[hash].Fee
+		conflictsFee += conflictFee
 
 		// We'll track each conflict's parents to ensure the replacement
 		// isn't spending any new unconfirmed inputs.
@@ -933,11 +1232,9 @@ func (mp *TxPool) validateReplacement(tx *btcutil.Tx,
 func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit,
 	rejectDupOrphans bool) ([]*chainhash.Hash, *TxDesc, error) {
 
-	txHash := tx.Hash()
-
 	// Check for mempool acceptance.
 	r, err := mp.checkMempoolAcceptance(
-		tx, isNew, rateLimit, rejectDupOrphans,
+		tx, isNew, rateLimit, rejectDupOrphans, nil,
 	)
 	if err != nil {
 		return nil, nil, err
@@ -948,6 +1245,26 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit,
 		return r.MissingParents, nil, nil
 	}
 
+	txD, err := mp.acceptTransaction(tx, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nil, txD, nil
+}
+
+// acceptTransaction adds the passed transaction, which must have passed the
+// checks performed by checkMempoolAcceptance as described by the passed
+// result, to the pool after removing the transactions it replaces.  The pool
+// is then trimmed to its maximum size and an error is returned if the
+// transaction ended up being evicted itself.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) acceptTransaction(tx *btcutil.Tx,
+	r *MempoolAcceptResult) (*TxDesc, error) {
+
+	txHash := tx.Hash()
+
 	// Now that we've deemed the transaction as valid, we can add it to the
 	// mempool. If it ended up replacing any transactions, we'll remove them
 	// first.
@@ -960,14 +1277,248 @@ func (mp *TxPool) maybeAcceptTransaction(tx *btcutil.Tx, isNew, rateLimit,
 		// The conflict set should already include the descendants for
 		// each one, so we don't need to remove the redeemers within
 		// this call as they'll be removed eventually.
-		mp.removeTransaction(conflict, false)
+		mp.removeTransaction(conflict, false, false)
 	}
 	txD := mp.addTransaction(r.utxoView, tx, r.bestHeight, int64(r.TxFee))
 
+	// Evict transactions until the pool fits within its maximum size.  The
+	// transaction is rejected if it ended up being evicted itself.
+	mp.trimToSize()
+	if !mp.isTransactionInPool(txHash) {
+		str := fmt.Sprintf("transaction %v was evicted since the "+
+			"mempool is full", txHash)
+		return nil, txRuleError(wire.RejectInsufficientFee, str)
+	}
+
 	log.Debugf("Accepted transaction %v (pool size: %v)", txHash,
 		len(mp.pool))
 
-	return nil, txD, nil
+	return txD, nil
+}
+
+// updateAncestorScores adds the passed number of transactions, fee and
+// virtual size to the descendant totals of the passed ancestors in the main
+// pool and updates their positions in the eviction queue accordingly.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) updateAncestorScores(
+	ancestors map[chainhash.Hash]*btcutil.Tx, count, fee, size int64) {
+
+	for hash := range ancestors {
+		ancestor := mp.pool[hash]
+		ancestor.descendantCount += count
+		ancestor.descendantFee += fee
+		ancestor.descendantSize += size
+		ancestor.evictionScore = ancestor.descendantScore()
+		heap.Fix(&mp.evictionQueue, ancestor.evictionIndex)
+	}
+}
+
+// updateAncestorTotals sets the ancestor totals of the passed transaction in
+// the main pool from the passed set of its ancestors.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) updateAncestorTotals(txD *TxDesc,
+	ancestors map[chainhash.Hash]*btcutil.Tx) {
+
+	txD.ancestorCount = 1
+	txD.ancestorFee = txD.modifiedFee()
+	txD.ancestorSize = txD.vsize
+	for hash := range ancestors {
+		ancestor := mp.pool[hash]
+		txD.ancestorCount++
+		txD.ancestorFee += ancestor.modifiedFee()
+		txD.ancestorSize += ancestor.vsize
+	}
+}
+
+// updateDescendantTotals recomputes the descendant totals of the passed
+// transaction in the main pool and updates its position in the eviction queue
+// accordingly.  The cache is optional and is passed to txDescendants.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) updateDescendantTotals(txD *TxDesc,
+	cache map[chainhash.Hash]map[chainhash.Hash]*btcutil.Tx) {
+
+	txD.descendantCount = 1
+	txD.descendantFee = txD.modifiedFee()
+	txD.descendantSize = txD.vsize
+	for hash := range mp.txDescendants(txD.Tx, cache) {
+		descendant := mp.pool[hash]
+		txD.descendantCount++
+		txD.descendantFee += descendant.modifiedFee()
+		txD.descendantSize += descendant.vsize
+	}
+	txD.evictionScore = txD.descendantScore()
+	heap.Fix(&mp.evictionQueue, txD.evictionIndex)
+}
+
+// updateRelativeTotals recomputes the descendant totals of the passed
+// ancestors and the ancestor totals of the passed descendants of a
+// transaction which was just added to or removed from the main pool.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) updateRelativeTotals(ancestors,
+	descendants map[chainhash.Hash]*btcutil.Tx) {
+
+	ancestorsCache := make(map[chainhash.Hash]map[chainhash.Hash]*btcutil.Tx)
+	for hash, descendant := range descendants {
+		mp.updateAncestorTotals(
+			mp.pool[hash], mp.txAncestors(descendant, ancestorsCache),
+		)
+	}
+
+	descendantsCache := make(map[chainhash.Hash]map[chainhash.Hash]*btcutil.Tx)
+	for hash := range ancestors {
+		mp.updateDescendantTotals(mp.pool[hash], descendantsCache)
+	}
+}
+
+// trimToSize evicts the transactions with the lowest descendant score along
+// with their descendants until the total virtual size of the pool no longer
+// exceeds the maximum allowed by the policy.  The descendant score of a
+// transaction is the greater of its own fee rate and the fee rate of the
+// package formed by it and its descendants, which ensures a transaction
+// paying for its ancestors is not evicted before them.  The rolling minimum
+// fee rate is raised above the fee rate of each evicted package.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) trimToSize() {
+	maxSize := mp.cfg.Policy.MaxPoolSize
+	if maxSize <= 0 {
+		return
+	}
+
+	for mp.totalSize > maxSize && len(mp.evictionQueue) > 0 {
+		worst := mp.evictionQueue[0]
+		pkgFee, pkgSize := worst.descendantFee, worst.descendantSize
+
+		// Transactions paying less than the fee rate of the evicted
+		// package plus the incremental relay fee are not accepted until
+		// the rolling minimum fee rate decays.
+		feeRate := float64(pkgFee*1000/pkgSize) +
+			float64(mp.cfg.Policy.MinRelayTxFee)
+		mp.trackPackageRemoved(feeRate)
+
+		log.Debugf("Evicting transaction %v and its descendants "+
+			"(fee_rate=%v sat/kb) since the mempool is full",
+			worst.Tx.Hash(), pkgFee*1000/pkgSize)
+
+		mp.removeTransaction(worst.Tx, true, false)
+	}
+}
+
+// trackPackageRemoved raises the rolling minimum fee rate to the passed fee
+// rate in satoshi/kB of an evicted package if it is higher.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) trackPackageRemoved(feeRate float64) {
+	mp.updateMinFeeRate(time.Now().Unix())
+	if feeRate > mp.rollingMinFeeRate {
+		mp.rollingMinFeeRate = feeRate
+		mp.blockSinceFeeBump = false
+	}
+}
+
+// updateMinFeeRate stores the rolling minimum fee rate decayed up to the
+// passed unix time and makes it the time of the last update, so the decay is
+// not lost when the time of the last update moves forward.  Like the decay
+// itself, the update is skipped when the rate was updated less than
+// rollingFeeUpdateInterval ago unless the rate is not decaying yet.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) updateMinFeeRate(now int64) {
+	if mp.blockSinceFeeBump &&
+		now <= mp.lastRollingFeeUpdate+rollingFeeUpdateInterval {
+
+		return
+	}
+
+	mp.rollingMinFeeRate = mp.decayedMinFeeRate(now)
+	mp.lastRollingFeeUpdate = now
+}
+
+// decayedMinFeeRate returns the rolling minimum fee rate in satoshi/kB after
+// decaying it up to the passed unix time.  The rate halves every
+// rollingFeeHalfLife, or four times as often when the pool is below a quarter
+// of its maximum size and twice as often when it is below half, and drops to
+// zero once it is below half the minimum relay fee.  It does not decay until
+// a block has been connected since it was last raised.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) decayedMinFeeRate(now int64) float64 {
+	if !mp.blockSinceFeeBump || mp.rollingMinFeeRate == 0 {
+		return mp.rollingMinFeeRate
+	}
+	if now <= mp.lastRollingFeeUpdate+rollingFeeUpdateInterval {
+		return mp.rollingMinFeeRate
+	}
+
+	halfLife := rollingFeeHalfLife.Seconds()
+	switch maxSize := mp.cfg.Policy.MaxPoolSize; {
+	case mp.totalSize < maxSize/4:
+		halfLife /= 4
+	case mp.totalSize < maxSize/2:
+		halfLife /= 2
+	}
+
+	elapsed := float64(now - mp.lastRollingFeeUpdate)
+	feeRate := mp.rollingMinFeeRate / math.Pow(2, elapsed/halfLife)
+	if feeRate < float64(mp.cfg.Policy.MinRelayTxFee)/2 {
+		return 0
+	}
+
+	return feeRate
+}
+
+// minFeeRate returns the minimum fee rate in satoshi/kB a transaction must pay
+// to be accepted into the pool due to previous evictions, or zero when there
+// is none.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) minFeeRate() btcutil.Amount {
+	feeRate := mp.decayedMinFeeRate(time.Now().Unix())
+	if feeRate == 0 {
+		return 0
+	}
+
+	// Once a block has been connected, the rate never drops below the
+	// incremental relay fee before it is reset entirely.
+	minFeeRate := btcutil.Amount(math.Round(feeRate))
+	if mp.blockSinceFeeBump && minFeeRate < mp.cfg.Policy.MinRelayTxFee {
+		minFeeRate = mp.cfg.Policy.MinRelayTxFee
+	}
+
+	return minFeeRate
+}
+
+// MinFeeRate returns the minimum fee rate in satoshi/kB a transaction must pay
+// to be accepted into the pool.  It is the greater of the minimum relay fee
+// and the rolling minimum fee rate, which rises as transactions are evicted
+// due to the pool exceeding its maximum size and decays over time.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MinFeeRate() btcutil.Amount {
+	mp.mtx.RLock()
+	minFeeRate := mp.minFeeRate()
+	mp.mtx.RUnlock()
+
+	if minFeeRate < mp.cfg.Policy.MinRelayTxFee {
+		return mp.cfg.Policy.MinRelayTxFee
+	}
+	return minFeeRate
+}
+
+// TotalSize returns the total virtual size in bytes of the transactions in
+// the main pool.  This is the size limited by the MaxPoolSize policy.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) TotalSize() int64 {
+	mp.mtx.RLock()
+	totalSize := mp.totalSize
+	mp.mtx.RUnlock()
+
+	return totalSize
 }
 
 // MaybeAcceptTransaction is the main workhorse for handling insertion of new
@@ -1203,7 +1754,8 @@ func (mp *TxPool) TxDescs() []*TxDesc {
 }
 
 // MiningDescs returns a slice of mining descriptors for all the transactions
-// in the pool.
+// in the pool.  The descriptors are copies since the fee deltas of the
+// transactions may change while they are used.
 //
 // This is part of the mining.TxSource interface implementation and is safe for
 // concurrent access as required by the interface contract.
@@ -1212,7 +1764,8 @@ func (mp *TxPool) MiningDescs() []*mining.TxDesc {
 	descs := make([]*mining.TxDesc, len(mp.pool))
 	i := 0
 	for _, desc := range mp.pool {
-		descs[i] = &desc.TxDesc
+		miningDesc := desc.TxDesc
+		descs[i] = &miningDesc
 		i++
 	}
 	mp.mtx.RUnlock()
@@ -1269,8 +1822,220 @@ func (mp *TxPool) RawMempoolVerbose() map[string]*btcjson.GetRawMempoolVerboseRe
 	return result
 }
 
+// mempoolEntry returns the passed transaction of the main pool as a fully
+// populated btcjson result.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) mempoolEntry(txD *TxDesc) *btcjson.GetMempoolEntryResult {
+	tx := txD.Tx
+	fee := btcutil.Amount(txD.Fee).ToBTC()
+	modifiedFee := btcutil.Amount(txD.modifiedFee()).ToBTC()
+	ancestorFee := btcutil.Amount(txD.ancestorFee).ToBTC()
+	descendantFee := btcutil.Amount(txD.descendantFee).ToBTC()
+	entry := &btcjson.GetMempoolEntryResult{
+		VSize:             int32(txD.vsize),
+		Size:              int32(tx.MsgTx().SerializeSize()),
+		Weight:            blockchain.GetTransactionWeight(tx),
+		Fee:               fee,
+		ModifiedFee:       modifiedFee,
+		Time:              txD.Added.Unix(),
+		Height:            int64(txD.Height),
+		DescendantCount:   txD.descendantCount,
+		DescendantSize:    txD.descendantSize,
+		DescendantFees:    float64(txD.descendantFee),
+		AncestorCount:     txD.ancestorCount,
+		AncestorSize:      txD.ancestorSize,
+		AncestorFees:      float64(txD.ancestorFee),
+		WTxId:             tx.WitnessHash().String(),
+		BIP125Replaceable: mp.signalsReplacement(tx, nil),
+		Fees: btcjson.MempoolFees{
+			Base:       fee,
+			Modified:   modifiedFee,
+			Ancestor:   ancestorFee,
+			Descendant: descendantFee,
+		},
+		Depends: make([]string, 0),
+		SpentBy: make([]string, 0),
+	}
+
+	for hash := range mp.unconfirmedParents(tx, nil) {
+		entry.Depends = append(entry.Depends, hash.String())
+	}
+	sort.Strings(entry.Depends)
+
+	spentBy := make(map[chainhash.Hash]struct{})
+	op := wire.OutPoint{Hash: *tx.Hash()}
+	for i := range tx.MsgTx().TxOut {
+		op.Index = uint32(i)
+		if child, ok := mp.outpoints[op]; ok {
+			spentBy[*child.Hash()] = struct{}{}
+		}
+	}
+	for hash := range spentBy {
+		entry.SpentBy = append(entry.SpentBy, hash.String())
+	}
+	sort.Strings(entry.SpentBy)
+
+	return entry
+}
+
+// MempoolEntry returns the transaction with the passed hash in the main pool
+// as a fully populated btcjson result, which includes the totals of its
+// unconfirmed ancestors and descendants.  An error is returned when the
+// transaction is not in the main pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MempoolEntry(
+	txHash *chainhash.Hash) (*btcjson.GetMempoolEntryResult, error) {
+
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	txD, exists := mp.pool[*txHash]
+	if !exists {
+		return nil, fmt.Errorf("transaction is not in the pool")
+	}
+
+	return mp.mempoolEntry(txD), nil
+}
+
+// MempoolAncestors returns the unconfirmed ancestors of the transaction with
+// the passed hash in the main pool as fully populated btcjson results keyed by
+// their hash.  An error is returned when the transaction is not in the main
+// pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MempoolAncestors(
+	txHash *chainhash.Hash) (map[string]*btcjson.GetMempoolEntryResult, error) {
+
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	txD, exists := mp.pool[*txHash]
+	if !exists {
+		return nil, fmt.Errorf("transaction is not in the pool")
+	}
+
+	return mp.mempoolEntries(mp.txAncestors(txD.Tx, nil)), nil
+}
+
+// MempoolDescendants returns the unconfirmed descendants of the transaction
+// with the passed hash in the main pool as fully populated btcjson results
+// keyed by their hash.  An error is returned when the transaction is not in
+// the main pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) MempoolDescendants(
+	txHash *chainhash.Hash) (map[string]*btcjson.GetMempoolEntryResult, error) {
+
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	txD, exists := mp.pool[*txHash]
+	if !exists {
+		return nil, fmt.Errorf("transaction is not in the pool")
+	}
+
+	return mp.mempoolEntries(mp.txDescendants(txD.Tx, nil)), nil
+}
+
+// mempoolEntries returns the passed transactions of the main pool as fully
+// populated btcjson results keyed by their hash.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) mempoolEntries(
+	txns map[chainhash.Hash]*btcutil.Tx) map[string]*btcjson.GetMempoolEntryResult {
+
+	entries := make(map[string]*btcjson.GetMempoolEntryResult, len(txns))
+	for hash := range txns {
+		entries[hash.String()] = mp.mempoolEntry(mp.pool[hash])
+	}
+
+	return entries
+}
+
+// modifiedFee returns the passed fee of the transaction with the passed hash
+// plus the fee delta it was prioritized with, if any.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) modifiedFee(txHash *chainhash.Hash, fee int64) int64 {
+	return fee + mp.feeDeltas[*txHash]
+}
+
+// PrioritiseTransaction adds the passed fee delta in satoshi to the fee delta
+// of the transaction with the passed hash.  The fee delta is added to the fee
+// the transaction pays when checking it against the relay fee policy, when
+// selecting transactions to evict once the pool is full, and when selecting
+// transactions for new blocks.  It is recorded even when the transaction is
+// not in the main pool, in which case it is applied once the transaction is
+// added to the pool, and it is cleared once the transaction is included in a
+// block or the fee delta drops back to zero.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) PrioritiseTransaction(txHash *chainhash.Hash,
+	feeDelta int64) {
+
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	delta := mp.feeDeltas[*txHash] + feeDelta
+	if delta == 0 {
+		delete(mp.feeDeltas, *txHash)
+	} else {
+		mp.feeDeltas[*txHash] = delta
+	}
+	log.Debugf("Prioritised transaction %v by %d to a fee delta of %d",
+		txHash, feeDelta, delta)
+
+	txD, exists := mp.pool[*txHash]
+	if !exists {
+		return
+	}
+
+	// The modified fee of the transaction contributes to the ancestor
+	// totals of its descendants and the descendant totals of its
+	// ancestors.
+	txD.FeeDelta = delta
+	txD.ancestorFee += feeDelta
+	txD.descendantFee += feeDelta
+	txD.evictionScore = txD.descendantScore()
+	heap.Fix(&mp.evictionQueue, txD.evictionIndex)
+	mp.updateAncestorScores(mp.txAncestors(txD.Tx, nil), 0, feeDelta, 0)
+	for hash := range mp.txDescendants(txD.Tx, nil) {
+		mp.pool[hash].ancestorFee += feeDelta
+	}
+	atomic.StoreInt64(&mp.lastUpdated, time.Now().Unix())
+}
+
+// PrioritisedTransactions returns the fee deltas recorded by
+// PrioritiseTransaction as fully populated btcjson results keyed by the hash
+// of their transaction.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) PrioritisedTransactions() map[string]*btcjson.PrioritisedTransactionResult {
+	mp.mtx.RLock()
+	defer mp.mtx.RUnlock()
+
+	result := make(map[string]*btcjson.PrioritisedTransactionResult,
+		len(mp.feeDeltas))
+	for hash, delta := range mp.feeDeltas {
+		entry := &btcjson.PrioritisedTransactionResult{
+			FeeDelta: delta,
+		}
+		if txD, exists := mp.pool[hash]; exists {
+			modifiedFee := txD.modifiedFee()
+			entry.InMempool = true
+			entry.ModifiedFee = &modifiedFee
+		}
+		result[hash.String()] = entry
+	}
+
+	return result
+}
+
 // LastUpdated returns the last time a transaction was added to or removed from
-// the main pool.  It does not include the orphan pool.
+// the main pool or the fee delta of a transaction in the main pool changed.
+// It does not include the orphan pool.
 //
 // This function is safe for concurrent access.
 func (mp *TxPool) LastUpdated() time.Time {
@@ -1320,7 +2085,7 @@ func (mp *TxPool) CheckMempoolAcceptance(tx *btcutil.Tx) (
 	// which has the effect that we always check the fee paid from this tx
 	// is greater than min relay fee. We also reject this tx if it's
 	// already an orphan.
-	result, err := mp.checkMempoolAcceptance(tx, true, true, true)
+	result, err := mp.checkMempoolAcceptance(tx, true, true, true, nil)
 	if err != nil {
 		log.Errorf("CheckMempoolAcceptance: %v", err)
 		return nil, err
@@ -1334,9 +2099,13 @@ func (mp *TxPool) CheckMempoolAcceptance(tx *btcutil.Tx) (
 
 // checkMempoolAcceptance performs a series of validations on the given
 // transaction. It returns an error when the transaction fails to meet the
-// mempool policy, otherwise a `mempoolAcceptResult` is returned.
+// mempool policy, otherwise a `mempoolAcceptResult` is returned.  When the
+// transaction is validated as part of a package, the passed package context
+// provides the outputs of the other transactions in the package and
+// determines whether the fee checks are left to the caller.
 func (mp *TxPool) checkMempoolAcceptance(tx *btcutil.Tx,
-	isNew, rateLimit, rejectDupOrphans bool) (*MempoolAcceptResult, error) {
+	isNew, rateLimit, rejectDupOrphans bool,
+	pkg *packageContext) (*MempoolAcceptResult, error) {
 
 	txHash := tx.Hash()
 
@@ -1417,6 +2186,9 @@ func (mp *TxPool) checkMempoolAcceptance(tx *btcutil.Tx,
 
 		return nil, err
 	}
+	if pkg != nil {
+		pkg.fetchInputUtxos(tx, utxoView)
+	}
 
 	// Don't allow the transaction if it exists in the main chain and is
 	// already fully spent.
@@ -1513,25 +2285,51 @@ func (mp *TxPool) checkMempoolAcceptance(tx *btcutil.Tx,
 
 	txSize := GetTxVirtualSize(tx)
 
-	// Don't allow transactions with fees too low to get into a mined
-	// block.
-	err = mp.validateRelayFeeMet(
-		tx, txFee, txSize, utxoView, nextBlockHeight, isNew, rateLimit,
-	)
+	// Enforce the TRUC policy, which may require evicting a sibling of the
+	// transaction, and don't allow dust outputs unless they're ephemeral.
+	sibling, err := mp.validateTrucPolicy(tx, txSize, pkg)
 	if err != nil {
 		return nil, err
 	}
+	if err := mp.validateEphemeralDust(tx, txFee, pkg); err != nil {
+		return nil, err
+	}
+
+	// Don't allow transactions with fees too low to get into a mined
+	// block unless the caller checks the fees of the package the
+	// transaction is part of instead.  The fee checks apply to the fee
+	// delta the transaction was prioritized with, if any, along with the
+	// fee it pays.
+	modifiedFee := mp.modifiedFee(txHash, txFee)
+	if pkg == nil || !pkg.skipFeeChecks {
+		err = mp.validateFees(
+			tx, modifiedFee, txSize, utxoView, nextBlockHeight,
+			isNew, rateLimit,
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	// If the transaction has any conflicts, and we've made it this far,
-	// then we're processing a potential replacement.
+	// If the transaction has any conflicts or evicts a sibling, and we've
+	// made it this far, then we're processing a potential replacement.
 	var conflicts map[chainhash.Hash]*btcutil.Tx
-	if isReplacement {
-		conflicts, err = mp.validateReplacement(tx, txFee)
+	if isReplacement || sibling != nil {
+		conflicts, err = mp.validateReplacement(
+			tx, modifiedFee, sibling,
+		)
 		if err != nil {
 			return nil, err
 		}
 	}
 
+	// Don't allow the transaction to extend the chains of unconfirmed
+	// transactions in the pool beyond the ancestor and descendant limits.
+	err = mp.validateChainLimits(tx, txSize, conflicts, pkg)
+	if err != nil {
+		return nil, err
+	}
+
 	// Verify crypto signatures for each input and reject the transaction
 	// if any don't verify.
 	err = blockchain.ValidateTransactionScripts(tx, utxoView,
@@ -1604,10 +2402,12 @@ func (mp *TxPool) validateStandardness(tx *btcutil.Tx, nextBlockHeight int32,
 		return nil
 	}
 
-	// Check the transaction standard.
-	err := CheckTransactionStandard(
+	// Check the transaction standard.  Dust outputs are allowed as long as
+	// they are ephemeral, which is checked separately.
+	err := checkTransactionStandard(
 		tx, nextBlockHeight, medianTimePast,
 		mp.cfg.Policy.MinRelayTxFee, mp.cfg.Policy.MaxTxVersion,
+		MaxDustOutputsPerTx,
 	)
 	if err != nil {
 		// Attempt to extract a reject code from the error so it can be
@@ -1755,6 +2555,52 @@ func (mp *TxPool) validateRelayFeeMet(tx *btcutil.Tx, txFee, txSize int64,
 	return nil
 }
 
+// validateFees checks that the transaction pays both the minimum relay fee
+// and, unless it is being added back to the pool from blocks that have been
+// disconnected during a reorg, the rolling minimum fee rate of the pool.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) validateFees(tx *btcutil.Tx, txFee, txSize int64,
+	utxoView *blockchain.UtxoViewpoint, nextBlockHeight int32,
+	isNew, rateLimit bool) error {
+
+	err := mp.validateRelayFeeMet(
+		tx, txFee, txSize, utxoView, nextBlockHeight, isNew, rateLimit,
+	)
+	if err != nil {
+		return err
+	}
+
+	if isNew || rateLimit {
+		return mp.validateMinFeeRateMet(tx, txFee, txSize)
+	}
+
+	return nil
+}
+
+// validateMinFeeRateMet checks that the rolling minimum fee rate of the pool,
+// which is raised when transactions are evicted due to the pool exceeding its
+// maximum size, is covered by this transaction.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) validateMinFeeRateMet(tx *btcutil.Tx, txFee,
+	txSize int64) error {
+
+	minFeeRate := mp.minFeeRate()
+	if minFeeRate == 0 {
+		return nil
+	}
+
+	minFee := calcMinRequiredTxRelayFee(txSize, minFeeRate)
+	if txFee >= minFee {
+		return nil
+	}
+
+	str := fmt.Sprintf("transaction %v has %d fees which is under the "+
+		"mempool minimum fee of %d", tx.Hash(), txFee, minFee)
+	return txRuleError(wire.RejectInsufficientFee, str)
+}
+
 // New returns a new memory pool for validating and storing standalone
 // transactions until they are mined into a block.
 func New(cfg *Config) *TxPool {
@@ -1765,5 +2611,7 @@ func New(cfg *Config) *TxPool {
 		orphansByPrev:  make(map[wire.OutPoint]map[chainhash.Hash]*btcutil.Tx),
 		nextExpireScan: time.Now().Add(orphanExpireScanInterval),
 		outpoints:      make(map[wire.OutPoint]*btcutil.Tx),
+		sequence:       1,
+		feeDeltas:      make(map[chainhash.Hash]int64),
 	}
 }
@@ -14,11 +14,13 @@ import (
 
 	"github.com/bynil/btcd/blockchain"
 	"github.com/bynil/btcd/btcec/v2"
+	"github.com/bynil/btcd/btcjson"
 	"github.com/bynil/btcd/btcutil"
 	"github.com/bynil/btcd/chaincfg"
 	"github.com/bynil/btcd/chaincfg/chainhash"
 	"github.com/bynil/btcd/txscript"
 	"github.com/bynil/btcd/wire"
+	"github.com/davecgh/go-spew/spew"
 )
 
 // fakeChain is used by the pool harness to provide generated test utxos and
@@ -1851,3 +1853,425 @@ func TestRBF(t *testing.T) {
 		}
 	}
 }
+
+// TestTxNotifications ensures the transaction added and removed callbacks are
+// invoked with increasing mempool sequence numbers and that removals due to
+// inclusion in a block are not reported as removals.
+func TestTxNotifications(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+
+	type event struct {
+		added bool
+		hash  chainhash.Hash
+		seq   uint64
+	}
+	var events []event
+	harness.txPool.cfg.OnTxAdded = func(tx *btcutil.Tx, seq uint64) {
+		events = append(events, event{true, *tx.Hash(), seq})
+	}
+	harness.txPool.cfg.OnTxRemoved = func(tx *btcutil.Tx, seq uint64) {
+		events = append(events, event{false, *tx.Hash(), seq})
+	}
+
+	chainedTxns, err := harness.CreateTxChain(spendableOuts[0], 3)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+	for _, tx := range chainedTxns {
+		_, err := harness.txPool.ProcessTransaction(tx, true, false, 0)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: unexpected error: %v", err)
+		}
+	}
+
+	// Remove the first transaction as if it were included in a block and
+	// then evict the remainder of the chain.
+	harness.txPool.RemoveConfirmedTransaction(chainedTxns[0])
+	harness.txPool.RemoveTransaction(chainedTxns[1], true)
+
+	want := []event{
+		{true, *chainedTxns[0].Hash(), 1},
+		{true, *chainedTxns[1].Hash(), 2},
+		{true, *chainedTxns[2].Hash(), 3},
+		{false, *chainedTxns[2].Hash(), 5},
+		{false, *chainedTxns[1].Hash(), 6},
+	}
+	if !reflect.DeepEqual(events, want) {
+		t.Fatalf("got events %v, want %v", events, want)
+	}
+}
+
+// TestTrimToSize ensures the transactions with the lowest descendant score are
+// evicted once the pool exceeds its maximum size and that the rolling minimum
+// fee rate is raised and decays as expected.
+func TestTrimToSize(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	txPool := harness.txPool
+
+	// Add three unrelated transactions with differing fees and limit the
+	// pool to their total size.
+	coinbase := ctx.addCoinbaseTx(4)
+	a := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 0)}, 1, 1000,
+		false, false,
+	)
+	b := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 1)}, 1, 5000,
+		false, false,
+	)
+	c := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 2)}, 1, 3000,
+		false, false,
+	)
+	txPool.cfg.Policy.MaxPoolSize = txPool.TotalSize()
+	if got := txPool.MinFeeRate(); got != txPool.cfg.Policy.MinRelayTxFee {
+		t.Fatalf("got min fee rate %v, want the min relay fee", got)
+	}
+
+	// Spending the lowest fee transaction with a high fee child raises its
+	// descendant score above the others, so the transaction with the next
+	// lowest fee rate must be evicted instead.
+	d := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(a, 0)}, 1, 20000, false,
+		false,
+	)
+	testPoolMembership(ctx, a, false, true)
+	testPoolMembership(ctx, b, false, true)
+	testPoolMembership(ctx, c, false, false)
+	testPoolMembership(ctx, d, false, true)
+	if txPool.TotalSize() > txPool.cfg.Policy.MaxPoolSize {
+		t.Fatalf("pool size %d exceeds the maximum %d",
+			txPool.TotalSize(), txPool.cfg.Policy.MaxPoolSize)
+	}
+
+	// The descendant totals tracked for each transaction must match its
+	// descendants that remain in the pool.
+	txPool.mtx.RLock()
+	if len(txPool.evictionQueue) != len(txPool.pool) {
+		t.Fatalf("eviction queue has %d transactions, want %d",
+			len(txPool.evictionQueue), len(txPool.pool))
+	}
+	for _, txD := range txPool.pool {
+		wantFee, wantSize := txD.Fee, GetTxVirtualSize(txD.Tx)
+		for hash, descendant := range txPool.txDescendants(txD.Tx, nil) {
+			wantFee += txPool.pool[hash].Fee
+			wantSize += GetTxVirtualSize(descendant)
+		}
+		if txD.descendantFee != wantFee ||
+			txD.descendantSize != wantSize {
+
+			t.Fatalf("got descendant fee %d and size %d for %v, "+
+				"want %d and %d", txD.descendantFee,
+				txD.descendantSize, txD.Tx.Hash(), wantFee,
+				wantSize)
+		}
+	}
+	txPool.mtx.RUnlock()
+
+	// The minimum fee rate must now exceed the fee rate of the evicted
+	// transaction by the incremental relay fee.
+	cFeeRate := 3000 * 1000 / GetTxVirtualSize(c)
+	wantFeeRate := btcutil.Amount(cFeeRate) +
+		txPool.cfg.Policy.MinRelayTxFee
+	if got := txPool.MinFeeRate(); got != wantFeeRate {
+		t.Fatalf("got min fee rate %v, want %v", got, wantFeeRate)
+	}
+
+	// A transaction paying less than the minimum fee rate is rejected even
+	// though it covers the minimum relay fee.
+	e, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 3)}, 1, 2000,
+		false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	_, err = txPool.ProcessTransaction(e, true, false, 0)
+	if err == nil {
+		t.Fatal("expected transaction below the min fee rate to be " +
+			"rejected")
+	}
+	code, _ := extractRejectCode(err)
+	if code != wire.RejectInsufficientFee {
+		t.Fatalf("got reject code %v, want %v", code,
+			wire.RejectInsufficientFee)
+	}
+
+	// The rate must not decay until a block has been connected.
+	now := time.Now().Unix()
+	halfLife := int64(rollingFeeHalfLife.Seconds())
+	txPool.mtx.Lock()
+	defer txPool.mtx.Unlock()
+	if got := txPool.decayedMinFeeRate(now + halfLife); got !=
+		float64(wantFeeRate) {
+
+		t.Fatalf("got decayed fee rate %v before a block, want %v",
+			got, wantFeeRate)
+	}
+
+	// Once a block has been connected, the rate halves every half-life
+	// while the pool is at least half full and drops to zero once it is
+	// below half the incremental relay fee.
+	txPool.blockConnected(now)
+	got := txPool.decayedMinFeeRate(now + halfLife)
+	if got != float64(wantFeeRate)/2 {
+		t.Fatalf("got decayed fee rate %v, want %v", got,
+			float64(wantFeeRate)/2)
+	}
+	if got := txPool.decayedMinFeeRate(now + 6*halfLife); got != 0 {
+		t.Fatalf("got decayed fee rate %v, want 0", got)
+	}
+
+	// Connecting another block must not undo the decay so far.
+	txPool.blockConnected(now + halfLife)
+	got = txPool.decayedMinFeeRate(now + 2*halfLife)
+	if got != float64(wantFeeRate)/4 {
+		t.Fatalf("got decayed fee rate %v after another block, want %v",
+			got, float64(wantFeeRate)/4)
+	}
+
+	// The rate decays four times as fast when the pool is below a quarter
+	// of its maximum size.
+	txPool.cfg.Policy.MaxPoolSize = (txPool.totalSize + 1) * 4
+	got = txPool.decayedMinFeeRate(now + halfLife + halfLife/4)
+	if got != float64(wantFeeRate)/4 {
+		t.Fatalf("got decayed fee rate %v, want %v", got,
+			float64(wantFeeRate)/4)
+	}
+}
+
+// TestMempoolEntry ensures the entries returned for the transactions in the
+// pool report their ancestor and descendant totals and relatives.
+func TestMempoolEntry(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	txPool := harness.txPool
+
+	// Create a chain of three transactions where the first one signals
+	// replacement.
+	coinbase := ctx.addCoinbaseTx(1)
+	a := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 0)}, 1, 1000,
+		true, false,
+	)
+	b := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(a, 0)}, 1, 2000, false,
+		false,
+	)
+	c := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(b, 0)}, 1, 3000, false,
+		false,
+	)
+
+	entry, err := txPool.MempoolEntry(b.Hash())
+	if err != nil {
+		t.Fatalf("MempoolEntry: unexpected error: %v", err)
+	}
+	aSize, bSize := GetTxVirtualSize(a), GetTxVirtualSize(b)
+	cSize := GetTxVirtualSize(c)
+	want := &btcjson.GetMempoolEntryResult{
+		VSize:             int32(bSize),
+		Size:              int32(b.MsgTx().SerializeSize()),
+		Weight:            blockchain.GetTransactionWeight(b),
+		Fee:               0.00002,
+		ModifiedFee:       0.00002,
+		Time:              entry.Time,
+		Height:            entry.Height,
+		DescendantCount:   2,
+		DescendantSize:    bSize + cSize,
+		DescendantFees:    5000,
+		AncestorCount:     2,
+		AncestorSize:      aSize + bSize,
+		AncestorFees:      3000,
+		WTxId:             b.WitnessHash().String(),
+		BIP125Replaceable: true,
+		Fees: btcjson.MempoolFees{
+			Base:       0.00002,
+			Modified:   0.00002,
+			Ancestor:   0.00003,
+			Descendant: 0.00005,
+		},
+		Depends: []string{a.Hash().String()},
+		SpentBy: []string{c.Hash().String()},
+	}
+	if !reflect.DeepEqual(entry, want) {
+		t.Fatalf("got entry %v, want %v", spew.Sdump(entry),
+			spew.Sdump(want))
+	}
+
+	// The ancestors of the last transaction and the descendants of the
+	// first one are the other two transactions.
+	ancestors, err := txPool.MempoolAncestors(c.Hash())
+	if err != nil {
+		t.Fatalf("MempoolAncestors: unexpected error: %v", err)
+	}
+	if len(ancestors) != 2 || ancestors[a.Hash().String()] == nil ||
+		ancestors[b.Hash().String()] == nil {
+
+		t.Fatalf("got unexpected ancestors %v", ancestors)
+	}
+	descendants, err := txPool.MempoolDescendants(a.Hash())
+	if err != nil {
+		t.Fatalf("MempoolDescendants: unexpected error: %v", err)
+	}
+	if len(descendants) != 2 ||
+		descendants[b.Hash().String()] == nil ||
+		descendants[c.Hash().String()] == nil {
+
+		t.Fatalf("got unexpected descendants %v", descendants)
+	}
+
+	// Transactions which aren't in the pool are reported.
+	if _, err := txPool.MempoolEntry(coinbase.Hash()); err == nil {
+		t.Fatal("MempoolEntry: expected an error for a transaction " +
+			"not in the pool")
+	}
+}
+
+// TestPrioritiseTransaction ensures the fee deltas transactions are
+// prioritized with apply to the fee checks, the relative totals and the
+// eviction order of the pool, including the ones recorded before the
+// transaction was added to the pool, and that they are cleared once the
+// transaction is confirmed.
+func TestPrioritiseTransaction(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	txPool := harness.txPool
+	txPool.cfg.Policy.FreeTxRelayLimit = 0
+
+	// A transaction without fees is rejected since free transactions
+	// aren't relayed.
+	coinbase := ctx.addCoinbaseTx(3)
+	a, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 0)}, 1, 0, false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	_, err = txPool.ProcessTransaction(a, false, true, 0)
+	if err == nil {
+		t.Fatal("expected transaction without fees to be rejected")
+	}
+	code, _ := extractRejectCode(err)
+	if code != wire.RejectInsufficientFee {
+		t.Fatalf("got reject code %v, want %v", code,
+			wire.RejectInsufficientFee)
+	}
+
+	// Prioritizing the transaction before it is in the pool records the
+	// fee delta, which lets the transaction in once it is applied.
+	txPool.PrioritiseTransaction(a.Hash(), 600)
+	txPool.PrioritiseTransaction(a.Hash(), 400)
+	prioritised := txPool.PrioritisedTransactions()
+	want := &btcjson.PrioritisedTransactionResult{FeeDelta: 1000}
+	if got := prioritised[a.Hash().String()]; len(prioritised) != 1 ||
+		!reflect.DeepEqual(got, want) {
+
+		t.Fatalf("got prioritized transactions %v, want %v for %v",
+			spew.Sdump(prioritised), spew.Sdump(want), a.Hash())
+	}
+	_, err = txPool.ProcessTransaction(a, false, true, 0)
+	if err != nil {
+		t.Fatalf("ProcessTransaction: unexpected error: %v", err)
+	}
+	testPoolMembership(ctx, a, false, true)
+
+	// Prioritizing a child of the transaction updates the relative totals
+	// of both.
+	b := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(a, 0)}, 1, 1000, false,
+		false,
+	)
+	txPool.PrioritiseTransaction(b.Hash(), 5000)
+	checkRelativeTotals(t, txPool)
+	modifiedFee := int64(6000)
+	want = &btcjson.PrioritisedTransactionResult{
+		FeeDelta:    5000,
+		InMempool:   true,
+		ModifiedFee: &modifiedFee,
+	}
+	prioritised = txPool.PrioritisedTransactions()
+	if got := prioritised[b.Hash().String()]; len(prioritised) != 2 ||
+		!reflect.DeepEqual(got, want) {
+
+		t.Fatalf("got prioritized transactions %v, want %v for %v",
+			spew.Sdump(prioritised), spew.Sdump(want), b.Hash())
+	}
+
+	// The fee deltas are handed to the mining code along with the
+	// transactions.
+	for _, desc := range txPool.MiningDescs() {
+		wantDelta := int64(1000)
+		if *desc.Tx.Hash() == *b.Hash() {
+			wantDelta = 5000
+		}
+		if desc.FeeDelta != wantDelta {
+			t.Fatalf("got fee delta %d for %v, want %d",
+				desc.FeeDelta, desc.Tx.Hash(), wantDelta)
+		}
+	}
+
+	// An unrelated transaction paying more than the package of the two
+	// transactions is evicted first once the child is prioritized, but not
+	// once the prioritization is undone.
+	c := ctx.addSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 1)}, 1, 3000,
+		false, false,
+	)
+	evictedFirst := func() *chainhash.Hash {
+		txPool.mtx.RLock()
+		defer txPool.mtx.RUnlock()
+		return txPool.evictionQueue[0].Tx.Hash()
+	}
+	if got := evictedFirst(); *got != *c.Hash() {
+		t.Fatalf("got %v evicted first, want %v", got, c.Hash())
+	}
+	txPool.PrioritiseTransaction(b.Hash(), -5000)
+	checkRelativeTotals(t, txPool)
+	if got := evictedFirst(); *got == *c.Hash() {
+		t.Fatalf("got %v evicted first after undoing the "+
+			"prioritization", got)
+	}
+	if _, ok := txPool.PrioritisedTransactions()[b.Hash().String()]; ok {
+		t.Fatal("expected fee delta dropping to zero to be cleared")
+	}
+
+	// The fee deltas are cleared once the transactions are confirmed,
+	// whether they are in the pool or not.
+	d, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(coinbase, 2)}, 1, 1000,
+		false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	txPool.PrioritiseTransaction(d.Hash(), 1000)
+	txPool.RemoveConfirmedTransaction(a)
+	txPool.RemoveConfirmedTransaction(d)
+	checkRelativeTotals(t, txPool)
+	if prioritised := txPool.PrioritisedTransactions(); len(prioritised) != 0 {
+		t.Fatalf("got prioritized transactions %v after confirming "+
+			"them", spew.Sdump(prioritised))
+	}
+}
@@ -40,6 +40,67 @@ func (m *MockTxMempool) RawMempoolVerbose() map[string]*btcjson.
 	return args.Get(0).(map[string]*btcjson.GetRawMempoolVerboseResult)
 }
 
+// MempoolEntry returns the transaction with the passed hash in the main pool
+// as a fully populated btcjson result.
+func (m *MockTxMempool) MempoolEntry(
+	txHash *chainhash.Hash) (*btcjson.GetMempoolEntryResult, error) {
+
+	args := m.Called(txHash)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*btcjson.GetMempoolEntryResult), args.Error(1)
+}
+
+// MempoolAncestors returns the unconfirmed ancestors of the transaction with
+// the passed hash in the main pool as fully populated btcjson results.
+func (m *MockTxMempool) MempoolAncestors(txHash *chainhash.Hash) (
+	map[string]*btcjson.GetMempoolEntryResult, error) {
+
+	args := m.Called(txHash)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(map[string]*btcjson.GetMempoolEntryResult),
+		args.Error(1)
+}
+
+// MempoolDescendants returns the unconfirmed descendants of the transaction
+// with the passed hash in the main pool as fully populated btcjson results.
+func (m *MockTxMempool) MempoolDescendants(txHash *chainhash.Hash) (
+	map[string]*btcjson.GetMempoolEntryResult, error) {
+
+	args := m.Called(txHash)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(map[string]*btcjson.GetMempoolEntryResult),
+		args.Error(1)
+}
+
+// PrioritiseTransaction adds the passed fee delta to the fee delta of the
+// transaction with the passed hash.
+func (m *MockTxMempool) PrioritiseTransaction(txHash *chainhash.Hash,
+	feeDelta int64) {
+
+	m.Called(txHash, feeDelta)
+}
+
+// PrioritisedTransactions returns the fee deltas recorded by
+// PrioritiseTransaction as fully populated btcjson results.
+func (m *MockTxMempool) PrioritisedTransactions() map[string]*btcjson.
+	PrioritisedTransactionResult {
+
+	args := m.Called()
+	return args.Get(0).(map[string]*btcjson.PrioritisedTransactionResult)
+}
+
 // Count returns the number of transactions in the main pool. It does not
 // include the orphan pool.
 func (m *MockTxMempool) Count() int {
@@ -111,6 +172,36 @@ func (m *MockTxMempool) CheckMempoolAcceptance(
 	return args.Get(0).(*MempoolAcceptResult), args.Error(1)
 }
 
+// CheckPackageAcceptance behaves similarly to bitcoind's `testmempoolaccept`
+// RPC method for more than one transaction. It checks whether the passed
+// package of transactions would be accepted to the mempool without adding any
+// of them.
+func (m *MockTxMempool) CheckPackageAcceptance(
+	txns []*btcutil.Tx) (*PackageAcceptResult, error) {
+
+	args := m.Called(txns)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*PackageAcceptResult), args.Error(1)
+}
+
+// ProcessPackage validates the passed package and adds its transactions to the
+// mempool.
+func (m *MockTxMempool) ProcessPackage(txns []*btcutil.Tx,
+	maxFeeRate btcutil.Amount) (*PackageAcceptResult, error) {
+
+	args := m.Called(txns, maxFeeRate)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*PackageAcceptResult), args.Error(1)
+}
+
 // CheckSpend checks whether the passed outpoint is already spent by a
 // transaction in the mempool. If that's the case the spending transaction will
 // be returned, if not nil will be returned.
@@ -123,3 +214,45 @@ func (m *MockTxMempool) CheckSpend(op wire.OutPoint) *btcutil.Tx {
 
 	return args.Get(0).(*btcutil.Tx)
 }
+
+// MinFeeRate returns the minimum fee rate in satoshi/kB a transaction must pay
+// to be accepted into the mempool.
+func (m *MockTxMempool) MinFeeRate() btcutil.Amount {
+	args := m.Called()
+	return args.Get(0).(btcutil.Amount)
+}
+
+// TotalSize returns the total virtual size in bytes of the transactions in the
+// main pool.
+func (m *MockTxMempool) TotalSize() int64 {
+	args := m.Called()
+	return args.Get(0).(int64)
+}
+
+// DumpToFile writes the transactions in the main pool to the file at the
+// passed path.
+func (m *MockTxMempool) DumpToFile(path string) error {
+	args := m.Called(path)
+	return args.Error(0)
+}
+
+// LoadFromFile loads the transactions from the mempool dump file at the passed
+// path.
+func (m *MockTxMempool) LoadFromFile(path string, useCurrentTime bool,
+	interrupt <-chan struct{}) (*LoadStats, error) {
+
+	args := m.Called(path, useCurrentTime, interrupt)
+
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+
+	return args.Get(0).(*LoadStats), args.Error(1)
+}
+
+// LoadTried returns whether an attempt to load the pool from a dump has
+// finished.
+func (m *MockTxMempool) LoadTried() bool {
+	args := m.Called()
+	return args.Bool(0)
+}
@@ -0,0 +1,579 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/bynil/btcd/blockchain"
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	"github.com/bynil/btcd/mining"
+	"github.com/bynil/btcd/wire"
+)
+
+const (
+	// MaxPackageCount is the maximum number of transactions in a package.
+	MaxPackageCount = 25
+
+	// MaxPackageWeight is the maximum total weight of the transactions in
+	// a package.  It allows a package to contain a transaction of the
+	// maximum standard weight along with a few small transactions.
+	MaxPackageWeight = 404000
+)
+
+// packageContext holds the state needed to validate a transaction as part of
+// a package.
+type packageContext struct {
+	// txns are the transactions of the package that are not in the pool
+	// and were validated so far.  Their outputs may be spent by the
+	// transaction being validated.
+	txns map[chainhash.Hash]*btcutil.Tx
+
+	// skipFeeChecks leaves the fee checks to the caller so the fee rate
+	// of the package can be checked instead of the fee rate of each
+	// transaction.
+	skipFeeChecks bool
+}
+
+// fetchInputUtxos adds the outputs of the transactions in the package that are
+// spent by the passed transaction and not found by the pool to the passed
+// view.
+func (pkg *packageContext) fetchInputUtxos(tx *btcutil.Tx,
+	utxoView *blockchain.UtxoViewpoint) {
+
+	for _, txIn := range tx.MsgTx().TxIn {
+		prevOut := &txIn.PreviousOutPoint
+		entry := utxoView.LookupEntry(*prevOut)
+		if entry != nil && !entry.IsSpent() {
+			continue
+		}
+
+		if pkgTx, exists := pkg.txns[prevOut.Hash]; exists {
+			// AddTxOut ignores out of range index values, so it is
+			// safe to call without bounds checking here.
+			utxoView.AddTxOut(pkgTx, prevOut.Index,
+				mining.UnminedHeight)
+		}
+	}
+}
+
+// PackageTxResult describes the outcome of validating a transaction as part of
+// a package.
+type PackageTxResult struct {
+	// Tx is the validated transaction.
+	Tx *btcutil.Tx
+
+	// Err is the reason the transaction was rejected.  It is nil when the
+	// transaction is accepted or already in the pool.
+	Err error
+
+	// MissingParents holds the hashes of the unknown transactions whose
+	// outputs the transaction spends when it was rejected for that
+	// reason.
+	MissingParents []*chainhash.Hash
+
+	// AlreadyInPool indicates the transaction was already in the pool, in
+	// which case it was not validated again.
+	AlreadyInPool bool
+
+	// TxFee is the fee paid by the transaction in satoshi.
+	TxFee btcutil.Amount
+
+	// TxSize is the virtual size of the transaction.
+	TxSize int64
+
+	// EffectiveFeeRate is the fee rate in satoshi/kB the transaction was
+	// evaluated at.  It is the fee rate of the transactions whose
+	// witness hashes are listed in EffectiveIncludes, which are more than
+	// the transaction itself when it was only accepted together with
+	// other transactions of the package.
+	EffectiveFeeRate  btcutil.Amount
+	EffectiveIncludes []*chainhash.Hash
+}
+
+// PackageAcceptResult describes the outcome of validating a package of
+// transactions.
+type PackageAcceptResult struct {
+	// TxResults holds the result of each transaction of the package in
+	// the order of the package.  Validation stops once a transaction is
+	// rejected, so the results of the transactions that were not
+	// validated are nil.
+	TxResults []*PackageTxResult
+
+	// Accepted holds the transactions added to the pool, including
+	// orphans that were added since they no longer miss any parents.
+	Accepted []*TxDesc
+
+	// Replaced holds the transactions removed from the pool since they
+	// conflicted with accepted transactions of the package.
+	Replaced []*btcutil.Tx
+}
+
+// Failed returns whether any transaction of the package was rejected or not
+// validated.
+func (r *PackageAcceptResult) Failed() bool {
+	for _, txResult := range r.TxResults {
+		if txResult == nil || txResult.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// newPackageTxResult returns the result of a transaction of a package that was
+// evaluated at its own fee rate.
+func newPackageTxResult(tx *btcutil.Tx, txFee btcutil.Amount,
+	txSize int64) *PackageTxResult {
+
+	return &PackageTxResult{
+		Tx:                tx,
+		TxFee:             txFee,
+		TxSize:            txSize,
+		EffectiveFeeRate:  txFee * 1000 / btcutil.Amount(txSize),
+		EffectiveIncludes: []*chainhash.Hash{tx.WitnessHash()},
+	}
+}
+
+// rejectedPackageTxResult returns the result of a transaction of a package
+// that was rejected either with the passed error or since it is missing the
+// passed parents.
+func rejectedPackageTxResult(tx *btcutil.Tx, err error,
+	missingParents []*chainhash.Hash) *PackageTxResult {
+
+	if err == nil {
+		str := fmt.Sprintf("transaction %v references outputs of "+
+			"unknown or fully-spent transaction %v", tx.Hash(),
+			missingParents[0])
+		err = txRuleError(wire.RejectDuplicate, str)
+	}
+
+	return &PackageTxResult{
+		Tx:             tx,
+		Err:            err,
+		MissingParents: missingParents,
+	}
+}
+
+// checkPackage ensures the passed transactions form a well formed package.
+// That is, the package is within the count and weight limits, contains no
+// duplicate transactions, is sorted topologically so every transaction only
+// spends the outputs of the transactions before it, and none of its
+// transactions spend the same output.
+func checkPackage(txns []*btcutil.Tx) error {
+	if len(txns) > MaxPackageCount {
+		str := fmt.Sprintf("package contains %d transactions which "+
+			"exceeds the maximum of %d", len(txns), MaxPackageCount)
+		return packageRuleError("package-too-many-transactions", str)
+	}
+
+	var weight int64
+	indexes := make(map[chainhash.Hash]int, len(txns))
+	for i, tx := range txns {
+		if _, exists := indexes[*tx.Hash()]; exists {
+			str := fmt.Sprintf("package contains transaction %v "+
+				"more than once", tx.Hash())
+			return packageRuleError("package-contains-duplicates",
+				str)
+		}
+		indexes[*tx.Hash()] = i
+		weight += blockchain.GetTransactionWeight(tx)
+	}
+	if weight > MaxPackageWeight {
+		str := fmt.Sprintf("package weight %d exceeds the maximum of "+
+			"%d", weight, MaxPackageWeight)
+		return packageRuleError("package-too-large", str)
+	}
+
+	spent := make(map[wire.OutPoint]struct{})
+	for i, tx := range txns {
+		for _, txIn := range tx.MsgTx().TxIn {
+			prevOut := txIn.PreviousOutPoint
+			if j, exists := indexes[prevOut.Hash]; exists && j > i {
+				str := fmt.Sprintf("package transaction %v "+
+					"spends transaction %v which comes "+
+					"after it", tx.Hash(), prevOut.Hash)
+				return packageRuleError("package-not-sorted",
+					str)
+			}
+			if _, exists := spent[prevOut]; exists {
+				str := fmt.Sprintf("package transactions spend "+
+					"output %v more than once", prevOut)
+				return packageRuleError("conflict-in-package",
+					str)
+			}
+			spent[prevOut] = struct{}{}
+		}
+	}
+
+	return nil
+}
+
+// checkChildWithParents ensures the passed package, which must be well formed
+// as ensured by checkPackage, consists of a child transaction, which comes
+// last, along with its parents, which don't spend each other.
+func checkChildWithParents(txns []*btcutil.Tx) error {
+	if len(txns) < 2 {
+		return nil
+	}
+
+	child := txns[len(txns)-1]
+	childParents := make(map[chainhash.Hash]struct{})
+	for _, txIn := range child.MsgTx().TxIn {
+		childParents[txIn.PreviousOutPoint.Hash] = struct{}{}
+	}
+
+	parents := make(map[chainhash.Hash]struct{}, len(txns)-1)
+	for _, parent := range txns[:len(txns)-1] {
+		parents[*parent.Hash()] = struct{}{}
+	}
+	for _, parent := range txns[:len(txns)-1] {
+		if _, exists := childParents[*parent.Hash()]; !exists {
+			str := fmt.Sprintf("package transaction %v is not a "+
+				"parent of the last transaction %v",
+				parent.Hash(), child.Hash())
+			return packageRuleError("package-not-child-with-parents",
+				str)
+		}
+
+		for _, txIn := range parent.MsgTx().TxIn {
+			prevHash := txIn.PreviousOutPoint.Hash
+			if _, exists := parents[prevHash]; exists {
+				str := fmt.Sprintf("package parent %v spends "+
+					"parent %v", parent.Hash(), prevHash)
+				return packageRuleError(
+					"package-not-child-with-parents", str)
+			}
+		}
+	}
+
+	return nil
+}
+
+// validateMaxFeeRate checks that the fee rate of the transaction does not
+// exceed the passed maximum fee rate in satoshi/kB.  A maximum of zero
+// disables the check.
+func validateMaxFeeRate(tx *btcutil.Tx, txFee btcutil.Amount, txSize int64,
+	maxFeeRate btcutil.Amount) error {
+
+	feeRate := txFee * 1000 / btcutil.Amount(txSize)
+	if maxFeeRate == 0 || feeRate <= maxFeeRate {
+		return nil
+	}
+
+	str := fmt.Sprintf("transaction %v has a fee rate of %d sat/kB which "+
+		"exceeds the maximum of %d", tx.Hash(), feeRate, maxFeeRate)
+	return txRuleError(wire.RejectNonstandard, str)
+}
+
+// validatePackageFees checks that the passed total fee and virtual size of the
+// transactions of a package cover both the minimum relay fee and the rolling
+// minimum fee rate of the pool.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) validatePackageFees(pkgFee btcutil.Amount,
+	pkgSize int64) error {
+
+	minFeeRate := mp.minFeeRate()
+	if minFeeRate < mp.cfg.Policy.MinRelayTxFee {
+		minFeeRate = mp.cfg.Policy.MinRelayTxFee
+	}
+
+	minFee := calcMinRequiredTxRelayFee(pkgSize, minFeeRate)
+	if int64(pkgFee) >= minFee {
+		return nil
+	}
+
+	str := fmt.Sprintf("package has %d fees which is under the required "+
+		"amount of %d", pkgFee, minFee)
+	return txRuleError(wire.RejectInsufficientFee, str)
+}
+
+// CheckPackageAcceptance behaves similarly to bitcoind's `testmempoolaccept`
+// RPC method for more than one transaction.  It checks whether the passed
+// package of transactions, which must be sorted topologically, would be
+// accepted to the mempool without adding any of them.  Unlike ProcessPackage,
+// every transaction must pay for itself and the package does not need to
+// consist of a child with its parents.
+//
+// An error is returned when the package as a whole violates the package
+// policy.  Otherwise, the outcome of each transaction is reported by the
+// returned result.  Validation stops at the first rejected transaction.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) CheckPackageAcceptance(
+	txns []*btcutil.Tx) (*PackageAcceptResult, error) {
+
+	if err := checkPackage(txns); err != nil {
+		return nil, err
+	}
+
+	// The lock is held for writes since the rate limiting state of free
+	// transactions is updated by the fee checks.
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	result := &PackageAcceptResult{
+		TxResults: make([]*PackageTxResult, len(txns)),
+	}
+	pkg := &packageContext{txns: make(map[chainhash.Hash]*btcutil.Tx)}
+	for i, tx := range txns {
+		r, err := mp.checkMempoolAcceptance(tx, true, true, true, pkg)
+		if err != nil || len(r.MissingParents) > 0 {
+			var missingParents []*chainhash.Hash
+			if err == nil {
+				missingParents = r.MissingParents
+			}
+			result.TxResults[i] = rejectedPackageTxResult(
+				tx, err, missingParents,
+			)
+			break
+		}
+
+		result.TxResults[i] = newPackageTxResult(tx, r.TxFee, r.TxSize)
+		pkg.txns[*tx.Hash()] = tx
+	}
+
+	return result, nil
+}
+
+// ProcessPackage validates the passed package of transactions and adds them to
+// the mempool.  The package must consist of a child transaction, which comes
+// last, along with its unconfirmed parents, which don't spend each other.
+//
+// Each transaction is first validated on its own so parents which pay a
+// sufficient fee are accepted regardless of the rest of the package.  The
+// transactions that are only rejected due to their fee, along with the child
+// when it spends any of them, are then validated together and accepted when
+// their combined fee rate is sufficient.  This allows a child to pay for
+// parents which don't pay the minimum fee rate on their own (CPFP).
+// Transactions which are only accepted as part of the package can't replace
+// transactions in the pool.
+//
+// Transactions whose own fee rate exceeds the passed maximum fee rate in
+// satoshi/kB are rejected.  A maximum of zero disables the check.
+//
+// An error is returned when the package as a whole violates the package
+// policy.  Otherwise, the outcome of each transaction is reported by the
+// returned result.  Validation stops at the first rejected transaction, while
+// the transactions accepted up to that point remain in the pool.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) ProcessPackage(txns []*btcutil.Tx,
+	maxFeeRate btcutil.Amount) (*PackageAcceptResult, error) {
+
+	if err := checkPackage(txns); err != nil {
+		return nil, err
+	}
+	if err := checkChildWithParents(txns); err != nil {
+		return nil, err
+	}
+
+	mp.mtx.Lock()
+	defer mp.mtx.Unlock()
+
+	result := &PackageAcceptResult{
+		TxResults: make([]*PackageTxResult, len(txns)),
+	}
+
+	// Attempt to accept each transaction on its own first.  Transactions
+	// only failing the fee checks and the transactions spending them are
+	// collected to be reconsidered as a package below.
+	var deferred []int
+	deferredTxns := make(map[chainhash.Hash]struct{})
+	for i, tx := range txns {
+		if txD, exists := mp.pool[*tx.Hash()]; exists {
+			txResult := newPackageTxResult(
+				tx, btcutil.Amount(txD.Fee), txD.vsize,
+			)
+			txResult.AlreadyInPool = true
+			result.TxResults[i] = txResult
+			continue
+		}
+
+		spendsDeferred := false
+		for _, txIn := range tx.MsgTx().TxIn {
+			prevHash := txIn.PreviousOutPoint.Hash
+			if _, exists := deferredTxns[prevHash]; exists {
+				spendsDeferred = true
+				break
+			}
+		}
+		if spendsDeferred {
+			deferred = append(deferred, i)
+			deferredTxns[*tx.Hash()] = struct{}{}
+			continue
+		}
+
+		r, err := mp.checkMempoolAcceptance(
+			tx, true, false, true, &packageContext{skipFeeChecks: true},
+		)
+		if err == nil && len(r.MissingParents) > 0 {
+			result.TxResults[i] = rejectedPackageTxResult(
+				tx, nil, r.MissingParents,
+			)
+			return result, nil
+		}
+		if err == nil {
+			err = validateMaxFeeRate(tx, r.TxFee, r.TxSize, maxFeeRate)
+		}
+		if err != nil {
+			result.TxResults[i] = rejectedPackageTxResult(tx, err, nil)
+			return result, nil
+		}
+
+		// Transactions with ephemeral dust are only accepted along
+		// with a child spending the dust, so they're always
+		// reconsidered as part of the package.
+		err = mp.validateFees(
+			tx, mp.modifiedFee(tx.Hash(), int64(r.TxFee)), r.TxSize,
+			r.utxoView, r.bestHeight+1, true, false,
+		)
+		if err == nil && mp.hasEphemeralDust(tx) {
+			err = txRuleError(wire.RejectDust, "transaction has "+
+				"ephemeral dust")
+		}
+		if err != nil {
+			log.Debugf("Reconsidering transaction %v as part of its "+
+				"package: %v", tx.Hash(), err)
+			deferred = append(deferred, i)
+			deferredTxns[*tx.Hash()] = struct{}{}
+			continue
+		}
+
+		txD, err := mp.acceptTransaction(tx, r)
+		if err != nil {
+			result.TxResults[i] = rejectedPackageTxResult(tx, err, nil)
+			return result, nil
+		}
+		result.TxResults[i] = newPackageTxResult(tx, r.TxFee, r.TxSize)
+		result.Accepted = append(result.Accepted, txD)
+		for _, conflict := range r.Conflicts {
+			result.Replaced = append(result.Replaced, conflict)
+		}
+	}
+
+	if len(deferred) > 0 {
+		mp.acceptPackageTxns(txns, deferred, maxFeeRate, result)
+	}
+
+	// Accept any orphan transactions that depend on the accepted
+	// transactions since they may no longer be orphans.
+	accepted := result.Accepted
+	for _, txD := range accepted {
+		result.Accepted = append(result.Accepted,
+			mp.processOrphans(txD.Tx)...)
+	}
+
+	return result, nil
+}
+
+// acceptPackageTxns validates the transactions of the passed package at the
+// passed indexes together and adds them to the pool when their combined fee
+// rate is sufficient.  The outcome is recorded in the passed result.
+//
+// This function MUST be called with the mempool lock held (for writes).
+func (mp *TxPool) acceptPackageTxns(txns []*btcutil.Tx, indexes []int,
+	maxFeeRate btcutil.Amount, result *PackageAcceptResult) {
+
+	var (
+		pkg = &packageContext{
+			txns:          make(map[chainhash.Hash]*btcutil.Tx),
+			skipFeeChecks: true,
+		}
+		checked  = make([]*MempoolAcceptResult, 0, len(indexes))
+		includes = make([]*chainhash.Hash, 0, len(indexes))
+		pkgFee   btcutil.Amount
+		pkgSize  int64
+	)
+	for _, i := range indexes {
+		tx := txns[i]
+		r, err := mp.checkMempoolAcceptance(tx, true, false, true, pkg)
+		if err == nil && len(r.MissingParents) > 0 {
+			result.TxResults[i] = rejectedPackageTxResult(
+				tx, nil, r.MissingParents,
+			)
+			return
+		}
+		if err == nil && len(r.Conflicts) > 0 {
+			str := fmt.Sprintf("transaction %v can't replace "+
+				"transactions in the mempool since it is only "+
+				"accepted as part of a package", tx.Hash())
+			err = txRuleError(wire.RejectInsufficientFee, str)
+		}
+		if err == nil {
+			err = validateMaxFeeRate(tx, r.TxFee, r.TxSize, maxFeeRate)
+		}
+		if err != nil {
+			result.TxResults[i] = rejectedPackageTxResult(tx, err, nil)
+			return
+		}
+
+		pkg.txns[*tx.Hash()] = tx
+		checked = append(checked, r)
+		includes = append(includes, tx.WitnessHash())
+		pkgFee += btcutil.Amount(
+			mp.modifiedFee(tx.Hash(), int64(r.TxFee)),
+		)
+		pkgSize += r.TxSize
+	}
+
+	// rejectAll marks all of the transactions as rejected with the passed
+	// error.
+	rejectAll := func(err error) {
+		for _, i := range indexes {
+			result.TxResults[i] = rejectedPackageTxResult(
+				txns[i], err, nil,
+			)
+		}
+	}
+
+	if err := mp.validatePackageFees(pkgFee, pkgSize); err != nil {
+		rejectAll(err)
+		return
+	}
+
+	// Add all of the transactions before trimming the pool so the parents
+	// are evicted based on the fee rate of the package rather than their
+	// own.
+	txDescs := make([]*TxDesc, 0, len(indexes))
+	for j, i := range indexes {
+		r := checked[j]
+		txD := mp.addTransaction(
+			r.utxoView, txns[i], r.bestHeight, int64(r.TxFee),
+		)
+		txDescs = append(txDescs, txD)
+	}
+	mp.trimToSize()
+	for _, i := range indexes {
+		if mp.isTransactionInPool(txns[i].Hash()) {
+			continue
+		}
+
+		// Remove what is left of the package since it is all or
+		// nothing.
+		for _, i := range indexes {
+			mp.removeTransaction(txns[i], true, false)
+		}
+		rejectAll(txRuleError(wire.RejectInsufficientFee,
+			"package was evicted since the mempool is full"))
+		return
+	}
+
+	feeRate := pkgFee * 1000 / btcutil.Amount(pkgSize)
+	for j, i := range indexes {
+		log.Debugf("Accepted transaction %v as part of a package "+
+			"(fee_rate=%v sat/kb)", txns[i].Hash(), feeRate)
+
+		result.TxResults[i] = &PackageTxResult{
+			Tx:                txns[i],
+			TxFee:             checked[j].TxFee,
+			TxSize:            checked[j].TxSize,
+			EffectiveFeeRate:  feeRate,
+			EffectiveIncludes: includes,
+		}
+	}
+	result.Accepted = append(result.Accepted, txDescs...)
+}
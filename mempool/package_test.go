@@ -0,0 +1,342 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"testing"
+
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg"
+)
+
+// newPackageTestContext returns a test context whose pool rejects free
+// transactions along with a confirmed transaction with the passed number of
+// spendable outputs.
+func newPackageTestContext(t *testing.T,
+	numOutputs uint32) (*testContext, *btcutil.Tx) {
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	harness.txPool.cfg.Policy.FreeTxRelayLimit = 0
+	ctx := &testContext{t, harness}
+
+	coinbase := ctx.addCoinbaseTx(1)
+	coinbaseOut := txOutToSpendableOut(coinbase, 0)
+	fundingTx := ctx.addSignedTx(
+		[]spendableOutput{coinbaseOut}, numOutputs, 0, false, true,
+	)
+
+	return ctx, fundingTx
+}
+
+// TestProcessPackageCPFP ensures a parent that does not pay the minimum relay
+// fee is accepted along with a child that pays for it, and that both are
+// rejected when the child does not pay enough for the package.
+func TestProcessPackageCPFP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		childFee  btcutil.Amount
+		wantAdded bool
+	}{
+		{
+			name:      "child pays for parent",
+			childFee:  10000,
+			wantAdded: true,
+		},
+		{
+			name:      "child does not pay for parent",
+			childFee:  250,
+			wantAdded: false,
+		},
+	}
+
+	for _, test := range tests {
+		ctx, fundingTx := newPackageTestContext(t, 1)
+		txPool := ctx.harness.txPool
+
+		// The parent is rejected on its own since it does not pay any
+		// fee.
+		parent, err := ctx.harness.CreateSignedTx(
+			[]spendableOutput{txOutToSpendableOut(fundingTx, 0)},
+			1, 0, false,
+		)
+		if err != nil {
+			t.Fatalf("%s: unable to create parent: %v", test.name,
+				err)
+		}
+		_, err = txPool.ProcessTransaction(parent, false, false, 0)
+		if err == nil {
+			t.Fatalf("%s: parent accepted without a fee", test.name)
+		}
+
+		child, err := ctx.harness.CreateSignedTx(
+			[]spendableOutput{txOutToSpendableOut(parent, 0)},
+			1, test.childFee, false,
+		)
+		if err != nil {
+			t.Fatalf("%s: unable to create child: %v", test.name,
+				err)
+		}
+
+		result, err := txPool.ProcessPackage(
+			[]*btcutil.Tx{parent, child}, 0,
+		)
+		if err != nil {
+			t.Fatalf("%s: ProcessPackage: unexpected error: %v",
+				test.name, err)
+		}
+		if result.Failed() == test.wantAdded {
+			t.Fatalf("%s: got failed %v, want %v", test.name,
+				result.Failed(), !test.wantAdded)
+		}
+		testPoolMembership(ctx, parent, false, test.wantAdded)
+		testPoolMembership(ctx, child, false, test.wantAdded)
+
+		if !test.wantAdded {
+			if len(result.Accepted) != 0 {
+				t.Fatalf("%s: got %d accepted transactions, "+
+					"want none", test.name,
+					len(result.Accepted))
+			}
+			continue
+		}
+
+		// Both transactions are evaluated at the fee rate of the
+		// package.
+		if len(result.Accepted) != 2 {
+			t.Fatalf("%s: got %d accepted transactions, want 2",
+				test.name, len(result.Accepted))
+		}
+		pkgSize := result.TxResults[0].TxSize +
+			result.TxResults[1].TxSize
+		wantFeeRate := test.childFee * 1000 / btcutil.Amount(pkgSize)
+		for i, r := range result.TxResults {
+			if r.EffectiveFeeRate != wantFeeRate {
+				t.Fatalf("%s: got effective fee rate %v for "+
+					"tx %d, want %v", test.name,
+					r.EffectiveFeeRate, i, wantFeeRate)
+			}
+			if len(r.EffectiveIncludes) != 2 {
+				t.Fatalf("%s: got %d effective includes for "+
+					"tx %d, want 2", test.name,
+					len(r.EffectiveIncludes), i)
+			}
+		}
+	}
+}
+
+// TestProcessPackageParentPaysForItself ensures a parent that pays the minimum
+// relay fee on its own is accepted on its own even when its child is rejected.
+func TestProcessPackageParentPaysForItself(t *testing.T) {
+	t.Parallel()
+
+	ctx, fundingTx := newPackageTestContext(t, 1)
+	txPool := ctx.harness.txPool
+
+	parent, err := ctx.harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(fundingTx, 0)}, 1, 1000,
+		false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create parent: %v", err)
+	}
+	child, err := ctx.harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(parent, 0)}, 1, 0, false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create child: %v", err)
+	}
+
+	result, err := txPool.ProcessPackage([]*btcutil.Tx{parent, child}, 0)
+	if err != nil {
+		t.Fatalf("ProcessPackage: unexpected error: %v", err)
+	}
+	if result.TxResults[0].Err != nil {
+		t.Fatalf("parent rejected: %v", result.TxResults[0].Err)
+	}
+	if result.TxResults[1].Err == nil {
+		t.Fatal("child accepted without a fee")
+	}
+	testPoolMembership(ctx, parent, false, true)
+	testPoolMembership(ctx, child, false, false)
+
+	// Submitting the package again reports the parent as already in the
+	// pool.
+	result, err = txPool.ProcessPackage([]*btcutil.Tx{parent, child}, 0)
+	if err != nil {
+		t.Fatalf("ProcessPackage: unexpected error: %v", err)
+	}
+	if !result.TxResults[0].AlreadyInPool {
+		t.Fatal("parent not reported as already in the pool")
+	}
+}
+
+// TestPackagePolicy ensures packages which violate the package policy are
+// rejected with the expected reason.
+func TestPackagePolicy(t *testing.T) {
+	t.Parallel()
+
+	ctx, fundingTx := newPackageTestContext(t, 2)
+	harness := ctx.harness
+
+	// createTx creates a transaction spending the passed outputs paying a
+	// fee of 1000 satoshi.
+	createTx := func(inputs ...spendableOutput) *btcutil.Tx {
+		tx, err := harness.CreateSignedTx(inputs, 1, 1000, false)
+		if err != nil {
+			t.Fatalf("unable to create transaction: %v", err)
+		}
+		return tx
+	}
+
+	parent1 := createTx(txOutToSpendableOut(fundingTx, 0))
+	parent2 := createTx(txOutToSpendableOut(fundingTx, 1))
+	child := createTx(
+		txOutToSpendableOut(parent1, 0), txOutToSpendableOut(parent2, 0),
+	)
+	grandchild := createTx(txOutToSpendableOut(child, 0))
+	conflict, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(fundingTx, 0)}, 1, 2000,
+		false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		txns   []*btcutil.Tx
+		reason string
+	}{
+		{
+			name:   "not sorted",
+			txns:   []*btcutil.Tx{child, parent1, parent2},
+			reason: "package-not-sorted",
+		},
+		{
+			name:   "duplicates",
+			txns:   []*btcutil.Tx{parent1, parent1, child},
+			reason: "package-contains-duplicates",
+		},
+		{
+			name:   "conflict",
+			txns:   []*btcutil.Tx{parent1, conflict},
+			reason: "conflict-in-package",
+		},
+		{
+			name:   "unrelated",
+			txns:   []*btcutil.Tx{parent1, parent2},
+			reason: "package-not-child-with-parents",
+		},
+		{
+			name:   "grandparent",
+			txns:   []*btcutil.Tx{parent1, parent2, child, grandchild},
+			reason: "package-not-child-with-parents",
+		},
+	}
+
+	for _, test := range tests {
+		_, err := harness.txPool.ProcessPackage(test.txns, 0)
+		ruleErr, ok := err.(RuleError)
+		if !ok {
+			t.Fatalf("%s: got error %v, want a rule error",
+				test.name, err)
+		}
+		pkgErr, ok := ruleErr.Err.(PackageRuleError)
+		if !ok {
+			t.Fatalf("%s: got error %v, want a package rule error",
+				test.name, err)
+		}
+		if pkgErr.Reason != test.reason {
+			t.Fatalf("%s: got reason %q, want %q", test.name,
+				pkgErr.Reason, test.reason)
+		}
+	}
+
+	// A package with all of its parents is accepted.
+	result, err := harness.txPool.ProcessPackage(
+		[]*btcutil.Tx{parent1, parent2, child}, 0,
+	)
+	if err != nil {
+		t.Fatalf("ProcessPackage: unexpected error: %v", err)
+	}
+	if result.Failed() {
+		t.Fatal("package with all of its parents rejected")
+	}
+}
+
+// TestCheckPackageAcceptance ensures a chain of transactions is checked as a
+// package without adding any of them to the pool and that checking stops at
+// the first rejected transaction.
+func TestCheckPackageAcceptance(t *testing.T) {
+	t.Parallel()
+
+	ctx, fundingTx := newPackageTestContext(t, 1)
+	harness := ctx.harness
+
+	var txns []*btcutil.Tx
+	prevOut := txOutToSpendableOut(fundingTx, 0)
+	for i := 0; i < 3; i++ {
+		tx, err := harness.CreateSignedTx(
+			[]spendableOutput{prevOut}, 1, 1000, false,
+		)
+		if err != nil {
+			t.Fatalf("unable to create transaction: %v", err)
+		}
+		txns = append(txns, tx)
+		prevOut = txOutToSpendableOut(tx, 0)
+	}
+
+	result, err := harness.txPool.CheckPackageAcceptance(txns)
+	if err != nil {
+		t.Fatalf("CheckPackageAcceptance: unexpected error: %v", err)
+	}
+	if result.Failed() {
+		t.Fatal("chain of transactions rejected")
+	}
+	for i, r := range result.TxResults {
+		if r.TxFee != 1000 {
+			t.Fatalf("got fee %v for tx %d, want 1000", r.TxFee, i)
+		}
+	}
+	if harness.txPool.Count() != 0 {
+		t.Fatalf("got %d transactions in the pool, want none",
+			harness.txPool.Count())
+	}
+
+	// Replace the middle transaction with one that does not pay a fee, so
+	// the last transaction is not checked.
+	free, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(txns[0], 0)}, 1, 0, false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	last, err := harness.CreateSignedTx(
+		[]spendableOutput{txOutToSpendableOut(free, 0)}, 1, 1000, false,
+	)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	result, err = harness.txPool.CheckPackageAcceptance(
+		[]*btcutil.Tx{txns[0], free, last},
+	)
+	if err != nil {
+		t.Fatalf("CheckPackageAcceptance: unexpected error: %v", err)
+	}
+	if result.TxResults[0] == nil || result.TxResults[0].Err != nil {
+		t.Fatal("first transaction rejected")
+	}
+	if result.TxResults[1] == nil || result.TxResults[1].Err == nil {
+		t.Fatal("free transaction not rejected")
+	}
+	if result.TxResults[2] != nil {
+		t.Fatal("transaction after the rejected one was checked")
+	}
+}
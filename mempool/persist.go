@@ -0,0 +1,397 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"bufio"
+	"container/heap"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	"github.com/bynil/btcd/wire"
+)
+
+const (
+	// dumpVersionNoXorKey is the version of the mempool dump format
+	// without obfuscation.
+	dumpVersionNoXorKey = 1
+
+	// dumpVersion is the version of the mempool dump format written by
+	// Dump.  Everything following the version and the key is obfuscated
+	// by XORing it with the key.
+	dumpVersion = 2
+
+	// dumpXorKeyLen is the length of the key used to obfuscate the dump.
+	dumpXorKeyLen = 8
+)
+
+// ErrLoadInterrupted is returned by Load when it is interrupted before all of
+// the transactions were loaded.
+var ErrLoadInterrupted = errors.New("mempool load interrupted")
+
+// LoadStats describes the result of loading the transactions from a mempool
+// dump.
+type LoadStats struct {
+	// Succeeded is the number of transactions accepted into the pool.
+	Succeeded int
+
+	// Failed is the number of transactions rejected by the pool.
+	Failed int
+
+	// AlreadyThere is the number of transactions that were already in the
+	// pool.
+	AlreadyThere int
+}
+
+// xorStream obfuscates the data read from or written to the underlying stream
+// by XORing each byte with the byte of the key at its offset within the dump.
+type xorStream struct {
+	r      io.Reader
+	w      io.Writer
+	key    []byte
+	offset int64
+}
+
+// apply XORs the passed data with the key starting from the current offset.
+func (s *xorStream) apply(p []byte) {
+	if len(s.key) == 0 {
+		s.offset += int64(len(p))
+		return
+	}
+	for i := range p {
+		p[i] ^= s.key[(s.offset+int64(i))%int64(len(s.key))]
+	}
+	s.offset += int64(len(p))
+}
+
+// Read reads from the underlying reader and removes the obfuscation.
+func (s *xorStream) Read(p []byte) (int, error) {
+	n, err := s.r.Read(p)
+	s.apply(p[:n])
+	return n, err
+}
+
+// Write obfuscates the passed data and writes it to the underlying writer.
+func (s *xorStream) Write(p []byte) (int, error) {
+	buf := make([]byte, len(p))
+	copy(buf, p)
+	s.apply(buf)
+	return s.w.Write(buf)
+}
+
+// Dump writes the transactions in the main pool to the passed writer along
+// with the time they entered the pool and the fee delta they were prioritized
+// with.  Transactions are written after their ancestors so they can be loaded
+// in order.  The fee deltas of transactions which are not in the main pool
+// follow.  The format is compatible with the mempool.dat file used by Bitcoin
+// Core.  Unbroadcast transactions are not supported, so their set is written
+// empty.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) Dump(w io.Writer) error {
+	mp.mtx.RLock()
+	type dumpEntry struct {
+		desc      *TxDesc
+		ancestors int
+		feeDelta  int64
+	}
+	entries := make([]dumpEntry, 0, len(mp.pool))
+	cache := make(map[chainhash.Hash]map[chainhash.Hash]*btcutil.Tx)
+	for _, txD := range mp.pool {
+		entries = append(entries, dumpEntry{
+			desc:      txD,
+			ancestors: len(mp.txAncestors(txD.Tx, cache)),
+			feeDelta:  txD.FeeDelta,
+		})
+	}
+	deltas := make(map[chainhash.Hash]int64, len(mp.feeDeltas))
+	for hash, delta := range mp.feeDeltas {
+		if _, exists := mp.pool[hash]; !exists {
+			deltas[hash] = delta
+		}
+	}
+	mp.mtx.RUnlock()
+
+	// A transaction always has more ancestors than each of its ancestors,
+	// so sorting by the number of ancestors orders parents before their
+	// children.
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].ancestors != entries[j].ancestors {
+			return entries[i].ancestors < entries[j].ancestors
+		}
+		return entries[i].desc.Added.Before(entries[j].desc.Added)
+	})
+
+	var key [dumpXorKeyLen]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], dumpVersion)
+	if _, err := w.Write(buf[:]); err != nil {
+		return err
+	}
+	if err := wire.WriteVarBytes(w, 0, key[:]); err != nil {
+		return err
+	}
+
+	// The obfuscation is applied based on the offset within the dump, so
+	// account for the version and key written above.
+	xw := &xorStream{w: w, key: key[:], offset: 8 + 1 + dumpXorKeyLen}
+
+	binary.LittleEndian.PutUint64(buf[:], uint64(len(entries)))
+	if _, err := xw.Write(buf[:]); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := entry.desc.Tx.MsgTx().Serialize(xw); err != nil {
+			return err
+		}
+
+		binary.LittleEndian.PutUint64(buf[:],
+			uint64(entry.desc.Added.Unix()))
+		if _, err := xw.Write(buf[:]); err != nil {
+			return err
+		}
+
+		binary.LittleEndian.PutUint64(buf[:],
+			uint64(entry.feeDelta))
+		if _, err := xw.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+
+	// Write the map of fee deltas for transactions not in the pool
+	// followed by the empty set of unbroadcast transactions.
+	if err := wire.WriteVarInt(xw, 0, uint64(len(deltas))); err != nil {
+		return err
+	}
+	for hash, delta := range deltas {
+		if _, err := xw.Write(hash[:]); err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint64(buf[:], uint64(delta))
+		if _, err := xw.Write(buf[:]); err != nil {
+			return err
+		}
+	}
+	return wire.WriteVarInt(xw, 0, 0)
+}
+
+// DumpToFile writes the transactions in the main pool to the file at the
+// passed path as described by Dump.  The file is replaced atomically.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) DumpToFile(path string) error {
+	tmpPath := path + ".new"
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	err = mp.Dump(w)
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return os.Rename(tmpPath, path)
+}
+
+// readUint64 reads a little-endian uint64 from the passed reader.
+func readUint64(r io.Reader) (uint64, error) {
+	var buf [8]byte
+	if _, err := io.ReadFull(r, buf[:]); err != nil {
+		return 0, err
+	}
+	return binary.LittleEndian.Uint64(buf[:]), nil
+}
+
+// Load reads a mempool dump as written by Dump or Bitcoin Core from the passed
+// reader and processes its transactions as if they were newly received, so
+// they are fully validated against the current chain and policy.  The time
+// each accepted transaction entered the pool is restored from the dump unless
+// useCurrentTime is set.  Loading stops with ErrLoadInterrupted when the
+// interrupt channel is closed.
+//
+// The fee deltas recorded in the dump are applied as if passed to
+// PrioritiseTransaction, including the ones of transactions which are not
+// loaded.  Unbroadcast transactions recorded in the dump, such as one written
+// by Bitcoin Core, are dropped since they are not supported.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) Load(r io.Reader, useCurrentTime bool,
+	interrupt <-chan struct{}) (*LoadStats, error) {
+
+	version, err := readUint64(r)
+	if err != nil {
+		return nil, err
+	}
+
+	xr := &xorStream{r: r, offset: 8}
+	switch version {
+	case dumpVersionNoXorKey:
+	case dumpVersion:
+		key, err := wire.ReadVarBytes(r, 0, dumpXorKeyLen, "xor key")
+		if err != nil {
+			return nil, err
+		}
+		xr.key = key
+		xr.offset += int64(wire.VarIntSerializeSize(uint64(len(key))) +
+			len(key))
+	default:
+		return nil, fmt.Errorf("unsupported mempool dump version %d",
+			version)
+	}
+
+	numTxns, err := readUint64(xr)
+	if err != nil {
+		return nil, err
+	}
+
+	stats := &LoadStats{}
+	for i := uint64(0); i < numTxns; i++ {
+		select {
+		case <-interrupt:
+			return stats, ErrLoadInterrupted
+		default:
+		}
+
+		var msgTx wire.MsgTx
+		if err := msgTx.Deserialize(xr); err != nil {
+			return stats, err
+		}
+		entryTime, err := readUint64(xr)
+		if err != nil {
+			return stats, err
+		}
+		feeDelta, err := readUint64(xr)
+		if err != nil {
+			return stats, err
+		}
+
+		// The fee delta is applied first so it is taken into account
+		// when the transaction is validated.
+		tx := btcutil.NewTx(&msgTx)
+		if feeDelta != 0 {
+			mp.PrioritiseTransaction(tx.Hash(), int64(feeDelta))
+		}
+
+		if mp.IsTransactionInPool(tx.Hash()) {
+			stats.AlreadyThere++
+			continue
+		}
+
+		acceptedTxns, err := mp.ProcessTransaction(tx, false, false, 0)
+		if err != nil {
+			log.Debugf("Unable to load transaction %v: %v",
+				tx.Hash(), err)
+			stats.Failed++
+			continue
+		}
+		stats.Succeeded++
+
+		if useCurrentTime {
+			continue
+		}
+		mp.mtx.Lock()
+		for _, txD := range acceptedTxns {
+			if mp.pool[*tx.Hash()] == txD {
+				txD.Added = time.Unix(int64(entryTime), 0)
+				heap.Fix(&mp.evictionQueue, txD.evictionIndex)
+			}
+		}
+		mp.mtx.Unlock()
+	}
+
+	// The map of fee deltas for transactions not in the pool follows.
+	numDeltas, err := wire.ReadVarInt(xr, 0)
+	if err != nil {
+		return stats, err
+	}
+	for i := uint64(0); i < numDeltas; i++ {
+		var hash chainhash.Hash
+		if _, err := io.ReadFull(xr, hash[:]); err != nil {
+			return stats, err
+		}
+		delta, err := readUint64(xr)
+		if err != nil {
+			return stats, err
+		}
+		mp.PrioritiseTransaction(&hash, int64(delta))
+	}
+
+	// The set of unbroadcast transactions is not supported, so it is only
+	// read to ensure the dump is well formed.
+	numUnbroadcast, err := wire.ReadVarInt(xr, 0)
+	if err != nil {
+		return stats, err
+	}
+	for i := uint64(0); i < numUnbroadcast; i++ {
+		var hash chainhash.Hash
+		if _, err := io.ReadFull(xr, hash[:]); err != nil {
+			return stats, err
+		}
+	}
+
+	return stats, nil
+}
+
+// LoadFromFile loads the transactions from the mempool dump file at the passed
+// path as described by Load.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) LoadFromFile(path string, useCurrentTime bool,
+	interrupt <-chan struct{}) (*LoadStats, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return mp.Load(bufio.NewReader(file), useCurrentTime, interrupt)
+}
+
+// SetLoadTried records whether an attempt to load the pool from a dump has
+// finished, which indicates the pool can be dumped without losing
+// transactions that were not loaded yet.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) SetLoadTried(loadTried bool) {
+	var v int32
+	if loadTried {
+		v = 1
+	}
+	atomic.StoreInt32(&mp.loadTried, v)
+}
+
+// LoadTried returns whether an attempt to load the pool from a dump has
+// finished.
+//
+// This function is safe for concurrent access.
+func (mp *TxPool) LoadTried() bool {
+	return atomic.LoadInt32(&mp.loadTried) == 1
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bynil/btcd/chaincfg"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	"github.com/bynil/btcd/wire"
+)
+
+// TestDumpLoad ensures the transactions dumped from a pool are loaded into
+// another pool along with the time they entered the original pool and the fee
+// deltas they were prioritized with.
+func TestDumpLoad(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+
+	// Add a chain of transactions in the order they depend on each other,
+	// then backdate their entry times in the reverse order to ensure the
+	// dump orders them by their dependencies.
+	chainedTxns, err := harness.CreateTxChain(spendableOuts[0], 4)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+	for i, tx := range chainedTxns {
+		acceptedTxns, err := harness.txPool.ProcessTransaction(tx, false,
+			false, 0)
+		if err != nil {
+			t.Fatalf("ProcessTransaction: unexpected error: %v", err)
+		}
+		acceptedTxns[0].Added = time.Unix(int64(1700000000-i*60), 0)
+	}
+
+	// Prioritize one of the transactions as well as a transaction which
+	// isn't in the pool.
+	var unknownHash chainhash.Hash
+	unknownHash[0] = 0x01
+	harness.txPool.PrioritiseTransaction(chainedTxns[1].Hash(), 500)
+	harness.txPool.PrioritiseTransaction(&unknownHash, -700)
+
+	var buf bytes.Buffer
+	if err := harness.txPool.Dump(&buf); err != nil {
+		t.Fatalf("Dump: unexpected error: %v", err)
+	}
+	if version := binary.LittleEndian.Uint64(buf.Bytes()); version !=
+		dumpVersion {
+
+		t.Fatalf("got dump version %d, want %d", version, dumpVersion)
+	}
+	dump := buf.Bytes()
+
+	// Load the dump into a new pool backed by the same chain.
+	cfg := harness.txPool.cfg
+	txPool := New(&cfg)
+	stats, err := txPool.Load(bytes.NewReader(dump), false, nil)
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if *stats != (LoadStats{Succeeded: len(chainedTxns)}) {
+		t.Fatalf("got load stats %+v", *stats)
+	}
+	for i, tx := range chainedTxns {
+		txD, ok := txPool.pool[*tx.Hash()]
+		if !ok {
+			t.Fatalf("transaction %d was not loaded", i)
+		}
+		want := time.Unix(int64(1700000000-i*60), 0)
+		if !txD.Added.Equal(want) {
+			t.Fatalf("transaction %d: got entry time %v, want %v",
+				i, txD.Added, want)
+		}
+	}
+
+	// The fee deltas are restored whether the transaction is in the pool
+	// or not.
+	wantDeltas := map[chainhash.Hash]int64{
+		*chainedTxns[1].Hash(): 500,
+		unknownHash:            -700,
+	}
+	if !reflect.DeepEqual(txPool.feeDeltas, wantDeltas) {
+		t.Fatalf("got fee deltas %v, want %v", txPool.feeDeltas,
+			wantDeltas)
+	}
+	if got := txPool.pool[*chainedTxns[1].Hash()].FeeDelta; got != 500 {
+		t.Fatalf("got fee delta %d for the loaded transaction, want "+
+			"500", got)
+	}
+
+	// Loading the dump again reports the transactions already in the
+	// pool.
+	stats, err = txPool.Load(bytes.NewReader(dump), true, nil)
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if *stats != (LoadStats{AlreadyThere: len(chainedTxns)}) {
+		t.Fatalf("got load stats %+v", *stats)
+	}
+
+	// Loading is stopped once interrupted.
+	interrupt := make(chan struct{})
+	close(interrupt)
+	_, err = New(&cfg).Load(bytes.NewReader(dump), true, interrupt)
+	if err != ErrLoadInterrupted {
+		t.Fatalf("got error %v, want %v", err, ErrLoadInterrupted)
+	}
+}
+
+// TestLoadVersions ensures dumps without obfuscation are loaded and dumps with
+// an unknown version are rejected.
+func TestLoadVersions(t *testing.T) {
+	t.Parallel()
+
+	harness, spendableOuts, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	chainedTxns, err := harness.CreateTxChain(spendableOuts[0], 1)
+	if err != nil {
+		t.Fatalf("unable to create transaction chain: %v", err)
+	}
+
+	// Create a dump without obfuscation containing the transaction, a fee
+	// delta for a transaction that is not in the pool, and no unbroadcast
+	// transactions.
+	var buf bytes.Buffer
+	writeUint64 := func(v uint64) {
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], v)
+		buf.Write(b[:])
+	}
+	writeUint64(dumpVersionNoXorKey)
+	writeUint64(1)
+	if err := chainedTxns[0].MsgTx().Serialize(&buf); err != nil {
+		t.Fatalf("unable to serialize transaction: %v", err)
+	}
+	writeUint64(1700000000)
+	writeUint64(0)
+	wire.WriteVarInt(&buf, 0, 1)
+	buf.Write(make([]byte, 32))
+	writeUint64(1000)
+	wire.WriteVarInt(&buf, 0, 0)
+	dump := buf.Bytes()
+
+	stats, err := harness.txPool.Load(bytes.NewReader(dump), false, nil)
+	if err != nil {
+		t.Fatalf("Load: unexpected error: %v", err)
+	}
+	if *stats != (LoadStats{Succeeded: 1}) {
+		t.Fatalf("got load stats %+v", *stats)
+	}
+	if !harness.txPool.IsTransactionInPool(chainedTxns[0].Hash()) {
+		t.Fatal("transaction was not loaded")
+	}
+
+	// Truncated dumps are rejected.
+	_, err = harness.txPool.Load(bytes.NewReader(dump[:len(dump)-1]),
+		false, nil)
+	if err == nil {
+		t.Fatal("expected truncated dump to be rejected")
+	}
+
+	// Unknown versions are rejected.
+	binary.LittleEndian.PutUint64(dump, 3)
+	_, err = harness.txPool.Load(bytes.NewReader(dump), false, nil)
+	if err == nil {
+		t.Fatal("expected unknown version to be rejected")
+	}
+}
@@ -100,6 +100,19 @@ func checkInputsStandard(tx *btcutil.Tx, utxoView *blockchain.UtxoViewpoint) err
 		// function.
 		entry := utxoView.LookupEntry(txIn.PreviousOutPoint)
 		originPkScript := entry.PkScript()
+
+		// Pay-to-anchor outputs must be spent with an empty witness
+		// since anyone can spend them.
+		if txscript.IsPayToAnchor(originPkScript) {
+			if len(txIn.Witness) != 0 {
+				str := fmt.Sprintf("transaction input #%d "+
+					"spends a pay-to-anchor output with a "+
+					"non-empty witness", i)
+				return txRuleError(wire.RejectNonstandard, str)
+			}
+			continue
+		}
+
 		switch txscript.GetScriptClass(originPkScript) {
 		case txscript.ScriptHashTy:
 			numSigOps := txscript.GetPreciseSigOpCount(
@@ -165,6 +178,12 @@ func checkPkScriptStandard(pkScript []byte, scriptClass txscript.ScriptClass) er
 		}
 
 	case txscript.NonStandardTy:
+		// Pay-to-anchor scripts are standard even though they are
+		// not a recognized form.
+		if txscript.IsPayToAnchor(pkScript) {
+			return nil
+		}
+
 		return txRuleError(wire.RejectNonstandard,
 			"non-standard script form")
 	}
@@ -286,6 +305,19 @@ func CheckTransactionStandard(tx *btcutil.Tx, height int32,
 	medianTimePast time.Time, minRelayTxFee btcutil.Amount,
 	maxTxVersion int32) error {
 
+	return checkTransactionStandard(
+		tx, height, medianTimePast, minRelayTxFee, maxTxVersion, 0,
+	)
+}
+
+// checkTransactionStandard performs the checks described by
+// CheckTransactionStandard while allowing up to the passed number of "dust"
+// outputs.  The mempool allows dust outputs that are spent by a child in the
+// same package, which is known as ephemeral dust.
+func checkTransactionStandard(tx *btcutil.Tx, height int32,
+	medianTimePast time.Time, minRelayTxFee btcutil.Amount,
+	maxTxVersion int32, maxDustOutputs int) error {
+
 	// The transaction must be a currently supported version.
 	msgTx := tx.MsgTx()
 	if msgTx.Version > maxTxVersion || msgTx.Version < 1 {
@@ -336,8 +368,10 @@ func CheckTransactionStandard(tx *btcutil.Tx, height int32,
 	}
 
 	// None of the output public key scripts can be a non-standard script or
-	// be "dust" (except when the script is a null data script).
+	// be "dust" (except when the script is a null data script or the
+	// number of dust outputs is within the allowed maximum).
 	numNullDataOutputs := 0
+	numDustOutputs := 0
 	for i, txOut := range msgTx.TxOut {
 		scriptClass := txscript.GetScriptClass(txOut.PkScript)
 		err := checkPkScriptStandard(txOut.PkScript, scriptClass)
@@ -359,6 +393,11 @@ func CheckTransactionStandard(tx *btcutil.Tx, height int32,
 		if scriptClass == txscript.NullDataTy {
 			numNullDataOutputs++
 		} else if IsDust(txOut, minRelayTxFee) {
+			numDustOutputs++
+			if numDustOutputs <= maxDustOutputs {
+				continue
+			}
+
 			str := fmt.Sprintf("transaction output %d: payment is "+
 				"dust: %v", i, txOut.Value)
 			return txRuleError(wire.RejectDust, str)
@@ -0,0 +1,266 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"fmt"
+
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	"github.com/bynil/btcd/txscript"
+	"github.com/bynil/btcd/wire"
+)
+
+const (
+	// TrucVersion is the transaction version which opts in to the
+	// topologically restricted until confirmation (TRUC) policy defined by
+	// BIP 431.
+	TrucVersion = 3
+
+	// TrucMaxVsize is the maximum virtual size of a TRUC transaction.
+	TrucMaxVsize = 10000
+
+	// TrucChildMaxVsize is the maximum virtual size of a TRUC transaction
+	// with an unconfirmed parent.
+	TrucChildMaxVsize = 1000
+
+	// MaxDustOutputsPerTx is the maximum number of dust outputs a
+	// transaction may have.  Dust outputs are only allowed in transactions
+	// which pay no fee and whose dust is spent by a child in the same
+	// package, so they never remain in the mempool unspent.
+	MaxDustOutputsPerTx = 1
+)
+
+// unconfirmedParent returns the transaction with the passed hash from either
+// the pool or the package the transaction being validated is part of.  It
+// returns nil when the transaction is in neither, which means it is confirmed.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) unconfirmedParent(hash *chainhash.Hash,
+	pkg *packageContext) *btcutil.Tx {
+
+	if txD, exists := mp.pool[*hash]; exists {
+		return txD.Tx
+	}
+	if pkg != nil {
+		if tx, exists := pkg.txns[*hash]; exists {
+			return tx
+		}
+	}
+
+	return nil
+}
+
+// unconfirmedParents returns the unconfirmed transactions spent by the passed
+// transaction keyed by their hash.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) unconfirmedParents(tx *btcutil.Tx,
+	pkg *packageContext) map[chainhash.Hash]*btcutil.Tx {
+
+	parents := make(map[chainhash.Hash]*btcutil.Tx)
+	for _, txIn := range tx.MsgTx().TxIn {
+		prevHash := &txIn.PreviousOutPoint.Hash
+		if parent := mp.unconfirmedParent(prevHash, pkg); parent != nil {
+			parents[*prevHash] = parent
+		}
+	}
+
+	return parents
+}
+
+// validateTrucPolicy checks that the transaction complies with the TRUC policy
+// defined by BIP 431.  That is, TRUC transactions only spend unconfirmed TRUC
+// transactions and vice versa, a TRUC transaction is limited in size, has at
+// most one unconfirmed ancestor, and a TRUC transaction with an unconfirmed
+// parent is further limited in size.
+//
+// An unconfirmed TRUC transaction may only have a single child.  When the
+// parent of the transaction already has a child in the pool which the
+// transaction does not conflict with, that sibling is returned so it can be
+// evicted in favor of the transaction if the transaction is a valid
+// replacement of it.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) validateTrucPolicy(tx *btcutil.Tx, txSize int64,
+	pkg *packageContext) (*btcutil.Tx, error) {
+
+	txHash := tx.Hash()
+	isTruc := tx.MsgTx().Version == TrucVersion
+	parents := mp.unconfirmedParents(tx, pkg)
+	for _, parent := range parents {
+		parentIsTruc := parent.MsgTx().Version == TrucVersion
+		switch {
+		case isTruc && !parentIsTruc:
+			str := fmt.Sprintf("version=%d tx %v cannot spend from "+
+				"non-version=%d tx %v", TrucVersion, txHash,
+				TrucVersion, parent.Hash())
+			return nil, txRuleError(wire.RejectNonstandard, str)
+
+		case !isTruc && parentIsTruc:
+			str := fmt.Sprintf("non-version=%d tx %v cannot spend "+
+				"from version=%d tx %v", TrucVersion, txHash,
+				TrucVersion, parent.Hash())
+			return nil, txRuleError(wire.RejectNonstandard, str)
+		}
+	}
+
+	// There is nothing more to check for transactions which don't opt in.
+	if !isTruc {
+		return nil, nil
+	}
+
+	if txSize > TrucMaxVsize {
+		str := fmt.Sprintf("version=%d tx %v is too big: %d > %d "+
+			"virtual bytes", TrucVersion, txHash, txSize,
+			TrucMaxVsize)
+		return nil, txRuleError(wire.RejectNonstandard, str)
+	}
+
+	// Nothing more to check when the transaction has no unconfirmed
+	// parent.
+	if len(parents) == 0 {
+		return nil, nil
+	}
+
+	// The transaction may only have a single unconfirmed ancestor, so it
+	// must have a single unconfirmed parent which must not have any
+	// unconfirmed parents itself.
+	var parent *btcutil.Tx
+	for _, p := range parents {
+		parent = p
+	}
+	if len(parents) > 1 || len(mp.unconfirmedParents(parent, pkg)) > 0 {
+		str := fmt.Sprintf("version=%d tx %v would have too many "+
+			"ancestors", TrucVersion, txHash)
+		return nil, txRuleError(wire.RejectNonstandard, str)
+	}
+
+	if txSize > TrucChildMaxVsize {
+		str := fmt.Sprintf("version=%d child tx %v is too big: %d > "+
+			"%d virtual bytes", TrucVersion, txHash, txSize,
+			TrucChildMaxVsize)
+		return nil, txRuleError(wire.RejectNonstandard, str)
+	}
+
+	// The parent may only have a single child.  A child in the same
+	// package can't be replaced, while a child in the pool that the
+	// transaction does not directly conflict with is a sibling which may
+	// be evicted.
+	parentHash := parent.Hash()
+	tooManyDescendants := func() error {
+		str := fmt.Sprintf("version=%d tx %v would exceed the "+
+			"descendant limit of tx %v", TrucVersion, txHash,
+			parentHash)
+		return txRuleError(wire.RejectNonstandard, str)
+	}
+	if pkg != nil {
+		for _, pkgTx := range pkg.txns {
+			for _, txIn := range pkgTx.MsgTx().TxIn {
+				if txIn.PreviousOutPoint.Hash == *parentHash {
+					return nil, tooManyDescendants()
+				}
+			}
+		}
+	}
+
+	var sibling *btcutil.Tx
+	op := wire.OutPoint{Hash: *parentHash}
+	for i := range parent.MsgTx().TxOut {
+		op.Index = uint32(i)
+		if child, exists := mp.outpoints[op]; exists {
+			sibling = child
+			break
+		}
+	}
+	if sibling == nil {
+		return nil, nil
+	}
+	for _, txIn := range tx.MsgTx().TxIn {
+		if mp.outpoints[txIn.PreviousOutPoint] == sibling {
+			// The existing child is replaced as a regular
+			// conflict.
+			return nil, nil
+		}
+	}
+	if mp.cfg.Policy.RejectReplacement {
+		return nil, tooManyDescendants()
+	}
+
+	return sibling, nil
+}
+
+// dustOutputs returns the indexes of the outputs of the transaction which are
+// considered dust.
+func (mp *TxPool) dustOutputs(tx *btcutil.Tx) []uint32 {
+	var dust []uint32
+	for i, txOut := range tx.MsgTx().TxOut {
+		if txscript.IsNullData(txOut.PkScript) {
+			continue
+		}
+		if IsDust(txOut, mp.cfg.Policy.MinRelayTxFee) {
+			dust = append(dust, uint32(i))
+		}
+	}
+
+	return dust
+}
+
+// hasEphemeralDust returns whether the transaction has dust outputs that must
+// be spent by a child in the same package for the transaction to be accepted.
+func (mp *TxPool) hasEphemeralDust(tx *btcutil.Tx) bool {
+	return !mp.cfg.Policy.AcceptNonStd && len(mp.dustOutputs(tx)) > 0
+}
+
+// validateEphemeralDust checks that a transaction with a dust output pays no
+// fee and is validated as part of a package whose fees are checked as a whole,
+// so the dust output can be spent by a child in the same package.  It also
+// checks that the transaction spends all of the dust outputs of its
+// unconfirmed parents, so the dust never remains in the pool unspent.
+//
+// This function MUST be called with the mempool lock held (for reads).
+func (mp *TxPool) validateEphemeralDust(tx *btcutil.Tx, txFee int64,
+	pkg *packageContext) error {
+
+	// Dust outputs are non-standard unless they are ephemeral.
+	if mp.cfg.Policy.AcceptNonStd {
+		return nil
+	}
+
+	txHash := tx.Hash()
+	if mp.hasEphemeralDust(tx) {
+		if txFee != 0 {
+			str := fmt.Sprintf("transaction %v with a dust output "+
+				"must not pay a fee, has %d", txHash, txFee)
+			return txRuleError(wire.RejectDust, str)
+		}
+		if pkg == nil || !pkg.skipFeeChecks {
+			str := fmt.Sprintf("transaction %v with a dust output "+
+				"must be spent by a child in the same package",
+				txHash)
+			return txRuleError(wire.RejectDust, str)
+		}
+	}
+
+	spent := make(map[wire.OutPoint]struct{}, len(tx.MsgTx().TxIn))
+	for _, txIn := range tx.MsgTx().TxIn {
+		spent[txIn.PreviousOutPoint] = struct{}{}
+	}
+	for parentHash, parent := range mp.unconfirmedParents(tx, pkg) {
+		for _, idx := range mp.dustOutputs(parent) {
+			op := wire.OutPoint{Hash: parentHash, Index: idx}
+			if _, ok := spent[op]; ok {
+				continue
+			}
+
+			str := fmt.Sprintf("transaction %v does not spend the "+
+				"ephemeral dust output %v of its parent", txHash,
+				op)
+			return txRuleError(wire.RejectNonstandard, str)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mempool
+
+import (
+	"testing"
+
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg"
+	"github.com/bynil/btcd/txscript"
+	"github.com/bynil/btcd/wire"
+)
+
+// payToAnchorScript is the script of a pay-to-anchor output.
+var payToAnchorScript = []byte{txscript.OP_1, txscript.OP_DATA_2, 0x4e, 0x73}
+
+// signHarnessTx signs all inputs of the passed transaction which don't spend a
+// pay-to-anchor output with the harness key.
+func signHarnessTx(t *testing.T, harness *poolHarness, msgTx *wire.MsgTx,
+	anchorInputs map[int]struct{}) *btcutil.Tx {
+
+	t.Helper()
+
+	for i := range msgTx.TxIn {
+		if _, ok := anchorInputs[i]; ok {
+			msgTx.TxIn[i].SignatureScript = nil
+			continue
+		}
+
+		sigScript, err := txscript.SignatureScript(msgTx, i,
+			harness.payScript, txscript.SigHashAll, harness.signKey,
+			true)
+		if err != nil {
+			t.Fatalf("unable to sign transaction: %v", err)
+		}
+		msgTx.TxIn[i].SignatureScript = sigScript
+	}
+
+	return btcutil.NewTx(msgTx)
+}
+
+// createVersionedTx creates a transaction with the passed version that spends
+// the inputs and splits their amount minus the fee evenly among the outputs.
+func createVersionedTx(t *testing.T, harness *poolHarness,
+	inputs []spendableOutput, numOutputs uint32, fee btcutil.Amount,
+	version int32) *btcutil.Tx {
+
+	t.Helper()
+
+	tx, err := harness.CreateSignedTx(inputs, numOutputs, fee, false)
+	if err != nil {
+		t.Fatalf("unable to create transaction: %v", err)
+	}
+	msgTx := tx.MsgTx()
+	msgTx.Version = version
+
+	return signHarnessTx(t, harness, msgTx, nil)
+}
+
+// TestTrucPolicy ensures TRUC transactions are limited to a single unconfirmed
+// parent and child of the same version, and that a child evicts its sibling
+// when it pays enough to replace it.
+func TestTrucPolicy(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	harness.txPool.cfg.Policy.MaxTxVersion = TrucVersion
+	ctx := &testContext{t, harness}
+
+	coinbase := ctx.addCoinbaseTx(2)
+	confirmedOut := txOutToSpendableOut(coinbase, 0)
+	otherOut := txOutToSpendableOut(coinbase, 1)
+
+	// accept processes the transaction and ensures it is accepted or
+	// rejected as expected.
+	accept := func(name string, tx *btcutil.Tx, wantAccepted bool) {
+		t.Helper()
+
+		_, err := harness.txPool.ProcessTransaction(tx, false, false, 0)
+		if wantAccepted && err != nil {
+			t.Fatalf("%s: unexpected error: %v", name, err)
+		}
+		if !wantAccepted && err == nil {
+			t.Fatalf("%s: transaction accepted", name)
+		}
+		testPoolMembership(ctx, tx, false, wantAccepted)
+	}
+
+	parent := createVersionedTx(
+		t, harness, []spendableOutput{confirmedOut}, 3, 1000,
+		TrucVersion,
+	)
+	accept("truc parent", parent, true)
+
+	nonTrucParent := createVersionedTx(
+		t, harness, []spendableOutput{otherOut}, 1, 1000, 1,
+	)
+	accept("non-truc parent", nonTrucParent, true)
+
+	// A TRUC transaction can't spend a non-TRUC transaction and vice
+	// versa.
+	accept("non-truc child of truc parent", createVersionedTx(
+		t, harness, []spendableOutput{txOutToSpendableOut(parent, 0)},
+		1, 1000, 1,
+	), false)
+	accept("truc child of non-truc parent", createVersionedTx(
+		t, harness,
+		[]spendableOutput{txOutToSpendableOut(nonTrucParent, 0)},
+		1, 1000, TrucVersion,
+	), false)
+
+	// A TRUC child is limited in size.
+	accept("big truc child", createVersionedTx(
+		t, harness, []spendableOutput{txOutToSpendableOut(parent, 0)},
+		40, 10000, TrucVersion,
+	), false)
+
+	child := createVersionedTx(
+		t, harness, []spendableOutput{txOutToSpendableOut(parent, 0)},
+		1, 1000, TrucVersion,
+	)
+	accept("truc child", child, true)
+
+	// A TRUC transaction can only have a single unconfirmed ancestor.
+	accept("truc grandchild", createVersionedTx(
+		t, harness, []spendableOutput{txOutToSpendableOut(child, 0)},
+		1, 1000, TrucVersion,
+	), false)
+
+	// A sibling that does not pay enough to replace the existing child
+	// is rejected, while one that does evicts the existing child.
+	accept("cheap sibling", createVersionedTx(
+		t, harness, []spendableOutput{txOutToSpendableOut(parent, 1)},
+		1, 1000, TrucVersion,
+	), false)
+	sibling := createVersionedTx(
+		t, harness, []spendableOutput{txOutToSpendableOut(parent, 1)},
+		1, 3000, TrucVersion,
+	)
+	accept("sibling", sibling, true)
+	testPoolMembership(ctx, child, false, false)
+
+	// TRUC transactions are replaceable without signaling it.
+	replacement := createVersionedTx(
+		t, harness, []spendableOutput{txOutToSpendableOut(parent, 1)},
+		1, 5000, TrucVersion,
+	)
+	accept("replacement", replacement, true)
+	testPoolMembership(ctx, sibling, false, false)
+}
+
+// TestEphemeralDust ensures a transaction with a dust output is only accepted
+// when it pays no fee and a child in the same package spends the dust.
+func TestEphemeralDust(t *testing.T) {
+	t.Parallel()
+
+	harness, _, err := newPoolHarness(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("unable to create test pool: %v", err)
+	}
+	ctx := &testContext{t, harness}
+	coinbase := ctx.addCoinbaseTx(1)
+	coinbaseOut := txOutToSpendableOut(coinbase, 0)
+
+	// createParent creates a transaction paying the passed fee with a
+	// pay-to-anchor output as its last output.
+	createParent := func(fee btcutil.Amount) *btcutil.Tx {
+		tx, err := harness.CreateSignedTx(
+			[]spendableOutput{coinbaseOut}, 1, fee, false,
+		)
+		if err != nil {
+			t.Fatalf("unable to create transaction: %v", err)
+		}
+		msgTx := tx.MsgTx()
+		msgTx.AddTxOut(wire.NewTxOut(0, payToAnchorScript))
+
+		return signHarnessTx(t, harness, msgTx, nil)
+	}
+
+	// createChild creates a transaction spending the first output of the
+	// parent and, if requested, its pay-to-anchor output.
+	createChild := func(parent *btcutil.Tx, spendAnchor bool) *btcutil.Tx {
+		tx, err := harness.CreateSignedTx(
+			[]spendableOutput{txOutToSpendableOut(parent, 0)}, 1,
+			2000, false,
+		)
+		if err != nil {
+			t.Fatalf("unable to create transaction: %v", err)
+		}
+		msgTx := tx.MsgTx()
+		if !spendAnchor {
+			return tx
+		}
+
+		msgTx.AddTxIn(wire.NewTxIn(
+			&wire.OutPoint{Hash: *parent.Hash(), Index: 1}, nil,
+			nil,
+		))
+		return signHarnessTx(
+			t, harness, msgTx, map[int]struct{}{1: {}},
+		)
+	}
+
+	// The dust can't be added on its own or pay a fee.
+	parent := createParent(0)
+	_, err = harness.txPool.ProcessTransaction(parent, false, false, 0)
+	if err == nil {
+		t.Fatal("transaction with ephemeral dust accepted on its own")
+	}
+	feeParent := createParent(1000)
+	result, err := harness.txPool.ProcessPackage([]*btcutil.Tx{
+		feeParent, createChild(feeParent, true),
+	}, 0)
+	if err != nil {
+		t.Fatalf("ProcessPackage: unexpected error: %v", err)
+	}
+	if !result.Failed() || len(result.Accepted) != 0 {
+		t.Fatal("transaction with dust paying a fee accepted")
+	}
+
+	// A child which does not spend the dust is rejected along with its
+	// parent.
+	result, err = harness.txPool.ProcessPackage([]*btcutil.Tx{
+		parent, createChild(parent, false),
+	}, 0)
+	if err != nil {
+		t.Fatalf("ProcessPackage: unexpected error: %v", err)
+	}
+	if !result.Failed() || len(result.Accepted) != 0 {
+		t.Fatal("package leaving the dust unspent accepted")
+	}
+	testPoolMembership(ctx, parent, false, false)
+
+	// A child which spends the dust is accepted along with its parent.
+	child := createChild(parent, true)
+	result, err = harness.txPool.ProcessPackage(
+		[]*btcutil.Tx{parent, child}, 0,
+	)
+	if err != nil {
+		t.Fatalf("ProcessPackage: unexpected error: %v", err)
+	}
+	for i, r := range result.TxResults {
+		if r == nil || r.Err != nil {
+			t.Fatalf("tx %d of the package rejected: %v", i, r)
+		}
+	}
+	testPoolMembership(ctx, parent, false, true)
+	testPoolMembership(ctx, child, false, true)
+}
@@ -8,6 +8,8 @@ import (
 	"bytes"
 	"container/heap"
 	"fmt"
+	"maps"
+	"sort"
 	"time"
 
 	"github.com/bynil/btcd/blockchain"
@@ -51,6 +53,11 @@ type TxDesc struct {
 
 	// FeePerKB is the fee the transaction pays in Satoshi per 1000 bytes.
 	FeePerKB int64
+
+	// FeeDelta is the fee delta the transaction was prioritized with.  It
+	// is added to the fee of the transaction when selecting transactions
+	// for new blocks, which doesn't change the fee it actually pays.
+	FeeDelta int64
 }
 
 // TxSource represents a source of transactions to consider for inclusion in
@@ -60,7 +67,8 @@ type TxDesc struct {
 // concurrent access with respect to the source.
 type TxSource interface {
 	// LastUpdated returns the last time a transaction was added to or
-	// removed from the source pool.
+	// removed from the source pool or the fee delta of a transaction in
+	// the source pool changed.
 	LastUpdated() time.Time
 
 	// MiningDescs returns a slice of mining descriptors for all the
@@ -78,14 +86,50 @@ type TxSource interface {
 type txPrioItem struct {
 	tx       *btcutil.Tx
 	fee      int64
+	feeDelta int64
 	priority float64
 	feePerKB int64
 
+	// weight and sigOpCost are the weight and the signature operation
+	// cost of the transaction.
+	weight    int64
+	sigOpCost int64
+
 	// dependsOn holds a map of transaction hashes which this one depends
 	// on.  It will only be set when the transaction references other
 	// transactions in the source pool and hence must come after them in
 	// a block.
 	dependsOn map[chainhash.Hash]struct{}
+
+	// ancestors holds the items of the transactions which this one
+	// depends on either directly or indirectly and which haven't been
+	// included in the block yet, while descendants holds the items of the
+	// transactions which depend on this one.  Together with the
+	// transaction itself, the ancestors form its ancestor package.
+	ancestors   map[chainhash.Hash]*txPrioItem
+	descendants map[chainhash.Hash]*txPrioItem
+
+	// ancestorFee, ancestorWeight and ancestorSigOpCost are the totals of
+	// the ancestor package of the transaction, where the fees include the
+	// fee deltas of the transactions.  They are updated as the ancestors
+	// are included in the block.
+	ancestorFee       int64
+	ancestorWeight    int64
+	ancestorSigOpCost int64
+
+	// index is the index of the item in the priority queue holding it, or
+	// -1 when it isn't in a priority queue.
+	index int
+}
+
+// setPackageFeePerKB sets the fee per kilobyte of the item to the fee rate of
+// its ancestor package.  Prioritizing transactions by it allows transactions
+// paying high fees to pull the low-fee transactions they depend on into the
+// block, which is also known as child-pays-for-parent.
+func (item *txPrioItem) setPackageFeePerKB() {
+	vsize := (item.ancestorWeight + blockchain.WitnessScaleFactor - 1) /
+		blockchain.WitnessScaleFactor
+	item.feePerKB = item.ancestorFee * 1000 / vsize
 }
 
 // txPriorityQueueLessFunc describes a function that can be used as a compare
@@ -116,12 +160,16 @@ func (pq *txPriorityQueue) Less(i, j int) bool {
 // part of the heap.Interface implementation.
 func (pq *txPriorityQueue) Swap(i, j int) {
 	pq.items[i], pq.items[j] = pq.items[j], pq.items[i]
+	pq.items[i].index = i
+	pq.items[j].index = j
 }
 
 // Push pushes the passed item onto the priority queue.  It is part of the
 // heap.Interface implementation.
 func (pq *txPriorityQueue) Push(x interface{}) {
-	pq.items = append(pq.items, x.(*txPrioItem))
+	item := x.(*txPrioItem)
+	item.index = len(pq.items)
+	pq.items = append(pq.items, item)
 }
 
 // Pop removes the highest priority item (according to Less) from the priority
@@ -129,6 +177,7 @@ func (pq *txPriorityQueue) Push(x interface{}) {
 func (pq *txPriorityQueue) Pop() interface{} {
 	n := len(pq.items)
 	item := pq.items[n-1]
+	item.index = -1
 	pq.items[n-1] = nil
 	pq.items = pq.items[0 : n-1]
 	return item
@@ -183,6 +232,136 @@ func newTxPriorityQueue(reserve int, sortByFee bool) *txPriorityQueue {
 	return pq
 }
 
+// linkTxPackages sets the ancestors and descendants of the passed items, which
+// are keyed by transaction hash, along with the totals of their ancestor
+// packages.  Items depending on transactions which aren't in the passed map
+// can't be included in the block, so they are removed from it.
+func linkTxPackages(items map[chainhash.Hash]*txPrioItem) {
+	// Remove the items which depend on unavailable transactions, either
+	// directly or indirectly.
+	available := make(map[chainhash.Hash]bool, len(items))
+	var isAvailable func(hash chainhash.Hash) bool
+	isAvailable = func(hash chainhash.Hash) bool {
+		if ok, exists := available[hash]; exists {
+			return ok
+		}
+		item, ok := items[hash]
+		if ok {
+			for parentHash := range item.dependsOn {
+				if !isAvailable(parentHash) {
+					ok = false
+					break
+				}
+			}
+		}
+		available[hash] = ok
+		return ok
+	}
+	for hash := range items {
+		if !isAvailable(hash) {
+			log.Tracef("Skipping tx %s since it depends on a "+
+				"transaction which is not available", hash)
+			delete(items, hash)
+		}
+	}
+
+	// Set the ancestors of each item from those of the items it depends
+	// on, which are set first.
+	var link func(item *txPrioItem)
+	link = func(item *txPrioItem) {
+		if item.ancestors != nil {
+			return
+		}
+
+		item.ancestors = make(map[chainhash.Hash]*txPrioItem)
+		for parentHash := range item.dependsOn {
+			parent := items[parentHash]
+			link(parent)
+			item.ancestors[parentHash] = parent
+			maps.Copy(item.ancestors, parent.ancestors)
+		}
+
+		item.ancestorFee = item.fee + item.feeDelta
+		item.ancestorWeight = item.weight
+		item.ancestorSigOpCost = item.sigOpCost
+		for _, ancestor := range item.ancestors {
+			item.ancestorFee += ancestor.fee + ancestor.feeDelta
+			item.ancestorWeight += ancestor.weight
+			item.ancestorSigOpCost += ancestor.sigOpCost
+
+			if ancestor.descendants == nil {
+				ancestor.descendants = make(
+					map[chainhash.Hash]*txPrioItem)
+			}
+			ancestor.descendants[*item.tx.Hash()] = item
+		}
+		item.setPackageFeePerKB()
+	}
+	for _, item := range items {
+		link(item)
+	}
+}
+
+// markItemIncluded removes the passed item, whose transaction has been
+// included in the block, from the passed items and priority queue.  Since
+// the transaction is no longer part of the ancestor packages of its
+// descendants, their totals are updated and they are re-prioritized
+// accordingly.  The descendants which no longer depend on any transaction
+// which hasn't been included are returned.
+func markItemIncluded(items map[chainhash.Hash]*txPrioItem,
+	pq *txPriorityQueue, item *txPrioItem) []*txPrioItem {
+
+	hash := *item.tx.Hash()
+	if item.index >= 0 {
+		heap.Remove(pq, item.index)
+	}
+	delete(items, hash)
+
+	var ready []*txPrioItem
+	for _, descendant := range item.descendants {
+		delete(descendant.ancestors, hash)
+		descendant.ancestorFee -= item.fee + item.feeDelta
+		descendant.ancestorWeight -= item.weight
+		descendant.ancestorSigOpCost -= item.sigOpCost
+		descendant.setPackageFeePerKB()
+		if descendant.index >= 0 {
+			heap.Fix(pq, descendant.index)
+		}
+
+		if _, ok := descendant.dependsOn[hash]; ok {
+			delete(descendant.dependsOn, hash)
+			if len(descendant.dependsOn) == 0 {
+				ready = append(ready, descendant)
+			}
+		}
+	}
+	return ready
+}
+
+// removeItemWithDescendants removes the passed item, whose transaction can't
+// be included in the block, from the passed items and priority queue along
+// with its descendants, which can't be included without it.
+func removeItemWithDescendants(items map[chainhash.Hash]*txPrioItem,
+	pq *txPriorityQueue, item *txPrioItem) {
+
+	removed := make([]*txPrioItem, 0, len(item.descendants)+1)
+	removed = append(removed, item)
+	for _, descendant := range item.descendants {
+		removed = append(removed, descendant)
+	}
+
+	for _, item := range removed {
+		hash := *item.tx.Hash()
+		for _, ancestor := range item.ancestors {
+			delete(ancestor.descendants, hash)
+		}
+		if item.index >= 0 {
+			heap.Remove(pq, item.index)
+		}
+		delete(items, hash)
+	}
+}
+
 // BlockTemplate houses a block that has yet to be solved along with additional
 // details about the fees and the number of signature operations for each
 // transaction in the block.
@@ -391,32 +570,38 @@ func NewBlkTmplGenerator(policy *Policy, params *chaincfg.Params,
 // factors.  First, each transaction has a priority calculated based on its
 // value, age of inputs, and size.  Transactions which consist of larger
 // amounts, older inputs, and small sizes have the highest priority.  Second, a
-// fee per kilobyte is calculated for each transaction.  Transactions with a
-// higher fee per kilobyte are preferred.  Finally, the block generation related
-// policy settings are all taken into account.
+// fee per kilobyte is calculated for the ancestor package of each transaction,
+// which is the transaction along with all of the transactions in the source
+// pool it depends on that haven't been included in the block yet.  Packages
+// with a higher fee per kilobyte are preferred, which allows a transaction
+// paying a high fee to pull in the low-fee transactions it depends on (child
+// pays for parent).  The fee deltas the transactions were prioritized with are
+// added to their fees for this purpose, while the fees of the template only
+// include the fees the transactions actually pay.  Finally, the block
+// generation related policy settings are all taken into account.
 //
-// Transactions which only spend outputs from other transactions already in the
-// block chain are immediately added to a priority queue which either
-// prioritizes based on the priority (then fee per kilobyte) or the fee per
-// kilobyte (then priority) depending on whether or not the BlockPrioritySize
-// policy setting allots space for high-priority transactions.  Transactions
-// which spend outputs from other transactions in the source pool are added to a
-// dependency map so they can be added to the priority queue once the
-// transactions they depend on have been included.
+// When the BlockPrioritySize policy setting allots space for high-priority
+// transactions, it is first filled with the transactions with the highest
+// priority (then ancestor fee per kilobyte) which only depend on transactions
+// already in the block chain or already included in the block.
 //
 // Once the high-priority area (if configured) has been filled with
 // transactions, or the priority falls below what is considered high-priority,
-// the priority queue is updated to prioritize by fees per kilobyte (then
-// priority).
+// the remaining transactions are selected by the fees per kilobyte of their
+// ancestor packages (then priority).  Each time a package is selected, all of
+// its transactions are included in dependency order, and the packages of
+// their descendants are updated to no longer include them so they are
+// re-prioritized accordingly.
 //
-// When the fees per kilobyte drop below the TxMinFreeFee policy setting, the
-// transaction will be skipped unless the BlockMinSize policy setting is
-// nonzero, in which case the block will be filled with the low-fee/free
+// When the ancestor fees per kilobyte drop below the TxMinFreeFee policy
+// setting, the package will be skipped unless the BlockMinSize policy setting
+// is nonzero, in which case the block will be filled with the low-fee/free
 // transactions until the block size reaches that minimum size.
 //
-// Any transactions which would cause the block to exceed the BlockMaxSize
-// policy setting, exceed the maximum allowed signature operations per block, or
-// otherwise cause the block to be invalid are skipped.
+// Any packages which would cause the block to exceed the BlockMaxWeight policy
+// setting or the maximum allowed signature operations cost per block are
+// skipped, as well as transactions which would otherwise cause the block to be
+// invalid along with the transactions depending on them.
 //
 // Given the above, a block generated by this function is of the following form:
 //
@@ -430,8 +615,8 @@ func NewBlkTmplGenerator(policy *Policy, params *chaincfg.Params,
 //	|                                   |   |
 //	|                                   |   |
 //	|                                   |   |--- policy.BlockMaxSize
-//	|  Transactions prioritized by fee  |   |
-//	|  until <= policy.TxMinFreeFee     |   |
+//	|  Packages prioritized by ancestor |   |
+//	|  fee until <= policy.TxMinFreeFee |   |
 //	|                                   |   |
 //	|                                   |   |
 //	|                                   |   |
@@ -465,15 +650,11 @@ func (g *BlkTmplGenerator) NewBlockTemplate(payToAddress btcutil.Address) (*Bloc
 	}
 	coinbaseSigOpCost := int64(blockchain.CountSigOps(coinbaseTx)) * blockchain.WitnessScaleFactor
 
-	// Get the current source transactions and create a priority queue to
-	// hold the transactions which are ready for inclusion into a block
-	// along with some priority related and fee metadata.  Reserve the same
-	// number of items that are available for the priority queue.  Also,
-	// choose the initial sort order for the priority queue based on whether
-	// or not there is an area allocated for high-priority transactions.
+	// Get the current source transactions.  The high-priority area of the
+	// block is only filled when the BlockPrioritySize policy setting
+	// allots space for it.
 	sourceTxns := g.txSource.MiningDescs()
 	sortedByFee := g.policy.BlockPrioritySize == 0
-	priorityQueue := newTxPriorityQueue(len(sourceTxns), sortedByFee)
 
 	// Create a slice to hold the transactions to be included in the
 	// generated block with reserved space.  Also create a utxo view to
@@ -483,13 +664,6 @@ func (g *BlkTmplGenerator) NewBlockTemplate(payToAddress btcutil.Address) (*Bloc
 	blockTxns = append(blockTxns, coinbaseTx)
 	blockUtxos := blockchain.NewUtxoViewpoint()
 
-	// dependers is used to track transactions which depend on another
-	// transaction in the source pool.  This, in conjunction with the
-	// dependsOn map kept with each dependent transaction helps quickly
-	// determine which dependent transactions are now eligible for inclusion
-	// in the block once each transaction has been included.
-	dependers := make(map[chainhash.Hash]map[chainhash.Hash]*txPrioItem)
-
 	// Create slices to hold the fees and number of signature operations
 	// for each of the selected transactions and add an entry for the
 	// coinbase.  This allows the code below to simply append details about
@@ -501,9 +675,24 @@ func (g *BlkTmplGenerator) NewBlockTemplate(payToAddress btcutil.Address) (*Bloc
 	txFees = append(txFees, -1) // Updated once known
 	txSigOpCosts = append(txSigOpCosts, coinbaseSigOpCost)
 
+	// Query the version bits state to see if segwit has been activated, if
+	// so then this means that we'll include any transactions with witness
+	// data in the mempool, and also add the witness commitment as an
+	// OP_RETURN output in the coinbase transaction.
+	segwitState, err := g.chain.ThresholdState(chaincfg.DeploymentSegwit)
+	if err != nil {
+		return nil, err
+	}
+	segwitActive := segwitState == blockchain.ThresholdActive
+
 	log.Debugf("Considering %d transactions for inclusion to new block",
 		len(sourceTxns))
 
+	// items holds the transactions which are candidates for inclusion in
+	// the block keyed by their hash.  Transactions are removed from it
+	// once they are included or known to be unable to be included.
+	items := make(map[chainhash.Hash]*txPrioItem, len(sourceTxns))
+
 mempoolLoop:
 	for _, txDesc := range sourceTxns {
 		// A block can't have more than one coinbase or contain
@@ -520,6 +709,14 @@ mempoolLoop:
 			continue
 		}
 
+		// If segregated witness has not been activated yet, then we
+		// shouldn't include any witness transactions in the block.
+		if !segwitActive && tx.HasWitness() {
+			log.Tracef("Skipping witness tx %s since segwit is not "+
+				"active", tx.Hash())
+			continue
+		}
+
 		// Fetch all of the utxos referenced by this transaction.
 		// NOTE: This intentionally does not fetch inputs from the
 		// mempool since a transaction which depends on other
@@ -535,7 +732,7 @@ mempoolLoop:
 		// Setup dependencies for any transactions which reference
 		// other transactions in the mempool so they can be properly
 		// ordered below.
-		prioItem := &txPrioItem{tx: tx}
+		prioItem := &txPrioItem{tx: tx, index: -1}
 		for _, txIn := range tx.MsgTx().TxIn {
 			originHash := &txIn.PreviousOutPoint.Hash
 			entry := utxos.LookupEntry(txIn.PreviousOutPoint)
@@ -551,18 +748,11 @@ mempoolLoop:
 				// The transaction is referencing another
 				// transaction in the source pool, so setup an
 				// ordering dependency.
-				deps, exists := dependers[*originHash]
-				if !exists {
-					deps = make(map[chainhash.Hash]*txPrioItem)
-					dependers[*originHash] = deps
-				}
-				deps[*prioItem.tx.Hash()] = prioItem
 				if prioItem.dependsOn == nil {
 					prioItem.dependsOn = make(
 						map[chainhash.Hash]struct{})
 				}
 				prioItem.dependsOn[*originHash] = struct{}{}
-
 			}
 		}
 
@@ -572,15 +762,10 @@ mempoolLoop:
 		prioItem.priority = CalcPriority(tx.MsgTx(), utxos,
 			nextBlockHeight)
 
-		// Calculate the fee in Satoshi/kB.
-		prioItem.feePerKB = txDesc.FeePerKB
 		prioItem.fee = txDesc.Fee
-
-		// Add the transaction to the priority queue to mark it ready
-		// for inclusion in the block unless it has dependencies.
-		if prioItem.dependsOn == nil {
-			heap.Push(priorityQueue, prioItem)
-		}
+		prioItem.feeDelta = txDesc.FeeDelta
+		prioItem.weight = blockchain.GetTransactionWeight(tx)
+		items[*tx.Hash()] = prioItem
 
 		// Merge the referenced outputs from the input transactions to
 		// this transaction into the block utxo view.  This allows the
@@ -588,8 +773,33 @@ mempoolLoop:
 		mergeUtxoView(blockUtxos, utxos)
 	}
 
-	log.Tracef("Priority queue len %d, dependers len %d",
-		priorityQueue.Len(), len(dependers))
+	// Calculate the signature operation cost of each transaction upfront
+	// so the cost of whole ancestor packages is known before including
+	// them.  Since transactions may spend the outputs of other
+	// transactions in the source pool, those outputs are added to a
+	// separate view for this purpose.
+	sigOpUtxos := blockchain.NewUtxoViewpoint()
+	mergeUtxoView(sigOpUtxos, blockUtxos)
+	for _, prioItem := range items {
+		sigOpUtxos.AddTxOuts(prioItem.tx, nextBlockHeight)
+	}
+	for hash, prioItem := range items {
+		sigOpCost, err := blockchain.GetSigOpCost(prioItem.tx, false,
+			sigOpUtxos, true, segwitActive)
+		if err != nil {
+			log.Tracef("Skipping tx %s due to error in "+
+				"GetSigOpCost: %v", hash, err)
+			delete(items, hash)
+			continue
+		}
+		prioItem.sigOpCost = int64(sigOpCost)
+	}
+
+	// Determine the ancestor package of each transaction, which also
+	// removes the transactions depending on ones which were skipped.
+	linkTxPackages(items)
+
+	log.Tracef("%d transactions are candidates for inclusion", len(items))
 
 	// The starting block size is the size of the block header plus the max
 	// possible transaction count size, plus the size of the coinbase
@@ -599,190 +809,258 @@ mempoolLoop:
 	blockSigOpCost := coinbaseSigOpCost
 	totalFees := int64(0)
 
-	// Query the version bits state to see if segwit has been activated, if
-	// so then this means that we'll include any transactions with witness
-	// data in the mempool, and also add the witness commitment as an
-	// OP_RETURN output in the coinbase transaction.
-	segwitState, err := g.chain.ThresholdState(chaincfg.DeploymentSegwit)
-	if err != nil {
-		return nil, err
+	// Once a transaction bearing witness data is included, a witness
+	// commitment needs to be included in the coinbase transaction as well.
+	// Therefore, account for the additional weight within the block with
+	// a model coinbase tx with a witness commitment, which is the
+	// difference of the transaction before and after the addition of the
+	// commitment.
+	coinbaseCopy := btcutil.NewTx(coinbaseTx.MsgTx().Copy())
+	coinbaseCopy.MsgTx().TxIn[0].Witness = [][]byte{
+		bytes.Repeat([]byte("a"), blockchain.CoinbaseWitnessDataLen),
 	}
-	segwitActive := segwitState == blockchain.ThresholdActive
+	coinbaseCopy.MsgTx().AddTxOut(&wire.TxOut{
+		PkScript: bytes.Repeat([]byte("a"),
+			blockchain.CoinbaseWitnessPkScriptLength),
+	})
+	commitmentWeight := uint32(blockchain.GetTransactionWeight(coinbaseCopy) -
+		blockchain.GetTransactionWeight(coinbaseTx))
 
 	witnessIncluded := false
 
-	// Choose which transactions make it into the block.
-	for priorityQueue.Len() > 0 {
-		// Grab the highest priority (or highest fee per kilobyte
-		// depending on the sort order) transaction.
-		prioItem := heap.Pop(priorityQueue).(*txPrioItem)
+	// includeTx adds the transaction of the passed item to the block once
+	// its inputs pass all of the necessary preconditions, and returns
+	// whether it was added.
+	includeTx := func(prioItem *txPrioItem) bool {
 		tx := prioItem.tx
+		_, err := blockchain.CheckTransactionInputs(tx, nextBlockHeight,
+			blockUtxos, g.chainParams)
+		if err != nil {
+			log.Tracef("Skipping tx %s due to error in "+
+				"CheckTransactionInputs: %v", tx.Hash(), err)
+			return false
+		}
+		err = blockchain.ValidateTransactionScripts(tx, blockUtxos,
+			txscript.StandardVerifyFlags, g.sigCache,
+			g.hashCache)
+		if err != nil {
+			log.Tracef("Skipping tx %s due to error in "+
+				"ValidateTransactionScripts: %v", tx.Hash(), err)
+			return false
+		}
 
-		switch {
-		// If segregated witness has not been activated yet, then we
-		// shouldn't include any witness transactions in the block.
-		case !segwitActive && tx.HasWitness():
-			continue
+		// Spend the transaction inputs in the block utxo view and add
+		// an entry for it to ensure any transactions which reference
+		// this one have it available as an input and can ensure they
+		// aren't double spending.
+		spendTransaction(blockUtxos, tx, nextBlockHeight)
+
+		// Keep track of if we've included a transaction with witness
+		// data or not.  If so, then we'll need to include the witness
+		// commitment as the last output in the coinbase transaction.
+		if !witnessIncluded && tx.HasWitness() {
+			blockWeight += commitmentWeight
+			witnessIncluded = true
+		}
 
-		// Otherwise, Keep track of if we've included a transaction
-		// with witness data or not. If so, then we'll need to include
-		// the witness commitment as the last output in the coinbase
-		// transaction.
-		case segwitActive && !witnessIncluded && tx.HasWitness():
-			// If we're about to include a transaction bearing
-			// witness data, then we'll also need to include a
-			// witness commitment in the coinbase transaction.
-			// Therefore, we account for the additional weight
-			// within the block with a model coinbase tx with a
-			// witness commitment.
-			coinbaseCopy := btcutil.NewTx(coinbaseTx.MsgTx().Copy())
-			coinbaseCopy.MsgTx().TxIn[0].Witness = [][]byte{
-				bytes.Repeat([]byte("a"),
-					blockchain.CoinbaseWitnessDataLen),
+		// Add the transaction to the block, increment counters, and
+		// save the fees and signature operation counts to the block
+		// template.
+		blockTxns = append(blockTxns, tx)
+		blockWeight += uint32(prioItem.weight)
+		blockSigOpCost += prioItem.sigOpCost
+		totalFees += prioItem.fee
+		txFees = append(txFees, prioItem.fee)
+		txSigOpCosts = append(txSigOpCosts, prioItem.sigOpCost)
+
+		log.Tracef("Adding tx %s (priority %.2f, feePerKB %d)",
+			tx.Hash(), prioItem.priority, prioItem.feePerKB)
+
+		return true
+	}
+
+	// Fill the high-priority area of the block, if configured, with the
+	// transactions with the highest priority.  Transactions are ready for
+	// inclusion once all of the transactions they depend on have been
+	// included.
+	if !sortedByFee {
+		priorityQueue := newTxPriorityQueue(len(items), false)
+		for _, prioItem := range items {
+			if len(prioItem.dependsOn) == 0 {
+				heap.Push(priorityQueue, prioItem)
 			}
-			coinbaseCopy.MsgTx().AddTxOut(&wire.TxOut{
-				PkScript: bytes.Repeat([]byte("a"),
-					blockchain.CoinbaseWitnessPkScriptLength),
-			})
+		}
 
-			// In order to accurately account for the weight
-			// addition due to this coinbase transaction, we'll add
-			// the difference of the transaction before and after
-			// the addition of the commitment to the block weight.
-			weightDiff := blockchain.GetTransactionWeight(coinbaseCopy) -
-				blockchain.GetTransactionWeight(coinbaseTx)
+		for priorityQueue.Len() > 0 {
+			// Grab the highest priority transaction.
+			prioItem := heap.Pop(priorityQueue).(*txPrioItem)
+			tx := prioItem.tx
 
-			blockWeight += uint32(weightDiff)
+			// Enforce maximum block size.  Also check for overflow.
+			txWeight := uint32(prioItem.weight)
+			if !witnessIncluded && tx.HasWitness() {
+				txWeight += commitmentWeight
+			}
+			blockPlusTxWeight := blockWeight + txWeight
+			if blockPlusTxWeight < blockWeight ||
+				blockPlusTxWeight >= g.policy.BlockMaxWeight {
 
-			witnessIncluded = true
+				log.Tracef("Skipping tx %s because it would "+
+					"exceed the max block weight", tx.Hash())
+				continue
+			}
+
+			// Enforce maximum signature operation cost per block.
+			// Also check for overflow.
+			if blockSigOpCost+prioItem.sigOpCost < blockSigOpCost ||
+				blockSigOpCost+prioItem.sigOpCost > blockchain.MaxBlockSigOpsCost {
+
+				log.Tracef("Skipping tx %s because it would "+
+					"exceed the maximum sigops per block",
+					tx.Hash())
+				continue
+			}
+
+			// Prioritize by ancestor fees per kilobyte once the block
+			// is larger than the priority size or there are no more
+			// high-priority transactions.
+			if blockPlusTxWeight >= g.policy.BlockPrioritySize ||
+				prioItem.priority <= MinHighPriority {
+
+				log.Tracef("Switching to sort by ancestor fees "+
+					"per kilobyte blockSize %d >= "+
+					"BlockPrioritySize %d || priority %.2f <= "+
+					"minHighPriority %.2f", blockPlusTxWeight,
+					g.policy.BlockPrioritySize,
+					prioItem.priority, MinHighPriority)
+
+				sortedByFee = true
+
+				// Leave the transaction to be re-prioritized by
+				// fees if it won't fit into the high-priority
+				// section or the priority is too low.
+				// Otherwise this transaction will be the final
+				// one in the high-priority section, so just
+				// fall though to the code below so it is added
+				// now.
+				if blockPlusTxWeight > g.policy.BlockPrioritySize ||
+					prioItem.priority < MinHighPriority {
+
+					break
+				}
+			}
+
+			if !includeTx(prioItem) {
+				logSkippedDeps(tx, prioItem.descendants)
+				removeItemWithDescendants(items, priorityQueue,
+					prioItem)
+				continue
+			}
+
+			// Add transactions which depend on this one (and also
+			// do not have any other unsatisified dependencies) to
+			// the priority queue.
+			ready := markItemIncluded(items, priorityQueue, prioItem)
+			for _, item := range ready {
+				heap.Push(priorityQueue, item)
+			}
+
+			if sortedByFee {
+				break
+			}
 		}
 
-		// Grab any transactions which depend on this one.
-		deps := dependers[*tx.Hash()]
+		// The items left in the priority queue are prioritized by fees
+		// below.
+		for _, prioItem := range items {
+			prioItem.index = -1
+		}
+	}
+
+	// Fill the rest of the block with the transactions whose ancestor
+	// packages pay the highest fees per kilobyte.  Each time a package is
+	// selected, the transaction is included along with all of its
+	// ancestors which haven't been included yet, and the packages of their
+	// descendants are updated to no longer count them.
+	feeQueue := newTxPriorityQueue(len(items), true)
+	for _, prioItem := range items {
+		heap.Push(feeQueue, prioItem)
+	}
+	for feeQueue.Len() > 0 {
+		// Grab the transaction with the highest ancestor fee per
+		// kilobyte.
+		prioItem := heap.Pop(feeQueue).(*txPrioItem)
+		tx := prioItem.tx
 
-		// Enforce maximum block size.  Also check for overflow.
-		txWeight := uint32(blockchain.GetTransactionWeight(tx))
-		blockPlusTxWeight := blockWeight + txWeight
-		if blockPlusTxWeight < blockWeight ||
-			blockPlusTxWeight >= g.policy.BlockMaxWeight {
+		// Order the package so every transaction comes after the
+		// transactions it depends on, which is the case when sorted by
+		// the number of ancestors since a transaction has more
+		// ancestors than any of its ancestors.
+		pkg := make([]*txPrioItem, 0, len(prioItem.ancestors)+1)
+		pkgHasWitness := tx.HasWitness()
+		for _, ancestor := range prioItem.ancestors {
+			pkg = append(pkg, ancestor)
+			pkgHasWitness = pkgHasWitness || ancestor.tx.HasWitness()
+		}
+		sort.Slice(pkg, func(i, j int) bool {
+			return len(pkg[i].ancestors) < len(pkg[j].ancestors)
+		})
+		pkg = append(pkg, prioItem)
+
+		// Enforce maximum block size.  Also check for overflow.  The
+		// transaction may still be included later as an ancestor of
+		// another package, so it's only skipped.
+		pkgWeight := uint32(prioItem.ancestorWeight)
+		if !witnessIncluded && pkgHasWitness {
+			pkgWeight += commitmentWeight
+		}
+		blockPlusPkgWeight := blockWeight + pkgWeight
+		if blockPlusPkgWeight < blockWeight ||
+			blockPlusPkgWeight >= g.policy.BlockMaxWeight {
 
-			log.Tracef("Skipping tx %s because it would exceed "+
-				"the max block weight", tx.Hash())
-			logSkippedDeps(tx, deps)
+			log.Tracef("Skipping tx %s because its package would "+
+				"exceed the max block weight", tx.Hash())
 			continue
 		}
 
 		// Enforce maximum signature operation cost per block.  Also
 		// check for overflow.
-		sigOpCost, err := blockchain.GetSigOpCost(tx, false,
-			blockUtxos, true, segwitActive)
-		if err != nil {
-			log.Tracef("Skipping tx %s due to error in "+
-				"GetSigOpCost: %v", tx.Hash(), err)
-			logSkippedDeps(tx, deps)
-			continue
-		}
-		if blockSigOpCost+int64(sigOpCost) < blockSigOpCost ||
-			blockSigOpCost+int64(sigOpCost) > blockchain.MaxBlockSigOpsCost {
-			log.Tracef("Skipping tx %s because it would "+
+		blockPlusPkgSigOpCost := blockSigOpCost + prioItem.ancestorSigOpCost
+		if blockPlusPkgSigOpCost < blockSigOpCost ||
+			blockPlusPkgSigOpCost > blockchain.MaxBlockSigOpsCost {
+
+			log.Tracef("Skipping tx %s because its package would "+
 				"exceed the maximum sigops per block", tx.Hash())
-			logSkippedDeps(tx, deps)
 			continue
 		}
 
 		// Skip free transactions once the block is larger than the
 		// minimum block size.
-		if sortedByFee &&
-			prioItem.feePerKB < int64(g.policy.TxMinFreeFee) &&
-			blockPlusTxWeight >= g.policy.BlockMinWeight {
+		if prioItem.feePerKB < int64(g.policy.TxMinFreeFee) &&
+			blockPlusPkgWeight >= g.policy.BlockMinWeight {
 
-			log.Tracef("Skipping tx %s with feePerKB %d "+
+			log.Tracef("Skipping tx %s with package feePerKB %d "+
 				"< TxMinFreeFee %d and block weight %d >= "+
 				"minBlockWeight %d", tx.Hash(), prioItem.feePerKB,
-				g.policy.TxMinFreeFee, blockPlusTxWeight,
+				g.policy.TxMinFreeFee, blockPlusPkgWeight,
 				g.policy.BlockMinWeight)
-			logSkippedDeps(tx, deps)
 			continue
 		}
 
-		// Prioritize by fee per kilobyte once the block is larger than
-		// the priority size or there are no more high-priority
-		// transactions.
-		if !sortedByFee && (blockPlusTxWeight >= g.policy.BlockPrioritySize ||
-			prioItem.priority <= MinHighPriority) {
-
-			log.Tracef("Switching to sort by fees per "+
-				"kilobyte blockSize %d >= BlockPrioritySize "+
-				"%d || priority %.2f <= minHighPriority %.2f",
-				blockPlusTxWeight, g.policy.BlockPrioritySize,
-				prioItem.priority, MinHighPriority)
-
-			sortedByFee = true
-			priorityQueue.SetLessFunc(txPQByFee)
-
-			// Put the transaction back into the priority queue and
-			// skip it so it is re-priortized by fees if it won't
-			// fit into the high-priority section or the priority
-			// is too low.  Otherwise this transaction will be the
-			// final one in the high-priority section, so just fall
-			// though to the code below so it is added now.
-			if blockPlusTxWeight > g.policy.BlockPrioritySize ||
-				prioItem.priority < MinHighPriority {
-
-				heap.Push(priorityQueue, prioItem)
+		// Add the transactions of the package to the block.  A
+		// transaction which fails validation can't be included along
+		// with its descendants, which may include the rest of the
+		// package.
+		for _, item := range pkg {
+			if _, ok := items[*item.tx.Hash()]; !ok {
 				continue
 			}
-		}
-
-		// Ensure the transaction inputs pass all of the necessary
-		// preconditions before allowing it to be added to the block.
-		_, err = blockchain.CheckTransactionInputs(tx, nextBlockHeight,
-			blockUtxos, g.chainParams)
-		if err != nil {
-			log.Tracef("Skipping tx %s due to error in "+
-				"CheckTransactionInputs: %v", tx.Hash(), err)
-			logSkippedDeps(tx, deps)
-			continue
-		}
-		err = blockchain.ValidateTransactionScripts(tx, blockUtxos,
-			txscript.StandardVerifyFlags, g.sigCache,
-			g.hashCache)
-		if err != nil {
-			log.Tracef("Skipping tx %s due to error in "+
-				"ValidateTransactionScripts: %v", tx.Hash(), err)
-			logSkippedDeps(tx, deps)
-			continue
-		}
-
-		// Spend the transaction inputs in the block utxo view and add
-		// an entry for it to ensure any transactions which reference
-		// this one have it available as an input and can ensure they
-		// aren't double spending.
-		spendTransaction(blockUtxos, tx, nextBlockHeight)
-
-		// Add the transaction to the block, increment counters, and
-		// save the fees and signature operation counts to the block
-		// template.
-		blockTxns = append(blockTxns, tx)
-		blockWeight += txWeight
-		blockSigOpCost += int64(sigOpCost)
-		totalFees += prioItem.fee
-		txFees = append(txFees, prioItem.fee)
-		txSigOpCosts = append(txSigOpCosts, int64(sigOpCost))
-
-		log.Tracef("Adding tx %s (priority %.2f, feePerKB %.2f)",
-			prioItem.tx.Hash(), prioItem.priority, prioItem.feePerKB)
-
-		// Add transactions which depend on this one (and also do not
-		// have any other unsatisified dependencies) to the priority
-		// queue.
-		for _, item := range deps {
-			// Add the transaction to the priority queue if there
-			// are no more dependencies after this one.
-			delete(item.dependsOn, *tx.Hash())
-			if len(item.dependsOn) == 0 {
-				heap.Push(priorityQueue, item)
+			if !includeTx(item) {
+				logSkippedDeps(item.tx, item.descendants)
+				removeItemWithDescendants(items, feeQueue, item)
+				continue
 			}
+			markItemIncluded(items, feeQueue, item)
 		}
 	}
 
@@ -10,6 +10,8 @@ import (
 	"testing"
 
 	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	"github.com/bynil/btcd/wire"
 )
 
 // TestTxFeePrioHeap ensures the priority queue for transaction fees and
@@ -108,3 +110,160 @@ func TestTxFeePrioHeap(t *testing.T) {
 		highest = prioItem
 	}
 }
+
+// TestTxPackages ensures the ancestor packages of transactions are linked and
+// prioritized by their fee rate as expected, and that they are updated as
+// their transactions are included in or removed from the block.
+func TestTxPackages(t *testing.T) {
+	// newItem returns an item for a unique transaction with the passed fee
+	// and weight which depends on the passed transactions.
+	var lockTime uint32
+	newItem := func(fee, weight int64, parents ...*txPrioItem) *txPrioItem {
+		lockTime++
+		msgTx := wire.NewMsgTx(wire.TxVersion)
+		msgTx.LockTime = lockTime
+		item := &txPrioItem{
+			tx:        btcutil.NewTx(msgTx),
+			fee:       fee,
+			weight:    weight,
+			sigOpCost: 4,
+			index:     -1,
+		}
+		for _, parent := range parents {
+			if item.dependsOn == nil {
+				item.dependsOn = make(map[chainhash.Hash]struct{})
+			}
+			item.dependsOn[*parent.tx.Hash()] = struct{}{}
+		}
+		return item
+	}
+
+	// A low-fee parent with a high-fee child, an unrelated transaction
+	// paying a fee rate in between, and a chain depending on a transaction
+	// which isn't available.
+	parent := newItem(100, 1000)
+	child := newItem(10000, 1000, parent)
+	unrelated := newItem(2500, 1000)
+	missing := newItem(5000, 1000)
+	orphan := newItem(5000, 1000, missing)
+	orphanChild := newItem(5000, 1000, orphan)
+	items := make(map[chainhash.Hash]*txPrioItem)
+	for _, item := range []*txPrioItem{parent, child, unrelated, orphan,
+		orphanChild} {
+
+		items[*item.tx.Hash()] = item
+	}
+
+	linkTxPackages(items)
+	if len(items) != 3 {
+		t.Fatalf("got %d items after linking, want 3", len(items))
+	}
+	if _, ok := items[*orphan.tx.Hash()]; ok {
+		t.Fatal("item depending on unavailable transaction was kept")
+	}
+	if _, ok := child.ancestors[*parent.tx.Hash()]; !ok ||
+		len(child.ancestors) != 1 {
+
+		t.Fatalf("got ancestors %v for child, want the parent",
+			child.ancestors)
+	}
+	if _, ok := parent.descendants[*child.tx.Hash()]; !ok ||
+		len(parent.descendants) != 1 {
+
+		t.Fatalf("got descendants %v for parent, want the child",
+			parent.descendants)
+	}
+	if child.ancestorFee != 10100 || child.ancestorWeight != 2000 ||
+		child.ancestorSigOpCost != 8 || child.feePerKB != 20200 {
+
+		t.Fatalf("got ancestor fee %d, weight %d, sigop cost %d and "+
+			"fee per kB %d for child, want 10100, 2000, 8 and "+
+			"20200", child.ancestorFee, child.ancestorWeight,
+			child.ancestorSigOpCost, child.feePerKB)
+	}
+
+	// The child lifts its parent above the unrelated transaction, even
+	// though the parent pays a lower fee rate on its own.
+	feeQueue := newTxPriorityQueue(len(items), true)
+	for _, item := range items {
+		heap.Push(feeQueue, item)
+	}
+	for i, item := range feeQueue.items {
+		if item.index != i {
+			t.Fatalf("got index %d for item at index %d",
+				item.index, i)
+		}
+	}
+	if item := feeQueue.items[0]; item != child {
+		t.Fatalf("got tx %v with fee per kB %d as the best package, "+
+			"want the child", item.tx.Hash(), item.feePerKB)
+	}
+
+	// Including the parent leaves the child on its own, which is ready
+	// for inclusion and remains the best package.
+	ready := markItemIncluded(items, feeQueue, parent)
+	if len(ready) != 1 || ready[0] != child {
+		t.Fatalf("got ready items %v, want the child", ready)
+	}
+	if parent.index != -1 || feeQueue.Len() != 2 || len(items) != 2 {
+		t.Fatalf("included parent was not removed")
+	}
+	if child.ancestorFee != 10000 || child.ancestorWeight != 1000 ||
+		child.ancestorSigOpCost != 4 || child.feePerKB != 40000 ||
+		len(child.ancestors) != 0 || len(child.dependsOn) != 0 {
+
+		t.Fatalf("got ancestor fee %d, weight %d, sigop cost %d and "+
+			"fee per kB %d for child after including the parent, "+
+			"want 10000, 1000, 4 and 40000", child.ancestorFee,
+			child.ancestorWeight, child.ancestorSigOpCost,
+			child.feePerKB)
+	}
+	if item := heap.Pop(feeQueue).(*txPrioItem); item != child {
+		t.Fatalf("got tx %v as the best package, want the child",
+			item.tx.Hash())
+	}
+
+	// Removing a transaction which can't be included also removes its
+	// descendants.
+	items = map[chainhash.Hash]*txPrioItem{
+		*parent.tx.Hash(): parent,
+		*child.tx.Hash():  child,
+	}
+	parent.ancestors, child.ancestors = nil, nil
+	parent.descendants = nil
+	child.dependsOn = map[chainhash.Hash]struct{}{
+		*parent.tx.Hash(): {},
+	}
+	linkTxPackages(items)
+	feeQueue = newTxPriorityQueue(len(items), true)
+	heap.Push(feeQueue, parent)
+	heap.Push(feeQueue, child)
+	removeItemWithDescendants(items, feeQueue, parent)
+	if len(items) != 0 || feeQueue.Len() != 0 {
+		t.Fatalf("got %d items and %d queued items after removing "+
+			"the parent, want none", len(items), feeQueue.Len())
+	}
+
+	// The fee delta a transaction was prioritized with counts towards the
+	// fee rate of the packages it is part of.
+	prioritized := newItem(0, 1000)
+	prioritized.feeDelta = 10000
+	prioritizedChild := newItem(100, 1000, prioritized)
+	items = map[chainhash.Hash]*txPrioItem{
+		*prioritized.tx.Hash():      prioritized,
+		*prioritizedChild.tx.Hash(): prioritizedChild,
+	}
+	linkTxPackages(items)
+	if prioritized.feePerKB != 40000 ||
+		prioritizedChild.ancestorFee != 10100 {
+
+		t.Fatalf("got fee per kB %d and child ancestor fee %d for "+
+			"prioritized tx, want 40000 and 10100",
+			prioritized.feePerKB, prioritizedChild.ancestorFee)
+	}
+	markItemIncluded(items, nil, prioritized)
+	if prioritizedChild.ancestorFee != 100 {
+		t.Fatalf("got child ancestor fee %d after including the "+
+			"prioritized tx, want 100", prioritizedChild.ancestorFee)
+	}
+}
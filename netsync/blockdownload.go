@@ -0,0 +1,436 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netsync
+
+import (
+	"container/list"
+	"sync/atomic"
+	"time"
+
+	"github.com/bynil/btcd/blockchain"
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	peerpkg "github.com/bynil/btcd/peer"
+	"github.com/bynil/btcd/wire"
+)
+
+const (
+	// blockDownloadWindow is the maximum number of blocks past the block
+	// that is next to be processed which are requested during a
+	// headers-first sync.  Blocks may arrive out of order, so this also
+	// bounds the number of downloaded blocks held in memory while they
+	// wait for their parent.
+	blockDownloadWindow = 1024
+
+	// maxBlocksInFlightPerPeer is the maximum number of blocks requested
+	// from a single peer at once during a headers-first sync.
+	maxBlocksInFlightPerPeer = 16
+
+	// blockStallTimeout is the time after which the peer the next block to
+	// be processed was requested from is considered to be stalling the
+	// download when the download window is full.
+	blockStallTimeout = 5 * time.Second
+
+	// blockRequestTimeout is the time after which a peer which has not
+	// delivered any of the blocks requested from it is considered to be
+	// stalling the download.
+	blockRequestTimeout = time.Minute
+
+	// blockStallSampleInterval is the interval at which we check whether a
+	// peer is stalling the block download.
+	blockStallSampleInterval = time.Second
+)
+
+// canServeBlock returns whether the peer is expected to be able to serve the
+// block at the passed height.
+func canServeBlock(peer *peerpkg.Peer, height int32) bool {
+	lastBlock := peer.LastBlock()
+	if height > lastBlock {
+		return false
+	}
+
+	// Pruned peers only serve the blocks close to their tip.
+	services := peer.Services()
+	if !services.HasFlag(wire.SFNodeNetwork) &&
+		height <= lastBlock-wire.NodeNetworkLimitedBlockThreshold {
+
+		return false
+	}
+
+	return true
+}
+
+// blockDownloadPeer returns the sync candidate with the fewest blocks in flight
+// that is able to serve the block at the passed height and has room for more
+// requests.  It returns nil when there is no such peer.
+func (sm *SyncManager) blockDownloadPeer(height int32,
+	segwitActive bool) *peerpkg.Peer {
+
+	var bestPeer *peerpkg.Peer
+	var bestInFlight int
+	for peer, state := range sm.peerStates {
+		if !state.syncCandidate || !peer.Connected() {
+			continue
+		}
+		if segwitActive && !peer.IsWitnessEnabled() {
+			continue
+		}
+
+		// Don't request more blocks from a peer which recently stalled
+		// the download.
+		if time.Since(state.stallTime) < blockRequestTimeout {
+			continue
+		}
+
+		inFlight := len(state.requestedBlocks)
+		if inFlight >= maxBlocksInFlightPerPeer {
+			continue
+		}
+		if !canServeBlock(peer, height) {
+			continue
+		}
+
+		if bestPeer == nil || inFlight < bestInFlight {
+			bestPeer = peer
+			bestInFlight = inFlight
+		}
+	}
+
+	return bestPeer
+}
+
+// fetchHeaderBlocks requests the blocks of the headers within the block
+// download window which have not been requested yet.  The requests are spread
+// over all of the sync candidates that are able to serve the blocks.
+func (sm *SyncManager) fetchHeaderBlocks() {
+	if !sm.headersFirstMode {
+		return
+	}
+
+	// The front of the header list is the block that was most recently
+	// processed, so it is never fetched.
+	frontEl := sm.headerList.Front()
+	if frontEl == nil {
+		log.Warnf("fetchHeaderBlocks called with an empty header list")
+		return
+	}
+	windowEnd := frontEl.Value.(*headerNode).height + blockDownloadWindow
+
+	segwitActive, err := sm.chain.IsDeploymentActive(
+		chaincfg.DeploymentSegwit,
+	)
+	if err != nil {
+		log.Errorf("Unable to query for segwit soft-fork state: %v",
+			err)
+		return
+	}
+
+	now := time.Now()
+	windowFull := false
+	requests := make(map[*peerpkg.Peer]*wire.MsgGetData)
+	for e := frontEl.Next(); e != nil; e = e.Next() {
+		node := e.Value.(*headerNode)
+		if node.height > windowEnd {
+			windowFull = true
+			break
+		}
+
+		// Skip blocks that are already downloaded or requested.
+		if node.block != nil || node.peer != nil {
+			continue
+		}
+
+		iv := wire.NewInvVect(wire.InvTypeBlock, node.hash)
+		haveInv, err := sm.haveInventory(iv)
+		if err != nil {
+			log.Warnf("Unexpected failure when checking for "+
+				"existing inventory during header block "+
+				"fetch: %v", err)
+		}
+		if haveInv {
+			continue
+		}
+
+		// Stop once no peer is able to take the request.  Peers which
+		// can't serve this block can't serve the later ones either.
+		peer := sm.blockDownloadPeer(node.height, segwitActive)
+		if peer == nil {
+			break
+		}
+
+		sm.headerBlocks[*node.hash] = e
+		node.peer = peer
+		sm.queueBlockRequest(requests, peer, iv, now)
+	}
+
+	// Track how long the download has been limited by the window, so a
+	// peer holding up the next block can be detected.
+	switch {
+	case !windowFull:
+		sm.windowFullSince = time.Time{}
+	case sm.windowFullSince.IsZero():
+		sm.windowFullSince = now
+	}
+
+	sendBlockRequests(requests)
+}
+
+// queueBlockRequest records the block of the passed inventory vector as
+// requested from the peer and adds it to the getdata message for the peer.
+func (sm *SyncManager) queueBlockRequest(
+	requests map[*peerpkg.Peer]*wire.MsgGetData, peer *peerpkg.Peer,
+	iv *wire.InvVect, now time.Time) {
+
+	state := sm.peerStates[peer]
+	if len(state.requestedBlocks) == 0 {
+		state.lastBlockTime = now
+	}
+	state.requestedBlocks[iv.Hash] = struct{}{}
+	sm.requestedBlocks[iv.Hash] = struct{}{}
+
+	// If we're fetching from a witness enabled peer post-fork, then ensure
+	// that we receive all the witness data in the blocks.
+	if peer.IsWitnessEnabled() {
+		iv.Type = wire.InvTypeWitnessBlock
+	}
+
+	gdmsg, ok := requests[peer]
+	if !ok {
+		gdmsg = wire.NewMsgGetDataSizeHint(maxBlocksInFlightPerPeer)
+		requests[peer] = gdmsg
+	}
+	gdmsg.AddInvVect(iv)
+}
+
+// sendBlockRequests sends the queued getdata messages to their peers.
+func sendBlockRequests(requests map[*peerpkg.Peer]*wire.MsgGetData) {
+	for peer, gdmsg := range requests {
+		log.Debugf("Requesting %d blocks from peer %s",
+			len(gdmsg.InvList), peer)
+		peer.QueueMessage(gdmsg, nil)
+	}
+}
+
+// fetchSnapshotBlocks requests the blocks needed to validate the chain before
+// the base of a loaded UTXO set snapshot in the background.  They are only
+// requested once the chain is current, so they don't hold up the sync to the
+// tip.
+func (sm *SyncManager) fetchSnapshotBlocks() {
+	if sm.headersFirstMode || !sm.current() {
+		return
+	}
+
+	hashes := sm.chain.SnapshotBlocksNeeded(blockDownloadWindow)
+	if len(hashes) == 0 {
+		return
+	}
+
+	segwitActive, err := sm.chain.IsDeploymentActive(
+		chaincfg.DeploymentSegwit,
+	)
+	if err != nil {
+		log.Errorf("Unable to query for segwit soft-fork state: %v",
+			err)
+		return
+	}
+
+	now := time.Now()
+	requests := make(map[*peerpkg.Peer]*wire.MsgGetData)
+	for _, hash := range hashes {
+		if _, exists := sm.requestedBlocks[*hash]; exists {
+			continue
+		}
+		height, err := sm.chain.BlockHeightByHash(hash)
+		if err != nil {
+			log.Warnf("Unable to find the height of block %v: %v",
+				hash, err)
+			break
+		}
+
+		peer := sm.blockDownloadPeer(height, segwitActive)
+		if peer == nil {
+			break
+		}
+		iv := wire.NewInvVect(wire.InvTypeBlock, hash)
+		sm.queueBlockRequest(requests, peer, iv, now)
+	}
+
+	sendBlockRequests(requests)
+}
+
+// handleHeaderBlock handles a block that was requested during a headers-first
+// sync.  The block is held until all of the blocks before it are processed, so
+// blocks are processed in order regardless of which peer delivers them first.
+func (sm *SyncManager) handleHeaderBlock(node *headerNode,
+	block *btcutil.Block, peer *peerpkg.Peer, state *peerSyncState) {
+
+	blockHash := block.Hash()
+	delete(state.requestedBlocks, *blockHash)
+	delete(sm.requestedBlocks, *blockHash)
+	delete(sm.partialBlocks, *blockHash)
+	state.lastBlockTime = time.Now()
+
+	if node.block == nil {
+		node.block = block
+		node.peer = peer
+	}
+
+	sm.processHeaderBlocks()
+	sm.fetchHeaderBlocks()
+}
+
+// processHeaderBlocks processes the downloaded blocks that directly follow the
+// most recently processed block in the header list and requests more headers
+// when that makes room for them.  Once the blocks of all of the headers are
+// processed, it switches to normal mode.
+func (sm *SyncManager) processHeaderBlocks() {
+	for sm.headersFirstMode {
+		frontEl := sm.headerList.Front()
+		e := frontEl.Next()
+		if e == nil {
+			break
+		}
+
+		node := e.Value.(*headerNode)
+		if node.block == nil {
+			// The block might have been added to the chain by
+			// other means, such as the submitblock RPC.
+			haveBlock, err := sm.chain.HaveBlock(node.hash)
+			if err != nil || !haveBlock {
+				break
+			}
+			delete(sm.headerBlocks, *node.hash)
+		} else {
+			// Blocks before the latest checkpoint verified in the
+			// header chain are eligible for less validation since
+			// the headers have already been verified to link
+			// together and match the checkpoint.
+			behaviorFlags := blockchain.BFNone
+			if node.height <= sm.fastAddHeight {
+				behaviorFlags |= blockchain.BFFastAdd
+			}
+
+			delete(sm.headerBlocks, *node.hash)
+			if !sm.processBlock(node.block, node.peer, behaviorFlags) {
+				// The header chain the block belongs to is not
+				// valid, so start over with a new sync peer.
+				log.Warnf("Block %v at height %d of the header "+
+					"chain was rejected -- restarting sync",
+					node.hash, node.height)
+				sm.updateSyncPeer(false)
+				return
+			}
+		}
+
+		sm.headerList.Remove(frontEl)
+		sm.windowFullSince = time.Time{}
+		sm.lastProgressTime = time.Now()
+	}
+
+	// Processing the blocks makes room for more headers in the header
+	// list.
+	sm.requestHeaders()
+
+	if sm.headersFirstMode && sm.headersSynced &&
+		sm.headerList.Len() == 1 {
+
+		sm.finishHeadersFirst()
+	}
+}
+
+// finishHeadersFirst switches to normal mode once the blocks of all of the
+// headers have been processed and requests any blocks the sync peer learned
+// about in the meantime.
+func (sm *SyncManager) finishHeadersFirst() {
+	sm.headersFirstMode = false
+	sm.headerList.Init()
+	sm.headerBlocks = make(map[chainhash.Hash]*list.Element)
+	sm.windowFullSince = time.Time{}
+	log.Infof("Processed the blocks of all headers -- switching to " +
+		"normal mode")
+
+	if err := sm.chain.FlushUtxoCache(blockchain.FlushPeriodic); err != nil {
+		log.Errorf("Error while flushing the blockchain cache: %v", err)
+	}
+
+	if sm.syncPeer == nil {
+		return
+	}
+	locator, err := sm.chain.LatestBlockLocator()
+	if err != nil {
+		log.Errorf("Failed to get block locator for the latest "+
+			"block: %v", err)
+		return
+	}
+	err = sm.syncPeer.PushGetBlocksMsg(locator, &zeroHash)
+	if err != nil {
+		log.Warnf("Failed to send getblocks message to peer %s: %v",
+			sm.syncPeer.Addr(), err)
+	}
+}
+
+// handleBlockStallSample detects peers which stall a headers-first block
+// download and reassigns the blocks requested from them to other peers.  A
+// peer is stalling when it did not deliver any of the blocks requested from it
+// in a timely manner, or when it holds up the next block to be processed while
+// the download window is full.  Stalling peers are disconnected, except for the
+// sync peer which also serves the header chain.
+//
+// Outside of a headers-first sync, it requests the blocks needed to validate
+// the chain before the base of a loaded UTXO set snapshot instead.
+func (sm *SyncManager) handleBlockStallSample() {
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		return
+	}
+	if !sm.headersFirstMode {
+		sm.fetchSnapshotBlocks()
+		return
+	}
+
+	now := time.Now()
+	stalled := make(map[*peerpkg.Peer]struct{})
+	for peer, state := range sm.peerStates {
+		if len(state.requestedBlocks) == 0 {
+			continue
+		}
+		if now.Sub(state.lastBlockTime) > blockRequestTimeout {
+			stalled[peer] = struct{}{}
+		}
+	}
+	if !sm.windowFullSince.IsZero() &&
+		now.Sub(sm.windowFullSince) > blockStallTimeout {
+
+		if e := sm.headerList.Front().Next(); e != nil {
+			node := e.Value.(*headerNode)
+			if node.block == nil && node.peer != nil {
+				stalled[node.peer] = struct{}{}
+			}
+		}
+	}
+	if len(stalled) == 0 {
+		return
+	}
+
+	for peer := range stalled {
+		state, exists := sm.peerStates[peer]
+		if !exists {
+			continue
+		}
+
+		log.Infof("Peer %s is stalling the block download with %d "+
+			"blocks in flight -- reassigning them", peer,
+			len(state.requestedBlocks))
+		sm.clearRequestedState(state)
+		state.requestedBlocks = make(map[chainhash.Hash]struct{})
+		state.stallTime = now
+		if peer != sm.syncPeer {
+			peer.Disconnect()
+		}
+	}
+
+	sm.windowFullSince = time.Time{}
+	sm.fetchHeaderBlocks()
+}
@@ -0,0 +1,277 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netsync
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bynil/btcd/btcutil"
+	peerpkg "github.com/bynil/btcd/peer"
+	"github.com/bynil/btcd/wire"
+)
+
+// newBlockDownloadHarness returns a harness with two connected peers which
+// know about the passed number of blocks past the tip of the chain, along with
+// those blocks.  The sync manager is in headers-first mode and received the
+// headers of the blocks from its sync peer.
+func newBlockDownloadHarness(t *testing.T,
+	numBlocks int) (*cmpctBlockHarness, []*btcutil.Block) {
+
+	h := newCmpctBlockHarness(t)
+	h.connectPeer(h.params)
+
+	best := h.chain.BestSnapshot()
+	blocks := make([]*btcutil.Block, 0, numBlocks)
+	prevHash, height := &best.Hash, best.Height
+	for i := 0; i < numBlocks; i++ {
+		height++
+		block := h.createBlockOn(prevHash, height, nil)
+		blocks = append(blocks, block)
+		prevHash = block.Hash()
+	}
+	for peer := range h.sm.peerStates {
+		peer.UpdateLastBlockHeight(height)
+	}
+
+	h.sm.startSync()
+	if !h.sm.headersFirstMode || h.sm.syncPeer == nil {
+		t.Fatal("sync manager did not start a headers-first sync")
+	}
+
+	headers := wire.NewMsgHeaders()
+	for _, block := range blocks {
+		headers.AddBlockHeader(&block.MsgBlock().Header)
+	}
+	h.sm.handleHeadersMsg(&headersMsg{
+		headers: headers,
+		peer:    h.sm.syncPeer,
+	})
+	if !h.sm.headersSynced {
+		t.Fatal("headers not synced")
+	}
+
+	return h, blocks
+}
+
+// requestedFrom returns the peer the passed block was requested from.
+func (h *cmpctBlockHarness) requestedFrom(block *btcutil.Block) *peerpkg.Peer {
+	for peer, state := range h.sm.peerStates {
+		if _, exists := state.requestedBlocks[*block.Hash()]; exists {
+			return peer
+		}
+	}
+	return nil
+}
+
+// deliverBlock hands the passed block to the sync manager as if it was
+// received from the peer it was requested from.
+func (h *cmpctBlockHarness) deliverBlock(block *btcutil.Block) {
+	peer := h.requestedFrom(block)
+	if peer == nil {
+		h.t.Fatalf("block %v was not requested", block.Hash())
+	}
+	h.sm.handleBlockMsg(&blockMsg{block: block, peer: peer})
+}
+
+// TestParallelBlockDownload ensures the blocks of the headers are requested
+// from all peers and processed in order regardless of the order they arrive
+// in.
+func TestParallelBlockDownload(t *testing.T) {
+	const numBlocks = 2*maxBlocksInFlightPerPeer + 8
+	h, blocks := newBlockDownloadHarness(t, numBlocks)
+
+	// The requests are spread over both peers up to their limit.
+	for peer, state := range h.sm.peerStates {
+		if len(state.requestedBlocks) != maxBlocksInFlightPerPeer {
+			t.Fatalf("got %d blocks in flight from peer %s, want %d",
+				len(state.requestedBlocks), peer,
+				maxBlocksInFlightPerPeer)
+		}
+	}
+
+	// Blocks that arrive before their parent are held until the parent
+	// is processed.
+	startTip := h.chain.BestSnapshot()
+	for _, block := range blocks[1:maxBlocksInFlightPerPeer] {
+		h.deliverBlock(block)
+	}
+	if tip := h.chain.BestSnapshot(); tip.Hash != startTip.Hash {
+		t.Fatalf("got tip %v before the first block arrived, want %v",
+			tip.Hash, startTip.Hash)
+	}
+	h.deliverBlock(blocks[0])
+	h.expectBestBlock(blocks[maxBlocksInFlightPerPeer-1])
+
+	// Delivering the remaining blocks in reverse order of their request
+	// completes the sync and switches to normal mode.
+	for h.sm.headersFirstMode {
+		delivered := false
+		for i := len(blocks) - 1; i >= 0; i-- {
+			if h.requestedFrom(blocks[i]) != nil {
+				h.deliverBlock(blocks[i])
+				delivered = true
+				break
+			}
+		}
+		if !delivered {
+			t.Fatal("no block in flight before the sync finished")
+		}
+	}
+	h.expectBestBlock(blocks[len(blocks)-1])
+	if h.sm.headerList.Len() != 0 || len(h.sm.headerBlocks) != 0 {
+		t.Fatal("header state not cleared after the sync finished")
+	}
+}
+
+// TestBlockDownloadStall ensures the blocks requested from a peer that stalls
+// the download are requested from other peers and that the stalling peer is
+// disconnected.
+func TestBlockDownloadStall(t *testing.T) {
+	const numBlocks = maxBlocksInFlightPerPeer + 4
+	h, blocks := newBlockDownloadHarness(t, numBlocks)
+
+	var staller *peerpkg.Peer
+	for peer := range h.sm.peerStates {
+		if peer != h.sm.syncPeer {
+			staller = peer
+		}
+	}
+	stallerState := h.sm.peerStates[staller]
+	numStalled := len(stallerState.requestedBlocks)
+	if numStalled == 0 {
+		t.Fatal("no blocks requested from the peer")
+	}
+
+	// Nothing happens while the peer is within the request timeout.
+	h.sm.handleBlockStallSample()
+	if len(stallerState.requestedBlocks) != numStalled {
+		t.Fatal("blocks reassigned before the request timeout")
+	}
+
+	stallerState.lastBlockTime = time.Now().Add(-2 * blockRequestTimeout)
+	h.sm.handleBlockStallSample()
+	if len(stallerState.requestedBlocks) != 0 {
+		t.Fatalf("got %d blocks in flight from the stalling peer, "+
+			"want none", len(stallerState.requestedBlocks))
+	}
+	if staller.Connected() {
+		t.Fatal("stalling peer not disconnected")
+	}
+
+	// All of the blocks are now requested from the sync peer, up to its
+	// limit.
+	syncState := h.sm.peerStates[h.sm.syncPeer]
+	if len(syncState.requestedBlocks) != maxBlocksInFlightPerPeer {
+		t.Fatalf("got %d blocks in flight from the sync peer, want %d",
+			len(syncState.requestedBlocks), maxBlocksInFlightPerPeer)
+	}
+	for _, block := range blocks {
+		if peer := h.requestedFrom(block); peer == staller {
+			t.Fatalf("block %v still requested from the stalling "+
+				"peer", block.Hash())
+		}
+	}
+
+	// The sync completes from the remaining peer.
+	for h.sm.headersFirstMode {
+		delivered := false
+		for _, block := range blocks {
+			if h.requestedFrom(block) != nil {
+				h.deliverBlock(block)
+				delivered = true
+				break
+			}
+		}
+		if !delivered {
+			t.Fatal("no block in flight before the sync finished")
+		}
+	}
+	h.expectBestBlock(blocks[len(blocks)-1])
+}
+
+// TestHeadersContextValidation ensures headers that are only invalid in the
+// context of the chain they extend are rejected during a headers-first sync.
+func TestHeadersContextValidation(t *testing.T) {
+	h := newCmpctBlockHarness(t)
+	h.connectPeer(h.params)
+
+	// A block with a timestamp before the median time of the previous
+	// blocks passes the sanity checks of its header.
+	best := h.chain.BestSnapshot()
+	h.blockTime = best.MedianTime.Add(-time.Hour)
+	block := h.createBlockOn(&best.Hash, best.Height+1, nil)
+	for peer := range h.sm.peerStates {
+		peer.UpdateLastBlockHeight(best.Height + 1)
+	}
+
+	h.sm.startSync()
+	syncPeer := h.sm.syncPeer
+	if !h.sm.headersFirstMode || syncPeer == nil {
+		t.Fatal("sync manager did not start a headers-first sync")
+	}
+
+	headers := wire.NewMsgHeaders()
+	headers.AddBlockHeader(&block.MsgBlock().Header)
+	h.sm.handleHeadersMsg(&headersMsg{headers: headers, peer: syncPeer})
+	if syncPeer.Connected() {
+		t.Fatal("peer sending an invalid header not disconnected")
+	}
+	if h.sm.headerList.Len() != 1 {
+		t.Fatalf("got %d headers in the header list, want none",
+			h.sm.headerList.Len()-1)
+	}
+}
+
+// TestHeaderListLimit ensures headers are only requested while the header list
+// has room for them and that a peer sending more headers than that is
+// disconnected.
+func TestHeaderListLimit(t *testing.T) {
+	h, _ := newBlockDownloadHarness(t, 1)
+
+	// Pretend more headers are to come and fill the header list up to the
+	// point where a full headers message no longer fits.
+	h.sm.headersSynced = false
+	h.sm.headersRequested = false
+	for h.sm.headerList.Len() <= maxHeaderListLen-wire.MaxBlockHeadersPerMsg {
+		back := h.sm.headerList.Back().Value.(*headerNode)
+		h.sm.headerList.PushBack(&headerNode{
+			height: back.height + 1,
+			hash:   back.hash,
+		})
+	}
+	h.sm.requestHeaders()
+	if h.sm.headersRequested {
+		t.Fatal("headers requested with a full header list")
+	}
+
+	// Removing a header from the list makes room for a full headers
+	// message.
+	h.sm.headerList.Remove(h.sm.headerList.Back())
+	h.sm.requestHeaders()
+	if !h.sm.headersRequested {
+		t.Fatal("headers not requested with room in the header list")
+	}
+
+	// More headers than requested is misbehavior.
+	for h.sm.headerList.Len() < maxHeaderListLen {
+		back := h.sm.headerList.Back().Value.(*headerNode)
+		h.sm.headerList.PushBack(&headerNode{
+			height: back.height + 1,
+			hash:   back.hash,
+		})
+	}
+	syncPeer := h.sm.syncPeer
+	headers := wire.NewMsgHeaders()
+	headers.AddBlockHeader(&wire.BlockHeader{})
+	h.sm.handleHeadersMsg(&headersMsg{headers: headers, peer: syncPeer})
+	if syncPeer.Connected() {
+		t.Fatal("peer sending too many headers not disconnected")
+	}
+	if h.sm.headerList.Len() != maxHeaderListLen {
+		t.Fatalf("got %d headers in the header list, want %d",
+			h.sm.headerList.Len(), maxHeaderListLen)
+	}
+}
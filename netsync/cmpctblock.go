@@ -0,0 +1,372 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netsync
+
+import (
+	"sync/atomic"
+
+	"github.com/bynil/btcd/blockchain"
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	peerpkg "github.com/bynil/btcd/peer"
+	"github.com/bynil/btcd/wire"
+)
+
+const (
+	// maxHBCmpctBlockPeers is the maximum number of peers that are asked
+	// to announce new blocks by directly sending compact blocks
+	// (high-bandwidth mode) as recommended by BIP0152.
+	maxHBCmpctBlockPeers = 3
+
+	// maxPartialBlocks is the maximum number of compact blocks that can be
+	// awaiting missing transactions at the same time.
+	maxPartialBlocks = 16
+)
+
+// cmpctBlockMsg packages a bitcoin cmpctblock message and the peer it came
+// from together so the block handler has access to that information.
+type cmpctBlockMsg struct {
+	cmpctBlock *wire.MsgCmpctBlock
+	peer       *peerpkg.Peer
+	reply      chan struct{}
+}
+
+// blockTxnMsg packages a bitcoin blocktxn message and the peer it came from
+// together so the block handler has access to that information.
+type blockTxnMsg struct {
+	blockTxn *wire.MsgBlockTxn
+	peer     *peerpkg.Peer
+	reply    chan struct{}
+}
+
+// partialBlock houses a block that is being reconstructed from a compact
+// block while the transactions that could not be found in the mempool are
+// requested from the peer that sent it.
+type partialBlock struct {
+	peer    *peerpkg.Peer
+	header  wire.BlockHeader
+	txns    []*wire.MsgTx
+	missing []uint32
+}
+
+// requestFullBlock requests the full block with the passed hash from the
+// peer.  It is used whenever a compact block cannot be reconstructed.
+func (sm *SyncManager) requestFullBlock(peer *peerpkg.Peer,
+	state *peerSyncState, hash *chainhash.Hash) {
+
+	delete(sm.partialBlocks, *hash)
+
+	limitAdd(sm.requestedBlocks, *hash, maxRequestedBlocks)
+	limitAdd(state.requestedBlocks, *hash, maxRequestedBlocks)
+
+	invType := wire.InvTypeBlock
+	if peer.IsWitnessEnabled() {
+		invType = wire.InvTypeWitnessBlock
+	}
+	gdmsg := wire.NewMsgGetData()
+	gdmsg.AddInvVect(wire.NewInvVect(invType, hash))
+	peer.QueueMessage(gdmsg, nil)
+}
+
+// handleCmpctBlockMsg handles cmpctblock messages from all peers.  It
+// attempts to reconstruct the block from the transactions in the mempool and
+// requests any missing transactions via a getblocktxn message.  In case the
+// block can't be reconstructed, the full block is requested instead.
+func (sm *SyncManager) handleCmpctBlockMsg(cmsg *cmpctBlockMsg) {
+	peer := cmsg.peer
+	state, exists := sm.peerStates[peer]
+	if !exists {
+		log.Warnf("Received cmpctblock message from unknown peer %s",
+			peer)
+		return
+	}
+
+	msg := cmsg.cmpctBlock
+	blockHash := msg.Header.BlockHash()
+	iv := wire.NewInvVect(wire.InvTypeBlock, &blockHash)
+	peer.AddKnownInventory(iv)
+
+	// Compact blocks are only useful once we are synced since the
+	// mempool is needed to reconstruct them.  The block will be
+	// downloaded in full during the initial sync otherwise.
+	_, requested := state.requestedBlocks[blockHash]
+	if !requested && (sm.headersFirstMode || !sm.current()) {
+		log.Debugf("Ignoring compact block %v from %s while syncing",
+			blockHash, peer)
+		return
+	}
+
+	peer.UpdateLastAnnouncedBlock(&blockHash)
+
+	// Nothing to do if the block is already known or is already being
+	// reconstructed.
+	haveBlock, err := sm.chain.HaveBlock(&blockHash)
+	if err != nil {
+		log.Warnf("Unexpected failure when checking for existing "+
+			"block %v: %v", blockHash, err)
+		return
+	}
+	if haveBlock {
+		return
+	}
+	if _, exists := sm.partialBlocks[blockHash]; exists {
+		return
+	}
+
+	// Only version 2 compact blocks, which commit to the witness data of
+	// each transaction, can be reconstructed.  Likewise, blocks which
+	// don't connect to a known block are fetched in full so the usual
+	// orphan handling applies.
+	if peer.CmpctBlockVersion() != wire.CmpctBlockVersion2 ||
+		len(sm.partialBlocks) >= maxPartialBlocks {
+
+		sm.requestFullBlock(peer, state, &blockHash)
+		return
+	}
+	havePrev, err := sm.chain.HaveBlock(&msg.Header.PrevBlock)
+	if err != nil || !havePrev {
+		sm.requestFullBlock(peer, state, &blockHash)
+		return
+	}
+
+	numTxns := msg.TotalTransactions()
+	if numTxns == 0 {
+		log.Warnf("Compact block %v from %s has no transactions -- "+
+			"disconnecting", blockHash, peer)
+		peer.Disconnect()
+		return
+	}
+
+	// Place the prefilled transactions and map the short ids to the
+	// remaining positions in the block.
+	txns := make([]*wire.MsgTx, numTxns)
+	for _, ptx := range msg.PrefilledTxns {
+		txns[ptx.Index] = ptx.Tx
+	}
+	shortIDs := make(map[uint64]uint32, len(msg.ShortIDs))
+	next := 0
+	for i := range txns {
+		if txns[i] != nil {
+			continue
+		}
+		shortID := msg.ShortIDs[next]
+		next++
+
+		// Two transactions in the block having the same short id
+		// makes reconstruction impossible, so get the full block.
+		if _, exists := shortIDs[shortID]; exists {
+			log.Debugf("Short id collision in compact block %v "+
+				"from %s", blockHash, peer)
+			sm.requestFullBlock(peer, state, &blockHash)
+			return
+		}
+		shortIDs[shortID] = uint32(i)
+	}
+
+	// Fill in as many transactions as possible from the mempool.  A
+	// position that is matched by more than one mempool transaction is
+	// ambiguous and therefore requested from the peer.
+	key := msg.ShortIDKey()
+	ambiguous := make(map[uint32]struct{})
+	for _, txDesc := range sm.txMemPool.TxDescs() {
+		shortID := wire.ShortTxID(&key, txDesc.Tx.WitnessHash())
+		index, exists := shortIDs[shortID]
+		if !exists {
+			continue
+		}
+		if txns[index] != nil {
+			ambiguous[index] = struct{}{}
+			continue
+		}
+		txns[index] = txDesc.Tx.MsgTx()
+	}
+	for index := range ambiguous {
+		txns[index] = nil
+	}
+
+	var missing []uint32
+	for i, tx := range txns {
+		if tx == nil {
+			missing = append(missing, uint32(i))
+		}
+	}
+
+	// Track the block as requested from this peer so the resulting block
+	// is accepted and the request is cleaned up when the peer goes away.
+	limitAdd(sm.requestedBlocks, blockHash, maxRequestedBlocks)
+	limitAdd(state.requestedBlocks, blockHash, maxRequestedBlocks)
+
+	if len(missing) == 0 {
+		log.Debugf("Reconstructed compact block %v from %s using "+
+			"%d mempool transactions", blockHash, peer,
+			len(msg.ShortIDs))
+		sm.processCmpctBlock(peer, state, &msg.Header, txns)
+		return
+	}
+
+	log.Debugf("Requesting %d of %d transactions of compact block %v "+
+		"from %s", len(missing), numTxns, blockHash, peer)
+	sm.partialBlocks[blockHash] = &partialBlock{
+		peer:    peer,
+		header:  msg.Header,
+		txns:    txns,
+		missing: missing,
+	}
+	getBlockTxn := wire.NewMsgGetBlockTxn(&blockHash)
+	getBlockTxn.Indexes = missing
+	peer.QueueMessage(getBlockTxn, nil)
+}
+
+// handleBlockTxnMsg handles blocktxn messages from all peers.  The provided
+// transactions complete a compact block previously received from the same
+// peer.
+func (sm *SyncManager) handleBlockTxnMsg(bmsg *blockTxnMsg) {
+	peer := bmsg.peer
+	state, exists := sm.peerStates[peer]
+	if !exists {
+		log.Warnf("Received blocktxn message from unknown peer %s",
+			peer)
+		return
+	}
+
+	msg := bmsg.blockTxn
+	pb, exists := sm.partialBlocks[msg.BlockHash]
+	if !exists || pb.peer != peer {
+		log.Debugf("Ignoring unrequested blocktxn for block %v from "+
+			"%s", msg.BlockHash, peer)
+		return
+	}
+	delete(sm.partialBlocks, msg.BlockHash)
+
+	if len(msg.Transactions) != len(pb.missing) {
+		log.Debugf("Peer %s sent %d transactions for block %v, "+
+			"expected %d -- requesting full block", peer,
+			len(msg.Transactions), msg.BlockHash, len(pb.missing))
+		sm.requestFullBlock(peer, state, &msg.BlockHash)
+		return
+	}
+	for i, index := range pb.missing {
+		pb.txns[index] = msg.Transactions[i]
+	}
+
+	sm.processCmpctBlock(peer, state, &pb.header, pb.txns)
+}
+
+// processCmpctBlock assembles the block from the passed header and
+// reconstructed transactions and processes it like any other block received
+// from the peer.  The block is only processed when its merkle root and
+// witness commitment match since a short id collision could otherwise cause a
+// valid block to be marked invalid.  The full block is requested instead in
+// that case.
+func (sm *SyncManager) processCmpctBlock(peer *peerpkg.Peer,
+	state *peerSyncState, header *wire.BlockHeader, txns []*wire.MsgTx) {
+
+	msgBlock := wire.MsgBlock{
+		Header:       *header,
+		Transactions: txns,
+	}
+	block := btcutil.NewBlock(&msgBlock)
+	blockHash := block.Hash()
+
+	merkleRoot := blockchain.CalcMerkleRoot(block.Transactions(), false)
+	if !header.MerkleRoot.IsEqual(&merkleRoot) {
+		log.Debugf("Reconstructed compact block %v from %s has an "+
+			"invalid merkle root -- requesting full block",
+			blockHash, peer)
+		sm.requestFullBlock(peer, state, blockHash)
+		return
+	}
+	if err := blockchain.ValidateWitnessCommitment(block); err != nil {
+		log.Debugf("Reconstructed compact block %v from %s has an "+
+			"invalid witness commitment -- requesting full block",
+			blockHash, peer)
+		sm.requestFullBlock(peer, state, blockHash)
+		return
+	}
+
+	sm.handleBlockMsg(&blockMsg{block: block, peer: peer})
+}
+
+// updateHBCmpctBlockPeers selects the passed peer, which just provided a new
+// valid block, as one of the peers asked to announce blocks using compact
+// blocks in high-bandwidth mode.  When there are already the maximum number
+// of such peers, the one which was selected the longest time ago is switched
+// back to low-bandwidth mode.
+func (sm *SyncManager) updateHBCmpctBlockPeers(peer *peerpkg.Peer) {
+	if peer.CmpctBlockVersion() != wire.CmpctBlockVersion2 {
+		return
+	}
+
+	// Move the peer to the back of the list when it already is a
+	// high-bandwidth peer.
+	for i, hbPeer := range sm.hbCmpctBlockPeers {
+		if hbPeer == peer {
+			copy(sm.hbCmpctBlockPeers[i:], sm.hbCmpctBlockPeers[i+1:])
+			sm.hbCmpctBlockPeers[len(sm.hbCmpctBlockPeers)-1] = peer
+			return
+		}
+	}
+
+	if len(sm.hbCmpctBlockPeers) >= maxHBCmpctBlockPeers {
+		evicted := sm.hbCmpctBlockPeers[0]
+		sm.hbCmpctBlockPeers = sm.hbCmpctBlockPeers[1:]
+		evicted.QueueMessage(wire.NewMsgSendCmpct(false,
+			wire.CmpctBlockVersion2), nil)
+	}
+
+	log.Debugf("Requesting high-bandwidth compact block announcements "+
+		"from %s", peer)
+	sm.hbCmpctBlockPeers = append(sm.hbCmpctBlockPeers, peer)
+	peer.QueueMessage(wire.NewMsgSendCmpct(true, wire.CmpctBlockVersion2),
+		nil)
+}
+
+// clearCmpctBlockState removes all compact block related state associated
+// with the passed peer.
+func (sm *SyncManager) clearCmpctBlockState(peer *peerpkg.Peer) {
+	for hash, pb := range sm.partialBlocks {
+		if pb.peer == peer {
+			delete(sm.partialBlocks, hash)
+		}
+	}
+	for i, hbPeer := range sm.hbCmpctBlockPeers {
+		if hbPeer == peer {
+			sm.hbCmpctBlockPeers = append(sm.hbCmpctBlockPeers[:i],
+				sm.hbCmpctBlockPeers[i+1:]...)
+			break
+		}
+	}
+}
+
+// QueueCmpctBlock adds the passed cmpctblock message and peer to the block
+// handling queue.  Responds to the done channel argument after the message
+// is processed.
+func (sm *SyncManager) QueueCmpctBlock(cmpctBlock *wire.MsgCmpctBlock,
+	peer *peerpkg.Peer, done chan struct{}) {
+
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		done <- struct{}{}
+		return
+	}
+
+	sm.msgChan <- &cmpctBlockMsg{cmpctBlock: cmpctBlock, peer: peer,
+		reply: done}
+}
+
+// QueueBlockTxn adds the passed blocktxn message and peer to the block
+// handling queue.  Responds to the done channel argument after the message is
+// processed.
+func (sm *SyncManager) QueueBlockTxn(blockTxn *wire.MsgBlockTxn,
+	peer *peerpkg.Peer, done chan struct{}) {
+
+	// Don't accept more blocks if we're shutting down.
+	if atomic.LoadInt32(&sm.shutdown) != 0 {
+		done <- struct{}{}
+		return
+	}
+
+	sm.msgChan <- &blockTxnMsg{blockTxn: blockTxn, peer: peer, reply: done}
+}
@@ -0,0 +1,457 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netsync
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bynil/btcd/blockchain"
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+	"github.com/bynil/btcd/database"
+	_ "github.com/bynil/btcd/database/ffldb"
+	"github.com/bynil/btcd/mempool"
+	peerpkg "github.com/bynil/btcd/peer"
+	"github.com/bynil/btcd/txscript"
+	"github.com/bynil/btcd/wire"
+)
+
+// anyoneCanSpendScript is the public key script of all outputs created by the
+// tests.  It is padded with a data push so spending transactions exceed the
+// minimum transaction size and leaves a single true value on the stack so it
+// can be spent with an empty signature script.
+var anyoneCanSpendScript = append(append([]byte{txscript.OP_DATA_20},
+	make([]byte, 20)...), txscript.OP_DROP, txscript.OP_TRUE)
+
+func init() {
+	DisableLog()
+}
+
+// mockPeerNotifier implements the PeerNotifier interface without doing
+// anything.
+type mockPeerNotifier struct{}
+
+func (m *mockPeerNotifier) AnnounceNewTransactions([]*mempool.TxDesc)               {}
+func (m *mockPeerNotifier) UpdatePeerHeights(*chainhash.Hash, int32, *peerpkg.Peer) {}
+func (m *mockPeerNotifier) RelayInventory(*wire.InvVect, interface{})               {}
+func (m *mockPeerNotifier) TransactionConfirmed(*btcutil.Tx)                        {}
+
+// cmpctBlockHarness houses a sync manager backed by a regression test chain
+// and mempool along with a connected peer that negotiated version 2 compact
+// blocks.
+type cmpctBlockHarness struct {
+	t         *testing.T
+	params    *chaincfg.Params
+	chain     *blockchain.BlockChain
+	txPool    *mempool.TxPool
+	sm        *SyncManager
+	peer      *peerpkg.Peer
+	received  chan wire.Message
+	blockTime time.Time
+	spendable []*wire.MsgTx
+}
+
+// newCmpctBlockHarness returns a harness with a chain that has enough mature
+// coinbase outputs to spend.
+func newCmpctBlockHarness(t *testing.T) *cmpctBlockHarness {
+	params := chaincfg.RegressionNetParams
+	params.Checkpoints = nil
+
+	dbPath := filepath.Join(t.TempDir(), "ffldb")
+	db, err := database.Create("ffldb", dbPath, params.Net)
+	if err != nil {
+		t.Fatalf("unable to create database: %v", err)
+	}
+	t.Cleanup(func() {
+		db.Close()
+		os.RemoveAll(dbPath)
+	})
+
+	chain, err := blockchain.New(&blockchain.Config{
+		DB:          db,
+		ChainParams: &params,
+		TimeSource:  blockchain.NewMedianTime(),
+	})
+	if err != nil {
+		t.Fatalf("unable to create chain: %v", err)
+	}
+
+	h := &cmpctBlockHarness{
+		t:         t,
+		params:    &params,
+		chain:     chain,
+		received:  make(chan wire.Message, 10),
+		blockTime: time.Unix(time.Now().Add(-2*time.Hour).Unix(), 0),
+	}
+
+	// Mine enough blocks for the coinbases of the first few blocks to
+	// mature.
+	numBlocks := int(params.CoinbaseMaturity) + 10
+	for i := 0; i < numBlocks; i++ {
+		block := h.createBlock(nil)
+		if _, _, err := chain.ProcessBlock(block, blockchain.BFNone); err != nil {
+			t.Fatalf("unable to process block %d: %v", i, err)
+		}
+		if i < 10 {
+			h.spendable = append(h.spendable,
+				block.MsgBlock().Transactions[0])
+		}
+	}
+
+	h.txPool = mempool.New(&mempool.Config{
+		Policy: mempool.Policy{
+			AcceptNonStd:         true,
+			DisableRelayPriority: true,
+			FreeTxRelayLimit:     15.0,
+			MaxOrphanTxs:         5,
+			MaxOrphanTxSize:      1000,
+			MaxSigOpCostPerTx:    blockchain.MaxBlockSigOpsCost / 4,
+			MinRelayTxFee:        1000,
+			MaxTxVersion:         2,
+		},
+		ChainParams:    &params,
+		FetchUtxoView:  chain.FetchUtxoView,
+		BestHeight:     func() int32 { return chain.BestSnapshot().Height },
+		MedianTimePast: func() time.Time { return chain.BestSnapshot().MedianTime },
+		CalcSequenceLock: func(tx *btcutil.Tx,
+			view *blockchain.UtxoViewpoint) (*blockchain.SequenceLock, error) {
+
+			return chain.CalcSequenceLock(tx, view, true)
+		},
+		IsDeploymentActive: chain.IsDeploymentActive,
+	})
+
+	h.sm, err = New(&Config{
+		PeerNotifier:       &mockPeerNotifier{},
+		Chain:              chain,
+		TxMemPool:          h.txPool,
+		ChainParams:        &params,
+		DisableCheckpoints: true,
+		MaxPeers:           8,
+	})
+	if err != nil {
+		t.Fatalf("unable to create sync manager: %v", err)
+	}
+
+	h.connectPeer(&params)
+	return h
+}
+
+// connectPeer connects a peer that announces support for version 2 compact
+// blocks and records the messages it receives from the sync manager.
+func (h *cmpctBlockHarness) connectPeer(params *chaincfg.Params) {
+	record := func(msg wire.Message) {
+		select {
+		case h.received <- msg:
+		default:
+		}
+	}
+	remoteCfg := &peerpkg.Config{
+		ChainParams:    params,
+		Services:       wire.SFNodeNetwork | wire.SFNodeWitness,
+		AllowSelfConns: true,
+		Listeners: peerpkg.MessageListeners{
+			OnVerAck: func(p *peerpkg.Peer, msg *wire.MsgVerAck) {
+				p.QueueMessage(wire.NewMsgSendCmpct(false,
+					wire.CmpctBlockVersion2), nil)
+			},
+			OnGetData: func(p *peerpkg.Peer, msg *wire.MsgGetData) {
+				record(msg)
+			},
+			OnGetBlockTxn: func(p *peerpkg.Peer, msg *wire.MsgGetBlockTxn) {
+				record(msg)
+			},
+		},
+	}
+	localCfg := &peerpkg.Config{
+		ChainParams:    params,
+		Services:       wire.SFNodeNetwork | wire.SFNodeWitness,
+		AllowSelfConns: true,
+	}
+
+	// The peers are connected over the loopback interface since the
+	// handshake requires both sides to write at the same time.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		h.t.Fatalf("unable to listen: %v", err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			close(accepted)
+			return
+		}
+		accepted <- conn
+	}()
+	remoteConn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		h.t.Fatalf("unable to connect: %v", err)
+	}
+	localConn, ok := <-accepted
+	if !ok {
+		h.t.Fatalf("unable to accept connection")
+	}
+
+	local := peerpkg.NewInboundPeer(localCfg)
+	remote, err := peerpkg.NewOutboundPeer(remoteCfg,
+		listener.Addr().String())
+	if err != nil {
+		h.t.Fatalf("unable to create peer: %v", err)
+	}
+	local.AssociateConnection(localConn)
+	remote.AssociateConnection(remoteConn)
+	h.t.Cleanup(func() {
+		local.Disconnect()
+		remote.Disconnect()
+	})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for local.CmpctBlockVersion() != wire.CmpctBlockVersion2 {
+		if time.Now().After(deadline) {
+			h.t.Fatalf("peer did not negotiate compact blocks")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	h.peer = local
+	h.sm.peerStates[local] = &peerSyncState{
+		syncCandidate:   true,
+		requestedTxns:   make(map[chainhash.Hash]struct{}),
+		requestedBlocks: make(map[chainhash.Hash]struct{}),
+	}
+}
+
+// createBlock returns a solved block that extends the current best chain and
+// includes the passed transactions.
+func (h *cmpctBlockHarness) createBlock(txns []*wire.MsgTx) *btcutil.Block {
+	best := h.chain.BestSnapshot()
+	return h.createBlockOn(&best.Hash, best.Height+1, txns)
+}
+
+// createBlockOn returns a solved block at the passed height that extends the
+// block with the passed hash and includes the passed transactions.
+func (h *cmpctBlockHarness) createBlockOn(prevHash *chainhash.Hash,
+	height int32, txns []*wire.MsgTx) *btcutil.Block {
+
+	params := h.params
+
+	coinbaseScript, err := txscript.NewScriptBuilder().
+		AddInt64(int64(height)).AddInt64(0).Script()
+	if err != nil {
+		h.t.Fatalf("unable to create coinbase script: %v", err)
+	}
+	coinbase := wire.NewMsgTx(wire.TxVersion)
+	coinbase.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: *wire.NewOutPoint(&chainhash.Hash{},
+			wire.MaxPrevOutIndex),
+		SignatureScript: coinbaseScript,
+		Sequence:        wire.MaxTxInSequenceNum,
+	})
+	coinbase.AddTxOut(wire.NewTxOut(blockchain.CalcBlockSubsidy(height,
+		params), anyoneCanSpendScript))
+
+	h.blockTime = h.blockTime.Add(time.Second)
+	msgBlock := &wire.MsgBlock{
+		Header: wire.BlockHeader{
+			Version:   4,
+			PrevBlock: *prevHash,
+			Timestamp: h.blockTime,
+			Bits:      params.PowLimitBits,
+		},
+		Transactions: append([]*wire.MsgTx{coinbase}, txns...),
+	}
+	block := btcutil.NewBlock(msgBlock)
+	msgBlock.Header.MerkleRoot = blockchain.CalcMerkleRoot(
+		block.Transactions(), false)
+
+	target := blockchain.CompactToBig(params.PowLimitBits)
+	for {
+		hash := msgBlock.Header.BlockHash()
+		if blockchain.HashToBig(&hash).Cmp(target) <= 0 {
+			break
+		}
+		msgBlock.Header.Nonce++
+	}
+
+	return btcutil.NewBlock(msgBlock)
+}
+
+// spendTx returns a transaction spending the next mature coinbase.
+func (h *cmpctBlockHarness) spendTx() *wire.MsgTx {
+	coinbase := h.spendable[0]
+	h.spendable = h.spendable[1:]
+	coinbaseHash := coinbase.TxHash()
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: *wire.NewOutPoint(&coinbaseHash, 0),
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	tx.AddTxOut(wire.NewTxOut(coinbase.TxOut[0].Value-10000,
+		anyoneCanSpendScript))
+	return tx
+}
+
+// addToMempool adds the passed transaction to the mempool.
+func (h *cmpctBlockHarness) addToMempool(tx *wire.MsgTx) {
+	_, err := h.txPool.ProcessTransaction(btcutil.NewTx(tx), false, false, 0)
+	if err != nil {
+		h.t.Fatalf("unable to add transaction to mempool: %v", err)
+	}
+}
+
+// sendCmpctBlock hands a compact block for the passed block to the sync
+// manager as if it was received from the peer.
+func (h *cmpctBlockHarness) sendCmpctBlock(block *btcutil.Block) {
+	cmpctBlock := wire.NewMsgCmpctBlockFromBlock(block.MsgBlock(), 1234,
+		wire.CmpctBlockVersion2)
+	h.sm.handleCmpctBlockMsg(&cmpctBlockMsg{
+		cmpctBlock: cmpctBlock,
+		peer:       h.peer,
+	})
+}
+
+// sendBlockTxn hands a blocktxn message with the passed transactions of the
+// passed block to the sync manager as if it was received from the peer.
+func (h *cmpctBlockHarness) sendBlockTxn(block *btcutil.Block,
+	txns []*wire.MsgTx) {
+
+	h.sm.handleBlockTxnMsg(&blockTxnMsg{
+		blockTxn: wire.NewMsgBlockTxn(block.Hash(), txns),
+		peer:     h.peer,
+	})
+}
+
+// expectMessage waits for the peer to receive a message and returns it.
+func (h *cmpctBlockHarness) expectMessage() wire.Message {
+	select {
+	case msg := <-h.received:
+		return msg
+	case <-time.After(5 * time.Second):
+		h.t.Fatalf("peer did not receive a message")
+	}
+	return nil
+}
+
+// expectBestBlock ensures the passed block is the tip of the best chain.
+func (h *cmpctBlockHarness) expectBestBlock(block *btcutil.Block) {
+	best := h.chain.BestSnapshot()
+	if !best.Hash.IsEqual(block.Hash()) {
+		h.t.Fatalf("best block is %v, want %v", best.Hash, block.Hash())
+	}
+}
+
+// expectFullBlockRequest ensures the full block was requested from the peer
+// instead of being reconstructed.
+func (h *cmpctBlockHarness) expectFullBlockRequest(block *btcutil.Block) {
+	if _, exists := h.sm.partialBlocks[*block.Hash()]; exists {
+		h.t.Fatalf("block %v is still awaiting transactions",
+			block.Hash())
+	}
+	if _, exists := h.sm.requestedBlocks[*block.Hash()]; !exists {
+		h.t.Fatalf("block %v is not requested", block.Hash())
+	}
+	if have, _ := h.chain.HaveBlock(block.Hash()); have {
+		h.t.Fatalf("block %v was processed", block.Hash())
+	}
+
+	msg, ok := h.expectMessage().(*wire.MsgGetData)
+	if !ok {
+		h.t.Fatalf("peer did not receive getdata")
+	}
+	if len(msg.InvList) != 1 ||
+		msg.InvList[0].Type != wire.InvTypeWitnessBlock ||
+		!msg.InvList[0].Hash.IsEqual(block.Hash()) {
+
+		h.t.Fatalf("unexpected getdata %v", msg.InvList)
+	}
+}
+
+// expectPartialBlock ensures the transactions at the passed indexes of the
+// block were requested from the peer.
+func (h *cmpctBlockHarness) expectPartialBlock(block *btcutil.Block,
+	missing []uint32) {
+
+	if _, exists := h.sm.partialBlocks[*block.Hash()]; !exists {
+		h.t.Fatalf("block %v is not awaiting transactions",
+			block.Hash())
+	}
+	msg, ok := h.expectMessage().(*wire.MsgGetBlockTxn)
+	if !ok {
+		h.t.Fatalf("peer did not receive getblocktxn")
+	}
+	if !msg.BlockHash.IsEqual(block.Hash()) ||
+		len(msg.Indexes) != len(missing) {
+
+		h.t.Fatalf("unexpected getblocktxn %v %v", msg.BlockHash,
+			msg.Indexes)
+	}
+	for i := range missing {
+		if msg.Indexes[i] != missing[i] {
+			h.t.Fatalf("requested indexes %v, want %v",
+				msg.Indexes, missing)
+		}
+	}
+}
+
+// TestCmpctBlockReconstruction ensures compact blocks are reconstructed from
+// the mempool and the transactions provided by the peer, and that the full
+// block is requested whenever reconstruction fails.
+func TestCmpctBlockReconstruction(t *testing.T) {
+	h := newCmpctBlockHarness(t)
+
+	// A block made up entirely of mempool transactions is reconstructed
+	// and processed without any further requests.
+	tx1, tx2 := h.spendTx(), h.spendTx()
+	h.addToMempool(tx1)
+	h.addToMempool(tx2)
+	block := h.createBlock([]*wire.MsgTx{tx1, tx2})
+	h.sendCmpctBlock(block)
+	h.expectBestBlock(block)
+	if len(h.sm.partialBlocks) != 0 {
+		t.Fatalf("unexpected partial blocks after mempool hit")
+	}
+	if h.txPool.Count() != 0 {
+		t.Fatalf("mined transactions are still in the mempool")
+	}
+
+	// Transactions missing from the mempool are requested and the block
+	// is processed once the peer provides them.
+	tx3, tx4 := h.spendTx(), h.spendTx()
+	h.addToMempool(tx3)
+	block = h.createBlock([]*wire.MsgTx{tx3, tx4})
+	h.sendCmpctBlock(block)
+	h.expectPartialBlock(block, []uint32{2})
+	h.sendBlockTxn(block, []*wire.MsgTx{tx4})
+	h.expectBestBlock(block)
+	if len(h.sm.partialBlocks) != 0 {
+		t.Fatalf("unexpected partial blocks after blocktxn")
+	}
+
+	// A blocktxn with the wrong number of transactions results in the
+	// full block being requested.
+	tx5 := h.spendTx()
+	block = h.createBlock([]*wire.MsgTx{tx5})
+	h.sendCmpctBlock(block)
+	h.expectPartialBlock(block, []uint32{1})
+	h.sendBlockTxn(block, nil)
+	h.expectFullBlockRequest(block)
+
+	// A blocktxn with transactions that don't match the merkle root
+	// results in the full block being requested.
+	tx6, tx7 := h.spendTx(), h.spendTx()
+	block = h.createBlock([]*wire.MsgTx{tx6})
+	h.sendCmpctBlock(block)
+	h.expectPartialBlock(block, []uint32{1})
+	h.sendBlockTxn(block, []*wire.MsgTx{tx7})
+	h.expectFullBlockRequest(block)
+}
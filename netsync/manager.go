@@ -23,11 +23,6 @@ import (
 )
 
 const (
-	// minInFlightBlocks is the minimum number of blocks that should be
-	// in the request queue for headers-first mode before requesting
-	// more.
-	minInFlightBlocks = 10
-
 	// maxRejectedTxns is the maximum number of rejected transactions
 	// hashes to store in memory.
 	maxRejectedTxns = 1000
@@ -47,6 +42,11 @@ const (
 	// stallSampleInterval the interval at which we will check to see if our
 	// sync has stalled.
 	stallSampleInterval = 30 * time.Second
+
+	// maxHeaderListLen is the maximum number of headers held in the header
+	// list during a headers-first sync.  More headers are only requested
+	// from the sync peer once the blocks of enough of them are processed.
+	maxHeaderListLen = 50 * wire.MaxBlockHeadersPerMsg
 )
 
 // zeroHash is the zero value hash (all zeros).  It is defined as a convenience.
@@ -139,10 +139,16 @@ type pauseMsg struct {
 }
 
 // headerNode is used as a node in a list of headers that are linked together
-// between checkpoints.
+// during a headers-first sync.
 type headerNode struct {
 	height int32
 	hash   *chainhash.Hash
+
+	// The following fields track the download of the block.  The peer is
+	// the one the block was requested from, or the one that delivered it
+	// once the block is set.
+	block *btcutil.Block
+	peer  *peerpkg.Peer
 }
 
 // peerSyncState stores additional information that the SyncManager tracks
@@ -152,6 +158,11 @@ type peerSyncState struct {
 	requestQueue    []*wire.InvVect
 	requestedTxns   map[chainhash.Hash]struct{}
 	requestedBlocks map[chainhash.Hash]struct{}
+
+	// The following fields are used to detect peers which stall a
+	// headers-first block download.
+	lastBlockTime time.Time
+	stallTime     time.Time
 }
 
 // limitAdd is a helper function for maps that require a maximum limit by
@@ -198,11 +209,24 @@ type SyncManager struct {
 	peerStates       map[*peerpkg.Peer]*peerSyncState
 	lastProgressTime time.Time
 
-	// The following fields are used for headers-first mode.
-	headersFirstMode bool
-	headerList       *list.List
-	startHeader      *list.Element
-	nextCheckpoint   *chaincfg.Checkpoint
+	// The following fields are used for headers-first mode.  The header
+	// list starts with the most recently processed block and is extended
+	// up to the best header known to the sync peer, while the blocks of
+	// the headers are downloaded from all sync candidates in parallel.
+	headersFirstMode   bool
+	headersSynced      bool
+	headersRequested   bool
+	headerList         *list.List
+	headerBlocks       map[chainhash.Hash]*list.Element
+	windowFullSince    time.Time
+	nextCheckpoint     *chaincfg.Checkpoint
+	fastAddHeight      int32
+	disableCheckpoints bool
+	timeSource         blockchain.MedianTimeSource
+
+	// The following fields are used for compact block relay (BIP0152).
+	partialBlocks     map[chainhash.Hash]*partialBlock
+	hbCmpctBlockPeers []*peerpkg.Peer
 
 	// An optional fee estimator.
 	feeEstimator *mempool.FeeEstimator
@@ -212,16 +236,21 @@ type SyncManager struct {
 // syncing from a new peer.
 func (sm *SyncManager) resetHeaderState(newestHash *chainhash.Hash, newestHeight int32) {
 	sm.headersFirstMode = false
+	sm.headersSynced = false
+	sm.headersRequested = false
 	sm.headerList.Init()
-	sm.startHeader = nil
-
-	// When there is a next checkpoint, add an entry for the latest known
-	// block into the header pool.  This allows the next downloaded header
-	// to prove it links to the chain properly.
-	if sm.nextCheckpoint != nil {
-		node := headerNode{height: newestHeight, hash: newestHash}
-		sm.headerList.PushBack(&node)
+	sm.headerBlocks = make(map[chainhash.Hash]*list.Element)
+	sm.windowFullSince = time.Time{}
+	sm.fastAddHeight = 0
+	if !sm.disableCheckpoints {
+		sm.nextCheckpoint = sm.findNextHeaderCheckpoint(newestHeight)
 	}
+
+	// Add an entry for the latest known block into the header pool.  This
+	// allows the next downloaded header to prove it links to the chain
+	// properly.
+	node := headerNode{height: newestHeight, hash: newestHash}
+	sm.headerList.PushBack(&node)
 }
 
 // findNextHeaderCheckpoint returns the next checkpoint after the passed height.
@@ -345,32 +374,35 @@ func (sm *SyncManager) startSync() {
 		log.Infof("Syncing to block height %d from peer %v",
 			bestPeer.LastBlock(), bestPeer.Addr())
 
-		// When the current height is less than a known checkpoint we
-		// can use block headers to learn about which blocks comprise
-		// the chain up to the checkpoint and perform less validation
-		// for them.  This is possible since each header contains the
-		// hash of the previous header and a merkle root.  Therefore if
-		// we validate all of the received headers link together
-		// properly and the checkpoint hashes match, we can be sure the
-		// hashes for the blocks in between are accurate.  Further, once
-		// the full blocks are downloaded, the merkle root is computed
-		// and compared against the value in the header which proves the
-		// full block hasn't been tampered with.
+		// When the peer knows about blocks we don't have, use block
+		// headers to learn about which blocks comprise the chain up to
+		// the best header of the peer, so the blocks can be downloaded
+		// from all sync candidates in parallel.  This is possible since
+		// each header contains the hash of the previous header and a
+		// merkle root.  Therefore if we validate all of the received
+		// headers link together properly, we can be sure the hashes for
+		// the blocks in between are accurate.  Further, once the full
+		// blocks are downloaded, the merkle root is computed and
+		// compared against the value in the header which proves the
+		// full block hasn't been tampered with.  Blocks up to a
+		// checkpoint the headers were verified against perform less
+		// validation.
 		//
-		// Once we have passed the final checkpoint, or checkpoints are
-		// disabled, use standard inv messages learn about the blocks
-		// and fully validate them.  Finally, regression test mode does
-		// not support the headers-first approach so do normal block
-		// downloads when in regression test mode.
-		if sm.nextCheckpoint != nil &&
-			best.Height < sm.nextCheckpoint.Height &&
+		// Otherwise, use standard inv messages learn about the blocks.
+		// Finally, regression test mode does not support the
+		// headers-first approach so do normal block downloads when in
+		// regression test mode.
+		if bestPeer.LastBlock() > best.Height &&
 			sm.chainParams != &chaincfg.RegressionNetParams {
 
-			bestPeer.PushGetHeadersMsg(locator, sm.nextCheckpoint.Hash)
+			sm.resetHeaderState(&best.Hash, best.Height)
+			bestPeer.PushGetHeadersMsg(locator, &zeroHash)
 			sm.headersFirstMode = true
+			sm.headersRequested = true
+			sm.progressLogger.SetLastLogTime(time.Now())
 			log.Infof("Downloading headers for blocks %d to "+
 				"%d from peer %s", best.Height+1,
-				sm.nextCheckpoint.Height, bestPeer.Addr())
+				bestPeer.LastBlock(), bestPeer.Addr())
 		} else {
 			bestPeer.PushGetBlocksMsg(locator, &zeroHash)
 		}
@@ -552,12 +584,18 @@ func (sm *SyncManager) handleDonePeerMsg(peer *peerpkg.Peer) {
 	log.Infof("Lost peer %s", peer)
 
 	sm.clearRequestedState(state)
+	sm.clearCmpctBlockState(peer)
 
 	if peer == sm.syncPeer {
 		// Update the sync peer. The server has already disconnected the
 		// peer before signaling to the sync manager.
 		sm.updateSyncPeer(false)
+		return
 	}
+
+	// Request the blocks that were in flight from the peer from the
+	// remaining peers.
+	sm.fetchHeaderBlocks()
 }
 
 // clearRequestedState wipes all expected transactions and blocks from the sync
@@ -571,11 +609,19 @@ func (sm *SyncManager) clearRequestedState(state *peerSyncState) {
 	}
 
 	// Remove requested blocks from the global map so that they will be
-	// fetched from elsewhere next time we get an inv.
-	// TODO: we could possibly here check which peers have these blocks
-	// and request them now to speed things up a little.
+	// fetched from elsewhere next time we get an inv.  Blocks requested
+	// during a headers-first sync are requested from other peers the next
+	// time the blocks of the headers are fetched.
 	for blockHash := range state.requestedBlocks {
 		delete(sm.requestedBlocks, blockHash)
+
+		if e, exists := sm.headerBlocks[blockHash]; exists {
+			node := e.Value.(*headerNode)
+			if node.block == nil {
+				node.peer = nil
+				delete(sm.headerBlocks, blockHash)
+			}
+		}
 	}
 }
 
@@ -715,27 +761,13 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
-	// When in headers-first mode, if the block matches the hash of the
-	// first header in the list of headers that are being fetched, it's
-	// eligible for less validation since the headers have already been
-	// verified to link together and are valid up to the next checkpoint.
-	// Also, remove the list entry for all blocks except the checkpoint
-	// since it is needed to verify the next round of headers links
-	// properly.
-	isCheckpointBlock := false
-	behaviorFlags := blockchain.BFNone
+	// Blocks requested for the headers of a headers-first sync are
+	// processed in the order of the headers.
 	if sm.headersFirstMode {
-		firstNodeEl := sm.headerList.Front()
-		if firstNodeEl != nil {
-			firstNode := firstNodeEl.Value.(*headerNode)
-			if blockHash.IsEqual(firstNode.hash) {
-				behaviorFlags |= blockchain.BFFastAdd
-				if firstNode.hash.IsEqual(sm.nextCheckpoint.Hash) {
-					isCheckpointBlock = true
-				} else {
-					sm.headerList.Remove(firstNodeEl)
-				}
-			}
+		if e, exists := sm.headerBlocks[*blockHash]; exists {
+			node := e.Value.(*headerNode)
+			sm.handleHeaderBlock(node, bmsg.block, peer, state)
+			return
 		}
 	}
 
@@ -744,10 +776,33 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 	// will fail the insert and thus we'll retry next time we get an inv.
 	delete(state.requestedBlocks, *blockHash)
 	delete(sm.requestedBlocks, *blockHash)
+	delete(sm.partialBlocks, *blockHash)
+
+	sm.processBlock(bmsg.block, peer, blockchain.BFNone)
+
+	// If we are not in headers first mode, it's a good time to periodically
+	// flush the blockchain cache because we don't expect new blocks immediately.
+	if !sm.headersFirstMode {
+		if err := sm.chain.FlushUtxoCache(blockchain.FlushPeriodic); err != nil {
+			log.Errorf("Error while flushing the blockchain cache: %v", err)
+		}
+	}
+
+	sm.fetchSnapshotBlocks()
+}
+
+// processBlock processes a block received from the peer and updates the known
+// heights of the peers accordingly.  It returns whether the block was
+// connected to the block chain or a side chain, as opposed to being rejected
+// or an orphan.
+func (sm *SyncManager) processBlock(block *btcutil.Block, peer *peerpkg.Peer,
+	behaviorFlags blockchain.BehaviorFlags) bool {
+
+	blockHash := block.Hash()
 
 	// Process the block to include validation, best chain selection, orphan
 	// handling, etc.
-	_, isOrphan, err := sm.chain.ProcessBlock(bmsg.block, behaviorFlags)
+	_, isOrphan, err := sm.chain.ProcessBlock(block, behaviorFlags)
 	if err != nil {
 		// When the error is a rule error, it means the block was simply
 		// rejected as opposed to something actually going wrong, so log
@@ -769,7 +824,7 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		// send it.
 		code, reason := mempool.ErrToRejectErr(err)
 		peer.PushRejectMsg(wire.CmdBlock, code, reason, blockHash, false)
-		return
+		return false
 	}
 
 	// Meta-data about the new block this peer is reporting. We use this
@@ -791,9 +846,9 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		// block height from the scriptSig of the coinbase transaction.
 		// Extraction is only attempted if the block's version is
 		// high enough (ver 2+).
-		header := &bmsg.block.MsgBlock().Header
+		header := &block.MsgBlock().Header
 		if blockchain.ShouldHaveSerializedBlockHeight(header) {
-			coinbaseTx := bmsg.block.Transactions()[0]
+			coinbaseTx := block.Transactions()[0]
 			cbHeight, err := blockchain.ExtractCoinbaseHeight(coinbaseTx)
 			if err != nil {
 				log.Warnf("Unable to extract height from "+
@@ -821,7 +876,7 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 
 		// When the block is not an orphan, log information about it and
 		// update the chain state.
-		sm.progressLogger.LogBlockHeight(bmsg.block, sm.chain)
+		sm.progressLogger.LogBlockHeight(block, sm.chain)
 
 		// Update this peer's latest block height, for future
 		// potential sync node candidacy.
@@ -831,6 +886,12 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 
 		// Clear the rejected transactions.
 		sm.rejectedTxns = make(map[chainhash.Hash]struct{})
+
+		// Ask peers which provide new blocks in a timely manner to
+		// announce future blocks via compact blocks.
+		if sm.current() {
+			sm.updateHBCmpctBlockPeers(peer)
+		}
 	}
 
 	// Update the block height for this peer. But only send a message to
@@ -845,115 +906,7 @@ func (sm *SyncManager) handleBlockMsg(bmsg *blockMsg) {
 		}
 	}
 
-	// If we are not in headers first mode, it's a good time to periodically
-	// flush the blockchain cache because we don't expect new blocks immediately.
-	// After that, there is nothing more to do.
-	if !sm.headersFirstMode {
-		if err := sm.chain.FlushUtxoCache(blockchain.FlushPeriodic); err != nil {
-			log.Errorf("Error while flushing the blockchain cache: %v", err)
-		}
-		return
-	}
-
-	// This is headers-first mode, so if the block is not a checkpoint
-	// request more blocks using the header list when the request queue is
-	// getting short.
-	if !isCheckpointBlock {
-		if sm.startHeader != nil &&
-			len(state.requestedBlocks) < minInFlightBlocks {
-			sm.fetchHeaderBlocks()
-		}
-		return
-	}
-
-	// This is headers-first mode and the block is a checkpoint.  When
-	// there is a next checkpoint, get the next round of headers by asking
-	// for headers starting from the block after this one up to the next
-	// checkpoint.
-	prevHeight := sm.nextCheckpoint.Height
-	prevHash := sm.nextCheckpoint.Hash
-	sm.nextCheckpoint = sm.findNextHeaderCheckpoint(prevHeight)
-	if sm.nextCheckpoint != nil {
-		locator := blockchain.BlockLocator([]*chainhash.Hash{prevHash})
-		err := peer.PushGetHeadersMsg(locator, sm.nextCheckpoint.Hash)
-		if err != nil {
-			log.Warnf("Failed to send getheaders message to "+
-				"peer %s: %v", peer.Addr(), err)
-			return
-		}
-		log.Infof("Downloading headers for blocks %d to %d from "+
-			"peer %s", prevHeight+1, sm.nextCheckpoint.Height,
-			sm.syncPeer.Addr())
-		return
-	}
-
-	// This is headers-first mode, the block is a checkpoint, and there are
-	// no more checkpoints, so switch to normal mode by requesting blocks
-	// from the block after this one up to the end of the chain (zero hash).
-	sm.headersFirstMode = false
-	sm.headerList.Init()
-	log.Infof("Reached the final checkpoint -- switching to normal mode")
-	locator := blockchain.BlockLocator([]*chainhash.Hash{blockHash})
-	err = peer.PushGetBlocksMsg(locator, &zeroHash)
-	if err != nil {
-		log.Warnf("Failed to send getblocks message to peer %s: %v",
-			peer.Addr(), err)
-		return
-	}
-}
-
-// fetchHeaderBlocks creates and sends a request to the syncPeer for the next
-// list of blocks to be downloaded based on the current list of headers.
-func (sm *SyncManager) fetchHeaderBlocks() {
-	// Nothing to do if there is no start header.
-	if sm.startHeader == nil {
-		log.Warnf("fetchHeaderBlocks called with no start header")
-		return
-	}
-
-	// Build up a getdata request for the list of blocks the headers
-	// describe.  The size hint will be limited to wire.MaxInvPerMsg by
-	// the function, so no need to double check it here.
-	gdmsg := wire.NewMsgGetDataSizeHint(uint(sm.headerList.Len()))
-	numRequested := 0
-	for e := sm.startHeader; e != nil; e = e.Next() {
-		node, ok := e.Value.(*headerNode)
-		if !ok {
-			log.Warn("Header list node type is not a headerNode")
-			continue
-		}
-
-		iv := wire.NewInvVect(wire.InvTypeBlock, node.hash)
-		haveInv, err := sm.haveInventory(iv)
-		if err != nil {
-			log.Warnf("Unexpected failure when checking for "+
-				"existing inventory during header block "+
-				"fetch: %v", err)
-		}
-		if !haveInv {
-			syncPeerState := sm.peerStates[sm.syncPeer]
-
-			sm.requestedBlocks[*node.hash] = struct{}{}
-			syncPeerState.requestedBlocks[*node.hash] = struct{}{}
-
-			// If we're fetching from a witness enabled peer
-			// post-fork, then ensure that we receive all the
-			// witness data in the blocks.
-			if sm.syncPeer.IsWitnessEnabled() {
-				iv.Type = wire.InvTypeWitnessBlock
-			}
-
-			gdmsg.AddInvVect(iv)
-			numRequested++
-		}
-		sm.startHeader = e.Next()
-		if numRequested >= wire.MaxInvPerMsg {
-			break
-		}
-	}
-	if len(gdmsg.InvList) > 0 {
-		sm.syncPeer.QueueMessage(gdmsg, nil)
-	}
+	return !isOrphan
 }
 
 // handleHeadersMsg handles block header messages from all peers.  Headers are
@@ -976,18 +929,29 @@ func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
 		return
 	}
 
-	// Nothing to do for an empty headers message.
-	if numHeaders == 0 {
+	// Headers are only requested from the sync peer.  Ignore any headers
+	// from a previous sync peer.
+	if peer != sm.syncPeer {
+		log.Debugf("Ignoring %d headers from non-sync peer %s",
+			numHeaders, peer)
+		return
+	}
+
+	// Headers are only requested while the header list has room for a
+	// full headers message, so the peer is misbehaving if it sends more.
+	if sm.headerList.Len()+numHeaders > maxHeaderListLen {
+		log.Warnf("Got %d headers from %s with %d headers pending "+
+			"-- disconnecting", numHeaders, peer.Addr(),
+			sm.headerList.Len()-1)
+		peer.Disconnect()
 		return
 	}
+	sm.headersRequested = false
 
 	// Process all of the received headers ensuring each one connects to the
-	// previous and that checkpoints match.
-	receivedCheckpoint := false
-	var finalHash *chainhash.Hash
+	// previous, has a valid proof of work, and that checkpoints match.
 	for _, blockHeader := range msg.Headers {
 		blockHash := blockHeader.BlockHash()
-		finalHash = &blockHash
 
 		// Ensure there is a previous header to compare against.
 		prevNodeEl := sm.headerList.Back()
@@ -998,17 +962,9 @@ func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
 			return
 		}
 
-		// Ensure the header properly connects to the previous one and
-		// add it to the list of headers.
-		node := headerNode{hash: &blockHash}
+		// Ensure the header properly connects to the previous one.
 		prevNode := prevNodeEl.Value.(*headerNode)
-		if prevNode.hash.IsEqual(&blockHeader.PrevBlock) {
-			node.height = prevNode.height + 1
-			e := sm.headerList.PushBack(&node)
-			if sm.startHeader == nil {
-				sm.startHeader = e
-			}
-		} else {
+		if !prevNode.hash.IsEqual(&blockHeader.PrevBlock) {
 			log.Warnf("Received block header that does not "+
 				"properly connect to the chain from peer %s "+
 				"-- disconnecting", peer.Addr())
@@ -1016,52 +972,91 @@ func (sm *SyncManager) handleHeadersMsg(hmsg *headersMsg) {
 			return
 		}
 
+		// Ensure the header is valid, both on its own and in the
+		// context of the headers before it, since the headers are no
+		// longer limited to a checkpoint.  This includes checking its
+		// proof of work, its difficulty against the retarget rules, its
+		// timestamp against the median time of the previous blocks and
+		// the checkpoints.  The header is then added to the block index,
+		// since knowing the headers allows a UTXO set snapshot to be
+		// loaded.
+		err := sm.chain.ProcessBlockHeader(blockHeader,
+			blockchain.BFNone)
+		if err != nil {
+			log.Warnf("Received invalid block header %v from peer "+
+				"%s: %v -- disconnecting", blockHash,
+				peer.Addr(), err)
+			peer.Disconnect()
+			return
+		}
+
+		// Add the header to the list of headers.
+		node := headerNode{
+			height: prevNode.height + 1,
+			hash:   &blockHash,
+		}
+		sm.headerList.PushBack(&node)
+
 		// Verify the header at the next checkpoint height matches.
-		if node.height == sm.nextCheckpoint.Height {
-			if node.hash.IsEqual(sm.nextCheckpoint.Hash) {
-				receivedCheckpoint = true
-				log.Infof("Verified downloaded block "+
-					"header against checkpoint at height "+
-					"%d/hash %s", node.height, node.hash)
-			} else {
-				log.Warnf("Block header at height %d/hash "+
-					"%s from peer %s does NOT match "+
-					"expected checkpoint hash of %s -- "+
-					"disconnecting", node.height,
-					node.hash, peer.Addr(),
-					sm.nextCheckpoint.Hash)
-				peer.Disconnect()
-				return
-			}
-			break
+		if sm.nextCheckpoint == nil ||
+			node.height != sm.nextCheckpoint.Height {
+
+			continue
 		}
-	}
+		if !node.hash.IsEqual(sm.nextCheckpoint.Hash) {
+			log.Warnf("Block header at height %d/hash %s from "+
+				"peer %s does NOT match expected checkpoint "+
+				"hash of %s -- disconnecting", node.height,
+				node.hash, peer.Addr(), sm.nextCheckpoint.Hash)
+			peer.Disconnect()
+			return
+		}
+		log.Infof("Verified downloaded block header against "+
+			"checkpoint at height %d/hash %s", node.height,
+			node.hash)
+		sm.fastAddHeight = node.height
+		sm.nextCheckpoint = sm.findNextHeaderCheckpoint(node.height)
+	}
+	sm.lastProgressTime = time.Now()
+
+	// A full headers message means the peer likely has more headers, so
+	// the next batch is requested once there is room for it in the header
+	// list.  Otherwise, the header chain is complete.
+	if numHeaders != wire.MaxBlockHeadersPerMsg && !sm.headersSynced {
+		sm.headersSynced = true
+		log.Infof("Received the block headers up to height %d from "+
+			"peer %s", sm.headerList.Back().Value.(*headerNode).height,
+			peer.Addr())
+	}
+
+	// Fetch the blocks of the new headers from all sync candidates.
+	sm.fetchHeaderBlocks()
+	sm.processHeaderBlocks()
+}
+
+// requestHeaders requests the next batch of headers, starting from the latest
+// header in the header list, from the sync peer.  Nothing is requested when
+// the header chain is complete, a request is already outstanding, or the header
+// list does not have room for a full headers message.
+func (sm *SyncManager) requestHeaders() {
+	if !sm.headersFirstMode || sm.headersSynced || sm.headersRequested ||
+		sm.syncPeer == nil {
 
-	// When this header is a checkpoint, switch to fetching the blocks for
-	// all of the headers since the last checkpoint.
-	if receivedCheckpoint {
-		// Since the first entry of the list is always the final block
-		// that is already in the database and is only used to ensure
-		// the next header links properly, it must be removed before
-		// fetching the blocks.
-		sm.headerList.Remove(sm.headerList.Front())
-		log.Infof("Received %v block headers: Fetching blocks",
-			sm.headerList.Len())
-		sm.progressLogger.SetLastLogTime(time.Now())
-		sm.fetchHeaderBlocks()
+		return
+	}
+	if sm.headerList.Len()+wire.MaxBlockHeadersPerMsg > maxHeaderListLen {
 		return
 	}
 
-	// This header is not a checkpoint, so request the next batch of
-	// headers starting from the latest known header and ending with the
-	// next checkpoint.
+	finalHash := sm.headerList.Back().Value.(*headerNode).hash
 	locator := blockchain.BlockLocator([]*chainhash.Hash{finalHash})
-	err := peer.PushGetHeadersMsg(locator, sm.nextCheckpoint.Hash)
+	err := sm.syncPeer.PushGetHeadersMsg(locator, &zeroHash)
 	if err != nil {
-		log.Warnf("Failed to send getheaders message to "+
-			"peer %s: %v", peer.Addr(), err)
+		log.Warnf("Failed to send getheaders message to peer %s: %v",
+			sm.syncPeer.Addr(), err)
 		return
 	}
+	sm.headersRequested = true
 }
 
 // handleNotFoundMsg handles notfound messages from all peers.
@@ -1076,6 +1071,8 @@ func (sm *SyncManager) handleNotFoundMsg(nfmsg *notFoundMsg) {
 		// verify the hash was actually announced by the peer
 		// before deleting from the global requested maps.
 		switch inv.Type {
+		case wire.InvTypeCmpctBlock:
+			fallthrough
 		case wire.InvTypeWitnessBlock:
 			fallthrough
 		case wire.InvTypeBlock:
@@ -1310,6 +1307,16 @@ func (sm *SyncManager) handleInvMsg(imsg *invMsg) {
 					iv.Type = wire.InvTypeWitnessBlock
 				}
 
+				// Once synced, new blocks are most likely
+				// made up of transactions that are already in
+				// the mempool, so request a compact block from
+				// peers that support them.
+				if sm.current() && peer.CmpctBlockVersion() ==
+					wire.CmpctBlockVersion2 {
+
+					iv.Type = wire.InvTypeCmpctBlock
+				}
+
 				gdmsg.AddInvVect(iv)
 				numRequested++
 			}
@@ -1353,6 +1360,8 @@ func (sm *SyncManager) handleInvMsg(imsg *invMsg) {
 func (sm *SyncManager) blockHandler() {
 	stallTicker := time.NewTicker(stallSampleInterval)
 	defer stallTicker.Stop()
+	blockStallTicker := time.NewTicker(blockStallSampleInterval)
+	defer blockStallTicker.Stop()
 
 out:
 	for {
@@ -1370,6 +1379,14 @@ out:
 				sm.handleBlockMsg(msg)
 				msg.reply <- struct{}{}
 
+			case *cmpctBlockMsg:
+				sm.handleCmpctBlockMsg(msg)
+				msg.reply <- struct{}{}
+
+			case *blockTxnMsg:
+				sm.handleBlockTxnMsg(msg)
+				msg.reply <- struct{}{}
+
 			case *invMsg:
 				sm.handleInvMsg(msg)
 
@@ -1419,6 +1436,9 @@ out:
 		case <-stallTicker.C:
 			sm.handleStallSample()
 
+		case <-blockStallTicker.C:
+			sm.handleBlockStallSample()
+
 		case <-sm.quit:
 			break out
 		}
@@ -1453,9 +1473,10 @@ func (sm *SyncManager) handleBlockchainNotification(notification *blockchain.Not
 			break
 		}
 
-		// Generate the inventory vector and relay it.
+		// Generate the inventory vector and relay it.  The block itself
+		// is passed along so it can be announced via compact blocks.
 		iv := wire.NewInvVect(wire.InvTypeBlock, block.Hash())
-		sm.peerNotifier.RelayInventory(iv, block.MsgBlock().Header)
+		sm.peerNotifier.RelayInventory(iv, block)
 
 	// A block has been connected to the main block chain.
 	case blockchain.NTBlockConnected:
@@ -1480,13 +1501,14 @@ func (sm *SyncManager) handleBlockchainNotification(notification *blockchain.Not
 		// transaction are NOT removed recursively because they are still
 		// valid.
 		for _, tx := range block.Transactions()[1:] {
-			sm.txMemPool.RemoveTransaction(tx, false)
+			sm.txMemPool.RemoveConfirmedTransaction(tx)
 			sm.txMemPool.RemoveDoubleSpends(tx)
 			sm.txMemPool.RemoveOrphan(tx)
 			sm.peerNotifier.TransactionConfirmed(tx)
 			acceptedTxs := sm.txMemPool.ProcessOrphans(tx)
 			sm.peerNotifier.AnnounceNewTransactions(acceptedTxs)
 		}
+		sm.txMemPool.BlockConnected()
 
 		// Register block with the fee estimator, if it exists.
 		if sm.feeEstimator != nil {
@@ -1682,22 +1704,27 @@ func New(config *Config) (*SyncManager, error) {
 		rejectedTxns:    make(map[chainhash.Hash]struct{}),
 		requestedTxns:   make(map[chainhash.Hash]struct{}),
 		requestedBlocks: make(map[chainhash.Hash]struct{}),
+		partialBlocks:   make(map[chainhash.Hash]*partialBlock),
 		peerStates:      make(map[*peerpkg.Peer]*peerSyncState),
 		progressLogger:  newBlockProgressLogger("Processed", log),
 		msgChan:         make(chan interface{}, config.MaxPeers*3),
 		headerList:      list.New(),
+		headerBlocks:    make(map[chainhash.Hash]*list.Element),
 		quit:            make(chan struct{}),
 		feeEstimator:    config.FeeEstimator,
+		timeSource:      config.TimeSource,
+
+		disableCheckpoints: config.DisableCheckpoints,
+	}
+	if sm.timeSource == nil {
+		sm.timeSource = blockchain.NewMedianTime()
 	}
 
+	// Initialize the header state, including the next checkpoint, based on
+	// the current height.
 	best := sm.chain.BestSnapshot()
-	if !config.DisableCheckpoints {
-		// Initialize the next checkpoint based on the current height.
-		sm.nextCheckpoint = sm.findNextHeaderCheckpoint(best.Height)
-		if sm.nextCheckpoint != nil {
-			sm.resetHeaderState(&best.Hash, best.Height)
-		}
-	} else {
+	sm.resetHeaderState(&best.Hash, best.Height)
+	if config.DisableCheckpoints {
 		log.Info("Checkpoints are disabled")
 	}
 
@@ -22,6 +22,7 @@ import (
 	"github.com/bynil/btcd/blockchain"
 	"github.com/bynil/btcd/chaincfg"
 	"github.com/bynil/btcd/chaincfg/chainhash"
+	"github.com/bynil/btcd/v2transport"
 	"github.com/bynil/btcd/wire"
 	"github.com/davecgh/go-spew/spew"
 	"github.com/decred/dcrd/lru"
@@ -203,6 +204,22 @@ type MessageListeners struct {
 	// OnSendAddrV2 is invoked when a peer receives a sendaddrv2 message.
 	OnSendAddrV2 func(p *Peer, msg *wire.MsgSendAddrV2)
 
+	// OnSendCmpct is invoked when a peer receives a sendcmpct bitcoin
+	// message.
+	OnSendCmpct func(p *Peer, msg *wire.MsgSendCmpct)
+
+	// OnCmpctBlock is invoked when a peer receives a cmpctblock bitcoin
+	// message.
+	OnCmpctBlock func(p *Peer, msg *wire.MsgCmpctBlock)
+
+	// OnGetBlockTxn is invoked when a peer receives a getblocktxn bitcoin
+	// message.
+	OnGetBlockTxn func(p *Peer, msg *wire.MsgGetBlockTxn)
+
+	// OnBlockTxn is invoked when a peer receives a blocktxn bitcoin
+	// message.
+	OnBlockTxn func(p *Peer, msg *wire.MsgBlockTxn)
+
 	// OnRead is invoked when a peer receives a bitcoin message.  It
 	// consists of the number of bytes read, the message, and whether or not
 	// an error in the read occurred.  Typically, callers will opt to use
@@ -289,6 +306,13 @@ type Config struct {
 	// scenarios where the stall behavior isn't important to the system
 	// under test.
 	DisableStallHandler bool
+
+	// V2Transport specifies whether the BIP0324 v2 encrypted transport
+	// should be used.  Inbound peers accept both v1 and v2 connections
+	// when it is set, while outbound peers initiate a v2 handshake and
+	// report a failed attempt via ShouldDowngradeToV1 so the caller can
+	// reconnect using v1.
+	V2Transport bool
 }
 
 // minUint32 is a helper function to return the minimum of two uint32s.
@@ -444,6 +468,14 @@ type Peer struct {
 
 	conn net.Conn
 
+	// connReader is the reader messages are read from when the v1 protocol
+	// is used.  It is usually conn, but replays the bytes read while
+	// detecting the transport version for inbound peers.  It, along with
+	// v2Transport, is only modified during the transport negotiation before
+	// any messages are exchanged.
+	connReader  io.Reader
+	v2Transport *v2transport.Transport
+
 	// These fields are set at creation time and never modified, so they are
 	// safe to read from concurrently without a mutex.
 	addr    string
@@ -462,6 +494,9 @@ type Peer struct {
 	verAckReceived       bool
 	witnessEnabled       bool
 	sendAddrV2           bool
+	cmpctBlockVersion    uint64 // highest compact block version of peer
+	cmpctBlockHBMode     bool   // peer wants high-bandwidth cmpctblocks
+	v2Downgrade          bool   // v2 handshake failed due to a v1 peer
 
 	wireEncoding wire.MessageEncoding
 
@@ -539,6 +574,14 @@ func (p *Peer) AddKnownInventory(invVect *wire.InvVect) {
 	p.knownInventory.Add(invVect)
 }
 
+// HasKnownInventory returns whether the passed inventory is in the cache of
+// known inventory for the peer.
+//
+// This function is safe for concurrent access.
+func (p *Peer) HasKnownInventory(invVect *wire.InvVect) bool {
+	return p.knownInventory.Contains(invVect)
+}
+
 // StatsSnapshot returns a snapshot of the current peer flags and statistics.
 //
 // This function is safe for concurrent access.
@@ -827,6 +870,50 @@ func (p *Peer) IsWitnessEnabled() bool {
 	return witnessEnabled
 }
 
+// CmpctBlockVersion returns the highest compact block version the peer has
+// signalled support for via a sendcmpct message, or zero if it has not
+// signalled support for compact blocks at all.
+//
+// This function is safe for concurrent access.
+func (p *Peer) CmpctBlockVersion() uint64 {
+	p.flagsMtx.Lock()
+	version := p.cmpctBlockVersion
+	p.flagsMtx.Unlock()
+
+	return version
+}
+
+// WantsCmpctBlocks returns if the peer requested new blocks to be announced
+// by directly sending a cmpctblock message (BIP0152 high-bandwidth mode)
+// rather than an inv or headers message.
+//
+// This function is safe for concurrent access.
+func (p *Peer) WantsCmpctBlocks() bool {
+	p.flagsMtx.Lock()
+	hbMode := p.cmpctBlockHBMode
+	p.flagsMtx.Unlock()
+
+	return hbMode
+}
+
+// UsingV2Transport returns whether the connection to the peer uses the BIP0324
+// v2 encrypted transport.
+func (p *Peer) UsingV2Transport() bool {
+	return p.v2Transport != nil
+}
+
+// ShouldDowngradeToV1 returns whether an outbound v2 handshake with the peer
+// failed in a way that indicates the remote peer only supports the v1
+// protocol, in which case the caller should reconnect with V2Transport
+// disabled.
+func (p *Peer) ShouldDowngradeToV1() bool {
+	p.flagsMtx.Lock()
+	downgrade := p.v2Downgrade
+	p.flagsMtx.Unlock()
+
+	return downgrade
+}
+
 // WantsAddrV2 returns if the peer supports addrv2 messages instead of the
 // legacy addr messages.
 func (p *Peer) WantsAddrV2() bool {
@@ -1067,8 +1154,19 @@ func (p *Peer) handlePongMsg(msg *wire.MsgPong) {
 
 // readMessage reads the next bitcoin message from the peer with logging.
 func (p *Peer) readMessage(encoding wire.MessageEncoding) (wire.Message, []byte, error) {
-	n, msg, buf, err := wire.ReadMessageWithEncodingN(p.conn,
-		p.ProtocolVersion(), p.cfg.ChainParams.Net, encoding)
+	var (
+		n   int
+		msg wire.Message
+		buf []byte
+		err error
+	)
+	if p.v2Transport != nil {
+		n, msg, buf, err = p.v2Transport.ReadMessage(p.ProtocolVersion(),
+			encoding)
+	} else {
+		n, msg, buf, err = wire.ReadMessageWithEncodingN(p.connReader,
+			p.ProtocolVersion(), p.cfg.ChainParams.Net, encoding)
+	}
 	atomic.AddUint64(&p.bytesReceived, uint64(n))
 	if p.cfg.Listeners.OnRead != nil {
 		p.cfg.Listeners.OnRead(p, n, msg, err)
@@ -1130,8 +1228,17 @@ func (p *Peer) writeMessage(msg wire.Message, enc wire.MessageEncoding) error {
 	}))
 
 	// Write the message to the peer.
-	n, err := wire.WriteMessageWithEncodingN(p.conn, msg,
-		p.ProtocolVersion(), p.cfg.ChainParams.Net, enc)
+	var (
+		n   int
+		err error
+	)
+	if p.v2Transport != nil {
+		n, err = p.v2Transport.WriteMessage(msg, p.ProtocolVersion(),
+			enc)
+	} else {
+		n, err = wire.WriteMessageWithEncodingN(p.conn, msg,
+			p.ProtocolVersion(), p.cfg.ChainParams.Net, enc)
+	}
 	atomic.AddUint64(&p.bytesSent, uint64(n))
 	if p.cfg.Listeners.OnWrite != nil {
 		p.cfg.Listeners.OnWrite(p, n, msg, err)
@@ -1214,8 +1321,10 @@ func (p *Peer) maybeAddDeadline(pendingResponses map[string]time.Time, msgCmd st
 		pendingResponses[wire.CmdInv] = deadline
 
 	case wire.CmdGetData:
-		// Expects a block, merkleblock, tx, or notfound message.
+		// Expects a block, cmpctblock, merkleblock, tx, or notfound
+		// message.
 		pendingResponses[wire.CmdBlock] = deadline
+		pendingResponses[wire.CmdCmpctBlock] = deadline
 		pendingResponses[wire.CmdMerkleBlock] = deadline
 		pendingResponses[wire.CmdTx] = deadline
 		pendingResponses[wire.CmdNotFound] = deadline
@@ -1226,6 +1335,10 @@ func (p *Peer) maybeAddDeadline(pendingResponses map[string]time.Time, msgCmd st
 		// headers.
 		deadline = time.Now().Add(stallResponseTimeout * 3)
 		pendingResponses[wire.CmdHeaders] = deadline
+
+	case wire.CmdGetBlockTxn:
+		// Expects a blocktxn message.
+		pendingResponses[wire.CmdBlockTxn] = deadline
 	}
 }
 
@@ -1277,12 +1390,15 @@ out:
 				switch msgCmd := msg.message.Command(); msgCmd {
 				case wire.CmdBlock:
 					fallthrough
+				case wire.CmdCmpctBlock:
+					fallthrough
 				case wire.CmdMerkleBlock:
 					fallthrough
 				case wire.CmdTx:
 					fallthrough
 				case wire.CmdNotFound:
 					delete(pendingResponses, wire.CmdBlock)
+					delete(pendingResponses, wire.CmdCmpctBlock)
 					delete(pendingResponses, wire.CmdMerkleBlock)
 					delete(pendingResponses, wire.CmdTx)
 					delete(pendingResponses, wire.CmdNotFound)
@@ -1412,12 +1528,10 @@ out:
 				continue
 			}
 
-			// Since the protocol version is 70016 but we don't
-			// implement compact blocks, we have to ignore unknown
-			// messages after the version-verack handshake. This
-			// matches bitcoind's behavior and is necessary since
-			// compact blocks negotiation occurs after the
-			// handshake.
+			// Ignore unknown messages after the version-verack
+			// handshake.  This matches bitcoind's behavior and is
+			// necessary since feature negotiation for newer
+			// protocol extensions can occur after the handshake.
 			if err == wire.ErrUnknownMessage {
 				log.Debugf("Received unknown message from %s:"+
 					" %v", p, err)
@@ -1612,6 +1726,48 @@ out:
 				p.cfg.Listeners.OnSendHeaders(p, msg)
 			}
 
+		case *wire.MsgSendCmpct:
+			// Only versions 1 and 2 are defined.  Per BIP0152, the
+			// announcement mode applies to the version it was sent
+			// with, so only track it for the preferred version 2
+			// when the peer is witness enabled.
+			p.flagsMtx.Lock()
+			version := msg.CmpctBlockVersion
+			if version == wire.CmpctBlockVersion1 ||
+				version == wire.CmpctBlockVersion2 {
+
+				if version > p.cmpctBlockVersion {
+					p.cmpctBlockVersion = version
+				}
+				preferred := wire.CmpctBlockVersion1
+				if p.witnessEnabled {
+					preferred = wire.CmpctBlockVersion2
+				}
+				if version == preferred {
+					p.cmpctBlockHBMode = msg.AnnounceUsingCmpctBlock
+				}
+			}
+			p.flagsMtx.Unlock()
+
+			if p.cfg.Listeners.OnSendCmpct != nil {
+				p.cfg.Listeners.OnSendCmpct(p, msg)
+			}
+
+		case *wire.MsgCmpctBlock:
+			if p.cfg.Listeners.OnCmpctBlock != nil {
+				p.cfg.Listeners.OnCmpctBlock(p, msg)
+			}
+
+		case *wire.MsgGetBlockTxn:
+			if p.cfg.Listeners.OnGetBlockTxn != nil {
+				p.cfg.Listeners.OnGetBlockTxn(p, msg)
+			}
+
+		case *wire.MsgBlockTxn:
+			if p.cfg.Listeners.OnBlockTxn != nil {
+				p.cfg.Listeners.OnBlockTxn(p, msg)
+			}
+
 		default:
 			log.Debugf("Received unhandled message of type %v "+
 				"from %v", rmsg.Command(), p)
@@ -2279,12 +2435,55 @@ func (p *Peer) negotiateOutboundProtocol() error {
 	return p.waitToFinishNegotiation(protoVersion)
 }
 
+// negotiateTransport performs the BIP0324 v2 handshake when the v2 transport
+// is enabled.  Inbound peers fall back to the v1 protocol when the remote peer
+// is detected to use it, while outbound peers record that they should
+// downgrade when the remote peer rejects the handshake.
+func (p *Peer) negotiateTransport() error {
+	if !p.cfg.V2Transport {
+		return nil
+	}
+
+	if p.inbound {
+		transport, conn, err := v2transport.NewResponder(p.conn,
+			p.cfg.ChainParams.Net)
+		if err != nil {
+			return err
+		}
+		p.connReader = conn
+		p.v2Transport = transport
+	} else {
+		transport, err := v2transport.NewInitiator(p.conn,
+			p.cfg.ChainParams.Net)
+		if err == v2transport.ErrShouldDowngradeToV1 {
+			p.flagsMtx.Lock()
+			p.v2Downgrade = true
+			p.flagsMtx.Unlock()
+		}
+		if err != nil {
+			return err
+		}
+		p.v2Transport = transport
+	}
+
+	if p.v2Transport != nil {
+		log.Debugf("Using v2 transport with %s (session id %x)", p,
+			p.v2Transport.SessionID())
+	}
+	return nil
+}
+
 // start begins processing input and output messages.
 func (p *Peer) start() error {
 	log.Tracef("Starting peer %s", p)
 
 	negotiateErr := make(chan error, 1)
 	go func() {
+		if err := p.negotiateTransport(); err != nil {
+			negotiateErr <- err
+			return
+		}
+
 		if p.inbound {
 			negotiateErr <- p.negotiateInboundProtocol()
 		} else {
@@ -2325,6 +2524,7 @@ func (p *Peer) AssociateConnection(conn net.Conn) {
 	}
 
 	p.conn = conn
+	p.connReader = conn
 	p.timeConnected = time.Now()
 
 	if p.inbound {
@@ -355,7 +355,7 @@ func TestPeerConnection(t *testing.T) {
 // TestPeerListeners tests that the peer listeners are called as expected.
 func TestPeerListeners(t *testing.T) {
 	verack := make(chan struct{}, 1)
-	ok := make(chan wire.Message, 22)
+	ok := make(chan wire.Message, 26)
 	peerCfg := &peer.Config{
 		Listeners: peer.MessageListeners{
 			OnGetAddr: func(p *peer.Peer, msg *wire.MsgGetAddr) {
@@ -449,6 +449,18 @@ func TestPeerListeners(t *testing.T) {
 			OnAddrV2: func(p *peer.Peer, msg *wire.MsgAddrV2) {
 				ok <- msg
 			},
+			OnSendCmpct: func(p *peer.Peer, msg *wire.MsgSendCmpct) {
+				ok <- msg
+			},
+			OnCmpctBlock: func(p *peer.Peer, msg *wire.MsgCmpctBlock) {
+				ok <- msg
+			},
+			OnGetBlockTxn: func(p *peer.Peer, msg *wire.MsgGetBlockTxn) {
+				ok <- msg
+			},
+			OnBlockTxn: func(p *peer.Peer, msg *wire.MsgBlockTxn) {
+				ok <- msg
+			},
 		},
 		UserAgentName:     "peer",
 		UserAgentVersion:  "1.0",
@@ -599,6 +611,23 @@ func TestPeerListeners(t *testing.T) {
 			"OnSendHeaders",
 			wire.NewMsgSendHeaders(),
 		},
+		{
+			"OnSendCmpct",
+			wire.NewMsgSendCmpct(true, wire.CmpctBlockVersion2),
+		},
+		{
+			"OnCmpctBlock",
+			wire.NewMsgCmpctBlock(wire.NewBlockHeader(1,
+				&chainhash.Hash{}, &chainhash.Hash{}, 1, 1), 1),
+		},
+		{
+			"OnGetBlockTxn",
+			wire.NewMsgGetBlockTxn(&chainhash.Hash{}),
+		},
+		{
+			"OnBlockTxn",
+			wire.NewMsgBlockTxn(&chainhash.Hash{}, nil),
+		},
 		{
 			"OnSendAddrV2",
 			wire.NewMsgSendAddrV2(),
@@ -1209,3 +1238,106 @@ func TestSendAddrV2Handshake(t *testing.T) {
 		outPeer.WaitForDisconnect()
 	}
 }
+
+// TestV2TransportHandshake tests that peers negotiate the BIP0324 v2 transport
+// when both sides support it, that inbound peers fall back to v1 for v1
+// outbound peers, and that outbound peers report when they should downgrade.
+func TestV2TransportHandshake(t *testing.T) {
+	tests := []struct {
+		name          string
+		inboundV2     bool
+		outboundV2    bool
+		wantV2        bool
+		wantVerAck    bool
+		wantDowngrade bool
+	}{
+		{"both v2", true, true, true, true, false},
+		{"v2 inbound, v1 outbound", true, false, false, true, false},
+		{"v1 inbound, v2 outbound", false, true, false, false, true},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		verack := make(chan struct{}, 2)
+		listeners := peer.MessageListeners{
+			OnVerAck: func(p *peer.Peer, msg *wire.MsgVerAck) {
+				verack <- struct{}{}
+			},
+		}
+		inCfg := &peer.Config{
+			Listeners:      listeners,
+			AllowSelfConns: true,
+			ChainParams:    &chaincfg.MainNetParams,
+			V2Transport:    test.inboundV2,
+		}
+		outCfg := &peer.Config{
+			Listeners:      listeners,
+			AllowSelfConns: true,
+			ChainParams:    &chaincfg.MainNetParams,
+			V2Transport:    test.outboundV2,
+		}
+
+		inPeer := peer.NewInboundPeer(inCfg)
+		outPeer, err := peer.NewOutboundPeer(outCfg, "10.0.0.2:8333")
+		if err != nil {
+			t.Fatalf("%s: NewOutboundPeer: unexpected err: %v",
+				test.name, err)
+		}
+		if err := setupPeerConnection(inPeer, outPeer); err != nil {
+			t.Fatalf("%s: setupPeerConnection: unexpected err: %v",
+				test.name, err)
+		}
+
+		if test.wantVerAck {
+			for j := 0; j < 2; j++ {
+				select {
+				case <-verack:
+				case <-time.After(time.Second * 2):
+					t.Fatalf("%s: verack timeout", test.name)
+				}
+			}
+		} else {
+			// The outbound peer is expected to be disconnected during
+			// the handshake.
+			select {
+			case <-disconnected(outPeer):
+			case <-time.After(time.Second * 2):
+				t.Fatalf("%s: disconnect timeout", test.name)
+			}
+		}
+
+		if test.wantVerAck {
+			if inPeer.UsingV2Transport() != test.wantV2 {
+				t.Fatalf("#%d %s: inbound v2 transport got %v, "+
+					"want %v", i, test.name,
+					inPeer.UsingV2Transport(), test.wantV2)
+			}
+			if outPeer.UsingV2Transport() != test.wantV2 {
+				t.Fatalf("#%d %s: outbound v2 transport got %v, "+
+					"want %v", i, test.name,
+					outPeer.UsingV2Transport(), test.wantV2)
+			}
+		}
+		if outPeer.ShouldDowngradeToV1() != test.wantDowngrade {
+			t.Fatalf("#%d %s: downgrade got %v, want %v", i,
+				test.name, outPeer.ShouldDowngradeToV1(),
+				test.wantDowngrade)
+		}
+
+		inPeer.Disconnect()
+		outPeer.Disconnect()
+		inPeer.WaitForDisconnect()
+		outPeer.WaitForDisconnect()
+	}
+}
+
+// disconnected returns a channel which is closed once the passed peer has
+// disconnected.
+func disconnected(p *peer.Peer) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		p.WaitForDisconnect()
+		close(done)
+	}()
+	return done
+}
@@ -28,6 +28,11 @@ type SigHashType string
 
 // Constants used to indicate the signature hash type for SignRawTransaction.
 const (
+	// SigHashDefault indicates the default signature hash type, which
+	// signs ALL of the outputs and omits the hash type from taproot
+	// signatures.
+	SigHashDefault SigHashType = "DEFAULT"
+
 	// SigHashAll indicates ALL of the outputs should be signed.
 	SigHashAll SigHashType = "ALL"
 
@@ -726,6 +731,154 @@ func (c *Client) SignRawTransactionWithWallet3(tx *wire.MsgTx,
 	return c.SignRawTransactionWithWallet3Async(tx, inputs, hashType).Receive()
 }
 
+// FutureSignRawTransactionWithKeyResult is a future promise to deliver the
+// result of the SignRawTransactionWithKeyAsync RPC invocation (or an
+// applicable error).
+type FutureSignRawTransactionWithKeyResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// signed transaction as well as whether or not all inputs are now signed.
+func (r FutureSignRawTransactionWithKeyResult) Receive() (*wire.MsgTx, bool, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Unmarshal as a signrawtransactionwithkey result.
+	var signRawTxWithKeyResult btcjson.SignRawTransactionWithKeyResult
+	err = json.Unmarshal(res, &signRawTxWithKeyResult)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Decode the serialized transaction hex to raw bytes.
+	serializedTx, err := hex.DecodeString(signRawTxWithKeyResult.Hex)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// Deserialize the transaction and return it.
+	var msgTx wire.MsgTx
+	if err := msgTx.Deserialize(bytes.NewReader(serializedTx)); err != nil {
+		return nil, false, err
+	}
+
+	return &msgTx, signRawTxWithKeyResult.Complete, nil
+}
+
+// SignRawTransactionWithKeyAsync returns an instance of a type that can be
+// used to get the result of the RPC at some future time by invoking the
+// Receive function on the returned instance.
+//
+// See SignRawTransactionWithKey for the blocking version and more details.
+func (c *Client) SignRawTransactionWithKeyAsync(tx *wire.MsgTx,
+	privKeys []*btcutil.WIF, inputs []btcjson.RawTxWitnessInput,
+	hashType SigHashType) FutureSignRawTransactionWithKeyResult {
+
+	txHex := ""
+	if tx != nil {
+		// Serialize the transaction and convert to hex string.
+		buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+		if err := tx.Serialize(buf); err != nil {
+			return newFutureError(err)
+		}
+		txHex = hex.EncodeToString(buf.Bytes())
+	}
+
+	wifs := make([]string, 0, len(privKeys))
+	for _, privKey := range privKeys {
+		wifs = append(wifs, privKey.String())
+	}
+
+	var inputsPtr *[]btcjson.RawTxWitnessInput
+	if inputs != nil {
+		inputsPtr = &inputs
+	}
+	var hashTypePtr *string
+	if hashType != "" {
+		hashTypePtr = btcjson.String(string(hashType))
+	}
+
+	cmd := btcjson.NewSignRawTransactionWithKeyCmd(txHex, wifs, inputsPtr,
+		hashTypePtr)
+	return c.SendCmd(cmd)
+}
+
+// SignRawTransactionWithKey signs inputs for the passed transaction with the
+// passed private keys and returns the signed transaction as well as whether
+// or not all inputs are now signed.
+//
+// The only input transactions that need to be specified are ones the RPC
+// server does not have in its UTXO set or memory pool, or the ones spending
+// script hash outputs which need the redeem or witness script.  An empty hash
+// type uses the default signature hash type.
+func (c *Client) SignRawTransactionWithKey(tx *wire.MsgTx,
+	privKeys []*btcutil.WIF, inputs []btcjson.RawTxWitnessInput,
+	hashType SigHashType) (*wire.MsgTx, bool, error) {
+
+	return c.SignRawTransactionWithKeyAsync(tx, privKeys, inputs,
+		hashType).Receive()
+}
+
+// FutureCombineRawTransactionResult is a future promise to deliver the result
+// of the CombineRawTransactionAsync RPC invocation (or an applicable error).
+type FutureCombineRawTransactionResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// combined transaction.
+func (r FutureCombineRawTransactionResult) Receive() (*wire.MsgTx, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a string.
+	var txHex string
+	err = json.Unmarshal(res, &txHex)
+	if err != nil {
+		return nil, err
+	}
+
+	// Decode the serialized transaction hex to raw bytes.
+	serializedTx, err := hex.DecodeString(txHex)
+	if err != nil {
+		return nil, err
+	}
+
+	// Deserialize the transaction and return it.
+	var msgTx wire.MsgTx
+	if err := msgTx.Deserialize(bytes.NewReader(serializedTx)); err != nil {
+		return nil, err
+	}
+	return &msgTx, nil
+}
+
+// CombineRawTransactionAsync returns an instance of a type that can be used to
+// get the result of the RPC at some future time by invoking the Receive
+// function on the returned instance.
+//
+// See CombineRawTransaction for the blocking version and more details.
+func (c *Client) CombineRawTransactionAsync(txns []*wire.MsgTx) FutureCombineRawTransactionResult {
+	txHexes := make([]string, 0, len(txns))
+	for _, tx := range txns {
+		// Serialize the transaction and convert to hex string.
+		buf := bytes.NewBuffer(make([]byte, 0, tx.SerializeSize()))
+		if err := tx.Serialize(buf); err != nil {
+			return newFutureError(err)
+		}
+		txHexes = append(txHexes, hex.EncodeToString(buf.Bytes()))
+	}
+
+	cmd := btcjson.NewCombineRawTransactionCmd(txHexes)
+	return c.SendCmd(cmd)
+}
+
+// CombineRawTransaction combines the signatures of multiple partially signed
+// copies of the same transaction into one transaction.
+func (c *Client) CombineRawTransaction(txns []*wire.MsgTx) (*wire.MsgTx, error) {
+	return c.CombineRawTransactionAsync(txns).Receive()
+}
+
 // FutureSearchRawTransactionsResult is a future promise to deliver the result
 // of the SearchRawTransactionsAsync RPC invocation (or an applicable error).
 type FutureSearchRawTransactionsResult chan *Response
@@ -1082,3 +1235,214 @@ func (c *Client) GetTxSpendingPrevOut(outpoints []wire.OutPoint) (
 
 	return c.GetTxSpendingPrevOutAsync(outpoints).Receive()
 }
+
+// FuturePsbtResult is a future promise to deliver the result of an RPC which
+// returns a base64 encoded PSBT, such as CombinePsbt, CreatePsbt, JoinPsbts
+// and UtxoUpdatePsbt (or an applicable error).
+type FuturePsbtResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// base64 encoded PSBT.
+func (r FuturePsbtResult) Receive() (string, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return "", err
+	}
+
+	// Unmarshal result as a string.
+	var psbt string
+	err = json.Unmarshal(res, &psbt)
+	if err != nil {
+		return "", err
+	}
+
+	return psbt, nil
+}
+
+// FutureAnalyzePsbtResult is a future promise to deliver the result of an
+// AnalyzePsbt RPC invocation (or an applicable error).
+type FutureAnalyzePsbtResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// analysis of the PSBT.
+func (r FutureAnalyzePsbtResult) Receive() (*btcjson.AnalyzePsbtResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as an analyzepsbt result object.
+	var analyzeRes btcjson.AnalyzePsbtResult
+	err = json.Unmarshal(res, &analyzeRes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &analyzeRes, nil
+}
+
+// AnalyzePsbtAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See AnalyzePsbt for the blocking version and more details.
+func (c *Client) AnalyzePsbtAsync(psbt string) FutureAnalyzePsbtResult {
+	cmd := btcjson.NewAnalyzePsbtCmd(psbt)
+	return c.SendCmd(cmd)
+}
+
+// AnalyzePsbt returns the state of the passed base64 encoded PSBT and of its
+// inputs, and the role which is next to act on it.
+func (c *Client) AnalyzePsbt(psbt string) (*btcjson.AnalyzePsbtResult, error) {
+	return c.AnalyzePsbtAsync(psbt).Receive()
+}
+
+// CombinePsbtAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See CombinePsbt for the blocking version and more details.
+func (c *Client) CombinePsbtAsync(psbts []string) FuturePsbtResult {
+	cmd := btcjson.NewCombinePsbtCmd(psbts)
+	return c.SendCmd(cmd)
+}
+
+// CombinePsbt combines the passed base64 encoded PSBTs of the same
+// transaction into one.
+func (c *Client) CombinePsbt(psbts []string) (string, error) {
+	return c.CombinePsbtAsync(psbts).Receive()
+}
+
+// CreatePsbtAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See CreatePsbt for the blocking version and more details.
+func (c *Client) CreatePsbtAsync(inputs []btcjson.CreatePsbtInput,
+	outputs []btcjson.PsbtOutput, lockTime *int64,
+	replaceable *bool) FuturePsbtResult {
+
+	cmd := btcjson.NewCreatePsbtCmd(inputs, outputs, lockTime, replaceable)
+	return c.SendCmd(cmd)
+}
+
+// CreatePsbt returns a new base64 encoded PSBT spending the passed inputs to
+// the passed outputs.
+func (c *Client) CreatePsbt(inputs []btcjson.CreatePsbtInput,
+	outputs []btcjson.PsbtOutput, lockTime *int64,
+	replaceable *bool) (string, error) {
+
+	return c.CreatePsbtAsync(inputs, outputs, lockTime, replaceable).Receive()
+}
+
+// FutureDecodePsbtResult is a future promise to deliver the result of a
+// DecodePsbt RPC invocation (or an applicable error).
+type FutureDecodePsbtResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// decoded PSBT.
+func (r FutureDecodePsbtResult) Receive() (*btcjson.DecodePsbtResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a decodepsbt result object.
+	var decodeRes btcjson.DecodePsbtResult
+	err = json.Unmarshal(res, &decodeRes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decodeRes, nil
+}
+
+// DecodePsbtAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See DecodePsbt for the blocking version and more details.
+func (c *Client) DecodePsbtAsync(psbt string) FutureDecodePsbtResult {
+	cmd := btcjson.NewDecodePsbtCmd(psbt)
+	return c.SendCmd(cmd)
+}
+
+// DecodePsbt returns information about the passed base64 encoded PSBT.
+func (c *Client) DecodePsbt(psbt string) (*btcjson.DecodePsbtResult, error) {
+	return c.DecodePsbtAsync(psbt).Receive()
+}
+
+// FutureFinalizePsbtResult is a future promise to deliver the result of a
+// FinalizePsbt RPC invocation (or an applicable error).
+type FutureFinalizePsbtResult chan *Response
+
+// Receive waits for the Response promised by the future and returns the
+// finalized PSBT or the extracted transaction.
+func (r FutureFinalizePsbtResult) Receive() (*btcjson.FinalizePsbtResult, error) {
+	res, err := ReceiveFuture(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unmarshal result as a finalizepsbt result object.
+	var finalizeRes btcjson.FinalizePsbtResult
+	err = json.Unmarshal(res, &finalizeRes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &finalizeRes, nil
+}
+
+// FinalizePsbtAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See FinalizePsbt for the blocking version and more details.
+func (c *Client) FinalizePsbtAsync(psbt string,
+	extract *bool) FutureFinalizePsbtResult {
+
+	cmd := btcjson.NewFinalizePsbtCmd(psbt, extract)
+	return c.SendCmd(cmd)
+}
+
+// FinalizePsbt finalizes the inputs of the passed base64 encoded PSBT and,
+// when it's complete and extract is nil or true, returns the network
+// serialized transaction instead of the PSBT.
+func (c *Client) FinalizePsbt(psbt string,
+	extract *bool) (*btcjson.FinalizePsbtResult, error) {
+
+	return c.FinalizePsbtAsync(psbt, extract).Receive()
+}
+
+// JoinPsbtsAsync returns an instance of a type that can be used to get the
+// result of the RPC at some future time by invoking the Receive function on
+// the returned instance.
+//
+// See JoinPsbts for the blocking version and more details.
+func (c *Client) JoinPsbtsAsync(psbts []string) FuturePsbtResult {
+	cmd := btcjson.NewJoinPsbtsCmd(psbts)
+	return c.SendCmd(cmd)
+}
+
+// JoinPsbts joins the inputs and outputs of the passed base64 encoded PSBTs
+// into one PSBT.
+func (c *Client) JoinPsbts(psbts []string) (string, error) {
+	return c.JoinPsbtsAsync(psbts).Receive()
+}
+
+// UtxoUpdatePsbtAsync returns an instance of a type that can be used to get
+// the result of the RPC at some future time by invoking the Receive function
+// on the returned instance.
+//
+// See UtxoUpdatePsbt for the blocking version and more details.
+func (c *Client) UtxoUpdatePsbtAsync(psbt string) FuturePsbtResult {
+	cmd := btcjson.NewUtxoUpdatePsbtCmd(psbt)
+	return c.SendCmd(cmd)
+}
+
+// UtxoUpdatePsbt adds the UTXOs spent by the inputs of the passed base64
+// encoded PSBT which the node knows about.
+func (c *Client) UtxoUpdatePsbt(psbt string) (string, error) {
+	return c.UtxoUpdatePsbtAsync(psbt).Receive()
+}
@@ -10,16 +10,21 @@ import (
 	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/big"
 	"math/rand"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -29,9 +34,16 @@ import (
 	"github.com/btcsuite/websocket"
 	"github.com/bynil/btcd/blockchain"
 	"github.com/bynil/btcd/blockchain/indexers"
+	"github.com/bynil/btcd/btcec/v2"
 	"github.com/bynil/btcd/btcec/v2/ecdsa"
+	"github.com/bynil/btcd/btcec/v2/schnorr"
 	"github.com/bynil/btcd/btcjson"
 	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/btcutil/base58"
+	"github.com/bynil/btcd/btcutil/bloom"
+	"github.com/bynil/btcd/btcutil/descriptor"
+	"github.com/bynil/btcd/btcutil/hdkeychain"
+	"github.com/bynil/btcd/btcutil/psbt"
 	"github.com/bynil/btcd/chaincfg"
 	"github.com/bynil/btcd/chaincfg/chainhash"
 	"github.com/bynil/btcd/database"
@@ -41,6 +53,7 @@ import (
 	"github.com/bynil/btcd/peer"
 	"github.com/bynil/btcd/txscript"
 	"github.com/bynil/btcd/wire"
+	"github.com/bynil/btcd/zmq"
 )
 
 // API version constants
@@ -133,59 +146,88 @@ type commandHandler func(*rpcServer, interface{}, <-chan struct{}) (interface{},
 // a dependency loop.
 var rpcHandlers map[string]commandHandler
 var rpcHandlersBeforeInit = map[string]commandHandler{
-	"addnode":                handleAddNode,
-	"createrawtransaction":   handleCreateRawTransaction,
-	"debuglevel":             handleDebugLevel,
-	"decoderawtransaction":   handleDecodeRawTransaction,
-	"decodescript":           handleDecodeScript,
-	"estimatefee":            handleEstimateFee,
-	"generate":               handleGenerate,
-	"getaddednodeinfo":       handleGetAddedNodeInfo,
-	"getbestblock":           handleGetBestBlock,
-	"getbestblockhash":       handleGetBestBlockHash,
-	"getblock":               handleGetBlock,
-	"getblockchaininfo":      handleGetBlockChainInfo,
-	"getblockcount":          handleGetBlockCount,
-	"getblockhash":           handleGetBlockHash,
-	"getblockheader":         handleGetBlockHeader,
-	"getblocktemplate":       handleGetBlockTemplate,
-	"getchaintips":           handleGetChainTips,
-	"getcfilter":             handleGetCFilter,
-	"getcfilterheader":       handleGetCFilterHeader,
-	"getconnectioncount":     handleGetConnectionCount,
-	"getcurrentnet":          handleGetCurrentNet,
-	"getdifficulty":          handleGetDifficulty,
-	"getgenerate":            handleGetGenerate,
-	"gethashespersec":        handleGetHashesPerSec,
-	"getheaders":             handleGetHeaders,
-	"getinfo":                handleGetInfo,
-	"getmempoolinfo":         handleGetMempoolInfo,
-	"getmininginfo":          handleGetMiningInfo,
-	"getnettotals":           handleGetNetTotals,
-	"getnetworkhashps":       handleGetNetworkHashPS,
-	"getnodeaddresses":       handleGetNodeAddresses,
-	"getpeerinfo":            handleGetPeerInfo,
-	"getrawmempool":          handleGetRawMempool,
-	"getrawtransaction":      handleGetRawTransaction,
-	"gettxout":               handleGetTxOut,
-	"help":                   handleHelp,
-	"invalidateblock":        handleInvalidateBlock,
-	"node":                   handleNode,
-	"ping":                   handlePing,
-	"reconsiderblock":        handleReconsiderBlock,
-	"searchrawtransactions":  handleSearchRawTransactions,
-	"sendrawtransaction":     handleSendRawTransaction,
-	"setgenerate":            handleSetGenerate,
-	"signmessagewithprivkey": handleSignMessageWithPrivKey,
-	"stop":                   handleStop,
-	"submitblock":            handleSubmitBlock,
-	"uptime":                 handleUptime,
-	"validateaddress":        handleValidateAddress,
-	"verifychain":            handleVerifyChain,
-	"verifymessage":          handleVerifyMessage,
-	"version":                handleVersion,
-	"testmempoolaccept":      handleTestMempoolAccept,
-	"gettxspendingprevout":   handleGetTxSpendingPrevOut,
+	"addnode":                    handleAddNode,
+	"analyzepsbt":                handleAnalyzePsbt,
+	"combinepsbt":                handleCombinePsbt,
+	"combinerawtransaction":      handleCombineRawTransaction,
+	"createpsbt":                 handleCreatePsbt,
+	"createrawtransaction":       handleCreateRawTransaction,
+	"debuglevel":                 handleDebugLevel,
+	"decodepsbt":                 handleDecodePsbt,
+	"decoderawtransaction":       handleDecodeRawTransaction,
+	"decodescript":               handleDecodeScript,
+	"deriveaddresses":            handleDeriveAddresses,
+	"dumptxoutset":               handleDumpTxOutSet,
+	"estimatefee":                handleEstimateFee,
+	"finalizepsbt":               handleFinalizePsbt,
+	"generate":                   handleGenerate,
+	"getaddednodeinfo":           handleGetAddedNodeInfo,
+	"getbestblock":               handleGetBestBlock,
+	"getbestblockhash":           handleGetBestBlockHash,
+	"getblock":                   handleGetBlock,
+	"getblockchaininfo":          handleGetBlockChainInfo,
+	"getblockcount":              handleGetBlockCount,
+	"getblockfilter":             handleGetBlockFilter,
+	"getblockhash":               handleGetBlockHash,
+	"getblockheader":             handleGetBlockHeader,
+	"getblockstats":              handleGetBlockStats,
+	"getblocktemplate":           handleGetBlockTemplate,
+	"getchaintips":               handleGetChainTips,
+	"getcfilter":                 handleGetCFilter,
+	"getcfilterheader":           handleGetCFilterHeader,
+	"getconnectioncount":         handleGetConnectionCount,
+	"getcurrentnet":              handleGetCurrentNet,
+	"getdescriptorinfo":          handleGetDescriptorInfo,
+	"getdifficulty":              handleGetDifficulty,
+	"getgenerate":                handleGetGenerate,
+	"gethashespersec":            handleGetHashesPerSec,
+	"getheaders":                 handleGetHeaders,
+	"getindexinfo":               handleGetIndexInfo,
+	"getinfo":                    handleGetInfo,
+	"getmempoolancestors":        handleGetMempoolAncestors,
+	"getmempooldescendants":      handleGetMempoolDescendants,
+	"getmempoolentry":            handleGetMempoolEntry,
+	"getmempoolinfo":             handleGetMempoolInfo,
+	"getmininginfo":              handleGetMiningInfo,
+	"getnettotals":               handleGetNetTotals,
+	"getnetworkhashps":           handleGetNetworkHashPS,
+	"getnodeaddresses":           handleGetNodeAddresses,
+	"getpeerinfo":                handleGetPeerInfo,
+	"getprioritisedtransactions": handleGetPrioritisedTransactions,
+	"getrawmempool":              handleGetRawMempool,
+	"getrawtransaction":          handleGetRawTransaction,
+	"gettxout":                   handleGetTxOut,
+	"gettxoutproof":              handleGetTxOutProof,
+	"gettxoutsetinfo":            handleGetTxOutSetInfo,
+	"getzmqnotifications":        handleGetZmqNotifications,
+	"help":                       handleHelp,
+	"importmempool":              handleImportMempool,
+	"invalidateblock":            handleInvalidateBlock,
+	"joinpsbts":                  handleJoinPsbts,
+	"loadtxoutset":               handleLoadTxOutSet,
+	"node":                       handleNode,
+	"ping":                       handlePing,
+	"prioritisetransaction":      handlePrioritiseTransaction,
+	"reconsiderblock":            handleReconsiderBlock,
+	"savemempool":                handleSaveMempool,
+	"scantxoutset":               handleScanTxOutSet,
+	"searchrawtransactions":      handleSearchRawTransactions,
+	"sendrawtransaction":         handleSendRawTransaction,
+	"setgenerate":                handleSetGenerate,
+	"signmessagewithprivkey":     handleSignMessageWithPrivKey,
+	"signrawtransactionwithkey":  handleSignRawTransactionWithKey,
+	"stop":                       handleStop,
+	"submitblock":                handleSubmitBlock,
+	"submitpackage":              handleSubmitPackage,
+	"uptime":                     handleUptime,
+	"utxoupdatepsbt":             handleUtxoUpdatePsbt,
+	"validateaddress":            handleValidateAddress,
+	"verifychain":                handleVerifyChain,
+	"verifymessage":              handleVerifyMessage,
+	"verifytxoutproof":           handleVerifyTxOutProof,
+	"version":                    handleVersion,
+	"testmempoolaccept":          handleTestMempoolAccept,
+	"gettxspendingprevout":       handleGetTxSpendingPrevOut,
 }
 
 // list of commands that we recognize, but for which btcd has no support because
@@ -208,7 +250,6 @@ var rpcAskWallet = map[string]struct{}{
 	"getreceivedbyaccount":   {},
 	"getreceivedbyaddress":   {},
 	"gettransaction":         {},
-	"gettxoutsetinfo":        {},
 	"getunconfirmedbalance":  {},
 	"getwalletinfo":          {},
 	"importprivkey":          {},
@@ -239,7 +280,6 @@ var rpcAskWallet = map[string]struct{}{
 // Commands that are currently unimplemented, but should ultimately be.
 var rpcUnimplemented = map[string]struct{}{
 	"estimatepriority": {},
-	"getmempoolentry":  {},
 	"getnetworkinfo":   {},
 	"getwork":          {},
 	"preciousblock":    {},
@@ -261,37 +301,56 @@ var rpcLimited = map[string]struct{}{
 	"help": {},
 
 	// HTTP/S-only commands
-	"createrawtransaction":  {},
-	"decoderawtransaction":  {},
-	"decodescript":          {},
-	"estimatefee":           {},
-	"getbestblock":          {},
-	"getbestblockhash":      {},
-	"getblock":              {},
-	"getblockcount":         {},
-	"getblockhash":          {},
-	"getblockheader":        {},
-	"getchaintips":          {},
-	"getcfilter":            {},
-	"getcfilterheader":      {},
-	"getcurrentnet":         {},
-	"getdifficulty":         {},
-	"getheaders":            {},
-	"getinfo":               {},
-	"getnettotals":          {},
-	"getnetworkhashps":      {},
-	"getrawmempool":         {},
-	"getrawtransaction":     {},
-	"gettxout":              {},
-	"invalidateblock":       {},
-	"reconsiderblock":       {},
-	"searchrawtransactions": {},
-	"sendrawtransaction":    {},
-	"submitblock":           {},
-	"uptime":                {},
-	"validateaddress":       {},
-	"verifymessage":         {},
-	"version":               {},
+	"analyzepsbt":                {},
+	"combinepsbt":                {},
+	"combinerawtransaction":      {},
+	"createpsbt":                 {},
+	"createrawtransaction":       {},
+	"decodepsbt":                 {},
+	"decoderawtransaction":       {},
+	"decodescript":               {},
+	"deriveaddresses":            {},
+	"estimatefee":                {},
+	"finalizepsbt":               {},
+	"getbestblock":               {},
+	"getbestblockhash":           {},
+	"getblock":                   {},
+	"getblockcount":              {},
+	"getblockfilter":             {},
+	"getblockhash":               {},
+	"getblockheader":             {},
+	"getchaintips":               {},
+	"getcfilter":                 {},
+	"getcfilterheader":           {},
+	"getcurrentnet":              {},
+	"getdescriptorinfo":          {},
+	"getdifficulty":              {},
+	"getheaders":                 {},
+	"getinfo":                    {},
+	"getmempoolancestors":        {},
+	"getmempooldescendants":      {},
+	"getmempoolentry":            {},
+	"getnettotals":               {},
+	"getnetworkhashps":           {},
+	"getprioritisedtransactions": {},
+	"getrawmempool":              {},
+	"getrawtransaction":          {},
+	"gettxout":                   {},
+	"gettxoutproof":              {},
+	"gettxoutsetinfo":            {},
+	"invalidateblock":            {},
+	"joinpsbts":                  {},
+	"reconsiderblock":            {},
+	"searchrawtransactions":      {},
+	"sendrawtransaction":         {},
+	"submitblock":                {},
+	"submitpackage":              {},
+	"uptime":                     {},
+	"utxoupdatepsbt":             {},
+	"validateaddress":            {},
+	"verifymessage":              {},
+	"verifytxoutproof":           {},
+	"version":                    {},
 }
 
 // builderScript is a convenience function which is used for hard-coded scripts
@@ -521,6 +580,828 @@ func messageToHex(msg wire.Message) (string, error) {
 	return hex.EncodeToString(buf.Bytes()), nil
 }
 
+// psbtRole is one of the roles of BIP0174 which process a PSBT, in the order
+// in which they do.
+type psbtRole int
+
+// These constants define the roles reported by the analyzepsbt command.
+const (
+	psbtRoleCreator psbtRole = iota
+	psbtRoleUpdater
+	psbtRoleSigner
+	psbtRoleFinalizer
+	psbtRoleExtractor
+)
+
+// psbtRoleStrings is a map of PSBT roles back to their names.
+var psbtRoleStrings = map[psbtRole]string{
+	psbtRoleCreator:   "creator",
+	psbtRoleUpdater:   "updater",
+	psbtRoleSigner:    "signer",
+	psbtRoleFinalizer: "finalizer",
+	psbtRoleExtractor: "extractor",
+}
+
+// String returns the name of the role as reported by analyzepsbt.
+func (r psbtRole) String() string {
+	return psbtRoleStrings[r]
+}
+
+// sigHashTypeStrings is a map of signature hash types to their names as used
+// by the PSBT and signing commands.
+var sigHashTypeStrings = map[txscript.SigHashType]string{
+	txscript.SigHashDefault:                               "DEFAULT",
+	txscript.SigHashAll:                                   "ALL",
+	txscript.SigHashNone:                                  "NONE",
+	txscript.SigHashSingle:                                "SINGLE",
+	txscript.SigHashAll | txscript.SigHashAnyOneCanPay:    "ALL|ANYONECANPAY",
+	txscript.SigHashNone | txscript.SigHashAnyOneCanPay:   "NONE|ANYONECANPAY",
+	txscript.SigHashSingle | txscript.SigHashAnyOneCanPay: "SINGLE|ANYONECANPAY",
+}
+
+// dummySignatureLen is the size of the largest DER encoded signature with its
+// signature hash type, which is used to estimate the size of the signature
+// script and witness of inputs which aren't signed yet.
+const dummySignatureLen = 72
+
+// decodePsbtArg decodes the passed base64 encoded PSBT argument of a PSBT
+// command.
+func decodePsbtArg(b64 string) (*psbt.Packet, error) {
+	packet, err := psbt.NewFromRawBytes(strings.NewReader(b64), true)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "TX decode failed: " + err.Error(),
+		}
+	}
+	return packet, nil
+}
+
+// encodePsbt returns the base64 encoding of the passed PSBT.
+func encodePsbt(packet *psbt.Packet) (string, error) {
+	b64, err := packet.B64Encode()
+	if err != nil {
+		context := "Failed to serialize PSBT"
+		return "", internalRPCError(err.Error(), context)
+	}
+	return b64, nil
+}
+
+// psbtInputUtxo returns the output spent by the input of the passed index of a
+// PSBT, or nil when the PSBT contains neither the previous transaction nor the
+// witness UTXO of the input.  An error is returned when the previous
+// transaction doesn't match the outpoint of the input.
+func psbtInputUtxo(packet *psbt.Packet, i int) (*wire.TxOut, error) {
+	pIn := &packet.Inputs[i]
+	if pIn.NonWitnessUtxo != nil {
+		prevOut := packet.UnsignedTx.TxIn[i].PreviousOutPoint
+		if pIn.NonWitnessUtxo.TxHash() != prevOut.Hash ||
+			prevOut.Index >= uint32(len(pIn.NonWitnessUtxo.TxOut)) {
+
+			return nil, fmt.Errorf("input %d specifies invalid "+
+				"prevout", i)
+		}
+		return pIn.NonWitnessUtxo.TxOut[prevOut.Index], nil
+	}
+	return pIn.WitnessUtxo, nil
+}
+
+// psbtFee returns the fee paid by the transaction of a PSBT.  False is
+// returned when the UTXO of any of its inputs is unknown.
+func psbtFee(packet *psbt.Packet) (int64, bool, error) {
+	var fee int64
+	for i := range packet.Inputs {
+		utxo, err := psbtInputUtxo(packet, i)
+		if err != nil {
+			return 0, false, err
+		}
+		if utxo == nil {
+			return 0, false, nil
+		}
+		if utxo.Value < 0 || utxo.Value > btcutil.MaxSatoshi {
+			return 0, false, fmt.Errorf("input %d has invalid "+
+				"value", i)
+		}
+		fee += utxo.Value
+	}
+	for _, txOut := range packet.UnsignedTx.TxOut {
+		if txOut.Value < 0 || txOut.Value > btcutil.MaxSatoshi {
+			return 0, false, errors.New("output amount invalid")
+		}
+		fee -= txOut.Value
+	}
+	return fee, true, nil
+}
+
+// deserializePsbtWitness returns the witness of the passed serialized final
+// script witness of a PSBT input.
+func deserializePsbtWitness(serialized []byte) (wire.TxWitness, error) {
+	r := bytes.NewReader(serialized)
+	count, err := wire.ReadVarInt(r, 0)
+	if err != nil {
+		return nil, err
+	}
+	if count > uint64(len(serialized)) {
+		return nil, errors.New("witness item count too large")
+	}
+	witness := make(wire.TxWitness, count)
+	for i := range witness {
+		witness[i], err = wire.ReadVarBytes(r, 0,
+			uint32(len(serialized)), "witness item")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return witness, nil
+}
+
+// psbtSpendAnalysis describes what the input of a PSBT is missing before it
+// can be finalized, and how large its signature script and witness are.
+type psbtSpendAnalysis struct {
+	// missing holds the scripts, public keys and signatures the input
+	// is missing.
+	missing btcjson.AnalyzePsbtMissing
+
+	// signable reports whether the input is only missing signatures.
+	signable bool
+
+	// solvable reports whether the signature script and witness of the
+	// input are known up to the signatures, in which case sigScript and
+	// witness hold them with dummy signatures in place of the missing
+	// ones.
+	solvable  bool
+	sigScript []byte
+	witness   wire.TxWitness
+}
+
+// complete returns whether the input has all the signatures it requires to
+// be finalized.
+func (a *psbtSpendAnalysis) complete() bool {
+	return a.solvable && len(a.missing.Signatures) == 0 && !a.signable
+}
+
+// psbtPubKey returns the public key of the passed hash160 which the PSBT input
+// has a signature or derivation path for, or nil if it has neither.
+func psbtPubKey(pIn *psbt.PInput, keyHash []byte) []byte {
+	for _, sig := range pIn.PartialSigs {
+		if bytes.Equal(btcutil.Hash160(sig.PubKey), keyHash) {
+			return sig.PubKey
+		}
+	}
+	for _, derivation := range pIn.Bip32Derivation {
+		if bytes.Equal(btcutil.Hash160(derivation.PubKey), keyHash) {
+			return derivation.PubKey
+		}
+	}
+	return nil
+}
+
+// psbtPartialSig returns the signature of the passed public key of the PSBT
+// input, or nil if it has none.
+func psbtPartialSig(pIn *psbt.PInput, pubKey []byte) []byte {
+	for _, sig := range pIn.PartialSigs {
+		if bytes.Equal(sig.PubKey, pubKey) {
+			return sig.Signature
+		}
+	}
+	return nil
+}
+
+// signature returns the signature of the passed public key of the PSBT
+// input.  A dummy signature is returned and the signature is recorded as
+// missing when the input doesn't have it.
+func (a *psbtSpendAnalysis) signature(pIn *psbt.PInput, pubKey []byte) []byte {
+	if sig := psbtPartialSig(pIn, pubKey); sig != nil {
+		return sig
+	}
+	a.missing.Signatures = append(a.missing.Signatures,
+		hex.EncodeToString(btcutil.Hash160(pubKey)))
+	return make([]byte, dummySignatureLen)
+}
+
+// solvePubKeyHash returns the stack which spends a pay-to-pubkey-hash script
+// or witness program with the passed key hash, and whether the public key is
+// known.
+func (a *psbtSpendAnalysis) solvePubKeyHash(pIn *psbt.PInput,
+	keyHash []byte) ([][]byte, bool) {
+
+	pubKey := psbtPubKey(pIn, keyHash)
+	if pubKey == nil {
+		a.missing.PubKeys = append(a.missing.PubKeys,
+			hex.EncodeToString(keyHash))
+		return nil, false
+	}
+	return [][]byte{a.signature(pIn, pubKey), pubKey}, true
+}
+
+// solveScript returns the stack which spends the passed script, and whether
+// the script is of a standard type this analysis understands.
+func (a *psbtSpendAnalysis) solveScript(pIn *psbt.PInput,
+	script []byte) ([][]byte, bool) {
+
+	switch txscript.GetScriptClass(script) {
+	case txscript.PubKeyHashTy:
+		return a.solvePubKeyHash(pIn, script[3:23])
+
+	case txscript.PubKeyTy:
+		pushes, err := txscript.PushedData(script)
+		if err != nil || len(pushes) != 1 {
+			return nil, false
+		}
+		return [][]byte{a.signature(pIn, pushes[0])}, true
+
+	case txscript.MultiSigTy:
+		_, numSigs, err := txscript.CalcMultiSigStats(script)
+		if err != nil {
+			return nil, false
+		}
+		pubKeys, err := txscript.PushedData(script)
+		if err != nil {
+			return nil, false
+		}
+
+		// Only the required number of signatures is needed, and the
+		// signatures are missing for all keys without one otherwise.
+		// The stack starts with the dummy element consumed by
+		// OP_CHECKMULTISIG.
+		stack := [][]byte{nil}
+		var missing []string
+		for _, pubKey := range pubKeys {
+			sig := psbtPartialSig(pIn, pubKey)
+			switch {
+			case sig == nil:
+				missing = append(missing, hex.EncodeToString(
+					btcutil.Hash160(pubKey)))
+			case len(stack) <= numSigs:
+				stack = append(stack, sig)
+			}
+		}
+		if len(stack) <= numSigs {
+			a.missing.Signatures = append(a.missing.Signatures,
+				missing...)
+		}
+		for len(stack) <= numSigs {
+			stack = append(stack, make([]byte, dummySignatureLen))
+		}
+		return stack, true
+	}
+
+	return nil, false
+}
+
+// analyzeTaprootSpend returns the analysis of a PSBT input spending a taproot
+// output.
+func analyzeTaprootSpend(pIn *psbt.PInput) *psbtSpendAnalysis {
+	a := &psbtSpendAnalysis{}
+	switch {
+	case len(pIn.TaprootKeySpendSig) > 0:
+		a.solvable = true
+		a.witness = wire.TxWitness{pIn.TaprootKeySpendSig}
+
+	// The finalizer spends the leaf of the first script path signature
+	// with all the signatures.
+	case len(pIn.TaprootScriptSpendSig) > 0:
+		leafHash := pIn.TaprootScriptSpendSig[0].LeafHash
+		leaf, err := psbt.FindLeafScript(pIn, leafHash)
+		if err != nil {
+			return a
+		}
+		a.solvable = true
+		for _, sig := range pIn.TaprootScriptSpendSig {
+			witnessSig := sig.Signature
+			if sig.SigHash != txscript.SigHashDefault {
+				witnessSig = append(witnessSig[:len(witnessSig):len(witnessSig)],
+					byte(sig.SigHash))
+			}
+			a.witness = append(a.witness, witnessSig)
+		}
+		a.witness = append(a.witness, leaf.Script, leaf.ControlBlock)
+
+	// The size of the witness is only estimated for a key path spend,
+	// with a signature hash type byte unless the default one is used.
+	case pIn.TaprootInternalKey != nil:
+		sigLen := schnorr.SignatureSize
+		if pIn.SighashType != txscript.SigHashDefault {
+			sigLen++
+		}
+		a.signable = true
+		a.solvable = true
+		a.witness = wire.TxWitness{make([]byte, sigLen)}
+
+	case len(pIn.TaprootLeafScript) > 0:
+		a.signable = true
+	}
+	return a
+}
+
+// analyzePsbtSpend returns the analysis of a PSBT input spending an output
+// with the passed script.  Spends of standard scripts, optionally nested in
+// pay-to-script-hash or pay-to-witness-script-hash, as well as taproot spends
+// are understood.
+func analyzePsbtSpend(pIn *psbt.PInput, pkScript []byte) *psbtSpendAnalysis {
+	a := &psbtSpendAnalysis{}
+	script := pkScript
+	var redeemScript []byte
+	if txscript.IsPayToScriptHash(script) {
+		if pIn.RedeemScript == nil {
+			a.missing.RedeemScript = hex.EncodeToString(script[2:22])
+			return a
+		}
+		redeemScript = pIn.RedeemScript
+		script = redeemScript
+	}
+
+	var stack [][]byte
+	var ok, segwit bool
+	switch {
+	case redeemScript == nil && txscript.IsPayToTaproot(script):
+		return analyzeTaprootSpend(pIn)
+
+	case txscript.IsPayToWitnessPubKeyHash(script):
+		segwit = true
+		stack, ok = a.solvePubKeyHash(pIn, script[2:])
+
+	case txscript.IsPayToWitnessScriptHash(script):
+		segwit = true
+		if pIn.WitnessScript == nil {
+			a.missing.WitnessScript = hex.EncodeToString(script[2:])
+			return a
+		}
+		stack, ok = a.solveScript(pIn, pIn.WitnessScript)
+		stack = append(stack, pIn.WitnessScript)
+
+	default:
+		stack, ok = a.solveScript(pIn, script)
+	}
+	if !ok {
+		return a
+	}
+
+	// The stack is the witness of segwit spends, and is pushed by the
+	// signature script otherwise.  The signature script of a nested
+	// spend ends with the redeem script.
+	builder := txscript.NewScriptBuilder()
+	if segwit {
+		a.witness = stack
+	} else {
+		for _, data := range stack {
+			builder.AddData(data)
+		}
+	}
+	if redeemScript != nil {
+		builder.AddData(redeemScript)
+	}
+	sigScript, err := builder.Script()
+	if err != nil {
+		return a
+	}
+	a.sigScript = sigScript
+	a.solvable = true
+	a.signable = len(a.missing.Signatures) > 0
+	return a
+}
+
+// handleAnalyzePsbt handles analyzepsbt commands.
+func handleAnalyzePsbt(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.AnalyzePsbtCmd)
+
+	packet, err := decodePsbtArg(c.Psbt)
+	if err != nil {
+		return nil, err
+	}
+
+	// To match the behavior of the reference client, an invalid PSBT
+	// results in an error in the reply rather than a failed request.
+	invalid := func(err error) (interface{}, error) {
+		return &btcjson.AnalyzePsbtResult{
+			Next:  psbtRoleCreator.String(),
+			Error: "PSBT is not valid. " + err.Error(),
+		}, nil
+	}
+
+	// Determine the next role of each input, and build a transaction
+	// with the actual or dummy signature scripts and witnesses of the
+	// inputs to estimate its size.
+	reply := &btcjson.AnalyzePsbtResult{
+		Inputs: make([]btcjson.AnalyzePsbtInput, len(packet.Inputs)),
+	}
+	next := psbtRoleExtractor
+	tx := packet.UnsignedTx.Copy()
+	solvable := true
+	for i := range packet.Inputs {
+		pIn := &packet.Inputs[i]
+		utxo, err := psbtInputUtxo(packet, i)
+		if err != nil {
+			return invalid(err)
+		}
+		if utxo != nil && txscript.IsUnspendable(utxo.PkScript) {
+			return invalid(fmt.Errorf("input %d spends unspendable "+
+				"output", i))
+		}
+
+		input := &reply.Inputs[i]
+		input.HasUtxo = utxo != nil
+		var role psbtRole
+		switch {
+		case pIn.FinalScriptSig != nil || pIn.FinalScriptWitness != nil:
+			input.IsFinal = true
+			role = psbtRoleExtractor
+			tx.TxIn[i].SignatureScript = pIn.FinalScriptSig
+			if pIn.FinalScriptWitness != nil {
+				witness, err := deserializePsbtWitness(
+					pIn.FinalScriptWitness,
+				)
+				if err != nil {
+					return invalid(fmt.Errorf("input %d has "+
+						"invalid final witness", i))
+				}
+				tx.TxIn[i].Witness = witness
+			}
+
+		case utxo == nil:
+			role = psbtRoleUpdater
+			solvable = false
+
+		default:
+			a := analyzePsbtSpend(pIn, utxo.PkScript)
+			missing := a.missing
+			if missing.PubKeys != nil || missing.Signatures != nil ||
+				missing.RedeemScript != "" ||
+				missing.WitnessScript != "" {
+
+				input.Missing = &missing
+			}
+			switch {
+			case a.complete():
+				role = psbtRoleFinalizer
+			case a.signable:
+				role = psbtRoleSigner
+			default:
+				role = psbtRoleUpdater
+			}
+			solvable = solvable && a.solvable
+			tx.TxIn[i].SignatureScript = a.sigScript
+			tx.TxIn[i].Witness = a.witness
+		}
+		input.Next = role.String()
+		if role < next {
+			next = role
+		}
+	}
+	reply.Next = next.String()
+
+	// The fee is only known when the UTXOs of all inputs are, and the
+	// size is only estimated when the signature scripts and witnesses
+	// of all inputs are known up to their signatures.
+	fee, ok, err := psbtFee(packet)
+	if err != nil {
+		return invalid(err)
+	}
+	if !ok {
+		return reply, nil
+	}
+	if fee < 0 {
+		return invalid(errors.New("output amounts exceed input " +
+			"amounts"))
+	}
+	feeBTC := btcutil.Amount(fee).ToBTC()
+	reply.Fee = &feeBTC
+	if solvable {
+		vsize := mempool.GetTxVirtualSize(btcutil.NewTx(tx))
+		feeRate := btcutil.Amount(fee * 1000 / vsize).ToBTC()
+		reply.EstimatedVSize = &vsize
+		reply.EstimatedFeeRate = &feeRate
+	}
+	return reply, nil
+}
+
+// handleCombinePsbt handles combinepsbt commands.
+func handleCombinePsbt(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.CombinePsbtCmd)
+
+	if len(c.Psbts) == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Parameter 'txs' cannot be empty",
+		}
+	}
+	packets := make([]*psbt.Packet, 0, len(c.Psbts))
+	for _, b64 := range c.Psbts {
+		packet, err := decodePsbtArg(b64)
+		if err != nil {
+			return nil, err
+		}
+		packets = append(packets, packet)
+	}
+
+	combined, err := psbt.Combine(packets...)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: err.Error(),
+		}
+	}
+	return encodePsbt(combined)
+}
+
+// decodeRawTxArg decodes the passed hex encoded transaction argument of a
+// command.
+func decodeRawTxArg(hexStr string) (*wire.MsgTx, error) {
+	if len(hexStr)%2 != 0 {
+		hexStr = "0" + hexStr
+	}
+	serializedTx, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, rpcDecodeHexError(hexStr)
+	}
+	var mtx wire.MsgTx
+	err = mtx.Deserialize(bytes.NewReader(serializedTx))
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "TX decode failed: " + err.Error(),
+		}
+	}
+	return &mtx, nil
+}
+
+// unsignedTxHash returns the hash of the passed transaction without the
+// signature scripts and witnesses of its inputs.
+func unsignedTxHash(mtx *wire.MsgTx) chainhash.Hash {
+	unsigned := mtx.Copy()
+	for _, txIn := range unsigned.TxIn {
+		txIn.SignatureScript = nil
+		txIn.Witness = nil
+	}
+	return unsigned.TxHash()
+}
+
+// handleCombineRawTransaction handles combinerawtransaction commands.
+func handleCombineRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.CombineRawTransactionCmd)
+
+	if len(c.Txs) == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "Missing transactions",
+		}
+	}
+	txns := make([]*wire.MsgTx, 0, len(c.Txs))
+	for _, hexStr := range c.Txs {
+		mtx, err := decodeRawTxArg(hexStr)
+		if err != nil {
+			return nil, err
+		}
+		if len(txns) > 0 && unsignedTxHash(mtx) != unsignedTxHash(txns[0]) {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: "Transactions must be copies of the " +
+					"same transaction",
+			}
+		}
+		txns = append(txns, mtx)
+	}
+
+	// The outputs spent by all inputs are needed to check the signatures
+	// being merged.
+	merged := txns[0]
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	prevOuts := make([]*wire.TxOut, len(merged.TxIn))
+	for i, txIn := range merged.TxIn {
+		prevOut, err := fetchUnspentOutput(s, txIn.PreviousOutPoint)
+		if err != nil {
+			return nil, err
+		}
+		if prevOut == nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCVerify,
+				Message: "Input not found or already spent",
+			}
+		}
+		prevOutFetcher.AddPrevOut(txIn.PreviousOutPoint, prevOut)
+		prevOuts[i] = prevOut
+	}
+	sigHashes := txscript.NewTxSigHashes(merged, prevOutFetcher)
+
+	params := s.cfg.ChainParams
+	for i, txIn := range merged.TxIn {
+		for _, mtx := range txns[1:] {
+			other := mtx.TxIn[i]
+			txIn.SignatureScript, txIn.Witness = txscript.CombineTxInScripts(
+				params, merged, i, sigHashes, prevOuts[i].Value,
+				prevOuts[i].PkScript, txIn.SignatureScript,
+				txIn.Witness, other.SignatureScript, other.Witness,
+			)
+		}
+	}
+
+	return messageToHex(merged)
+}
+
+// createPsbtOutputs returns the transaction outputs of the passed outputs of
+// a createpsbt command, which each pay an amount in BTC to an address or
+// embed hex encoded data in a null data script.
+func createPsbtOutputs(outputs []btcjson.PsbtOutput,
+	params *chaincfg.Params) ([]*wire.TxOut, error) {
+
+	var txOuts []*wire.TxOut
+	seen := make(map[string]struct{})
+	for _, output := range outputs {
+		if len(output) != 1 {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: "Invalid parameter, key-value pair " +
+					"must contain exactly one key",
+			}
+		}
+		for key, value := range output {
+			if _, ok := seen[key]; ok {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCInvalidParameter,
+					Message: "Invalid parameter, " +
+						"duplicated key: " + key,
+				}
+			}
+			seen[key] = struct{}{}
+
+			var txOut *wire.TxOut
+			var err error
+			if key == "data" {
+				txOut, err = createPsbtDataOutput(value)
+			} else {
+				txOut, err = createPsbtAddressOutput(key, value,
+					params)
+			}
+			if err != nil {
+				return nil, err
+			}
+			txOuts = append(txOuts, txOut)
+		}
+	}
+	return txOuts, nil
+}
+
+// createPsbtDataOutput returns the null data output of the passed hex encoded
+// data of a createpsbt command.
+func createPsbtDataOutput(value interface{}) (*wire.TxOut, error) {
+	hexStr, ok := value.(string)
+	if !ok {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCType,
+			Message: "Data must be a hexadecimal string",
+		}
+	}
+	data, err := hex.DecodeString(hexStr)
+	if err != nil {
+		return nil, rpcDecodeHexError(hexStr)
+	}
+	pkScript, err := txscript.NullDataScript(data)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid data output: " + err.Error(),
+		}
+	}
+	return wire.NewTxOut(0, pkScript), nil
+}
+
+// createPsbtAddressOutput returns the output of a createpsbt command which
+// pays the passed amount in BTC to the passed address.
+func createPsbtAddressOutput(encodedAddr string, value interface{},
+	params *chaincfg.Params) (*wire.TxOut, error) {
+
+	// The amount may be passed as a number or a string.
+	var amount float64
+	switch v := value.(type) {
+	case float64:
+		amount = v
+	case string:
+		var err error
+		amount, err = strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCType,
+				Message: "Invalid amount",
+			}
+		}
+	default:
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCType,
+			Message: "Amount is not a number or string",
+		}
+	}
+
+	// Ensure amount is in the valid range for monetary amounts.
+	if amount <= 0 || amount*btcutil.SatoshiPerBitcoin > btcutil.MaxSatoshi {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCType,
+			Message: "Invalid amount",
+		}
+	}
+
+	// Decode the provided address and ensure the network encoded with it
+	// matches the network the server is currently on.  Unlike
+	// createrawtransaction, outputs may pay to any type of address.
+	addr, err := btcutil.DecodeAddress(encodedAddr, params)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Invalid address or key: " + err.Error(),
+		}
+	}
+	if !addr.IsForNet(params) {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Invalid address: " + encodedAddr +
+				" is for the wrong network",
+		}
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		context := "Failed to generate pay-to-address script"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	satoshi, err := btcutil.NewAmount(amount)
+	if err != nil {
+		context := "Failed to convert amount"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	return wire.NewTxOut(int64(satoshi), pkScript), nil
+}
+
+// handleCreatePsbt handles createpsbt commands.
+func handleCreatePsbt(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.CreatePsbtCmd)
+
+	// Validate the locktime, if given.
+	var lockTime uint32
+	if c.LockTime != nil {
+		if *c.LockTime < 0 ||
+			*c.LockTime > int64(wire.MaxTxInSequenceNum) {
+
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "Locktime out of range",
+			}
+		}
+		lockTime = uint32(*c.LockTime)
+	}
+	replaceable := c.Replaceable != nil && *c.Replaceable
+
+	// Inputs without an explicit sequence number signal replaceability
+	// when requested, and enable the lock time when one is given.
+	defaultSequence := wire.MaxTxInSequenceNum
+	switch {
+	case replaceable:
+		defaultSequence = mempool.MaxRBFSequence
+	case lockTime != 0:
+		defaultSequence = wire.MaxTxInSequenceNum - 1
+	}
+
+	mtx := wire.NewMsgTx(wire.TxVersion)
+	for _, input := range c.Inputs {
+		txHash, err := chainhash.NewHashFromStr(input.Txid)
+		if err != nil {
+			return nil, rpcDecodeHexError(input.Txid)
+		}
+
+		sequence := defaultSequence
+		if input.Sequence != nil {
+			if replaceable && *input.Sequence > mempool.MaxRBFSequence {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCInvalidParameter,
+					Message: "Invalid parameter combination: " +
+						"Sequence number(s) contradict " +
+						"replaceable option",
+				}
+			}
+			sequence = *input.Sequence
+		}
+
+		prevOut := wire.NewOutPoint(txHash, input.Vout)
+		txIn := wire.NewTxIn(prevOut, nil, nil)
+		txIn.Sequence = sequence
+		mtx.AddTxIn(txIn)
+	}
+
+	txOuts, err := createPsbtOutputs(c.Outputs, s.cfg.ChainParams)
+	if err != nil {
+		return nil, err
+	}
+	for _, txOut := range txOuts {
+		mtx.AddTxOut(txOut)
+	}
+	mtx.LockTime = lockTime
+
+	packet, err := psbt.NewFromUnsignedTx(mtx)
+	if err != nil {
+		context := "Failed to create PSBT"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	return encodePsbt(packet)
+}
+
 // handleCreateRawTransaction handles createrawtransaction commands.
 func handleCreateRawTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.CreateRawTransactionCmd)
@@ -738,43 +1619,334 @@ func createVoutList(mtx *wire.MsgTx, chainParams *chaincfg.Params, filterAddrMap
 
 		voutList = append(voutList, vout)
 	}
-
-	return voutList
+
+	return voutList
+}
+
+// createTxRawResult converts the passed transaction and associated parameters
+// to a raw transaction JSON object.
+func createTxRawResult(chainParams *chaincfg.Params, mtx *wire.MsgTx,
+	txHash string, blkHeader *wire.BlockHeader, blkHash string,
+	blkHeight int32, chainHeight int32) (*btcjson.TxRawResult, error) {
+
+	mtxHex, err := messageToHex(mtx)
+	if err != nil {
+		return nil, err
+	}
+
+	txReply := &btcjson.TxRawResult{
+		Hex:      mtxHex,
+		Txid:     txHash,
+		Hash:     mtx.WitnessHash().String(),
+		Size:     int32(mtx.SerializeSize()),
+		Vsize:    int32(mempool.GetTxVirtualSize(btcutil.NewTx(mtx))),
+		Weight:   int32(blockchain.GetTransactionWeight(btcutil.NewTx(mtx))),
+		Vin:      createVinList(mtx),
+		Vout:     createVoutList(mtx, chainParams, nil),
+		Version:  uint32(mtx.Version),
+		LockTime: mtx.LockTime,
+	}
+
+	if blkHeader != nil {
+		// This is not a typo, they are identical in bitcoind as well.
+		txReply.Time = blkHeader.Timestamp.Unix()
+		txReply.Blocktime = blkHeader.Timestamp.Unix()
+		txReply.BlockHash = blkHash
+		txReply.Confirmations = uint64(1 + chainHeight - blkHeight)
+	}
+
+	return txReply, nil
+}
+
+// formatFingerprint returns the hex encoding of the passed master key
+// fingerprint of a PSBT, which is serialized in little endian.
+func formatFingerprint(fingerprint uint32) string {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], fingerprint)
+	return hex.EncodeToString(b[:])
+}
+
+// formatBip32Path returns the passed BIP0032 derivation path in the form
+// m/84'/0'/0'/0/1.
+func formatBip32Path(path []uint32) string {
+	var b strings.Builder
+	b.WriteString("m")
+	for _, index := range path {
+		if index >= hdkeychain.HardenedKeyStart {
+			fmt.Fprintf(&b, "/%d'", index-hdkeychain.HardenedKeyStart)
+		} else {
+			fmt.Fprintf(&b, "/%d", index)
+		}
+	}
+	return b.String()
+}
+
+// psbtScriptResult returns the JSON object of the passed redeem or witness
+// script of a PSBT, or nil if there isn't a script.
+func psbtScriptResult(script []byte) *btcjson.PsbtScript {
+	if script == nil {
+		return nil
+	}
+
+	// The disassembled string will contain [error] inline if the script
+	// doesn't fully parse, so ignore the error here.
+	disbuf, _ := txscript.DisasmString(script)
+	return &btcjson.PsbtScript{
+		Asm:  disbuf,
+		Hex:  hex.EncodeToString(script),
+		Type: txscript.GetScriptClass(script).String(),
+	}
+}
+
+// psbtBip32DerivResults returns the JSON objects of the passed derivation
+// paths of a PSBT.
+func psbtBip32DerivResults(derivations []*psbt.Bip32Derivation) []btcjson.PsbtBip32Deriv {
+	var results []btcjson.PsbtBip32Deriv
+	for _, d := range derivations {
+		results = append(results, btcjson.PsbtBip32Deriv{
+			PubKey:            hex.EncodeToString(d.PubKey),
+			MasterFingerprint: formatFingerprint(d.MasterKeyFingerprint),
+			Path:              formatBip32Path(d.Bip32Path),
+		})
+	}
+	return results
+}
+
+// psbtTaprootBip32DerivResults returns the JSON objects of the passed taproot
+// derivation paths of a PSBT.
+func psbtTaprootBip32DerivResults(derivations []*psbt.TaprootBip32Derivation) []btcjson.PsbtTaprootBip32Deriv {
+	var results []btcjson.PsbtTaprootBip32Deriv
+	for _, d := range derivations {
+		leafHashes := make([]string, 0, len(d.LeafHashes))
+		for _, leafHash := range d.LeafHashes {
+			leafHashes = append(leafHashes, hex.EncodeToString(leafHash))
+		}
+		results = append(results, btcjson.PsbtTaprootBip32Deriv{
+			PubKey:            hex.EncodeToString(d.XOnlyPubKey),
+			MasterFingerprint: formatFingerprint(d.MasterKeyFingerprint),
+			Path:              formatBip32Path(d.Bip32Path),
+			LeafHashes:        leafHashes,
+		})
+	}
+	return results
+}
+
+// psbtUnknownsResult returns the hex encoded keys and values of the passed
+// unknown fields of a PSBT.
+func psbtUnknownsResult(unknowns []*psbt.Unknown) map[string]string {
+	results := make(map[string]string, len(unknowns))
+	for _, u := range unknowns {
+		results[hex.EncodeToString(u.Key)] = hex.EncodeToString(u.Value)
+	}
+	return results
+}
+
+// decodeTaprootTree returns the leaves of the passed serialized taproot tree
+// of a PSBT output, which is a list of the depth, leaf version and script of
+// each leaf in depth-first order.
+func decodeTaprootTree(tree []byte) ([]btcjson.PsbtTaprootLeaf, error) {
+	var leaves []btcjson.PsbtTaprootLeaf
+	r := bytes.NewReader(tree)
+	for r.Len() > 0 {
+		var header [2]byte
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			return nil, err
+		}
+		script, err := wire.ReadVarBytes(r, 0, uint32(len(tree)),
+			"leaf script")
+		if err != nil {
+			return nil, err
+		}
+		leaves = append(leaves, btcjson.PsbtTaprootLeaf{
+			Depth:   header[0],
+			LeafVer: header[1],
+			Script:  hex.EncodeToString(script),
+		})
+	}
+	return leaves, nil
+}
+
+// encodeXPub returns the base58 encoding of the passed serialized extended
+// public key.
+func encodeXPub(extendedKey []byte) string {
+	checksum := chainhash.DoubleHashB(extendedKey)[:4]
+	return base58.Encode(append(append([]byte{}, extendedKey...),
+		checksum...))
+}
+
+// createDecodePsbtInput returns the JSON object of the passed PSBT input.
+func createDecodePsbtInput(pIn *psbt.PInput, params *chaincfg.Params) btcjson.DecodePsbtInput {
+	var input btcjson.DecodePsbtInput
+	if tx := pIn.NonWitnessUtxo; tx != nil {
+		input.NonWitnessUtxo = &btcjson.TxRawDecodeResult{
+			Txid:     tx.TxHash().String(),
+			Version:  tx.Version,
+			Locktime: tx.LockTime,
+			Vin:      createVinList(tx),
+			Vout:     createVoutList(tx, params, nil),
+		}
+	}
+	if txOut := pIn.WitnessUtxo; txOut != nil {
+		vout := createVoutList(&wire.MsgTx{TxOut: []*wire.TxOut{txOut}},
+			params, nil)[0]
+		input.WitnessUtxo = &btcjson.PsbtWitnessUtxo{
+			Amount:       vout.Value,
+			ScriptPubKey: vout.ScriptPubKey,
+		}
+	}
+	if len(pIn.PartialSigs) > 0 {
+		input.PartialSignatures = make(map[string]string)
+		for _, sig := range pIn.PartialSigs {
+			input.PartialSignatures[hex.EncodeToString(sig.PubKey)] =
+				hex.EncodeToString(sig.Signature)
+		}
+	}
+	if pIn.SighashType != 0 {
+		input.Sighash = sigHashTypeStrings[pIn.SighashType]
+		if input.Sighash == "" {
+			input.Sighash = strconv.Itoa(int(pIn.SighashType))
+		}
+	}
+	input.RedeemScript = psbtScriptResult(pIn.RedeemScript)
+	input.WitnessScript = psbtScriptResult(pIn.WitnessScript)
+	input.Bip32Derivs = psbtBip32DerivResults(pIn.Bip32Derivation)
+	if pIn.FinalScriptSig != nil {
+		disbuf, _ := txscript.DisasmString(pIn.FinalScriptSig)
+		input.FinalScriptSig = &btcjson.ScriptSig{
+			Asm: disbuf,
+			Hex: hex.EncodeToString(pIn.FinalScriptSig),
+		}
+	}
+	if pIn.FinalScriptWitness != nil {
+		witness, err := deserializePsbtWitness(pIn.FinalScriptWitness)
+		if err == nil {
+			input.FinalScriptWitness = witness.ToHexStrings()
+		}
+	}
+	input.TaprootKeyPathSig = hex.EncodeToString(pIn.TaprootKeySpendSig)
+	for _, sig := range pIn.TaprootScriptSpendSig {
+		input.TaprootScriptPathSigs = append(input.TaprootScriptPathSigs,
+			btcjson.PsbtTaprootScriptSig{
+				PubKey:   hex.EncodeToString(sig.XOnlyPubKey),
+				LeafHash: hex.EncodeToString(sig.LeafHash),
+				Sig:      hex.EncodeToString(sig.Signature),
+			})
+	}
+
+	// The control blocks of the same leaf script are grouped together.
+	for _, leaf := range pIn.TaprootLeafScript {
+		script := hex.EncodeToString(leaf.Script)
+		controlBlock := hex.EncodeToString(leaf.ControlBlock)
+		found := false
+		for i := range input.TaprootScripts {
+			s := &input.TaprootScripts[i]
+			if s.Script == script && s.LeafVer == uint8(leaf.LeafVersion) {
+				s.ControlBlocks = append(s.ControlBlocks, controlBlock)
+				found = true
+				break
+			}
+		}
+		if !found {
+			input.TaprootScripts = append(input.TaprootScripts,
+				btcjson.PsbtTaprootScript{
+					Script:        script,
+					LeafVer:       uint8(leaf.LeafVersion),
+					ControlBlocks: []string{controlBlock},
+				})
+		}
+	}
+	input.TaprootBip32Derivs = psbtTaprootBip32DerivResults(
+		pIn.TaprootBip32Derivation,
+	)
+	input.TaprootInternalKey = hex.EncodeToString(pIn.TaprootInternalKey)
+	input.TaprootMerkleRoot = hex.EncodeToString(pIn.TaprootMerkleRoot)
+	input.RequiredTimeLockTime = pIn.RequiredTimeLockTime
+	input.RequiredHeightLockTime = pIn.RequiredHeightLockTime
+	if len(pIn.Unknowns) > 0 {
+		input.Unknown = psbtUnknownsResult(pIn.Unknowns)
+	}
+	return input
+}
+
+// createDecodePsbtOutput returns the JSON object of the passed PSBT output.
+func createDecodePsbtOutput(pOut *psbt.POutput) btcjson.DecodePsbtOutput {
+	output := btcjson.DecodePsbtOutput{
+		RedeemScript:       psbtScriptResult(pOut.RedeemScript),
+		WitnessScript:      psbtScriptResult(pOut.WitnessScript),
+		Bip32Derivs:        psbtBip32DerivResults(pOut.Bip32Derivation),
+		TaprootInternalKey: hex.EncodeToString(pOut.TaprootInternalKey),
+		TaprootBip32Derivs: psbtTaprootBip32DerivResults(
+			pOut.TaprootBip32Derivation,
+		),
+	}
+
+	// The taproot tree is omitted when it doesn't decode, like other
+	// fields this package doesn't understand.
+	if pOut.TaprootTapTree != nil {
+		leaves, err := decodeTaprootTree(pOut.TaprootTapTree)
+		if err == nil {
+			output.TaprootTree = leaves
+		}
+	}
+	if len(pOut.Unknowns) > 0 {
+		output.Unknown = psbtUnknownsResult(pOut.Unknowns)
+	}
+	return output
 }
 
-// createTxRawResult converts the passed transaction and associated parameters
-// to a raw transaction JSON object.
-func createTxRawResult(chainParams *chaincfg.Params, mtx *wire.MsgTx,
-	txHash string, blkHeader *wire.BlockHeader, blkHash string,
-	blkHeight int32, chainHeight int32) (*btcjson.TxRawResult, error) {
+// handleDecodePsbt handles decodepsbt commands.
+func handleDecodePsbt(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.DecodePsbtCmd)
 
-	mtxHex, err := messageToHex(mtx)
+	packet, err := decodePsbtArg(c.Psbt)
 	if err != nil {
 		return nil, err
 	}
 
-	txReply := &btcjson.TxRawResult{
-		Hex:      mtxHex,
-		Txid:     txHash,
-		Hash:     mtx.WitnessHash().String(),
-		Size:     int32(mtx.SerializeSize()),
-		Vsize:    int32(mempool.GetTxVirtualSize(btcutil.NewTx(mtx))),
-		Weight:   int32(blockchain.GetTransactionWeight(btcutil.NewTx(mtx))),
-		Vin:      createVinList(mtx),
-		Vout:     createVoutList(mtx, chainParams, nil),
-		Version:  uint32(mtx.Version),
-		LockTime: mtx.LockTime,
+	params := s.cfg.ChainParams
+	mtx := packet.UnsignedTx
+	reply := &btcjson.DecodePsbtResult{
+		Tx: btcjson.TxRawDecodeResult{
+			Txid:     mtx.TxHash().String(),
+			Version:  mtx.Version,
+			Locktime: mtx.LockTime,
+			Vin:      createVinList(mtx),
+			Vout:     createVoutList(mtx, params, nil),
+		},
+		GlobalXPubs: make([]btcjson.PsbtGlobalXPub, 0, len(packet.XPubs)),
+		PsbtVersion: packet.Version,
+		Unknown:     psbtUnknownsResult(packet.Unknowns),
+		Inputs:      make([]btcjson.DecodePsbtInput, 0, len(packet.Inputs)),
+		Outputs:     make([]btcjson.DecodePsbtOutput, 0, len(packet.Outputs)),
+	}
+	for _, xPub := range packet.XPubs {
+		reply.GlobalXPubs = append(reply.GlobalXPubs, btcjson.PsbtGlobalXPub{
+			XPub:              encodeXPub(xPub.ExtendedKey),
+			MasterFingerprint: formatFingerprint(xPub.MasterKeyFingerprint),
+			Path:              formatBip32Path(xPub.Bip32Path),
+		})
 	}
-
-	if blkHeader != nil {
-		// This is not a typo, they are identical in bitcoind as well.
-		txReply.Time = blkHeader.Timestamp.Unix()
-		txReply.Blocktime = blkHeader.Timestamp.Unix()
-		txReply.BlockHash = blkHash
-		txReply.Confirmations = uint64(1 + chainHeight - blkHeight)
+	if packet.Version == 2 {
+		modifiable := uint8(packet.TxModifiable)
+		reply.FallbackLockTime = packet.FallbackLockTime
+		reply.TxModifiable = &modifiable
+	}
+	for i := range packet.Inputs {
+		reply.Inputs = append(reply.Inputs,
+			createDecodePsbtInput(&packet.Inputs[i], params))
+	}
+	for i := range packet.Outputs {
+		reply.Outputs = append(reply.Outputs,
+			createDecodePsbtOutput(&packet.Outputs[i]))
 	}
 
-	return txReply, nil
+	// The fee is only known when the UTXOs of all inputs are.
+	fee, ok, err := psbtFee(packet)
+	if err == nil && ok {
+		feeBTC := btcutil.Amount(fee).ToBTC()
+		reply.Fee = &feeBTC
+	}
+	return reply, nil
 }
 
 // handleDecodeRawTransaction handles decoderawtransaction commands.
@@ -865,6 +2037,168 @@ func handleDecodeScript(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 	return reply, nil
 }
 
+// maxDescriptorRange is the maximum number of indexes of the range of a ranged
+// descriptor the deriveaddresses and scantxoutset commands derive scripts for.
+const maxDescriptorRange = 1000000
+
+// parseDescriptorRange returns the first and last index of the passed range of
+// a ranged descriptor.  The range is either the last index, or the first and
+// the last index.
+func parseDescriptorRange(r *btcjson.DescriptorRange) (uint32, uint32, error) {
+	var begin, end int
+	switch v := r.Value.(type) {
+	case int:
+		end = v
+	case []int:
+		begin, end = v[0], v[1]
+	default:
+		return 0, 0, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Range must be an integer or an array of two integers",
+		}
+	}
+
+	var err string
+	switch {
+	case begin < 0 || end < 0:
+		err = "Range should be greater or equal than 0"
+	case end < begin:
+		err = "Range end should be equal to or greater than begin"
+	case end-begin >= maxDescriptorRange || end > math.MaxInt32:
+		err = "Range is too large"
+	}
+	if err != "" {
+		return 0, 0, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: err,
+		}
+	}
+	return uint32(begin), uint32(end), nil
+}
+
+// rpcInvalidDescriptorError is a convenience function for returning a nicely
+// formatted RPC error which indicates the passed descriptor is invalid.
+func rpcInvalidDescriptorError(err error) *btcjson.RPCError {
+	return &btcjson.RPCError{
+		Code:    btcjson.ErrRPCInvalidAddressOrKey,
+		Message: fmt.Sprintf("Invalid descriptor: %v", err),
+	}
+}
+
+// handleDeriveAddresses implements the deriveaddresses command.
+func handleDeriveAddresses(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.DeriveAddressesCmd)
+
+	// Unlike other commands, the descriptor must have a checksum so the
+	// addresses aren't derived from a mistyped descriptor.
+	_, checksum, err := descriptor.SplitChecksum(c.Descriptor)
+	if err != nil {
+		return nil, rpcInvalidDescriptorError(err)
+	}
+	if checksum == "" {
+		return nil, rpcInvalidDescriptorError(errors.New("missing checksum"))
+	}
+	desc, err := descriptor.Parse(c.Descriptor, s.cfg.ChainParams)
+	if err != nil {
+		return nil, rpcInvalidDescriptorError(err)
+	}
+
+	var begin, end uint32
+	switch {
+	case desc.IsRange() && c.Range == nil:
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Range must be specified for a ranged descriptor",
+		}
+
+	case !desc.IsRange() && c.Range != nil:
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Range should not be specified for an un-ranged descriptor",
+		}
+
+	case c.Range != nil:
+		begin, end, err = parseDescriptorRange(c.Range)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	addresses := make([]string, 0, end-begin+1)
+	for i := begin; i <= end; i++ {
+		addrs, err := desc.Addresses(i)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidAddressOrKey,
+				Message: fmt.Sprintf("Unable to derive addresses: %v", err),
+			}
+		}
+		for _, addr := range addrs {
+			addresses = append(addresses, addr.EncodeAddress())
+		}
+	}
+	return btcjson.DeriveAddressesResult(addresses), nil
+}
+
+// utxoSnapshotPath returns the path of a UTXO set snapshot file.  Relative
+// paths are relative to the data directory.
+func utxoSnapshotPath(path string) string {
+	path = cleanAndExpandPath(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cfg.DataDir, path)
+	}
+	return path
+}
+
+// handleDumpTxOutSet implements the dumptxoutset command.
+func handleDumpTxOutSet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.DumpTxOutSetCmd)
+
+	path := utxoSnapshotPath(c.Path)
+	if _, err := os.Stat(path); err == nil {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("%s already exists.  If you are "+
+				"sure this is what you want, move it out of the "+
+				"way first", path),
+		}
+	}
+
+	// Write the snapshot to a temporary file first so an interrupted dump
+	// does not leave a truncated snapshot behind.
+	tmpPath := path + ".incomplete"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Unable to create snapshot file: %v", err),
+		}
+	}
+	stats, err := s.cfg.Chain.DumpUtxoSnapshot(f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, path)
+	}
+	if err != nil {
+		os.Remove(tmpPath)
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Unable to dump the UTXO set: %v", err),
+		}
+	}
+
+	return &btcjson.DumpTxOutSetResult{
+		CoinsWritten: stats.NumCoins,
+		BaseHash:     stats.BaseHash.String(),
+		BaseHeight:   stats.BaseHeight,
+		Path:         path,
+		TxOutSetHash: stats.HashSerialized.String(),
+		NChainTx:     stats.ChainTxCount,
+	}, nil
+}
+
 // handleEstimateFee handles estimatefee commands.
 func handleEstimateFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.EstimateFeeCmd)
@@ -887,6 +2221,70 @@ func handleEstimateFee(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return float64(feeRate), nil
 }
 
+// isSegWitPsbtInput returns whether the input of a PSBT spending an output
+// with the passed script is a segwit spend, which is the case for witness
+// programs and pay-to-script-hash outputs with a witness program as the
+// redeem script.
+func isSegWitPsbtInput(pIn *psbt.PInput, pkScript []byte) bool {
+	if txscript.IsPayToScriptHash(pkScript) {
+		return pIn.RedeemScript != nil &&
+			txscript.IsWitnessProgram(pIn.RedeemScript)
+	}
+	return txscript.IsWitnessProgram(pkScript)
+}
+
+// handleFinalizePsbt handles finalizepsbt commands.
+func handleFinalizePsbt(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.FinalizePsbtCmd)
+
+	packet, err := decodePsbtArg(c.Psbt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Finalize every input which can be, and leave the others as they
+	// are.  The finalizer only treats inputs with a witness UTXO as
+	// segwit spends, so it's added to those which only have the previous
+	// transaction.
+	for i := range packet.Inputs {
+		pIn := &packet.Inputs[i]
+		utxo, err := psbtInputUtxo(packet, i)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCDeserialization,
+				Message: "PSBT is not valid: " + err.Error(),
+			}
+		}
+		if pIn.WitnessUtxo == nil && utxo != nil &&
+			isSegWitPsbtInput(pIn, utxo.PkScript) {
+
+			pIn.WitnessUtxo = utxo
+		}
+		_, _ = psbt.MaybeFinalize(packet, i)
+	}
+
+	complete := packet.IsComplete()
+	reply := &btcjson.FinalizePsbtResult{Complete: complete}
+	if complete && (c.Extract == nil || *c.Extract) {
+		tx, err := psbt.Extract(packet)
+		if err != nil {
+			context := "Failed to extract transaction"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		reply.Hex, err = messageToHex(tx)
+		if err != nil {
+			return nil, err
+		}
+		return reply, nil
+	}
+
+	reply.Psbt, err = encodePsbt(packet)
+	if err != nil {
+		return nil, err
+	}
+	return reply, nil
+}
+
 // handleGenerate handles generate commands.
 func handleGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Respond with an error if there are no addresses to pay the
@@ -1407,6 +2805,338 @@ func handleGetBlockHeader(s *rpcServer, cmd interface{}, closeChan <-chan struct
 	return blockHeaderReply, nil
 }
 
+// perUTXOOverhead is the size added to the serialized size of each output to
+// approximate the size of its utxo set entry as done by Bitcoin Core.  It is
+// the size of an outpoint, the block height and the coinbase flag.
+const perUTXOOverhead = 41
+
+// calcTruncatedMedian returns the median of the passed values with the
+// average of the two middle values truncated when there is an even number of
+// values.  The values are sorted in place.
+func calcTruncatedMedian(values []int64) int64 {
+	if len(values) == 0 {
+		return 0
+	}
+
+	sort.Slice(values, func(i, j int) bool { return values[i] < values[j] })
+	mid := len(values) / 2
+	if len(values)%2 == 0 {
+		return (values[mid-1] + values[mid]) / 2
+	}
+	return values[mid]
+}
+
+// feeRateWeight houses the fee rate of a transaction along with its weight
+// for the purposes of calculating weighted fee rate percentiles.
+type feeRateWeight struct {
+	feeRate int64
+	weight  int64
+}
+
+// calcFeeRatePercentiles returns the 10th, 25th, 50th, 75th and 90th fee rate
+// percentiles of the passed transactions weighted by their weight.  The
+// entries are sorted in place.
+func calcFeeRatePercentiles(entries []feeRateWeight, totalWeight int64) []int64 {
+	percentiles := make([]int64, 5)
+	if len(entries) == 0 {
+		return percentiles
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].feeRate != entries[j].feeRate {
+			return entries[i].feeRate < entries[j].feeRate
+		}
+		return entries[i].weight < entries[j].weight
+	})
+
+	total := float64(totalWeight)
+	thresholds := []float64{
+		total / 10, total / 4, total / 2, total * 3 / 4, total * 9 / 10,
+	}
+	var next int
+	var cumulativeWeight int64
+	for _, entry := range entries {
+		cumulativeWeight += entry.weight
+		for next < len(thresholds) &&
+			float64(cumulativeWeight) >= thresholds[next] {
+
+			percentiles[next] = entry.feeRate
+			next++
+		}
+	}
+
+	// Fill any remaining percentiles with the highest fee rate.
+	for ; next < len(percentiles); next++ {
+		percentiles[next] = entries[len(entries)-1].feeRate
+	}
+	return percentiles
+}
+
+// txOutUTXOSize returns the approximate size the passed output occupies in the
+// utxo set.
+func txOutUTXOSize(pkScript []byte) int64 {
+	return int64(8 + wire.VarIntSerializeSize(uint64(len(pkScript))) +
+		len(pkScript) + perUTXOOverhead)
+}
+
+// handleGetBlockStats implements the getblockstats command.
+func handleGetBlockStats(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetBlockStatsCmd)
+
+	// Load the requested block by either its height or hash.
+	var block *btcutil.Block
+	switch hashOrHeight := c.HashOrHeight.Value.(type) {
+	case int:
+		best := s.cfg.Chain.BestSnapshot()
+		if hashOrHeight < 0 {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: fmt.Sprintf("Target block height %d is "+
+					"negative", hashOrHeight),
+			}
+		}
+		if hashOrHeight > int(best.Height) {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: fmt.Sprintf("Target block height %d after "+
+					"current tip %d", hashOrHeight, best.Height),
+			}
+		}
+
+		var err error
+		block, err = s.cfg.Chain.BlockByHeight(int32(hashOrHeight))
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCBlockNotFound,
+				Message: "Block not found",
+			}
+		}
+
+	case string:
+		hash, err := chainhash.NewHashFromStr(hashOrHeight)
+		if err != nil {
+			return nil, rpcDecodeHexError(hashOrHeight)
+		}
+		block, err = s.cfg.Chain.BlockByHash(hash)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCBlockNotFound,
+				Message: "Block not found",
+			}
+		}
+
+	default:
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Expected a block height or hash",
+		}
+	}
+
+	// The outputs spent by the block are loaded from the spend journal so
+	// the fees can be calculated without the transaction index.
+	stxos, err := s.cfg.Chain.FetchSpendJournal(block)
+	if err != nil {
+		context := "Failed to load spend journal"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	header := &block.MsgBlock().Header
+	medianTime := header.Timestamp
+	if block.Height() > 0 {
+		medianTime, err = s.cfg.Chain.PastMedianTime(header)
+		if err != nil {
+			context := "Failed to calculate median time"
+			return nil, internalRPCError(err.Error(), context)
+		}
+	}
+
+	// The genesis block and the duplicate coinbases of the blocks that
+	// violate BIP0030 do not add to the utxo set.
+	skipCoinbaseUTXOs := block.Height() == 0 ||
+		blockchain.IsBIP0030Block(block)
+
+	var (
+		inputs, outputs, utxos           int64
+		totalOut, totalSize, totalWeight int64
+		totalFee, maxFee, maxFeeRate     int64
+		maxTxSize, swTxs, swTotalSize    int64
+		swTotalWeight                    int64
+		utxoSizeInc, utxoSizeIncActual   int64
+		minFee, minFeeRate               int64 = btcutil.MaxSatoshi, btcutil.MaxSatoshi
+		minTxSize                        int64 = math.MaxInt64
+		fees, txSizes                    []int64
+		feeRates                         []feeRateWeight
+		stxoIdx                          int
+	)
+	for i, tx := range block.Transactions() {
+		msgTx := tx.MsgTx()
+		outputs += int64(len(msgTx.TxOut))
+
+		var txTotalOut int64
+		for _, txOut := range msgTx.TxOut {
+			txTotalOut += txOut.Value
+			size := txOutUTXOSize(txOut.PkScript)
+			utxoSizeInc += size
+
+			if (i == 0 && skipCoinbaseUTXOs) ||
+				txscript.IsUnspendable(txOut.PkScript) {
+
+				continue
+			}
+			utxos++
+			utxoSizeIncActual += size
+		}
+
+		// The coinbase input and reward are not included in the
+		// remaining statistics.
+		if i == 0 {
+			continue
+		}
+		inputs += int64(len(msgTx.TxIn))
+		totalOut += txTotalOut
+
+		txSize := int64(msgTx.SerializeSize())
+		txSizes = append(txSizes, txSize)
+		if txSize > maxTxSize {
+			maxTxSize = txSize
+		}
+		if txSize < minTxSize {
+			minTxSize = txSize
+		}
+		totalSize += txSize
+
+		weight := blockchain.GetTransactionWeight(tx)
+		totalWeight += weight
+
+		if msgTx.HasWitness() {
+			swTxs++
+			swTotalSize += txSize
+			swTotalWeight += weight
+		}
+
+		var txTotalIn int64
+		for range msgTx.TxIn {
+			if stxoIdx >= len(stxos) {
+				context := "Failed to load spent outputs"
+				return nil, internalRPCError("spend journal "+
+					"is missing entries", context)
+			}
+			stxo := &stxos[stxoIdx]
+			stxoIdx++
+
+			txTotalIn += stxo.Amount
+			size := txOutUTXOSize(stxo.PkScript)
+			utxoSizeInc -= size
+			utxoSizeIncActual -= size
+		}
+
+		fee := txTotalIn - txTotalOut
+		fees = append(fees, fee)
+		if fee > maxFee {
+			maxFee = fee
+		}
+		if fee < minFee {
+			minFee = fee
+		}
+		totalFee += fee
+
+		// The fee rate is in satoshis per virtual byte.
+		var feeRate int64
+		if weight > 0 {
+			feeRate = fee * blockchain.WitnessScaleFactor / weight
+		}
+		feeRates = append(feeRates, feeRateWeight{feeRate, weight})
+		if feeRate > maxFeeRate {
+			maxFeeRate = feeRate
+		}
+		if feeRate < minFeeRate {
+			minFeeRate = feeRate
+		}
+	}
+	if minFee == btcutil.MaxSatoshi {
+		minFee = 0
+	}
+	if minFeeRate == btcutil.MaxSatoshi {
+		minFeeRate = 0
+	}
+	if minTxSize == math.MaxInt64 {
+		minTxSize = 0
+	}
+
+	var avgFee, avgFeeRate, avgTxSize int64
+	numTxns := int64(len(block.Transactions()))
+	if numTxns > 1 {
+		avgFee = totalFee / (numTxns - 1)
+		avgTxSize = totalSize / (numTxns - 1)
+	}
+	if totalWeight > 0 {
+		avgFeeRate = totalFee * blockchain.WitnessScaleFactor / totalWeight
+	}
+
+	result := &btcjson.GetBlockStatsResult{
+		AverageFee:             avgFee,
+		AverageFeeRate:         avgFeeRate,
+		AverageTxSize:          avgTxSize,
+		FeeratePercentiles:     calcFeeRatePercentiles(feeRates, totalWeight),
+		Hash:                   block.Hash().String(),
+		Height:                 int64(block.Height()),
+		Ins:                    inputs,
+		MaxFee:                 maxFee,
+		MaxFeeRate:             maxFeeRate,
+		MaxTxSize:              maxTxSize,
+		MedianFee:              calcTruncatedMedian(fees),
+		MedianTime:             medianTime.Unix(),
+		MedianTxSize:           calcTruncatedMedian(txSizes),
+		MinFee:                 minFee,
+		MinFeeRate:             minFeeRate,
+		MinTxSize:              minTxSize,
+		Outs:                   outputs,
+		SegWitTotalSize:        swTotalSize,
+		SegWitTotalWeight:      swTotalWeight,
+		SegWitTxs:              swTxs,
+		Subsidy:                blockchain.CalcBlockSubsidy(block.Height(), s.cfg.ChainParams),
+		Time:                   header.Timestamp.Unix(),
+		TotalOut:               totalOut,
+		TotalSize:              totalSize,
+		TotalWeight:            totalWeight,
+		TotalFee:               totalFee,
+		Txs:                    numTxns,
+		UTXOIncrease:           outputs - inputs,
+		UTXOSizeIncrease:       utxoSizeInc,
+		UTXOIncreaseActual:     utxos - inputs,
+		UTXOSizeIncreaseActual: utxoSizeIncActual,
+	}
+	if c.Stats == nil || len(*c.Stats) == 0 {
+		return result, nil
+	}
+
+	// Only return the selected statistics.  They are selected from the
+	// JSON encoding of the result so the names match exactly.
+	marshalled, err := json.Marshal(result)
+	if err != nil {
+		context := "Failed to marshal block stats"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	var allStats map[string]json.RawMessage
+	if err := json.Unmarshal(marshalled, &allStats); err != nil {
+		context := "Failed to unmarshal block stats"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	selected := make(map[string]json.RawMessage, len(*c.Stats))
+	for _, stat := range *c.Stats {
+		value, ok := allStats[stat]
+		if !ok {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: fmt.Sprintf("Invalid selected statistic "+
+					"'%s'", stat),
+			}
+		}
+		selected[stat] = value
+	}
+	return selected, nil
+}
+
 // encodeTemplateID encodes the passed details into an ID that can be used to
 // uniquely identify a block template.
 func encodeTemplateID(prevHash *chainhash.Hash, lastGenerated time.Time) string {
@@ -2219,7 +3949,61 @@ func handleGetChainTips(s *rpcServer, cmd interface{}, closeChan <-chan struct{}
 		})
 	}
 
-	return ret, nil
+	return ret, nil
+}
+
+// handleGetBlockFilter implements the getblockfilter command.
+func handleGetBlockFilter(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetBlockFilterCmd)
+
+	// The basic filter is the only filter type defined by BIP0158.
+	if c.FilterType != nil && *c.FilterType != btcjson.FilterTypeBasic {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Unknown filtertype %q", *c.FilterType),
+		}
+	}
+	if s.cfg.CfIndex == nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCNoCFIndex,
+			Message: "The CF index must be enabled for this command",
+		}
+	}
+
+	hash, err := chainhash.NewHashFromStr(c.BlockHash)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.BlockHash)
+	}
+
+	filterBytes, err := s.cfg.CfIndex.FilterByBlockHash(hash,
+		wire.GCSFilterRegular)
+	if err != nil || len(filterBytes) == 0 {
+		rpcsLog.Debugf("Could not find committed filter for %v: %v",
+			hash, err)
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found",
+		}
+	}
+	headerBytes, err := s.cfg.CfIndex.FilterHeaderByBlockHash(hash,
+		wire.GCSFilterRegular)
+	if err != nil || len(headerBytes) != chainhash.HashSize {
+		rpcsLog.Debugf("Could not find header of committed filter "+
+			"for %v: %v", hash, err)
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found",
+		}
+	}
+
+	// The filter header is returned in the byte order of hashes like the
+	// getcfilterheader command.
+	var header chainhash.Hash
+	copy(header[:], headerBytes)
+	return &btcjson.GetBlockFilterResult{
+		Filter: hex.EncodeToString(filterBytes),
+		Header: header.String(),
+	}, nil
 }
 
 // handleGetCFilter implements the getcfilter command.
@@ -2298,6 +4082,36 @@ func handleGetDifficulty(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 	return getDifficultyRatio(best.Bits, s.cfg.ChainParams), nil
 }
 
+// handleGetDescriptorInfo implements the getdescriptorinfo command.
+func handleGetDescriptorInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetDescriptorInfoCmd)
+
+	desc, err := descriptor.Parse(c.Descriptor, s.cfg.ChainParams)
+	if err != nil {
+		return nil, rpcInvalidDescriptorError(err)
+	}
+
+	// The checksum is the checksum of the passed descriptor, which is not
+	// necessarily the same as the one of the returned descriptor since it
+	// only has public keys and might be encoded differently.
+	body, _, err := descriptor.SplitChecksum(c.Descriptor)
+	if err != nil {
+		return nil, rpcInvalidDescriptorError(err)
+	}
+	checksum, err := descriptor.Checksum(body)
+	if err != nil {
+		return nil, rpcInvalidDescriptorError(err)
+	}
+
+	return &btcjson.GetDescriptorInfoResult{
+		Descriptor:     desc.String(),
+		Checksum:       checksum,
+		IsRange:        desc.IsRange(),
+		IsSolvable:     desc.IsSolvable(),
+		HasPrivateKeys: desc.HasPrivateKeys(),
+	}, nil
+}
+
 // handleGetGenerate implements the getgenerate command.
 func handleGetGenerate(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	return s.cfg.CPUMiner.IsMining(), nil
@@ -2349,6 +4163,35 @@ func handleGetHeaders(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return hexBlockHeaders, nil
 }
 
+// handleGetIndexInfo implements the getindexinfo command.
+func handleGetIndexInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetIndexInfoCmd)
+
+	result := make(map[string]btcjson.GetIndexInfoResult)
+	if s.cfg.IndexManager == nil {
+		return result, nil
+	}
+
+	tips, err := s.cfg.IndexManager.IndexTips()
+	if err != nil {
+		context := "Failed to fetch index tips"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	// An index is synced when its tip is the tip of the main chain.
+	best := s.cfg.Chain.BestSnapshot()
+	for _, tip := range tips {
+		if c.IndexName != nil && *c.IndexName != tip.Name {
+			continue
+		}
+		result[tip.Name] = btcjson.GetIndexInfoResult{
+			Synced:          tip.Hash == best.Hash,
+			BestBlockHeight: tip.Height,
+		}
+	}
+	return result, nil
+}
+
 // handleGetInfo implements the getinfo command. We only return the fields
 // that are not related to wallet functionality.
 func handleGetInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
@@ -2368,6 +4211,84 @@ func handleGetInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (in
 	return ret, nil
 }
 
+// rpcNotInMempoolError is a convenience function for returning a nicely
+// formatted RPC error which indicates the provided transaction is not in the
+// memory pool.
+func rpcNotInMempoolError(txHash *chainhash.Hash) *btcjson.RPCError {
+	return btcjson.NewRPCError(btcjson.ErrRPCInvalidAddressOrKey,
+		fmt.Sprintf("Transaction %v not in mempool", txHash))
+}
+
+// mempoolRelatives returns the result of the getmempoolancestors and
+// getmempooldescendants commands from the passed mempool entries, which is a
+// sorted array of their transaction hashes unless the verbose flag is set.
+func mempoolRelatives(entries map[string]*btcjson.GetMempoolEntryResult,
+	verbose *bool) interface{} {
+
+	if verbose != nil && *verbose {
+		return entries
+	}
+
+	hashStrings := make([]string, 0, len(entries))
+	for hash := range entries {
+		hashStrings = append(hashStrings, hash)
+	}
+	sort.Strings(hashStrings)
+
+	return hashStrings
+}
+
+// handleGetMempoolAncestors implements the getmempoolancestors command.
+func handleGetMempoolAncestors(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetMempoolAncestorsCmd)
+
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+
+	entries, err := s.cfg.TxMemPool.MempoolAncestors(txHash)
+	if err != nil {
+		return nil, rpcNotInMempoolError(txHash)
+	}
+
+	return mempoolRelatives(entries, c.Verbose), nil
+}
+
+// handleGetMempoolDescendants implements the getmempooldescendants command.
+func handleGetMempoolDescendants(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetMempoolDescendantsCmd)
+
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+
+	entries, err := s.cfg.TxMemPool.MempoolDescendants(txHash)
+	if err != nil {
+		return nil, rpcNotInMempoolError(txHash)
+	}
+
+	return mempoolRelatives(entries, c.Verbose), nil
+}
+
+// handleGetMempoolEntry implements the getmempoolentry command.
+func handleGetMempoolEntry(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetMempoolEntryCmd)
+
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.TxID)
+	}
+
+	entry, err := s.cfg.TxMemPool.MempoolEntry(txHash)
+	if err != nil {
+		return nil, rpcNotInMempoolError(txHash)
+	}
+
+	return entry, nil
+}
+
 // handleGetMempoolInfo implements the getmempoolinfo command.
 func handleGetMempoolInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	mempoolTxns := s.cfg.TxMemPool.TxDescs()
@@ -2378,8 +4299,13 @@ func handleGetMempoolInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct
 	}
 
 	ret := &btcjson.GetMempoolInfoResult{
-		Size:  int64(len(mempoolTxns)),
-		Bytes: numBytes,
+		Loaded:        s.cfg.TxMemPool.LoadTried(),
+		Size:          int64(len(mempoolTxns)),
+		Bytes:         numBytes,
+		Usage:         s.cfg.TxMemPool.TotalSize(),
+		MaxMempool:    cfg.MaxMempool * 1000000,
+		MempoolMinFee: s.cfg.TxMemPool.MinFeeRate().ToBTC(),
+		MinRelayTxFee: cfg.minRelayTxFee.ToBTC(),
 	}
 
 	return ret, nil
@@ -2599,6 +4525,12 @@ func handleGetPeerInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return infos, nil
 }
 
+// handleGetPrioritisedTransactions implements the getprioritisedtransactions
+// command.
+func handleGetPrioritisedTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	return s.cfg.TxMemPool.PrioritisedTransactions(), nil
+}
+
 // handleGetRawMempool implements the getrawmempool command.
 func handleGetRawMempool(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.GetRawMempoolCmd)
@@ -2854,6 +4786,234 @@ func handleGetTxOut(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (i
 	return txOutReply, nil
 }
 
+// handleGetTxOutSetInfo implements the gettxoutsetinfo command.
+func handleGetTxOutSetInfo(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetTxOutSetInfoCmd)
+
+	var hashType blockchain.UtxoSetHashType
+	switch *c.HashType {
+	case "hash_serialized_3":
+		hashType = blockchain.UtxoSetHashSerialized
+	case "muhash":
+		hashType = blockchain.UtxoSetHashMuHash
+	case "none":
+		hashType = blockchain.UtxoSetHashNone
+	default:
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("'%s' is not a valid hash_type",
+				*c.HashType),
+		}
+	}
+
+	// Resolve the requested block, which defaults to the current tip.
+	best := s.cfg.Chain.BestSnapshot()
+	blockHash := best.Hash
+	if c.HashOrHeight != nil {
+		switch hashOrHeight := c.HashOrHeight.Value.(type) {
+		case int:
+			if hashOrHeight < 0 || hashOrHeight > int(best.Height) {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCInvalidParameter,
+					Message: fmt.Sprintf("Target block height %d "+
+						"out of range", hashOrHeight),
+				}
+			}
+			hash, err := s.cfg.Chain.BlockHashByHeight(int32(hashOrHeight))
+			if err != nil {
+				return nil, &btcjson.RPCError{
+					Code:    btcjson.ErrRPCBlockNotFound,
+					Message: "Block not found",
+				}
+			}
+			blockHash = *hash
+
+		case string:
+			hash, err := chainhash.NewHashFromStr(hashOrHeight)
+			if err != nil {
+				return nil, rpcDecodeHexError(hashOrHeight)
+			}
+			if !s.cfg.Chain.MainChainHasBlock(hash) {
+				return nil, &btcjson.RPCError{
+					Code:    btcjson.ErrRPCBlockNotFound,
+					Message: "Block not found in the main chain",
+				}
+			}
+			blockHash = *hash
+		}
+	}
+
+	// The coin statistics index only provides the MuHash of the UTXO set,
+	// so the UTXO set is iterated for the serialized hash at the tip.
+	useIndex := s.cfg.CoinStatsIndex != nil && *c.UseIndex
+	if useIndex && hashType == blockchain.UtxoSetHashSerialized {
+		if blockHash != best.Hash {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: "hash_serialized_3 hash type cannot be " +
+					"queried for a specific block",
+			}
+		}
+		useIndex = false
+	}
+
+	var stats *blockchain.UtxoSetStats
+	var err error
+	if useIndex {
+		stats, err = s.cfg.CoinStatsIndex.CoinStatsByBlockHash(&blockHash)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCMisc,
+				Message: "Unable to read UTXO set: " + err.Error(),
+			}
+		}
+	} else {
+		if blockHash != best.Hash {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: "Querying specific block heights requires " +
+					"the coin statistics index (--coinstatsindex)",
+			}
+		}
+		stats, err = s.cfg.Chain.FetchUtxoSetStats(hashType)
+		if err != nil {
+			context := "Failed to read UTXO set"
+			return nil, internalRPCError(err.Error(), context)
+		}
+	}
+
+	result := &btcjson.GetTxOutSetInfoResult{
+		Height:       int64(stats.Height),
+		BestBlock:    stats.Hash,
+		Transactions: stats.Transactions,
+		TxOuts:       stats.TxOuts,
+		BogoSize:     stats.BogoSize,
+		TotalAmount:  btcutil.Amount(stats.TotalAmount),
+	}
+	switch hashType {
+	case blockchain.UtxoSetHashSerialized:
+		result.HashSerialized3 = &stats.HashSerialized
+	case blockchain.UtxoSetHashMuHash:
+		result.MuHash = &stats.MuHash
+	}
+	return result, nil
+}
+
+// handleGetTxOutProof implements the gettxoutproof command.
+func handleGetTxOutProof(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.GetTxOutProofCmd)
+
+	if len(c.TxIDs) == 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid parameter, txids must not be empty",
+		}
+	}
+	txHashes := make([]*chainhash.Hash, 0, len(c.TxIDs))
+	seen := make(map[chainhash.Hash]struct{}, len(c.TxIDs))
+	for _, txID := range c.TxIDs {
+		txHash, err := chainhash.NewHashFromStr(txID)
+		if err != nil {
+			return nil, rpcDecodeHexError(txID)
+		}
+		if _, ok := seen[*txHash]; ok {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCInvalidParameter,
+				Message: fmt.Sprintf("Invalid parameter, "+
+					"duplicated txid: %s", txID),
+			}
+		}
+		seen[*txHash] = struct{}{}
+		txHashes = append(txHashes, txHash)
+	}
+
+	// Use the block with the provided hash when there is one.  Otherwise,
+	// look up the block containing the first transaction in the
+	// transaction index.
+	var blockHash *chainhash.Hash
+	if c.BlockHash != nil {
+		var err error
+		blockHash, err = chainhash.NewHashFromStr(*c.BlockHash)
+		if err != nil {
+			return nil, rpcDecodeHexError(*c.BlockHash)
+		}
+	} else {
+		if s.cfg.TxIndex == nil {
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCNoTxInfo,
+				Message: "The transaction index must be " +
+					"enabled to locate the block of a " +
+					"transaction (specify --txindex) or the " +
+					"block hash must be provided",
+			}
+		}
+
+		blockRegion, err := s.cfg.TxIndex.TxBlockRegion(txHashes[0])
+		if err != nil {
+			context := "Failed to retrieve transaction location"
+			return nil, internalRPCError(err.Error(), context)
+		}
+		if blockRegion == nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCNoTxInfo,
+				Message: "Transaction not yet in block",
+			}
+		}
+		blockHash = blockRegion.Hash
+	}
+
+	block, err := s.cfg.Chain.BlockByHash(blockHash)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCBlockNotFound,
+			Message: "Block not found",
+		}
+	}
+
+	mBlock, matched := bloom.NewMerkleBlockWithTxHashes(block, txHashes)
+	if len(matched) != len(txHashes) {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Not all transactions found in specified or " +
+				"retrieved block",
+		}
+	}
+
+	var buf bytes.Buffer
+	err = mBlock.BtcEncode(&buf, wire.ProtocolVersion, wire.LatestEncoding)
+	if err != nil {
+		context := "Failed to serialize merkle block"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// handleGetZmqNotifications implements the getzmqnotifications command.
+func handleGetZmqNotifications(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	result := btcjson.GetZmqNotificationResult{}
+	if s.cfg.ZMQNotifier == nil {
+		return &result, nil
+	}
+
+	for _, n := range s.cfg.ZMQNotifier.Notifications() {
+		address, err := url.Parse(n.Address)
+		if err != nil {
+			return nil, internalRPCError(err.Error(),
+				"Unable to parse ZMQ endpoint")
+		}
+		result = append(result, struct {
+			Type          string
+			Address       *url.URL
+			HighWaterMark int
+		}{
+			Type:          n.Type,
+			Address:       address,
+			HighWaterMark: n.HighWaterMark,
+		})
+	}
+	return &result, nil
+}
+
 // handleInvalidateBlock implements the invalidateblock command.
 func handleInvalidateBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.InvalidateBlockCmd)
@@ -2887,27 +5047,220 @@ func handleHelp(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (inter
 			context := "Failed to generate RPC usage"
 			return nil, internalRPCError(err.Error(), context)
 		}
-		return usage, nil
+		return usage, nil
+	}
+
+	// Check that the command asked for is supported and implemented.  Only
+	// search the main list of handlers since help should not be provided
+	// for commands that are unimplemented or related to wallet
+	// functionality.
+	if _, ok := rpcHandlers[command]; !ok {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Unknown command: " + command,
+		}
+	}
+
+	// Get the help for the command.
+	help, err := s.helpCacher.rpcMethodHelp(command)
+	if err != nil {
+		context := "Failed to generate help"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	return help, nil
+}
+
+// handleImportMempool implements the importmempool command.
+func handleImportMempool(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ImportMempoolCmd)
+
+	// The transactions are validated against the current chain, which
+	// requires the chain to be synced.
+	if !s.cfg.SyncMgr.IsCurrent() {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCClientInInitialDownload,
+			Message: "Can only import the mempool after the block " +
+				"download and sync is done",
+		}
+	}
+
+	useCurrentTime := true
+	if c.Options != nil && c.Options.UseCurrentTime != nil {
+		useCurrentTime = *c.Options.UseCurrentTime
+	}
+
+	path := cleanAndExpandPath(c.FilePath)
+	stats, err := s.cfg.TxMemPool.LoadFromFile(path, useCurrentTime, nil)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Unable to import mempool file: %v", err),
+		}
+	}
+
+	rpcsLog.Infof("Imported mempool from %s: %d succeeded, %d failed, "+
+		"%d already there", path, stats.Succeeded, stats.Failed,
+		stats.AlreadyThere)
+
+	return &btcjson.ImportMempoolResult{}, nil
+}
+
+// handleJoinPsbts handles joinpsbts commands.
+func handleJoinPsbts(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.JoinPsbtsCmd)
+
+	if len(c.Psbts) < 2 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "At least two PSBTs are required to join PSBTs.",
+		}
+	}
+
+	// The joined transaction has the highest version and the lowest lock
+	// time of the joined ones.  Version 2 PSBTs are joined as version 0
+	// ones.
+	packets := make([]*psbt.Packet, 0, len(c.Psbts))
+	version := int32(1)
+	lockTime := uint32(math.MaxUint32)
+	for _, b64 := range c.Psbts {
+		packet, err := decodePsbtArg(b64)
+		if err != nil {
+			return nil, err
+		}
+		if err := packet.ConvertToV0(); err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCDeserialization,
+				Message: "TX decode failed: " + err.Error(),
+			}
+		}
+		if packet.UnsignedTx.Version > version {
+			version = packet.UnsignedTx.Version
+		}
+		if packet.UnsignedTx.LockTime < lockTime {
+			lockTime = packet.UnsignedTx.LockTime
+		}
+		packets = append(packets, packet)
+	}
+
+	mtx := wire.NewMsgTx(version)
+	mtx.LockTime = lockTime
+	var inputs []psbt.PInput
+	var outputs []psbt.POutput
+	var xPubs []psbt.XPub
+	var unknowns []*psbt.Unknown
+	seenInputs := make(map[wire.OutPoint]struct{})
+	seenXPubs := make(map[string]struct{})
+	seenUnknowns := make(map[string]struct{})
+	for _, packet := range packets {
+		for i, txIn := range packet.UnsignedTx.TxIn {
+			prevOut := txIn.PreviousOutPoint
+			if _, ok := seenInputs[prevOut]; ok {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCInvalidParameter,
+					Message: fmt.Sprintf("Input %v:%d exists "+
+						"in multiple PSBTs", prevOut.Hash,
+						prevOut.Index),
+				}
+			}
+			seenInputs[prevOut] = struct{}{}
+			mtx.AddTxIn(txIn)
+			inputs = append(inputs, packet.Inputs[i])
+		}
+		for i, txOut := range packet.UnsignedTx.TxOut {
+			mtx.AddTxOut(txOut)
+			outputs = append(outputs, packet.Outputs[i])
+		}
+		for _, xPub := range packet.XPubs {
+			key := string(xPub.ExtendedKey)
+			if _, ok := seenXPubs[key]; !ok {
+				seenXPubs[key] = struct{}{}
+				xPubs = append(xPubs, xPub)
+			}
+		}
+		for _, u := range packet.Unknowns {
+			if _, ok := seenUnknowns[string(u.Key)]; !ok {
+				seenUnknowns[string(u.Key)] = struct{}{}
+				unknowns = append(unknowns, u)
+			}
+		}
+	}
+
+	// Shuffle the inputs and outputs so the joined transaction doesn't
+	// reveal which ones came from the same PSBT.
+	rand.Shuffle(len(inputs), func(i, j int) {
+		mtx.TxIn[i], mtx.TxIn[j] = mtx.TxIn[j], mtx.TxIn[i]
+		inputs[i], inputs[j] = inputs[j], inputs[i]
+	})
+	rand.Shuffle(len(outputs), func(i, j int) {
+		mtx.TxOut[i], mtx.TxOut[j] = mtx.TxOut[j], mtx.TxOut[i]
+		outputs[i], outputs[j] = outputs[j], outputs[i]
+	})
+
+	joined, err := psbt.NewFromUnsignedTx(mtx)
+	if err != nil {
+		context := "Failed to create PSBT"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	joined.Inputs = inputs
+	joined.Outputs = outputs
+	joined.XPubs = xPubs
+	joined.Unknowns = unknowns
+	return encodePsbt(joined)
+}
+
+// handleLoadTxOutSet implements the loadtxoutset command.
+func handleLoadTxOutSet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.LoadTxOutSetCmd)
+
+	path := utxoSnapshotPath(c.Path)
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Unable to open snapshot file: %v", err),
+		}
 	}
+	defer f.Close()
 
-	// Check that the command asked for is supported and implemented.  Only
-	// search the main list of handlers since help should not be provided
-	// for commands that are unimplemented or related to wallet
-	// functionality.
-	if _, ok := rpcHandlers[command]; !ok {
+	stats, err := s.cfg.Chain.LoadUtxoSnapshot(f)
+	if err != nil {
 		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCInvalidParameter,
-			Message: "Unknown command: " + command,
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Unable to load UTXO snapshot: %v", err),
 		}
 	}
 
-	// Get the help for the command.
-	help, err := s.helpCacher.rpcMethodHelp(command)
+	rpcsLog.Infof("Loaded UTXO snapshot with %d coins at block %v "+
+		"(height %d)", stats.NumCoins, stats.BaseHash, stats.BaseHeight)
+
+	return &btcjson.LoadTxOutSetResult{
+		CoinsLoaded: stats.NumCoins,
+		TipHash:     s.cfg.Chain.BestSnapshot().Hash.String(),
+		BaseHeight:  stats.BaseHeight,
+		Path:        path,
+	}, nil
+}
+
+// handlePrioritiseTransaction implements the prioritisetransaction command.
+func handlePrioritiseTransaction(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.PrioritiseTransactionCmd)
+
+	txHash, err := chainhash.NewHashFromStr(c.TxID)
 	if err != nil {
-		context := "Failed to generate help"
-		return nil, internalRPCError(err.Error(), context)
+		return nil, rpcDecodeHexError(c.TxID)
 	}
-	return help, nil
+
+	if c.Dummy != 0 {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: "Priority is no longer supported, dummy " +
+				"argument to prioritisetransaction must be 0.",
+		}
+	}
+
+	s.cfg.TxMemPool.PrioritiseTransaction(txHash, c.FeeDelta)
+
+	return true, nil
 }
 
 // handlePing implements the ping command.
@@ -3161,6 +5514,186 @@ func handleReconsiderBlock(s *rpcServer, cmd interface{}, closeChan <-chan struc
 	return nil, err
 }
 
+// handleSaveMempool implements the savemempool command.
+func handleSaveMempool(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	// Saving the mempool before it was loaded would overwrite the
+	// transactions that were not loaded yet.
+	if !s.cfg.TxMemPool.LoadTried() {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "The mempool was not loaded yet",
+		}
+	}
+
+	path := mempoolDumpPath()
+	if err := s.cfg.TxMemPool.DumpToFile(path); err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: fmt.Sprintf("Unable to dump mempool to disk: %v", err),
+		}
+	}
+
+	return &btcjson.SaveMempoolResult{FileName: path}, nil
+}
+
+// defaultScanRange is the range of the indexes of a ranged descriptor the
+// scantxoutset command scans for when the range is not given.
+const defaultScanRange = 1000
+
+// errUtxoScanAborted is returned while scanning the UTXO set when the scan is
+// aborted.
+var errUtxoScanAborted = errors.New("UTXO set scan aborted")
+
+// scanDescriptor returns the descriptor the scantxoutset command reports for
+// an unspent output with the passed script, which is its address, or the
+// script itself when it does not have an address.
+func scanDescriptor(pkScript []byte, params *chaincfg.Params) string {
+	desc := "raw(" + hex.EncodeToString(pkScript) + ")"
+	class, addrs, _, err := txscript.ExtractPkScriptAddrs(pkScript, params)
+	if err == nil && len(addrs) == 1 && class != txscript.PubKeyTy &&
+		class != txscript.MultiSigTy {
+
+		desc = "addr(" + addrs[0].EncodeAddress() + ")"
+	}
+
+	// The descriptor only consists of characters which can be part of a
+	// descriptor.
+	desc, _ = descriptor.AddChecksum(desc)
+	return desc
+}
+
+// handleScanTxOutSet implements the scantxoutset command.
+func handleScanTxOutSet(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.ScanTxOutSetCmd)
+
+	switch c.Action {
+	case "status":
+		if atomic.LoadInt32(&s.utxoScanning) == 0 {
+			return nil, nil
+		}
+		return &btcjson.ScanTxOutSetStatusResult{
+			Progress: int(atomic.LoadInt32(&s.utxoScanProgress)),
+		}, nil
+
+	case "abort":
+		if atomic.LoadInt32(&s.utxoScanning) == 0 {
+			return false, nil
+		}
+		atomic.StoreInt32(&s.utxoScanAbort, 1)
+		return true, nil
+
+	case "start":
+		if c.ScanObjects == nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCInvalidParameter,
+				Message: "scanobjects argument is required for the start action",
+			}
+		}
+
+	default:
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Invalid action '%s'", c.Action),
+		}
+	}
+
+	// Collect the scripts to scan for before starting the scan.
+	scripts := make(map[string]string)
+	for _, obj := range *c.ScanObjects {
+		desc, err := descriptor.Parse(obj.Desc, s.cfg.ChainParams)
+		if err != nil {
+			return nil, rpcInvalidDescriptorError(err)
+		}
+
+		var begin, end uint32
+		if desc.IsRange() {
+			end = defaultScanRange - 1
+			if obj.Range != nil {
+				begin, end, err = parseDescriptorRange(obj.Range)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		for i := begin; i <= end; i++ {
+			descScripts, err := desc.Scripts(i)
+			if err != nil {
+				return nil, rpcInvalidDescriptorError(err)
+			}
+			for _, script := range descScripts {
+				scripts[string(script)] = scanDescriptor(script,
+					s.cfg.ChainParams)
+			}
+		}
+	}
+
+	if !atomic.CompareAndSwapInt32(&s.utxoScanning, 0, 1) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCMisc,
+			Message: "Scan already in progress, use action \"abort\" or \"status\"",
+		}
+	}
+	defer atomic.StoreInt32(&s.utxoScanning, 0)
+	atomic.StoreInt32(&s.utxoScanAbort, 0)
+	atomic.StoreInt32(&s.utxoScanProgress, 0)
+
+	result := &btcjson.ScanTxOutSetResult{
+		Success:  true,
+		Unspents: []btcjson.ScanTxOutSetUnspent{},
+	}
+	var totalAmount btcutil.Amount
+	best, err := s.cfg.Chain.ForEachUtxo(func(outpoint wire.OutPoint,
+		entry *blockchain.UtxoEntry) error {
+
+		// The unspent outputs are iterated in the order of the hashes
+		// of their transactions, so the progress is estimated from the
+		// first bytes of the hash.
+		result.TxOuts++
+		if result.TxOuts%8192 == 0 {
+			progress := (int32(outpoint.Hash[0])<<8 |
+				int32(outpoint.Hash[1])) * 100 >> 16
+			atomic.StoreInt32(&s.utxoScanProgress, progress)
+
+			if atomic.LoadInt32(&s.utxoScanAbort) != 0 {
+				return errUtxoScanAborted
+			}
+			select {
+			case <-closeChan:
+				return errUtxoScanAborted
+			default:
+			}
+		}
+
+		desc, ok := scripts[string(entry.PkScript())]
+		if !ok {
+			return nil
+		}
+		totalAmount += btcutil.Amount(entry.Amount())
+		result.Unspents = append(result.Unspents, btcjson.ScanTxOutSetUnspent{
+			TxID:         outpoint.Hash.String(),
+			Vout:         outpoint.Index,
+			ScriptPubKey: hex.EncodeToString(entry.PkScript()),
+			Desc:         desc,
+			Amount:       btcutil.Amount(entry.Amount()).ToBTC(),
+			Coinbase:     entry.IsCoinBase(),
+			Height:       entry.BlockHeight(),
+		})
+		return nil
+	})
+	if err == errUtxoScanAborted {
+		return &btcjson.ScanTxOutSetResult{Success: false}, nil
+	}
+	if err != nil {
+		context := "Failed to scan the UTXO set"
+		return nil, internalRPCError(err.Error(), context)
+	}
+
+	result.Height = best.Height
+	result.BestBlock = best.Hash.String()
+	result.TotalAmount = totalAmount.ToBTC()
+	return result, nil
+}
+
 // handleSearchRawTransactions implements the searchrawtransactions command.
 func handleSearchRawTransactions(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	// Respond with an error if the address index is not enabled.
@@ -3571,7 +6104,25 @@ const messageSignatureHeader = "Bitcoin Signed Message:\n"
 func handleSignMessageWithPrivKey(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.SignMessageWithPrivKeyCmd)
 
-	wif, err := btcutil.DecodeWIF(c.PrivKey)
+	wif, err := decodeWIFArg(c.PrivKey, s.cfg.ChainParams)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	wire.WriteVarString(&buf, 0, messageSignatureHeader)
+	wire.WriteVarString(&buf, 0, c.Message)
+	messageHash := chainhash.DoubleHashB(buf.Bytes())
+
+	sig := ecdsa.SignCompact(wif.PrivKey, messageHash, wif.CompressPubKey)
+
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// decodeWIFArg decodes the passed WIF encoded private key argument of a
+// command, which must be for the passed network.
+func decodeWIFArg(privKey string, params *chaincfg.Params) (*btcutil.WIF, error) {
+	wif, err := btcutil.DecodeWIF(privKey)
 	if err != nil {
 		message := "Invalid private key"
 		switch err {
@@ -3584,22 +6135,363 @@ func handleSignMessageWithPrivKey(s *rpcServer, cmd interface{}, closeChan <-cha
 			Code:    btcjson.ErrRPCInvalidAddressOrKey,
 			Message: message,
 		}
-	}
-	if !wif.IsForNet(s.cfg.ChainParams) {
-		return nil, &btcjson.RPCError{
-			Code:    btcjson.ErrRPCInvalidAddressOrKey,
-			Message: "Private key for wrong network",
+	}
+	if !wif.IsForNet(params) {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Private key for wrong network",
+		}
+	}
+	return wif, nil
+}
+
+// signingKey is a private key which signrawtransactionwithkey signs with,
+// along with whether its public key is serialized compressed.
+type signingKey struct {
+	key        *btcec.PrivateKey
+	compressed bool
+}
+
+// addSigningKey adds the passed private key to the keys signrawtransactionwithkey
+// signs with, under the addresses of the outputs it can spend.  Tapscript
+// keys are looked up with the pubkey address of their compressed public key,
+// so that one is always added.
+func addSigningKey(keys map[string]signingKey, wif *btcutil.WIF,
+	params *chaincfg.Params) error {
+
+	pubKey := wif.PrivKey.PubKey()
+	addr, err := btcutil.NewAddressPubKey(wif.SerializePubKey(), params)
+	if err != nil {
+		return err
+	}
+	keys[addr.EncodeAddress()] = signingKey{wif.PrivKey, wif.CompressPubKey}
+
+	compressedPubKey := pubKey.SerializeCompressed()
+	addr, err = btcutil.NewAddressPubKey(compressedPubKey, params)
+	if err != nil {
+		return err
+	}
+	keys[addr.EncodeAddress()] = signingKey{wif.PrivKey, true}
+	if !wif.CompressPubKey {
+		return nil
+	}
+
+	witnessAddr, err := btcutil.NewAddressWitnessPubKeyHash(
+		btcutil.Hash160(compressedPubKey), params,
+	)
+	if err != nil {
+		return err
+	}
+	keys[witnessAddr.EncodeAddress()] = signingKey{wif.PrivKey, true}
+
+	outputKey := txscript.ComputeTaprootKeyNoScript(pubKey)
+	taprootAddr, err := btcutil.NewAddressTaproot(
+		schnorr.SerializePubKey(outputKey), params,
+	)
+	if err != nil {
+		return err
+	}
+	keys[taprootAddr.EncodeAddress()] = signingKey{wif.PrivKey, true}
+	return nil
+}
+
+// addSigningScripts adds the passed redeem and witness scripts of a previous
+// output of a signrawtransactionwithkey command to the scripts it signs
+// with, under their pay-to-script-hash and pay-to-witness-script-hash
+// addresses.  A witness script is also added nested in a
+// pay-to-script-hash output.
+func addSigningScripts(scripts map[string][]byte, input *btcjson.RawTxWitnessInput,
+	params *chaincfg.Params) error {
+
+	if input.RedeemScript != nil {
+		redeemScript, err := hex.DecodeString(*input.RedeemScript)
+		if err != nil {
+			return rpcDecodeHexError(*input.RedeemScript)
+		}
+		addr, err := btcutil.NewAddressScriptHash(redeemScript, params)
+		if err != nil {
+			return err
+		}
+		scripts[addr.EncodeAddress()] = redeemScript
+	}
+	if input.WitnessScript != nil {
+		witnessScript, err := hex.DecodeString(*input.WitnessScript)
+		if err != nil {
+			return rpcDecodeHexError(*input.WitnessScript)
+		}
+		scriptHash := sha256.Sum256(witnessScript)
+		addr, err := btcutil.NewAddressWitnessScriptHash(scriptHash[:],
+			params)
+		if err != nil {
+			return err
+		}
+		scripts[addr.EncodeAddress()] = witnessScript
+
+		program, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return err
+		}
+		nestedAddr, err := btcutil.NewAddressScriptHash(program, params)
+		if err != nil {
+			return err
+		}
+		scripts[nestedAddr.EncodeAddress()] = program
+	}
+	return nil
+}
+
+// parseSigHashType returns the signature hash type with the passed name.
+func parseSigHashType(name string) (txscript.SigHashType, error) {
+	for hashType, hashTypeName := range sigHashTypeStrings {
+		if hashTypeName == name {
+			return hashType, nil
+		}
+	}
+	return 0, &btcjson.RPCError{
+		Code:    btcjson.ErrRPCInvalidParameter,
+		Message: fmt.Sprintf("'%s' is not a valid sighash parameter.", name),
+	}
+}
+
+// signRawTxError returns the error entry of the passed input for the result
+// of a signrawtransactionwithkey command.
+func signRawTxError(txIn *wire.TxIn, message string) btcjson.SignRawTransactionError {
+	var witness []string
+	if len(txIn.Witness) > 0 {
+		witness = txIn.Witness.ToHexStrings()
+	}
+	return btcjson.SignRawTransactionError{
+		TxID:      txIn.PreviousOutPoint.Hash.String(),
+		Vout:      txIn.PreviousOutPoint.Index,
+		Witness:   witness,
+		ScriptSig: hex.EncodeToString(txIn.SignatureScript),
+		Sequence:  txIn.Sequence,
+		Error:     message,
+	}
+}
+
+// handleSignRawTransactionWithKey handles signrawtransactionwithkey commands.
+func handleSignRawTransactionWithKey(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.SignRawTransactionWithKeyCmd)
+
+	mtx, err := decodeRawTxArg(c.RawTx)
+	if err != nil {
+		return nil, err
+	}
+	sigHashName := "DEFAULT"
+	if c.SigHashType != nil {
+		sigHashName = *c.SigHashType
+	}
+	sigHashType, err := parseSigHashType(sigHashName)
+	if err != nil {
+		return nil, err
+	}
+
+	params := s.cfg.ChainParams
+	keys := make(map[string]signingKey)
+	for _, privKey := range c.PrivKeys {
+		wif, err := decodeWIFArg(privKey, params)
+		if err != nil {
+			return nil, err
+		}
+		if err := addSigningKey(keys, wif, params); err != nil {
+			context := "Failed to derive addresses of key"
+			return nil, internalRPCError(err.Error(), context)
+		}
+	}
+
+	// The outputs spent by the inputs are taken from the passed previous
+	// outputs first, and from the UTXO set otherwise.  The amounts of
+	// passed previous outputs are only known when they are included or
+	// the output is in the UTXO set.
+	prevOuts := make(map[wire.OutPoint]*wire.TxOut)
+	missingAmounts := make(map[wire.OutPoint]struct{})
+	scripts := make(map[string][]byte)
+	if c.Inputs != nil {
+		for i := range *c.Inputs {
+			input := &(*c.Inputs)[i]
+			txHash, err := chainhash.NewHashFromStr(input.Txid)
+			if err != nil {
+				return nil, rpcDecodeHexError(input.Txid)
+			}
+			pkScript, err := hex.DecodeString(input.ScriptPubKey)
+			if err != nil {
+				return nil, rpcDecodeHexError(input.ScriptPubKey)
+			}
+			outpoint := wire.OutPoint{Hash: *txHash, Index: input.Vout}
+
+			utxo, err := fetchUnspentOutput(s, outpoint)
+			if err != nil {
+				return nil, err
+			}
+			if utxo != nil && !bytes.Equal(utxo.PkScript, pkScript) {
+				return nil, &btcjson.RPCError{
+					Code: btcjson.ErrRPCDeserialization,
+					Message: fmt.Sprintf("Previous output "+
+						"scriptPubKey mismatch for %v",
+						outpoint),
+				}
+			}
+
+			var amount btcutil.Amount
+			switch {
+			case input.Amount != nil:
+				amount, err = btcutil.NewAmount(*input.Amount)
+				if err != nil {
+					return nil, &btcjson.RPCError{
+						Code:    btcjson.ErrRPCType,
+						Message: "Invalid amount",
+					}
+				}
+			case utxo != nil:
+				amount = btcutil.Amount(utxo.Value)
+			default:
+				missingAmounts[outpoint] = struct{}{}
+			}
+			prevOuts[outpoint] = wire.NewTxOut(int64(amount), pkScript)
+
+			if err := addSigningScripts(scripts, input, params); err != nil {
+				return nil, err
+			}
+		}
+	}
+	allPrevOutsKnown := len(missingAmounts) == 0
+	prevOutFetcher := txscript.NewMultiPrevOutFetcher(nil)
+	for _, txIn := range mtx.TxIn {
+		outpoint := txIn.PreviousOutPoint
+		prevOut, ok := prevOuts[outpoint]
+		if !ok {
+			prevOut, err = fetchUnspentOutput(s, outpoint)
+			if err != nil {
+				return nil, err
+			}
+			if prevOut != nil {
+				prevOuts[outpoint] = prevOut
+			}
+		}
+
+		// Signature hashes can't be calculated without the outputs
+		// spent by all inputs, so an empty output takes the place of
+		// missing ones.  Taproot inputs aren't signed in that case.
+		if prevOut == nil {
+			allPrevOutsKnown = false
+			prevOut = wire.NewTxOut(0, nil)
+		}
+		prevOutFetcher.AddPrevOut(outpoint, prevOut)
+	}
+	sigHashes := txscript.NewTxSigHashes(mtx, prevOutFetcher)
+
+	kdb := txscript.KeyClosure(func(addr btcutil.Address) (*btcec.PrivateKey, bool, error) {
+		key, ok := keys[addr.EncodeAddress()]
+		if !ok {
+			return nil, false, errors.New("no key for address")
+		}
+		return key.key, key.compressed, nil
+	})
+	sdb := txscript.ScriptClosure(func(addr btcutil.Address) ([]byte, error) {
+		script, ok := scripts[addr.EncodeAddress()]
+		if !ok {
+			return nil, errors.New("no script for address")
+		}
+		return script, nil
+	})
+
+	var signErrors []btcjson.SignRawTransactionError
+	for i, txIn := range mtx.TxIn {
+		outpoint := txIn.PreviousOutPoint
+		prevOut, ok := prevOuts[outpoint]
+		if !ok {
+			signErrors = append(signErrors, signRawTxError(txIn,
+				"Input not found or already spent"))
+			continue
+		}
+
+		// Signatures of taproot inputs commit to the outputs spent by
+		// all inputs, while other ones commit to the amount of their
+		// own for segwit spends.  Only taproot inputs use the default
+		// signature hash type, which is the same as SIGHASH_ALL for
+		// other ones.
+		isTaproot := txscript.IsPayToTaproot(prevOut.PkScript)
+		_, missingAmount := missingAmounts[outpoint]
+		switch {
+		case isTaproot && !allPrevOutsKnown:
+			signErrors = append(signErrors, signRawTxError(txIn,
+				"Unable to sign taproot input without the "+
+					"outputs spent by all inputs"))
+			continue
+		case missingAmount && isSegWitSpend(prevOut.PkScript, sdb, params):
+			signErrors = append(signErrors, signRawTxError(txIn,
+				"Missing amount"))
+			continue
+		}
+		hashType := sigHashType
+		if hashType == txscript.SigHashDefault && !isTaproot {
+			hashType = txscript.SigHashAll
+		}
+		if hashType&^txscript.SigHashAnyOneCanPay == txscript.SigHashSingle &&
+			i >= len(mtx.TxOut) {
+
+			signErrors = append(signErrors, signRawTxError(txIn,
+				"Signature hash type SINGLE without a "+
+					"corresponding output"))
+			continue
+		}
+
+		// Inputs which can't be signed keep their existing signature
+		// script and witness, which are merged with the new
+		// signatures otherwise.
+		sigScript, witness, signErr := txscript.SignTxWitnessOutput(
+			params, mtx, i, sigHashes, prevOut.Value,
+			prevOut.PkScript, hashType, kdb, sdb,
+			txIn.SignatureScript, txIn.Witness,
+		)
+		if signErr == nil {
+			txIn.SignatureScript = sigScript
+			txIn.Witness = witness
+		}
+
+		vm, err := txscript.NewEngine(prevOut.PkScript, mtx, i,
+			txscript.StandardVerifyFlags, nil, sigHashes,
+			prevOut.Value, prevOutFetcher)
+		if err == nil {
+			err = vm.Execute()
+		}
+		if err != nil {
+			message := err.Error()
+			if signErr != nil {
+				message = "Unable to sign input: " + signErr.Error()
+			}
+			signErrors = append(signErrors, signRawTxError(txIn, message))
 		}
 	}
 
-	var buf bytes.Buffer
-	wire.WriteVarString(&buf, 0, messageSignatureHeader)
-	wire.WriteVarString(&buf, 0, c.Message)
-	messageHash := chainhash.DoubleHashB(buf.Bytes())
+	txHex, err := messageToHex(mtx)
+	if err != nil {
+		return nil, err
+	}
+	return &btcjson.SignRawTransactionWithKeyResult{
+		Hex:      txHex,
+		Complete: len(signErrors) == 0,
+		Errors:   signErrors,
+	}, nil
+}
 
-	sig := ecdsa.SignCompact(wif.PrivKey, messageHash, wif.CompressPubKey)
+// isSegWitSpend returns whether spending an output with the passed script is
+// a segwit spend, which is the case for witness programs and
+// pay-to-script-hash outputs with a witness program as the redeem script
+// found in the passed script database.
+func isSegWitSpend(pkScript []byte, sdb txscript.ScriptDB,
+	params *chaincfg.Params) bool {
 
-	return base64.StdEncoding.EncodeToString(sig), nil
+	if !txscript.IsPayToScriptHash(pkScript) {
+		return txscript.IsWitnessProgram(pkScript)
+	}
+	addr, err := btcutil.NewAddressScriptHashFromHash(pkScript[2:22], params)
+	if err != nil {
+		return false
+	}
+	redeemScript, err := sdb.GetScript(addr)
+	return err == nil && txscript.IsWitnessProgram(redeemScript)
 }
 
 // handleStop implements the stop command.
@@ -3644,11 +6536,236 @@ func handleSubmitBlock(s *rpcServer, cmd interface{}, closeChan <-chan struct{})
 	return nil, nil
 }
 
+// handleSubmitPackage implements the submitpackage command.
+func handleSubmitPackage(s *rpcServer, cmd interface{},
+	closeChan <-chan struct{}) (interface{}, error) {
+
+	c := cmd.(*btcjson.SubmitPackageCmd)
+
+	txns, err := decodePackageTxns(c.RawTxns)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject the package before validating it when any of its
+	// transactions burns more than the caller allows.
+	maxBurnAmount, err := btcutil.NewAmount(*c.MaxBurnAmount)
+	if err != nil || maxBurnAmount < 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid maxburnamount",
+		}
+	}
+	for _, tx := range txns {
+		for _, txOut := range tx.MsgTx().TxOut {
+			if !txscript.IsUnspendable(txOut.PkScript) ||
+				btcutil.Amount(txOut.Value) <= maxBurnAmount {
+
+				continue
+			}
+
+			return nil, &btcjson.RPCError{
+				Code: btcjson.ErrRPCTxError,
+				Message: "Unspendable output exceeds maximum " +
+					"configured by user (maxburnamount)",
+			}
+		}
+	}
+
+	// The mempool expects the maximum fee rate in satoshi/kB.
+	maxFeeRate, err := btcutil.NewAmount(*c.MaxFeeRate)
+	if err != nil || maxFeeRate < 0 {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidParameter,
+			Message: "Invalid maxfeerate",
+		}
+	}
+
+	pkgResult, err := s.cfg.TxMemPool.ProcessPackage(txns, maxFeeRate)
+	if err != nil {
+		if _, ok := err.(mempool.RuleError); !ok {
+			rpcsLog.Errorf("Failed to process package: %v", err)
+			return nil, internalRPCError(err.Error(), "")
+		}
+
+		rpcsLog.Debugf("Rejected package: %v", err)
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCTxError,
+			Message: "package rejected: " + err.Error(),
+		}
+	}
+
+	result := &btcjson.SubmitPackageResult{
+		PackageMsg:           "success",
+		TxResults:            make(map[string]btcjson.SubmitPackageTxResult),
+		ReplacedTransactions: make([]string, 0, len(pkgResult.Replaced)),
+	}
+	if pkgResult.Failed() {
+		result.PackageMsg = "transaction failed"
+	}
+	for i, tx := range txns {
+		txResult := btcjson.SubmitPackageTxResult{
+			Txid: tx.Hash().String(),
+		}
+
+		r := pkgResult.TxResults[i]
+		switch {
+		// NOTE: "package-not-validated" and "missing-inputs" are what
+		// bitcoind returns here, so we mimic the same error messages.
+		case r == nil:
+			txResult.Error = "package-not-validated"
+
+		case r.MissingParents != nil:
+			txResult.Error = "missing-inputs"
+
+		case r.Err != nil:
+			txResult.Error = r.Err.Error()
+
+		default:
+			txResult.Vsize = int32(r.TxSize)
+			txResult.Fees = packageTxFees(r)
+		}
+
+		result.TxResults[tx.WitnessHash().String()] = txResult
+	}
+	for _, tx := range pkgResult.Replaced {
+		result.ReplacedTransactions = append(
+			result.ReplacedTransactions, tx.Hash().String(),
+		)
+	}
+
+	if len(pkgResult.Accepted) == 0 {
+		return result, nil
+	}
+
+	// Generate and relay inventory vectors for all newly accepted
+	// transactions into the memory pool and notify both websocket and
+	// getblocktemplate long poll clients of them.
+	s.cfg.ConnMgr.RelayTransactions(pkgResult.Accepted)
+	s.NotifyNewTransactions(pkgResult.Accepted)
+
+	// Keep track of the accepted transactions of the package so that they
+	// can be rebroadcast if they don't make their way into a block.
+	for _, txD := range pkgResult.Accepted {
+		iv := wire.NewInvVect(wire.InvTypeTx, txD.Tx.Hash())
+		s.cfg.ConnMgr.AddRebroadcastInventory(iv, txD)
+	}
+
+	return result, nil
+}
+
 // handleUptime implements the uptime command.
 func handleUptime(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	return time.Now().Unix() - s.cfg.StartupTime, nil
 }
 
+// fetchPrevTx returns the transaction with the passed hash from the memory
+// pool or, when the transaction index is enabled, the block database.  Nil is
+// returned when the transaction isn't found.
+func fetchPrevTx(s *rpcServer, txHash *chainhash.Hash) (*wire.MsgTx, error) {
+	if tx, err := s.cfg.TxMemPool.FetchTransaction(txHash); err == nil {
+		return tx.MsgTx(), nil
+	}
+	if s.cfg.TxIndex == nil {
+		return nil, nil
+	}
+
+	blockRegion, err := s.cfg.TxIndex.TxBlockRegion(txHash)
+	if err != nil {
+		context := "Failed to retrieve transaction location"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if blockRegion == nil {
+		return nil, nil
+	}
+	var txBytes []byte
+	err = s.cfg.DB.View(func(dbTx database.Tx) error {
+		var err error
+		txBytes, err = dbTx.FetchBlockRegion(blockRegion)
+		return err
+	})
+	if err != nil {
+		return nil, nil
+	}
+	var msgTx wire.MsgTx
+	if err := msgTx.Deserialize(bytes.NewReader(txBytes)); err != nil {
+		context := "Failed to deserialize transaction"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	return &msgTx, nil
+}
+
+// fetchUnspentOutput returns the unspent output with the passed outpoint
+// from the chainstate or the memory pool, or nil if there isn't one.
+func fetchUnspentOutput(s *rpcServer, outpoint wire.OutPoint) (*wire.TxOut, error) {
+	entry, err := s.cfg.Chain.FetchUtxoEntry(outpoint)
+	if err != nil {
+		context := "Failed to fetch utxo"
+		return nil, internalRPCError(err.Error(), context)
+	}
+	if entry != nil && !entry.IsSpent() {
+		return wire.NewTxOut(entry.Amount(), entry.PkScript()), nil
+	}
+
+	tx, err := s.cfg.TxMemPool.FetchTransaction(&outpoint.Hash)
+	if err != nil || outpoint.Index >= uint32(len(tx.MsgTx().TxOut)) {
+		return nil, nil
+	}
+	if s.cfg.TxMemPool.CheckSpend(outpoint) != nil {
+		return nil, nil
+	}
+	return tx.MsgTx().TxOut[outpoint.Index], nil
+}
+
+// handleUtxoUpdatePsbt handles utxoupdatepsbt commands.
+func handleUtxoUpdatePsbt(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.UtxoUpdatePsbtCmd)
+
+	packet, err := decodePsbtArg(c.Psbt)
+	if err != nil {
+		return nil, err
+	}
+
+	// Segwit inputs get the witness UTXO from the chainstate or the
+	// memory pool, while others get the previous transaction from the
+	// memory pool or the transaction index, since it's required to sign
+	// them.  Inputs which already have the data or are finalized are
+	// left alone.
+	for i, txIn := range packet.UnsignedTx.TxIn {
+		pIn := &packet.Inputs[i]
+		if pIn.FinalScriptSig != nil || pIn.FinalScriptWitness != nil ||
+			pIn.NonWitnessUtxo != nil {
+
+			continue
+		}
+
+		utxo := pIn.WitnessUtxo
+		if utxo == nil {
+			utxo, err = fetchUnspentOutput(s, txIn.PreviousOutPoint)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if utxo != nil && isSegWitPsbtInput(pIn, utxo.PkScript) {
+			pIn.WitnessUtxo = utxo
+			continue
+		}
+
+		prevOut := txIn.PreviousOutPoint
+		tx, err := fetchPrevTx(s, &prevOut.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if tx != nil && prevOut.Index < uint32(len(tx.TxOut)) &&
+			!isSegWitPsbtInput(pIn, tx.TxOut[prevOut.Index].PkScript) {
+
+			pIn.NonWitnessUtxo = tx
+		}
+	}
+
+	return encodePsbt(packet)
+}
+
 // handleValidateAddress implements the validateaddress command.
 func handleValidateAddress(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
 	c := cmd.(*btcjson.ValidateAddressCmd)
@@ -3811,6 +6928,52 @@ func handleVerifyMessage(s *rpcServer, cmd interface{}, closeChan <-chan struct{
 	return address.EncodeAddress() == c.Address, nil
 }
 
+// handleVerifyTxOutProof implements the verifytxoutproof command.
+func handleVerifyTxOutProof(s *rpcServer, cmd interface{}, closeChan <-chan struct{}) (interface{}, error) {
+	c := cmd.(*btcjson.VerifyTxOutProofCmd)
+
+	proof, err := hex.DecodeString(c.Proof)
+	if err != nil {
+		return nil, rpcDecodeHexError(c.Proof)
+	}
+	var mBlock wire.MsgMerkleBlock
+	err = mBlock.BtcDecode(bytes.NewReader(proof), wire.ProtocolVersion,
+		wire.LatestEncoding)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCDeserialization,
+			Message: "Proof decode failed: " + err.Error(),
+		}
+	}
+
+	// Proofs which don't commit to the merkle root of the header prove
+	// nothing, so no transactions are returned for them.
+	matches := make([]string, 0)
+	txHashes, _, err := bloom.ExtractMatches(&mBlock)
+	if err != nil {
+		return matches, nil
+	}
+
+	blockHash := mBlock.Header.BlockHash()
+	block, err := s.cfg.Chain.BlockByHash(&blockHash)
+	if err != nil {
+		return nil, &btcjson.RPCError{
+			Code:    btcjson.ErrRPCInvalidAddressOrKey,
+			Message: "Block not found in chain",
+		}
+	}
+
+	// The proof is only valid if it was built for the actual number of
+	// transactions in the block.
+	if uint32(len(block.Transactions())) != mBlock.Transactions {
+		return matches, nil
+	}
+	for _, txHash := range txHashes {
+		matches = append(matches, txHash.String())
+	}
+	return matches, nil
+}
+
 // handleVersion implements the version command.
 //
 // NOTE: This is a btcsuite extension ported from github.com/decred/dcrd.
@@ -3832,25 +6995,15 @@ func handleTestMempoolAccept(s *rpcServer, cmd interface{},
 
 	c := cmd.(*btcjson.TestMempoolAcceptCmd)
 
-	// Create txns to hold the decoded tx.
-	txns := make([]*btcutil.Tx, 0, len(c.RawTxns))
-
-	// Iterate the raw hex slice and decode them.
-	for _, rawTx := range c.RawTxns {
-		rawBytes, err := hex.DecodeString(rawTx)
-		if err != nil {
-			return nil, rpcDecodeHexError(rawTx)
-		}
-
-		tx, err := btcutil.NewTxFromBytes(rawBytes)
-		if err != nil {
-			return nil, &btcjson.RPCError{
-				Code:    btcjson.ErrRPCDeserialization,
-				Message: "TX decode failed: " + err.Error(),
-			}
-		}
+	txns, err := decodePackageTxns(c.RawTxns)
+	if err != nil {
+		return nil, err
+	}
 
-		txns = append(txns, tx)
+	// More than one transaction is evaluated as a package, so the
+	// transactions may spend each other.
+	if len(txns) > 1 {
+		return testPackageAccept(s, txns, c.MaxFeeRate)
 	}
 
 	results := make([]*btcjson.TestMempoolAcceptResult, 0, len(txns))
@@ -3918,6 +7071,135 @@ func handleTestMempoolAccept(s *rpcServer, cmd interface{},
 	return results, nil
 }
 
+// testPackageAccept returns the results of the testmempoolaccept command for
+// the passed package of transactions.
+func testPackageAccept(s *rpcServer, txns []*btcutil.Tx,
+	maxFeeRate float64) ([]*btcjson.TestMempoolAcceptResult, error) {
+
+	results := make([]*btcjson.TestMempoolAcceptResult, 0, len(txns))
+	for _, tx := range txns {
+		results = append(results, &btcjson.TestMempoolAcceptResult{
+			Txid:  tx.Hash().String(),
+			Wtxid: tx.WitnessHash().String(),
+		})
+	}
+
+	pkgResult, err := s.cfg.TxMemPool.CheckPackageAcceptance(txns)
+	if err != nil {
+		ruleErr, ok := err.(mempool.RuleError)
+		if !ok {
+			return nil, internalRPCError(err.Error(), "")
+		}
+
+		// A package error applies to all of its transactions, none
+		// of which are validated.
+		pkgErr, ok := ruleErr.Err.(mempool.PackageRuleError)
+		if !ok {
+			return nil, internalRPCError(err.Error(), "")
+		}
+		for _, item := range results {
+			item.PackageError = pkgErr.Reason
+		}
+
+		return results, nil
+	}
+
+	// The transactions are only reported as allowed when the whole package
+	// would be accepted, which is the case when none of them were
+	// rejected.  Otherwise, only the reason the rejected transaction was
+	// rejected for is reported since the rest were either not validated
+	// or depend on the outcome of the rejected transaction.
+	if pkgResult.Failed() {
+		for i, r := range pkgResult.TxResults {
+			switch {
+			case r == nil || r.Err == nil && r.MissingParents == nil:
+				continue
+
+			// NOTE: "missing-inputs" is what bitcoind returns here,
+			// so we mimic the same error message.
+			case r.MissingParents != nil:
+				results[i].RejectReason = "missing-inputs"
+
+			default:
+				results[i].RejectReason = r.Err.Error()
+			}
+		}
+
+		return results, nil
+	}
+
+	for i, r := range pkgResult.TxResults {
+		item := results[i]
+
+		// Stop at the first transaction that exceeds the max fee rate
+		// since the package would not be accepted.
+		item.Fees, item.Allowed = validateFeeRate(
+			r.TxFee, r.TxSize, maxFeeRate,
+		)
+		if !item.Allowed {
+			// NOTE: "max-fee-exceeded" is what bitcoind returns
+			// here, so we mimic the same error message.
+			item.RejectReason = "max-fee-exceeded"
+			break
+		}
+
+		item.Vsize = int32(r.TxSize)
+		item.Fees = packageTxFees(r)
+	}
+
+	return results, nil
+}
+
+// packageTxFees returns the fees of the passed transaction of a package.
+func packageTxFees(r *mempool.PackageTxResult) *btcjson.TestMempoolAcceptFees {
+	includes := make([]string, 0, len(r.EffectiveIncludes))
+	for _, wtxid := range r.EffectiveIncludes {
+		includes = append(includes, wtxid.String())
+	}
+
+	return &btcjson.TestMempoolAcceptFees{
+		Base:              r.TxFee.ToBTC(),
+		EffectiveFeeRate:  r.EffectiveFeeRate.ToBTC(),
+		EffectiveIncludes: includes,
+	}
+}
+
+// decodePackageTxns decodes the passed serialized transactions, of which there
+// must be at least one and at most the maximum number of transactions in a
+// package.
+func decodePackageTxns(rawTxns []string) ([]*btcutil.Tx, error) {
+	if len(rawTxns) == 0 || len(rawTxns) > mempool.MaxPackageCount {
+		return nil, &btcjson.RPCError{
+			Code: btcjson.ErrRPCInvalidParameter,
+			Message: fmt.Sprintf("Array must contain between 1 "+
+				"and %d transactions.", mempool.MaxPackageCount),
+		}
+	}
+
+	// Create txns to hold the decoded tx.
+	txns := make([]*btcutil.Tx, 0, len(rawTxns))
+
+	// Iterate the raw hex slice and decode them.
+	for _, rawTx := range rawTxns {
+		rawBytes, err := hex.DecodeString(rawTx)
+		if err != nil {
+			return nil, rpcDecodeHexError(rawTx)
+		}
+
+		tx, err := btcutil.NewTxFromBytes(rawBytes)
+		if err != nil {
+			return nil, &btcjson.RPCError{
+				Code:    btcjson.ErrRPCDeserialization,
+				Message: "TX decode failed: " + err.Error(),
+			}
+		}
+
+		txns = append(txns, tx)
+	}
+
+	return txns, nil
+}
+
 // handleGetTxSpendingPrevOut implements the gettxspendingprevout command.
 func handleGetTxSpendingPrevOut(s *rpcServer, cmd interface{},
 	closeChan <-chan struct{}) (interface{}, error) {
@@ -4004,6 +7286,13 @@ type rpcServer struct {
 	helpCacher             *helpCacher
 	requestProcessShutdown chan struct{}
 	quit                   chan int
+
+	// utxoScanning, utxoScanProgress and utxoScanAbort track the UTXO set
+	// scan of the scantxoutset command, of which only one runs at a time.
+	// They must be accessed atomically.
+	utxoScanning     int32
+	utxoScanProgress int32
+	utxoScanAbort    int32
 }
 
 // httpStatusLine returns a response Status-Line (RFC 2616 Section 6.1)
@@ -4831,9 +8120,21 @@ type rpcserverConfig struct {
 	AddrIndex *indexers.AddrIndex
 	CfIndex   *indexers.CfIndex
 
+	// CoinStatsIndex is the coin statistics index.  It is nil when the
+	// index is disabled.
+	CoinStatsIndex *indexers.CoinStatsIndex
+
+	// IndexManager manages the enabled optional indexes.  It is nil when
+	// none of them are enabled.
+	IndexManager *indexers.Manager
+
 	// The fee estimator keeps track of how long transactions are left in
 	// the mempool before they are mined into blocks.
 	FeeEstimator *mempool.FeeEstimator
+
+	// ZMQNotifier publishes notifications over ZeroMQ.  It is nil when no
+	// ZeroMQ endpoints are configured.
+	ZMQNotifier *zmq.Notifier
 }
 
 // newRPCServer returns a new instance of the rpcServer struct.
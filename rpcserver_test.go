@@ -1,15 +1,29 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"strings"
 	"testing"
 
+	"github.com/btcsuite/btclog"
+	"github.com/bynil/btcd/blockchain"
+	"github.com/bynil/btcd/blockchain/indexers"
+	"github.com/bynil/btcd/btcec/v2"
+	"github.com/bynil/btcd/btcec/v2/schnorr"
 	"github.com/bynil/btcd/btcjson"
 	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/btcutil/hdkeychain"
+	"github.com/bynil/btcd/btcutil/psbt"
+	"github.com/bynil/btcd/chaincfg"
 	"github.com/bynil/btcd/chaincfg/chainhash"
+	"github.com/bynil/btcd/database"
 	"github.com/bynil/btcd/mempool"
+	"github.com/bynil/btcd/txscript"
 	"github.com/bynil/btcd/wire"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 )
 
@@ -106,9 +120,9 @@ func decodeTxHex(t *testing.T, txHex string) *btcutil.Tx {
 	return tx
 }
 
-// TestHandleTestMempoolAcceptMixedResults checks that when different txns get
-// different responses from calling the mempool method `CheckMempoolAcceptance`
-// their results are correctly returned.
+// TestHandleTestMempoolAcceptMixedResults checks that when the txns of a
+// package get different results from calling the mempool method
+// `CheckPackageAcceptance` their results are correctly returned.
 func TestHandleTestMempoolAcceptMixedResults(t *testing.T) {
 	t.Parallel()
 
@@ -126,33 +140,41 @@ func TestHandleTestMempoolAcceptMixedResults(t *testing.T) {
 	tx1 := decodeTxHex(t, txHex1)
 	tx2 := decodeTxHex(t, txHex2)
 	tx3 := decodeTxHex(t, txHex3)
+	txns := []*btcutil.Tx{tx1, tx2, tx3}
 
 	// Create a slice to hold the expected results. We will use three txns
-	// so we expect threeresults.
+	// so we expect three results.
 	expectedResults := make([]*btcjson.TestMempoolAcceptResult, 3)
 
-	// We now mock the first call to `CheckMempoolAcceptance` to return an
-	// error.
-	dummyErr := errors.New("dummy error")
-	mm.On("CheckMempoolAcceptance", tx1).Return(nil, dummyErr).Once()
+	// We mock the call to `CheckPackageAcceptance` to return a result
+	// saying the first tx passed, the second tx is missing inputs and the
+	// third tx was not validated.
+	const feeSats = btcutil.Amount(1000)
+	mm.On("CheckPackageAcceptance", txns).Return(
+		&mempool.PackageAcceptResult{
+			TxResults: []*mempool.PackageTxResult{
+				{
+					Tx:     tx1,
+					TxFee:  feeSats,
+					TxSize: 100,
+				},
+				{
+					Tx:             tx2,
+					Err:            errors.New("dummy error"),
+					MissingParents: []*chainhash.Hash{},
+				},
+				nil,
+			},
+		}, nil,
+	).Once()
 
-	// Since the call failed, we expect the first result to give us the
-	// error.
+	// Since the package would not be accepted, we expect the first result
+	// to not be allowed and to give no reason since the tx itself passed.
 	expectedResults[0] = &btcjson.TestMempoolAcceptResult{
-		Txid:         tx1.Hash().String(),
-		Wtxid:        tx1.WitnessHash().String(),
-		Allowed:      false,
-		RejectReason: dummyErr.Error(),
+		Txid:  tx1.Hash().String(),
+		Wtxid: tx1.WitnessHash().String(),
 	}
 
-	// We mock the second call to `CheckMempoolAcceptance` to return a
-	// result saying the tx is missing inputs.
-	mm.On("CheckMempoolAcceptance", tx2).Return(
-		&mempool.MempoolAcceptResult{
-			MissingParents: []*chainhash.Hash{},
-		}, nil,
-	).Once()
-
 	// We expect the second result to give us the missing-inputs error.
 	expectedResults[1] = &btcjson.TestMempoolAcceptResult{
 		Txid:         tx2.Hash().String(),
@@ -161,26 +183,11 @@ func TestHandleTestMempoolAcceptMixedResults(t *testing.T) {
 		RejectReason: "missing-inputs",
 	}
 
-	// We mock the third call to `CheckMempoolAcceptance` to return a
-	// result saying the tx allowed.
-	const feeSats = btcutil.Amount(1000)
-	mm.On("CheckMempoolAcceptance", tx3).Return(
-		&mempool.MempoolAcceptResult{
-			TxFee:  feeSats,
-			TxSize: 100,
-		}, nil,
-	).Once()
-
-	// We expect the third result to give us the fee details.
+	// We expect the third result to give us nothing since the tx was not
+	// validated.
 	expectedResults[2] = &btcjson.TestMempoolAcceptResult{
-		Txid:    tx3.Hash().String(),
-		Wtxid:   tx3.WitnessHash().String(),
-		Allowed: true,
-		Vsize:   100,
-		Fees: &btcjson.TestMempoolAcceptFees{
-			Base:             feeSats.ToBTC(),
-			EffectiveFeeRate: feeSats.ToBTC() * 1e3 / 100,
-		},
+		Txid:  tx3.Hash().String(),
+		Wtxid: tx3.WitnessHash().String(),
 	}
 
 	// Create a mock request with default max fee rate of 0.1 BTC/KvB.
@@ -199,6 +206,152 @@ func TestHandleTestMempoolAcceptMixedResults(t *testing.T) {
 	mm.AssertExpectations(t)
 }
 
+// TestHandleTestMempoolAcceptPackage checks that the results of a package are
+// correctly returned when the package is allowed, exceeds the max fee rate or
+// violates the package policy.
+func TestHandleTestMempoolAcceptPackage(t *testing.T) {
+	t.Parallel()
+
+	tx1 := decodeTxHex(t, txHex1)
+	tx2 := decodeTxHex(t, txHex2)
+	txns := []*btcutil.Tx{tx1, tx2}
+
+	// newResult returns the result of a tx that passed paying the given
+	// fee for a size of 100 vbytes.
+	newResult := func(tx *btcutil.Tx,
+		fee btcutil.Amount) *mempool.PackageTxResult {
+
+		return &mempool.PackageTxResult{
+			Tx:                tx,
+			TxFee:             fee,
+			TxSize:            100,
+			EffectiveFeeRate:  fee * 10,
+			EffectiveIncludes: []*chainhash.Hash{tx.WitnessHash()},
+		}
+	}
+
+	// allowed returns the expected result of an allowed tx paying the
+	// given fee.
+	allowed := func(tx *btcutil.Tx,
+		fee btcutil.Amount) *btcjson.TestMempoolAcceptResult {
+
+		return &btcjson.TestMempoolAcceptResult{
+			Txid:    tx.Hash().String(),
+			Wtxid:   tx.WitnessHash().String(),
+			Allowed: true,
+			Vsize:   100,
+			Fees: &btcjson.TestMempoolAcceptFees{
+				Base:             fee.ToBTC(),
+				EffectiveFeeRate: (fee * 10).ToBTC(),
+				EffectiveIncludes: []string{
+					tx.WitnessHash().String(),
+				},
+			},
+		}
+	}
+
+	// notValidated returns the expected result of a tx that was not
+	// validated.
+	notValidated := func(tx *btcutil.Tx,
+		pkgErr string) *btcjson.TestMempoolAcceptResult {
+
+		return &btcjson.TestMempoolAcceptResult{
+			Txid:         tx.Hash().String(),
+			Wtxid:        tx.WitnessHash().String(),
+			PackageError: pkgErr,
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		result          *mempool.PackageAcceptResult
+		err             error
+		expectedResults []*btcjson.TestMempoolAcceptResult
+	}{
+		{
+			name: "allowed",
+			result: &mempool.PackageAcceptResult{
+				TxResults: []*mempool.PackageTxResult{
+					newResult(tx1, 1000),
+					newResult(tx2, 2000),
+				},
+			},
+			expectedResults: []*btcjson.TestMempoolAcceptResult{
+				allowed(tx1, 1000),
+				allowed(tx2, 2000),
+			},
+		},
+		{
+			name: "max fee exceeded",
+			result: &mempool.PackageAcceptResult{
+				TxResults: []*mempool.PackageTxResult{
+					newResult(tx1, 1000),
+					newResult(tx2, btcutil.SatoshiPerBitcoin),
+				},
+			},
+			expectedResults: []*btcjson.TestMempoolAcceptResult{
+				allowed(tx1, 1000),
+				{
+					Txid:         tx2.Hash().String(),
+					Wtxid:        tx2.WitnessHash().String(),
+					RejectReason: "max-fee-exceeded",
+				},
+			},
+		},
+		{
+			name: "package error",
+			err: mempool.RuleError{Err: mempool.PackageRuleError{
+				Reason:      "conflict-in-package",
+				Description: "dummy description",
+			}},
+			expectedResults: []*btcjson.TestMempoolAcceptResult{
+				notValidated(tx1, "conflict-in-package"),
+				notValidated(tx2, "conflict-in-package"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require := require.New(t)
+
+			// Create a testing server with a mock mempool that
+			// returns the result of the test case.
+			mm := &mempool.MockTxMempool{}
+			s := &rpcServer{cfg: rpcserverConfig{
+				TxMemPool: mm,
+			}}
+			if tc.result != nil {
+				mm.On("CheckPackageAcceptance", txns).Return(
+					tc.result, nil,
+				).Once()
+			} else {
+				mm.On("CheckPackageAcceptance", txns).Return(
+					nil, tc.err,
+				).Once()
+			}
+
+			// Call the method handler and assert the expected
+			// results are returned.
+			cmd := btcjson.NewTestMempoolAcceptCmd(
+				[]string{txHex1, txHex2}, 0.1,
+			)
+			closeChan := make(chan struct{})
+			results, err := handleTestMempoolAccept(
+				s, cmd, closeChan,
+			)
+			require.NoError(err)
+			require.Equal(tc.expectedResults, results)
+
+			mm.AssertExpectations(t)
+		})
+	}
+}
+
 // TestValidateFeeRate checks that `validateFeeRate` behaves as expected.
 func TestValidateFeeRate(t *testing.T) {
 	t.Parallel()
@@ -495,3 +648,710 @@ func TestGetTxSpendingPrevOut(t *testing.T) {
 	require.NoError(err)
 	require.Equal(expectedResults, results)
 }
+
+// TestGetMempoolRelatives ensures the getmempoolentry, getmempoolancestors and
+// getmempooldescendants commands return the entries provided by the mempool
+// and report transactions which are not in the mempool.
+func TestGetMempoolRelatives(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	// Create a testing server with a mock mempool.
+	mm := &mempool.MockTxMempool{}
+	defer mm.AssertExpectations(t)
+	s := &rpcServer{cfg: rpcserverConfig{
+		TxMemPool: mm,
+	}}
+	closeChan := make(chan struct{})
+
+	inPool := chainhash.Hash{1}
+	notInPool := chainhash.Hash{2}
+	entry := &btcjson.GetMempoolEntryResult{VSize: 100, AncestorCount: 3}
+	entries := map[string]*btcjson.GetMempoolEntryResult{
+		chainhash.Hash{4}.String(): entry,
+		chainhash.Hash{3}.String(): entry,
+	}
+	notFound := errors.New("transaction is not in the pool")
+	mm.On("MempoolEntry", &inPool).Return(entry, nil)
+	mm.On("MempoolEntry", &notInPool).Return(nil, notFound)
+	mm.On("MempoolAncestors", &inPool).Return(entries, nil)
+	mm.On("MempoolAncestors", &notInPool).Return(nil, notFound)
+	mm.On("MempoolDescendants", &inPool).Return(entries, nil)
+
+	result, err := handleGetMempoolEntry(
+		s, btcjson.NewGetMempoolEntryCmd(inPool.String()), closeChan,
+	)
+	require.NoError(err)
+	require.Equal(entry, result)
+
+	// The hashes of the relatives are sorted unless the verbose flag is
+	// set.
+	wantHashes := []string{
+		chainhash.Hash{3}.String(), chainhash.Hash{4}.String(),
+	}
+	result, err = handleGetMempoolAncestors(s,
+		btcjson.NewGetMempoolAncestorsCmd(inPool.String(), nil),
+		closeChan)
+	require.NoError(err)
+	require.Equal(wantHashes, result)
+
+	result, err = handleGetMempoolDescendants(s,
+		btcjson.NewGetMempoolDescendantsCmd(
+			inPool.String(), btcjson.Bool(true),
+		), closeChan)
+	require.NoError(err)
+	require.Equal(entries, result)
+
+	// Transactions which aren't in the mempool and invalid hashes are
+	// reported.
+	_, err = handleGetMempoolEntry(
+		s, btcjson.NewGetMempoolEntryCmd(notInPool.String()), closeChan,
+	)
+	var rpcErr *btcjson.RPCError
+	require.ErrorAs(err, &rpcErr)
+	require.Equal(btcjson.ErrRPCInvalidAddressOrKey, rpcErr.Code)
+
+	_, err = handleGetMempoolAncestors(s,
+		btcjson.NewGetMempoolAncestorsCmd(notInPool.String(), nil),
+		closeChan)
+	require.ErrorAs(err, &rpcErr)
+	require.Equal(btcjson.ErrRPCInvalidAddressOrKey, rpcErr.Code)
+
+	_, err = handleGetMempoolDescendants(s,
+		btcjson.NewGetMempoolDescendantsCmd("invalid", nil), closeChan)
+	require.ErrorAs(err, &rpcErr)
+	require.Equal(btcjson.ErrRPCDecodeHexString, rpcErr.Code)
+}
+
+// TestPrioritiseTransaction ensures the prioritisetransaction command records
+// the fee delta in the mempool and rejects a nonzero dummy argument, and that
+// getprioritisedtransactions returns the fee deltas provided by the mempool.
+func TestPrioritiseTransaction(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+
+	// Create a testing server with a mock mempool.
+	mm := &mempool.MockTxMempool{}
+	defer mm.AssertExpectations(t)
+	s := &rpcServer{cfg: rpcserverConfig{
+		TxMemPool: mm,
+	}}
+	closeChan := make(chan struct{})
+
+	txHash := chainhash.Hash{1}
+	modifiedFee := int64(1500)
+	prioritised := map[string]*btcjson.PrioritisedTransactionResult{
+		txHash.String(): {
+			FeeDelta:    1000,
+			InMempool:   true,
+			ModifiedFee: &modifiedFee,
+		},
+	}
+	mm.On("PrioritiseTransaction", &txHash, int64(1000)).Return().Once()
+	mm.On("PrioritisedTransactions").Return(prioritised)
+
+	result, err := handlePrioritiseTransaction(s,
+		btcjson.NewPrioritiseTransactionCmd(txHash.String(), 1000),
+		closeChan)
+	require.NoError(err)
+	require.Equal(true, result)
+
+	result, err = handleGetPrioritisedTransactions(s,
+		btcjson.NewGetPrioritisedTransactionsCmd(), closeChan)
+	require.NoError(err)
+	require.Equal(prioritised, result)
+
+	// A nonzero dummy argument and invalid hashes are rejected.
+	cmd := btcjson.NewPrioritiseTransactionCmd(txHash.String(), 1000)
+	cmd.Dummy = 1
+	_, err = handlePrioritiseTransaction(s, cmd, closeChan)
+	var rpcErr *btcjson.RPCError
+	require.ErrorAs(err, &rpcErr)
+	require.Equal(btcjson.ErrRPCInvalidParameter, rpcErr.Code)
+
+	_, err = handlePrioritiseTransaction(s,
+		btcjson.NewPrioritiseTransactionCmd("invalid", 1000), closeChan)
+	require.ErrorAs(err, &rpcErr)
+	require.Equal(btcjson.ErrRPCDecodeHexString, rpcErr.Code)
+}
+
+// TestCalcTruncatedMedian ensures the median used by getblockstats truncates
+// the average of the middle values for an even number of values.
+func TestCalcTruncatedMedian(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name   string
+		values []int64
+		want   int64
+	}{
+		{"empty", nil, 0},
+		{"single", []int64{7}, 7},
+		{"odd", []int64{9, 1, 5}, 5},
+		{"even", []int64{4, 1, 2, 8}, 3},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.want, calcTruncatedMedian(tc.values))
+		})
+	}
+}
+
+// TestCalcFeeRatePercentiles ensures the fee rate percentiles used by
+// getblockstats are weighted by transaction weight.
+func TestCalcFeeRatePercentiles(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name        string
+		entries     []feeRateWeight
+		totalWeight int64
+		want        []int64
+	}{
+		{
+			name: "empty",
+			want: []int64{0, 0, 0, 0, 0},
+		},
+		{
+			name:        "single",
+			entries:     []feeRateWeight{{feeRate: 5, weight: 400}},
+			totalWeight: 400,
+			want:        []int64{5, 5, 5, 5, 5},
+		},
+		{
+			// The heavy transaction paying 2 sat/vb covers the
+			// weight up to the median.
+			name: "weighted",
+			entries: []feeRateWeight{
+				{feeRate: 10, weight: 100},
+				{feeRate: 2, weight: 600},
+				{feeRate: 30, weight: 100},
+				{feeRate: 20, weight: 200},
+			},
+			totalWeight: 1000,
+			want:        []int64{2, 2, 2, 20, 20},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			got := calcFeeRatePercentiles(tc.entries, tc.totalWeight)
+			require.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// TestPsbtCommands ensures a PSBT spending a P2WPKH output can be created,
+// analyzed, decoded and finalized with the PSBT commands.
+func TestPsbtCommands(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	params := &chaincfg.RegressionNetParams
+	s := &rpcServer{cfg: rpcserverConfig{ChainParams: params}}
+	closeChan := make(chan struct{})
+
+	privKey, pubKey := btcec.PrivKeyFromBytes(bytes.Repeat([]byte{0x01}, 32))
+	pubKeyBytes := pubKey.SerializeCompressed()
+	pubKeyHash := btcutil.Hash160(pubKeyBytes)
+	addr, err := btcutil.NewAddressWitnessPubKeyHash(pubKeyHash, params)
+	require.NoError(err)
+	pkScript, err := txscript.PayToAddrScript(addr)
+	require.NoError(err)
+	const utxoValue = 100000000
+
+	// Create a replaceable PSBT which spends a P2WPKH output and pays
+	// half of it back to the same address.
+	prevOut := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 1}
+	result, err := handleCreatePsbt(s, btcjson.NewCreatePsbtCmd(
+		[]btcjson.CreatePsbtInput{{
+			Txid: prevOut.Hash.String(), Vout: prevOut.Index,
+		}},
+		[]btcjson.PsbtOutput{{addr.EncodeAddress(): 0.5}},
+		nil, btcjson.Bool(true),
+	), closeChan)
+	require.NoError(err)
+	packet, err := psbt.NewFromRawBytes(
+		strings.NewReader(result.(string)), true,
+	)
+	require.NoError(err)
+	require.Equal(uint32(mempool.MaxRBFSequence),
+		packet.UnsignedTx.TxIn[0].Sequence)
+	require.Equal(prevOut, packet.UnsignedTx.TxIn[0].PreviousOutPoint)
+	require.Equal(int64(utxoValue/2), packet.UnsignedTx.TxOut[0].Value)
+
+	encode := func() string {
+		b64, err := packet.B64Encode()
+		require.NoError(err)
+		return b64
+	}
+	analyze := func() *btcjson.AnalyzePsbtResult {
+		result, err := handleAnalyzePsbt(
+			s, btcjson.NewAnalyzePsbtCmd(encode()), closeChan,
+		)
+		require.NoError(err)
+		return result.(*btcjson.AnalyzePsbtResult)
+	}
+
+	// The UTXO has to be added first.
+	analysis := analyze()
+	require.Equal("updater", analysis.Next)
+	require.False(analysis.Inputs[0].HasUtxo)
+	require.Nil(analysis.Fee)
+
+	// Then the public key.
+	packet.Inputs[0].WitnessUtxo = wire.NewTxOut(utxoValue, pkScript)
+	analysis = analyze()
+	require.Equal("updater", analysis.Next)
+	require.Equal(&btcjson.AnalyzePsbtMissing{
+		PubKeys: []string{hex.EncodeToString(pubKeyHash)},
+	}, analysis.Inputs[0].Missing)
+	require.Equal(0.5, *analysis.Fee)
+	require.Nil(analysis.EstimatedVSize)
+
+	// Then the signature, at which point the size is estimated.
+	packet.Inputs[0].Bip32Derivation = []*psbt.Bip32Derivation{{
+		PubKey:               pubKeyBytes,
+		MasterKeyFingerprint: 0x01020304,
+		Bip32Path:            []uint32{hdkeychain.HardenedKeyStart + 84, 1},
+	}}
+	analysis = analyze()
+	require.Equal("signer", analysis.Next)
+	require.Equal(&btcjson.AnalyzePsbtMissing{
+		Signatures: []string{hex.EncodeToString(pubKeyHash)},
+	}, analysis.Inputs[0].Missing)
+	require.NotNil(analysis.EstimatedVSize)
+	estimatedVSize := *analysis.EstimatedVSize
+
+	prevOutFetcher := txscript.NewCannedPrevOutputFetcher(
+		pkScript, utxoValue,
+	)
+	sigHashes := txscript.NewTxSigHashes(packet.UnsignedTx, prevOutFetcher)
+	sig, err := txscript.RawTxInWitnessSignature(packet.UnsignedTx,
+		sigHashes, 0, utxoValue, pkScript, txscript.SigHashAll, privKey)
+	require.NoError(err)
+	updater := psbt.Updater{Upsbt: packet}
+	outcome, err := updater.Sign(0, sig, pubKeyBytes, nil, nil)
+	require.NoError(err)
+	require.EqualValues(psbt.SignSuccesful, outcome)
+
+	analysis = analyze()
+	require.Equal("finalizer", analysis.Next)
+	require.Nil(analysis.Inputs[0].Missing)
+	require.Equal(estimatedVSize, *analysis.EstimatedVSize)
+
+	result, err = handleDecodePsbt(
+		s, btcjson.NewDecodePsbtCmd(encode()), closeChan,
+	)
+	require.NoError(err)
+	decoded := result.(*btcjson.DecodePsbtResult)
+	require.Equal(packet.UnsignedTx.TxHash().String(), decoded.Tx.Txid)
+	require.Equal(map[string]string{
+		hex.EncodeToString(pubKeyBytes): hex.EncodeToString(sig),
+	}, decoded.Inputs[0].PartialSignatures)
+	require.Equal([]btcjson.PsbtBip32Deriv{{
+		PubKey:            hex.EncodeToString(pubKeyBytes),
+		MasterFingerprint: "04030201",
+		Path:              "m/84'/1",
+	}}, decoded.Inputs[0].Bip32Derivs)
+	require.Equal(1.0, decoded.Inputs[0].WitnessUtxo.Amount)
+	require.Equal(0.5, *decoded.Fee)
+
+	// Without extracting it, the finalized PSBT is returned and only has
+	// to be extracted.
+	result, err = handleFinalizePsbt(s, btcjson.NewFinalizePsbtCmd(
+		encode(), btcjson.Bool(false),
+	), closeChan)
+	require.NoError(err)
+	finalized := result.(*btcjson.FinalizePsbtResult)
+	require.True(finalized.Complete)
+	require.Empty(finalized.Hex)
+	result, err = handleAnalyzePsbt(
+		s, btcjson.NewAnalyzePsbtCmd(finalized.Psbt), closeChan,
+	)
+	require.NoError(err)
+	require.Equal("extractor", result.(*btcjson.AnalyzePsbtResult).Next)
+
+	// The extracted transaction is valid, and its size is at most one
+	// byte less than the estimate due to the dummy signature being of the
+	// maximum size.
+	result, err = handleFinalizePsbt(
+		s, btcjson.NewFinalizePsbtCmd(encode(), nil), closeChan,
+	)
+	require.NoError(err)
+	finalized = result.(*btcjson.FinalizePsbtResult)
+	require.True(finalized.Complete)
+	require.Empty(finalized.Psbt)
+	tx := decodeTxHex(t, finalized.Hex)
+	vm, err := txscript.NewEngine(pkScript, tx.MsgTx(), 0,
+		txscript.StandardVerifyFlags, nil, sigHashes, utxoValue,
+		prevOutFetcher)
+	require.NoError(err)
+	require.NoError(vm.Execute())
+	vsize := mempool.GetTxVirtualSize(tx)
+	require.LessOrEqual(vsize, estimatedVSize)
+	require.GreaterOrEqual(vsize, estimatedVSize-1)
+}
+
+// TestCombineJoinPsbts ensures PSBTs of the same transaction are combined
+// and PSBTs of different transactions are joined.
+func TestCombineJoinPsbts(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	s := &rpcServer{cfg: rpcserverConfig{
+		ChainParams: &chaincfg.RegressionNetParams,
+	}}
+	closeChan := make(chan struct{})
+
+	newPacket := func(version int32, lockTime uint32,
+		prevOuts ...wire.OutPoint) *psbt.Packet {
+
+		tx := wire.NewMsgTx(version)
+		for i := range prevOuts {
+			tx.AddTxIn(wire.NewTxIn(&prevOuts[i], nil, nil))
+			tx.AddTxOut(wire.NewTxOut(int64(1000*(i+1)), []byte{
+				txscript.OP_TRUE,
+			}))
+		}
+		tx.LockTime = lockTime
+		packet, err := psbt.NewFromUnsignedTx(tx)
+		require.NoError(err)
+		return packet
+	}
+	encode := func(packets ...*psbt.Packet) []string {
+		var b64s []string
+		for _, packet := range packets {
+			b64, err := packet.B64Encode()
+			require.NoError(err)
+			b64s = append(b64s, b64)
+		}
+		return b64s
+	}
+	decode := func(result interface{}) *psbt.Packet {
+		packet, err := psbt.NewFromRawBytes(
+			strings.NewReader(result.(string)), true,
+		)
+		require.NoError(err)
+		return packet
+	}
+	prevOut1 := wire.OutPoint{Hash: chainhash.Hash{0x01}}
+	prevOut2 := wire.OutPoint{Hash: chainhash.Hash{0x02}}
+
+	// Combining merges the inputs of the PSBTs.
+	a := newPacket(2, 0, prevOut1)
+	b := newPacket(2, 0, prevOut1)
+	a.Inputs[0].WitnessUtxo = wire.NewTxOut(5000, []byte{txscript.OP_TRUE})
+	b.Inputs[0].SighashType = txscript.SigHashAll
+	result, err := handleCombinePsbt(
+		s, btcjson.NewCombinePsbtCmd(encode(a, b)), closeChan,
+	)
+	require.NoError(err)
+	combined := decode(result)
+	require.Equal(a.Inputs[0].WitnessUtxo, combined.Inputs[0].WitnessUtxo)
+	require.Equal(txscript.SigHashAll, combined.Inputs[0].SighashType)
+
+	// PSBTs of different transactions can't be combined.
+	c := newPacket(2, 0, prevOut2)
+	_, err = handleCombinePsbt(
+		s, btcjson.NewCombinePsbtCmd(encode(a, c)), closeChan,
+	)
+	require.Error(err)
+	require.Equal(btcjson.ErrRPCInvalidParameter,
+		err.(*btcjson.RPCError).Code)
+
+	// Joining results in a transaction with all inputs and outputs, the
+	// highest version and the lowest lock time.
+	d := newPacket(1, 100, prevOut2)
+	result, err = handleJoinPsbts(
+		s, btcjson.NewJoinPsbtsCmd(encode(a, d)), closeChan,
+	)
+	require.NoError(err)
+	joined := decode(result)
+	require.Equal(int32(2), joined.UnsignedTx.Version)
+	require.Equal(uint32(0), joined.UnsignedTx.LockTime)
+	require.Len(joined.UnsignedTx.TxIn, 2)
+	require.Len(joined.UnsignedTx.TxOut, 2)
+	for i, txIn := range joined.UnsignedTx.TxIn {
+		if txIn.PreviousOutPoint == prevOut1 {
+			require.Equal(a.Inputs[0].WitnessUtxo,
+				joined.Inputs[i].WitnessUtxo)
+		} else {
+			require.Nil(joined.Inputs[i].WitnessUtxo)
+		}
+	}
+
+	// An input can't be in more than one of the joined PSBTs, and at
+	// least two PSBTs have to be joined.
+	_, err = handleJoinPsbts(
+		s, btcjson.NewJoinPsbtsCmd(encode(a, b)), closeChan,
+	)
+	require.Error(err)
+	_, err = handleJoinPsbts(
+		s, btcjson.NewJoinPsbtsCmd(encode(a)), closeChan,
+	)
+	require.Error(err)
+}
+
+// newTestChain returns a chain with only the genesis block of the passed
+// network, backed by a database in a temporary directory.  The logging of
+// the chain is disabled since the log rotator isn't set up in tests.
+func newTestChain(t *testing.T, params *chaincfg.Params) *blockchain.BlockChain {
+	t.Helper()
+
+	blockchain.UseLogger(btclog.Disabled)
+	database.UseLogger(btclog.Disabled)
+
+	db, err := database.Create("ffldb", t.TempDir(), params.Net)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	chain, err := blockchain.New(&blockchain.Config{
+		DB:               db,
+		UtxoCacheMaxSize: 10 * 1024 * 1024,
+		ChainParams:      params,
+		TimeSource:       blockchain.NewMedianTime(),
+	})
+	require.NoError(t, err)
+	return chain
+}
+
+// TestSignCombineRawTransaction ensures signrawtransactionwithkey signs the
+// inputs it has the keys and scripts for, and combinerawtransaction merges
+// transactions signed with different keys.
+func TestSignCombineRawTransaction(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	params := &chaincfg.RegressionNetParams
+	mm := &mempool.MockTxMempool{}
+	s := &rpcServer{cfg: rpcserverConfig{
+		ChainParams: params,
+		Chain:       newTestChain(t, params),
+		TxMemPool:   mm,
+	}}
+	closeChan := make(chan struct{})
+
+	newWIF := func(b byte) (*btcutil.WIF, *btcutil.AddressPubKey) {
+		privKey, pubKey := btcec.PrivKeyFromBytes(bytes.Repeat([]byte{b}, 32))
+		wif, err := btcutil.NewWIF(privKey, params, true)
+		require.NoError(err)
+		addr, err := btcutil.NewAddressPubKey(
+			pubKey.SerializeCompressed(), params,
+		)
+		require.NoError(err)
+		return wif, addr
+	}
+	wif1, addr1 := newWIF(0x01)
+	wif2, addr2 := newWIF(0x02)
+	payTo := func(addr btcutil.Address) []byte {
+		pkScript, err := txscript.PayToAddrScript(addr)
+		require.NoError(err)
+		return pkScript
+	}
+
+	// The funding transaction in the memory pool pays to a P2PKH, P2WPKH,
+	// P2SH-P2WPKH, BIP0086 P2TR and 2-of-2 multisig P2WSH output.
+	p2wpkhAddr, err := btcutil.NewAddressWitnessPubKeyHash(
+		addr1.AddressPubKeyHash().ScriptAddress(), params,
+	)
+	require.NoError(err)
+	p2wpkhScript := payTo(p2wpkhAddr)
+	np2wpkhAddr, err := btcutil.NewAddressScriptHash(p2wpkhScript, params)
+	require.NoError(err)
+	p2trAddr, err := btcutil.NewAddressTaproot(schnorr.SerializePubKey(
+		txscript.ComputeTaprootKeyNoScript(addr1.PubKey()),
+	), params)
+	require.NoError(err)
+	multiSigScript, err := txscript.MultiSigScript(
+		[]*btcutil.AddressPubKey{addr1, addr2}, 2,
+	)
+	require.NoError(err)
+	scriptHash := sha256.Sum256(multiSigScript)
+	p2wshAddr, err := btcutil.NewAddressWitnessScriptHash(
+		scriptHash[:], params,
+	)
+	require.NoError(err)
+
+	fundTx := wire.NewMsgTx(2)
+	fundTx.AddTxIn(&wire.TxIn{})
+	for _, addr := range []btcutil.Address{
+		addr1.AddressPubKeyHash(), p2wpkhAddr, np2wpkhAddr, p2trAddr,
+		p2wshAddr,
+	} {
+		fundTx.AddTxOut(wire.NewTxOut(100000, payTo(addr)))
+	}
+	fundHash := fundTx.TxHash()
+	mm.On("FetchTransaction", &fundHash).Return(btcutil.NewTx(fundTx), nil)
+	mm.On("FetchTransaction", mock.Anything).Return(nil, errors.New("not found"))
+	mm.On("CheckSpend", mock.Anything).Return(nil)
+
+	spendTx := wire.NewMsgTx(2)
+	for i := range fundTx.TxOut {
+		spendTx.AddTxIn(wire.NewTxIn(
+			wire.NewOutPoint(&fundHash, uint32(i)), nil, nil,
+		))
+	}
+	spendTx.AddTxOut(wire.NewTxOut(400000, p2wpkhScript))
+	spendHex, err := messageToHex(spendTx)
+	require.NoError(err)
+
+	prevTxs := &[]btcjson.RawTxWitnessInput{{
+		Txid:         fundHash.String(),
+		Vout:         2,
+		ScriptPubKey: hex.EncodeToString(fundTx.TxOut[2].PkScript),
+		RedeemScript: btcjson.String(hex.EncodeToString(p2wpkhScript)),
+	}, {
+		Txid:          fundHash.String(),
+		Vout:          4,
+		ScriptPubKey:  hex.EncodeToString(fundTx.TxOut[4].PkScript),
+		WitnessScript: btcjson.String(hex.EncodeToString(multiSigScript)),
+	}}
+	sign := func(txHex string, wifs []*btcutil.WIF,
+		sigHashType *string) *btcjson.SignRawTransactionWithKeyResult {
+
+		var privKeys []string
+		for _, wif := range wifs {
+			privKeys = append(privKeys, wif.String())
+		}
+		result, err := handleSignRawTransactionWithKey(s,
+			btcjson.NewSignRawTransactionWithKeyCmd(
+				txHex, privKeys, prevTxs, sigHashType,
+			), closeChan)
+		require.NoError(err)
+		return result.(*btcjson.SignRawTransactionWithKeyResult)
+	}
+
+	// The first key signs all inputs but the multisig one, which needs
+	// the signature of the second key too.
+	signed1 := sign(spendHex, []*btcutil.WIF{wif1}, nil)
+	require.False(signed1.Complete)
+	require.Len(signed1.Errors, 1)
+	require.Equal(uint32(4), signed1.Errors[0].Vout)
+	require.Len(signed1.Errors[0].Witness, 4)
+
+	signed2 := sign(spendHex, []*btcutil.WIF{wif2},
+		btcjson.String("ALL|ANYONECANPAY"))
+	require.False(signed2.Complete)
+	require.Len(signed2.Errors, len(fundTx.TxOut))
+
+	// Both are merged by combining the transactions, and by signing the
+	// partially signed transaction with the other key.
+	result, err := handleCombineRawTransaction(s,
+		btcjson.NewCombineRawTransactionCmd(
+			[]string{signed1.Hex, signed2.Hex},
+		), closeChan)
+	require.NoError(err)
+	combined := sign(result.(string), nil, nil)
+	require.True(combined.Complete, "%v", combined.Errors)
+	require.Equal(result.(string), combined.Hex)
+
+	resigned := sign(signed1.Hex, []*btcutil.WIF{wif2}, nil)
+	require.True(resigned.Complete, "%v", resigned.Errors)
+
+	// Inputs which aren't found are reported, and invalid sighash types
+	// are rejected.
+	missingTx := spendTx.Copy()
+	missingTx.TxIn[0].PreviousOutPoint.Hash = chainhash.Hash{0x01}
+	missingHex, err := messageToHex(missingTx)
+	require.NoError(err)
+	missing := sign(missingHex, []*btcutil.WIF{wif1, wif2}, nil)
+	require.False(missing.Complete)
+	require.Equal("Input not found or already spent",
+		missing.Errors[0].Error)
+
+	_, err = handleSignRawTransactionWithKey(s,
+		btcjson.NewSignRawTransactionWithKeyCmd(
+			spendHex, nil, nil, btcjson.String("ANYONECANPAY"),
+		), closeChan)
+	require.Error(err)
+
+	_, err = handleCombineRawTransaction(s,
+		btcjson.NewCombineRawTransactionCmd(
+			[]string{signed1.Hex, missingHex},
+		), closeChan)
+	require.Error(err)
+}
+
+// TestGetIndexInfo ensures getindexinfo reports the enabled indexes under the
+// names Bitcoin Core uses for them and filters them by those names.
+func TestGetIndexInfo(t *testing.T) {
+	t.Parallel()
+
+	require := require.New(t)
+	params := &chaincfg.RegressionNetParams
+
+	blockchain.UseLogger(btclog.Disabled)
+	database.UseLogger(btclog.Disabled)
+	indexers.UseLogger(btclog.Disabled)
+
+	db, err := database.Create("ffldb", t.TempDir(), params.Net)
+	require.NoError(err)
+	t.Cleanup(func() { db.Close() })
+
+	indexManager := indexers.NewManager(db, []indexers.Indexer{
+		indexers.NewTxIndex(db),
+		indexers.NewCfIndex(db, params),
+		indexers.NewCoinStatsIndex(db),
+		indexers.NewAddrIndex(db, params),
+	})
+	chain, err := blockchain.New(&blockchain.Config{
+		DB:               db,
+		UtxoCacheMaxSize: 10 * 1024 * 1024,
+		ChainParams:      params,
+		TimeSource:       blockchain.NewMedianTime(),
+		IndexManager:     indexManager,
+	})
+	require.NoError(err)
+
+	s := &rpcServer{cfg: rpcserverConfig{
+		ChainParams:  params,
+		Chain:        chain,
+		IndexManager: indexManager,
+	}}
+
+	synced := btcjson.GetIndexInfoResult{Synced: true, BestBlockHeight: 0}
+	tests := []struct {
+		name      string
+		indexName *string
+		want      map[string]btcjson.GetIndexInfoResult
+	}{{
+		name: "all indexes",
+		want: map[string]btcjson.GetIndexInfoResult{
+			"txindex":                  synced,
+			"basic block filter index": synced,
+			"coinstatsindex":           synced,
+			"addrindex":                synced,
+		},
+	}, {
+		name:      "txindex",
+		indexName: btcjson.String("txindex"),
+		want:      map[string]btcjson.GetIndexInfoResult{"txindex": synced},
+	}, {
+		name:      "basic block filter index",
+		indexName: btcjson.String("basic block filter index"),
+		want: map[string]btcjson.GetIndexInfoResult{
+			"basic block filter index": synced,
+		},
+	}, {
+		name:      "coinstatsindex",
+		indexName: btcjson.String("coinstatsindex"),
+		want:      map[string]btcjson.GetIndexInfoResult{"coinstatsindex": synced},
+	}, {
+		name:      "display name",
+		indexName: btcjson.String("transaction index"),
+		want:      map[string]btcjson.GetIndexInfoResult{},
+	}}
+
+	for _, test := range tests {
+		cmd := btcjson.NewGetIndexInfoCmd(test.indexName)
+		result, err := handleGetIndexInfo(s, cmd, nil)
+		require.NoError(err, test.name)
+		require.Equal(test.want, result, test.name)
+	}
+}
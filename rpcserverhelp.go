@@ -40,6 +40,55 @@ var helpDescsEnUS = map[string]string{
 	"node-target":        "Either the IP address and port of the peer to operate on, or a valid peer ID.",
 	"node-connectsubcmd": "'perm' to make the connected peer a permanent one, 'temp' to try a single connect to a peer",
 
+	// AnalyzePsbtCmd help.
+	"analyzepsbt--synopsis": "Analyzes a PSBT and reports the next role to process it, what each input is missing, and the estimated size and fee of the final transaction.",
+	"analyzepsbt-psbt":      "A base64 encoded PSBT",
+
+	// AnalyzePsbtResult help.
+	"analyzepsbtresult-inputs":            "The analysis of each input",
+	"analyzepsbtresult-estimated_vsize":   "The estimated virtual size of the final transaction (only if the UTXOs of all inputs are known and all inputs can be signed for)",
+	"analyzepsbtresult-estimated_feerate": "The estimated fee rate of the final transaction in BTC/kvB (only if the size is estimated)",
+	"analyzepsbtresult-fee":               "The fee paid by the transaction in BTC (only if the UTXOs of all inputs are known)",
+	"analyzepsbtresult-next":              "The next role to process the PSBT: creator, updater, signer, finalizer or extractor",
+	"analyzepsbtresult-error":             "The reason the PSBT is invalid, if it is",
+
+	// AnalyzePsbtInput help.
+	"analyzepsbtinput-has_utxo": "Whether the UTXO spent by the input is known",
+	"analyzepsbtinput-is_final": "Whether the input is finalized",
+	"analyzepsbtinput-missing":  "What the input is missing before it can be finalized",
+	"analyzepsbtinput-next":     "The next role to process the input",
+
+	// AnalyzePsbtMissing help.
+	"analyzepsbtmissing-pubkeys":       "The hash160 of the public keys whose derivation paths are missing",
+	"analyzepsbtmissing-signatures":    "The hash160 of the public keys whose signatures are missing",
+	"analyzepsbtmissing-redeemscript":  "The hash160 of the missing redeem script",
+	"analyzepsbtmissing-witnessscript": "The sha256 of the missing witness script",
+
+	// CombinePsbtCmd help.
+	"combinepsbt--synopsis": "Combines multiple PSBTs of the same transaction into one PSBT with the data of all of them.",
+	"combinepsbt-psbts":     "The base64 encoded PSBTs to combine",
+	"combinepsbt--result0":  "The base64 encoded combined PSBT",
+
+	// CombineRawTransactionCmd help.
+	"combinerawtransaction--synopsis": "Combines multiple partially signed copies of the same transaction into one transaction with the signatures of all of them.\n" +
+		"The outputs spent by the inputs must be in the UTXO set or the memory pool.",
+	"combinerawtransaction-txs":      "The hex-encoded partially signed transactions to combine",
+	"combinerawtransaction--result0": "The hex-encoded combined transaction",
+
+	// CreatePsbtInput help.
+	"createpsbtinput-txid":     "The hash of the input transaction",
+	"createpsbtinput-vout":     "The specific output of the input transaction to redeem",
+	"createpsbtinput-sequence": "The sequence number of the input (default depends on the replaceable and locktime arguments)",
+
+	// CreatePsbtCmd help.
+	"createpsbt--synopsis": "Returns a new version 0 PSBT of a transaction spending the provided inputs and creating the provided outputs.\n" +
+		"The inputs of the transaction are not signed, and the UTXOs they spend are not added to the PSBT.",
+	"createpsbt-inputs":      "The inputs of the transaction",
+	"createpsbt-outputs":     "JSON objects with either an address as the key and an amount in BTC as the value, or the key 'data' with hex-encoded data to embed in a null data output as the value",
+	"createpsbt-locktime":    "Locktime value; a non-zero value will also locktime-activate the inputs",
+	"createpsbt-replaceable": "Whether the inputs signal BIP0125 replaceability",
+	"createpsbt--result0":    "The base64 encoded PSBT",
+
 	// TransactionInput help.
 	"transactioninput-txid": "The hash of the input transaction",
 	"transactioninput-vout": "The specific output of the input transaction to redeem",
@@ -47,7 +96,7 @@ var helpDescsEnUS = map[string]string{
 	// CreateRawTransactionCmd help.
 	"createrawtransaction--synopsis": "Returns a new transaction spending the provided inputs and sending to the provided addresses.\n" +
 		"The transaction inputs are not signed in the created transaction.\n" +
-		"The signrawtransaction RPC command provided by wallet must be used to sign the resulting transaction.",
+		"The signrawtransactionwithkey RPC command or the signrawtransaction RPC command provided by wallet must be used to sign the resulting transaction.",
 	"createrawtransaction-inputs":         "The inputs to the transaction",
 	"createrawtransaction-amounts":        "JSON object with the destination addresses as keys and amounts as values",
 	"createrawtransaction-amounts--key":   "address",
@@ -101,6 +150,102 @@ var helpDescsEnUS = map[string]string{
 	"txrawdecoderesult-vin":      "The transaction inputs as JSON objects",
 	"txrawdecoderesult-vout":     "The transaction outputs as JSON objects",
 
+	// DecodePsbtCmd help.
+	"decodepsbt--synopsis": "Returns a JSON object representing the provided base64 encoded PSBT.",
+	"decodepsbt-psbt":      "A base64 encoded PSBT",
+
+	// DecodePsbtResult help.
+	"decodepsbtresult-tx":                "The unsigned transaction of the PSBT",
+	"decodepsbtresult-global_xpubs":      "The extended public keys of the PSBT",
+	"decodepsbtresult-psbt_version":      "The version of the PSBT",
+	"decodepsbtresult-fallback_locktime": "The lock time of the transaction when none of its inputs require one (version 2 only)",
+	"decodepsbtresult-tx_modifiable":     "The modifiable flags of the PSBT (version 2 only)",
+	"decodepsbtresult-unknown":           "The unknown global fields of the PSBT as hex-encoded keys and values",
+	"decodepsbtresult-unknown--key":      "key",
+	"decodepsbtresult-unknown--value":    "value",
+	"decodepsbtresult-unknown--desc":     "The hex-encoded key and value of a field",
+	"decodepsbtresult-inputs":            "The inputs of the PSBT",
+	"decodepsbtresult-outputs":           "The outputs of the PSBT",
+	"decodepsbtresult-fee":               "The fee paid by the transaction in BTC (only if the UTXOs of all inputs are known)",
+
+	// PsbtGlobalXPub help.
+	"psbtglobalxpub-xpub":               "The extended public key",
+	"psbtglobalxpub-master_fingerprint": "The fingerprint of the master key",
+	"psbtglobalxpub-path":               "The derivation path of the extended public key",
+
+	// DecodePsbtInput help.
+	"decodepsbtinput-non_witness_utxo":          "The transaction spent by the input",
+	"decodepsbtinput-witness_utxo":              "The output spent by the input",
+	"decodepsbtinput-partial_signatures":        "The signatures of the input",
+	"decodepsbtinput-partial_signatures--key":   "pubkey",
+	"decodepsbtinput-partial_signatures--value": "signature",
+	"decodepsbtinput-partial_signatures--desc":  "The hex-encoded public key and its signature",
+	"decodepsbtinput-sighash":                   "The signature hash type to sign the input with",
+	"decodepsbtinput-redeem_script":             "The redeem script of the input",
+	"decodepsbtinput-witness_script":            "The witness script of the input",
+	"decodepsbtinput-bip32_derivs":              "The derivation paths of the public keys of the input",
+	"decodepsbtinput-final_scriptSig":           "The final signature script of the input",
+	"decodepsbtinput-final_scriptwitness":       "The final witness stack of the input",
+	"decodepsbtinput-taproot_key_path_sig":      "The hex-encoded taproot key path signature of the input",
+	"decodepsbtinput-taproot_script_path_sigs":  "The taproot script path signatures of the input",
+	"decodepsbtinput-taproot_scripts":           "The taproot leaf scripts of the input",
+	"decodepsbtinput-taproot_bip32_derivs":      "The derivation paths of the taproot public keys of the input",
+	"decodepsbtinput-taproot_internal_key":      "The hex-encoded taproot internal key of the input",
+	"decodepsbtinput-taproot_merkle_root":       "The hex-encoded taproot merkle root of the input",
+	"decodepsbtinput-required_time_locktime":    "The minimum time based lock time the input requires (version 2 only)",
+	"decodepsbtinput-required_height_locktime":  "The minimum height based lock time the input requires (version 2 only)",
+	"decodepsbtinput-unknown":                   "The unknown fields of the input as hex-encoded keys and values",
+	"decodepsbtinput-unknown--key":              "key",
+	"decodepsbtinput-unknown--value":            "value",
+	"decodepsbtinput-unknown--desc":             "The hex-encoded key and value of a field",
+
+	// DecodePsbtOutput help.
+	"decodepsbtoutput-redeem_script":        "The redeem script of the output",
+	"decodepsbtoutput-witness_script":       "The witness script of the output",
+	"decodepsbtoutput-bip32_derivs":         "The derivation paths of the public keys of the output",
+	"decodepsbtoutput-taproot_internal_key": "The hex-encoded taproot internal key of the output",
+	"decodepsbtoutput-taproot_tree":         "The leaves of the taproot tree of the output in depth-first order",
+	"decodepsbtoutput-taproot_bip32_derivs": "The derivation paths of the taproot public keys of the output",
+	"decodepsbtoutput-unknown":              "The unknown fields of the output as hex-encoded keys and values",
+	"decodepsbtoutput-unknown--key":         "key",
+	"decodepsbtoutput-unknown--value":       "value",
+	"decodepsbtoutput-unknown--desc":        "The hex-encoded key and value of a field",
+
+	// PsbtWitnessUtxo help.
+	"psbtwitnessutxo-amount":       "The amount of the output in BTC",
+	"psbtwitnessutxo-scriptPubKey": "The public key script of the output as a JSON object",
+
+	// PsbtScript help.
+	"psbtscript-asm":  "Disassembly of the script",
+	"psbtscript-hex":  "Hex-encoded bytes of the script",
+	"psbtscript-type": "The type of the script (e.g. 'multisig')",
+
+	// PsbtBip32Deriv help.
+	"psbtbip32deriv-pubkey":             "The hex-encoded public key",
+	"psbtbip32deriv-master_fingerprint": "The fingerprint of the master key",
+	"psbtbip32deriv-path":               "The derivation path of the public key",
+
+	// PsbtTaprootScriptSig help.
+	"psbttaprootscriptsig-pubkey":    "The hex-encoded x-only public key",
+	"psbttaprootscriptsig-leaf_hash": "The hash of the leaf the signature is for",
+	"psbttaprootscriptsig-sig":       "The hex-encoded signature",
+
+	// PsbtTaprootScript help.
+	"psbttaprootscript-script":         "The hex-encoded leaf script",
+	"psbttaprootscript-leaf_ver":       "The leaf version of the script",
+	"psbttaprootscript-control_blocks": "The hex-encoded control blocks of the script",
+
+	// PsbtTaprootBip32Deriv help.
+	"psbttaprootbip32deriv-pubkey":             "The hex-encoded x-only public key",
+	"psbttaprootbip32deriv-master_fingerprint": "The fingerprint of the master key",
+	"psbttaprootbip32deriv-path":               "The derivation path of the public key",
+	"psbttaprootbip32deriv-leaf_hashes":        "The hashes of the leaves the public key is used in",
+
+	// PsbtTaprootLeaf help.
+	"psbttaprootleaf-depth":    "The depth of the leaf in the tree",
+	"psbttaprootleaf-leaf_ver": "The leaf version of the script",
+	"psbttaprootleaf-script":   "The hex-encoded leaf script",
+
 	// DecodeRawTransactionCmd help.
 	"decoderawtransaction--synopsis": "Returns a JSON object representing the provided serialized, hex-encoded transaction.",
 	"decoderawtransaction-hextx":     "Serialized, hex-encoded transaction",
@@ -117,6 +262,15 @@ var helpDescsEnUS = map[string]string{
 	"decodescript--synopsis": "Returns a JSON object with information about the provided hex-encoded script.",
 	"decodescript-hexscript": "Hex-encoded script",
 
+	// DescriptorRange help.
+	"descriptorrange-value": "The last index as a number, or the first and last index as an array of two numbers",
+
+	// DeriveAddressesCmd help.
+	"deriveaddresses--synopsis":  "Derives the addresses of an output descriptor.",
+	"deriveaddresses-descriptor": "The descriptor, which must include its checksum",
+	"deriveaddresses-range":      "The last index, or the first and last index, of the range to derive the addresses of a ranged descriptor at",
+	"deriveaddresses--result0":   "The derived addresses",
+
 	// EstimateFeeCmd help.
 	"estimatefee--synopsis": "Estimate the fee per kilobyte in satoshis " +
 		"required for a transaction to be mined before a certain number of " +
@@ -126,6 +280,16 @@ var helpDescsEnUS = map[string]string{
 	"estimatefee--result0": "Estimated fee per kilobyte in satoshis for a block to " +
 		"be mined in the next NumBlocks blocks.",
 
+	// FinalizePsbtCmd help.
+	"finalizepsbt--synopsis": "Finalizes the inputs of a PSBT which can be finalized, and extracts the final transaction when all inputs are finalized.",
+	"finalizepsbt-psbt":      "A base64 encoded PSBT",
+	"finalizepsbt-extract":   "Whether to return the final transaction rather than the PSBT when it is complete",
+
+	// FinalizePsbtResult help.
+	"finalizepsbtresult-psbt":     "The base64 encoded PSBT (only if it is not extracted)",
+	"finalizepsbtresult-hex":      "The hex-encoded final transaction (only if it is complete and extracted)",
+	"finalizepsbtresult-complete": "Whether all inputs are finalized",
+
 	// GenerateCmd help
 	"generate--synopsis": "Generates a set number of blocks (simnet or regtest only) and returns a JSON\n" +
 		" array of their hashes.",
@@ -262,6 +426,15 @@ var helpDescsEnUS = map[string]string{
 	"getblockcount--synopsis": "Returns the number of blocks in the longest block chain.",
 	"getblockcount--result0":  "The current block count",
 
+	// GetBlockFilterCmd help.
+	"getblockfilter--synopsis":  "Returns the BIP0158 filter of the block with the given hash along with its filter header.",
+	"getblockfilter-blockhash":  "The hash of the block",
+	"getblockfilter-filtertype": "The type of the filter, only basic is supported",
+
+	// GetBlockFilterResult help.
+	"getblockfilterresult-filter": "The hex-encoded filter",
+	"getblockfilterresult-header": "The filter header",
+
 	// GetBlockHashCmd help.
 	"getblockhash--synopsis": "Returns hash of the block in best block chain at the given height.",
 	"getblockhash-index":     "The block height",
@@ -289,6 +462,47 @@ var helpDescsEnUS = map[string]string{
 	"getblockheaderverboseresult-previousblockhash": "The hash of the previous block",
 	"getblockheaderverboseresult-nextblockhash":     "The hash of the next block (only if there is one)",
 
+	// GetBlockStatsCmd help.
+	"getblockstats--synopsis":    "Returns per block statistics for the block at the given height or with the given hash.  Amounts are in satoshis and fee rates in satoshis per virtual byte.",
+	"getblockstats-hashorheight": "The height or hash of the block",
+	"getblockstats-stats":        "The statistics to return (default: all)",
+
+	// HashOrHeight help.
+	"hashorheight-value": "The height or hash of the block",
+
+	// GetBlockStatsResult help.
+	"getblockstatsresult-avgfee":               "Average fee in the block",
+	"getblockstatsresult-avgfeerate":           "Average fee rate",
+	"getblockstatsresult-avgtxsize":            "Average transaction size",
+	"getblockstatsresult-blockhash":            "The block hash",
+	"getblockstatsresult-feerate_percentiles":  "Fee rates at the 10th, 25th, 50th, 75th, and 90th percentile weight unit",
+	"getblockstatsresult-height":               "The height of the block",
+	"getblockstatsresult-ins":                  "The number of inputs (excluding coinbase)",
+	"getblockstatsresult-maxfee":               "Maximum fee in the block",
+	"getblockstatsresult-maxfeerate":           "Maximum fee rate in the block",
+	"getblockstatsresult-maxtxsize":            "Maximum transaction size",
+	"getblockstatsresult-medianfee":            "Truncated median fee in the block",
+	"getblockstatsresult-mediantime":           "The block median time past",
+	"getblockstatsresult-mediantxsize":         "Truncated median transaction size",
+	"getblockstatsresult-minfee":               "Minimum fee in the block",
+	"getblockstatsresult-minfeerate":           "Minimum fee rate in the block",
+	"getblockstatsresult-mintxsize":            "Minimum transaction size",
+	"getblockstatsresult-outs":                 "The number of outputs",
+	"getblockstatsresult-swtotal_size":         "Total size of all segwit transactions",
+	"getblockstatsresult-swtotal_weight":       "Total weight of all segwit transactions",
+	"getblockstatsresult-swtxs":                "The number of segwit transactions",
+	"getblockstatsresult-subsidy":              "The block subsidy",
+	"getblockstatsresult-time":                 "The block time",
+	"getblockstatsresult-total_out":            "Total amount in all outputs (excluding coinbase)",
+	"getblockstatsresult-total_size":           "Total size of all non-coinbase transactions",
+	"getblockstatsresult-total_weight":         "Total weight of all non-coinbase transactions",
+	"getblockstatsresult-totalfee":             "The fee total",
+	"getblockstatsresult-txs":                  "The number of transactions (including coinbase)",
+	"getblockstatsresult-utxo_increase":        "The increase/decrease in the number of unspent outputs",
+	"getblockstatsresult-utxo_size_inc":        "The increase/decrease in size for the utxo index",
+	"getblockstatsresult-utxo_increase_actual": "The increase/decrease in the number of unspent outputs, not counting unspendable outputs",
+	"getblockstatsresult-utxo_size_inc_actual": "The increase/decrease in size for the utxo index, not counting unspendable outputs",
+
 	// TemplateRequest help.
 	"templaterequest-mode":         "This is 'template', 'proposal', or omitted",
 	"templaterequest-capabilities": "List of capabilities",
@@ -378,6 +592,17 @@ var helpDescsEnUS = map[string]string{
 	"getcurrentnet--synopsis": "Get bitcoin network the server is running on.",
 	"getcurrentnet--result0":  "The network identifier",
 
+	// GetDescriptorInfoCmd help.
+	"getdescriptorinfo--synopsis":  "Returns information about an output descriptor.",
+	"getdescriptorinfo-descriptor": "The descriptor",
+
+	// GetDescriptorInfoResult help.
+	"getdescriptorinforesult-descriptor":     "The descriptor in canonical form, without private keys",
+	"getdescriptorinforesult-checksum":       "The checksum of the input descriptor",
+	"getdescriptorinforesult-isrange":        "Whether the descriptor is ranged",
+	"getdescriptorinforesult-issolvable":     "Whether the descriptor is solvable",
+	"getdescriptorinforesult-hasprivatekeys": "Whether the descriptor has at least one private key",
+
 	// GetDifficultyCmd help.
 	"getdifficulty--synopsis": "Returns the proof-of-work difficulty as a multiple of the minimum difficulty.",
 	"getdifficulty--result0":  "The difficulty",
@@ -426,15 +651,77 @@ var helpDescsEnUS = map[string]string{
 	"getheaders-hashstop":      "Block hash to stop including block headers for; if not found, all headers to the latest known block are returned.",
 	"getheaders--result0":      "Serialized block headers of all located blocks, limited to some arbitrary maximum number of hashes (currently 2000, which matches the wire protocol headers message, but this is not guaranteed)",
 
+	// GetIndexInfoCmd help.
+	"getindexinfo--synopsis":       "Returns the status of the enabled optional indexes.",
+	"getindexinfo-indexname":       "Only return the status of the index with this name (txindex, basic block filter index, coinstatsindex or addrindex)",
+	"getindexinfo--result0--desc":  "Index status objects keyed by the name of the index",
+	"getindexinfo--result0--key":   "Name of the index",
+	"getindexinfo--result0--value": "Object containing the status of the index",
+
+	// GetIndexInfoResult help.
+	"getindexinforesult-synced":            "Whether the index is synced to the tip of the main chain",
+	"getindexinforesult-best_block_height": "The height of the last block included in the index",
+
 	// GetInfoCmd help.
 	"getinfo--synopsis": "Returns a JSON object containing various state info.",
 
+	// GetMempoolAncestorsCmd help.
+	"getmempoolancestors--synopsis":   "Returns the unconfirmed ancestors of a transaction in the memory pool.",
+	"getmempoolancestors-txid":        "The hash of the transaction",
+	"getmempoolancestors-verbose":     "Returns JSON object when true or an array of transaction hashes when false",
+	"getmempoolancestors--condition0": "verbose=false",
+	"getmempoolancestors--condition1": "verbose=true",
+	"getmempoolancestors--result0":    "Array of transaction hashes",
+
+	// GetMempoolDescendantsCmd help.
+	"getmempooldescendants--synopsis":   "Returns the unconfirmed descendants of a transaction in the memory pool.",
+	"getmempooldescendants-txid":        "The hash of the transaction",
+	"getmempooldescendants-verbose":     "Returns JSON object when true or an array of transaction hashes when false",
+	"getmempooldescendants--condition0": "verbose=false",
+	"getmempooldescendants--condition1": "verbose=true",
+	"getmempooldescendants--result0":    "Array of transaction hashes",
+
+	// GetMempoolEntryCmd help.
+	"getmempoolentry--synopsis": "Returns information about a transaction in the memory pool.",
+	"getmempoolentry-txid":      "The hash of the transaction",
+
+	// GetMempoolEntryResult help.
+	"getmempoolentryresult-vsize":              "The virtual size of the transaction",
+	"getmempoolentryresult-size":               "Transaction size in bytes",
+	"getmempoolentryresult-weight":             "The transaction's weight (between vsize*4-3 and vsize*4)",
+	"getmempoolentryresult-fee":                "Transaction fee in bitcoins",
+	"getmempoolentryresult-modifiedfee":        "Transaction fee in bitcoins used for mining priority",
+	"getmempoolentryresult-time":               "Local time transaction entered pool in seconds since 1 Jan 1970 GMT",
+	"getmempoolentryresult-height":             "Block height when transaction entered the pool",
+	"getmempoolentryresult-descendantcount":    "Number of unconfirmed descendants of the transaction in the pool, including the transaction itself",
+	"getmempoolentryresult-descendantsize":     "Total virtual size of the transaction and its unconfirmed descendants in the pool",
+	"getmempoolentryresult-descendantfees":     "Total fee in satoshi of the transaction and its unconfirmed descendants in the pool",
+	"getmempoolentryresult-ancestorcount":      "Number of unconfirmed ancestors of the transaction in the pool, including the transaction itself",
+	"getmempoolentryresult-ancestorsize":       "Total virtual size of the transaction and its unconfirmed ancestors in the pool",
+	"getmempoolentryresult-ancestorfees":       "Total fee in satoshi of the transaction and its unconfirmed ancestors in the pool",
+	"getmempoolentryresult-wtxid":              "The witness hash of the transaction",
+	"getmempoolentryresult-fees":               "Fees of the transaction and its relatives in bitcoins",
+	"getmempoolentryresult-depends":            "Unconfirmed transactions used as inputs for this transaction",
+	"getmempoolentryresult-spentby":            "Unconfirmed transactions spending outputs of this transaction",
+	"getmempoolentryresult-bip125-replaceable": "Whether the transaction can be replaced due to BIP0125 (replace-by-fee) signaling, either by itself or an unconfirmed ancestor",
+
+	// MempoolFees help.
+	"mempoolfees-base":       "Transaction fee in bitcoins",
+	"mempoolfees-modified":   "Transaction fee in bitcoins used for mining priority",
+	"mempoolfees-ancestor":   "Total fee in bitcoins of the transaction and its unconfirmed ancestors in the pool",
+	"mempoolfees-descendant": "Total fee in bitcoins of the transaction and its unconfirmed descendants in the pool",
+
 	// GetMempoolInfoCmd help.
 	"getmempoolinfo--synopsis": "Returns memory pool information",
 
 	// GetMempoolInfoResult help.
-	"getmempoolinforesult-bytes": "Size in bytes of the mempool",
-	"getmempoolinforesult-size":  "Number of transactions in the mempool",
+	"getmempoolinforesult-loaded":        "Whether loading the mempool saved on the last shutdown has finished",
+	"getmempoolinforesult-bytes":         "Total serialized size in bytes of the transactions in the mempool",
+	"getmempoolinforesult-size":          "Number of transactions in the mempool",
+	"getmempoolinforesult-usage":         "Total virtual size in bytes of the transactions in the mempool as defined by BIP0141, which is what maxmempool limits (not the memory usage of the mempool)",
+	"getmempoolinforesult-maxmempool":    "Maximum size in bytes of the mempool",
+	"getmempoolinforesult-mempoolminfee": "Minimum fee rate in BTC/kB for a transaction to be accepted; the greater of minrelaytxfee and the rate raised by evictions due to the mempool being full",
+	"getmempoolinforesult-minrelaytxfee": "Minimum fee rate in BTC/kB for a transaction to be relayed",
 
 	// GetMiningInfoResult help.
 	"getmininginforesult-blocks":             "Height of the latest best block",
@@ -504,6 +791,17 @@ var helpDescsEnUS = map[string]string{
 	// GetPeerInfoCmd help.
 	"getpeerinfo--synopsis": "Returns data about each connected network peer as an array of json objects.",
 
+	// GetPrioritisedTransactionsCmd help.
+	"getprioritisedtransactions--synopsis":       "Returns the fee deltas of the transactions prioritized with prioritisetransaction.",
+	"getprioritisedtransactions--result0--desc":  "Prioritized transactions keyed by the transaction hash",
+	"getprioritisedtransactions--result0--key":   "The hash of the transaction",
+	"getprioritisedtransactions--result0--value": "Object containing the fee delta of the transaction",
+
+	// PrioritisedTransactionResult help.
+	"prioritisedtransactionresult-fee_delta":    "The fee delta in satoshi the transaction is prioritized with",
+	"prioritisedtransactionresult-in_mempool":   "Whether the transaction is in the memory pool",
+	"prioritisedtransactionresult-modified_fee": "The fee in satoshi of the transaction including the fee delta, only set when the transaction is in the memory pool",
+
 	// GetRawMempoolVerboseResult help.
 	"getrawmempoolverboseresult-size":             "Transaction size in bytes",
 	"getrawmempoolverboseresult-fee":              "Transaction fee in bitcoins",
@@ -544,6 +842,41 @@ var helpDescsEnUS = map[string]string{
 	"gettxout-vout":           "The index of the output",
 	"gettxout-includemempool": "Include the mempool when true",
 
+	// GetTxOutProofCmd help.
+	"gettxoutproof--synopsis": "Returns a hex-encoded proof that the transactions are included in a block.\n" +
+		"The block is located using the transaction index when no block hash is provided.",
+	"gettxoutproof-txids":     "The hashes of the transactions to prove the inclusion of",
+	"gettxoutproof-blockhash": "The hash of the block containing the transactions",
+	"gettxoutproof--result0":  "The serialized, hex-encoded merkle block proving the inclusion of the transactions",
+
+	// GetTxOutSetInfoCmd help.
+	"gettxoutsetinfo--synopsis": "Returns statistics about the unspent transaction output set.\n" +
+		"The UTXO set at the current tip is iterated unless the coin statistics index (--coinstatsindex) is enabled,\n" +
+		"which is required to query the statistics at an earlier block.",
+	"gettxoutsetinfo-hashtype":     "The hash of the UTXO set to return: hash_serialized_3, muhash or none (hash_serialized_3 is not available at earlier blocks)",
+	"gettxoutsetinfo-hashorheight": "The height or hash of the block to return the statistics at (default: the current tip)",
+	"gettxoutsetinfo-useindex":     "Use the coin statistics index when it is enabled",
+
+	// GetTxOutSetInfoResult help.
+	"gettxoutsetinforesult-height":            "The height of the block the statistics are at",
+	"gettxoutsetinforesult-bestblock":         "The hash of the block the statistics are at",
+	"gettxoutsetinforesult-transactions":      "The number of transactions with unspent outputs (not available from the index)",
+	"gettxoutsetinforesult-txouts":            "The number of unspent transaction outputs",
+	"gettxoutsetinforesult-bogosize":          "A database independent metric for the size of the UTXO set",
+	"gettxoutsetinforesult-hash_serialized_2": "The hash_serialized_2 hash of the UTXO set reported by older versions of Bitcoin Core (not reported)",
+	"gettxoutsetinforesult-hash_serialized_3": "The serialized hash of the UTXO set (only with the hash_serialized_3 hash type)",
+	"gettxoutsetinforesult-muhash":            "The MuHash3072 hash of the UTXO set (only with the muhash hash type)",
+	"gettxoutsetinforesult-disk_size":         "The size of the UTXO set on disk (not reported)",
+	"gettxoutsetinforesult-total_amount":      "The total amount of the unspent transaction outputs in BTC",
+
+	// GetZmqNotificationsCmd help.
+	// GetZmqNotificationResult help.
+	"zmqnotification-type":    "Type of notification",
+	"zmqnotification-address": "Address of the publisher",
+	"zmqnotification-hwm":     "Outbound message high water mark",
+
+	"getzmqnotifications--synopsis": "Returns information about the active ZeroMQ notifications.",
+
 	// InvalidateBlockCmd help.
 	"invalidateblock--synopsis": "Invalidates the block of the given block hash. To re-validate the invalidated block, use the reconsiderblock rpc",
 	"invalidateblock-blockhash": "The block hash of the block to invalidate",
@@ -595,6 +928,36 @@ var helpDescsEnUS = map[string]string{
 	"signmessagewithprivkey-message":   "The message to create a signature of",
 	"signmessagewithprivkey--result0":  "The signature of the message encoded in base 64",
 
+	// RawTxWitnessInput help.
+	"rawtxwitnessinput-txid":          "The hash of the transaction of the previous output",
+	"rawtxwitnessinput-vout":          "The index of the previous output",
+	"rawtxwitnessinput-scriptPubKey":  "The hex-encoded public key script of the previous output",
+	"rawtxwitnessinput-redeemScript":  "The hex-encoded redeem script of pay-to-script-hash outputs",
+	"rawtxwitnessinput-witnessScript": "The hex-encoded witness script of pay-to-witness-script-hash outputs",
+	"rawtxwitnessinput-amount":        "The amount of the previous output in BTC, required for segwit outputs not in the UTXO set",
+
+	// SignRawTransactionError help.
+	"signrawtransactionerror-txid":      "The hash of the transaction of the previous output",
+	"signrawtransactionerror-vout":      "The index of the previous output",
+	"signrawtransactionerror-witness":   "The hex-encoded witness items of the input",
+	"signrawtransactionerror-scriptSig": "The hex-encoded signature script of the input",
+	"signrawtransactionerror-sequence":  "The sequence number of the input",
+	"signrawtransactionerror-error":     "The reason the input isn't signed or is invalid",
+
+	// SignRawTransactionWithKeyResult help.
+	"signrawtransactionwithkeyresult-hex":      "The hex-encoded transaction with the signatures",
+	"signrawtransactionwithkeyresult-complete": "Whether all inputs are signed",
+	"signrawtransactionwithkeyresult-errors":   "The inputs which aren't signed or are invalid (only if there are any)",
+
+	// SignRawTransactionWithKeyCmd help.
+	"signrawtransactionwithkey--synopsis": "Signs the inputs of a transaction with the provided private keys.\n" +
+		"The outputs spent by the inputs are taken from the provided previous outputs, and from the UTXO set or the memory pool otherwise.\n" +
+		"Pay-to-pubkey, pay-to-pubkey-hash and multisig outputs are signed, directly or in pay-to-script-hash and segwit outputs, as well as key path spends of BIP0086 taproot outputs.",
+	"signrawtransactionwithkey-rawtx":       "The hex-encoded transaction to sign",
+	"signrawtransactionwithkey-privkeys":    "The WIF encoded private keys to sign with",
+	"signrawtransactionwithkey-inputs":      "The previous outputs spent by the inputs which aren't in the UTXO set, and the redeem and witness scripts of script hash outputs",
+	"signrawtransactionwithkey-sighashtype": "The signature hash type, which is one of DEFAULT, ALL, NONE, SINGLE, ALL|ANYONECANPAY, NONE|ANYONECANPAY or SINGLE|ANYONECANPAY",
+
 	// StopCmd help.
 	"stop--synopsis": "Shutdown btcd.",
 	"stop--result0":  "The string 'btcd stopping.'",
@@ -602,6 +965,89 @@ var helpDescsEnUS = map[string]string{
 	// SubmitBlockOptions help.
 	"submitblockoptions-workid": "This parameter is currently ignored",
 
+	// DumpTxOutSetCmd help.
+	"dumptxoutset--synopsis": "Writes a snapshot of the UTXO set at the chain tip to a file.\n" +
+		"The file format is compatible with the snapshots written by Bitcoin Core.",
+	"dumptxoutset-path": "Path of the snapshot file, relative to the data directory unless absolute.  The file must not exist yet",
+
+	// DumpTxOutSetResult help.
+	"dumptxoutsetresult-coins_written": "The number of coins written to the snapshot",
+	"dumptxoutsetresult-base_hash":     "The hash of the block the snapshot was taken at",
+	"dumptxoutsetresult-base_height":   "The height of the block the snapshot was taken at",
+	"dumptxoutsetresult-path":          "Absolute path of the snapshot file",
+	"dumptxoutsetresult-txoutset_hash": "The hash_serialized_3 hash of the UTXO set",
+	"dumptxoutsetresult-nchaintx":      "The number of transactions in the chain up to and including the base block",
+
+	// JoinPsbtsCmd help.
+	"joinpsbts--synopsis": "Joins PSBTs of different transactions into one PSBT with the inputs and outputs of all of them in random order.\n" +
+		"The joined transaction has the highest version and the lowest lock time of the joined ones, and no input may be in more than one PSBT.",
+	"joinpsbts-psbts":    "The base64 encoded PSBTs to join",
+	"joinpsbts--result0": "The base64 encoded joined version 0 PSBT",
+
+	// LoadTxOutSetCmd help.
+	"loadtxoutset--synopsis": "Loads a UTXO set snapshot and continues the chain from the block it was taken at.\n" +
+		"The snapshot must match one pinned in the chain parameters and the header of its base block must be known.\n" +
+		"The chain before the base block is downloaded and validated in the background, and the chain is rejected if the resulting UTXO set does not match the snapshot.",
+	"loadtxoutset-path": "Path of the snapshot file, relative to the data directory unless absolute",
+
+	// LoadTxOutSetResult help.
+	"loadtxoutsetresult-coins_loaded": "The number of coins loaded from the snapshot",
+	"loadtxoutsetresult-tip_hash":     "The hash of the chain tip after loading the snapshot",
+	"loadtxoutsetresult-base_height":  "The height of the block the snapshot was taken at",
+	"loadtxoutsetresult-path":         "Absolute path of the snapshot file",
+
+	// ImportMempoolOptions help.
+	"importmempooloptions-use_current_time": "Whether to use the current time as the time the transactions entered the mempool rather than the time recorded in the file",
+
+	// ImportMempoolCmd help.
+	"importmempool--synopsis": "Imports the transactions from a mempool file as written by savemempool into the mempool.\n" +
+		"The transactions are validated as if they were newly received.\n" +
+		"Fee deltas and unbroadcast transactions recorded in the file are dropped since they are not supported.",
+	"importmempool-filepath": "Path of the mempool file",
+	"importmempool-options":  "Import options",
+
+	// SaveMempoolCmd help.
+	"savemempool--synopsis": "Saves the mempool to disk so it is loaded on the next startup.\n" +
+		"The file format is compatible with the mempool.dat file used by Bitcoin Core.\n" +
+		"Fee deltas and unbroadcast transactions are not supported, so none are recorded.",
+
+	// SaveMempoolResult help.
+	"savemempoolresult-filename": "Path of the mempool file",
+
+	// ScanObject help.
+	"scanobject-desc":  "The output descriptor",
+	"scanobject-range": "The last index, or the first and last index, of the range to scan a ranged descriptor at (default: 1000)",
+
+	// ScanTxOutSetCmd help.
+	"scantxoutset--synopsis": "Scans the unspent transaction output set for the outputs of output descriptors.\n" +
+		"Only one scan runs at a time, and a running scan can be queried with the status action or stopped with the abort action.",
+	"scantxoutset-action":      "The action to execute: start, abort or status",
+	"scantxoutset-scanobjects": "The descriptors to scan for, required by the start action",
+	"scantxoutset--condition0": "action=start",
+	"scantxoutset--condition1": "action=status",
+	"scantxoutset--condition2": "action=abort",
+	"scantxoutset--result2":    "Whether a scan was running and is aborted",
+
+	// ScanTxOutSetResult help.
+	"scantxoutsetresult-success":      "Whether the scan completed without being aborted",
+	"scantxoutsetresult-txouts":       "The number of unspent transaction outputs scanned",
+	"scantxoutsetresult-height":       "The height of the block the unspent transaction output set is at",
+	"scantxoutsetresult-bestblock":    "The hash of the block the unspent transaction output set is at",
+	"scantxoutsetresult-unspents":     "The unspent transaction outputs found",
+	"scantxoutsetresult-total_amount": "The total amount of the unspent transaction outputs found in BTC",
+
+	// ScanTxOutSetUnspent help.
+	"scantxoutsetunspent-txid":         "The hash of the transaction",
+	"scantxoutsetunspent-vout":         "The index of the output",
+	"scantxoutsetunspent-scriptPubKey": "The hex-encoded output script",
+	"scantxoutsetunspent-desc":         "A descriptor of the output script",
+	"scantxoutsetunspent-amount":       "The amount of the output in BTC",
+	"scantxoutsetunspent-coinbase":     "Whether the output is of a coinbase transaction",
+	"scantxoutsetunspent-height":       "The height of the block which contains the transaction",
+
+	// ScanTxOutSetStatusResult help.
+	"scantxoutsetstatusresult-progress": "The approximate progress of the running scan in percent",
+
 	// SubmitBlockCmd help.
 	"submitblock--synopsis":   "Attempts to submit a new serialized, hex-encoded block to the network.",
 	"submitblock-hexblock":    "Serialized, hex-encoded block",
@@ -610,6 +1056,27 @@ var helpDescsEnUS = map[string]string{
 	"submitblock--condition1": "Block rejected",
 	"submitblock--result1":    "The reason the block was rejected",
 
+	// SubmitPackageCmd help.
+	"submitpackage--synopsis":     "Submits a package of serialized, hex-encoded transactions, consisting of a child transaction along with its unconfirmed parents, to the local mempool and relays the accepted transactions to the network.\nThe transactions which don't pay the minimum fee rate on their own are evaluated at the fee rate of the package.",
+	"submitpackage-rawtxns":       "Serialized transactions sorted topologically, with the child transaction last",
+	"submitpackage-maxfeerate":    "Reject transactions whose fee rate is higher than the specified value in BTC/kB, 0 disables the check",
+	"submitpackage-maxburnamount": "Reject transactions with provably unspendable outputs whose value exceeds the specified value in BTC",
+
+	// SubmitPackageResult help.
+	"submitpackageresult-package_msg":           "The transaction results are only present when the message is \"success\" or \"transaction failed\"",
+	"submitpackageresult-tx-results":            "The results of the transactions",
+	"submitpackageresult-tx-results--key":       "wtxid",
+	"submitpackageresult-tx-results--value":     "The result of the transaction",
+	"submitpackageresult-tx-results--desc":      "The result of each transaction keyed by its witness hash in hex",
+	"submitpackageresult-replaced-transactions": "The hashes of the transactions replaced by transactions of the package",
+
+	// SubmitPackageTxResult help.
+	"submitpackagetxresult-txid":        "The transaction hash in hex",
+	"submitpackagetxresult-other-wtxid": "The witness hash of a different transaction with the same hash already in the mempool, if any",
+	"submitpackagetxresult-vsize":       "Virtual transaction size as defined in BIP 141 (only present when the transaction is in the mempool)",
+	"submitpackagetxresult-fees":        "Transaction fees (only present when the transaction is in the mempool)",
+	"submitpackagetxresult-error":       "The reason the transaction was rejected (only present when the transaction is not in the mempool)",
+
 	// ValidateAddressResult help.
 	"validateaddresschainresult-isvalid":         "Whether or not the address is valid",
 	"validateaddresschainresult-address":         "The bitcoin address (only when isvalid is true)",
@@ -639,6 +1106,12 @@ var helpDescsEnUS = map[string]string{
 	"verifymessage-message":   "The signed message",
 	"verifymessage--result0":  "Whether or not the signature verified",
 
+	// VerifyTxOutProofCmd help.
+	"verifytxoutproof--synopsis": "Verifies a proof produced by gettxoutproof and returns the hashes of the transactions it proves are included in a main chain block.\n" +
+		"An empty list is returned when the proof is invalid.",
+	"verifytxoutproof-proof":    "The hex-encoded proof produced by gettxoutproof",
+	"verifytxoutproof--result0": "The hashes of the transactions the proof commits to",
+
 	// -------- Websocket-specific help --------
 
 	// Session help.
@@ -685,6 +1158,15 @@ var helpDescsEnUS = map[string]string{
 	"loadtxfilter-addresses": "Array of addresses to add to the transaction filter",
 	"loadtxfilter-outpoints": "Array of outpoints to add to the transaction filter",
 
+	// PrioritiseTransactionCmd help.
+	"prioritisetransaction--synopsis": "Accepts the transaction into mined blocks at a higher (or lower) priority by adding the fee delta to its fee.\n" +
+		"The fee delta isn't paid by the transaction, but only changes its fee as seen by the relay fee checks, the eviction of transactions and the selection of transactions for new blocks.\n" +
+		"Fee deltas of transactions not in the memory pool are kept until the transaction is added to it or confirmed.",
+	"prioritisetransaction-txid":     "The hash of the transaction",
+	"prioritisetransaction-dummy":    "Unused, must be zero",
+	"prioritisetransaction-feedelta": "The fee delta in satoshi to add to the fee of the transaction, which may be negative",
+	"prioritisetransaction--result0": "Always true",
+
 	// ReconsiderBlockCmd help.
 	"reconsiderblock--synopsis": "Reconsiders the block of the given block hash. Can be used to re-validate blocks invalidated with invalidateblock",
 	"reconsiderblock-blockhash": "The block hash of the block to reconsider",
@@ -712,6 +1194,12 @@ var helpDescsEnUS = map[string]string{
 	"uptime--synopsis": "Returns the total uptime of the server.",
 	"uptime--result0":  "The number of seconds that the server has been running",
 
+	// UtxoUpdatePsbtCmd help.
+	"utxoupdatepsbt--synopsis": "Adds the UTXOs spent by the inputs of a PSBT.\n" +
+		"Segwit inputs get the spent output from the unspent transaction output set or the memory pool, and other inputs get the spent transaction from the memory pool or the transaction index.",
+	"utxoupdatepsbt-psbt":     "A base64 encoded PSBT",
+	"utxoupdatepsbt--result0": "The base64 encoded updated PSBT",
+
 	// Version help.
 	"version--synopsis":       "Returns the JSON-RPC API version (semver)",
 	"version--result0--desc":  "Version objects keyed by the program or API name",
@@ -755,63 +1243,101 @@ var helpDescsEnUS = map[string]string{
 	"gettxspendingprevoutresult-spendingtxid": "The hash of the transaction that spends the output.",
 }
 
+// zmqNotification describes the JSON encoding of an entry of
+// btcjson.GetZmqNotificationResult for the help since the result itself is a
+// list of anonymous structs with a custom marshaller.
+type zmqNotification struct {
+	Type    string `json:"type"`
+	Address string `json:"address"`
+	HWM     int    `json:"hwm"`
+}
+
 // rpcResultTypes specifies the result types that each RPC command can return.
 // This information is used to generate the help.  Each result type must be a
 // pointer to the type (or nil to indicate no return value).
 var rpcResultTypes = map[string][]interface{}{
-	"addnode":                nil,
-	"createrawtransaction":   {(*string)(nil)},
-	"debuglevel":             {(*string)(nil), (*string)(nil)},
-	"decoderawtransaction":   {(*btcjson.TxRawDecodeResult)(nil)},
-	"decodescript":           {(*btcjson.DecodeScriptResult)(nil)},
-	"estimatefee":            {(*float64)(nil)},
-	"generate":               {(*[]string)(nil)},
-	"getaddednodeinfo":       {(*[]string)(nil), (*[]btcjson.GetAddedNodeInfoResult)(nil)},
-	"getbestblock":           {(*btcjson.GetBestBlockResult)(nil)},
-	"getbestblockhash":       {(*string)(nil)},
-	"getblock":               {(*string)(nil), (*btcjson.GetBlockVerboseResult)(nil)},
-	"getblockcount":          {(*int64)(nil)},
-	"getblockhash":           {(*string)(nil)},
-	"getblockheader":         {(*string)(nil), (*btcjson.GetBlockHeaderVerboseResult)(nil)},
-	"getblocktemplate":       {(*btcjson.GetBlockTemplateResult)(nil), (*string)(nil), nil},
-	"getblockchaininfo":      {(*btcjson.GetBlockChainInfoResult)(nil)},
-	"getchaintips":           {(*[]btcjson.GetChainTipsResult)(nil)},
-	"getcfilter":             {(*string)(nil)},
-	"getcfilterheader":       {(*string)(nil)},
-	"getconnectioncount":     {(*int32)(nil)},
-	"getcurrentnet":          {(*uint32)(nil)},
-	"getdifficulty":          {(*float64)(nil)},
-	"getgenerate":            {(*bool)(nil)},
-	"gethashespersec":        {(*float64)(nil)},
-	"getheaders":             {(*[]string)(nil)},
-	"getinfo":                {(*btcjson.InfoChainResult)(nil)},
-	"getmempoolinfo":         {(*btcjson.GetMempoolInfoResult)(nil)},
-	"getmininginfo":          {(*btcjson.GetMiningInfoResult)(nil)},
-	"getnettotals":           {(*btcjson.GetNetTotalsResult)(nil)},
-	"getnetworkhashps":       {(*float64)(nil)},
-	"getnodeaddresses":       {(*[]btcjson.GetNodeAddressesResult)(nil)},
-	"getpeerinfo":            {(*[]btcjson.GetPeerInfoResult)(nil)},
-	"getrawmempool":          {(*[]string)(nil), (*btcjson.GetRawMempoolVerboseResult)(nil)},
-	"getrawtransaction":      {(*string)(nil), (*btcjson.TxRawResult)(nil)},
-	"gettxout":               {(*btcjson.GetTxOutResult)(nil)},
-	"node":                   nil,
-	"help":                   {(*string)(nil), (*string)(nil)},
-	"invalidateblock":        nil,
-	"ping":                   nil,
-	"reconsiderblock":        nil,
-	"searchrawtransactions":  {(*string)(nil), (*[]btcjson.SearchRawTransactionsResult)(nil)},
-	"sendrawtransaction":     {(*string)(nil)},
-	"setgenerate":            nil,
-	"signmessagewithprivkey": {(*string)(nil)},
-	"stop":                   {(*string)(nil)},
-	"submitblock":            {nil, (*string)(nil)},
-	"uptime":                 {(*int64)(nil)},
-	"validateaddress":        {(*btcjson.ValidateAddressChainResult)(nil)},
-	"verifychain":            {(*bool)(nil)},
-	"verifymessage":          {(*bool)(nil)},
-	"version":                {(*map[string]btcjson.VersionResult)(nil)},
-	"testmempoolaccept":      {(*[]btcjson.TestMempoolAcceptResult)(nil)},
-	"gettxspendingprevout":   {(*[]btcjson.GetTxSpendingPrevOutResult)(nil)},
+	"addnode":                    nil,
+	"analyzepsbt":                {(*btcjson.AnalyzePsbtResult)(nil)},
+	"combinepsbt":                {(*string)(nil)},
+	"combinerawtransaction":      {(*string)(nil)},
+	"createpsbt":                 {(*string)(nil)},
+	"createrawtransaction":       {(*string)(nil)},
+	"debuglevel":                 {(*string)(nil), (*string)(nil)},
+	"decodepsbt":                 {(*btcjson.DecodePsbtResult)(nil)},
+	"decoderawtransaction":       {(*btcjson.TxRawDecodeResult)(nil)},
+	"decodescript":               {(*btcjson.DecodeScriptResult)(nil)},
+	"deriveaddresses":            {(*btcjson.DeriveAddressesResult)(nil)},
+	"dumptxoutset":               {(*btcjson.DumpTxOutSetResult)(nil)},
+	"estimatefee":                {(*float64)(nil)},
+	"finalizepsbt":               {(*btcjson.FinalizePsbtResult)(nil)},
+	"generate":                   {(*[]string)(nil)},
+	"getaddednodeinfo":           {(*[]string)(nil), (*[]btcjson.GetAddedNodeInfoResult)(nil)},
+	"getbestblock":               {(*btcjson.GetBestBlockResult)(nil)},
+	"getbestblockhash":           {(*string)(nil)},
+	"getblock":                   {(*string)(nil), (*btcjson.GetBlockVerboseResult)(nil)},
+	"getblockcount":              {(*int64)(nil)},
+	"getblockfilter":             {(*btcjson.GetBlockFilterResult)(nil)},
+	"getblockhash":               {(*string)(nil)},
+	"getblockheader":             {(*string)(nil), (*btcjson.GetBlockHeaderVerboseResult)(nil)},
+	"getblockstats":              {(*btcjson.GetBlockStatsResult)(nil)},
+	"getblocktemplate":           {(*btcjson.GetBlockTemplateResult)(nil), (*string)(nil), nil},
+	"getblockchaininfo":          {(*btcjson.GetBlockChainInfoResult)(nil)},
+	"getchaintips":               {(*[]btcjson.GetChainTipsResult)(nil)},
+	"getcfilter":                 {(*string)(nil)},
+	"getcfilterheader":           {(*string)(nil)},
+	"getconnectioncount":         {(*int32)(nil)},
+	"getcurrentnet":              {(*uint32)(nil)},
+	"getdescriptorinfo":          {(*btcjson.GetDescriptorInfoResult)(nil)},
+	"getdifficulty":              {(*float64)(nil)},
+	"getgenerate":                {(*bool)(nil)},
+	"gethashespersec":            {(*float64)(nil)},
+	"getheaders":                 {(*[]string)(nil)},
+	"getindexinfo":               {(*map[string]btcjson.GetIndexInfoResult)(nil)},
+	"getinfo":                    {(*btcjson.InfoChainResult)(nil)},
+	"getmempoolancestors":        {(*[]string)(nil), (*btcjson.GetMempoolEntryResult)(nil)},
+	"getmempooldescendants":      {(*[]string)(nil), (*btcjson.GetMempoolEntryResult)(nil)},
+	"getmempoolentry":            {(*btcjson.GetMempoolEntryResult)(nil)},
+	"getmempoolinfo":             {(*btcjson.GetMempoolInfoResult)(nil)},
+	"getmininginfo":              {(*btcjson.GetMiningInfoResult)(nil)},
+	"getnettotals":               {(*btcjson.GetNetTotalsResult)(nil)},
+	"getnetworkhashps":           {(*float64)(nil)},
+	"getnodeaddresses":           {(*[]btcjson.GetNodeAddressesResult)(nil)},
+	"getpeerinfo":                {(*[]btcjson.GetPeerInfoResult)(nil)},
+	"getprioritisedtransactions": {(*map[string]btcjson.PrioritisedTransactionResult)(nil)},
+	"getrawmempool":              {(*[]string)(nil), (*btcjson.GetRawMempoolVerboseResult)(nil)},
+	"getrawtransaction":          {(*string)(nil), (*btcjson.TxRawResult)(nil)},
+	"gettxout":                   {(*btcjson.GetTxOutResult)(nil)},
+	"gettxoutproof":              {(*string)(nil)},
+	"gettxoutsetinfo":            {(*btcjson.GetTxOutSetInfoResult)(nil)},
+	"getzmqnotifications":        {(*[]zmqNotification)(nil)},
+	"node":                       nil,
+	"help":                       {(*string)(nil), (*string)(nil)},
+	"importmempool":              {(*btcjson.ImportMempoolResult)(nil)},
+	"invalidateblock":            nil,
+	"joinpsbts":                  {(*string)(nil)},
+	"loadtxoutset":               {(*btcjson.LoadTxOutSetResult)(nil)},
+	"ping":                       nil,
+	"prioritisetransaction":      {(*bool)(nil)},
+	"reconsiderblock":            nil,
+	"savemempool":                {(*btcjson.SaveMempoolResult)(nil)},
+	"scantxoutset":               {(*btcjson.ScanTxOutSetResult)(nil), (*btcjson.ScanTxOutSetStatusResult)(nil), (*bool)(nil)},
+	"searchrawtransactions":      {(*string)(nil), (*[]btcjson.SearchRawTransactionsResult)(nil)},
+	"sendrawtransaction":         {(*string)(nil)},
+	"setgenerate":                nil,
+	"signmessagewithprivkey":     {(*string)(nil)},
+	"signrawtransactionwithkey":  {(*btcjson.SignRawTransactionWithKeyResult)(nil)},
+	"stop":                       {(*string)(nil)},
+	"submitblock":                {nil, (*string)(nil)},
+	"submitpackage":              {(*btcjson.SubmitPackageResult)(nil)},
+	"uptime":                     {(*int64)(nil)},
+	"utxoupdatepsbt":             {(*string)(nil)},
+	"validateaddress":            {(*btcjson.ValidateAddressChainResult)(nil)},
+	"verifychain":                {(*bool)(nil)},
+	"verifymessage":              {(*bool)(nil)},
+	"verifytxoutproof":           {(*[]string)(nil)},
+	"version":                    {(*map[string]btcjson.VersionResult)(nil)},
+	"testmempoolaccept":          {(*[]btcjson.TestMempoolAcceptResult)(nil)},
+	"gettxspendingprevout":       {(*[]btcjson.GetTxSpendingPrevOutResult)(nil)},
 
 	// Websocket commands.
 	"loadtxfilter":              nil,
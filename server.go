@@ -14,6 +14,8 @@ import (
 	"fmt"
 	"math"
 	"net"
+	"os"
+	"path/filepath"
 	"runtime"
 	"sort"
 	"strconv"
@@ -38,6 +40,7 @@ import (
 	"github.com/bynil/btcd/peer"
 	"github.com/bynil/btcd/txscript"
 	"github.com/bynil/btcd/wire"
+	"github.com/bynil/btcd/zmq"
 	"github.com/decred/dcrd/lru"
 )
 
@@ -58,6 +61,47 @@ const (
 	// retries when connecting to persistent peers.  It is adjusted by the
 	// number of retries such that there is a retry backoff.
 	connectionRetryInterval = time.Second * 5
+
+	// maxCmpctBlockDepth is the maximum depth of a block that is served
+	// as a compact block when requested.  Deeper blocks are sent in full
+	// since the peer is unlikely to have their transactions.
+	maxCmpctBlockDepth = 5
+
+	// maxBlockTxnDepth is the maximum depth of a block for which
+	// individual transactions are served in response to a getblocktxn
+	// message.  The full block is sent for deeper blocks.
+	maxBlockTxnDepth = 10
+
+	// feeFilterCheckInterval is the interval at which the fee filter sent
+	// to each peer is checked for updates.
+	feeFilterCheckInterval = time.Minute
+
+	// feeFilterBroadcastInterval is the minimum amount of time in between
+	// fee filter updates sent to a peer unless the fee filter changed
+	// significantly.
+	feeFilterBroadcastInterval = time.Minute * 10
+
+	// maxFeeFilterChangeDelay is the maximum amount of time a significant
+	// change of the fee filter is delayed before it is sent to a peer.
+	maxFeeFilterChangeDelay = time.Minute * 5
+
+	// mempoolDumpFilename is the name of the file in the data directory
+	// the mempool is saved to on shutdown and loaded from on startup.
+	mempoolDumpFilename = "mempool.dat"
+
+	// mempoolLoadCheckInterval is the interval at which the chain is
+	// checked to be synced before loading the saved mempool.
+	mempoolLoadCheckInterval = time.Second * 5
+
+	// v1OnlyAddrTimeout is the amount of time an address that rejected a
+	// v2 transport handshake is connected to using v1 before attempting
+	// the v2 transport again.
+	v1OnlyAddrTimeout = time.Hour * 24
+
+	// maxV1OnlyAddrs is the maximum number of addresses that rejected a v2
+	// transport handshake which are remembered.  The oldest address is
+	// forgotten to make room for a new one.
+	maxV1OnlyAddrs = 1000
 )
 
 var (
@@ -235,14 +279,20 @@ type server struct {
 	// if the associated index is not enabled.  These fields are set during
 	// initial creation of the server and never changed afterwards, so they
 	// do not need to be protected for concurrent access.
-	txIndex   *indexers.TxIndex
-	addrIndex *indexers.AddrIndex
-	cfIndex   *indexers.CfIndex
+	txIndex        *indexers.TxIndex
+	addrIndex      *indexers.AddrIndex
+	cfIndex        *indexers.CfIndex
+	coinStatsIndex *indexers.CoinStatsIndex
+	indexManager   *indexers.Manager
 
 	// The fee estimator keeps track of how long transactions are left in
 	// the mempool before they are mined into blocks.
 	feeEstimator *mempool.FeeEstimator
 
+	// zmqNotifier publishes block and transaction notifications over
+	// ZeroMQ.  It is nil when no ZeroMQ endpoints are configured.
+	zmqNotifier *zmq.Notifier
+
 	// cfCheckptCaches stores a cached slice of filter headers for cfcheckpt
 	// messages for each filter type.
 	cfCheckptCaches    map[wire.FilterType][]cfHeaderKV
@@ -255,6 +305,15 @@ type server struct {
 	// agentWhitelist is a list of whitelisted user agent substrings, no
 	// whitelisting will be applied if the list is empty or nil.
 	agentWhitelist []string
+
+	// v2TransportAddrs tracks the outbound addresses that advertised
+	// support for the BIP0324 v2 transport when they were selected, while
+	// v1OnlyAddrs tracks the time addresses rejected a v2 handshake so
+	// they're connected to using v1 until v1OnlyAddrTimeout passes.  They
+	// are only used when the v2 transport is enabled.
+	v2TransportMtx   sync.Mutex
+	v2TransportAddrs map[string]struct{}
+	v1OnlyAddrs      map[string]time.Time
 }
 
 // serverPeer extends the peer to maintain state shared by the server and
@@ -278,6 +337,13 @@ type serverPeer struct {
 	knownAddresses lru.Cache
 	banScore       connmgr.DynamicBanScore
 	quit           chan struct{}
+
+	// feeFilterSent is the fee filter last sent to the peer and
+	// nextFeeFilterSend is the earliest time an update is sent to it.  They
+	// are only accessed by the peer handler.
+	feeFilterSent     int64
+	nextFeeFilterSend time.Time
+
 	// The following chans are used to sync blockmanager and server.
 	txProcessed    chan struct{}
 	blockProcessed chan struct{}
@@ -531,6 +597,55 @@ func (sp *serverPeer) OnVersion(_ *peer.Peer, msg *wire.MsgVersion) *wire.MsgRej
 // to kick start communication with them.
 func (sp *serverPeer) OnVerAck(_ *peer.Peer, _ *wire.MsgVerAck) {
 	sp.server.AddPeer(sp)
+
+	// Signal support for compact block relay (BIP0152).  Blocks are
+	// initially announced to us using inv messages (low-bandwidth mode)
+	// and the sync manager asks peers that provide blocks in a timely
+	// manner to switch to high-bandwidth mode later on.  Only version 2,
+	// which includes witness data, is supported for receiving.
+	if sp.ProtocolVersion() >= wire.BIP0152Version && sp.IsWitnessEnabled() {
+		sp.QueueMessage(wire.NewMsgSendCmpct(false,
+			wire.CmpctBlockVersion2), nil)
+	}
+}
+
+// maybeSendFeeFilter sends the passed fee filter in satoshi/kB to the peer if
+// it differs from the one last sent to it.  Updates are sent at most once per
+// feeFilterBroadcastInterval, however significant changes are sent within
+// maxFeeFilterChangeDelay.  It must only be called from the peer handler.
+func (sp *serverPeer) maybeSendFeeFilter(feeFilter int64) {
+	if sp.ProtocolVersion() < wire.FeeFilterVersion {
+		return
+	}
+
+	// Peers are asked not to relay any transactions at all in blocks only
+	// mode, which only needs to be sent once.
+	if cfg.BlocksOnly {
+		if sp.feeFilterSent == btcutil.MaxSatoshi {
+			return
+		}
+		feeFilter = btcutil.MaxSatoshi
+		sp.nextFeeFilterSend = time.Time{}
+	}
+
+	now := time.Now()
+	if now.After(sp.nextFeeFilterSend) {
+		if feeFilter != sp.feeFilterSent {
+			sp.QueueMessage(wire.NewMsgFeeFilter(feeFilter), nil)
+			sp.feeFilterSent = feeFilter
+		}
+		sp.nextFeeFilterSend = now.Add(feeFilterBroadcastInterval)
+		return
+	}
+
+	// Send significant changes sooner than the next scheduled update.
+	changeDeadline := now.Add(maxFeeFilterChangeDelay)
+	if changeDeadline.Before(sp.nextFeeFilterSend) &&
+		(feeFilter < 3*sp.feeFilterSent/4 ||
+			feeFilter > 4*sp.feeFilterSent/3) {
+
+		sp.nextFeeFilterSend = changeDeadline
+	}
 }
 
 // OnMemPool is invoked when a peer receives a mempool bitcoin message.
@@ -636,6 +751,76 @@ func (sp *serverPeer) OnBlock(_ *peer.Peer, msg *wire.MsgBlock, buf []byte) {
 	<-sp.blockProcessed
 }
 
+// OnCmpctBlock is invoked when a peer receives a cmpctblock bitcoin message.
+// It blocks until the compact block has been fully processed, which includes
+// requesting any transactions that are missing to reconstruct the block.
+func (sp *serverPeer) OnCmpctBlock(_ *peer.Peer, msg *wire.MsgCmpctBlock) {
+	sp.server.syncManager.QueueCmpctBlock(msg, sp.Peer, sp.blockProcessed)
+	<-sp.blockProcessed
+}
+
+// OnBlockTxn is invoked when a peer receives a blocktxn bitcoin message.  It
+// blocks until the transactions have been used to complete the compact block
+// they belong to and the resulting block has been fully processed.
+func (sp *serverPeer) OnBlockTxn(_ *peer.Peer, msg *wire.MsgBlockTxn) {
+	sp.server.syncManager.QueueBlockTxn(msg, sp.Peer, sp.blockProcessed)
+	<-sp.blockProcessed
+}
+
+// OnGetBlockTxn is invoked when a peer receives a getblocktxn bitcoin message.
+// It responds with a blocktxn message containing the requested transactions
+// of a recent block.  The full block is sent instead when the block is too
+// deep in the chain as is done by the reference implementation.
+func (sp *serverPeer) OnGetBlockTxn(_ *peer.Peer, msg *wire.MsgGetBlockTxn) {
+	chain := sp.server.chain
+	height, err := chain.BlockHeightByHash(&msg.BlockHash)
+	if err != nil {
+		peerLog.Debugf("Peer %v requested transactions of unknown "+
+			"block %v", sp, msg.BlockHash)
+		return
+	}
+	if chain.BestSnapshot().Height-height >= maxBlockTxnDepth {
+		encoding := wire.BaseEncoding
+		if sp.IsWitnessEnabled() {
+			encoding = wire.WitnessEncoding
+		}
+		done := make(chan struct{}, 1)
+		err := sp.server.pushBlockMsg(sp, &msg.BlockHash, done, nil,
+			encoding)
+		if err == nil {
+			<-done
+		}
+		return
+	}
+
+	block, err := chain.BlockByHash(&msg.BlockHash)
+	if err != nil {
+		peerLog.Debugf("Unable to fetch requested block hash %v: %v",
+			msg.BlockHash, err)
+		return
+	}
+
+	txns := block.MsgBlock().Transactions
+	blockTxn := wire.NewMsgBlockTxn(&msg.BlockHash,
+		make([]*wire.MsgTx, 0, len(msg.Indexes)))
+	for _, index := range msg.Indexes {
+		if index >= uint32(len(txns)) {
+			sp.addBanScore(100, 0, "getblocktxn with out of range "+
+				"index")
+			return
+		}
+		blockTxn.AddTransaction(txns[index])
+	}
+
+	encoding := wire.BaseEncoding
+	if sp.CmpctBlockVersion() == wire.CmpctBlockVersion2 {
+		encoding = wire.WitnessEncoding
+	}
+	done := make(chan struct{}, 1)
+	sp.QueueMessageWithEncoding(blockTxn, done, encoding)
+	<-done
+}
+
 // OnInv is invoked when a peer receives an inv bitcoin message and is
 // used to examine the inventory being advertised by the remote peer and react
 // accordingly.  We pass the message down to blockmanager which will call
@@ -723,6 +908,8 @@ func (sp *serverPeer) OnGetData(_ *peer.Peer, msg *wire.MsgGetData) {
 			err = sp.server.pushBlockMsg(sp, &iv.Hash, c, waitChan, wire.WitnessEncoding)
 		case wire.InvTypeBlock:
 			err = sp.server.pushBlockMsg(sp, &iv.Hash, c, waitChan, wire.BaseEncoding)
+		case wire.InvTypeCmpctBlock:
+			err = sp.server.pushCmpctBlockMsg(sp, &iv.Hash, c, waitChan)
 		case wire.InvTypeFilteredWitnessBlock:
 			err = sp.server.pushMerkleBlockMsg(sp, &iv.Hash, c, waitChan, wire.WitnessEncoding)
 		case wire.InvTypeFilteredBlock:
@@ -1621,6 +1808,59 @@ func (s *server) pushBlockMsg(sp *serverPeer, hash *chainhash.Hash, doneChan cha
 	return nil
 }
 
+// pushCmpctBlockMsg sends a cmpctblock message for the provided block hash to
+// the connected peer.  The full block is sent instead when the block is too
+// deep in the chain or the peer has not signalled support for compact blocks.
+// An error is returned if the block hash is not known.
+func (s *server) pushCmpctBlockMsg(sp *serverPeer, hash *chainhash.Hash,
+	doneChan chan<- struct{}, waitChan <-chan struct{}) error {
+
+	encoding := wire.BaseEncoding
+	if sp.IsWitnessEnabled() {
+		encoding = wire.WitnessEncoding
+	}
+
+	version := sp.CmpctBlockVersion()
+	height, err := s.chain.BlockHeightByHash(hash)
+	if version == 0 || err != nil ||
+		s.chain.BestSnapshot().Height-height >= maxCmpctBlockDepth {
+
+		return s.pushBlockMsg(sp, hash, doneChan, waitChan, encoding)
+	}
+
+	blk, err := s.chain.BlockByHash(hash)
+	if err != nil {
+		peerLog.Tracef("Unable to fetch requested block hash %v: %v",
+			hash, err)
+
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return err
+	}
+
+	nonce, err := wire.RandomUint64()
+	if err != nil {
+		if doneChan != nil {
+			doneChan <- struct{}{}
+		}
+		return err
+	}
+	cmpctBlock := wire.NewMsgCmpctBlockFromBlock(blk.MsgBlock(), nonce,
+		version)
+
+	// Once we have fetched data wait for any previous operation to finish.
+	if waitChan != nil {
+		<-waitChan
+	}
+
+	if version == wire.CmpctBlockVersion1 {
+		encoding = wire.BaseEncoding
+	}
+	sp.QueueMessageWithEncoding(cmpctBlock, doneChan, encoding)
+	return nil
+}
+
 // pushMerkleBlockMsg sends a merkleblock message for the provided block hash to
 // the connected peer.  Since a merkle block requires the peer to have a filter
 // loaded, this call will simply be ignored if there is no filter loaded.  An
@@ -1783,6 +2023,10 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 	// Signal the sync manager this peer is a new sync candidate.
 	s.syncManager.NewPeer(sp.Peer)
 
+	// Inform the peer of the minimum fee rate of the transactions we are
+	// interested in.
+	sp.maybeSendFeeFilter(int64(s.txMemPool.MinFeeRate()))
+
 	// Update the address manager and request known addresses from the
 	// remote peer for outbound connections. This is skipped when running on
 	// the simulation test network since it is only intended to connect to
@@ -1817,6 +2061,71 @@ func (s *server) handleAddPeerMsg(state *peerState, sp *serverPeer) bool {
 	return true
 }
 
+// useV2Transport returns whether an outbound connection to the address of the
+// passed connection request should attempt the BIP0324 v2 transport.  This is
+// the case for manually added peers and for addresses that advertised v2
+// support, unless a previous v2 handshake with the address failed.
+func (s *server) useV2Transport(c *connmgr.ConnReq) bool {
+	if !cfg.V2Transport {
+		return false
+	}
+
+	addr := c.Addr.String()
+
+	s.v2TransportMtx.Lock()
+	defer s.v2TransportMtx.Unlock()
+
+	_, advertised := s.v2TransportAddrs[addr]
+	delete(s.v2TransportAddrs, addr)
+	if s.isV1OnlyAddr(addr, time.Now()) {
+		return false
+	}
+	return advertised || c.Permanent
+}
+
+// isV1OnlyAddr returns whether the passed address rejected a v2 transport
+// handshake less than v1OnlyAddrTimeout before the passed time.  Expired
+// addresses are forgotten.
+//
+// This function MUST be called with the v2 transport lock held.
+func (s *server) isV1OnlyAddr(addr string, now time.Time) bool {
+	rejected, ok := s.v1OnlyAddrs[addr]
+	if !ok {
+		return false
+	}
+	if now.Sub(rejected) >= v1OnlyAddrTimeout {
+		delete(s.v1OnlyAddrs, addr)
+		return false
+	}
+	return true
+}
+
+// addV1OnlyAddr remembers that the passed address rejected a v2 transport
+// handshake at the passed time.  Expired addresses are pruned first, and the
+// oldest address is forgotten when maxV1OnlyAddrs addresses are remembered
+// already.
+//
+// This function MUST be called with the v2 transport lock held.
+func (s *server) addV1OnlyAddr(addr string, now time.Time) {
+	var oldestAddr string
+	var oldest time.Time
+	for a, rejected := range s.v1OnlyAddrs {
+		if now.Sub(rejected) >= v1OnlyAddrTimeout {
+			delete(s.v1OnlyAddrs, a)
+			continue
+		}
+		if oldestAddr == "" || rejected.Before(oldest) {
+			oldestAddr, oldest = a, rejected
+		}
+	}
+	if _, ok := s.v1OnlyAddrs[addr]; !ok &&
+		len(s.v1OnlyAddrs) >= maxV1OnlyAddrs {
+
+		delete(s.v1OnlyAddrs, oldestAddr)
+	}
+	s.v1OnlyAddrs[addr] = now
+}
+
 // handleDonePeerMsg deals with peers that have signalled they are done.  It is
 // invoked from the peerHandler goroutine.
 func (s *server) handleDonePeerMsg(state *peerState, sp *serverPeer) {
@@ -1833,11 +2142,28 @@ func (s *server) handleDonePeerMsg(state *peerState, sp *serverPeer) {
 	// our connection manager about the disconnection. This can happen if we
 	// process a peer's `done` message before its `add`.
 	if !sp.Inbound() {
+		// Remember peers that rejected the v2 transport handshake so
+		// that they're connected to using v1 from now on.
+		downgrade := sp.ShouldDowngradeToV1()
+		if downgrade {
+			srvrLog.Debugf("Peer %s does not support the v2 "+
+				"transport, reconnecting using v1", sp)
+			s.v2TransportMtx.Lock()
+			s.addV1OnlyAddr(sp.connReq.Addr.String(), time.Now())
+			s.v2TransportMtx.Unlock()
+		}
+
 		if sp.persistent {
 			s.connManager.Disconnect(sp.connReq.ID())
 		} else {
 			s.connManager.Remove(sp.connReq.ID())
-			go s.connManager.NewConnReq()
+			if downgrade {
+				go s.connManager.Connect(&connmgr.ConnReq{
+					Addr: sp.connReq.Addr,
+				})
+			} else {
+				go s.connManager.NewConnReq()
+			}
 		}
 	}
 
@@ -1868,21 +2194,67 @@ func (s *server) handleBanPeerMsg(state *peerState, sp *serverPeer) {
 // handleRelayInvMsg deals with relaying inventory to peers that are not already
 // known to have it.  It is invoked from the peerHandler goroutine.
 func (s *server) handleRelayInvMsg(state *peerState, msg relayMsg) {
+	// Compact blocks announced to peers in high-bandwidth mode are only
+	// created once per version and shared among all peers.
+	cmpctBlocks := make(map[uint64]*wire.MsgCmpctBlock)
+
 	state.forAllPeers(func(sp *serverPeer) {
 		if !sp.Connected() {
 			return
 		}
 
+		// If the inventory is a block and the peer requested compact
+		// block announcements (BIP0152 high-bandwidth mode), send the
+		// compact block directly.
+		version := sp.CmpctBlockVersion()
+		if msg.invVect.Type == wire.InvTypeBlock && version != 0 &&
+			sp.WantsCmpctBlocks() {
+
+			// Don't send the block to peers already known to have
+			// it, such as the peer that relayed it to us.
+			if sp.HasKnownInventory(msg.invVect) {
+				return
+			}
+
+			block, ok := msg.data.(*btcutil.Block)
+			if !ok {
+				peerLog.Warnf("Underlying data for compact " +
+					"block is not a block")
+				return
+			}
+			cmpctBlock, ok := cmpctBlocks[version]
+			if !ok {
+				nonce, err := wire.RandomUint64()
+				if err != nil {
+					peerLog.Errorf("Failed to generate "+
+						"compact block nonce: %v", err)
+					return
+				}
+				cmpctBlock = wire.NewMsgCmpctBlockFromBlock(
+					block.MsgBlock(), nonce, version)
+				cmpctBlocks[version] = cmpctBlock
+			}
+
+			encoding := wire.BaseEncoding
+			if version == wire.CmpctBlockVersion2 {
+				encoding = wire.WitnessEncoding
+			}
+			sp.AddKnownInventory(msg.invVect)
+			sp.QueueMessageWithEncoding(cmpctBlock, nil, encoding)
+			return
+		}
+
 		// If the inventory is a block and the peer prefers headers,
 		// generate and send a headers message instead of an inventory
 		// message.
 		if msg.invVect.Type == wire.InvTypeBlock && sp.WantsHeaders() {
-			blockHeader, ok := msg.data.(wire.BlockHeader)
+			block, ok := msg.data.(*btcutil.Block)
 			if !ok {
 				peerLog.Warnf("Underlying data for headers" +
-					" is not a block header")
+					" is not a block")
 				return
 			}
+			blockHeader := block.MsgBlock().Header
 			msgHeaders := wire.NewMsgHeaders()
 			if err := msgHeaders.AddBlockHeader(&blockHeader); err != nil {
 				peerLog.Errorf("Failed to add block"+
@@ -2129,6 +2501,9 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 			OnBlock:        sp.OnBlock,
 			OnInv:          sp.OnInv,
 			OnHeaders:      sp.OnHeaders,
+			OnCmpctBlock:   sp.OnCmpctBlock,
+			OnGetBlockTxn:  sp.OnGetBlockTxn,
+			OnBlockTxn:     sp.OnBlockTxn,
 			OnGetData:      sp.OnGetData,
 			OnGetBlocks:    sp.OnGetBlocks,
 			OnGetHeaders:   sp.OnGetHeaders,
@@ -2164,6 +2539,7 @@ func newPeerConfig(sp *serverPeer) *peer.Config {
 		ProtocolVersion:     peer.MaxProtocolVersion,
 		TrickleInterval:     cfg.TrickleInterval,
 		DisableStallHandler: cfg.DisableStallHandler,
+		V2Transport:         cfg.V2Transport,
 	}
 }
 
@@ -2186,7 +2562,9 @@ func (s *server) inboundPeerConnected(conn net.Conn) {
 // manager of the attempt.
 func (s *server) outboundPeerConnected(c *connmgr.ConnReq, conn net.Conn) {
 	sp := newServerPeer(s, c.Permanent)
-	p, err := peer.NewOutboundPeer(newPeerConfig(sp), c.Addr.String())
+	peerCfg := newPeerConfig(sp)
+	peerCfg.V2Transport = s.useV2Transport(c)
+	p, err := peer.NewOutboundPeer(peerCfg, c.Addr.String())
 	if err != nil {
 		srvrLog.Debugf("Cannot create outbound peer %s: %v", c.Addr, err)
 		if c.Permanent {
@@ -2261,6 +2639,9 @@ func (s *server) peerHandler() {
 	}
 	go s.connManager.Start()
 
+	feeFilterTicker := time.NewTicker(feeFilterCheckInterval)
+	defer feeFilterTicker.Stop()
+
 out:
 	for {
 		select {
@@ -2292,6 +2673,16 @@ out:
 		case qmsg := <-s.query:
 			s.handleQuery(state, qmsg)
 
+		// Update the fee filter of the peers as the minimum fee rate
+		// of the mempool changes.
+		case <-feeFilterTicker.C:
+			feeFilter := int64(s.txMemPool.MinFeeRate())
+			state.forAllPeers(func(sp *serverPeer) {
+				if sp.Connected() {
+					sp.maybeSendFeeFilter(feeFilter)
+				}
+			})
+
 		case <-s.quit:
 			// Disconnect all peers on server shutdown.
 			state.forAllPeers(func(sp *serverPeer) {
@@ -2491,6 +2882,58 @@ func (s *server) Start() {
 	if cfg.Generate {
 		s.cpuMiner.Start()
 	}
+
+	// Load the mempool saved on the last shutdown once the chain is synced.
+	if cfg.NoPersistMempool {
+		s.txMemPool.SetLoadTried(true)
+	} else {
+		s.wg.Add(1)
+		go s.loadMempoolHandler()
+	}
+}
+
+// mempoolDumpPath returns the path of the file the mempool is saved to on
+// shutdown and loaded from on startup.
+func mempoolDumpPath() string {
+	return filepath.Join(cfg.DataDir, mempoolDumpFilename)
+}
+
+// loadMempoolHandler loads the transactions saved on the last shutdown into
+// the mempool.  Loading is deferred until the chain is synced since the
+// mempool is not updated as blocks are connected before then, so the
+// transactions are validated against the current chain.  It must be run as a
+// goroutine.
+func (s *server) loadMempoolHandler() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(mempoolLoadCheckInterval)
+	defer ticker.Stop()
+	for !s.syncManager.IsCurrent() {
+		select {
+		case <-ticker.C:
+		case <-s.quit:
+			return
+		}
+	}
+
+	path := mempoolDumpPath()
+	stats, err := s.txMemPool.LoadFromFile(path, false, s.quit)
+	switch {
+	case errors.Is(err, mempool.ErrLoadInterrupted):
+		return
+
+	case os.IsNotExist(err):
+
+	case err != nil:
+		srvrLog.Errorf("Unable to load mempool from %s: %v", path, err)
+
+	default:
+		srvrLog.Infof("Loaded mempool from %s: %d succeeded, %d "+
+			"failed, %d already there", path, stats.Succeeded,
+			stats.Failed, stats.AlreadyThere)
+	}
+
+	s.txMemPool.SetLoadTried(true)
 }
 
 // Stop gracefully shuts down the server by stopping and disconnecting all
@@ -2512,6 +2955,20 @@ func (s *server) Stop() error {
 		s.rpcServer.Stop()
 	}
 
+	// Save the mempool so it can be loaded on the next startup.  It is not
+	// saved when it was not loaded yet to avoid losing the transactions of
+	// the previous dump.
+	if !cfg.NoPersistMempool && s.txMemPool.LoadTried() {
+		path := mempoolDumpPath()
+		if err := s.txMemPool.DumpToFile(path); err != nil {
+			srvrLog.Errorf("Unable to save mempool to %s: %v", path,
+				err)
+		} else {
+			srvrLog.Infof("Saved %d mempool transactions to %s",
+				s.txMemPool.Count(), path)
+		}
+	}
+
 	// Save fee estimator state in the database.
 	s.db.Update(func(tx database.Tx) error {
 		metadata := tx.Metadata()
@@ -2520,11 +2977,32 @@ func (s *server) Stop() error {
 		return nil
 	})
 
+	// Disconnect ZeroMQ subscribers if needed.
+	if s.zmqNotifier != nil {
+		s.zmqNotifier.Stop()
+	}
+
 	// Signal the remaining goroutines to quit.
 	close(s.quit)
 	return nil
 }
 
+// handleZMQNotification publishes the block connected and disconnected
+// notifications from the chain over ZeroMQ.
+func (s *server) handleZMQNotification(notification *blockchain.Notification) {
+	switch notification.Type {
+	case blockchain.NTBlockConnected:
+		if block, ok := notification.Data.(*btcutil.Block); ok {
+			s.zmqNotifier.BlockConnected(block)
+		}
+
+	case blockchain.NTBlockDisconnected:
+		if block, ok := notification.Data.(*btcutil.Block); ok {
+			s.zmqNotifier.BlockDisconnected(block)
+		}
+	}
+}
+
 // WaitForShutdown blocks until the main listener and peer handlers are stopped.
 func (s *server) WaitForShutdown() {
 	s.wg.Wait()
@@ -2734,6 +3212,9 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	if cfg.Prune != 0 {
 		services &^= wire.SFNodeNetwork
 	}
+	if cfg.V2Transport {
+		services |= wire.SFNodeP2PV2
+	}
 
 	amgr := addrmgr.New(cfg.DataDir, btcdLookup)
 
@@ -2778,6 +3259,8 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		cfCheckptCaches:      make(map[wire.FilterType][]cfHeaderKV),
 		agentBlacklist:       agentBlacklist,
 		agentWhitelist:       agentWhitelist,
+		v2TransportAddrs:     make(map[string]struct{}),
+		v1OnlyAddrs:          make(map[string]time.Time),
 	}
 
 	// Create the transaction and address indexes if needed.
@@ -2811,11 +3294,17 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		s.cfIndex = indexers.NewCfIndex(db, chainParams)
 		indexes = append(indexes, s.cfIndex)
 	}
+	if cfg.CoinStatsIndex {
+		indxLog.Info("Coin statistics index is enabled")
+		s.coinStatsIndex = indexers.NewCoinStatsIndex(db)
+		indexes = append(indexes, s.coinStatsIndex)
+	}
 
 	// Create an index manager if any of the optional indexes are enabled.
 	var indexManager blockchain.IndexManager
 	if len(indexes) > 0 {
-		indexManager = indexers.NewManager(db, indexes)
+		s.indexManager = indexers.NewManager(db, indexes)
+		indexManager = s.indexManager
 	}
 
 	// Merge given checkpoints with the default ones unless they are disabled.
@@ -2836,6 +3325,7 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		Interrupt:        interrupt,
 		ChainParams:      s.chainParams,
 		Checkpoints:      checkpoints,
+		AssumeValid:      cfg.assumeValid,
 		TimeSource:       s.timeSource,
 		SigCache:         s.sigCache,
 		IndexManager:     indexManager,
@@ -2846,6 +3336,35 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 	if err != nil {
 		return nil, err
 	}
+	if assumeValid := s.chain.AssumeValid(); assumeValid != nil {
+		srvrLog.Infof("Assuming ancestors of block %v have valid "+
+			"scripts", assumeValid)
+	} else {
+		srvrLog.Infof("Verifying the scripts of all blocks")
+	}
+
+	// Publish ZeroMQ notifications if any of the endpoints are configured.
+	// The notifier subscribes to the chain before the sync manager so that
+	// block disconnections are published before the transactions they
+	// contain are added back to the mempool.
+	zmqCfg := zmq.Config{
+		HashBlock:     cfg.ZMQPubHashBlock,
+		HashTx:        cfg.ZMQPubHashTx,
+		RawBlock:      cfg.ZMQPubRawBlock,
+		RawTx:         cfg.ZMQPubRawTx,
+		Sequence:      cfg.ZMQPubSequence,
+		HighWaterMark: cfg.ZMQPubHWM,
+	}
+	if zmqCfg.HashBlock != "" || zmqCfg.HashTx != "" ||
+		zmqCfg.RawBlock != "" || zmqCfg.RawTx != "" ||
+		zmqCfg.Sequence != "" {
+
+		s.zmqNotifier, err = zmq.New(&zmqCfg)
+		if err != nil {
+			return nil, err
+		}
+		s.chain.Subscribe(s.handleZMQNotification)
+	}
 
 	// Search for a FeeEstimator state in the database. If none can be found
 	// or if it cannot be loaded, create a new one.
@@ -2886,8 +3405,13 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 			MaxOrphanTxSize:      defaultMaxOrphanTxSize,
 			MaxSigOpCostPerTx:    blockchain.MaxBlockSigOpsCost / 4,
 			MinRelayTxFee:        cfg.minRelayTxFee,
-			MaxTxVersion:         2,
+			MaxTxVersion:         mempool.TrucVersion,
 			RejectReplacement:    cfg.RejectReplacement,
+			MaxPoolSize:          cfg.MaxMempool * 1000000,
+			MaxAncestorCount:     cfg.LimitAncestorCount,
+			MaxAncestorSize:      cfg.LimitAncestorSize * 1000,
+			MaxDescendantCount:   cfg.LimitDescendantCount,
+			MaxDescendantSize:    cfg.LimitDescendantSize * 1000,
 		},
 		ChainParams:    chainParams,
 		FetchUtxoView:  s.chain.FetchUtxoView,
@@ -2902,6 +3426,10 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		AddrIndex:          s.addrIndex,
 		FeeEstimator:       s.feeEstimator,
 	}
+	if s.zmqNotifier != nil {
+		txC.OnTxAdded = s.zmqNotifier.TransactionAdded
+		txC.OnTxRemoved = s.zmqNotifier.TransactionRemoved
+	}
 	s.txMemPool = mempool.New(&txC)
 
 	s.syncManager, err = netsync.New(&netsync.Config{
@@ -2909,6 +3437,7 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		Chain:              s.chain,
 		TxMemPool:          s.txMemPool,
 		ChainParams:        s.chainParams,
+		TimeSource:         s.timeSource,
 		DisableCheckpoints: cfg.DisableCheckpoints,
 		MaxPeers:           cfg.MaxPeers,
 		FeeEstimator:       s.feeEstimator,
@@ -2984,7 +3513,22 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 				s.addrManager.Attempt(addr.NetAddress())
 
 				addrString := addrmgr.NetAddressKey(addr.NetAddress())
-				return addrStringToNetAddr(addrString)
+				netAddr, err := addrStringToNetAddr(addrString)
+				if err != nil {
+					return nil, err
+				}
+
+				// Remember addresses that advertise v2 transport
+				// support so the connection attempts it.
+				if cfg.V2Transport && addr.NetAddress().HasService(
+					wire.SFNodeP2PV2) {
+
+					s.v2TransportMtx.Lock()
+					s.v2TransportAddrs[netAddr.String()] = struct{}{}
+					s.v2TransportMtx.Unlock()
+				}
+
+				return netAddr, nil
 			}
 
 			return nil, errors.New("no valid connect address")
@@ -3039,21 +3583,24 @@ func newServer(listenAddrs, agentBlacklist, agentWhitelist []string,
 		}
 
 		s.rpcServer, err = newRPCServer(&rpcserverConfig{
-			Listeners:    rpcListeners,
-			StartupTime:  s.startupTime,
-			ConnMgr:      &rpcConnManager{&s},
-			SyncMgr:      &rpcSyncMgr{&s, s.syncManager},
-			TimeSource:   s.timeSource,
-			Chain:        s.chain,
-			ChainParams:  chainParams,
-			DB:           db,
-			TxMemPool:    s.txMemPool,
-			Generator:    blockTemplateGenerator,
-			CPUMiner:     s.cpuMiner,
-			TxIndex:      s.txIndex,
-			AddrIndex:    s.addrIndex,
-			CfIndex:      s.cfIndex,
-			FeeEstimator: s.feeEstimator,
+			Listeners:      rpcListeners,
+			StartupTime:    s.startupTime,
+			ConnMgr:        &rpcConnManager{&s},
+			SyncMgr:        &rpcSyncMgr{&s, s.syncManager},
+			TimeSource:     s.timeSource,
+			Chain:          s.chain,
+			ChainParams:    chainParams,
+			DB:             db,
+			TxMemPool:      s.txMemPool,
+			Generator:      blockTemplateGenerator,
+			CPUMiner:       s.cpuMiner,
+			TxIndex:        s.txIndex,
+			AddrIndex:      s.addrIndex,
+			CfIndex:        s.cfIndex,
+			CoinStatsIndex: s.coinStatsIndex,
+			IndexManager:   s.indexManager,
+			FeeEstimator:   s.feeEstimator,
+			ZMQNotifier:    s.zmqNotifier,
 		})
 		if err != nil {
 			return nil, err
@@ -0,0 +1,52 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestV1OnlyAddrs ensures the addresses that rejected a v2 transport handshake
+// are forgotten once they expire and that no more than maxV1OnlyAddrs
+// addresses are remembered.
+func TestV1OnlyAddrs(t *testing.T) {
+	t.Parallel()
+
+	s := &server{v1OnlyAddrs: make(map[string]time.Time)}
+	now := time.Unix(1700000000, 0)
+
+	s.addV1OnlyAddr("1.2.3.4:8333", now)
+	if !s.isV1OnlyAddr("1.2.3.4:8333", now.Add(v1OnlyAddrTimeout-1)) {
+		t.Fatal("expected address to be v1 only before it expires")
+	}
+	if s.isV1OnlyAddr("1.2.3.4:8333", now.Add(v1OnlyAddrTimeout)) {
+		t.Fatal("expected address to expire")
+	}
+	if len(s.v1OnlyAddrs) != 0 {
+		t.Fatalf("got %d addresses, want expired address to be "+
+			"forgotten", len(s.v1OnlyAddrs))
+	}
+
+	// Adding more than the maximum number of addresses forgets the oldest
+	// one, while expired addresses are pruned when adding one.
+	for i := 0; i <= maxV1OnlyAddrs; i++ {
+		addr := fmt.Sprintf("10.0.%d.%d:8333", i/256, i%256)
+		s.addV1OnlyAddr(addr, now.Add(time.Duration(i)*time.Second))
+	}
+	if len(s.v1OnlyAddrs) != maxV1OnlyAddrs {
+		t.Fatalf("got %d addresses, want %d", len(s.v1OnlyAddrs),
+			maxV1OnlyAddrs)
+	}
+	if _, ok := s.v1OnlyAddrs["10.0.0.0:8333"]; ok {
+		t.Fatal("expected the oldest address to be forgotten")
+	}
+	s.addV1OnlyAddr("1.2.3.4:8333", now.Add(v1OnlyAddrTimeout*2))
+	if len(s.v1OnlyAddrs) != 1 {
+		t.Fatalf("got %d addresses, want expired addresses to be "+
+			"pruned", len(s.v1OnlyAddrs))
+	}
+}
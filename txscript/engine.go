@@ -152,6 +152,9 @@ const (
 	TaprootWitnessVersion = 1
 )
 
+// payToAnchorProgram is the witness program of a pay-to-anchor output.
+var payToAnchorProgram = []byte{0x4e, 0x73}
+
 // halforder is used to tame ECDSA malleability (see BIP0062).
 var halfOrder = new(big.Int).Rsh(btcec.S256().N, 1)
 
@@ -760,6 +763,16 @@ func (vm *Engine) verifyWitnessProgram(witness wire.TxWitness) error {
 			vm.SetStack(witness[:len(witness)-2])
 		}
 
+	// Pay-to-anchor outputs can be spent by anyone, so they are exempt
+	// from the policy discouraging the use of new witness programs.  Only
+	// the witness version is left on the stack so the spend also passes
+	// the clean stack check.
+	case vm.isWitnessVersionActive(TaprootWitnessVersion) &&
+		bytes.Equal(vm.witnessProgram, payToAnchorProgram) && !vm.bip16:
+
+		vm.witnessProgram = nil
+		vm.SetStack([][]byte{{TaprootWitnessVersion}})
+
 	case vm.hasFlag(ScriptVerifyDiscourageUpgradeableWitnessProgram):
 		errStr := fmt.Sprintf("new witness program versions "+
 			"invalid: %v", vm.witnessProgram)
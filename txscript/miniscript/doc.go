@@ -0,0 +1,49 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package miniscript implements miniscript (BIP0379), a language for writing a
+subset of bitcoin scripts in a structured way which enables analysis,
+composition and generic signing.
+
+# Overview
+
+A miniscript expression such as and_v(v:pk(K1),or_d(pk(K2),older(144)))
+describes a spending policy which compiles to a witness script.  Parse parses
+an expression for either P2WSH or tapscript, and type checks it to ensure the
+resulting script is correct.  Lift does the opposite and recovers the
+expression of a script which is the encoding of a miniscript.
+
+A parsed Node compiles to its script with Script, and reports the maximum size
+of the witness which satisfies it with MaxWitnessSize.  CheckSanity reports
+whether a miniscript can be safely used by a wallet, which requires it to
+always need a signature to be spent, to only have non-malleable satisfactions,
+to not mix height and time based timelocks, and to stay within the resource
+limits of its context.
+
+# Fragments
+
+The following fragments and wrappers are supported, where KEY is a hex encoded
+compressed public key for P2WSH and a hex encoded x-only public key for
+tapscript:
+
+  - 0 and 1
+  - pk_k(KEY), pk_h(KEY), pk(KEY) and pkh(KEY)
+  - older(n) and after(n)
+  - sha256(H), hash256(H), ripemd160(H) and hash160(H)
+  - andor(X,Y,Z), and_v(X,Y), and_b(X,Y) and and_n(X,Y)
+  - or_b(X,Z), or_c(X,Z), or_d(X,Z) and or_i(X,Z)
+  - thresh(k,X1,...,Xn)
+  - multi(k,KEY1,...,KEYn) for P2WSH and multi_a(k,KEY1,...,KEYn) for tapscript
+  - the wrappers a:, s:, c:, t:, d:, v:, j:, n:, l: and u:
+
+# Satisfaction
+
+Satisfy produces the witness stack which satisfies a miniscript given the
+signatures, hash preimages and timelocks provided by a Satisfier.  Only
+non-malleable satisfactions which include a signature are produced, so a third
+party can't change the witness of a transaction to spend the output in a
+different way.
+*/
+package miniscript
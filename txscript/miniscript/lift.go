@@ -0,0 +1,589 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package miniscript
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/bynil/btcd/btcec/v2"
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/txscript"
+)
+
+// token is an opcode of a script along with the data it pushes.
+type token struct {
+	op   byte
+	data []byte
+}
+
+// isPush returns whether the token pushes the passed number of bytes.
+func (t token) isPush(size int) bool {
+	return t.op <= txscript.OP_PUSHDATA4 && len(t.data) == size
+}
+
+// decoder lifts a script to miniscript.  Since the type of an expression is
+// determined by how it ends, scripts are decoded from their last opcode.
+type decoder struct {
+	ctx Context
+
+	// tokens are the opcodes of the script, of which the first pos are
+	// yet to be decoded.
+	tokens []token
+	pos    int
+
+	// keys are the keys pk_h() fragments might refer to, keyed by their
+	// hash.
+	keys map[string]*btcec.PublicKey
+}
+
+// Lift recovers the miniscript the passed script is the encoding of in the
+// passed context.  Since the script of a pk_h() fragment only commits to the
+// hash of its key, the keys of those fragments must be passed.
+func Lift(script []byte, ctx Context, keys []*btcec.PublicKey) (*Node, error) {
+	if ctx != P2WSH && ctx != Tapscript {
+		return nil, fmt.Errorf("unknown context %v", ctx)
+	}
+
+	d := &decoder{ctx: ctx, keys: make(map[string]*btcec.PublicKey)}
+	tokenizer := txscript.MakeScriptTokenizer(0, script)
+	for tokenizer.Next() {
+		d.tokens = append(d.tokens, token{
+			op:   tokenizer.Opcode(),
+			data: tokenizer.Data(),
+		})
+	}
+	if err := tokenizer.Err(); err != nil {
+		return nil, err
+	}
+	d.pos = len(d.tokens)
+
+	tmp := &Node{ctx: ctx}
+	for _, key := range keys {
+		d.keys[string(btcutil.Hash160(tmp.serializeKey(key)))] = key
+	}
+
+	n, err := d.decodeExpr()
+	if err != nil {
+		return nil, err
+	}
+	if d.pos != 0 {
+		return nil, fmt.Errorf("unexpected opcode %s before the "+
+			"expression", d.peekName())
+	}
+	if err := n.checkTopLevel(); err != nil {
+		return nil, err
+	}
+
+	// Only scripts which are the exact encoding of the miniscript, such as
+	// those with minimal pushes, are miniscripts.
+	if !bytes.Equal(n.Script(), script) {
+		return nil, errors.New("script is not the encoding of a " +
+			"miniscript")
+	}
+	return n, nil
+}
+
+// peek returns the opcode of the next token to decode, or OP_INVALIDOPCODE
+// when all tokens are decoded.
+func (d *decoder) peek(i int) byte {
+	if d.pos-1-i < 0 {
+		return txscript.OP_INVALIDOPCODE
+	}
+	return d.tokens[d.pos-1-i].op
+}
+
+// peekName returns the name of the opcode of the next token to decode.
+func (d *decoder) peekName() string {
+	if d.pos == 0 {
+		return "start of script"
+	}
+	return opcodeName(d.peek(0))
+}
+
+// opcodeName returns the name of the passed opcode.
+func opcodeName(op byte) string {
+	if op > txscript.OP_0 && op <= txscript.OP_PUSHDATA4 {
+		return "data push"
+	}
+	name, _ := txscript.DisasmString([]byte{op})
+	return name
+}
+
+// next returns the next token to decode and marks it as decoded.
+func (d *decoder) next() (token, error) {
+	if d.pos == 0 {
+		return token{}, errors.New("unexpected start of script")
+	}
+	d.pos--
+	return d.tokens[d.pos], nil
+}
+
+// expect marks the next token as decoded, returning an error unless it has
+// the passed opcode.
+func (d *decoder) expect(op byte) error {
+	if d.peek(0) != op {
+		return fmt.Errorf("expected %s, got %s", opcodeName(op),
+			d.peekName())
+	}
+	d.pos--
+	return nil
+}
+
+// number decodes the next token as a positive number.
+func (d *decoder) number() (uint32, error) {
+	t, err := d.next()
+	if err != nil {
+		return 0, err
+	}
+	switch {
+	case t.op >= txscript.OP_1 && t.op <= txscript.OP_16:
+		return uint32(t.op-txscript.OP_1) + 1, nil
+
+	case t.op <= txscript.OP_PUSHDATA4 && len(t.data) > 0 &&
+		len(t.data) <= 5:
+
+		// Numbers are little endian with a sign bit, and must be
+		// positive and fit in 32 bits.
+		var v uint64
+		for i, b := range t.data {
+			v |= uint64(b) << (8 * i)
+		}
+		if t.data[len(t.data)-1]&0x80 == 0 && v > 0 && v <= 1<<32-1 {
+			return uint32(v), nil
+		}
+	}
+	return 0, errors.New("expected a positive number")
+}
+
+// key decodes the next token as a key.
+func (d *decoder) key() (*btcec.PublicKey, error) {
+	t, err := d.next()
+	if err != nil {
+		return nil, err
+	}
+	if !t.isPush(d.keySize()) {
+		return nil, errors.New("expected a key")
+	}
+	return parseKeyBytes(t.data, d.ctx)
+}
+
+// keySize returns the size of the keys in the context of the decoder.
+func (d *decoder) keySize() int {
+	if d.ctx == Tapscript {
+		return 32
+	}
+	return 33
+}
+
+// atBoundary returns whether the next token ends the expression being decoded,
+// which is when it can't be the last opcode of another expression.
+func (d *decoder) atBoundary() bool {
+	switch d.peek(0) {
+	case txscript.OP_INVALIDOPCODE, txscript.OP_IF, txscript.OP_NOTIF,
+		txscript.OP_ELSE, txscript.OP_TOALTSTACK, txscript.OP_SWAP:
+
+		return true
+	}
+	return false
+}
+
+// decodeExpr decodes an expression of type B, V or K, which is a sequence of
+// expressions joined by and_v().
+func (d *decoder) decodeExpr() (*Node, error) {
+	var exprs []*Node
+	for {
+		n, err := d.decodeSingle()
+		if err != nil {
+			return nil, err
+		}
+		exprs = append(exprs, n)
+		if d.atBoundary() {
+			break
+		}
+	}
+
+	// The expressions were decoded from the last, and are joined from
+	// the right as and_v(X,and_v(Y,Z)).
+	n := exprs[0]
+	for _, expr := range exprs[1:] {
+		var err error
+		n, err = newNode(fragAndV, d.ctx, expr, n)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// decodeWrapped decodes an expression of type W, which is a:X or s:X.
+func (d *decoder) decodeWrapped() (*Node, error) {
+	if d.peek(0) == txscript.OP_FROMALTSTACK {
+		d.pos--
+		x, err := d.decodeExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := d.expect(txscript.OP_TOALTSTACK); err != nil {
+			return nil, err
+		}
+		return newNode(fragWrapA, d.ctx, x)
+	}
+
+	x, err := d.decodeExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := d.expect(txscript.OP_SWAP); err != nil {
+		return nil, err
+	}
+	return newNode(fragWrapS, d.ctx, x)
+}
+
+// decodeSingle decodes the last expression of a sequence of expressions
+// joined by and_v().  The first argument of and_b, or_b, or_c, or_d, andor and
+// thresh is decoded this way too, so a preceding sequence is joined around the
+// combinator rather than within its argument.
+func (d *decoder) decodeSingle() (*Node, error) {
+	// wrap decodes the next expression and returns it wrapped in the
+	// wrapper with the passed fragment.
+	wrap := func(f fragment) (*Node, error) {
+		x, err := d.decodeSingle()
+		if err != nil {
+			return nil, err
+		}
+		return newNode(f, d.ctx, x)
+	}
+
+	op := d.peek(0)
+	switch {
+	case d.pos == 0:
+		return nil, errors.New("unexpected start of script")
+
+	case op == txscript.OP_0:
+		d.pos--
+		return newNode(fragFalse, d.ctx)
+
+	case op == txscript.OP_1:
+		d.pos--
+		return newNode(fragTrue, d.ctx)
+
+	case d.tokens[d.pos-1].isPush(d.keySize()):
+		key, err := d.key()
+		if err != nil {
+			return nil, err
+		}
+		n := &Node{fragment: fragPkK, ctx: d.ctx,
+			keys: []*btcec.PublicKey{key}}
+		return n, n.check()
+
+	case op == txscript.OP_EQUALVERIFY && d.peek(3) == txscript.OP_DUP &&
+		d.peek(2) == txscript.OP_HASH160 &&
+		d.tokens[d.pos-2].isPush(20):
+
+		hash := d.tokens[d.pos-2].data
+		key, ok := d.keys[string(hash)]
+		if !ok {
+			return nil, fmt.Errorf("no key with hash %x for pk_h()",
+				hash)
+		}
+		d.pos -= 4
+		n := &Node{fragment: fragPkH, ctx: d.ctx,
+			keys: []*btcec.PublicKey{key}}
+		return n, n.check()
+
+	case op == txscript.OP_CHECKSEQUENCEVERIFY ||
+		op == txscript.OP_CHECKLOCKTIMEVERIFY:
+
+		d.pos--
+		k, err := d.number()
+		if err != nil {
+			return nil, err
+		}
+		f := fragOlder
+		if op == txscript.OP_CHECKLOCKTIMEVERIFY {
+			f = fragAfter
+		}
+		n := &Node{fragment: f, ctx: d.ctx, k: k}
+		return n, n.check()
+
+	case op == txscript.OP_EQUAL && (d.pos >= 2 &&
+		(d.tokens[d.pos-2].isPush(32) || d.tokens[d.pos-2].isPush(20))):
+
+		return d.decodeHash()
+
+	case op == txscript.OP_EQUAL:
+		return d.decodeThresh()
+
+	case op == txscript.OP_CHECKMULTISIG && d.ctx == P2WSH:
+		return d.decodeMulti()
+
+	case op == txscript.OP_NUMEQUAL && d.ctx == Tapscript:
+		return d.decodeMultiA()
+
+	case op == txscript.OP_CHECKSIG:
+		d.pos--
+		return wrap(fragWrapC)
+
+	case op == txscript.OP_VERIFY:
+		d.pos--
+		return wrap(fragWrapV)
+
+	case op == txscript.OP_CHECKSIGVERIFY ||
+		op == txscript.OP_CHECKMULTISIGVERIFY ||
+		op == txscript.OP_EQUALVERIFY || op == txscript.OP_NUMEQUALVERIFY:
+
+		// The verification of v: is merged into the last opcode of
+		// the expression it wraps, so decode it as that opcode.
+		for plain, verify := range verifyOpcodes {
+			if verify == op {
+				d.tokens[d.pos-1].op = plain
+			}
+		}
+		return wrap(fragWrapV)
+
+	case op == txscript.OP_0NOTEQUAL:
+		d.pos--
+		return wrap(fragWrapN)
+
+	case op == txscript.OP_BOOLAND || op == txscript.OP_BOOLOR:
+		d.pos--
+		y, err := d.decodeWrapped()
+		if err != nil {
+			return nil, err
+		}
+		x, err := d.decodeSingle()
+		if err != nil {
+			return nil, err
+		}
+		f := fragAndB
+		if op == txscript.OP_BOOLOR {
+			f = fragOrB
+		}
+		return newNode(f, d.ctx, x, y)
+
+	case op == txscript.OP_ENDIF:
+		return d.decodeIf()
+	}
+
+	return nil, fmt.Errorf("unexpected opcode %s", d.peekName())
+}
+
+// decodeHash decodes a hash fragment, which is SIZE 32 EQUALVERIFY followed by
+// the hash opcode and the hash along with EQUAL.
+func (d *decoder) decodeHash() (*Node, error) {
+	d.pos--
+	hash := d.tokens[d.pos-1].data
+	d.pos--
+
+	var f fragment
+	found := false
+	for hf, op := range hashOpcodes {
+		if op == d.peek(0) {
+			f, found = hf, true
+		}
+	}
+	size := 32
+	if f == fragRipemd160 || f == fragHash160 {
+		size = 20
+	}
+	if !found || len(hash) != size {
+		return nil, fmt.Errorf("unexpected hash opcode %s", d.peekName())
+	}
+	d.pos--
+
+	if err := d.expect(txscript.OP_EQUALVERIFY); err != nil {
+		return nil, err
+	}
+	if v, err := d.number(); err != nil || v != 32 {
+		return nil, errors.New("expected the preimage size 32")
+	}
+	if err := d.expect(txscript.OP_SIZE); err != nil {
+		return nil, err
+	}
+
+	n := &Node{fragment: f, ctx: d.ctx, data: hash}
+	return n, n.check()
+}
+
+// decodeThresh decodes a thresh() fragment, which is its first subexpression
+// followed by the others each followed by ADD, and then the threshold along
+// with EQUAL.
+func (d *decoder) decodeThresh() (*Node, error) {
+	d.pos--
+	k, err := d.number()
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []*Node
+	for d.peek(0) == txscript.OP_ADD {
+		d.pos--
+		sub, err := d.decodeWrapped()
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	first, err := d.decodeSingle()
+	if err != nil {
+		return nil, err
+	}
+	subs = append(subs, first)
+
+	// The subexpressions were decoded from the last.
+	for i, j := 0, len(subs)-1; i < j; i, j = i+1, j-1 {
+		subs[i], subs[j] = subs[j], subs[i]
+	}
+	if int(k) > len(subs) {
+		return nil, fmt.Errorf("thresh() threshold %d exceeds the "+
+			"number of subexpressions %d", k, len(subs))
+	}
+
+	n := &Node{fragment: fragThresh, ctx: d.ctx, k: k, subs: subs}
+	return n, n.check()
+}
+
+// decodeMulti decodes a multi() fragment, which is the threshold followed by
+// the keys, their number and CHECKMULTISIG.
+func (d *decoder) decodeMulti() (*Node, error) {
+	d.pos--
+	numKeys, err := d.number()
+	if err != nil {
+		return nil, err
+	}
+	if numKeys > txscript.MaxPubKeysPerMultiSig {
+		return nil, fmt.Errorf("multi() has %d keys, more than the "+
+			"maximum of %d", numKeys, txscript.MaxPubKeysPerMultiSig)
+	}
+
+	keys := make([]*btcec.PublicKey, numKeys)
+	for i := len(keys) - 1; i >= 0; i-- {
+		keys[i], err = d.key()
+		if err != nil {
+			return nil, err
+		}
+	}
+	k, err := d.number()
+	if err != nil {
+		return nil, err
+	}
+	if k > numKeys {
+		return nil, fmt.Errorf("multi() threshold %d exceeds the "+
+			"number of keys %d", k, numKeys)
+	}
+
+	n := &Node{fragment: fragMulti, ctx: d.ctx, k: k, keys: keys}
+	return n, n.check()
+}
+
+// decodeMultiA decodes a multi_a() fragment, which is the first key followed
+// by CHECKSIG, the other keys each followed by CHECKSIGADD, and then the
+// threshold along with NUMEQUAL.
+func (d *decoder) decodeMultiA() (*Node, error) {
+	d.pos--
+	k, err := d.number()
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []*btcec.PublicKey
+	for d.peek(0) == txscript.OP_CHECKSIGADD {
+		d.pos--
+		key, err := d.key()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := d.expect(txscript.OP_CHECKSIG); err != nil {
+		return nil, err
+	}
+	key, err := d.key()
+	if err != nil {
+		return nil, err
+	}
+	keys = append(keys, key)
+
+	// The keys were decoded from the last.
+	for i, j := 0, len(keys)-1; i < j; i, j = i+1, j-1 {
+		keys[i], keys[j] = keys[j], keys[i]
+	}
+	if len(keys) > maxMultiAKeys || int(k) > len(keys) {
+		return nil, fmt.Errorf("multi_a() has an invalid threshold %d "+
+			"of %d keys", k, len(keys))
+	}
+
+	n := &Node{fragment: fragMultiA, ctx: d.ctx, k: k, keys: keys}
+	return n, n.check()
+}
+
+// decodeIf decodes the fragments ending in ENDIF, which are d:, j:, or_c(),
+// or_d(), or_i() and andor().
+func (d *decoder) decodeIf() (*Node, error) {
+	d.pos--
+	last, err := d.decodeExpr()
+	if err != nil {
+		return nil, err
+	}
+
+	switch d.peek(0) {
+	case txscript.OP_ELSE:
+		// IF X ELSE Z ENDIF is or_i(X,Z), and X NOTIF Z ELSE Y ENDIF
+		// is andor(X,Y,Z).
+		d.pos--
+		first, err := d.decodeExpr()
+		if err != nil {
+			return nil, err
+		}
+		if d.peek(0) == txscript.OP_IF {
+			d.pos--
+			return newNode(fragOrI, d.ctx, first, last)
+		}
+		if err := d.expect(txscript.OP_NOTIF); err != nil {
+			return nil, err
+		}
+		x, err := d.decodeSingle()
+		if err != nil {
+			return nil, err
+		}
+		return newNode(fragAndOr, d.ctx, x, last, first)
+
+	case txscript.OP_IF:
+		// DUP IF X ENDIF is d:X, and SIZE 0NOTEQUAL IF X ENDIF is j:X.
+		d.pos--
+		if d.peek(0) == txscript.OP_DUP {
+			d.pos--
+			return newNode(fragWrapD, d.ctx, last)
+		}
+		if d.peek(0) == txscript.OP_0NOTEQUAL &&
+			d.peek(1) == txscript.OP_SIZE {
+
+			d.pos -= 2
+			return newNode(fragWrapJ, d.ctx, last)
+		}
+		return nil, fmt.Errorf("unexpected opcode %s before IF",
+			d.peekName())
+
+	case txscript.OP_NOTIF:
+		// X IFDUP NOTIF Z ENDIF is or_d(X,Z), and X NOTIF Z ENDIF is
+		// or_c(X,Z).
+		d.pos--
+		f := fragOrC
+		if d.peek(0) == txscript.OP_IFDUP {
+			d.pos--
+			f = fragOrD
+		}
+		x, err := d.decodeSingle()
+		if err != nil {
+			return nil, err
+		}
+		return newNode(f, d.ctx, x, last)
+	}
+
+	return nil, fmt.Errorf("unexpected opcode %s before ENDIF",
+		d.peekName())
+}
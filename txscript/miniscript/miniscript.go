@@ -0,0 +1,860 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package miniscript
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bynil/btcd/btcec/v2"
+	"github.com/bynil/btcd/btcec/v2/schnorr"
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/txscript"
+)
+
+const (
+	// maxMultiAKeys is the maximum number of keys of a multi_a() fragment.
+	maxMultiAKeys = 999
+
+	// maxStandardP2WSHScriptSize is the maximum size of a witness script
+	// which is standard.
+	maxStandardP2WSHScriptSize = 3600
+
+	// maxStandardP2WSHStackItems is the maximum number of witness stack
+	// items, excluding the witness script, which is standard.
+	maxStandardP2WSHStackItems = 100
+)
+
+// Context is the script context a miniscript is used in.
+type Context uint8
+
+const (
+	// P2WSH is the context of a witness script of a P2WSH output.
+	P2WSH Context = iota
+
+	// Tapscript is the context of a leaf script of a taproot output.
+	Tapscript
+)
+
+// String returns the context as a human-readable name.
+func (c Context) String() string {
+	switch c {
+	case P2WSH:
+		return "P2WSH"
+	case Tapscript:
+		return "Tapscript"
+	}
+	return "Unknown Context (" + strconv.Itoa(int(c)) + ")"
+}
+
+// fragment identifies a miniscript fragment.  Aliases such as pk(), and_n()
+// and the t:, l: and u: wrappers are represented by the fragments they stand
+// for.
+type fragment uint8
+
+const (
+	fragFalse fragment = iota
+	fragTrue
+	fragPkK
+	fragPkH
+	fragOlder
+	fragAfter
+	fragSha256
+	fragHash256
+	fragRipemd160
+	fragHash160
+	fragWrapA
+	fragWrapS
+	fragWrapC
+	fragWrapD
+	fragWrapV
+	fragWrapJ
+	fragWrapN
+	fragAndV
+	fragAndB
+	fragOrB
+	fragOrC
+	fragOrD
+	fragOrI
+	fragAndOr
+	fragThresh
+	fragMulti
+	fragMultiA
+)
+
+// hashFragments maps the names of the hash fragments to their fragments.
+var hashFragments = map[string]fragment{
+	"sha256":    fragSha256,
+	"hash256":   fragHash256,
+	"ripemd160": fragRipemd160,
+	"hash160":   fragHash160,
+}
+
+// binaryFragments maps the names of the fragments with two subexpressions to
+// their fragments.
+var binaryFragments = map[string]fragment{
+	"and_v": fragAndV,
+	"and_b": fragAndB,
+	"or_b":  fragOrB,
+	"or_c":  fragOrC,
+	"or_d":  fragOrD,
+	"or_i":  fragOrI,
+}
+
+// wrapperFragments maps the wrapper characters which are fragments of their
+// own to their fragments.
+var wrapperFragments = map[byte]fragment{
+	'a': fragWrapA,
+	's': fragWrapS,
+	'c': fragWrapC,
+	'd': fragWrapD,
+	'v': fragWrapV,
+	'j': fragWrapJ,
+	'n': fragWrapN,
+}
+
+// Node is a miniscript expression along with its subexpressions.  Nodes are
+// created by Parse and Lift, which ensure they are valid.
+type Node struct {
+	fragment fragment
+	ctx      Context
+	typ      typ
+
+	// k is the threshold of thresh(), multi() and multi_a(), and the
+	// timelock of older() and after().
+	k uint32
+
+	// keys are the keys of the key fragments, and data is the hash of the
+	// hash fragments.
+	keys []*btcec.PublicKey
+	data []byte
+
+	subs []*Node
+}
+
+// newNode returns a new node with the passed properties after ensuring it is
+// valid.
+func newNode(f fragment, ctx Context, subs ...*Node) (*Node, error) {
+	n := &Node{fragment: f, ctx: ctx, subs: subs}
+	return n, n.check()
+}
+
+// check computes the type of the node and returns an error when it is invalid.
+func (n *Node) check() error {
+	n.typ = computeType(n)
+	if n.typ == 0 {
+		return fmt.Errorf("%s is not a valid miniscript expression", n)
+	}
+	return nil
+}
+
+// Parse parses the passed miniscript expression for the passed context and
+// ensures it is valid, which requires its type to be B and all of its keys to
+// be distinct.
+func Parse(s string, ctx Context) (*Node, error) {
+	if ctx != P2WSH && ctx != Tapscript {
+		return nil, fmt.Errorf("unknown context %v", ctx)
+	}
+	n, err := parse(s, ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := n.checkTopLevel(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// checkTopLevel returns an error when the node can't be the top level
+// expression of a script.
+func (n *Node) checkTopLevel() error {
+	if !n.typ.is("B") {
+		return fmt.Errorf("top level expression %s is of type %s rather "+
+			"than B", n, n.typ.only("VKW"))
+	}
+
+	seen := make(map[string]struct{})
+	for _, key := range n.Keys() {
+		s := string(n.serializeKey(key))
+		if _, ok := seen[s]; ok {
+			return fmt.Errorf("key %x is used more than once",
+				n.serializeKey(key))
+		}
+		seen[s] = struct{}{}
+	}
+	return nil
+}
+
+// splitArgs splits the passed arguments of a fragment at the commas which are
+// not nested in parentheses.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unexpected ')' in '%s'", s)
+			}
+		case ',':
+			if depth == 0 {
+				args = append(args, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parentheses in '%s'", s)
+	}
+	return append(args, s[start:]), nil
+}
+
+// parseNumber parses the passed decimal number, which must be in the passed
+// range.
+func parseNumber(s string, min, max uint64) (uint32, error) {
+	v, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || v < min || v > max || (len(s) > 1 && s[0] == '0') {
+		return 0, fmt.Errorf("number '%s' is not in the range %d..%d", s,
+			min, max)
+	}
+	return uint32(v), nil
+}
+
+// parseKey parses the passed hex encoded public key, which is compressed for
+// P2WSH and x-only for tapscript.
+func parseKey(s string, ctx Context) (*btcec.PublicKey, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("key '%s' is not hex", s)
+	}
+	return parseKeyBytes(b, ctx)
+}
+
+// parseKeyBytes parses the passed serialized public key, which is compressed
+// for P2WSH and x-only for tapscript.
+func parseKeyBytes(b []byte, ctx Context) (*btcec.PublicKey, error) {
+	var key *btcec.PublicKey
+	var err error
+	switch {
+	case ctx == Tapscript && len(b) == schnorr.PubKeyBytesLen:
+		key, err = schnorr.ParsePubKey(b)
+	case ctx == P2WSH && len(b) == btcec.PubKeyBytesLenCompressed:
+		key, err = btcec.ParsePubKey(b)
+	default:
+		return nil, fmt.Errorf("key %x is not a valid %v key", b, ctx)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("key %x is not valid: %v", b, err)
+	}
+	return key, nil
+}
+
+// parse parses the passed miniscript expression, which might be preceded by
+// wrappers.
+func parse(s string, ctx Context) (*Node, error) {
+	if i := strings.IndexAny(s, ":("); i != -1 && s[i] == ':' {
+		wrappers := s[:i]
+		if wrappers == "" {
+			return nil, fmt.Errorf("empty wrappers in '%s'", s)
+		}
+		n, err := parse(s[i+1:], ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		// The wrappers apply from the innermost one, which is the last.
+		for j := len(wrappers) - 1; j >= 0; j-- {
+			n, err = wrap(wrappers[j], n)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return n, nil
+	}
+
+	switch s {
+	case "0":
+		return newNode(fragFalse, ctx)
+	case "1":
+		return newNode(fragTrue, ctx)
+	}
+
+	open := strings.IndexByte(s, '(')
+	if open == -1 || !strings.HasSuffix(s, ")") {
+		return nil, fmt.Errorf("'%s' is not a valid miniscript fragment", s)
+	}
+	name := s[:open]
+	args, err := splitArgs(s[open+1 : len(s)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	// expectArgs returns an error unless the fragment has the passed
+	// number of arguments.
+	expectArgs := func(num int) error {
+		if len(args) != num {
+			return fmt.Errorf("%s() expects %d arguments, got %d",
+				name, num, len(args))
+		}
+		return nil
+	}
+
+	// parseSubs parses the passed arguments as subexpressions.
+	parseSubs := func(args []string) ([]*Node, error) {
+		subs := make([]*Node, 0, len(args))
+		for _, arg := range args {
+			sub, err := parse(arg, ctx)
+			if err != nil {
+				return nil, err
+			}
+			subs = append(subs, sub)
+		}
+		return subs, nil
+	}
+
+	switch name {
+	case "pk_k", "pk_h", "pk", "pkh":
+		if err := expectArgs(1); err != nil {
+			return nil, err
+		}
+		key, err := parseKey(args[0], ctx)
+		if err != nil {
+			return nil, err
+		}
+		f := fragPkK
+		if name == "pk_h" || name == "pkh" {
+			f = fragPkH
+		}
+		n := &Node{fragment: f, ctx: ctx, keys: []*btcec.PublicKey{key}}
+		if err := n.check(); err != nil {
+			return nil, err
+		}
+		if name == "pk" || name == "pkh" {
+			return newNode(fragWrapC, ctx, n)
+		}
+		return n, nil
+
+	case "older", "after":
+		if err := expectArgs(1); err != nil {
+			return nil, err
+		}
+		k, err := parseNumber(args[0], 1, 1<<31-1)
+		if err != nil {
+			return nil, err
+		}
+		f := fragOlder
+		if name == "after" {
+			f = fragAfter
+		}
+		n := &Node{fragment: f, ctx: ctx, k: k}
+		return n, n.check()
+
+	case "sha256", "hash256", "ripemd160", "hash160":
+		if err := expectArgs(1); err != nil {
+			return nil, err
+		}
+		size := 32
+		if name == "ripemd160" || name == "hash160" {
+			size = 20
+		}
+		hash, err := hex.DecodeString(args[0])
+		if err != nil || len(hash) != size {
+			return nil, fmt.Errorf("%s() expects a %d byte hex encoded "+
+				"hash, got '%s'", name, size, args[0])
+		}
+		n := &Node{fragment: hashFragments[name], ctx: ctx, data: hash}
+		return n, n.check()
+
+	case "and_v", "and_b", "or_b", "or_c", "or_d", "or_i":
+		if err := expectArgs(2); err != nil {
+			return nil, err
+		}
+		subs, err := parseSubs(args)
+		if err != nil {
+			return nil, err
+		}
+		return newNode(binaryFragments[name], ctx, subs...)
+
+	case "andor":
+		if err := expectArgs(3); err != nil {
+			return nil, err
+		}
+		subs, err := parseSubs(args)
+		if err != nil {
+			return nil, err
+		}
+		return newNode(fragAndOr, ctx, subs...)
+
+	case "and_n":
+		if err := expectArgs(2); err != nil {
+			return nil, err
+		}
+		subs, err := parseSubs(args)
+		if err != nil {
+			return nil, err
+		}
+		zero, _ := newNode(fragFalse, ctx)
+		return newNode(fragAndOr, ctx, subs[0], subs[1], zero)
+
+	case "thresh":
+		if len(args) < 2 {
+			return nil, errors.New("thresh() requires a threshold and " +
+				"at least one subexpression")
+		}
+		k, err := parseNumber(args[0], 1, uint64(len(args)-1))
+		if err != nil {
+			return nil, err
+		}
+		subs, err := parseSubs(args[1:])
+		if err != nil {
+			return nil, err
+		}
+		n := &Node{fragment: fragThresh, ctx: ctx, k: k, subs: subs}
+		return n, n.check()
+
+	case "multi", "multi_a":
+		f, maxKeys := fragMulti, txscript.MaxPubKeysPerMultiSig
+		if name == "multi_a" {
+			f, maxKeys = fragMultiA, maxMultiAKeys
+		}
+		if (f == fragMulti) != (ctx == P2WSH) {
+			return nil, fmt.Errorf("%s() is not allowed in %v", name,
+				ctx)
+		}
+		if len(args) < 2 || len(args)-1 > maxKeys {
+			return nil, fmt.Errorf("%s() requires a threshold and "+
+				"1 to %d keys", name, maxKeys)
+		}
+		k, err := parseNumber(args[0], 1, uint64(len(args)-1))
+		if err != nil {
+			return nil, err
+		}
+		keys := make([]*btcec.PublicKey, 0, len(args)-1)
+		for _, arg := range args[1:] {
+			key, err := parseKey(arg, ctx)
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key)
+		}
+		n := &Node{fragment: f, ctx: ctx, k: k, keys: keys}
+		return n, n.check()
+	}
+
+	return nil, fmt.Errorf("'%s' is not a valid miniscript fragment", name)
+}
+
+// wrap returns the passed node wrapped in the wrapper with the passed
+// character.
+func wrap(w byte, n *Node) (*Node, error) {
+	if f, ok := wrapperFragments[w]; ok {
+		return newNode(f, n.ctx, n)
+	}
+
+	// The remaining wrappers are aliases of other fragments.
+	one, _ := newNode(fragTrue, n.ctx)
+	zero, _ := newNode(fragFalse, n.ctx)
+	switch w {
+	case 't':
+		return newNode(fragAndV, n.ctx, n, one)
+	case 'l':
+		return newNode(fragOrI, n.ctx, zero, n)
+	case 'u':
+		return newNode(fragOrI, n.ctx, n, zero)
+	}
+	return nil, fmt.Errorf("'%c' is not a valid wrapper", w)
+}
+
+// serializeKey returns the serialization of the passed key in the script of
+// the node.
+func (n *Node) serializeKey(key *btcec.PublicKey) []byte {
+	if n.ctx == Tapscript {
+		return schnorr.SerializePubKey(key)
+	}
+	return key.SerializeCompressed()
+}
+
+// keyString returns the hex encoded serialization of the key of the node with
+// the passed index.
+func (n *Node) keyString(i int) string {
+	return hex.EncodeToString(n.serializeKey(n.keys[i]))
+}
+
+// Context returns the script context of the miniscript.
+func (n *Node) Context() Context {
+	return n.ctx
+}
+
+// Type returns the type properties of the miniscript as defined by BIP0379,
+// such as "Bzduesmxk" for the expression 0.
+func (n *Node) Type() string {
+	return n.typ.String()
+}
+
+// Keys returns the public keys of the miniscript in the order they appear in.
+func (n *Node) Keys() []*btcec.PublicKey {
+	keys := append([]*btcec.PublicKey(nil), n.keys...)
+	for _, sub := range n.subs {
+		keys = append(keys, sub.Keys()...)
+	}
+	return keys
+}
+
+// String returns the miniscript expression, using the aliases pk(), pkh(),
+// and_n() and the t:, l: and u: wrappers where possible.
+func (n *Node) String() string {
+	var wrappers strings.Builder
+	for {
+		w, inner := n.wrapper()
+		if inner == nil {
+			break
+		}
+		wrappers.WriteByte(w)
+		n = inner
+	}
+
+	body := n.body()
+	if wrappers.Len() == 0 {
+		return body
+	}
+	return wrappers.String() + ":" + body
+}
+
+// wrapper returns the wrapper character of the node and the node it wraps, or
+// a nil node when the node is not written as a wrapper.
+func (n *Node) wrapper() (byte, *Node) {
+	for w, f := range wrapperFragments {
+		if n.fragment != f {
+			continue
+		}
+
+		// c:pk_k() and c:pk_h() are written as pk() and pkh().
+		if f == fragWrapC && (n.subs[0].fragment == fragPkK ||
+			n.subs[0].fragment == fragPkH) {
+
+			return 0, nil
+		}
+		return w, n.subs[0]
+	}
+
+	switch {
+	case n.fragment == fragAndV && n.subs[1].fragment == fragTrue:
+		return 't', n.subs[0]
+	case n.fragment == fragOrI && n.subs[0].fragment == fragFalse:
+		return 'l', n.subs[1]
+	case n.fragment == fragOrI && n.subs[1].fragment == fragFalse:
+		return 'u', n.subs[0]
+	}
+	return 0, nil
+}
+
+// body returns the expression of the node without its wrappers.
+func (n *Node) body() string {
+	// call returns the fragment with the passed name and arguments.
+	call := func(name string, args ...string) string {
+		return name + "(" + strings.Join(args, ",") + ")"
+	}
+	sub := func(i int) string {
+		return n.subs[i].String()
+	}
+
+	switch n.fragment {
+	case fragFalse:
+		return "0"
+	case fragTrue:
+		return "1"
+	case fragPkK:
+		return call("pk_k", n.keyString(0))
+	case fragPkH:
+		return call("pk_h", n.keyString(0))
+	case fragWrapC:
+		// Only c:pk_k() and c:pk_h() are not written as wrappers.
+		name := "pk"
+		if n.subs[0].fragment == fragPkH {
+			name = "pkh"
+		}
+		return call(name, n.subs[0].keyString(0))
+	case fragOlder:
+		return call("older", strconv.FormatUint(uint64(n.k), 10))
+	case fragAfter:
+		return call("after", strconv.FormatUint(uint64(n.k), 10))
+	case fragSha256, fragHash256, fragRipemd160, fragHash160:
+		for name, f := range hashFragments {
+			if f == n.fragment {
+				return call(name, hex.EncodeToString(n.data))
+			}
+		}
+	case fragAndOr:
+		if n.subs[2].fragment == fragFalse {
+			return call("and_n", sub(0), sub(1))
+		}
+		return call("andor", sub(0), sub(1), sub(2))
+	case fragThresh, fragMulti, fragMultiA:
+		name := map[fragment]string{
+			fragThresh: "thresh",
+			fragMulti:  "multi",
+			fragMultiA: "multi_a",
+		}[n.fragment]
+		args := []string{strconv.FormatUint(uint64(n.k), 10)}
+		for i := range n.subs {
+			args = append(args, sub(i))
+		}
+		for i := range n.keys {
+			args = append(args, n.keyString(i))
+		}
+		return call(name, args...)
+	}
+
+	for name, f := range binaryFragments {
+		if f == n.fragment {
+			return call(name, sub(0), sub(1))
+		}
+	}
+
+	// Wrappers only get here when they are written as a fragment, which
+	// can't happen.
+	return "<invalid>"
+}
+
+// appendData appends a push of the passed data to the passed script.  Only
+// keys and hashes are pushed as data, which are shorter than OP_PUSHDATA1
+// requires, and numbers, which are pushed with appendNumber.
+func appendData(script, data []byte) []byte {
+	script = append(script, txscript.OP_DATA_1-1+byte(len(data)))
+	return append(script, data...)
+}
+
+// appendNumber appends the minimal push of the passed non-negative number to
+// the passed script.
+func appendNumber(script []byte, v uint32) []byte {
+	switch {
+	case v == 0:
+		return append(script, txscript.OP_0)
+	case v <= 16:
+		return append(script, txscript.OP_1-1+byte(v))
+	}
+
+	// Numbers are encoded in little endian with the most significant bit
+	// of the last byte as the sign bit, so another byte is needed when it
+	// is set.
+	var num []byte
+	for ; v > 0; v >>= 8 {
+		num = append(num, byte(v))
+	}
+	if num[len(num)-1]&0x80 != 0 {
+		num = append(num, 0)
+	}
+	return appendData(script, num)
+}
+
+// Script returns the script the miniscript compiles to.
+func (n *Node) Script() []byte {
+	return n.appendScript(nil)
+}
+
+// appendScript appends the script of the node to the passed script.
+func (n *Node) appendScript(s []byte) []byte {
+	switch n.fragment {
+	case fragFalse:
+		return append(s, txscript.OP_0)
+
+	case fragTrue:
+		return append(s, txscript.OP_1)
+
+	case fragPkK:
+		return appendData(s, n.serializeKey(n.keys[0]))
+
+	case fragPkH:
+		s = append(s, txscript.OP_DUP, txscript.OP_HASH160)
+		s = appendData(s, btcutil.Hash160(n.serializeKey(n.keys[0])))
+		return append(s, txscript.OP_EQUALVERIFY)
+
+	case fragOlder:
+		s = appendNumber(s, n.k)
+		return append(s, txscript.OP_CHECKSEQUENCEVERIFY)
+
+	case fragAfter:
+		s = appendNumber(s, n.k)
+		return append(s, txscript.OP_CHECKLOCKTIMEVERIFY)
+
+	case fragSha256, fragHash256, fragRipemd160, fragHash160:
+		s = append(s, txscript.OP_SIZE)
+		s = appendNumber(s, 32)
+		s = append(s, txscript.OP_EQUALVERIFY, hashOpcodes[n.fragment])
+		s = appendData(s, n.data)
+		return append(s, txscript.OP_EQUAL)
+
+	case fragWrapA:
+		s = append(s, txscript.OP_TOALTSTACK)
+		s = n.subs[0].appendScript(s)
+		return append(s, txscript.OP_FROMALTSTACK)
+
+	case fragWrapS:
+		s = append(s, txscript.OP_SWAP)
+		return n.subs[0].appendScript(s)
+
+	case fragWrapC:
+		s = n.subs[0].appendScript(s)
+		return append(s, txscript.OP_CHECKSIG)
+
+	case fragWrapD:
+		s = append(s, txscript.OP_DUP, txscript.OP_IF)
+		s = n.subs[0].appendScript(s)
+		return append(s, txscript.OP_ENDIF)
+
+	case fragWrapV:
+		s = n.subs[0].appendScript(s)
+
+		// The verification is merged into the last opcode when it has
+		// a VERIFY variant, which is the case when the subexpression
+		// does not have the x property.
+		if n.subs[0].typ.is("x") {
+			return append(s, txscript.OP_VERIFY)
+		}
+		s[len(s)-1] = verifyOpcodes[s[len(s)-1]]
+		return s
+
+	case fragWrapJ:
+		s = append(s, txscript.OP_SIZE, txscript.OP_0NOTEQUAL,
+			txscript.OP_IF)
+		s = n.subs[0].appendScript(s)
+		return append(s, txscript.OP_ENDIF)
+
+	case fragWrapN:
+		s = n.subs[0].appendScript(s)
+		return append(s, txscript.OP_0NOTEQUAL)
+
+	case fragAndV:
+		s = n.subs[0].appendScript(s)
+		return n.subs[1].appendScript(s)
+
+	case fragAndB:
+		s = n.subs[0].appendScript(s)
+		s = n.subs[1].appendScript(s)
+		return append(s, txscript.OP_BOOLAND)
+
+	case fragOrB:
+		s = n.subs[0].appendScript(s)
+		s = n.subs[1].appendScript(s)
+		return append(s, txscript.OP_BOOLOR)
+
+	case fragOrC:
+		s = n.subs[0].appendScript(s)
+		s = append(s, txscript.OP_NOTIF)
+		s = n.subs[1].appendScript(s)
+		return append(s, txscript.OP_ENDIF)
+
+	case fragOrD:
+		s = n.subs[0].appendScript(s)
+		s = append(s, txscript.OP_IFDUP, txscript.OP_NOTIF)
+		s = n.subs[1].appendScript(s)
+		return append(s, txscript.OP_ENDIF)
+
+	case fragOrI:
+		s = append(s, txscript.OP_IF)
+		s = n.subs[0].appendScript(s)
+		s = append(s, txscript.OP_ELSE)
+		s = n.subs[1].appendScript(s)
+		return append(s, txscript.OP_ENDIF)
+
+	case fragAndOr:
+		s = n.subs[0].appendScript(s)
+		s = append(s, txscript.OP_NOTIF)
+		s = n.subs[2].appendScript(s)
+		s = append(s, txscript.OP_ELSE)
+		s = n.subs[1].appendScript(s)
+		return append(s, txscript.OP_ENDIF)
+
+	case fragThresh:
+		for i, sub := range n.subs {
+			s = sub.appendScript(s)
+			if i > 0 {
+				s = append(s, txscript.OP_ADD)
+			}
+		}
+		s = appendNumber(s, n.k)
+		return append(s, txscript.OP_EQUAL)
+
+	case fragMulti:
+		s = appendNumber(s, n.k)
+		for _, key := range n.keys {
+			s = appendData(s, n.serializeKey(key))
+		}
+		s = appendNumber(s, uint32(len(n.keys)))
+		return append(s, txscript.OP_CHECKMULTISIG)
+
+	case fragMultiA:
+		for i, key := range n.keys {
+			s = appendData(s, n.serializeKey(key))
+			if i == 0 {
+				s = append(s, txscript.OP_CHECKSIG)
+			} else {
+				s = append(s, txscript.OP_CHECKSIGADD)
+			}
+		}
+		s = appendNumber(s, n.k)
+		return append(s, txscript.OP_NUMEQUAL)
+	}
+	return s
+}
+
+// hashOpcodes maps the hash fragments to the opcodes computing their hashes.
+var hashOpcodes = map[fragment]byte{
+	fragSha256:    txscript.OP_SHA256,
+	fragHash256:   txscript.OP_HASH256,
+	fragRipemd160: txscript.OP_RIPEMD160,
+	fragHash160:   txscript.OP_HASH160,
+}
+
+// verifyOpcodes maps the opcodes which have a VERIFY variant to it.
+var verifyOpcodes = map[byte]byte{
+	txscript.OP_CHECKSIG:      txscript.OP_CHECKSIGVERIFY,
+	txscript.OP_CHECKMULTISIG: txscript.OP_CHECKMULTISIGVERIFY,
+	txscript.OP_EQUAL:         txscript.OP_EQUALVERIFY,
+	txscript.OP_NUMEQUAL:      txscript.OP_NUMEQUALVERIFY,
+}
+
+// CheckSanity returns an error when the miniscript is not safe to use, which is
+// the case when it can be spent without a signature, has malleable
+// satisfactions, mixes height and time based timelocks, or exceeds the
+// resource limits of its context.
+func (n *Node) CheckSanity() error {
+	switch {
+	case !n.typ.is("s"):
+		return errors.New("miniscript can be satisfied without a " +
+			"signature")
+	case !n.typ.is("m"):
+		return errors.New("miniscript has malleable satisfactions")
+	case !n.typ.is("k"):
+		return errors.New("miniscript mixes height and time based " +
+			"timelocks")
+	}
+
+	if n.ctx == P2WSH {
+		if size := len(n.Script()); size > maxStandardP2WSHScriptSize {
+			return fmt.Errorf("script size of %d bytes exceeds the "+
+				"maximum of %d bytes", size,
+				maxStandardP2WSHScriptSize)
+		}
+		if ops := n.maxOps(); ops > txscript.MaxOpsPerScript {
+			return fmt.Errorf("satisfaction executes %d opcodes, "+
+				"more than the maximum of %d", ops,
+				txscript.MaxOpsPerScript)
+		}
+		sat, _ := n.witness(countWeights)
+		if sat.valid && sat.v > maxStandardP2WSHStackItems {
+			return fmt.Errorf("satisfaction has %d stack items, "+
+				"more than the maximum of %d", sat.v,
+				maxStandardP2WSHStackItems)
+		}
+	}
+	return nil
+}
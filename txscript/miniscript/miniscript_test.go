@@ -0,0 +1,743 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package miniscript
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/bynil/btcd/btcec/v2"
+	"github.com/bynil/btcd/btcec/v2/schnorr"
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/txscript"
+	"github.com/bynil/btcd/wire"
+)
+
+// testKeys are the private keys which the placeholders A, B and C of the test
+// expressions stand for.
+var testKeys = func() []*btcec.PrivateKey {
+	keys := make([]*btcec.PrivateKey, 3)
+	for i := range keys {
+		var b [32]byte
+		b[31] = byte(i + 1)
+		keys[i], _ = btcec.PrivKeyFromBytes(b[:])
+	}
+	return keys
+}()
+
+// testPreimage is the preimage of the hash which the placeholder H of the test
+// expressions stands for.
+var testPreimage = bytes.Repeat([]byte{0x42}, 32)
+
+// keyHex returns the encoding of the passed test key in the passed context.
+func keyHex(i int, ctx Context) string {
+	pub := testKeys[i].PubKey()
+	if ctx == Tapscript {
+		return hex.EncodeToString(schnorr.SerializePubKey(pub))
+	}
+	return hex.EncodeToString(pub.SerializeCompressed())
+}
+
+// expand replaces the placeholders of the passed test expression.
+func expand(s string, ctx Context) string {
+	h := sha256.Sum256(testPreimage)
+	return strings.NewReplacer(
+		"A", keyHex(0, ctx), "B", keyHex(1, ctx), "C", keyHex(2, ctx),
+		"H", hex.EncodeToString(h[:]),
+		"G", hex.EncodeToString(btcutil.Hash160(testPreimage)),
+	).Replace(s)
+}
+
+// keyData returns the push data of the passed test key in the passed context.
+func keyData(i int, ctx Context) []byte {
+	b, _ := hex.DecodeString(keyHex(i, ctx))
+	return b
+}
+
+// TestParse ensures expressions are type checked and compile to the expected
+// scripts, and that lifting the scripts recovers the expressions.
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	hash := sha256.Sum256(testPreimage)
+	hashA := btcutil.Hash160(keyData(0, P2WSH))
+	hashB := btcutil.Hash160(keyData(1, P2WSH))
+	tests := []struct {
+		expr   string
+		ctx    Context
+		typ    string
+		script func(*txscript.ScriptBuilder)
+	}{{
+		expr: "pk(A)",
+		typ:  "Bonduesmk",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(keyData(0, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+		},
+	}, {
+		expr: "pkh(A)",
+		typ:  "Bnduesmk",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160)
+			b.AddData(hashA).AddOp(txscript.OP_EQUALVERIFY)
+			b.AddOp(txscript.OP_CHECKSIG)
+		},
+	}, {
+		expr: "and_v(v:pk(A),pk(B))",
+		typ:  "Bnufsmk",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(keyData(0, P2WSH)).AddOp(txscript.OP_CHECKSIGVERIFY)
+			b.AddData(keyData(1, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+		},
+	}, {
+		expr: "and_v(v:pk(A),or_d(pk(B),older(12960)))",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(keyData(0, P2WSH)).AddOp(txscript.OP_CHECKSIGVERIFY)
+			b.AddData(keyData(1, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_IFDUP).AddOp(txscript.OP_NOTIF)
+			b.AddInt64(12960).AddOp(txscript.OP_CHECKSEQUENCEVERIFY)
+			b.AddOp(txscript.OP_ENDIF)
+		},
+	}, {
+		expr: "multi(2,A,B,C)",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddOp(txscript.OP_2).AddData(keyData(0, P2WSH))
+			b.AddData(keyData(1, P2WSH)).AddData(keyData(2, P2WSH))
+			b.AddOp(txscript.OP_3).AddOp(txscript.OP_CHECKMULTISIG)
+		},
+	}, {
+		expr: "thresh(2,pk(A),s:pk(B),a:pkh(C))",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(keyData(0, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_SWAP)
+			b.AddData(keyData(1, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_ADD).AddOp(txscript.OP_TOALTSTACK)
+			b.AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160)
+			b.AddData(btcutil.Hash160(keyData(2, P2WSH)))
+			b.AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_FROMALTSTACK).AddOp(txscript.OP_ADD)
+			b.AddOp(txscript.OP_2).AddOp(txscript.OP_EQUAL)
+		},
+	}, {
+		expr: "andor(pk(A),sha256(H),pk(B))",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(keyData(0, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_NOTIF)
+			b.AddData(keyData(1, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_ELSE)
+			b.AddOp(txscript.OP_SIZE).AddInt64(32)
+			b.AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_SHA256)
+			b.AddData(hash[:]).AddOp(txscript.OP_EQUAL)
+			b.AddOp(txscript.OP_ENDIF)
+		},
+	}, {
+		expr: "or_i(pk(A),and_v(v:pkh(B),after(100)))",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddOp(txscript.OP_IF)
+			b.AddData(keyData(0, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_ELSE)
+			b.AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160)
+			b.AddData(hashB).AddOp(txscript.OP_EQUALVERIFY)
+			b.AddOp(txscript.OP_CHECKSIGVERIFY)
+			b.AddInt64(100).AddOp(txscript.OP_CHECKLOCKTIMEVERIFY)
+			b.AddOp(txscript.OP_ENDIF)
+		},
+	}, {
+		expr: "and_b(pk(A),sdv:older(1))",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(keyData(0, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_SWAP).AddOp(txscript.OP_DUP)
+			b.AddOp(txscript.OP_IF).AddOp(txscript.OP_1)
+			b.AddOp(txscript.OP_CHECKSEQUENCEVERIFY)
+			b.AddOp(txscript.OP_VERIFY).AddOp(txscript.OP_ENDIF)
+			b.AddOp(txscript.OP_BOOLAND)
+		},
+	}, {
+		expr: "or_b(pk(A),a:pk(B))",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(keyData(0, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_TOALTSTACK)
+			b.AddData(keyData(1, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_FROMALTSTACK).AddOp(txscript.OP_BOOLOR)
+		},
+	}, {
+		expr: "and_n(pk(A),l:after(500000001))",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(keyData(0, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_NOTIF).AddOp(txscript.OP_0)
+			b.AddOp(txscript.OP_ELSE).AddOp(txscript.OP_IF)
+			b.AddOp(txscript.OP_0).AddOp(txscript.OP_ELSE)
+			b.AddInt64(500000001)
+			b.AddOp(txscript.OP_CHECKLOCKTIMEVERIFY)
+			b.AddOp(txscript.OP_ENDIF).AddOp(txscript.OP_ENDIF)
+		},
+	}, {
+		expr: "or_d(n:pk(A),j:pk(B))",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(keyData(0, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_0NOTEQUAL).AddOp(txscript.OP_IFDUP)
+			b.AddOp(txscript.OP_NOTIF).AddOp(txscript.OP_SIZE)
+			b.AddOp(txscript.OP_0NOTEQUAL).AddOp(txscript.OP_IF)
+			b.AddData(keyData(1, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_ENDIF).AddOp(txscript.OP_ENDIF)
+		},
+	}, {
+		expr: "t:or_c(pk(A),v:hash160(G))",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(keyData(0, P2WSH)).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_NOTIF)
+			b.AddOp(txscript.OP_SIZE).AddInt64(32)
+			b.AddOp(txscript.OP_EQUALVERIFY).AddOp(txscript.OP_HASH160)
+			b.AddData(btcutil.Hash160(testPreimage))
+			b.AddOp(txscript.OP_EQUALVERIFY)
+			b.AddOp(txscript.OP_ENDIF).AddOp(txscript.OP_1)
+		},
+	}, {
+		expr: "dv:older(1)",
+		typ:  "Bondemxhk",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddOp(txscript.OP_DUP).AddOp(txscript.OP_IF)
+			b.AddOp(txscript.OP_1)
+			b.AddOp(txscript.OP_CHECKSEQUENCEVERIFY)
+			b.AddOp(txscript.OP_VERIFY).AddOp(txscript.OP_ENDIF)
+		},
+	}, {
+		expr: "dv:older(1)",
+		ctx:  Tapscript,
+		typ:  "Bonduemxhk",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddOp(txscript.OP_DUP).AddOp(txscript.OP_IF)
+			b.AddOp(txscript.OP_1)
+			b.AddOp(txscript.OP_CHECKSEQUENCEVERIFY)
+			b.AddOp(txscript.OP_VERIFY).AddOp(txscript.OP_ENDIF)
+		},
+	}, {
+		expr: "older(144)",
+		typ:  "Bzfmxhk",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddInt64(144).AddOp(txscript.OP_CHECKSEQUENCEVERIFY)
+		},
+	}, {
+		expr: "0",
+		typ:  "Bzduesmxk",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddOp(txscript.OP_0)
+		},
+	}, {
+		expr: "multi_a(2,A,B,C)",
+		ctx:  Tapscript,
+		typ:  "Bduesmk",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(keyData(0, Tapscript)).AddOp(txscript.OP_CHECKSIG)
+			b.AddData(keyData(1, Tapscript))
+			b.AddOp(txscript.OP_CHECKSIGADD)
+			b.AddData(keyData(2, Tapscript))
+			b.AddOp(txscript.OP_CHECKSIGADD)
+			b.AddOp(txscript.OP_2).AddOp(txscript.OP_NUMEQUAL)
+		},
+	}, {
+		expr: "and_v(v:pk(A),pk(B))",
+		ctx:  Tapscript,
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(keyData(0, Tapscript))
+			b.AddOp(txscript.OP_CHECKSIGVERIFY)
+			b.AddData(keyData(1, Tapscript)).AddOp(txscript.OP_CHECKSIG)
+		},
+	}}
+
+	for _, test := range tests {
+		expr := expand(test.expr, test.ctx)
+		n, err := Parse(expr, test.ctx)
+		if err != nil {
+			t.Errorf("%s (%v): unexpected error: %v", test.expr,
+				test.ctx, err)
+			continue
+		}
+		if test.typ != "" && n.Type() != test.typ {
+			t.Errorf("%s (%v): unexpected type - got %s, want %s",
+				test.expr, test.ctx, n.Type(), test.typ)
+		}
+		if n.String() != expr {
+			t.Errorf("%s (%v): unexpected string - got %s",
+				test.expr, test.ctx, n.String())
+		}
+
+		b := txscript.NewScriptBuilder()
+		test.script(b)
+		want, err := b.Script()
+		if err != nil {
+			t.Fatalf("%s: unable to build script: %v", test.expr, err)
+		}
+		script := n.Script()
+		if !bytes.Equal(script, want) {
+			t.Errorf("%s (%v): unexpected script - got %x, want %x",
+				test.expr, test.ctx, script, want)
+			continue
+		}
+
+		var keys []*btcec.PublicKey
+		for _, key := range testKeys {
+			keys = append(keys, key.PubKey())
+		}
+		lifted, err := Lift(script, test.ctx, keys)
+		if err != nil {
+			t.Errorf("%s (%v): unable to lift: %v", test.expr,
+				test.ctx, err)
+			continue
+		}
+		if lifted.String() != expr {
+			t.Errorf("%s (%v): unexpected lifted expression - "+
+				"got %s", test.expr, test.ctx, lifted.String())
+		}
+	}
+}
+
+// TestParseInvalid ensures invalid expressions are rejected.
+func TestParseInvalid(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr string
+		ctx  Context
+	}{
+		{expr: "", ctx: P2WSH},
+		{expr: "pk(A", ctx: P2WSH},
+		{expr: "pk(A))", ctx: P2WSH},
+		{expr: "foo(A)", ctx: P2WSH},
+		{expr: "x:pk(A)", ctx: P2WSH},
+		{expr: ":pk(A)", ctx: P2WSH},
+		{expr: "pk(00)", ctx: P2WSH},
+		{expr: "pk_k(A)", ctx: P2WSH},
+		{expr: "v:pk(A)", ctx: P2WSH},
+		{expr: "and_v(pk(A),pk(B))", ctx: P2WSH},
+		{expr: "and_v(v:pk(A),pk(A))", ctx: P2WSH},
+		{expr: "or_b(pk(A),pk(B))", ctx: P2WSH},
+		{expr: "older(0)", ctx: P2WSH},
+		{expr: "older(2147483648)", ctx: P2WSH},
+		{expr: "after(01)", ctx: P2WSH},
+		{expr: "sha256(00)", ctx: P2WSH},
+		{expr: "thresh(3,pk(A),s:pk(B))", ctx: P2WSH},
+		{expr: "thresh(0,pk(A),s:pk(B))", ctx: P2WSH},
+		{expr: "multi(2,A,B,C)", ctx: Tapscript},
+		{expr: "multi_a(2,A,B,C)", ctx: P2WSH},
+		{expr: "multi(4,A,B,C)", ctx: P2WSH},
+	}
+	for _, test := range tests {
+		_, err := Parse(expand(test.expr, test.ctx), test.ctx)
+		if err == nil {
+			t.Errorf("%q (%v): expected error", test.expr, test.ctx)
+		}
+	}
+
+	// Keys must be encoded as expected by the context.
+	if _, err := Parse(expand("pk(A)", Tapscript), P2WSH); err == nil {
+		t.Error("expected error for x-only key in P2WSH")
+	}
+	if _, err := Parse(expand("pk(A)", P2WSH), Tapscript); err == nil {
+		t.Error("expected error for compressed key in tapscript")
+	}
+}
+
+// TestLiftInvalid ensures scripts which are not the canonical encoding of a
+// miniscript can't be lifted.
+func TestLiftInvalid(t *testing.T) {
+	t.Parallel()
+
+	key := keyData(0, P2WSH)
+	tests := []struct {
+		name   string
+		script func(*txscript.ScriptBuilder)
+	}{{
+		name: "empty script",
+		script: func(b *txscript.ScriptBuilder) {
+		},
+	}, {
+		name: "missing checksig",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(key)
+		},
+	}, {
+		name: "pkh without known key",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddOp(txscript.OP_DUP).AddOp(txscript.OP_HASH160)
+			b.AddData(btcutil.Hash160(keyData(1, P2WSH)))
+			b.AddOp(txscript.OP_EQUALVERIFY)
+			b.AddOp(txscript.OP_CHECKSIG)
+		},
+	}, {
+		name: "unmerged verify",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData(key).AddOp(txscript.OP_CHECKSIG)
+			b.AddOp(txscript.OP_VERIFY).AddOp(txscript.OP_1)
+		},
+	}, {
+		name: "non-minimal number",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddData([]byte{0x10, 0x00})
+			b.AddOp(txscript.OP_CHECKSEQUENCEVERIFY)
+		},
+	}, {
+		name: "trailing opcode",
+		script: func(b *txscript.ScriptBuilder) {
+			b.AddOp(txscript.OP_NOP)
+			b.AddData(key).AddOp(txscript.OP_CHECKSIG)
+		},
+	}}
+
+	keys := []*btcec.PublicKey{testKeys[0].PubKey()}
+	for _, test := range tests {
+		b := txscript.NewScriptBuilder()
+		test.script(b)
+		script, _ := b.Script()
+		if _, err := Lift(script, P2WSH, keys); err == nil {
+			t.Errorf("%s: expected error", test.name)
+		}
+	}
+}
+
+// TestCheckSanity ensures expressions which can't be safely used by a wallet
+// are reported.
+func TestCheckSanity(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr string
+		ctx  Context
+		sane bool
+	}{
+		{expr: "pk(A)", ctx: P2WSH, sane: true},
+		{
+			expr: "or_d(pk(A),and_v(v:pk(B),older(1)))",
+			ctx:  P2WSH,
+			sane: true,
+		},
+		{expr: "multi_a(2,A,B,C)", ctx: Tapscript, sane: true},
+
+		// No signature is required.
+		{expr: "older(1)", ctx: P2WSH, sane: false},
+		{expr: "or_d(pk(A),older(1))", ctx: P2WSH, sane: false},
+		{expr: "or_i(pk(A),sha256(H))", ctx: P2WSH, sane: false},
+
+		// The hash branch can be swapped by a third party.
+		{expr: "andor(sha256(H),pk(A),pk(B))", ctx: P2WSH, sane: false},
+
+		// Height and time based relative timelocks are mixed.
+		{
+			expr: "and_v(v:older(1),and_v(v:older(4194305),pk(A)))",
+			ctx:  P2WSH,
+			sane: false,
+		},
+	}
+	for _, test := range tests {
+		n, err := Parse(expand(test.expr, test.ctx), test.ctx)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.expr, err)
+			continue
+		}
+		err = n.CheckSanity()
+		if test.sane && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.expr, err)
+		}
+		if !test.sane && err == nil {
+			t.Errorf("%s: expected error", test.expr)
+		}
+	}
+
+	// A P2WSH script is limited to 201 non-push opcodes.
+	expr := "and_v(v:pk(A),pk(B))"
+	for i := 0; i < 100; i++ {
+		expr = "and_v(v:older(1)," + expr + ")"
+	}
+	n, err := Parse(expand(expr, P2WSH), P2WSH)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := n.CheckSanity(); err == nil {
+		t.Fatal("expected error for too many opcodes")
+	}
+	n, err = Parse(expand(expr, Tapscript), Tapscript)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := n.CheckSanity(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+// TestMaxWitnessSize ensures the maximum witness sizes are computed as
+// expected.
+func TestMaxWitnessSize(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr string
+		ctx  Context
+		size int
+	}{
+		{expr: "pk(A)", ctx: P2WSH, size: 73},
+		{expr: "pkh(A)", ctx: P2WSH, size: 73 + 34},
+		{expr: "multi(2,A,B,C)", ctx: P2WSH, size: 1 + 2*73},
+		{expr: "and_v(v:pk(A),pk(B))", ctx: Tapscript, size: 2 * 66},
+		{expr: "multi_a(2,A,B,C)", ctx: Tapscript, size: 2*66 + 1},
+		{expr: "or_d(pk(A),older(1))", ctx: P2WSH, size: 73},
+		{expr: "andor(pk(A),sha256(H),pk(B))", ctx: P2WSH, size: 73 + 33},
+	}
+	for _, test := range tests {
+		n, err := Parse(expand(test.expr, test.ctx), test.ctx)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.expr, err)
+			continue
+		}
+		size, err := n.MaxWitnessSize()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.expr, err)
+			continue
+		}
+		if size != test.size {
+			t.Errorf("%s: unexpected size - got %d, want %d",
+				test.expr, size, test.size)
+		}
+	}
+
+	n, err := Parse("older(1)", P2WSH)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if size, _ := n.MaxWitnessSize(); size != 0 {
+		t.Fatalf("unexpected size - got %d, want 0", size)
+	}
+	n, err = Parse("and_v(v:pk("+keyHex(0, P2WSH)+"),0)", P2WSH)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := n.MaxWitnessSize(); err == nil {
+		t.Fatal("expected error for unsatisfiable expression")
+	}
+}
+
+// testSatisfier signs with a subset of the test keys for a spending
+// transaction, and knows the test preimage and the timelocks of the
+// transaction.
+type testSatisfier struct {
+	sign     func(*btcec.PrivateKey) []byte
+	keys     []int
+	preimage bool
+	sequence uint32
+	lockTime uint32
+}
+
+func (s *testSatisfier) Sign(pubKey *btcec.PublicKey) ([]byte, bool) {
+	for _, i := range s.keys {
+		if testKeys[i].PubKey().IsEqual(pubKey) {
+			return s.sign(testKeys[i]), true
+		}
+	}
+	return nil, false
+}
+
+func (s *testSatisfier) Preimage(hash []byte) ([]byte, bool) {
+	h := sha256.Sum256(testPreimage)
+	if s.preimage && (bytes.Equal(hash, h[:]) ||
+		bytes.Equal(hash, btcutil.Hash160(testPreimage))) {
+
+		return testPreimage, true
+	}
+	return nil, false
+}
+
+func (s *testSatisfier) CheckOlder(sequence uint32) bool {
+	return sequence <= s.sequence
+}
+
+func (s *testSatisfier) CheckAfter(lockTime uint32) bool {
+	return lockTime <= s.lockTime
+}
+
+// TestSatisfy ensures the produced witnesses spend the outputs of their
+// miniscripts under the standard script verification flags.
+func TestSatisfy(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		expr     string
+		ctx      Context
+		keys     []int
+		preimage bool
+		sequence uint32
+		lockTime uint32
+		err      error
+	}{
+		{expr: "pk(A)", keys: []int{0}},
+		{expr: "pkh(A)", keys: []int{0}},
+		{expr: "pk(A)", keys: []int{1}, err: ErrNoSatisfaction},
+		{
+			expr: "and_v(v:pk(A),or_d(pk(B),older(12960)))",
+			keys: []int{0, 1},
+		},
+		{
+			expr:     "and_v(v:pk(A),or_d(pk(B),older(12960)))",
+			keys:     []int{0},
+			sequence: 12960,
+		},
+		{
+			expr:     "and_v(v:pk(A),or_d(pk(B),older(12960)))",
+			keys:     []int{0},
+			sequence: 100,
+			err:      ErrNoSatisfaction,
+		},
+		{expr: "multi(2,A,B,C)", keys: []int{0, 2}},
+		{expr: "multi(2,A,B,C)", keys: []int{1}, err: ErrNoSatisfaction},
+		{expr: "thresh(2,pk(A),s:pk(B),a:pkh(C))", keys: []int{1, 2}},
+		{expr: "andor(pk(A),sha256(H),pk(B))", keys: []int{0}, preimage: true},
+		{expr: "andor(pk(A),sha256(H),pk(B))", keys: []int{1}},
+		{
+			expr:     "or_i(pk(A),and_v(v:pkh(B),after(100)))",
+			keys:     []int{1},
+			lockTime: 100,
+		},
+		{expr: "or_b(pk(A),a:pk(B))", keys: []int{1}},
+		{expr: "or_d(n:pk(A),j:pk(B))", keys: []int{1}},
+		{expr: "t:or_c(pk(A),v:hash160(G))", keys: []int{0}},
+		{
+			expr:     "and_b(pk(A),sdv:older(1))",
+			keys:     []int{0},
+			sequence: 1,
+		},
+		{expr: "multi_a(2,A,B,C)", ctx: Tapscript, keys: []int{0, 2}},
+		{expr: "multi_a(2,A,B,C)", ctx: Tapscript, keys: []int{1, 2}},
+		{expr: "and_v(v:pk(A),pk(B))", ctx: Tapscript, keys: []int{0, 1}},
+		{
+			expr: "and_v(v:pk(A),pk(B))",
+			ctx:  Tapscript,
+			keys: []int{1},
+			err:  ErrNoSatisfaction,
+		},
+		{
+			expr:     "or_d(pk(A),and_v(v:pk(B),older(10)))",
+			ctx:      Tapscript,
+			keys:     []int{1},
+			sequence: 10,
+		},
+	}
+
+	for _, test := range tests {
+		n, err := Parse(expand(test.expr, test.ctx), test.ctx)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.expr, err)
+			continue
+		}
+		script := n.Script()
+
+		// Create the output which commits to the script, along with the
+		// final element of the witness which reveals it.
+		var pkScript, reveal []byte
+		var tapLeaf txscript.TapLeaf
+		switch test.ctx {
+		case P2WSH:
+			h := sha256.Sum256(script)
+			pkScript, _ = txscript.NewScriptBuilder().
+				AddOp(txscript.OP_0).AddData(h[:]).Script()
+		case Tapscript:
+			tapLeaf = txscript.NewBaseTapLeaf(script)
+			tree := txscript.AssembleTaprootScriptTree(tapLeaf)
+			internalKey := testKeys[2].PubKey()
+			ctrl := tree.LeafMerkleProofs[0].ToControlBlock(
+				internalKey,
+			)
+			reveal, _ = ctrl.ToBytes()
+			rootHash := tree.RootNode.TapHash()
+			outputKey := txscript.ComputeTaprootOutputKey(
+				internalKey, rootHash[:],
+			)
+			pkScript, _ = txscript.PayToTaprootScript(outputKey)
+		}
+
+		const amount = 100000
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(&wire.TxIn{
+			Sequence: wire.MaxTxInSequenceNum - 1,
+		})
+		if test.sequence != 0 {
+			tx.TxIn[0].Sequence = test.sequence
+		}
+		tx.LockTime = test.lockTime
+		tx.AddTxOut(wire.NewTxOut(amount-1000, pkScript))
+		fetcher := txscript.NewCannedPrevOutputFetcher(pkScript, amount)
+		sigHashes := txscript.NewTxSigHashes(tx, fetcher)
+
+		s := &testSatisfier{
+			keys:     test.keys,
+			preimage: test.preimage,
+			sequence: test.sequence,
+			lockTime: test.lockTime,
+		}
+		s.sign = func(key *btcec.PrivateKey) []byte {
+			var sig []byte
+			var err error
+			if test.ctx == Tapscript {
+				sig, err = txscript.RawTxInTapscriptSignature(
+					tx, sigHashes, 0, amount, pkScript,
+					tapLeaf, txscript.SigHashDefault, key,
+				)
+			} else {
+				sig, err = txscript.RawTxInWitnessSignature(
+					tx, sigHashes, 0, amount, script,
+					txscript.SigHashAll, key,
+				)
+			}
+			if err != nil {
+				t.Fatalf("unable to sign: %v", err)
+			}
+			return sig
+		}
+
+		witness, err := n.Satisfy(s)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s (%v): unexpected error - got %v, want %v",
+				test.expr, test.ctx, err, test.err)
+			continue
+		}
+		if test.err != nil {
+			continue
+		}
+
+		maxSize, err := n.MaxWitnessSize()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.expr, err)
+			continue
+		}
+		var size int
+		for _, elem := range witness {
+			size += wire.VarIntSerializeSize(uint64(len(elem))) +
+				len(elem)
+		}
+		if size > maxSize {
+			t.Errorf("%s (%v): witness size %d exceeds maximum %d",
+				test.expr, test.ctx, size, maxSize)
+		}
+
+		witness = append(witness, script)
+		if reveal != nil {
+			witness = append(witness, reveal)
+		}
+		tx.TxIn[0].Witness = witness
+		vm, err := txscript.NewEngine(
+			pkScript, tx, 0, txscript.StandardVerifyFlags, nil,
+			sigHashes, amount, fetcher,
+		)
+		if err != nil {
+			t.Errorf("%s (%v): unable to create engine: %v",
+				test.expr, test.ctx, err)
+			continue
+		}
+		if err := vm.Execute(); err != nil {
+			t.Errorf("%s (%v): witness doesn't satisfy the script: "+
+				"%v", test.expr, test.ctx, err)
+		}
+	}
+}
@@ -0,0 +1,616 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package miniscript
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+
+	"golang.org/x/crypto/ripemd160"
+
+	"github.com/bynil/btcd/btcec/v2"
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+)
+
+var (
+	// ErrNoSatisfaction is returned when a miniscript can't be satisfied
+	// without malleability with the data provided by a Satisfier.
+	ErrNoSatisfaction = errors.New("no non-malleable satisfaction is " +
+		"available")
+)
+
+// Satisfier provides the data needed to satisfy a miniscript.
+type Satisfier interface {
+	// Sign returns the signature of the spending transaction for the
+	// passed key, including its sighash type, and whether it is
+	// available.
+	Sign(pubKey *btcec.PublicKey) ([]byte, bool)
+
+	// Preimage returns the preimage of the passed hash and whether it is
+	// available.  The hash is a SHA256, HASH256, RIPEMD160 or HASH160
+	// hash as it appears in the miniscript.
+	Preimage(hash []byte) ([]byte, bool)
+
+	// CheckOlder returns whether the relative timelock of the passed
+	// sequence number is satisfied by the spending input.
+	CheckOlder(sequence uint32) bool
+
+	// CheckAfter returns whether the absolute timelock of the passed lock
+	// time is satisfied by the spending transaction.
+	CheckAfter(lockTime uint32) bool
+}
+
+// maxInt is a metric of a satisfaction or dissatisfaction, which is invalid
+// when it does not exist.
+type maxInt struct {
+	valid bool
+	v     int
+}
+
+// val returns a valid metric with the passed value.
+func val(v int) maxInt {
+	return maxInt{valid: true, v: v}
+}
+
+// add returns the sum of the metrics, which is only valid when both are.
+func (a maxInt) add(b maxInt) maxInt {
+	return maxInt{valid: a.valid && b.valid, v: a.v + b.v}
+}
+
+// max returns the larger of the valid metrics.
+func (a maxInt) max(b maxInt) maxInt {
+	switch {
+	case !a.valid:
+		return b
+	case !b.valid:
+		return a
+	case b.v > a.v:
+		return b
+	}
+	return a
+}
+
+// witnessWeights are the metrics of the elements of a witness stack.
+type witnessWeights struct {
+	sig, key, preimage, one, zero int
+}
+
+var (
+	// countWeights count the elements of a witness stack.
+	countWeights = witnessWeights{1, 1, 1, 1, 1}
+
+	// p2wshSizeWeights and tapscriptSizeWeights are the maximum sizes of
+	// the elements of a witness stack, including their length prefix.
+	p2wshSizeWeights     = witnessWeights{1 + 72, 1 + 33, 1 + 32, 1 + 1, 1}
+	tapscriptSizeWeights = witnessWeights{1 + 65, 1 + 32, 1 + 32, 1 + 1, 1}
+)
+
+// witness returns the maximum metric of the witness stacks which satisfy and
+// dissatisfy the node, given the metrics of their elements.
+func (n *Node) witness(w witnessWeights) (maxInt, maxInt) {
+	var subs [3]struct{ sat, dsat maxInt }
+	if n.fragment != fragThresh {
+		for i, sub := range n.subs {
+			subs[i].sat, subs[i].dsat = sub.witness(w)
+		}
+	}
+	x, y, z := subs[0], subs[1], subs[2]
+	invalid := maxInt{}
+
+	switch n.fragment {
+	case fragFalse:
+		return invalid, val(0)
+
+	case fragTrue, fragOlder, fragAfter:
+		return val(0), invalid
+
+	case fragPkK:
+		return val(w.sig), val(w.zero)
+
+	case fragPkH:
+		return val(w.sig + w.key), val(w.zero + w.key)
+
+	case fragSha256, fragHash256, fragRipemd160, fragHash160:
+		return val(w.preimage), val(w.preimage)
+
+	case fragWrapA, fragWrapS, fragWrapC, fragWrapN:
+		return x.sat, x.dsat
+
+	case fragWrapD:
+		return x.sat.add(val(w.one)), val(w.zero)
+
+	case fragWrapV:
+		return x.sat, invalid
+
+	case fragWrapJ:
+		return x.sat, val(w.zero)
+
+	case fragAndV:
+		return x.sat.add(y.sat), invalid
+
+	case fragAndB:
+		return x.sat.add(y.sat), x.dsat.add(y.dsat)
+
+	case fragOrB:
+		return x.dsat.add(y.sat).max(x.sat.add(y.dsat)),
+			x.dsat.add(y.dsat)
+
+	case fragOrC:
+		return x.sat.max(x.dsat.add(y.sat)), invalid
+
+	case fragOrD:
+		return x.sat.max(x.dsat.add(y.sat)), x.dsat.add(y.dsat)
+
+	case fragOrI:
+		return x.sat.add(val(w.one)).max(y.sat.add(val(w.zero))),
+			x.dsat.add(val(w.one)).max(y.dsat.add(val(w.zero)))
+
+	case fragAndOr:
+		return x.sat.add(y.sat).max(x.dsat.add(z.sat)),
+			x.dsat.add(z.dsat)
+
+	case fragThresh:
+		// sats[i] is the maximum metric of satisfying i of the
+		// subexpressions seen so far.
+		sats := []maxInt{val(0)}
+		for _, sub := range n.subs {
+			sat, dsat := sub.witness(w)
+			next := []maxInt{sats[0].add(dsat)}
+			for i := 1; i < len(sats); i++ {
+				next = append(next, sats[i].add(dsat).max(
+					sats[i-1].add(sat)))
+			}
+			sats = append(next, sats[len(sats)-1].add(sat))
+		}
+		return sats[n.k], sats[0]
+
+	case fragMulti:
+		return val(int(n.k)*w.sig + w.zero), val(int(n.k+1) * w.zero)
+
+	case fragMultiA:
+		numKeys := len(n.keys)
+		return val(int(n.k)*w.sig + (numKeys-int(n.k))*w.zero),
+			val(numKeys * w.zero)
+	}
+	return invalid, invalid
+}
+
+// ops returns the number of non-push opcodes of the script of the node, along
+// with the maximum number of public keys CHECKMULTISIG checks when it is
+// satisfied and dissatisfied, which count towards the opcode limit.
+func (n *Node) ops() (int, maxInt, maxInt) {
+	var subs [3]struct {
+		count     int
+		sat, dsat maxInt
+	}
+	if n.fragment != fragThresh {
+		for i, sub := range n.subs {
+			subs[i].count, subs[i].sat, subs[i].dsat = sub.ops()
+		}
+	}
+	x, y, z := subs[0], subs[1], subs[2]
+	invalid := maxInt{}
+
+	switch n.fragment {
+	case fragFalse:
+		return 0, invalid, val(0)
+
+	case fragTrue:
+		return 0, val(0), invalid
+
+	case fragPkK:
+		return 0, val(0), val(0)
+
+	case fragPkH:
+		return 3, val(0), val(0)
+
+	case fragOlder, fragAfter:
+		return 1, val(0), invalid
+
+	case fragSha256, fragHash256, fragRipemd160, fragHash160:
+		return 4, val(0), val(0)
+
+	case fragWrapA:
+		return 2 + x.count, x.sat, x.dsat
+
+	case fragWrapS, fragWrapC, fragWrapN:
+		return 1 + x.count, x.sat, x.dsat
+
+	case fragWrapD:
+		return 3 + x.count, x.sat, val(0)
+
+	case fragWrapV:
+		count := x.count
+		if n.subs[0].typ.is("x") {
+			count++
+		}
+		return count, x.sat, invalid
+
+	case fragWrapJ:
+		return 4 + x.count, x.sat, val(0)
+
+	case fragAndV:
+		return x.count + y.count, x.sat.add(y.sat), invalid
+
+	case fragAndB:
+		return 1 + x.count + y.count, x.sat.add(y.sat),
+			x.dsat.add(y.dsat)
+
+	case fragOrB:
+		return 1 + x.count + y.count,
+			x.sat.add(y.dsat).max(x.dsat.add(y.sat)),
+			x.dsat.add(y.dsat)
+
+	case fragOrC:
+		return 2 + x.count + y.count, x.sat.max(x.dsat.add(y.sat)),
+			invalid
+
+	case fragOrD:
+		return 3 + x.count + y.count, x.sat.max(x.dsat.add(y.sat)),
+			x.dsat.add(y.dsat)
+
+	case fragOrI:
+		return 3 + x.count + y.count, x.sat.max(y.sat),
+			x.dsat.max(y.dsat)
+
+	case fragAndOr:
+		return 3 + x.count + y.count + z.count,
+			x.sat.add(y.sat).max(x.dsat.add(z.sat)),
+			x.dsat.add(z.dsat)
+
+	case fragThresh:
+		count := 0
+		sats := []maxInt{val(0)}
+		for _, sub := range n.subs {
+			subCount, sat, dsat := sub.ops()
+			count += subCount + 1
+			next := []maxInt{sats[0].add(dsat)}
+			for i := 1; i < len(sats); i++ {
+				next = append(next, sats[i].add(dsat).max(
+					sats[i-1].add(sat)))
+			}
+			sats = append(next, sats[len(sats)-1].add(sat))
+		}
+		return count, sats[n.k], sats[0]
+
+	case fragMulti:
+		return 1, val(len(n.keys)), val(len(n.keys))
+
+	case fragMultiA:
+		return len(n.keys) + 1, val(0), val(0)
+	}
+	return 0, invalid, invalid
+}
+
+// maxOps returns the maximum number of opcodes counting towards the opcode
+// limit a satisfaction of the node executes.
+func (n *Node) maxOps() int {
+	count, sat, _ := n.ops()
+	return count + sat.v
+}
+
+// MaxWitnessSize returns the maximum size of the witness stack which satisfies
+// the miniscript, counting each element along with its length prefix.  The
+// script itself, and the control block for tapscript, are not included.
+func (n *Node) MaxWitnessSize() (int, error) {
+	w := p2wshSizeWeights
+	if n.ctx == Tapscript {
+		w = tapscriptSizeWeights
+	}
+	sat, _ := n.witness(w)
+	if !sat.valid {
+		return 0, errors.New("miniscript can't be satisfied")
+	}
+	return sat.v, nil
+}
+
+// witnessStack is a candidate witness stack satisfying or dissatisfying a
+// node, along with the properties needed to choose between candidates.
+type witnessStack struct {
+	stack     [][]byte
+	available bool
+	hasSig    bool
+	malleable bool
+}
+
+var (
+	// noWitness is a witness stack which is not available.
+	noWitness = witnessStack{}
+
+	// emptyWitness is an available witness stack without elements.
+	emptyWitness = witnessStack{available: true}
+)
+
+// elemWitness returns an available witness stack with the passed element.
+func elemWitness(elem []byte) witnessStack {
+	return witnessStack{stack: [][]byte{elem}, available: true}
+}
+
+// size returns the serialized size of the elements of the witness stack.
+func (w witnessStack) size() int {
+	size := 0
+	for _, elem := range w.stack {
+		size += 1 + len(elem)
+	}
+	return size
+}
+
+// cat returns the witness stack with the elements of the passed one pushed on
+// top, which is available when both are.
+func (w witnessStack) cat(top witnessStack) witnessStack {
+	if !w.available || !top.available {
+		return noWitness
+	}
+	stack := make([][]byte, 0, len(w.stack)+len(top.stack))
+	stack = append(append(stack, w.stack...), top.stack...)
+	return witnessStack{
+		stack:     stack,
+		available: true,
+		hasSig:    w.hasSig || top.hasSig,
+		malleable: w.malleable || top.malleable,
+	}
+}
+
+// setMalleable returns the witness stack marked as malleable when the passed
+// condition holds.
+func (w witnessStack) setMalleable(cond bool) witnessStack {
+	w.malleable = w.malleable || cond
+	return w
+}
+
+// choose returns the preferred of the passed witness stacks.  A third party
+// can replace a witness stack with another available one which does not
+// require a signature, so choosing between such stacks is malleable.
+func choose(a, b witnessStack) witnessStack {
+	switch {
+	case !a.available:
+		return b
+	case !b.available:
+		return a
+	case !a.hasSig && b.hasSig:
+		return a
+	case !b.hasSig && a.hasSig:
+		return b
+	case !a.hasSig && !b.hasSig:
+		a.malleable, b.malleable = true, true
+	case a.malleable && !b.malleable:
+		return b
+	case b.malleable && !a.malleable:
+		return a
+	}
+
+	// Pick the smaller of two malleable or two non-malleable stacks.
+	if b.size() < a.size() {
+		return b
+	}
+	return a
+}
+
+// chooseAll returns the preferred of all the passed witness stacks.
+func chooseAll(ws ...witnessStack) witnessStack {
+	res := noWitness
+	for _, w := range ws {
+		res = choose(res, w)
+	}
+	return res
+}
+
+// checkPreimage returns whether the passed preimage hashes to the hash of the
+// passed hash node.
+func checkPreimage(n *Node, preimage []byte) bool {
+	if len(preimage) != 32 {
+		return false
+	}
+
+	var hash []byte
+	switch n.fragment {
+	case fragSha256:
+		h := sha256.Sum256(preimage)
+		hash = h[:]
+	case fragHash256:
+		hash = chainhash.DoubleHashB(preimage)
+	case fragRipemd160:
+		h := ripemd160.New()
+		h.Write(preimage)
+		hash = h.Sum(nil)
+	case fragHash160:
+		hash = btcutil.Hash160(preimage)
+	}
+	return bytes.Equal(hash, n.data)
+}
+
+// satisfy returns the preferred witness stacks which satisfy and dissatisfy the
+// node with the data provided by the passed satisfier.
+func (n *Node) satisfy(s Satisfier) (witnessStack, witnessStack) {
+	var subs [3]struct{ sat, dsat witnessStack }
+	if n.fragment != fragThresh {
+		for i, sub := range n.subs {
+			subs[i].sat, subs[i].dsat = sub.satisfy(s)
+		}
+	}
+	x, y, z := subs[0], subs[1], subs[2]
+
+	zero := elemWitness(nil)
+	one := elemWitness([]byte{1})
+
+	// sig returns the witness stack with the signature for the passed key.
+	sig := func(key *btcec.PublicKey) witnessStack {
+		sig, ok := s.Sign(key)
+		if !ok {
+			return noWitness
+		}
+		w := elemWitness(sig)
+		w.hasSig = true
+		return w
+	}
+
+	switch n.fragment {
+	case fragFalse:
+		return noWitness, emptyWitness
+
+	case fragTrue:
+		return emptyWitness, noWitness
+
+	case fragPkK:
+		return sig(n.keys[0]), zero
+
+	case fragPkH:
+		key := elemWitness(n.serializeKey(n.keys[0]))
+		return sig(n.keys[0]).cat(key), zero.cat(key)
+
+	case fragOlder:
+		if s.CheckOlder(n.k) {
+			return emptyWitness, noWitness
+		}
+		return noWitness, noWitness
+
+	case fragAfter:
+		if s.CheckAfter(n.k) {
+			return emptyWitness, noWitness
+		}
+		return noWitness, noWitness
+
+	case fragSha256, fragHash256, fragRipemd160, fragHash160:
+		sat := noWitness
+		if preimage, ok := s.Preimage(n.data); ok &&
+			checkPreimage(n, preimage) {
+
+			sat = elemWitness(preimage)
+		}
+		return sat, elemWitness(make([]byte, 32))
+
+	case fragWrapA, fragWrapS, fragWrapC, fragWrapN:
+		return x.sat, x.dsat
+
+	case fragWrapD:
+		return x.sat.cat(one), zero
+
+	case fragWrapV:
+		return x.sat, noWitness
+
+	case fragWrapJ:
+		// Any dissatisfaction of the subexpression with a nonzero top
+		// element is another dissatisfaction, so the dissatisfaction
+		// is malleable when the subexpression might have one.
+		return x.sat, zero.setMalleable(x.dsat.available &&
+			!x.dsat.hasSig)
+
+	case fragAndV:
+		return y.sat.cat(x.sat), y.dsat.cat(x.sat)
+
+	case fragAndB:
+		return y.sat.cat(x.sat), chooseAll(
+			y.dsat.cat(x.dsat),
+			y.sat.cat(x.dsat).setMalleable(true),
+			y.dsat.cat(x.sat).setMalleable(true),
+		)
+
+	case fragOrB:
+		return chooseAll(
+			y.dsat.cat(x.sat),
+			y.sat.cat(x.dsat),
+			y.sat.cat(x.sat).setMalleable(true),
+		), y.dsat.cat(x.dsat)
+
+	case fragOrC:
+		return choose(x.sat, y.sat.cat(x.dsat)), noWitness
+
+	case fragOrD:
+		return choose(x.sat, y.sat.cat(x.dsat)), y.dsat.cat(x.dsat)
+
+	case fragOrI:
+		return choose(x.sat.cat(one), y.sat.cat(zero)),
+			choose(x.dsat.cat(one), y.dsat.cat(zero))
+
+	case fragAndOr:
+		return choose(y.sat.cat(x.sat), z.sat.cat(x.dsat)),
+			choose(y.dsat.cat(x.sat), z.dsat.cat(x.dsat))
+
+	case fragThresh:
+		// sats[i] is the preferred witness stack satisfying i of the
+		// last subexpressions seen so far, which are processed from
+		// the last since the first subexpression consumes the top of
+		// the stack.
+		sats := []witnessStack{emptyWitness}
+		for i := len(n.subs) - 1; i >= 0; i-- {
+			sat, dsat := n.subs[i].satisfy(s)
+			next := []witnessStack{sats[0].cat(dsat)}
+			for j := 1; j < len(sats); j++ {
+				next = append(next, choose(sats[j].cat(dsat),
+					sats[j-1].cat(sat)))
+			}
+			sats = append(next, sats[len(sats)-1].cat(sat))
+		}
+
+		// Satisfying any number of subexpressions other than k is a
+		// dissatisfaction, but only satisfying none is not malleable.
+		dsat := noWitness
+		for i, w := range sats {
+			if i != int(n.k) {
+				dsat = choose(dsat, w.setMalleable(i != 0))
+			}
+		}
+		return sats[n.k], dsat
+
+	case fragMulti:
+		// sats[i] is the preferred witness stack with i signatures of
+		// the keys seen so far, on top of the extra element consumed
+		// by CHECKMULTISIG.
+		sats := []witnessStack{zero}
+		for _, key := range n.keys {
+			sat := sig(key)
+			next := []witnessStack{sats[0]}
+			for j := 1; j < len(sats); j++ {
+				next = append(next, choose(sats[j],
+					sats[j-1].cat(sat)))
+			}
+			sats = append(next, sats[len(sats)-1].cat(sat))
+		}
+
+		dsat := zero
+		for i := uint32(0); i < n.k; i++ {
+			dsat = dsat.cat(zero)
+		}
+		return sats[n.k], dsat
+
+	case fragMultiA:
+		// The signature for the first key is at the top of the stack,
+		// so the keys are processed from the last.
+		sats := []witnessStack{emptyWitness}
+		for i := len(n.keys) - 1; i >= 0; i-- {
+			sat := sig(n.keys[i])
+			next := []witnessStack{sats[0].cat(zero)}
+			for j := 1; j < len(sats); j++ {
+				next = append(next, choose(sats[j].cat(zero),
+					sats[j-1].cat(sat)))
+			}
+			sats = append(next, sats[len(sats)-1].cat(sat))
+		}
+		return sats[n.k], sats[0]
+	}
+	return noWitness, noWitness
+}
+
+// Satisfy returns the witness stack which satisfies the miniscript with the
+// data provided by the passed satisfier, with the element consumed first last.
+// For P2WSH, the script must be appended to spend an output, and for tapscript
+// the script and the control block.  ErrNoSatisfaction is returned unless a
+// non-malleable satisfaction which requires a signature is available.
+func (n *Node) Satisfy(s Satisfier) ([][]byte, error) {
+	sat, _ := n.satisfy(s)
+	if !sat.available || sat.malleable || !sat.hasSig {
+		return nil, ErrNoSatisfaction
+	}
+
+	// Empty elements are represented as empty rather than nil slices, so
+	// they are distinct from missing ones.
+	stack := make([][]byte, len(sat.stack))
+	for i, elem := range sat.stack {
+		stack[i] = append([]byte{}, elem...)
+	}
+	return stack, nil
+}
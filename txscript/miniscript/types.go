@@ -0,0 +1,260 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package miniscript
+
+import (
+	"strings"
+
+	"github.com/bynil/btcd/txscript"
+	"github.com/bynil/btcd/wire"
+)
+
+// typeProps are the characters of the type properties of a miniscript
+// expression, in the order of their bits in a typ:
+//
+//	B, V, K, W: the basic types base, verify, key and wrapped
+//	z, o, n, d, u: zero-arg, one-arg, nonzero, dissatisfiable and unit
+//	e, f, s, m: expressive, forced, safe and nonmalleable
+//	x: expensive verify, so v: can't be merged into the last opcode
+//	g, h, i, j: contains a relative time, relative height, absolute time or
+//	  absolute height timelock
+//	k: does not mix height and time based timelocks
+const typeProps = "BVKWzondufesmxghijk"
+
+// typ is the set of type properties of a miniscript expression, see BIP0379.
+// An expression without any basic type is invalid.
+type typ uint32
+
+// props returns the type with the properties of the passed characters.
+func props(s string) typ {
+	var t typ
+	for i := 0; i < len(s); i++ {
+		t |= 1 << strings.IndexByte(typeProps, s[i])
+	}
+	return t
+}
+
+// is returns whether the type has all the properties of the passed characters.
+func (t typ) is(s string) bool {
+	p := props(s)
+	return t&p == p
+}
+
+// only returns the properties of the type which are among the passed
+// characters.
+func (t typ) only(s string) typ {
+	return t & props(s)
+}
+
+// String returns the characters of the properties of the type.
+func (t typ) String() string {
+	var b strings.Builder
+	for i := 0; i < len(typeProps); i++ {
+		if t&(1<<i) != 0 {
+			b.WriteByte(typeProps[i])
+		}
+	}
+	return b.String()
+}
+
+// when returns the passed type when the condition holds, and no properties
+// otherwise.
+func when(cond bool, t typ) typ {
+	if cond {
+		return t
+	}
+	return 0
+}
+
+// mixesTimelocks returns whether satisfying both passed types requires both a
+// height and a time based timelock of the same kind, which no transaction can
+// satisfy.
+func mixesTimelocks(x, y typ) bool {
+	return (x.is("g") && y.is("h")) || (x.is("h") && y.is("g")) ||
+		(x.is("i") && y.is("j")) || (x.is("j") && y.is("i"))
+}
+
+// computeType returns the type of the passed node from the types of its
+// subexpressions following the type system of BIP0379.  The returned type is
+// zero when the node is not valid.
+func computeType(n *Node) typ {
+	var x, y, z typ
+	switch len(n.subs) {
+	case 3:
+		z = n.subs[2].typ
+		fallthrough
+	case 2:
+		y = n.subs[1].typ
+		fallthrough
+	case 1:
+		x = n.subs[0].typ
+	}
+
+	var t typ
+	switch n.fragment {
+	case fragFalse:
+		t = props("Bzudemsxk")
+
+	case fragTrue:
+		t = props("Bzufmxk")
+
+	case fragPkK:
+		t = props("Konudemsxk")
+
+	case fragPkH:
+		t = props("Knudemsxk")
+
+	case fragOlder:
+		t = props("Bzfmxk") |
+			when(n.k&wire.SequenceLockTimeIsSeconds != 0, props("g")) |
+			when(n.k&wire.SequenceLockTimeIsSeconds == 0, props("h"))
+
+	case fragAfter:
+		t = props("Bzfmxk") |
+			when(n.k >= txscript.LockTimeThreshold, props("i")) |
+			when(n.k < txscript.LockTimeThreshold, props("j"))
+
+	case fragSha256, fragHash256, fragRipemd160, fragHash160:
+		t = props("Bonudmk")
+
+	case fragWrapA:
+		t = when(x.is("B"), props("W")) | x.only("ghijk") |
+			x.only("udfems") | props("x")
+
+	case fragWrapS:
+		t = when(x.is("Bo"), props("W")) | x.only("ghijk") |
+			x.only("udfemsx")
+
+	case fragWrapC:
+		t = when(x.is("K"), props("B")) | x.only("ghijk") |
+			x.only("ondfem") | props("us")
+
+	case fragWrapD:
+		// d: is only unit in tapscript, where the argument of OP_IF
+		// must be minimal by consensus rather than by policy.
+		t = when(x.is("Vz"), props("B")) | when(x.is("z"), props("o")) |
+			when(x.is("f"), props("e")) | x.only("ghijk") |
+			x.only("ms") | when(n.ctx == Tapscript, props("u")) |
+			props("ndx")
+
+	case fragWrapV:
+		t = when(x.is("B"), props("V")) | x.only("ghijk") |
+			x.only("zonms") | props("fx")
+
+	case fragWrapJ:
+		t = when(x.is("Bn"), props("B")) | when(x.is("f"), props("e")) |
+			x.only("ghijk") | x.only("oums") | props("ndx")
+
+	case fragWrapN:
+		t = x.only("ghijk") | x.only("Bzondfems") | props("ux")
+
+	case fragAndV:
+		t = when(x.is("V"), y.only("KVB")) | x.only("n") |
+			when(x.is("z"), y.only("n")) |
+			when((x|y).is("z"), (x|y).only("o")) | (x & y).only("dmz") |
+			(x | y).only("s") | when(y.is("f") || x.is("s"), props("f")) |
+			y.only("ux") | (x | y).only("ghij") |
+			when((x&y).is("k") && !mixesTimelocks(x, y), props("k"))
+
+	case fragAndB:
+		t = when(y.is("W"), x.only("B")) |
+			when((x|y).is("z"), (x|y).only("o")) | x.only("n") |
+			when(x.is("z"), y.only("n")) |
+			when((x&y).is("s"), (x&y).only("e")) | (x & y).only("dzm") |
+			when((x&y).is("f") || x.is("sf") || y.is("sf"), props("f")) |
+			(x | y).only("s") | props("ux") | (x | y).only("ghij") |
+			when((x&y).is("k") && !mixesTimelocks(x, y), props("k"))
+
+	case fragOrB:
+		t = when(x.is("Bd") && y.is("Wd"), props("B")) |
+			when((x|y).is("z"), (x|y).only("o")) |
+			when((x|y).is("s") && (x&y).is("e"), (x&y).only("m")) |
+			(x & y).only("zse") | props("dux") | (x | y).only("ghij") |
+			(x & y).only("k")
+
+	case fragOrC:
+		t = when(x.is("Bdu"), y.only("V")) | when(y.is("z"), x.only("o")) |
+			when(x.is("e") && (x|y).is("s"), (x&y).only("m")) |
+			(x & y).only("zs") | props("fx") | (x | y).only("ghij") |
+			(x & y).only("k")
+
+	case fragOrD:
+		t = when(x.is("Bdu"), y.only("B")) | when(y.is("z"), x.only("o")) |
+			when(x.is("e") && (x|y).is("s"), (x&y).only("m")) |
+			(x & y).only("zs") | y.only("ufde") | props("x") |
+			(x | y).only("ghij") | (x & y).only("k")
+
+	case fragOrI:
+		t = (x & y).only("VBKufs") | when((x&y).is("z"), props("o")) |
+			when((x|y).is("f"), (x|y).only("e")) |
+			when((x|y).is("s"), (x&y).only("m")) | (x | y).only("d") |
+			props("x") | (x | y).only("ghij") | (x & y).only("k")
+
+	case fragAndOr:
+		t = when(x.is("Bdu"), (y&z).only("BKV")) | (x & y & z).only("z") |
+			when((x|(y&z)).is("z"), (x|(y&z)).only("o")) |
+			(y & z).only("u") |
+			when(x.is("s") || y.is("f"), z.only("fe")) | z.only("d") |
+			when(x.is("e") && (x|y|z).is("s"), (x&y&z).only("m")) |
+			(z & (x | y)).only("s") | props("x") | (x | y | z).only("ghij") |
+			when((x&y&z).is("k") && !mixesTimelocks(x, y), props("k"))
+
+	case fragThresh:
+		t = threshType(n)
+
+	case fragMulti:
+		t = props("Bnudemsk")
+
+	case fragMultiA:
+		t = props("Budemsk")
+	}
+
+	// An expression is only valid with exactly one basic type.
+	switch t.only("BVKW") {
+	case props("B"), props("V"), props("K"), props("W"):
+		return t
+	}
+	return 0
+}
+
+// threshType returns the type of a thresh() node.
+func threshType(n *Node) typ {
+	allE, allM := true, true
+	var args, numS int
+	acc := props("k")
+	for i, sub := range n.subs {
+		t := sub.typ
+
+		// The first subexpression must be Bdu, and the others Wdu.
+		if (i == 0 && !t.is("Bdu")) || (i > 0 && !t.is("Wdu")) {
+			return 0
+		}
+		allE = allE && t.is("e")
+		allM = allM && t.is("m")
+		if t.is("s") {
+			numS++
+		}
+		switch {
+		case t.is("z"):
+		case t.is("o"):
+			args++
+		default:
+			args += 2
+		}
+
+		// Timelocks are mixed when more than one subexpression has to be
+		// satisfied, and two of them have different kinds of timelocks.
+		noMix := (acc & t).is("k") &&
+			(n.k <= 1 || !mixesTimelocks(acc, t))
+		acc = (acc | t).only("ghij") | when(noMix, props("k"))
+	}
+
+	numSubs, k := len(n.subs), int(n.k)
+	return props("Bdu") | when(args == 0, props("z")) |
+		when(args == 1, props("o")) |
+		when(allE && numS == numSubs, props("e")) |
+		when(allE && allM && numS >= numSubs-k, props("m")) |
+		when(numS >= numSubs-k+1, props("s")) | acc
+}
@@ -92,6 +92,16 @@ func IsPayToTaproot(script []byte) bool {
 	return isWitnessTaprootScript(script)
 }
 
+// IsPayToAnchor returns true if the passed script is a pay-to-anchor (P2A)
+// script, which is the witness version 1 program 0x4e73.  Pay-to-anchor outputs
+// can be spent by anyone with an empty witness, so they are used to allow any
+// party to bump the fee of the transaction creating them.
+func IsPayToAnchor(script []byte) bool {
+	return len(script) == 4 && script[0] == OP_1 &&
+		script[1] == OP_DATA_2 &&
+		bytes.Equal(script[2:], payToAnchorProgram)
+}
+
 // IsWitnessProgram returns true if the passed script is a valid witness
 // program which is encoded according to the passed witness program version. A
 // witness program must be a small integer (from 0-16), followed by 2-40 bytes
@@ -671,3 +671,67 @@ func TestIsUnspendable(t *testing.T) {
 		}
 	}
 }
+
+// TestIsPayToAnchor ensures the IsPayToAnchor function returns the expected
+// results and that pay-to-anchor outputs can be spent under the standard
+// verification flags unlike other unknown witness programs.
+func TestIsPayToAnchor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		pkScript []byte
+		expected bool
+	}{
+		{
+			name:     "pay-to-anchor",
+			pkScript: []byte{OP_1, OP_DATA_2, 0x4e, 0x73},
+			expected: true,
+		},
+		{
+			name:     "other witness v1 program",
+			pkScript: []byte{OP_1, OP_DATA_2, 0x4e, 0x74},
+			expected: false,
+		},
+		{
+			name:     "witness v0 program",
+			pkScript: []byte{OP_0, OP_DATA_2, 0x4e, 0x73},
+			expected: false,
+		},
+		{
+			name:     "empty",
+			pkScript: []byte{},
+			expected: false,
+		},
+	}
+
+	for _, test := range tests {
+		if got := IsPayToAnchor(test.pkScript); got != test.expected {
+			t.Errorf("%s: got %v want %v", test.name, got,
+				test.expected)
+			continue
+		}
+
+		// Spend the output with an empty witness, which only succeeds
+		// under the standard flags for pay-to-anchor outputs.
+		tx := wire.NewMsgTx(2)
+		tx.AddTxIn(&wire.TxIn{})
+		tx.AddTxOut(&wire.TxOut{PkScript: []byte{OP_TRUE}})
+		prevFetcher := NewCannedPrevOutputFetcher(test.pkScript, 0)
+		vm, err := NewEngine(
+			test.pkScript, tx, 0, StandardVerifyFlags, nil, nil, 0,
+			prevFetcher,
+		)
+		if err == nil {
+			err = vm.Execute()
+		}
+		if test.expected && err != nil {
+			t.Errorf("%s: unable to spend output: %v", test.name,
+				err)
+		}
+		if !test.expected && err == nil {
+			t.Errorf("%s: spent output with an empty witness",
+				test.name)
+		}
+	}
+}
@@ -5,6 +5,7 @@
 package txscript
 
 import (
+	"bytes"
 	"errors"
 
 	"github.com/bynil/btcd/btcec/v2"
@@ -306,6 +307,10 @@ func sign(chainParams *chaincfg.Params, tx *wire.MsgTx, idx int,
 	case NullDataTy:
 		return nil, class, nil, 0,
 			errors.New("can't sign NULLDATA transactions")
+	case WitnessV0PubKeyHashTy, WitnessV0ScriptHashTy, WitnessV1TaprootTy:
+		return nil, class, nil, 0,
+			errors.New("can't sign witness programs without a " +
+				"witness, use SignTxWitnessOutput")
 	default:
 		return nil, class, nil, 0,
 			errors.New("can't sign unknown transactions")
@@ -537,6 +542,15 @@ func (sc ScriptClosure) GetScript(address btcutil.Address) ([]byte, error) {
 	return sc(address)
 }
 
+// TapscriptDB is an interface type which a ScriptDB provided to
+// SignTxWitnessOutput may implement to spend taproot outputs through the
+// script path.  It encapsulates any user state required to get a tapscript
+// leaf committed to by a taproot address and the control block proving the
+// commitment.
+type TapscriptDB interface {
+	GetTapscript(btcutil.Address) ([]byte, *ControlBlock, error)
+}
+
 // SignTxOutput signs output idx of the given tx to resolve the script given in
 // pkScript with a signature type of hashType. Any keys required will be
 // looked up by calling getKey() with the string of the given address.
@@ -580,3 +594,561 @@ func SignTxOutput(chainParams *chaincfg.Params, tx *wire.MsgTx, idx int,
 		addresses, nrequired, sigScript, previousScript)
 	return mergedScript, nil
 }
+
+// signWitnessMultiSig signs as many of the public keys in the provided
+// multisig witness script as possible, reusing the valid signatures of the
+// previous witnesses for the same script.  A nil kdb only merges the previous
+// signatures.  It returns the generated witness stack, which is padded with
+// empty signatures when fewer than nRequired signatures are available.
+func signWitnessMultiSig(tx *wire.MsgTx, idx int, sigHashes *TxSigHashes,
+	amt int64, witnessScript []byte, hashType SigHashType,
+	addresses []btcutil.Address, nRequired int, kdb KeyDB,
+	previousWitnesses ...wire.TxWitness) wire.TxWitness {
+
+	// Match the signatures of the previous witnesses to the public keys
+	// they were made with, throwing away anything that doesn't parse or
+	// verify.
+	addrToSig := make(map[string][]byte)
+	for _, previousWitness := range previousWitnesses {
+		if len(previousWitness) <= 2 || !bytes.Equal(
+			previousWitness[len(previousWitness)-1], witnessScript) {
+
+			continue
+		}
+
+	sigLoop:
+		for _, sig := range previousWitness[1 : len(previousWitness)-1] {
+			if len(sig) < 1 {
+				continue
+			}
+			pSig, err := ecdsa.ParseDERSignature(sig[:len(sig)-1])
+			if err != nil {
+				continue
+			}
+			hash, err := calcWitnessSignatureHashRaw(witnessScript,
+				sigHashes, SigHashType(sig[len(sig)-1]), tx, idx, amt)
+			if err != nil {
+				continue
+			}
+			for _, addr := range addresses {
+				pubKey := addr.(*btcutil.AddressPubKey).PubKey()
+				if pSig.Verify(hash, pubKey) {
+					aStr := addr.EncodeAddress()
+					if _, ok := addrToSig[aStr]; !ok {
+						addrToSig[aStr] = sig
+					}
+					continue sigLoop
+				}
+			}
+		}
+	}
+
+	// The witness starts with an empty item for the extra argument
+	// consumed by OP_CHECKMULTISIG, and the signatures must be in the same
+	// order as the public keys in the script.
+	witness := wire.TxWitness{nil}
+	signed := 0
+	for _, addr := range addresses {
+		if signed == nRequired {
+			break
+		}
+		sig, ok := addrToSig[addr.EncodeAddress()]
+		if !ok {
+			if kdb == nil {
+				continue
+			}
+			key, _, err := kdb.GetKey(addr)
+			if err != nil {
+				continue
+			}
+			sig, err = RawTxInWitnessSignature(tx, sigHashes, idx, amt,
+				witnessScript, hashType, key)
+			if err != nil {
+				continue
+			}
+		}
+		witness = append(witness, sig)
+		signed++
+	}
+	for i := signed; i < nRequired; i++ {
+		witness = append(witness, nil)
+	}
+
+	return append(witness, witnessScript)
+}
+
+// signWitnessScript returns the witness stack spending a pay-to-witness-
+// script-hash output with the provided witness script, which may be a
+// pay-to-pubkey, pay-to-pubkey-hash or multisig script.
+func signWitnessScript(chainParams *chaincfg.Params, tx *wire.MsgTx, idx int,
+	sigHashes *TxSigHashes, amt int64, witnessScript []byte,
+	hashType SigHashType, kdb KeyDB,
+	previousWitness wire.TxWitness) (wire.TxWitness, error) {
+
+	class, addresses, nrequired, err := ExtractPkScriptAddrs(witnessScript,
+		chainParams)
+	if err != nil {
+		return nil, err
+	}
+
+	switch class {
+	case PubKeyTy:
+		key, _, err := kdb.GetKey(addresses[0])
+		if err != nil {
+			return nil, err
+		}
+		sig, err := RawTxInWitnessSignature(tx, sigHashes, idx, amt,
+			witnessScript, hashType, key)
+		if err != nil {
+			return nil, err
+		}
+
+		return wire.TxWitness{sig, witnessScript}, nil
+	case PubKeyHashTy:
+		key, compressed, err := kdb.GetKey(addresses[0])
+		if err != nil {
+			return nil, err
+		}
+		if !compressed {
+			return nil, errors.New("witness scripts require " +
+				"compressed keys")
+		}
+		sig, err := RawTxInWitnessSignature(tx, sigHashes, idx, amt,
+			witnessScript, hashType, key)
+		if err != nil {
+			return nil, err
+		}
+		pkData := key.PubKey().SerializeCompressed()
+
+		return wire.TxWitness{sig, pkData, witnessScript}, nil
+	case MultiSigTy:
+		return signWitnessMultiSig(tx, idx, sigHashes, amt,
+			witnessScript, hashType, addresses, nrequired, kdb,
+			previousWitness), nil
+	default:
+		return nil, errors.New("can't sign unknown witness scripts")
+	}
+}
+
+// extractTapscriptKeys returns the x-only public keys of the provided
+// tapscript leaf and the number of signatures required to satisfy it.  Only
+// the single key <pubkey> OP_CHECKSIG template and the multisig <pubkey>
+// OP_CHECKSIG <pubkey> OP_CHECKSIGADD ... <n> OP_NUMEQUAL template are
+// recognized.
+func extractTapscriptKeys(script []byte) ([][]byte, int, error) {
+	errUnknown := errors.New("can't sign unknown tapscripts")
+
+	const scriptVersion = 0
+	tokenizer := MakeScriptTokenizer(scriptVersion, script)
+	var keys [][]byte
+	atThreshold := false
+	for tokenizer.Next() {
+		if len(tokenizer.Data()) != schnorr.PubKeyBytesLen {
+			atThreshold = true
+			break
+		}
+		key := tokenizer.Data()
+
+		var checkSigOp byte = OP_CHECKSIGADD
+		if len(keys) == 0 {
+			checkSigOp = OP_CHECKSIG
+		}
+		if !tokenizer.Next() || tokenizer.Opcode() != checkSigOp {
+			return nil, 0, errUnknown
+		}
+		keys = append(keys, key)
+	}
+	if tokenizer.Err() != nil {
+		return nil, 0, tokenizer.Err()
+	}
+	if len(keys) == 0 {
+		return nil, 0, errUnknown
+	}
+	if !atThreshold {
+		if len(keys) != 1 {
+			return nil, 0, errUnknown
+		}
+		return keys, 1, nil
+	}
+
+	var threshold int
+	if op := tokenizer.Opcode(); IsSmallInt(op) {
+		threshold = AsSmallInt(op)
+	} else {
+		num, err := MakeScriptNum(tokenizer.Data(), true, maxScriptNumLen)
+		if err != nil {
+			return nil, 0, errUnknown
+		}
+		threshold = int(num.Int32())
+	}
+	if !tokenizer.Next() || tokenizer.Opcode() != OP_NUMEQUAL ||
+		!tokenizer.Done() || tokenizer.Err() != nil {
+
+		return nil, 0, errUnknown
+	}
+	if threshold < 1 || threshold > len(keys) {
+		return nil, 0, errUnknown
+	}
+
+	return keys, threshold, nil
+}
+
+// signTapscript returns the witness stack spending the script path of a
+// taproot output with the provided tapscript leaf and control block.  The
+// keys of the leaf are looked up as the pubkey addresses of their even
+// and odd public keys, and the signatures of the previous witnesses for the
+// same leaf are reused.  A nil kdb only merges the previous signatures.
+func signTapscript(chainParams *chaincfg.Params, tx *wire.MsgTx, idx int,
+	sigHashes *TxSigHashes, amt int64, pkScript []byte, leafScript []byte,
+	controlBlock *ControlBlock, hashType SigHashType, kdb KeyDB,
+	previousWitnesses ...wire.TxWitness) (wire.TxWitness, error) {
+
+	keys, threshold, err := extractTapscriptKeys(leafScript)
+	if err != nil {
+		return nil, err
+	}
+	ctrlBlockBytes, err := controlBlock.ToBytes()
+	if err != nil {
+		return nil, err
+	}
+
+	// The signatures of the previous witnesses are in the reverse order
+	// of the keys, since the signature of the first key must be on top of
+	// the stack.
+	prevSigs := make([][]byte, len(keys))
+	for _, previousWitness := range previousWitnesses {
+		n := len(previousWitness)
+		if n != len(keys)+2 ||
+			!bytes.Equal(previousWitness[n-2], leafScript) ||
+			!bytes.Equal(previousWitness[n-1], ctrlBlockBytes) {
+
+			continue
+		}
+		for i := range keys {
+			if len(prevSigs[i]) == 0 {
+				prevSigs[i] = previousWitness[n-3-i]
+			}
+		}
+	}
+
+	tapLeaf := NewTapLeaf(controlBlock.LeafVersion, leafScript)
+	sigs := make([][]byte, len(keys))
+	signed := 0
+	for i, xOnlyKey := range keys {
+		if signed == threshold {
+			break
+		}
+		if len(prevSigs[i]) != 0 {
+			sigs[i] = prevSigs[i]
+			signed++
+			continue
+		}
+		if kdb == nil {
+			continue
+		}
+		for _, prefix := range []byte{0x02, 0x03} {
+			pubKey := append([]byte{prefix}, xOnlyKey...)
+			addr, err := btcutil.NewAddressPubKey(pubKey, chainParams)
+			if err != nil {
+				continue
+			}
+			key, _, err := kdb.GetKey(addr)
+			if err != nil {
+				continue
+			}
+			sig, err := RawTxInTapscriptSignature(tx, sigHashes, idx,
+				amt, pkScript, tapLeaf, hashType, key)
+			if err != nil {
+				continue
+			}
+			sigs[i] = sig
+			signed++
+			break
+		}
+	}
+	if signed == 0 {
+		return nil, errors.New("no signatures for the tapscript")
+	}
+
+	witness := make(wire.TxWitness, 0, len(keys)+2)
+	for i := len(sigs) - 1; i >= 0; i-- {
+		witness = append(witness, sigs[i])
+	}
+	return append(witness, leafScript, ctrlBlockBytes), nil
+}
+
+// signTaproot returns the witness stack spending the provided taproot output.
+// The key path is used when the key returned by kdb for the taproot address is
+// the internal key of the output, either without a script tree as defined in
+// BIP0086 or committing to the tapscript leaf of the address returned by sdb
+// when it implements TapscriptDB.  The script path with that leaf is used
+// otherwise.
+func signTaproot(chainParams *chaincfg.Params, tx *wire.MsgTx, idx int,
+	sigHashes *TxSigHashes, amt int64, pkScript []byte,
+	address btcutil.Address, hashType SigHashType, kdb KeyDB,
+	sdb ScriptDB, previousWitness wire.TxWitness) (wire.TxWitness, error) {
+
+	var leafScript []byte
+	var controlBlock *ControlBlock
+	if tdb, ok := sdb.(TapscriptDB); ok {
+		script, ctrlBlock, err := tdb.GetTapscript(address)
+		if err == nil {
+			leafScript, controlBlock = script, ctrlBlock
+		}
+	}
+
+	key, _, keyErr := kdb.GetKey(address)
+	if keyErr == nil {
+		witnessProgram := pkScript[2:]
+		if controlBlock == nil {
+			outputKey := ComputeTaprootKeyNoScript(key.PubKey())
+			if bytes.Equal(schnorr.SerializePubKey(outputKey),
+				witnessProgram) {
+
+				return TaprootWitnessSignature(tx, sigHashes, idx,
+					amt, pkScript, hashType, key)
+			}
+		} else {
+			rootHash := controlBlock.RootHash(leafScript)
+			outputKey := ComputeTaprootOutputKey(key.PubKey(), rootHash)
+			if bytes.Equal(schnorr.SerializePubKey(outputKey),
+				witnessProgram) {
+
+				sig, err := RawTxInTaprootSignature(tx, sigHashes,
+					idx, amt, pkScript, rootHash, hashType, key)
+				if err != nil {
+					return nil, err
+				}
+				return wire.TxWitness{sig}, nil
+			}
+		}
+	}
+
+	if controlBlock == nil {
+		if keyErr != nil {
+			return nil, keyErr
+		}
+		return nil, errors.New("key is not the internal key of the " +
+			"taproot output")
+	}
+
+	return signTapscript(chainParams, tx, idx, sigHashes, amt, pkScript,
+		leafScript, controlBlock, hashType, kdb, previousWitness)
+}
+
+// signWitnessProgram returns the witness stack spending an output with the
+// provided witness program, which may be a pay-to-witness-pubkey-hash,
+// pay-to-witness-script-hash or taproot output.
+func signWitnessProgram(chainParams *chaincfg.Params, tx *wire.MsgTx, idx int,
+	sigHashes *TxSigHashes, amt int64, pkScript []byte,
+	hashType SigHashType, kdb KeyDB, sdb ScriptDB,
+	previousWitness wire.TxWitness) (wire.TxWitness, error) {
+
+	class, addresses, _, err := ExtractPkScriptAddrs(pkScript, chainParams)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) == 0 {
+		return nil, errors.New("can't sign unknown witness programs")
+	}
+
+	switch class {
+	case WitnessV0PubKeyHashTy:
+		key, compressed, err := kdb.GetKey(addresses[0])
+		if err != nil {
+			return nil, err
+		}
+		if !compressed {
+			return nil, errors.New("witness programs require " +
+				"compressed keys")
+		}
+
+		return WitnessSignature(tx, sigHashes, idx, amt, pkScript,
+			hashType, key, true)
+	case WitnessV0ScriptHashTy:
+		witnessScript, err := sdb.GetScript(addresses[0])
+		if err != nil {
+			return nil, err
+		}
+
+		return signWitnessScript(chainParams, tx, idx, sigHashes, amt,
+			witnessScript, hashType, kdb, previousWitness)
+	case WitnessV1TaprootTy:
+		return signTaproot(chainParams, tx, idx, sigHashes, amt,
+			pkScript, addresses[0], hashType, kdb, sdb,
+			previousWitness)
+	default:
+		return nil, errors.New("can't sign unknown witness programs")
+	}
+}
+
+// SignTxWitnessOutput signs output idx of the given tx to resolve the script
+// given in pkScript with a signature type of hashType, like SignTxOutput, and
+// also signs segwit outputs.  It returns the signature script and witness of
+// the input.  Pay-to-witness-pubkey-hash, pay-to-witness-script-hash and
+// taproot outputs are supported, directly or nested in a pay-to-script-hash
+// output for the version 0 ones.  sigHashes must be computed with the
+// outputs spent by all inputs of tx and amt is the value of the output being
+// spent.
+//
+// The keys of taproot outputs are looked up with the taproot address for the
+// key path and with the pubkey addresses of tapscript keys for the script
+// path, which is used when sdb implements TapscriptDB.  If previousScript or
+// previousWitness is provided then the signatures of multisig scripts in them
+// are merged with the newly generated ones.
+func SignTxWitnessOutput(chainParams *chaincfg.Params, tx *wire.MsgTx, idx int,
+	sigHashes *TxSigHashes, amt int64, pkScript []byte,
+	hashType SigHashType, kdb KeyDB, sdb ScriptDB, previousScript []byte,
+	previousWitness wire.TxWitness) ([]byte, wire.TxWitness, error) {
+
+	// Outputs which aren't witness programs or pay-to-script-hash outputs
+	// with a witness program as the redeem script are signed without a
+	// witness.
+	program := pkScript
+	var sigScript []byte
+	if IsPayToScriptHash(pkScript) {
+		_, addresses, _, err := ExtractPkScriptAddrs(pkScript,
+			chainParams)
+		if err != nil {
+			return nil, nil, err
+		}
+		redeemScript, err := sdb.GetScript(addresses[0])
+		if err != nil {
+			return nil, nil, err
+		}
+		if IsWitnessProgram(redeemScript) {
+			program = redeemScript
+			sigScript, err = NewScriptBuilder().AddData(
+				redeemScript).Script()
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if !IsWitnessProgram(program) {
+		sigScript, err := SignTxOutput(chainParams, tx, idx, pkScript,
+			hashType, kdb, sdb, previousScript)
+		return sigScript, nil, err
+	}
+
+	witness, err := signWitnessProgram(chainParams, tx, idx, sigHashes, amt,
+		program, hashType, kdb, sdb, previousWitness)
+	if err != nil {
+		return nil, nil, err
+	}
+	return sigScript, witness, nil
+}
+
+// mergeWitnesses merges the witnesses witness1 and witness2 that both
+// provide signatures for the witness program in input idx of tx.  The
+// signatures of multisig witness scripts and tapscripts spending the same
+// script are merged, while otherwise the largest witness is kept, which
+// matches the behaviour of mergeScripts.
+func mergeWitnesses(chainParams *chaincfg.Params, tx *wire.MsgTx, idx int,
+	sigHashes *TxSigHashes, amt int64, program []byte, witness1,
+	witness2 wire.TxWitness) wire.TxWitness {
+
+	if len(witness1) == 0 {
+		return witness2
+	}
+	if len(witness2) == 0 {
+		return witness1
+	}
+	largest := witness1
+	if witness2.SerializeSize() > witness1.SerializeSize() {
+		largest = witness2
+	}
+	n1, n2 := len(witness1), len(witness2)
+
+	switch GetScriptClass(program) {
+	case WitnessV0ScriptHashTy:
+		witnessScript := witness1[n1-1]
+		if !bytes.Equal(witnessScript, witness2[n2-1]) {
+			return largest
+		}
+		class, addresses, nrequired, err := ExtractPkScriptAddrs(
+			witnessScript, chainParams,
+		)
+		if err != nil || class != MultiSigTy {
+			return largest
+		}
+
+		return signWitnessMultiSig(tx, idx, sigHashes, amt,
+			witnessScript, SigHashAll, addresses, nrequired, nil,
+			witness1, witness2)
+
+	case WitnessV1TaprootTy:
+		// A key path spend is already complete.
+		if n1 == 1 {
+			return witness1
+		}
+		if n2 == 1 {
+			return witness2
+		}
+		if n1 < 2 || n2 < 2 ||
+			!bytes.Equal(witness1[n1-2], witness2[n2-2]) ||
+			!bytes.Equal(witness1[n1-1], witness2[n2-1]) {
+
+			return largest
+		}
+		controlBlock, err := ParseControlBlock(witness1[n1-1])
+		if err != nil {
+			return largest
+		}
+		witness, err := signTapscript(chainParams, tx, idx, sigHashes,
+			amt, program, witness1[n1-2], controlBlock, SigHashDefault,
+			nil, witness1, witness2)
+		if err != nil {
+			return largest
+		}
+		return witness
+
+	default:
+		return largest
+	}
+}
+
+// CombineTxInScripts merges the signature scripts and witnesses of two
+// partially signed copies of input idx of tx spending pkScript, which has an
+// amount of amt.  The signatures of multisig scripts, including multisig
+// witness scripts and tapscripts, are merged, while for other scripts the
+// largest solution is kept.  The redeem and witness scripts are taken from the
+// passed solutions, and sigHashes must be computed with the outputs spent by
+// all inputs of tx.
+func CombineTxInScripts(chainParams *chaincfg.Params, tx *wire.MsgTx, idx int,
+	sigHashes *TxSigHashes, amt int64, pkScript []byte, sigScript1 []byte,
+	witness1 wire.TxWitness, sigScript2 []byte,
+	witness2 wire.TxWitness) ([]byte, wire.TxWitness) {
+
+	// The signature script of a pay-to-script-hash output with a witness
+	// program as the redeem script only pushes the redeem script.
+	const scriptVersion = 0
+	program := pkScript
+	if IsPayToScriptHash(pkScript) {
+		sigScript := sigScript1
+		if len(sigScript) == 0 {
+			sigScript = sigScript2
+		}
+		redeemScript := finalOpcodeData(scriptVersion, sigScript)
+		if IsWitnessProgram(redeemScript) {
+			program = redeemScript
+		}
+	}
+	if !IsWitnessProgram(program) {
+		// The error is ignored since the class is only used to merge
+		// scripts which parse.
+		class, addresses, nrequired, _ := ExtractPkScriptAddrs(pkScript,
+			chainParams)
+		sigScript := mergeScripts(chainParams, tx, idx, pkScript, class,
+			addresses, nrequired, sigScript1, sigScript2)
+		return sigScript, nil
+	}
+
+	sigScript := sigScript1
+	if len(sigScript2) > len(sigScript1) {
+		sigScript = sigScript2
+	}
+	witness := mergeWitnesses(chainParams, tx, idx, sigHashes, amt,
+		program, witness1, witness2)
+	return sigScript, witness
+}
@@ -5,6 +5,7 @@
 package txscript
 
 import (
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"testing"
@@ -1896,3 +1897,394 @@ func TestRawTxInTapscriptSignature(t *testing.T) {
 		})
 	}
 }
+
+// tapscriptDB implements TapscriptDB on top of a ScriptDB with a single
+// tapscript leaf.
+type tapscriptDB struct {
+	ScriptDB
+	leafScript   []byte
+	controlBlock *ControlBlock
+}
+
+// GetTapscript implements TapscriptDB by returning the leaf of the database.
+func (db *tapscriptDB) GetTapscript(btcutil.Address) ([]byte, *ControlBlock,
+	error) {
+
+	return db.leafScript, db.controlBlock, nil
+}
+
+// TestSignTxWitnessOutput ensures SignTxWitnessOutput produces valid
+// witnesses for segwit v0 and taproot outputs, merging the signatures of
+// partially signed multisig scripts.
+func TestSignTxWitnessOutput(t *testing.T) {
+	t.Parallel()
+
+	params := &chaincfg.TestNet3Params
+	const inputAmt = 1e8
+
+	newKey := func() (*btcec.PrivateKey, *btcutil.AddressPubKey) {
+		key, err := btcec.NewPrivateKey()
+		require.NoError(t, err)
+		addr, err := btcutil.NewAddressPubKey(
+			key.PubKey().SerializeCompressed(), params,
+		)
+		require.NoError(t, err)
+		return key, addr
+	}
+	key1, addr1 := newKey()
+	key2, addr2 := newKey()
+	_, addr3 := newKey()
+
+	// The P2WPKH program is also the redeem script of the nested output.
+	p2wpkhAddr, err := btcutil.NewAddressWitnessPubKeyHash(
+		btcutil.Hash160(key1.PubKey().SerializeCompressed()), params,
+	)
+	require.NoError(t, err)
+	p2wpkhScript, err := PayToAddrScript(p2wpkhAddr)
+	require.NoError(t, err)
+	np2wpkhAddr, err := btcutil.NewAddressScriptHash(p2wpkhScript, params)
+	require.NoError(t, err)
+	np2wpkhScript, err := PayToAddrScript(np2wpkhAddr)
+	require.NoError(t, err)
+
+	// A 2-of-3 multisig witness script.
+	multiSigScript, err := MultiSigScript(
+		[]*btcutil.AddressPubKey{addr1, addr2, addr3}, 2,
+	)
+	require.NoError(t, err)
+	witnessScriptHash := sha256.Sum256(multiSigScript)
+	p2wshAddr, err := btcutil.NewAddressWitnessScriptHash(
+		witnessScriptHash[:], params,
+	)
+	require.NoError(t, err)
+	p2wshScript, err := PayToAddrScript(p2wshAddr)
+	require.NoError(t, err)
+
+	// A BIP0086 taproot output, and taproot outputs committing to a single
+	// key tapscript and a 2-of-2 multisig tapscript.
+	internalKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	bip86Script, err := PayToTaprootScript(
+		ComputeTaprootKeyNoScript(internalKey.PubKey()),
+	)
+	require.NoError(t, err)
+
+	singleLeafScript, err := NewScriptBuilder().
+		AddData(schnorr.SerializePubKey(key1.PubKey())).
+		AddOp(OP_CHECKSIG).Script()
+	require.NoError(t, err)
+	multiLeafScript, err := NewScriptBuilder().
+		AddData(schnorr.SerializePubKey(key1.PubKey())).
+		AddOp(OP_CHECKSIG).
+		AddData(schnorr.SerializePubKey(key2.PubKey())).
+		AddOp(OP_CHECKSIGADD).
+		AddInt64(2).AddOp(OP_NUMEQUAL).Script()
+	require.NoError(t, err)
+
+	tapscriptOutput := func(leafScript []byte) ([]byte, *ControlBlock) {
+		tree := AssembleTaprootScriptTree(NewBaseTapLeaf(leafScript))
+		rootHash := tree.RootNode.TapHash()
+		outputKey := ComputeTaprootOutputKey(
+			internalKey.PubKey(), rootHash[:],
+		)
+		pkScript, err := PayToTaprootScript(outputKey)
+		require.NoError(t, err)
+		controlBlock := tree.LeafMerkleProofs[0].ToControlBlock(
+			internalKey.PubKey(),
+		)
+		return pkScript, &controlBlock
+	}
+	singleLeafPkScript, singleControlBlock := tapscriptOutput(
+		singleLeafScript,
+	)
+	multiLeafPkScript, multiControlBlock := tapscriptOutput(multiLeafScript)
+
+	keys := func(addrKeys map[btcutil.Address]*btcec.PrivateKey) KeyDB {
+		m := make(map[string]addressToKey)
+		for addr, key := range addrKeys {
+			m[addr.EncodeAddress()] = addressToKey{key, true}
+		}
+		return mkGetKey(m)
+	}
+	taprootAddr := func(pkScript []byte) btcutil.Address {
+		_, addrs, _, err := ExtractPkScriptAddrs(pkScript, params)
+		require.NoError(t, err)
+		return addrs[0]
+	}
+	scripts := mkGetScript(map[string][]byte{
+		np2wpkhAddr.EncodeAddress(): p2wpkhScript,
+		p2wshAddr.EncodeAddress():   multiSigScript,
+	})
+
+	tests := []struct {
+		name     string
+		pkScript []byte
+		hashType SigHashType
+
+		// kdbs are the key databases the input is signed with in turn,
+		// passing the previous results along.
+		kdbs []KeyDB
+		sdb  ScriptDB
+	}{{
+		name:     "p2wpkh",
+		pkScript: p2wpkhScript,
+		hashType: SigHashAll,
+		kdbs: []KeyDB{keys(map[btcutil.Address]*btcec.PrivateKey{
+			p2wpkhAddr: key1,
+		})},
+		sdb: scripts,
+	}, {
+		name:     "p2sh-p2wpkh",
+		pkScript: np2wpkhScript,
+		hashType: SigHashSingle | SigHashAnyOneCanPay,
+		kdbs: []KeyDB{keys(map[btcutil.Address]*btcec.PrivateKey{
+			p2wpkhAddr: key1,
+		})},
+		sdb: scripts,
+	}, {
+		name:     "p2wsh multisig merge",
+		pkScript: p2wshScript,
+		hashType: SigHashAll,
+		kdbs: []KeyDB{
+			keys(map[btcutil.Address]*btcec.PrivateKey{addr2: key2}),
+			keys(map[btcutil.Address]*btcec.PrivateKey{addr1: key1}),
+		},
+		sdb: scripts,
+	}, {
+		name:     "p2tr bip86 key path",
+		pkScript: bip86Script,
+		hashType: SigHashDefault,
+		kdbs: []KeyDB{keys(map[btcutil.Address]*btcec.PrivateKey{
+			taprootAddr(bip86Script): internalKey,
+		})},
+		sdb: scripts,
+	}, {
+		name:     "p2tr key path with tapscript",
+		pkScript: singleLeafPkScript,
+		hashType: SigHashAll,
+		kdbs: []KeyDB{keys(map[btcutil.Address]*btcec.PrivateKey{
+			taprootAddr(singleLeafPkScript): internalKey,
+		})},
+		sdb: &tapscriptDB{scripts, singleLeafScript, singleControlBlock},
+	}, {
+		name:     "p2tr script path",
+		pkScript: singleLeafPkScript,
+		hashType: SigHashDefault,
+		kdbs: []KeyDB{keys(map[btcutil.Address]*btcec.PrivateKey{
+			addr1: key1,
+		})},
+		sdb: &tapscriptDB{scripts, singleLeafScript, singleControlBlock},
+	}, {
+		name:     "p2tr script path multisig merge",
+		pkScript: multiLeafPkScript,
+		hashType: SigHashDefault,
+		kdbs: []KeyDB{
+			keys(map[btcutil.Address]*btcec.PrivateKey{addr2: key2}),
+			keys(map[btcutil.Address]*btcec.PrivateKey{addr1: key1}),
+		},
+		sdb: &tapscriptDB{scripts, multiLeafScript, multiControlBlock},
+	}}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			tx := wire.NewMsgTx(2)
+			tx.AddTxIn(&wire.TxIn{PreviousOutPoint: wire.OutPoint{
+				Index: 1,
+			}})
+			tx.AddTxOut(&wire.TxOut{Value: inputAmt - 1000,
+				PkScript: p2wpkhScript})
+			prevFetcher := NewCannedPrevOutputFetcher(
+				test.pkScript, inputAmt,
+			)
+			sigHashes := NewTxSigHashes(tx, prevFetcher)
+
+			var sigScript []byte
+			var witness wire.TxWitness
+			for i, kdb := range test.kdbs {
+				sigScript, witness, err = SignTxWitnessOutput(
+					params, tx, 0, sigHashes, inputAmt,
+					test.pkScript, test.hashType, kdb,
+					test.sdb, sigScript, witness,
+				)
+				require.NoError(t, err)
+
+				tx.TxIn[0].SignatureScript = sigScript
+				tx.TxIn[0].Witness = witness
+				vm, err := NewEngine(test.pkScript, tx, 0,
+					StandardVerifyFlags, nil, sigHashes,
+					inputAmt, prevFetcher)
+				require.NoError(t, err)
+
+				// Only the last signer completes the input.
+				err = vm.Execute()
+				if i == len(test.kdbs)-1 {
+					require.NoError(t, err)
+				} else {
+					require.Error(t, err)
+				}
+			}
+		})
+	}
+
+	// Witness programs can't be signed without a witness.
+	_, err = SignTxOutput(params, wire.NewMsgTx(2), 0, p2wpkhScript,
+		SigHashAll, mkGetKey(nil), mkGetScript(nil), nil)
+	require.Error(t, err)
+}
+
+// TestCombineTxInScripts ensures the signatures of multisig witness scripts
+// and tapscripts signed separately are merged into a valid witness, keeping
+// the sighash types they were made with.
+func TestCombineTxInScripts(t *testing.T) {
+	t.Parallel()
+
+	params := &chaincfg.TestNet3Params
+	const inputAmt = 1e8
+
+	key1, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	key2, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	addrs := make([]*btcutil.AddressPubKey, 0, 2)
+	keyDBs := make([]KeyDB, 0, 2)
+	for _, key := range []*btcec.PrivateKey{key1, key2} {
+		addr, err := btcutil.NewAddressPubKey(
+			key.PubKey().SerializeCompressed(), params,
+		)
+		require.NoError(t, err)
+		addrs = append(addrs, addr)
+		keyDBs = append(keyDBs, mkGetKey(map[string]addressToKey{
+			addr.EncodeAddress(): {key, true},
+		}))
+	}
+
+	// A 2-of-2 multisig witness script.
+	multiSigScript, err := MultiSigScript(addrs, 2)
+	require.NoError(t, err)
+	witnessScriptHash := sha256.Sum256(multiSigScript)
+	p2wshAddr, err := btcutil.NewAddressWitnessScriptHash(
+		witnessScriptHash[:], params,
+	)
+	require.NoError(t, err)
+	p2wshScript, err := PayToAddrScript(p2wshAddr)
+	require.NoError(t, err)
+
+	// A taproot output committing to a 2-of-2 multisig tapscript.
+	internalKey, err := btcec.NewPrivateKey()
+	require.NoError(t, err)
+	leafScript, err := NewScriptBuilder().
+		AddData(schnorr.SerializePubKey(key1.PubKey())).
+		AddOp(OP_CHECKSIG).
+		AddData(schnorr.SerializePubKey(key2.PubKey())).
+		AddOp(OP_CHECKSIGADD).
+		AddInt64(2).AddOp(OP_NUMEQUAL).Script()
+	require.NoError(t, err)
+	tree := AssembleTaprootScriptTree(NewBaseTapLeaf(leafScript))
+	rootHash := tree.RootNode.TapHash()
+	p2trScript, err := PayToTaprootScript(ComputeTaprootOutputKey(
+		internalKey.PubKey(), rootHash[:],
+	))
+	require.NoError(t, err)
+	controlBlock := tree.LeafMerkleProofs[0].ToControlBlock(
+		internalKey.PubKey(),
+	)
+
+	p2wshDB := mkGetScript(map[string][]byte{
+		p2wshAddr.EncodeAddress(): multiSigScript,
+	})
+	p2trDB := &tapscriptDB{mkGetScript(nil), leafScript, &controlBlock}
+	const singleAnyOneCanPay = SigHashSingle | SigHashAnyOneCanPay
+
+	tests := []struct {
+		name     string
+		pkScript []byte
+		sdb      ScriptDB
+		hashType SigHashType
+	}{{
+		name:     "p2wsh multisig",
+		pkScript: p2wshScript,
+		sdb:      p2wshDB,
+		hashType: SigHashAll,
+	}, {
+		name:     "p2wsh multisig single anyonecanpay",
+		pkScript: p2wshScript,
+		sdb:      p2wshDB,
+		hashType: singleAnyOneCanPay,
+	}, {
+		name:     "p2tr tapscript multisig",
+		pkScript: p2trScript,
+		sdb:      p2trDB,
+		hashType: SigHashDefault,
+	}, {
+		name:     "p2tr tapscript multisig single anyonecanpay",
+		pkScript: p2trScript,
+		sdb:      p2trDB,
+		hashType: singleAnyOneCanPay,
+	}}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			tx := wire.NewMsgTx(2)
+			tx.AddTxIn(&wire.TxIn{PreviousOutPoint: wire.OutPoint{
+				Index: 1,
+			}})
+			tx.AddTxOut(wire.NewTxOut(inputAmt-1000, p2wshScript))
+			prevFetcher := NewCannedPrevOutputFetcher(
+				test.pkScript, inputAmt,
+			)
+			sigHashes := NewTxSigHashes(tx, prevFetcher)
+
+			// Each key signs its own copy of the input.
+			var sigScripts [][]byte
+			var witnesses []wire.TxWitness
+			for _, kdb := range keyDBs {
+				sigScript, witness, err := SignTxWitnessOutput(
+					params, tx, 0, sigHashes, inputAmt,
+					test.pkScript, test.hashType, kdb, test.sdb,
+					nil, nil,
+				)
+				require.NoError(t, err)
+				sigScripts = append(sigScripts, sigScript)
+				witnesses = append(witnesses, witness)
+			}
+
+			sigScript, witness := CombineTxInScripts(params, tx, 0,
+				sigHashes, inputAmt, test.pkScript, sigScripts[0],
+				witnesses[0], sigScripts[1], witnesses[1])
+
+			// The signatures are kept as they were made rather than
+			// being dropped or made again with another sighash type.
+			// They are followed by the leaf script and control block
+			// of a tapscript, and are between the extra item consumed
+			// by OP_CHECKMULTISIG and the witness script otherwise.
+			witnessSigs := func(w wire.TxWitness) [][]byte {
+				if IsPayToTaproot(test.pkScript) {
+					return w[:len(w)-2]
+				}
+				return w[1 : len(w)-1]
+			}
+			sigs := witnessSigs(witness)
+			for _, previousWitness := range witnesses {
+				for _, sig := range witnessSigs(previousWitness) {
+					if len(sig) == 0 {
+						continue
+					}
+					if test.hashType != SigHashDefault {
+						require.Equal(t, test.hashType,
+							SigHashType(sig[len(sig)-1]))
+					}
+					require.Contains(t, sigs, sig)
+				}
+			}
+			tx.TxIn[0].SignatureScript = sigScript
+			tx.TxIn[0].Witness = witness
+			vm, err := NewEngine(test.pkScript, tx, 0,
+				StandardVerifyFlags, nil, sigHashes, inputAmt,
+				prevFetcher)
+			require.NoError(t, err)
+			require.NoError(t, vm.Execute())
+		})
+	}
+}
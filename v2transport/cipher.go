@@ -0,0 +1,149 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package v2transport
+
+import (
+	"encoding/binary"
+
+	"golang.org/x/crypto/chacha20"
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+const (
+	// rekeyInterval is the number of messages after which the forward
+	// secure ciphers derive a new key.
+	rekeyInterval = 224
+
+	// keySize is the size of the keys used by the ciphers.
+	keySize = chacha20.KeySize
+)
+
+// FSChaCha20 is a forward secure ChaCha20 stream cipher as defined by BIP0324.
+// It is used to encrypt the packet lengths.  Every rekeyInterval chunks, the
+// next 32 bytes of keystream replace the key.
+type FSChaCha20 struct {
+	key           [keySize]byte
+	rekeyInterval uint64
+	chunkCounter  uint64
+	cipher        *chacha20.Cipher
+}
+
+// NewFSChaCha20 returns a new forward secure ChaCha20 cipher using the passed
+// initial key.
+func NewFSChaCha20(key [keySize]byte) *FSChaCha20 {
+	return newFSChaCha20(key, rekeyInterval)
+}
+
+// newFSChaCha20 returns a new forward secure ChaCha20 cipher using the passed
+// initial key which rekeys every interval chunks.
+func newFSChaCha20(key [keySize]byte, interval uint64) *FSChaCha20 {
+	c := &FSChaCha20{key: key, rekeyInterval: interval}
+	c.resetCipher()
+	return c
+}
+
+// resetCipher creates the underlying stream cipher for the current key and
+// rekey epoch.  The keystream for an epoch starts at block counter zero.
+func (c *FSChaCha20) resetCipher() {
+	var nonce [chacha20.NonceSize]byte
+	binary.LittleEndian.PutUint64(nonce[4:],
+		c.chunkCounter/c.rekeyInterval)
+
+	// The key and nonce sizes are always valid, so an error is impossible.
+	c.cipher, _ = chacha20.NewUnauthenticatedCipher(c.key[:], nonce[:])
+}
+
+// Crypt encrypts or decrypts the passed chunk in place.
+func (c *FSChaCha20) Crypt(chunk []byte) {
+	c.cipher.XORKeyStream(chunk, chunk)
+
+	c.chunkCounter++
+	if c.chunkCounter%c.rekeyInterval == 0 {
+		var newKey [keySize]byte
+		c.cipher.XORKeyStream(newKey[:], newKey[:])
+		c.key = newKey
+		c.resetCipher()
+	}
+}
+
+// FSChaCha20Poly1305 is a forward secure ChaCha20-Poly1305 AEAD as defined by
+// BIP0324.  It is used to encrypt the packet contents.  Every rekeyInterval
+// packets, a new key is derived by encrypting 32 zero bytes with a special
+// nonce.
+type FSChaCha20Poly1305 struct {
+	key           [keySize]byte
+	rekeyInterval uint64
+	packetCounter uint64
+}
+
+// NewFSChaCha20Poly1305 returns a new forward secure ChaCha20-Poly1305 AEAD
+// using the passed initial key.
+func NewFSChaCha20Poly1305(key [keySize]byte) *FSChaCha20Poly1305 {
+	return newFSChaCha20Poly1305(key, rekeyInterval)
+}
+
+// newFSChaCha20Poly1305 returns a new forward secure ChaCha20-Poly1305 AEAD
+// using the passed initial key which rekeys every interval packets.
+func newFSChaCha20Poly1305(key [keySize]byte,
+	interval uint64) *FSChaCha20Poly1305 {
+
+	return &FSChaCha20Poly1305{key: key, rekeyInterval: interval}
+}
+
+// nonce returns the nonce for the current packet.
+func (c *FSChaCha20Poly1305) nonce() [chacha20poly1305.NonceSize]byte {
+	var nonce [chacha20poly1305.NonceSize]byte
+	binary.LittleEndian.PutUint32(nonce[:4],
+		uint32(c.packetCounter%c.rekeyInterval))
+	binary.LittleEndian.PutUint64(nonce[4:],
+		c.packetCounter/c.rekeyInterval)
+	return nonce
+}
+
+// advance moves to the next packet and rekeys when the end of the current
+// rekey epoch has been reached.
+func (c *FSChaCha20Poly1305) advance(nonce [chacha20poly1305.NonceSize]byte) {
+	c.packetCounter++
+	if c.packetCounter%c.rekeyInterval != 0 {
+		return
+	}
+
+	var rekeyNonce [chacha20poly1305.NonceSize]byte
+	copy(rekeyNonce[:4], []byte{0xff, 0xff, 0xff, 0xff})
+	copy(rekeyNonce[4:], nonce[4:])
+
+	var zeros [keySize]byte
+	aead, _ := chacha20poly1305.New(c.key[:])
+	newKey := aead.Seal(nil, rekeyNonce[:], zeros[:], nil)
+	copy(c.key[:], newKey[:keySize])
+}
+
+// Encrypt encrypts and authenticates the passed plaintext along with the
+// additional authenticated data and returns the ciphertext with the
+// authentication tag appended.
+func (c *FSChaCha20Poly1305) Encrypt(aad, plaintext []byte) []byte {
+	nonce := c.nonce()
+
+	// The key size is always valid, so an error is impossible.
+	aead, _ := chacha20poly1305.New(c.key[:])
+	ciphertext := aead.Seal(nil, nonce[:], plaintext, aad)
+
+	c.advance(nonce)
+	return ciphertext
+}
+
+// Decrypt authenticates and decrypts the passed ciphertext, which includes
+// the authentication tag, along with the additional authenticated data.  An
+// error is returned when authentication fails.
+func (c *FSChaCha20Poly1305) Decrypt(aad, ciphertext []byte) ([]byte, error) {
+	nonce := c.nonce()
+
+	// The key size is always valid, so an error is impossible.
+	aead, _ := chacha20poly1305.New(c.key[:])
+	plaintext, err := aead.Open(nil, nonce[:], ciphertext, aad)
+
+	c.advance(nonce)
+	return plaintext, err
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package v2transport
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// hexToBytes converts the passed hex string into bytes and will panic if there
+// is an error.  It must only be called with hard-coded values.
+func hexToBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic("invalid hex in source file: " + s)
+	}
+	return b
+}
+
+// hexToKey converts the passed hex string into a cipher key and will panic if
+// there is an error.  It must only be called with hard-coded values.
+func hexToKey(s string) [keySize]byte {
+	var key [keySize]byte
+	if n := copy(key[:], hexToBytes(s)); n != keySize {
+		panic("invalid key in source file: " + s)
+	}
+	return key
+}
+
+// TestFSChaCha20 ensures the forward secure stream cipher decrypts what it
+// encrypted across rekey boundaries and that rekeying changes the keystream.
+func TestFSChaCha20(t *testing.T) {
+	key := [keySize]byte{0x01, 0x02, 0x03}
+	enc := NewFSChaCha20(key)
+	dec := NewFSChaCha20(key)
+
+	var first []byte
+	for i := 0; i < rekeyInterval*3; i++ {
+		chunk := []byte{byte(i), byte(i >> 8), 0x55}
+		plaintext := append([]byte{}, chunk...)
+
+		enc.Crypt(chunk)
+		if i == 0 {
+			first = append([]byte{}, chunk...)
+		}
+		dec.Crypt(chunk)
+		if !bytes.Equal(chunk, plaintext) {
+			t.Fatalf("chunk %d: got %x, want %x", i, chunk, plaintext)
+		}
+	}
+
+	// After rekeying, encrypting the same chunk must result in a different
+	// ciphertext than it did with the initial key.
+	if enc.key == key {
+		t.Fatal("key was not rotated")
+	}
+	chunk := []byte{0x00, 0x00, 0x55}
+	fresh := NewFSChaCha20(enc.key)
+	fresh.Crypt(chunk)
+	if bytes.Equal(chunk, first) {
+		t.Fatal("keystream did not change after rekeying")
+	}
+}
+
+// TestFSChaCha20Poly1305 ensures the forward secure AEAD decrypts what it
+// encrypted across rekey boundaries and rejects tampered packets.
+func TestFSChaCha20Poly1305(t *testing.T) {
+	key := [keySize]byte{0x0a, 0x0b, 0x0c}
+	enc := NewFSChaCha20Poly1305(key)
+	dec := NewFSChaCha20Poly1305(key)
+
+	aad := []byte("aad")
+	for i := 0; i < rekeyInterval*2+1; i++ {
+		plaintext := bytes.Repeat([]byte{byte(i)}, i%50)
+		ciphertext := enc.Encrypt(aad, plaintext)
+		got, err := dec.Decrypt(aad, ciphertext)
+		if err != nil {
+			t.Fatalf("packet %d: unexpected error: %v", i, err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Fatalf("packet %d: got %x, want %x", i, got, plaintext)
+		}
+	}
+	if enc.key == key {
+		t.Fatal("key was not rotated")
+	}
+
+	// Tampering with the ciphertext or the additional authenticated data
+	// must be detected.
+	ciphertext := enc.Encrypt(aad, []byte("payload"))
+	ciphertext[0] ^= 0x01
+	if _, err := dec.Decrypt(aad, ciphertext); err == nil {
+		t.Fatal("decrypt of tampered ciphertext succeeded")
+	}
+	ciphertext = enc.Encrypt(aad, []byte("payload"))
+	if _, err := dec.Decrypt([]byte("other"), ciphertext); err == nil {
+		t.Fatal("decrypt with wrong aad succeeded")
+	}
+}
+
+// TestFSChaCha20Vectors ensures the forward secure stream cipher matches the
+// FSChaCha20 test vectors of Bitcoin Core.  Each vector encrypts the plaintext
+// once per chunk of the rekey interval, after which the key is rotated, and
+// then checks the ciphertext of the plaintext under the rotated key.
+func TestFSChaCha20Vectors(t *testing.T) {
+	tests := []struct {
+		plaintext  string
+		key        string
+		interval   uint64
+		ciphertext string
+	}{{
+		plaintext:  "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		key:        "0000000000000000000000000000000000000000000000000000000000000000",
+		interval:   256,
+		ciphertext: "a93df4ef03011f3db95f60d996e1785df5de38fc39bfcb663a47bb5561928349",
+	}, {
+		plaintext:  "01",
+		key:        "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		interval:   5,
+		ciphertext: "ea",
+	}, {
+		plaintext:  "e93fdb5c762804b9a706816aca31e35b11d2aa3080108ef46a5b1f1508819c0a",
+		key:        "8ec4c3ccdaea336bdeb245636970be01266509b33f3d2642504eaf412206207a",
+		interval:   4096,
+		ciphertext: "8bfaa4eacff308fdb4a94a5ff25bd9d0c1f84b77f81239f67ff39d6e1ac280c9",
+	}}
+
+	for i, test := range tests {
+		c := newFSChaCha20(hexToKey(test.key), test.interval)
+		for j := uint64(0); j < test.interval; j++ {
+			c.Crypt(hexToBytes(test.plaintext))
+		}
+
+		chunk := hexToBytes(test.plaintext)
+		c.Crypt(chunk)
+		if got := hex.EncodeToString(chunk); got != test.ciphertext {
+			t.Fatalf("#%d: got ciphertext %s, want %s", i, got,
+				test.ciphertext)
+		}
+	}
+}
+
+// TestFSChaCha20Poly1305Vectors ensures the forward secure AEAD matches the
+// FSChaCha20Poly1305 test vectors of Bitcoin Core, which encrypt a packet
+// after the given number of empty packets and therefore after rekeying, and
+// that the resulting packet decrypts.
+func TestFSChaCha20Poly1305Vectors(t *testing.T) {
+	tests := []struct {
+		plaintext  string
+		aad        string
+		key        string
+		index      int
+		ciphertext string
+	}{{
+		plaintext: "d6a4cb04ef0f7c09c1866ed29dc24d820e75b0491032a51b4c3366f9ca35c19e" +
+			"a3047ec6be9d45f9637b63e1cf9eb4c2523a5aab7b851ebeba87199db0e839cf" +
+			"0d5c25e50168306377aedbe9089fd2463ded88b83211cf51b73b150608cc7a60" +
+			"0d0f11b9a742948482e1b109d8faf15b450aa7322e892fa2208c6691e3fecf4c" +
+			"711191b14d75a72147",
+		aad:   "786cb9b6ebf44288974cf0",
+		key:   "5c9e1c3951a74fba66708bf9d2c217571684556b6a6a3573bff2847d38612654",
+		index: 500,
+		ciphertext: "9dcebbd3281ea3dd8e9a1ef7d55a97abd6743e56ebc0c190cb2c4e14160b385e" +
+			"0bf508dddf754bd02c7c208447c131ce23e47a4a14dfaf5dd8bc601323950f75" +
+			"4e05d46e9232f83fc5120fbbef6f5347a826ec79a93820718d4ec7a2b7cfaaa4" +
+			"4b21e16d726448b62f803811aff4f6d827ed78e738ce8a507b81a8ae13131192" +
+			"8039213de18a5120dc9b7370baca878f50ff254418de3da50c",
+	}}
+
+	for i, test := range tests {
+		key := hexToKey(test.key)
+		enc := NewFSChaCha20Poly1305(key)
+		dec := NewFSChaCha20Poly1305(key)
+		for j := 0; j < test.index; j++ {
+			dummy := enc.Encrypt(nil, nil)
+			if _, err := dec.Decrypt(nil, dummy); err != nil {
+				t.Fatalf("#%d: packet %d: unexpected error: %v",
+					i, j, err)
+			}
+		}
+
+		aad, plaintext := hexToBytes(test.aad), hexToBytes(test.plaintext)
+		ciphertext := enc.Encrypt(aad, plaintext)
+		if got := hex.EncodeToString(ciphertext); got != test.ciphertext {
+			t.Fatalf("#%d: got ciphertext %s, want %s", i, got,
+				test.ciphertext)
+		}
+		got, err := dec.Decrypt(aad, ciphertext)
+		if err != nil {
+			t.Fatalf("#%d: unexpected error: %v", i, err)
+		}
+		if !bytes.Equal(got, plaintext) {
+			t.Fatalf("#%d: got plaintext %x, want %x", i, got,
+				plaintext)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package v2transport implements the BIP0324 version 2 encrypted peer-to-peer
+transport protocol.
+
+# Transport Overview
+
+The v2 transport replaces the plaintext v1 message framing with an
+opportunistically encrypted and authenticated stream.  Both sides exchange
+ElligatorSwift encoded ephemeral public keys, which are indistinguishable from
+random bytes, followed by random garbage of random length.  The keys are used
+to derive a shared secret from which the ciphers, garbage terminators, and a
+session id are derived via HKDF-SHA256.
+
+Each message is then sent as a packet consisting of a 3-byte length encrypted
+with a forward-secure ChaCha20 stream cipher and a header byte plus the message
+contents encrypted with a forward-secure ChaCha20-Poly1305 AEAD.  Both ciphers
+are rekeyed every 224 messages so that compromising the current keys does not
+reveal past traffic.
+
+# Downgrade
+
+A responder detects v1 peers by checking whether the first 16 bytes received
+are the start of a v1 version message for the network and hands the
+connection back to the caller along with the bytes that were already read.  An
+initiator connecting to a v1-only peer is disconnected by the remote peer
+during the handshake, which is reported as ErrShouldDowngradeToV1 so the
+caller may reconnect using the v1 protocol.
+*/
+package v2transport
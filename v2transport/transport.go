@@ -0,0 +1,459 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package v2transport
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"syscall"
+
+	"github.com/bynil/btcd/btcec/v2"
+	"github.com/bynil/btcd/wire"
+	"golang.org/x/crypto/chacha20poly1305"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	// MaxGarbageLen is the maximum number of garbage bytes which may be sent
+	// after the public key during the handshake.
+	MaxGarbageLen = 4095
+
+	// garbageTerminatorLen is the length of the garbage terminators.
+	garbageTerminatorLen = 16
+
+	// lengthFieldLen is the length of the encrypted packet length.
+	lengthFieldLen = 3
+
+	// headerLen is the length of the packet header which holds the flags.
+	headerLen = 1
+
+	// ignoreBitPos is the flag in the packet header which indicates the
+	// packet is a decoy that must be ignored.
+	ignoreBitPos = 1 << 7
+
+	// maxContentsLen is the maximum length of the contents of a packet.
+	// It allows for the largest message payload along with the long form
+	// of the message type.
+	maxContentsLen = 1 + wire.CommandSize + wire.MaxMessagePayload
+
+	// v1PrefixLen is the number of bytes a responder inspects to detect
+	// a v1 peer.
+	v1PrefixLen = 16
+)
+
+var (
+	// ErrShouldDowngradeToV1 is returned by NewInitiator when the remote
+	// peer closed the connection before sending its public key, which is
+	// what a peer that only supports the v1 protocol does.  Callers are
+	// expected to reconnect using the v1 protocol.
+	ErrShouldDowngradeToV1 = errors.New("remote peer does not support " +
+		"the v2 transport")
+
+	// ErrGarbageTerminatorNotFound is returned when the remote peer did
+	// not send the garbage terminator within the maximum garbage length.
+	ErrGarbageTerminatorNotFound = errors.New("garbage terminator not found")
+
+	// ErrPacketTooLarge is returned when the remote peer announces a packet
+	// larger than the maximum allowed contents length.
+	ErrPacketTooLarge = errors.New("packet exceeds max length")
+
+	// ErrAuthentication is returned when a packet fails authentication.
+	ErrAuthentication = errors.New("packet authentication failed")
+
+	// sharedSecretSalt is the prefix of the HKDF salt.  The network magic
+	// is appended to it.
+	sharedSecretSalt = []byte("bitcoin_v2_shared_secret")
+)
+
+// Transport houses the state of an established BIP0324 v2 transport session.
+// It encrypts outgoing and decrypts incoming messages on the underlying
+// connection.
+//
+// Reading and writing use independent state so it is safe to call ReadMessage
+// and WriteMessage concurrently, however neither may be called concurrently
+// with itself.
+type Transport struct {
+	conn      net.Conn
+	reader    *bufio.Reader
+	sessionID [32]byte
+
+	sendLCipher *FSChaCha20
+	sendPCipher *FSChaCha20Poly1305
+	recvLCipher *FSChaCha20
+	recvPCipher *FSChaCha20Poly1305
+}
+
+// keys houses the keys and garbage terminators derived from the shared secret
+// for one side of the connection.
+type keys struct {
+	sendL, sendP, recvL, recvP [keySize]byte
+	sendGarbageTerminator      [garbageTerminatorLen]byte
+	recvGarbageTerminator      [garbageTerminatorLen]byte
+	sessionID                  [32]byte
+}
+
+// deriveKeys derives the session keys from the passed ECDH shared secret for
+// the given network as defined by BIP0324.
+func deriveKeys(secret []byte, btcnet wire.BitcoinNet, initiator bool) (*keys, error) {
+	var magic [4]byte
+	binary.LittleEndian.PutUint32(magic[:], uint32(btcnet))
+	salt := append(append([]byte{}, sharedSecretSalt...), magic[:]...)
+	prk := hkdf.Extract(sha256.New, secret, salt)
+
+	expand := func(info string, out []byte) error {
+		r := hkdf.Expand(sha256.New, prk, []byte(info))
+		_, err := io.ReadFull(r, out)
+		return err
+	}
+
+	var (
+		k                          keys
+		initL, initP, respL, respP [keySize]byte
+		terminators                [garbageTerminatorLen * 2]byte
+	)
+	if err := expand("initiator_L", initL[:]); err != nil {
+		return nil, err
+	}
+	if err := expand("initiator_P", initP[:]); err != nil {
+		return nil, err
+	}
+	if err := expand("responder_L", respL[:]); err != nil {
+		return nil, err
+	}
+	if err := expand("responder_P", respP[:]); err != nil {
+		return nil, err
+	}
+	if err := expand("garbage_terminators", terminators[:]); err != nil {
+		return nil, err
+	}
+	if err := expand("session_id", k.sessionID[:]); err != nil {
+		return nil, err
+	}
+
+	if initiator {
+		k.sendL, k.sendP, k.recvL, k.recvP = initL, initP, respL, respP
+		copy(k.sendGarbageTerminator[:], terminators[:garbageTerminatorLen])
+		copy(k.recvGarbageTerminator[:], terminators[garbageTerminatorLen:])
+	} else {
+		k.sendL, k.sendP, k.recvL, k.recvP = respL, respP, initL, initP
+		copy(k.sendGarbageTerminator[:], terminators[garbageTerminatorLen:])
+		copy(k.recvGarbageTerminator[:], terminators[:garbageTerminatorLen])
+	}
+
+	return &k, nil
+}
+
+// randomGarbage returns a random amount of random bytes up to MaxGarbageLen.
+func randomGarbage() ([]byte, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(MaxGarbageLen+1))
+	if err != nil {
+		return nil, err
+	}
+	garbage := make([]byte, n.Int64())
+	if _, err := rand.Read(garbage); err != nil {
+		return nil, err
+	}
+	return garbage, nil
+}
+
+// v1Prefix returns the first 16 bytes of a v1 version message for the given
+// network.
+func v1Prefix(btcnet wire.BitcoinNet) []byte {
+	var prefix [v1PrefixLen]byte
+	binary.LittleEndian.PutUint32(prefix[:4], uint32(btcnet))
+	copy(prefix[4:], wire.CmdVersion)
+	return prefix[:]
+}
+
+// prefixConn is a net.Conn which first returns the bytes that were already
+// read from the underlying connection while detecting a v1 peer.
+type prefixConn struct {
+	net.Conn
+	prefix []byte
+}
+
+// Read returns the remaining prefix bytes before reading from the underlying
+// connection.
+//
+// This is part of the net.Conn interface.
+func (c *prefixConn) Read(b []byte) (int, error) {
+	if len(c.prefix) > 0 {
+		n := copy(b, c.prefix)
+		c.prefix = c.prefix[n:]
+		return n, nil
+	}
+	return c.Conn.Read(b)
+}
+
+// isDisconnectError returns whether the passed error indicates the remote
+// peer closed the connection.
+func isDisconnectError(err error) bool {
+	return errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE)
+}
+
+// NewInitiator performs the BIP0324 handshake as the initiator of the
+// connection and returns the established transport.
+//
+// ErrShouldDowngradeToV1 is returned when the remote peer disconnects before
+// sending its public key.
+func NewInitiator(conn net.Conn, btcnet wire.BitcoinNet) (*Transport, error) {
+	privKey, ourEllswift, err := btcec.EllswiftCreate()
+	if err != nil {
+		return nil, err
+	}
+	ourGarbage, err := randomGarbage()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := writeAll(conn, ourEllswift[:], ourGarbage); err != nil {
+		if isDisconnectError(err) {
+			return nil, ErrShouldDowngradeToV1
+		}
+		return nil, err
+	}
+
+	var theirEllswift [btcec.EllswiftEncodingLen]byte
+	if _, err := io.ReadFull(conn, theirEllswift[:]); err != nil {
+		if isDisconnectError(err) {
+			return nil, ErrShouldDowngradeToV1
+		}
+		return nil, err
+	}
+
+	return completeHandshake(conn, btcnet, privKey, ourEllswift,
+		theirEllswift, ourGarbage, true)
+}
+
+// NewResponder performs the BIP0324 handshake as the responder of the
+// connection.
+//
+// When the remote peer is detected to use the v1 protocol, a nil transport is
+// returned along with a connection that must be used in place of the passed
+// one since it replays the bytes that were read during the detection.
+// Otherwise, the returned connection is the passed one.
+func NewResponder(conn net.Conn, btcnet wire.BitcoinNet) (*Transport, net.Conn, error) {
+	var theirEllswift [btcec.EllswiftEncodingLen]byte
+	if _, err := io.ReadFull(conn, theirEllswift[:v1PrefixLen]); err != nil {
+		return nil, conn, err
+	}
+	if bytes.Equal(theirEllswift[:v1PrefixLen], v1Prefix(btcnet)) {
+		prefix := append([]byte{}, theirEllswift[:v1PrefixLen]...)
+		return nil, &prefixConn{Conn: conn, prefix: prefix}, nil
+	}
+	if _, err := io.ReadFull(conn, theirEllswift[v1PrefixLen:]); err != nil {
+		return nil, conn, err
+	}
+
+	privKey, ourEllswift, err := btcec.EllswiftCreate()
+	if err != nil {
+		return nil, conn, err
+	}
+	ourGarbage, err := randomGarbage()
+	if err != nil {
+		return nil, conn, err
+	}
+	if err := writeAll(conn, ourEllswift[:], ourGarbage); err != nil {
+		return nil, conn, err
+	}
+
+	t, err := completeHandshake(conn, btcnet, privKey, ourEllswift,
+		theirEllswift, ourGarbage, false)
+	return t, conn, err
+}
+
+// writeAll writes the concatenation of the passed byte slices to the
+// connection with a single write.
+func writeAll(conn net.Conn, bufs ...[]byte) error {
+	var b []byte
+	for _, buf := range bufs {
+		b = append(b, buf...)
+	}
+	_, err := conn.Write(b)
+	return err
+}
+
+// completeHandshake derives the session keys once both public keys are known,
+// sends the garbage terminator and version packet, and then reads the remote
+// peer's garbage, garbage terminator, and version packet.
+func completeHandshake(conn net.Conn, btcnet wire.BitcoinNet,
+	privKey *btcec.PrivateKey, ourEllswift,
+	theirEllswift [btcec.EllswiftEncodingLen]byte, ourGarbage []byte,
+	initiator bool) (*Transport, error) {
+
+	secret, err := btcec.V2Ecdh(privKey, theirEllswift, ourEllswift,
+		initiator)
+	if err != nil {
+		return nil, err
+	}
+	k, err := deriveKeys(secret[:], btcnet, initiator)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Transport{
+		conn:        conn,
+		reader:      bufio.NewReader(conn),
+		sessionID:   k.sessionID,
+		sendLCipher: NewFSChaCha20(k.sendL),
+		sendPCipher: NewFSChaCha20Poly1305(k.sendP),
+		recvLCipher: NewFSChaCha20(k.recvL),
+		recvPCipher: NewFSChaCha20Poly1305(k.recvP),
+	}
+
+	// Send the garbage terminator followed by the version packet which
+	// authenticates our garbage.  There are no transport extensions, so
+	// the version packet contents are empty.
+	versionPacket := t.encryptPacket(nil, ourGarbage, false)
+	err = writeAll(conn, k.sendGarbageTerminator[:], versionPacket)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the remote peer's garbage up to and including the terminator.
+	theirGarbage, err := readGarbage(t.reader, k.recvGarbageTerminator)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the version packet, skipping any decoy packets.  Only the first
+	// packet after the garbage authenticates it.
+	aad := theirGarbage
+	for {
+		_, ignore, err := t.readPacket(aad)
+		if err != nil {
+			return nil, err
+		}
+		aad = nil
+		if !ignore {
+			break
+		}
+	}
+
+	return t, nil
+}
+
+// readGarbage reads from the connection until the passed garbage terminator
+// is found and returns the garbage that preceded it.
+func readGarbage(r io.ByteReader, terminator [garbageTerminatorLen]byte) ([]byte, error) {
+	buf := make([]byte, 0, MaxGarbageLen+garbageTerminatorLen)
+	for len(buf) < garbageTerminatorLen ||
+		!bytes.Equal(buf[len(buf)-garbageTerminatorLen:], terminator[:]) {
+
+		if len(buf) == MaxGarbageLen+garbageTerminatorLen {
+			return nil, ErrGarbageTerminatorNotFound
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		buf = append(buf, b)
+	}
+
+	return buf[:len(buf)-garbageTerminatorLen], nil
+}
+
+// encryptPacket returns the encrypted packet for the passed contents.
+func (t *Transport) encryptPacket(contents, aad []byte, ignore bool) []byte {
+	var length [lengthFieldLen]byte
+	length[0] = byte(len(contents))
+	length[1] = byte(len(contents) >> 8)
+	length[2] = byte(len(contents) >> 16)
+	t.sendLCipher.Crypt(length[:])
+
+	plaintext := make([]byte, headerLen+len(contents))
+	if ignore {
+		plaintext[0] = ignoreBitPos
+	}
+	copy(plaintext[headerLen:], contents)
+
+	packet := make([]byte, 0, lengthFieldLen+len(plaintext)+
+		chacha20poly1305.Overhead)
+	packet = append(packet, length[:]...)
+	return append(packet, t.sendPCipher.Encrypt(aad, plaintext)...)
+}
+
+// readPacket reads and decrypts the next packet from the connection.  It
+// returns the packet contents and whether the packet is a decoy to be
+// ignored.
+func (t *Transport) readPacket(aad []byte) ([]byte, bool, error) {
+	var length [lengthFieldLen]byte
+	if _, err := io.ReadFull(t.reader, length[:]); err != nil {
+		return nil, false, err
+	}
+	t.recvLCipher.Crypt(length[:])
+	contentsLen := int(length[0]) | int(length[1])<<8 | int(length[2])<<16
+	if contentsLen > maxContentsLen {
+		return nil, false, ErrPacketTooLarge
+	}
+
+	ciphertext := make([]byte, headerLen+contentsLen+
+		chacha20poly1305.Overhead)
+	if _, err := io.ReadFull(t.reader, ciphertext); err != nil {
+		return nil, false, err
+	}
+	plaintext, err := t.recvPCipher.Decrypt(aad, ciphertext)
+	if err != nil {
+		return nil, false, ErrAuthentication
+	}
+
+	ignore := plaintext[0]&ignoreBitPos != 0
+	return plaintext[headerLen:], ignore, nil
+}
+
+// SessionID returns the session id which both sides of the connection derive
+// from the shared secret.  It may be compared out of band to detect a man in
+// the middle.
+func (t *Transport) SessionID() [32]byte {
+	return t.sessionID
+}
+
+// ReadMessage reads, decrypts, and parses the next bitcoin message for the
+// provided protocol version and message encoding.  Decoy packets are skipped.
+// It returns the number of bytes read in addition to the parsed message and
+// the raw payload.  wire.ErrUnknownMessage is returned for authentic packets
+// which contain an unknown message so callers may ignore them.
+func (t *Transport) ReadMessage(pver uint32,
+	enc wire.MessageEncoding) (int, wire.Message, []byte, error) {
+
+	totalBytes := 0
+	for {
+		contents, ignore, err := t.readPacket(nil)
+		if contents != nil {
+			totalBytes += lengthFieldLen + headerLen + len(contents) +
+				chacha20poly1305.Overhead
+		}
+		if err != nil {
+			return totalBytes, nil, nil, err
+		}
+		if ignore {
+			continue
+		}
+
+		msg, payload, err := wire.DecodeV2Message(contents, pver, enc)
+		return totalBytes, msg, payload, err
+	}
+}
+
+// WriteMessage encrypts and writes the passed bitcoin message for the
+// provided protocol version and message encoding.  It returns the number of
+// bytes written.
+func (t *Transport) WriteMessage(msg wire.Message, pver uint32,
+	enc wire.MessageEncoding) (int, error) {
+
+	contents, err := wire.EncodeV2Message(msg, pver, enc)
+	if err != nil {
+		return 0, err
+	}
+	return t.conn.Write(t.encryptPacket(contents, nil, false))
+}
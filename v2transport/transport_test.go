@@ -0,0 +1,375 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package v2transport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"io"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/bynil/btcd/btcec/v2"
+	"github.com/bynil/btcd/wire"
+)
+
+// connPair returns both ends of a loopback TCP connection.  A real
+// connection is used rather than net.Pipe since both sides of the handshake
+// write before reading, which requires buffering.
+func connPair(t *testing.T) (net.Conn, net.Conn) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unable to listen: %v", err)
+	}
+	defer listener.Close()
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			accepted <- nil
+			return
+		}
+		accepted <- conn
+	}()
+
+	outbound, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unable to dial: %v", err)
+	}
+	inbound := <-accepted
+	if inbound == nil {
+		t.Fatal("unable to accept connection")
+	}
+
+	deadline := time.Now().Add(10 * time.Second)
+	outbound.SetDeadline(deadline)
+	inbound.SetDeadline(deadline)
+	return outbound, inbound
+}
+
+// handshake performs the v2 handshake over a loopback connection and returns
+// the initiator and responder transports.
+func handshake(t *testing.T) (*Transport, *Transport) {
+	t.Helper()
+
+	outbound, inbound := connPair(t)
+	t.Cleanup(func() {
+		outbound.Close()
+		inbound.Close()
+	})
+
+	type result struct {
+		transport *Transport
+		conn      net.Conn
+		err       error
+	}
+	respResult := make(chan result, 1)
+	go func() {
+		transport, conn, err := NewResponder(inbound, wire.MainNet)
+		respResult <- result{transport, conn, err}
+	}()
+
+	initiator, err := NewInitiator(outbound, wire.MainNet)
+	if err != nil {
+		t.Fatalf("NewInitiator: unexpected error: %v", err)
+	}
+	resp := <-respResult
+	if resp.err != nil {
+		t.Fatalf("NewResponder: unexpected error: %v", resp.err)
+	}
+	if resp.transport == nil {
+		t.Fatal("NewResponder: v2 peer detected as v1")
+	}
+	if resp.conn != inbound {
+		t.Fatal("NewResponder: connection unexpectedly replaced")
+	}
+
+	return initiator, resp.transport
+}
+
+// TestHandshake ensures both sides of a v2 handshake agree on the session id
+// and are able to exchange messages in both directions, including across
+// rekey boundaries.
+func TestHandshake(t *testing.T) {
+	initiator, responder := handshake(t)
+
+	if initiator.SessionID() != responder.SessionID() {
+		t.Fatalf("session id mismatch - %x != %x",
+			initiator.SessionID(), responder.SessionID())
+	}
+
+	pver := wire.ProtocolVersion
+	tests := []struct {
+		from, to *Transport
+	}{
+		{initiator, responder},
+		{responder, initiator},
+	}
+	for _, test := range tests {
+		errChan := make(chan error, 1)
+		go func() {
+			for i := 0; i < rekeyInterval*2; i++ {
+				msg := wire.NewMsgPing(uint64(i))
+				_, err := test.from.WriteMessage(msg, pver,
+					wire.LatestEncoding)
+				if err != nil {
+					errChan <- err
+					return
+				}
+			}
+			errChan <- nil
+		}()
+
+		for i := 0; i < rekeyInterval*2; i++ {
+			_, msg, _, err := test.to.ReadMessage(pver,
+				wire.LatestEncoding)
+			if err != nil {
+				t.Fatalf("ReadMessage #%d: unexpected error: %v",
+					i, err)
+			}
+			want := wire.NewMsgPing(uint64(i))
+			if !reflect.DeepEqual(msg, want) {
+				t.Fatalf("ReadMessage #%d: got %v, want %v", i,
+					msg, want)
+			}
+		}
+		if err := <-errChan; err != nil {
+			t.Fatalf("WriteMessage: unexpected error: %v", err)
+		}
+	}
+
+	// Messages without a short id must also round trip.
+	go initiator.WriteMessage(wire.NewMsgVerAck(), pver,
+		wire.LatestEncoding)
+	_, msg, _, err := responder.ReadMessage(pver, wire.LatestEncoding)
+	if err != nil {
+		t.Fatalf("ReadMessage: unexpected error: %v", err)
+	}
+	if _, ok := msg.(*wire.MsgVerAck); !ok {
+		t.Fatalf("ReadMessage: got %T, want *wire.MsgVerAck", msg)
+	}
+}
+
+// TestDecoyPackets ensures packets with the ignore bit set are skipped.
+func TestDecoyPackets(t *testing.T) {
+	initiator, responder := handshake(t)
+
+	pver := wire.ProtocolVersion
+	decoy := initiator.encryptPacket([]byte("decoy"), nil, true)
+	go func() {
+		initiator.conn.Write(decoy)
+		initiator.WriteMessage(wire.NewMsgPong(7), pver,
+			wire.LatestEncoding)
+	}()
+
+	_, msg, _, err := responder.ReadMessage(pver, wire.LatestEncoding)
+	if err != nil {
+		t.Fatalf("ReadMessage: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(msg, wire.NewMsgPong(7)) {
+		t.Fatalf("ReadMessage: got %v, want pong", msg)
+	}
+}
+
+// TestResponderDetectsV1 ensures a responder hands the connection back when
+// the remote peer uses the v1 protocol and that the bytes read during the
+// detection are replayed.
+func TestResponderDetectsV1(t *testing.T) {
+	outbound, inbound := connPair(t)
+	defer outbound.Close()
+	defer inbound.Close()
+
+	// Send a version message header followed by arbitrary bytes.  Only the
+	// header is required for the detection.
+	var v1Msg []byte
+	v1Msg = append(v1Msg, v1Prefix(wire.MainNet)...)
+	v1Msg = append(v1Msg, 0x01, 0x02, 0x03, 0x04)
+	go outbound.Write(v1Msg)
+
+	transport, conn, err := NewResponder(inbound, wire.MainNet)
+	if err != nil {
+		t.Fatalf("NewResponder: unexpected error: %v", err)
+	}
+	if transport != nil {
+		t.Fatal("NewResponder: v1 peer detected as v2")
+	}
+
+	got := make([]byte, len(v1Msg))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		t.Fatalf("Read: unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, v1Msg) {
+		t.Fatalf("replayed bytes mismatch - got %x, want %x", got, v1Msg)
+	}
+}
+
+// TestInitiatorDowngrade ensures an initiator reports that it should
+// downgrade to v1 when the remote peer disconnects during the handshake as a
+// v1 peer would.
+func TestInitiatorDowngrade(t *testing.T) {
+	outbound, inbound := connPair(t)
+	defer outbound.Close()
+
+	go func() {
+		// Read what a v1 peer would consider a message header before
+		// disconnecting due to the wrong network magic.
+		var hdr [wire.MessageHeaderSize]byte
+		io.ReadFull(inbound, hdr[:])
+		inbound.Close()
+	}()
+
+	_, err := NewInitiator(outbound, wire.MainNet)
+	if err != ErrShouldDowngradeToV1 {
+		t.Fatalf("NewInitiator: got error %v, want %v", err,
+			ErrShouldDowngradeToV1)
+	}
+}
+
+// TestPacketEncodingVectors ensures the keys derived during the handshake and
+// the encrypted packets match the BIP0324 test vectors from
+// packet_encoding_test_vectors.csv, and that the remote side of the connection
+// decrypts the packets.  Each vector encrypts a packet after the given number
+// of empty packets, so the later ones are encrypted after rekeying.
+func TestPacketEncodingVectors(t *testing.T) {
+	tests := []struct {
+		privKey      string
+		ellswiftOurs string
+		ellswiftThem string
+		initiating   bool
+		index        int
+		contents     string
+		initiatorL   string
+		initiatorP   string
+		responderL   string
+		responderP   string
+		sendTerm     string
+		recvTerm     string
+		sessionID    string
+		ciphertext   string
+	}{{
+		privKey: "61062ea5071d800bbfd59e2e8b53d47d194b095ae5a4df04936b49772ef0d4d7",
+		ellswiftOurs: "ec0adff257bbfe500c188c80b4fdd640f6b45a482bbc15fc7cef5931deff0aa1" +
+			"86f6eb9bba7b85dc4dcc28b28722de1e3d9108b985e2967045668f66098e475b",
+		ellswiftThem: "a4a94dfce69b4a2a0a099313d10f9f7e7d649d60501c9e1d274c300e0d89aafa" +
+			"ffffffffffffffffffffffffffffffffffffffffffffffffffffffff8faf88d5",
+		initiating: true,
+		index:      1,
+		contents:   "8e",
+		initiatorL: "9a6478b5fbab1f4dd2f78994b774c03211c78312786e602da75a0d1767fb55cf",
+		initiatorP: "7d0c7820ba6a4d29ce40baf2caa6035e04f1e1cefd59f3e7e59e9e5af84f1f51",
+		responderL: "17bc726421e4054ac6a1d54915085aaa766f4d3cf67bbd168e6080eac289d15e",
+		responderP: "9f0fc1c0e85fd9a8eee07e6fc41dba2ff54c7729068a239ac97c37c524cca1c0",
+		sendTerm:   "faef555dfcdb936425d84aba524758f3",
+		recvTerm:   "02cb8ff24307a6e27de3b4e7ea3fa65b",
+		sessionID:  "ce72dffb015da62b0d0f5474cab8bc72605225b0cee3f62312ec680ec5f41ba5",
+		ciphertext: "7530d2a18720162ac09c25329a60d75adf36eda3c3",
+	}, {
+		privKey: "1f9c581b35231838f0f17cf0c979835baccb7f3abbbb96ffcc318ab71e6e126f",
+		ellswiftOurs: "a1855e10e94e00baa23041d916e259f7044e491da6171269694763f018c7e636" +
+			"93d29575dcb464ac816baa1be353ba12e3876cba7628bd0bd8e755e721eb0140",
+		ellswiftThem: "fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f" +
+			"0000000000000000000000000000000000000000000000000000000000000000",
+		initiating: false,
+		index:      999,
+		contents:   "3eb1d4e98035cfd8eeb29bac969ed3824a",
+		initiatorL: "b82a0a7ce7219777f914d2ab873c5c487c56bd7b68622594d67fe029a8fa7def",
+		initiatorP: "d760ba8f62dd3d29d7d5584e310caf2540285edc6b51c640f9497e99c3536fd2",
+		responderL: "9db0c6f9a903cbab5d7b3c58273a3421eec0001814ec53236bd405131a0d8e90",
+		responderP: "23d2b5e653e6a3a8db160a2ca03d11cb5a79983babba861fcb57c38413323c0c",
+		sendTerm:   "efb64fd80acd3825ac9bc2a67216535a",
+		recvTerm:   "b3cb553453bceb002897e751ff7588bf",
+		sessionID:  "9267c54560607de73f18c563b76a2442718879c52dd39852885d4a3c9912c9ea",
+		ciphertext: "1da1bcf589f9b61872f45b7fa5371dd3f8bdf5d515b0c5f9fe9f0044afb8dc0a" +
+			"a1cd39a8c4",
+	}}
+
+	for i, test := range tests {
+		privKey, _ := btcec.PrivKeyFromBytes(hexToBytes(test.privKey))
+		var ours, theirs [btcec.EllswiftEncodingLen]byte
+		copy(ours[:], hexToBytes(test.ellswiftOurs))
+		copy(theirs[:], hexToBytes(test.ellswiftThem))
+
+		secret, err := btcec.V2Ecdh(privKey, theirs, ours,
+			test.initiating)
+		if err != nil {
+			t.Fatalf("#%d: V2Ecdh: unexpected error: %v", i, err)
+		}
+		k, err := deriveKeys(secret[:], wire.MainNet, test.initiating)
+		if err != nil {
+			t.Fatalf("#%d: deriveKeys: unexpected error: %v", i, err)
+		}
+
+		sendL, sendP := test.initiatorL, test.initiatorP
+		recvL, recvP := test.responderL, test.responderP
+		if !test.initiating {
+			sendL, sendP, recvL, recvP = recvL, recvP, sendL, sendP
+		}
+		checks := []struct {
+			name string
+			got  []byte
+			want string
+		}{
+			{"send length key", k.sendL[:], sendL},
+			{"send packet key", k.sendP[:], sendP},
+			{"receive length key", k.recvL[:], recvL},
+			{"receive packet key", k.recvP[:], recvP},
+			{"send garbage terminator", k.sendGarbageTerminator[:],
+				test.sendTerm},
+			{"receive garbage terminator", k.recvGarbageTerminator[:],
+				test.recvTerm},
+			{"session id", k.sessionID[:], test.sessionID},
+		}
+		for _, check := range checks {
+			if got := hex.EncodeToString(check.got); got != check.want {
+				t.Fatalf("#%d: got %s %s, want %s", i, check.name,
+					got, check.want)
+			}
+		}
+
+		// Encrypt the empty packets preceding the packet of the vector
+		// and ensure the remote side decrypts all of them.
+		sender := &Transport{
+			sendLCipher: NewFSChaCha20(k.sendL),
+			sendPCipher: NewFSChaCha20Poly1305(k.sendP),
+		}
+		var stream bytes.Buffer
+		for j := 0; j < test.index; j++ {
+			stream.Write(sender.encryptPacket(nil, nil, false))
+		}
+		contents := hexToBytes(test.contents)
+		packet := sender.encryptPacket(contents, nil, false)
+		if got := hex.EncodeToString(packet); got != test.ciphertext {
+			t.Fatalf("#%d: got ciphertext %s, want %s", i, got,
+				test.ciphertext)
+		}
+		stream.Write(packet)
+
+		receiver := &Transport{
+			reader:      bufio.NewReader(&stream),
+			recvLCipher: NewFSChaCha20(k.sendL),
+			recvPCipher: NewFSChaCha20Poly1305(k.sendP),
+		}
+		for j := 0; j < test.index; j++ {
+			if _, _, err := receiver.readPacket(nil); err != nil {
+				t.Fatalf("#%d: packet %d: unexpected error: %v",
+					i, j, err)
+			}
+		}
+		got, ignore, err := receiver.readPacket(nil)
+		if err != nil {
+			t.Fatalf("#%d: unexpected error: %v", i, err)
+		}
+		if ignore || !bytes.Equal(got, contents) {
+			t.Fatalf("#%d: got contents %x (ignore %v), want %x", i,
+				got, ignore, contents)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestBlockTxnWire tests the MsgBlockTxn wire encode and decode for both the
+// base and witness encodings.
+func TestBlockTxnWire(t *testing.T) {
+	hash := blockOne.Header.BlockHash()
+	msg := NewMsgBlockTxn(&hash, nil)
+	msg.AddTransaction(multiTx)
+	msg.AddTransaction(multiWitnessTx)
+
+	// Ensure the command is expected value.
+	wantCmd := "blocktxn"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgBlockTxn: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	for _, enc := range []MessageEncoding{BaseEncoding, WitnessEncoding} {
+		var buf bytes.Buffer
+		if err := msg.BtcEncode(&buf, ProtocolVersion, enc); err != nil {
+			t.Fatalf("BtcEncode: unexpected error %v", err)
+		}
+
+		var readMsg MsgBlockTxn
+		rbuf := bytes.NewReader(buf.Bytes())
+		if err := readMsg.BtcDecode(rbuf, ProtocolVersion, enc); err != nil {
+			t.Fatalf("BtcDecode: unexpected error %v", err)
+		}
+		if readMsg.BlockHash != hash {
+			t.Fatalf("BtcDecode: wrong block hash - got %v, want %v",
+				readMsg.BlockHash, hash)
+		}
+		if len(readMsg.Transactions) != len(msg.Transactions) {
+			t.Fatalf("BtcDecode: wrong number of transactions - "+
+				"got %d, want %d", len(readMsg.Transactions),
+				len(msg.Transactions))
+		}
+		for i, tx := range readMsg.Transactions {
+			want := msg.Transactions[i].TxHash()
+			if enc == WitnessEncoding {
+				if tx.WitnessHash() != msg.Transactions[i].WitnessHash() {
+					t.Fatalf("BtcDecode: tx %d witness "+
+						"mismatch:\n%s", i, spew.Sdump(tx))
+				}
+			}
+			if tx.TxHash() != want {
+				t.Fatalf("BtcDecode: tx %d mismatch:\n%s", i,
+					spew.Sdump(tx))
+			}
+		}
+	}
+}
+
+// TestBlockTxnWireErrors performs negative tests against wire encode and
+// decode of MsgBlockTxn to confirm error paths work correctly.
+func TestBlockTxnWireErrors(t *testing.T) {
+	pver := ProtocolVersion
+	hash := blockOne.Header.BlockHash()
+
+	baseMsg := NewMsgBlockTxn(&hash, []*MsgTx{blockOne.Transactions[0]})
+	var baseBuf bytes.Buffer
+	if err := baseMsg.BtcEncode(&baseBuf, pver, BaseEncoding); err != nil {
+		t.Fatalf("BtcEncode: unexpected error %v", err)
+	}
+
+	// Message that claims more transactions than fit into a block.
+	tooManyBuf := append([]byte{}, hash[:]...)
+	tooManyBuf = append(tooManyBuf, 0xfe, 0xff, 0xff, 0xff, 0x00)
+
+	tests := []struct {
+		in       *MsgBlockTxn // Value to encode
+		buf      []byte       // Wire encoding
+		pver     uint32       // Protocol version for wire encoding
+		max      int          // Max size of fixed buffer to induce errors
+		writeErr error        // Expected write error
+		readErr  error        // Expected read error
+	}{
+		// Force error in block hash.
+		{baseMsg, baseBuf.Bytes(), pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in transaction count.
+		{baseMsg, baseBuf.Bytes(), pver, 32, io.ErrShortWrite, io.EOF},
+		// Force error in transaction.
+		{baseMsg, baseBuf.Bytes(), pver, 33, io.ErrShortWrite, io.EOF},
+		// Force error due to unsupported protocol version.
+		{baseMsg, baseBuf.Bytes(), BIP0152Version - 1, 1000,
+			&MessageError{}, &MessageError{}},
+		// Force error with too many transactions.
+		{baseMsg, tooManyBuf, pver, 1000, nil, &MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BtcEncode(w, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// Decode from wire format.
+		var msg MsgBlockTxn
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BtcDecode(r, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+	}
+}
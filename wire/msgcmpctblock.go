@@ -0,0 +1,306 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/aead/siphash"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+)
+
+const (
+	// CmpctBlockShortIDSize is the number of bytes a short transaction id
+	// occupies in a cmpctblock message.
+	CmpctBlockShortIDSize = 6
+
+	// shortIDMask is the mask applied to the 64-bit SipHash output to
+	// obtain the 48-bit short transaction id.
+	shortIDMask = (1 << (CmpctBlockShortIDSize * 8)) - 1
+
+	// maxPrefilledTxIndex is the maximum index a prefilled transaction, or
+	// a transaction requested via getblocktxn, may have.  It mirrors the
+	// limit enforced by the reference implementation.
+	maxPrefilledTxIndex = 0xffff
+)
+
+// PrefilledTx houses a transaction that is sent in full as part of a compact
+// block along with its absolute index in the block.  The index is encoded
+// differentially on the wire, however it is always absolute in this struct.
+type PrefilledTx struct {
+	Index uint32
+	Tx    *MsgTx
+}
+
+// MsgCmpctBlock implements the Message interface and represents a bitcoin
+// cmpctblock message as defined by BIP0152.  It is used to relay a block
+// header along with short transaction ids for the transactions the receiving
+// peer most likely already has in its mempool, plus a list of prefilled
+// transactions (typically only the coinbase) it most likely does not.
+//
+// This message was not added until protocol versions starting with
+// BIP0152Version.
+type MsgCmpctBlock struct {
+	Header        BlockHeader
+	Nonce         uint64
+	ShortIDs      []uint64
+	PrefilledTxns []*PrefilledTx
+}
+
+// TotalTransactions returns the total number of transactions in the block
+// described by the compact block.
+func (msg *MsgCmpctBlock) TotalTransactions() int {
+	return len(msg.ShortIDs) + len(msg.PrefilledTxns)
+}
+
+// ShortIDKey returns the SipHash-2-4 key used to compute the short
+// transaction ids of the compact block.  It is the first 16 bytes of the
+// single SHA256 of the serialized block header followed by the little-endian
+// nonce.
+func (msg *MsgCmpctBlock) ShortIDKey() [16]byte {
+	var buf bytes.Buffer
+	buf.Grow(MaxBlockHeaderPayload + 8)
+	_ = writeBlockHeader(&buf, 0, &msg.Header)
+	_ = writeElement(&buf, msg.Nonce)
+
+	var key [16]byte
+	copy(key[:], chainhash.HashB(buf.Bytes()))
+	return key
+}
+
+// ShortTxID returns the 48-bit short transaction id of the passed transaction
+// hash for the given SipHash key as described by BIP0152.  The hash must be
+// the txid for version 1 compact blocks and the wtxid for version 2.
+func ShortTxID(key *[16]byte, hash *chainhash.Hash) uint64 {
+	return siphash.Sum64(hash[:], key) & shortIDMask
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	buf := binarySerializer.Borrow()
+	defer binarySerializer.Return(buf)
+
+	err := readBlockHeaderBuf(r, pver, &msg.Header, buf)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(r, buf[:8]); err != nil {
+		return err
+	}
+	msg.Nonce = littleEndian.Uint64(buf[:8])
+
+	// Read the short ids and limit them to the max number of transactions
+	// that could possibly fit into a block.
+	count, err := ReadVarIntBuf(r, pver, buf)
+	if err != nil {
+		return err
+	}
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many short ids for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+	msg.ShortIDs = make([]uint64, count)
+	for i := uint64(0); i < count; i++ {
+		if _, err := io.ReadFull(r, buf[:CmpctBlockShortIDSize]); err != nil {
+			return err
+		}
+		buf[6], buf[7] = 0, 0
+		msg.ShortIDs[i] = littleEndian.Uint64(buf[:8])
+	}
+
+	// Read the prefilled transactions.  Their indexes are differentially
+	// encoded, so convert them to absolute indexes as they are read.
+	count, err = ReadVarIntBuf(r, pver, buf)
+	if err != nil {
+		return err
+	}
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many prefilled transactions for "+
+			"message [count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	scriptBuf := scriptPool.Borrow()
+	defer scriptPool.Return(scriptBuf)
+
+	msg.PrefilledTxns = make([]*PrefilledTx, 0, count)
+	var lastIndex uint64
+	for i := uint64(0); i < count; i++ {
+		diff, err := ReadVarIntBuf(r, pver, buf)
+		if err != nil {
+			return err
+		}
+		index := diff
+		if i > 0 {
+			index += lastIndex + 1
+		}
+		if diff > maxPrefilledTxIndex || index > maxPrefilledTxIndex {
+			str := fmt.Sprintf("prefilled transaction index %d "+
+				"exceeds max %d", index, maxPrefilledTxIndex)
+			return messageError("MsgCmpctBlock.BtcDecode", str)
+		}
+		lastIndex = index
+
+		tx := MsgTx{}
+		err = tx.btcDecode(r, pver, enc, buf, scriptBuf[:])
+		if err != nil {
+			return err
+		}
+		msg.PrefilledTxns = append(msg.PrefilledTxns, &PrefilledTx{
+			Index: uint32(index),
+			Tx:    &tx,
+		})
+	}
+
+	// The prefilled indexes must all point into the block.
+	if msg.TotalTransactions() > maxTxPerBlock {
+		str := fmt.Sprintf("too many transactions for message "+
+			"[count %v, max %v]", msg.TotalTransactions(),
+			maxTxPerBlock)
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+	if count > 0 && int(lastIndex) >= msg.TotalTransactions() {
+		str := fmt.Sprintf("prefilled transaction index %d out of "+
+			"range for %d transactions", lastIndex,
+			msg.TotalTransactions())
+		return messageError("MsgCmpctBlock.BtcDecode", str)
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("cmpctblock message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgCmpctBlock.BtcEncode", str)
+	}
+
+	buf := binarySerializer.Borrow()
+	defer binarySerializer.Return(buf)
+
+	err := writeBlockHeaderBuf(w, pver, &msg.Header, buf)
+	if err != nil {
+		return err
+	}
+
+	littleEndian.PutUint64(buf[:8], msg.Nonce)
+	if _, err := w.Write(buf[:8]); err != nil {
+		return err
+	}
+
+	err = WriteVarIntBuf(w, pver, uint64(len(msg.ShortIDs)), buf)
+	if err != nil {
+		return err
+	}
+	for _, shortID := range msg.ShortIDs {
+		littleEndian.PutUint64(buf[:8], shortID)
+		_, err := w.Write(buf[:CmpctBlockShortIDSize])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = WriteVarIntBuf(w, pver, uint64(len(msg.PrefilledTxns)), buf)
+	if err != nil {
+		return err
+	}
+	var lastIndex uint32
+	for i, ptx := range msg.PrefilledTxns {
+		diff := ptx.Index
+		if i > 0 {
+			if ptx.Index <= lastIndex {
+				str := fmt.Sprintf("prefilled transaction "+
+					"indexes are not strictly increasing "+
+					"[index %d, previous %d]", ptx.Index,
+					lastIndex)
+				return messageError("MsgCmpctBlock.BtcEncode",
+					str)
+			}
+			diff -= lastIndex + 1
+		}
+		lastIndex = ptx.Index
+
+		err = WriteVarIntBuf(w, pver, uint64(diff), buf)
+		if err != nil {
+			return err
+		}
+		err = ptx.Tx.btcEncode(w, pver, enc, buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgCmpctBlock) Command() string {
+	return CmdCmpctBlock
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgCmpctBlock) MaxPayloadLength(pver uint32) uint32 {
+	return MaxBlockPayload
+}
+
+// NewMsgCmpctBlock returns a new bitcoin cmpctblock message that conforms to
+// the Message interface.  See MsgCmpctBlock for details.
+func NewMsgCmpctBlock(bh *BlockHeader, nonce uint64) *MsgCmpctBlock {
+	return &MsgCmpctBlock{
+		Header:        *bh,
+		Nonce:         nonce,
+		ShortIDs:      make([]uint64, 0),
+		PrefilledTxns: make([]*PrefilledTx, 0),
+	}
+}
+
+// NewMsgCmpctBlockFromBlock returns a new cmpctblock message describing the
+// passed block.  Only the coinbase transaction is prefilled while every other
+// transaction is referenced by its short id.  The version determines whether
+// the short ids are computed from the txid (CmpctBlockVersion1) or the wtxid
+// (CmpctBlockVersion2).
+func NewMsgCmpctBlockFromBlock(block *MsgBlock, nonce uint64,
+	version uint64) *MsgCmpctBlock {
+
+	msg := NewMsgCmpctBlock(&block.Header, nonce)
+	if len(block.Transactions) == 0 {
+		return msg
+	}
+
+	msg.PrefilledTxns = append(msg.PrefilledTxns, &PrefilledTx{
+		Index: 0,
+		Tx:    block.Transactions[0],
+	})
+
+	key := msg.ShortIDKey()
+	msg.ShortIDs = make([]uint64, 0, len(block.Transactions)-1)
+	for _, tx := range block.Transactions[1:] {
+		var hash chainhash.Hash
+		if version == CmpctBlockVersion1 {
+			hash = tx.TxHash()
+		} else {
+			hash = tx.WitnessHash()
+		}
+		msg.ShortIDs = append(msg.ShortIDs, ShortTxID(&key, &hash))
+	}
+
+	return msg
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestCmpctBlock tests the MsgCmpctBlock API.
+func TestCmpctBlock(t *testing.T) {
+	pver := ProtocolVersion
+
+	bh := &blockOne.Header
+	msg := NewMsgCmpctBlock(bh, 0x1122334455667788)
+
+	// Ensure the command is expected value.
+	wantCmd := "cmpctblock"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgCmpctBlock: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	// Ensure max payload is expected value for latest protocol version.
+	wantPayload := uint32(MaxBlockPayload)
+	maxPayload := msg.MaxPayloadLength(pver)
+	if maxPayload != wantPayload {
+		t.Errorf("MaxPayloadLength: wrong max payload length for "+
+			"protocol version %d - got %v, want %v", pver,
+			maxPayload, wantPayload)
+	}
+
+	// Older protocol versions should fail encode and decode since the
+	// message didn't exist yet.
+	var buf bytes.Buffer
+	oldPver := BIP0152Version - 1
+	if err := msg.BtcEncode(&buf, oldPver, BaseEncoding); err == nil {
+		t.Errorf("encode of MsgCmpctBlock passed for old protocol "+
+			"version %v", oldPver)
+	}
+	var readMsg MsgCmpctBlock
+	if err := readMsg.BtcDecode(&buf, oldPver, BaseEncoding); err == nil {
+		t.Errorf("decode of MsgCmpctBlock passed for old protocol "+
+			"version %v", oldPver)
+	}
+}
+
+// TestCmpctBlockFromBlock ensures compact blocks created from a full block
+// prefill the coinbase and reference the remaining transactions by short ids
+// computed from the hash appropriate for the compact block version.
+func TestCmpctBlockFromBlock(t *testing.T) {
+	block := MsgBlock{Header: blockOne.Header}
+	block.AddTransaction(blockOne.Transactions[0])
+	block.AddTransaction(multiTx)
+	block.AddTransaction(multiWitnessTx)
+
+	for _, version := range []uint64{CmpctBlockVersion1, CmpctBlockVersion2} {
+		msg := NewMsgCmpctBlockFromBlock(&block, 42, version)
+		if msg.TotalTransactions() != len(block.Transactions) {
+			t.Fatalf("version %d: wrong number of transactions - "+
+				"got %d, want %d", version,
+				msg.TotalTransactions(), len(block.Transactions))
+		}
+		if len(msg.PrefilledTxns) != 1 ||
+			msg.PrefilledTxns[0].Index != 0 ||
+			msg.PrefilledTxns[0].Tx != block.Transactions[0] {
+
+			t.Fatalf("version %d: coinbase not prefilled", version)
+		}
+
+		key := msg.ShortIDKey()
+		for i, tx := range block.Transactions[1:] {
+			hash := tx.WitnessHash()
+			if version == CmpctBlockVersion1 {
+				hash = tx.TxHash()
+			}
+			want := ShortTxID(&key, &hash)
+			if msg.ShortIDs[i] != want {
+				t.Fatalf("version %d: short id %d mismatch - "+
+					"got %x, want %x", version, i,
+					msg.ShortIDs[i], want)
+			}
+			if msg.ShortIDs[i]>>48 != 0 {
+				t.Fatalf("version %d: short id %d exceeds 48 "+
+					"bits: %x", version, i, msg.ShortIDs[i])
+			}
+		}
+	}
+
+	// The short id of a transaction with witness data must differ
+	// between the versions.
+	v1 := NewMsgCmpctBlockFromBlock(&block, 42, CmpctBlockVersion1)
+	v2 := NewMsgCmpctBlockFromBlock(&block, 42, CmpctBlockVersion2)
+	if v1.ShortIDs[1] == v2.ShortIDs[1] {
+		t.Fatalf("witness transaction has the same short id for " +
+			"both versions")
+	}
+
+	// A different nonce must result in a different key.
+	other := NewMsgCmpctBlockFromBlock(&block, 43, CmpctBlockVersion2)
+	if other.ShortIDKey() == v2.ShortIDKey() {
+		t.Fatalf("different nonces produced the same short id key")
+	}
+}
+
+// TestCmpctBlockWire tests the MsgCmpctBlock wire encode and decode.
+func TestCmpctBlockWire(t *testing.T) {
+	msg := NewMsgCmpctBlock(&blockOne.Header, 0x0807060504030201)
+	msg.ShortIDs = append(msg.ShortIDs, 0x060504030201, 0xffffffffffff)
+	msg.PrefilledTxns = append(msg.PrefilledTxns,
+		&PrefilledTx{Index: 0, Tx: blockOne.Transactions[0]},
+		&PrefilledTx{Index: 2, Tx: blockOne.Transactions[0]},
+	)
+
+	var txBuf bytes.Buffer
+	if err := blockOne.Transactions[0].Serialize(&txBuf); err != nil {
+		t.Fatalf("Serialize: unexpected error %v", err)
+	}
+
+	wantBuf := append([]byte{}, blockOneBytes[:80]...)
+	wantBuf = append(wantBuf,
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08, // Nonce
+		0x02,                               // Varint for number of short ids
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, // Short id
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // Short id
+		0x02, // Varint for number of prefilled txns
+		0x00, // Index 0
+	)
+	wantBuf = append(wantBuf, txBuf.Bytes()...)
+	wantBuf = append(wantBuf, 0x01) // Index 2 - (0 + 1)
+	wantBuf = append(wantBuf, txBuf.Bytes()...)
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("BtcEncode: unexpected error %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), wantBuf) {
+		t.Fatalf("BtcEncode\n got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(wantBuf))
+	}
+
+	var readMsg MsgCmpctBlock
+	rbuf := bytes.NewReader(wantBuf)
+	if err := readMsg.BtcDecode(rbuf, ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("BtcDecode: unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(&readMsg, msg) {
+		t.Fatalf("BtcDecode\n got: %s want: %s", spew.Sdump(&readMsg),
+			spew.Sdump(msg))
+	}
+}
+
+// TestCmpctBlockWireErrors performs negative tests against wire encode and
+// decode of MsgCmpctBlock to confirm error paths work correctly.
+func TestCmpctBlockWireErrors(t *testing.T) {
+	pver := ProtocolVersion
+
+	baseMsg := NewMsgCmpctBlock(&blockOne.Header, 1)
+	baseMsg.ShortIDs = append(baseMsg.ShortIDs, 1)
+	baseBuf := append([]byte{}, blockOneBytes[:80]...)
+	baseBuf = append(baseBuf,
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, // Nonce
+		0x01,                               // Varint for number of short ids
+		0x01, 0x00, 0x00, 0x00, 0x00, 0x00, // Short id
+		0x00, // Varint for number of prefilled txns
+	)
+
+	// Message with prefilled indexes that aren't strictly increasing.
+	unorderedMsg := NewMsgCmpctBlock(&blockOne.Header, 1)
+	unorderedMsg.PrefilledTxns = append(unorderedMsg.PrefilledTxns,
+		&PrefilledTx{Index: 1, Tx: blockOne.Transactions[0]},
+		&PrefilledTx{Index: 1, Tx: blockOne.Transactions[0]},
+	)
+
+	// Message with a prefilled index that lies outside of the block.
+	var txBuf bytes.Buffer
+	if err := blockOne.Transactions[0].Serialize(&txBuf); err != nil {
+		t.Fatalf("Serialize: unexpected error %v", err)
+	}
+	outOfRangeBuf := append([]byte{}, baseBuf[:len(baseBuf)-1]...)
+	outOfRangeBuf = append(outOfRangeBuf, 0x01, 0x02)
+	outOfRangeBuf = append(outOfRangeBuf, txBuf.Bytes()...)
+
+	tests := []struct {
+		in       *MsgCmpctBlock // Value to encode
+		buf      []byte         // Wire encoding
+		pver     uint32         // Protocol version for wire encoding
+		max      int            // Max size of fixed buffer to induce errors
+		writeErr error          // Expected write error
+		readErr  error          // Expected read error
+	}{
+		// Force error in header.
+		{baseMsg, baseBuf, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in nonce.
+		{baseMsg, baseBuf, pver, 80, io.ErrShortWrite, io.EOF},
+		// Force error in short id count.
+		{baseMsg, baseBuf, pver, 88, io.ErrShortWrite, io.EOF},
+		// Force error in short id.
+		{baseMsg, baseBuf, pver, 89, io.ErrShortWrite, io.EOF},
+		// Force error in prefilled txn count.
+		{baseMsg, baseBuf, pver, 95, io.ErrShortWrite, io.EOF},
+		// Force error due to unsupported protocol version.
+		{baseMsg, baseBuf, BIP0152Version - 1, 96, &MessageError{},
+			&MessageError{}},
+		// Force error with unordered prefilled indexes.
+		{unorderedMsg, baseBuf, pver, 1000, &MessageError{}, nil},
+		// Force error with a prefilled index outside of the block.
+		{baseMsg, outOfRangeBuf, pver, 1000, nil, &MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BtcEncode(w, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// Decode from wire format.
+		var msg MsgCmpctBlock
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BtcDecode(r, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/bynil/btcd/chaincfg/chainhash"
+)
+
+// MsgGetBlockTxn implements the Message interface and represents a bitcoin
+// getblocktxn message as defined by BIP0152.  It is used to request the
+// transactions of a compact block that could not be reconstructed from the
+// mempool.  The peer responds with a blocktxn message.
+//
+// The indexes are absolute in this struct and differentially encoded on the
+// wire.
+//
+// This message was not added until protocol versions starting with
+// BIP0152Version.
+type MsgGetBlockTxn struct {
+	BlockHash chainhash.Hash
+	Indexes   []uint32
+}
+
+// AddIndex adds a transaction index to the message.  Indexes must be added in
+// strictly increasing order.
+func (msg *MsgGetBlockTxn) AddIndex(index uint32) error {
+	if len(msg.Indexes)+1 > maxTxPerBlock {
+		str := fmt.Sprintf("too many indexes in message [max %v]",
+			maxTxPerBlock)
+		return messageError("MsgGetBlockTxn.AddIndex", str)
+	}
+
+	msg.Indexes = append(msg.Indexes, index)
+	return nil
+}
+
+// BtcDecode decodes r using the bitcoin protocol encoding into the receiver.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) BtcDecode(r io.Reader, pver uint32, enc MessageEncoding) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetBlockTxn.BtcDecode", str)
+	}
+
+	buf := binarySerializer.Borrow()
+	defer binarySerializer.Return(buf)
+
+	if _, err := io.ReadFull(r, msg.BlockHash[:]); err != nil {
+		return err
+	}
+
+	count, err := ReadVarIntBuf(r, pver, buf)
+	if err != nil {
+		return err
+	}
+	if count > maxTxPerBlock {
+		str := fmt.Sprintf("too many indexes for message "+
+			"[count %v, max %v]", count, maxTxPerBlock)
+		return messageError("MsgGetBlockTxn.BtcDecode", str)
+	}
+
+	msg.Indexes = make([]uint32, 0, count)
+	var lastIndex uint64
+	for i := uint64(0); i < count; i++ {
+		diff, err := ReadVarIntBuf(r, pver, buf)
+		if err != nil {
+			return err
+		}
+		index := diff
+		if i > 0 {
+			index += lastIndex + 1
+		}
+		if diff > maxPrefilledTxIndex || index > maxPrefilledTxIndex {
+			str := fmt.Sprintf("transaction index %d exceeds "+
+				"max %d", index, maxPrefilledTxIndex)
+			return messageError("MsgGetBlockTxn.BtcDecode", str)
+		}
+		lastIndex = index
+		msg.Indexes = append(msg.Indexes, uint32(index))
+	}
+
+	return nil
+}
+
+// BtcEncode encodes the receiver to w using the bitcoin protocol encoding.
+// This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) BtcEncode(w io.Writer, pver uint32, enc MessageEncoding) error {
+	if pver < BIP0152Version {
+		str := fmt.Sprintf("getblocktxn message invalid for protocol "+
+			"version %d", pver)
+		return messageError("MsgGetBlockTxn.BtcEncode", str)
+	}
+
+	buf := binarySerializer.Borrow()
+	defer binarySerializer.Return(buf)
+
+	if _, err := w.Write(msg.BlockHash[:]); err != nil {
+		return err
+	}
+
+	err := WriteVarIntBuf(w, pver, uint64(len(msg.Indexes)), buf)
+	if err != nil {
+		return err
+	}
+	var lastIndex uint32
+	for i, index := range msg.Indexes {
+		diff := index
+		if i > 0 {
+			if index <= lastIndex {
+				str := fmt.Sprintf("indexes are not strictly "+
+					"increasing [index %d, previous %d]",
+					index, lastIndex)
+				return messageError("MsgGetBlockTxn.BtcEncode",
+					str)
+			}
+			diff -= lastIndex + 1
+		}
+		lastIndex = index
+
+		err = WriteVarIntBuf(w, pver, uint64(diff), buf)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Command returns the protocol command string for the message.  This is part
+// of the Message interface implementation.
+func (msg *MsgGetBlockTxn) Command() string {
+	return CmdGetBlockTxn
+}
+
+// MaxPayloadLength returns the maximum length the payload can be for the
+// receiver.  This is part of the Message interface implementation.
+func (msg *MsgGetBlockTxn) MaxPayloadLength(pver uint32) uint32 {
+	// Block hash + num indexes (varInt) + max allowed indexes, each of
+	// which is a varint of at most 3 bytes since they are limited to
+	// maxPrefilledTxIndex.
+	return chainhash.HashSize + MaxVarIntPayload + (maxTxPerBlock * 3)
+}
+
+// NewMsgGetBlockTxn returns a new bitcoin getblocktxn message that conforms to
+// the Message interface.  See MsgGetBlockTxn for details.
+func NewMsgGetBlockTxn(blockHash *chainhash.Hash) *MsgGetBlockTxn {
+	return &MsgGetBlockTxn{
+		BlockHash: *blockHash,
+		Indexes:   make([]uint32, 0),
+	}
+}
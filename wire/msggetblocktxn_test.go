@@ -0,0 +1,126 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestGetBlockTxnWire tests the MsgGetBlockTxn wire encode and decode.  In
+// particular, it ensures the indexes are differentially encoded.
+func TestGetBlockTxnWire(t *testing.T) {
+	hash := blockOne.Header.BlockHash()
+	msg := NewMsgGetBlockTxn(&hash)
+	for _, index := range []uint32{1, 2, 5, 300} {
+		if err := msg.AddIndex(index); err != nil {
+			t.Fatalf("AddIndex: unexpected error %v", err)
+		}
+	}
+
+	// Ensure the command is expected value.
+	wantCmd := "getblocktxn"
+	if cmd := msg.Command(); cmd != wantCmd {
+		t.Errorf("NewMsgGetBlockTxn: wrong command - got %v want %v",
+			cmd, wantCmd)
+	}
+
+	wantBuf := append([]byte{}, hash[:]...)
+	wantBuf = append(wantBuf,
+		0x04,             // Varint for number of indexes
+		0x01,             // 1
+		0x00,             // 2 - (1 + 1)
+		0x02,             // 5 - (2 + 1)
+		0xfd, 0x26, 0x01, // 300 - (5 + 1)
+	)
+
+	var buf bytes.Buffer
+	if err := msg.BtcEncode(&buf, ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("BtcEncode: unexpected error %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), wantBuf) {
+		t.Fatalf("BtcEncode\n got: %s want: %s",
+			spew.Sdump(buf.Bytes()), spew.Sdump(wantBuf))
+	}
+
+	var readMsg MsgGetBlockTxn
+	rbuf := bytes.NewReader(wantBuf)
+	if err := readMsg.BtcDecode(rbuf, ProtocolVersion, BaseEncoding); err != nil {
+		t.Fatalf("BtcDecode: unexpected error %v", err)
+	}
+	if !reflect.DeepEqual(&readMsg, msg) {
+		t.Fatalf("BtcDecode\n got: %s want: %s", spew.Sdump(&readMsg),
+			spew.Sdump(msg))
+	}
+}
+
+// TestGetBlockTxnWireErrors performs negative tests against wire encode and
+// decode of MsgGetBlockTxn to confirm error paths work correctly.
+func TestGetBlockTxnWireErrors(t *testing.T) {
+	pver := ProtocolVersion
+	hash := blockOne.Header.BlockHash()
+
+	baseMsg := NewMsgGetBlockTxn(&hash)
+	baseMsg.AddIndex(0)
+	baseBuf := append(append([]byte{}, hash[:]...), 0x01, 0x00)
+
+	// Message with indexes that aren't strictly increasing.
+	unorderedMsg := NewMsgGetBlockTxn(&hash)
+	unorderedMsg.AddIndex(2)
+	unorderedMsg.AddIndex(2)
+
+	// Message with an index which exceeds the max allowed.
+	bigIndexBuf := append(append([]byte{}, hash[:]...), 0x01, 0xfe, 0x00,
+		0x00, 0x01, 0x00)
+
+	tests := []struct {
+		in       *MsgGetBlockTxn // Value to encode
+		buf      []byte          // Wire encoding
+		pver     uint32          // Protocol version for wire encoding
+		max      int             // Max size of fixed buffer to induce errors
+		writeErr error           // Expected write error
+		readErr  error           // Expected read error
+	}{
+		// Force error in block hash.
+		{baseMsg, baseBuf, pver, 0, io.ErrShortWrite, io.EOF},
+		// Force error in index count.
+		{baseMsg, baseBuf, pver, 32, io.ErrShortWrite, io.EOF},
+		// Force error in index.
+		{baseMsg, baseBuf, pver, 33, io.ErrShortWrite, io.EOF},
+		// Force error due to unsupported protocol version.
+		{baseMsg, baseBuf, BIP0152Version - 1, 34, &MessageError{},
+			&MessageError{}},
+		// Force error with unordered indexes.
+		{unorderedMsg, baseBuf, pver, 1000, &MessageError{}, nil},
+		// Force error with an index that is too large.
+		{baseMsg, bigIndexBuf, pver, 1000, nil, &MessageError{}},
+	}
+
+	t.Logf("Running %d tests", len(tests))
+	for i, test := range tests {
+		// Encode to wire format.
+		w := newFixedWriter(test.max)
+		err := test.in.BtcEncode(w, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.writeErr) {
+			t.Errorf("BtcEncode #%d wrong error got: %v, want: %v",
+				i, err, test.writeErr)
+			continue
+		}
+
+		// Decode from wire format.
+		var msg MsgGetBlockTxn
+		r := newFixedReader(test.max, test.buf)
+		err = msg.BtcDecode(r, test.pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("BtcDecode #%d wrong error got: %v, want: %v",
+				i, err, test.readErr)
+			continue
+		}
+	}
+}
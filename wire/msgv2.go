@@ -0,0 +1,183 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"fmt"
+	"unicode/utf8"
+)
+
+// v2ShortIDs maps the commands that have a one byte short message type ID
+// in the BIP0324 v2 transport protocol to their ID.  Commands without a short
+// ID are encoded with a zero byte followed by the full 12-byte command.
+var v2ShortIDs = map[string]uint8{
+	CmdAddr:         1,
+	CmdBlock:        2,
+	CmdBlockTxn:     3,
+	CmdCmpctBlock:   4,
+	CmdFeeFilter:    5,
+	CmdFilterAdd:    6,
+	CmdFilterClear:  7,
+	CmdFilterLoad:   8,
+	CmdGetBlocks:    9,
+	CmdGetBlockTxn:  10,
+	CmdGetData:      11,
+	CmdGetHeaders:   12,
+	CmdHeaders:      13,
+	CmdInv:          14,
+	CmdMemPool:      15,
+	CmdMerkleBlock:  16,
+	CmdNotFound:     17,
+	CmdPing:         18,
+	CmdPong:         19,
+	CmdSendCmpct:    20,
+	CmdTx:           21,
+	CmdGetCFilters:  22,
+	CmdCFilter:      23,
+	CmdGetCFHeaders: 24,
+	CmdCFHeaders:    25,
+	CmdGetCFCheckpt: 26,
+	CmdCFCheckpt:    27,
+	CmdAddrV2:       28,
+}
+
+// v2Commands maps the BIP0324 short message type IDs back to their command.
+var v2Commands = func() map[uint8]string {
+	cmds := make(map[uint8]string, len(v2ShortIDs))
+	for cmd, id := range v2ShortIDs {
+		cmds[id] = cmd
+	}
+	return cmds
+}()
+
+// EncodeV2Message returns the contents of a BIP0324 v2 transport packet for
+// the passed message.  The contents consist of the message type, which is
+// either a one byte short ID or a zero byte followed by the 12-byte command,
+// and the serialized message payload.  Unlike the v1 encoding, there is no
+// network magic, length, or checksum since the transport provides them.
+func EncodeV2Message(msg Message, pver uint32, enc MessageEncoding) ([]byte, error) {
+	cmd := msg.Command()
+	if len(cmd) > CommandSize {
+		str := fmt.Sprintf("command [%s] is too long [max %v]",
+			cmd, CommandSize)
+		return nil, messageError("EncodeV2Message", str)
+	}
+
+	var bw bytes.Buffer
+	if id, ok := v2ShortIDs[cmd]; ok {
+		bw.WriteByte(id)
+	} else {
+		var command [1 + CommandSize]byte
+		copy(command[1:], cmd)
+		bw.Write(command[:])
+	}
+	typeLen := bw.Len()
+
+	if err := msg.BtcEncode(&bw, pver, enc); err != nil {
+		return nil, err
+	}
+
+	// Enforce maximum overall message payload.
+	lenp := bw.Len() - typeLen
+	if lenp > MaxMessagePayload {
+		str := fmt.Sprintf("message payload is too large - encoded "+
+			"%d bytes, but maximum message payload is %d bytes",
+			lenp, MaxMessagePayload)
+		return nil, messageError("EncodeV2Message", str)
+	}
+
+	// Enforce maximum message payload based on the message type.
+	mpl := msg.MaxPayloadLength(pver)
+	if uint32(lenp) > mpl {
+		str := fmt.Sprintf("message payload is too large - encoded "+
+			"%d bytes, but maximum message payload size for "+
+			"messages of type [%s] is %d.", lenp, cmd, mpl)
+		return nil, messageError("EncodeV2Message", str)
+	}
+
+	return bw.Bytes(), nil
+}
+
+// DecodeV2Message parses the contents of a BIP0324 v2 transport packet into a
+// bitcoin Message for the provided protocol version and message encoding.  It
+// returns the parsed Message along with the raw payload bytes.
+// ErrUnknownMessage is returned for well-formed contents of an unknown
+// message type so callers may ignore them as they would for the v1 encoding.
+func DecodeV2Message(contents []byte, pver uint32,
+	enc MessageEncoding) (Message, []byte, error) {
+
+	if len(contents) == 0 {
+		return nil, nil, messageError("DecodeV2Message",
+			"missing message type")
+	}
+
+	var command string
+	payload := contents[1:]
+	if id := contents[0]; id != 0 {
+		cmd, ok := v2Commands[id]
+		if !ok {
+			return nil, nil, ErrUnknownMessage
+		}
+		command = cmd
+	} else {
+		if len(contents) < 1+CommandSize {
+			return nil, nil, messageError("DecodeV2Message",
+				"message type is truncated")
+		}
+
+		// The command is padded with zero bytes which must not be
+		// followed by anything else.
+		rawCmd := contents[1 : 1+CommandSize]
+		cmdLen := bytes.IndexByte(rawCmd, 0)
+		if cmdLen == -1 {
+			cmdLen = CommandSize
+		}
+		for _, b := range rawCmd[cmdLen:] {
+			if b != 0 {
+				str := fmt.Sprintf("invalid command padding %v",
+					rawCmd)
+				return nil, nil, messageError("DecodeV2Message",
+					str)
+			}
+		}
+		command = string(rawCmd[:cmdLen])
+		payload = contents[1+CommandSize:]
+
+		if !utf8.ValidString(command) {
+			str := fmt.Sprintf("invalid command %v", []byte(command))
+			return nil, nil, messageError("DecodeV2Message", str)
+		}
+	}
+
+	if len(payload) > MaxMessagePayload {
+		str := fmt.Sprintf("message payload is too large - %d "+
+			"bytes, but max message payload is %d bytes.",
+			len(payload), MaxMessagePayload)
+		return nil, nil, messageError("DecodeV2Message", str)
+	}
+
+	msg, err := makeEmptyMessage(command)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mpl := msg.MaxPayloadLength(pver)
+	if uint32(len(payload)) > mpl {
+		str := fmt.Sprintf("payload exceeds max length - %v bytes, "+
+			"but max payload size for messages of type [%v] is %v.",
+			len(payload), command, mpl)
+		return nil, nil, messageError("DecodeV2Message", str)
+	}
+
+	// NOTE: This must be a *bytes.Buffer since the MsgVersion BtcDecode
+	// function requires it.
+	pr := bytes.NewBuffer(payload)
+	if err := msg.BtcDecode(pr, pver, enc); err != nil {
+		return nil, nil, err
+	}
+
+	return msg, payload, nil
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package wire
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/davecgh/go-spew/spew"
+)
+
+// TestV2Message tests encoding and decoding of BIP0324 v2 transport packet
+// contents for messages with and without a short message type ID.
+func TestV2Message(t *testing.T) {
+	pver := ProtocolVersion
+
+	tests := []struct {
+		in      Message // Message to encode
+		typeBuf []byte  // Expected message type encoding
+	}{
+		{NewMsgPing(123123), []byte{18}},
+		{NewMsgPong(123123), []byte{19}},
+		{NewMsgSendCmpct(true, CmpctBlockVersion2), []byte{20}},
+		{NewMsgAddrV2(), []byte{28}},
+		{NewMsgVerAck(), []byte{
+			0x00, 'v', 'e', 'r', 'a', 'c', 'k', 0, 0, 0, 0, 0, 0,
+		}},
+		{NewMsgSendAddrV2(), []byte{
+			0x00, 's', 'e', 'n', 'd', 'a', 'd', 'd', 'r', 'v', '2',
+			0, 0,
+		}},
+	}
+
+	for i, test := range tests {
+		contents, err := EncodeV2Message(test.in, pver, BaseEncoding)
+		if err != nil {
+			t.Fatalf("EncodeV2Message #%d: unexpected error %v", i, err)
+		}
+
+		var payload bytes.Buffer
+		if err := test.in.BtcEncode(&payload, pver, BaseEncoding); err != nil {
+			t.Fatalf("BtcEncode #%d: unexpected error %v", i, err)
+		}
+		want := append(append([]byte{}, test.typeBuf...),
+			payload.Bytes()...)
+		if !bytes.Equal(contents, want) {
+			t.Errorf("EncodeV2Message #%d\n got: %s want: %s", i,
+				spew.Sdump(contents), spew.Sdump(want))
+			continue
+		}
+
+		msg, rawPayload, err := DecodeV2Message(contents, pver,
+			BaseEncoding)
+		if err != nil {
+			t.Errorf("DecodeV2Message #%d: unexpected error %v", i,
+				err)
+			continue
+		}
+		if !reflect.DeepEqual(msg, test.in) {
+			t.Errorf("DecodeV2Message #%d\n got: %s want: %s", i,
+				spew.Sdump(msg), spew.Sdump(test.in))
+			continue
+		}
+		if !bytes.Equal(rawPayload, payload.Bytes()) {
+			t.Errorf("DecodeV2Message #%d: payload mismatch\n "+
+				"got: %s want: %s", i, spew.Sdump(rawPayload),
+				spew.Sdump(payload.Bytes()))
+			continue
+		}
+	}
+}
+
+// TestV2MessageErrors performs negative tests against decoding BIP0324 v2
+// transport packet contents to confirm error paths work correctly.
+func TestV2MessageErrors(t *testing.T) {
+	pver := ProtocolVersion
+
+	// Unknown short ID.
+	unknownID := []byte{0xff}
+
+	// Unknown long command.
+	unknownCmd := []byte{0x00, 'b', 'o', 'g', 'u', 's', 0, 0, 0, 0, 0, 0, 0}
+
+	// Truncated long command.
+	truncatedCmd := []byte{0x00, 'v', 'e', 'r'}
+
+	// Command with data after the zero padding.
+	badPadding := []byte{0x00, 'v', 'e', 'r', 'a', 'c', 'k', 0, 'x', 0, 0,
+		0, 0}
+
+	// Ping message with a truncated nonce.
+	shortPing := []byte{18, 0x01, 0x02}
+
+	tests := []struct {
+		buf     []byte // Packet contents
+		readErr error  // Expected read error
+	}{
+		{nil, &MessageError{}},
+		{unknownID, ErrUnknownMessage},
+		{unknownCmd, ErrUnknownMessage},
+		{truncatedCmd, &MessageError{}},
+		{badPadding, &MessageError{}},
+		{shortPing, io.ErrUnexpectedEOF},
+	}
+
+	for i, test := range tests {
+		_, _, err := DecodeV2Message(test.buf, pver, BaseEncoding)
+		if reflect.TypeOf(err) != reflect.TypeOf(test.readErr) {
+			t.Errorf("DecodeV2Message #%d wrong error got: %v, "+
+				"want: %v", i, err, test.readErr)
+			continue
+		}
+
+		// For errors which are not of type MessageError, check them for
+		// equality.
+		if _, ok := err.(*MessageError); !ok {
+			if err != test.readErr {
+				t.Errorf("DecodeV2Message #%d wrong error got: "+
+					"%v <%T>, want: %v <%T>", i, err, err,
+					test.readErr, test.readErr)
+			}
+		}
+	}
+}
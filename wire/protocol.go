@@ -52,6 +52,11 @@ const (
 	// feefilter message.
 	FeeFilterVersion uint32 = 70013
 
+	// BIP0152Version is the protocol version which added the compact
+	// block relay messages sendcmpct, cmpctblock, getblocktxn and
+	// blocktxn (pver >= BIP0152Version).
+	BIP0152Version uint32 = 70014
+
 	// AddrV2Version is the protocol version which added two new messages.
 	// sendaddrv2 is sent during the version-verack handshake and signals
 	// support for sending and receiving the addrv2 message. In the future,
@@ -103,6 +108,10 @@ const (
 	// SFNodeNetWorkLimited is a flag used to indicate a peer supports serving
 	// the last 288 blocks.
 	SFNodeNetworkLimited = 1 << 10
+
+	// SFNodeP2PV2 is a flag used to indicate a peer supports the BIP0324
+	// v2 encrypted transport protocol.
+	SFNodeP2PV2 = 1 << 11
 )
 
 // Map of service flags back to their constant names for pretty printing.
@@ -116,6 +125,7 @@ var sfStrings = map[ServiceFlag]string{
 	SFNodeCF:             "SFNodeCF",
 	SFNode2X:             "SFNode2X",
 	SFNodeNetworkLimited: "SFNodeNetworkLimited",
+	SFNodeP2PV2:          "SFNodeP2PV2",
 }
 
 // orderedSFStrings is an ordered list of service flags from highest to
@@ -130,6 +140,7 @@ var orderedSFStrings = []ServiceFlag{
 	SFNodeCF,
 	SFNode2X,
 	SFNodeNetworkLimited,
+	SFNodeP2PV2,
 }
 
 // HasFlag returns a bool indicating if the service has the given flag.
@@ -0,0 +1,33 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package zmq implements a ZeroMQ compatible notification publisher which mirrors
+the ZMQ interface of Bitcoin Core.
+
+# Publisher Overview
+
+Notifications are published on ZeroMQ PUB sockets speaking ZMTP 3.0 over TCP,
+implemented in pure Go, so any ZeroMQ SUB socket can subscribe to them.  The
+following topics are supported:
+
+  - hashblock: the hash of each connected block
+  - hashtx: the hash of each transaction added to the mempool or included in
+    a connected block
+  - rawblock: the serialized block for each connected block
+  - rawtx: the serialized transaction for each transaction added to the
+    mempool or included in a connected block
+  - sequence: block connections and disconnections along with mempool
+    additions and removals, including the mempool sequence number
+
+Each notification is a multipart message consisting of the topic, the body,
+and a 4-byte little-endian sequence number which is incremented separately
+for every topic so subscribers can detect dropped messages.  Hashes are sent
+in the usual reversed byte order in which they are displayed.
+
+Multiple topics may share the same address, in which case they are published
+on the same socket.  Messages for subscribers which fall behind by more than
+the high water mark are dropped, as ZeroMQ PUB sockets do.
+*/
+package zmq
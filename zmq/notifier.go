@@ -0,0 +1,296 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zmq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"sync"
+
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg/chainhash"
+)
+
+// These constants define the topics notifications are published on.  They
+// match the topics used by Bitcoin Core.
+const (
+	TopicHashBlock = "hashblock"
+	TopicHashTx    = "hashtx"
+	TopicRawBlock  = "rawblock"
+	TopicRawTx     = "rawtx"
+	TopicSequence  = "sequence"
+)
+
+// These constants define the labels of the sequence topic notifications.
+const (
+	sequenceBlockConnected    = 'C'
+	sequenceBlockDisconnected = 'D'
+	sequenceTxAdded           = 'A'
+	sequenceTxRemoved         = 'R'
+)
+
+// DefaultHighWaterMark is the default number of messages queued for each
+// subscriber before further messages are dropped.
+const DefaultHighWaterMark = 1000
+
+// Config houses the endpoints the notifications are published on.  An empty
+// endpoint disables the associated topic.  Endpoints are of the form
+// tcp://host:port.
+type Config struct {
+	// HashBlock is the endpoint to publish block hashes on.
+	HashBlock string
+
+	// HashTx is the endpoint to publish transaction hashes on.
+	HashTx string
+
+	// RawBlock is the endpoint to publish serialized blocks on.
+	RawBlock string
+
+	// RawTx is the endpoint to publish serialized transactions on.
+	RawTx string
+
+	// Sequence is the endpoint to publish block connections and
+	// disconnections along with mempool additions and removals on.
+	Sequence string
+
+	// HighWaterMark is the number of messages queued for each subscriber
+	// before further messages are dropped.  DefaultHighWaterMark is used
+	// when it is zero.
+	HighWaterMark int
+}
+
+// Notification describes an active notification as returned by the
+// getzmqnotifications RPC.
+type Notification struct {
+	// Type is the type of the notification, such as pubhashblock.
+	Type string
+
+	// Address is the endpoint the notification is published on.
+	Address string
+
+	// HighWaterMark is the outbound message high water mark.
+	HighWaterMark int
+}
+
+// topicNotifier publishes the notifications of a single topic.
+type topicNotifier struct {
+	topic    string
+	endpoint string
+	pub      *publisher
+	sequence uint32
+	topicBuf []byte
+}
+
+// publish sends the passed body on the topic with the sequence number of the
+// topic appended and increments it.
+//
+// This function MUST be called with the notifier lock held.
+func (t *topicNotifier) publish(body []byte) {
+	var seq [4]byte
+	binary.LittleEndian.PutUint32(seq[:], t.sequence)
+	t.sequence++
+
+	t.pub.send([][]byte{t.topicBuf, body, seq[:]})
+}
+
+// Notifier publishes block and transaction notifications on ZeroMQ PUB
+// sockets.  Its methods are safe for concurrent access.
+type Notifier struct {
+	mtx        sync.Mutex
+	hwm        int
+	topics     map[string]*topicNotifier
+	publishers map[string]*publisher
+}
+
+// New returns a notifier publishing the topics with a configured endpoint.
+// Topics sharing an endpoint are published on the same socket.
+func New(cfg *Config) (*Notifier, error) {
+	hwm := cfg.HighWaterMark
+	if hwm <= 0 {
+		hwm = DefaultHighWaterMark
+	}
+
+	n := &Notifier{
+		hwm:        hwm,
+		topics:     make(map[string]*topicNotifier),
+		publishers: make(map[string]*publisher),
+	}
+
+	endpoints := []struct {
+		topic    string
+		endpoint string
+	}{
+		{TopicHashBlock, cfg.HashBlock},
+		{TopicHashTx, cfg.HashTx},
+		{TopicRawBlock, cfg.RawBlock},
+		{TopicRawTx, cfg.RawTx},
+		{TopicSequence, cfg.Sequence},
+	}
+	for _, e := range endpoints {
+		if e.endpoint == "" {
+			continue
+		}
+
+		pub, ok := n.publishers[e.endpoint]
+		if !ok {
+			var err error
+			pub, err = newPublisher(e.endpoint, hwm)
+			if err != nil {
+				n.Stop()
+				return nil, err
+			}
+			n.publishers[e.endpoint] = pub
+			log.Infof("Publishing ZMQ notifications on %s",
+				e.endpoint)
+		}
+
+		n.topics[e.topic] = &topicNotifier{
+			topic:    e.topic,
+			endpoint: e.endpoint,
+			pub:      pub,
+			topicBuf: []byte(e.topic),
+		}
+	}
+
+	return n, nil
+}
+
+// Notifications returns the active notifications.
+func (n *Notifier) Notifications() []Notification {
+	var notifications []Notification
+	for _, topic := range []string{TopicHashBlock, TopicHashTx,
+		TopicRawBlock, TopicRawTx, TopicSequence} {
+
+		t, ok := n.topics[topic]
+		if !ok {
+			continue
+		}
+		notifications = append(notifications, Notification{
+			Type:          "pub" + t.topic,
+			Address:       t.endpoint,
+			HighWaterMark: n.hwm,
+		})
+	}
+	return notifications
+}
+
+// reversedHash returns the passed hash in the byte order it is displayed in.
+func reversedHash(hash *chainhash.Hash) []byte {
+	b := make([]byte, chainhash.HashSize)
+	for i := 0; i < chainhash.HashSize; i++ {
+		b[i] = hash[chainhash.HashSize-1-i]
+	}
+	return b
+}
+
+// sequenceBody returns the body of a sequence topic notification.  The
+// mempool sequence number is only included for mempool events.
+func sequenceBody(hash *chainhash.Hash, label byte, mempoolSeq *uint64) []byte {
+	body := reversedHash(hash)
+	body = append(body, label)
+	if mempoolSeq != nil {
+		var seq [8]byte
+		binary.LittleEndian.PutUint64(seq[:], *mempoolSeq)
+		body = append(body, seq[:]...)
+	}
+	return body
+}
+
+// notifyTransaction publishes the hashtx and rawtx notifications for the
+// passed transaction.
+//
+// This function MUST be called with the notifier lock held.
+func (n *Notifier) notifyTransaction(tx *btcutil.Tx) {
+	if t, ok := n.topics[TopicHashTx]; ok {
+		t.publish(reversedHash(tx.Hash()))
+	}
+	if t, ok := n.topics[TopicRawTx]; ok {
+		var buf bytes.Buffer
+		buf.Grow(tx.MsgTx().SerializeSize())
+		if err := tx.MsgTx().Serialize(&buf); err != nil {
+			log.Errorf("Unable to serialize transaction %v: %v",
+				tx.Hash(), err)
+			return
+		}
+		t.publish(buf.Bytes())
+	}
+}
+
+// BlockConnected publishes the notifications for a block connected to the
+// main chain.  Each transaction in the block is published on the hashtx and
+// rawtx topics, followed by the block on the sequence, hashblock, and rawblock
+// topics.
+func (n *Notifier) BlockConnected(block *btcutil.Block) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	for _, tx := range block.Transactions() {
+		n.notifyTransaction(tx)
+	}
+	if t, ok := n.topics[TopicSequence]; ok {
+		t.publish(sequenceBody(block.Hash(), sequenceBlockConnected,
+			nil))
+	}
+	if t, ok := n.topics[TopicHashBlock]; ok {
+		t.publish(reversedHash(block.Hash()))
+	}
+	if t, ok := n.topics[TopicRawBlock]; ok {
+		blockBytes, err := block.Bytes()
+		if err != nil {
+			log.Errorf("Unable to serialize block %v: %v",
+				block.Hash(), err)
+			return
+		}
+		t.publish(blockBytes)
+	}
+}
+
+// BlockDisconnected publishes the notifications for a block disconnected from
+// the main chain.  Each transaction in the block is published on the hashtx
+// and rawtx topics, followed by the block on the sequence topic.
+func (n *Notifier) BlockDisconnected(block *btcutil.Block) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	for _, tx := range block.Transactions() {
+		n.notifyTransaction(tx)
+	}
+	if t, ok := n.topics[TopicSequence]; ok {
+		t.publish(sequenceBody(block.Hash(), sequenceBlockDisconnected,
+			nil))
+	}
+}
+
+// TransactionAdded publishes the notifications for a transaction added to
+// the mempool with the passed mempool sequence number.
+func (n *Notifier) TransactionAdded(tx *btcutil.Tx, mempoolSeq uint64) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	n.notifyTransaction(tx)
+	if t, ok := n.topics[TopicSequence]; ok {
+		t.publish(sequenceBody(tx.Hash(), sequenceTxAdded, &mempoolSeq))
+	}
+}
+
+// TransactionRemoved publishes the notification for a transaction removed
+// from the mempool for any reason other than inclusion in a block with the
+// passed mempool sequence number.
+func (n *Notifier) TransactionRemoved(tx *btcutil.Tx, mempoolSeq uint64) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+
+	if t, ok := n.topics[TopicSequence]; ok {
+		t.publish(sequenceBody(tx.Hash(), sequenceTxRemoved,
+			&mempoolSeq))
+	}
+}
+
+// Stop closes all sockets and disconnects all subscribers.
+func (n *Notifier) Stop() {
+	for _, pub := range n.publishers {
+		pub.stop()
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/bynil/btcd/btcutil"
+	"github.com/bynil/btcd/chaincfg"
+	"github.com/bynil/btcd/wire"
+)
+
+// testSubscriber is a minimal SUB socket used to receive the notifications.
+type testSubscriber struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// subscribe connects to the passed publisher and subscribes to the topic.
+func subscribe(t *testing.T, pub *publisher, topic string) *testSubscriber {
+	t.Helper()
+
+	conn, err := net.Dial("tcp", pub.listener.Addr().String())
+	if err != nil {
+		t.Fatalf("unable to dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+
+	reader := bufio.NewReader(conn)
+	socketType, err := handshake(reader, conn, "SUB")
+	if err != nil {
+		t.Fatalf("handshake: unexpected error: %v", err)
+	}
+	if socketType != "PUB" {
+		t.Fatalf("handshake: got socket type %q, want PUB", socketType)
+	}
+
+	err = writeFrame(conn, 0, append([]byte{1}, topic...))
+	if err != nil {
+		t.Fatalf("unable to subscribe: %v", err)
+	}
+
+	// Wait for the subscription to be registered since the publisher does
+	// not acknowledge it.
+	for i := 0; i < 100; i++ {
+		pub.mtx.Lock()
+		var registered bool
+		for sub := range pub.subscribers {
+			registered = registered || sub.subscribed([]byte(topic))
+		}
+		pub.mtx.Unlock()
+		if registered {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	return &testSubscriber{conn: conn, reader: reader}
+}
+
+// readMessage reads a multipart message from the publisher.
+func (s *testSubscriber) readMessage(t *testing.T) [][]byte {
+	t.Helper()
+
+	var parts [][]byte
+	for {
+		f, err := readFrame(s.reader, 1<<20)
+		if err != nil {
+			t.Fatalf("readFrame: unexpected error: %v", err)
+		}
+		parts = append(parts, f.body)
+		if !f.hasMore() {
+			return parts
+		}
+	}
+}
+
+// expectMessage reads a message and ensures it has the expected topic, body
+// and sequence number.
+func (s *testSubscriber) expectMessage(t *testing.T, topic string,
+	body []byte, seq uint32) {
+
+	t.Helper()
+
+	parts := s.readMessage(t)
+	if len(parts) != 3 {
+		t.Fatalf("got %d message parts, want 3", len(parts))
+	}
+	if string(parts[0]) != topic {
+		t.Fatalf("got topic %q, want %q", parts[0], topic)
+	}
+	if !bytes.Equal(parts[1], body) {
+		t.Fatalf("%s: got body %x, want %x", topic, parts[1], body)
+	}
+	if got := binary.LittleEndian.Uint32(parts[2]); got != seq {
+		t.Fatalf("%s: got sequence %d, want %d", topic, got, seq)
+	}
+}
+
+// TestListenAddress ensures endpoints are converted to listen addresses as
+// expected.
+func TestListenAddress(t *testing.T) {
+	tests := []struct {
+		endpoint string
+		want     string
+		valid    bool
+	}{
+		{"tcp://127.0.0.1:28332", "127.0.0.1:28332", true},
+		{"tcp://*:28332", ":28332", true},
+		{"tcp://[::1]:28332", "[::1]:28332", true},
+		{"ipc:///tmp/btcd", "", false},
+		{"tcp://127.0.0.1", "", false},
+	}
+
+	for _, test := range tests {
+		got, err := listenAddress(test.endpoint)
+		if (err == nil) != test.valid {
+			t.Errorf("%s: got error %v, want valid %v", test.endpoint,
+				err, test.valid)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.endpoint, got, test.want)
+		}
+	}
+}
+
+// TestNotifier ensures the notifications are published to subscribers of the
+// topic with the expected bodies and sequence numbers.
+func TestNotifier(t *testing.T) {
+	n, err := New(&Config{
+		HashBlock: "tcp://127.0.0.1:0",
+		Sequence:  "tcp://127.0.0.1:0",
+	})
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	defer n.Stop()
+
+	notifications := n.Notifications()
+	if len(notifications) != 2 ||
+		notifications[0].Type != "pubhashblock" ||
+		notifications[1].Type != "pubsequence" ||
+		notifications[0].HighWaterMark != DefaultHighWaterMark {
+
+		t.Fatalf("unexpected notifications %v", notifications)
+	}
+
+	// Both topics share an endpoint, so subscribing to the hashblock topic
+	// must not deliver sequence notifications.
+	pub := n.topics[TopicHashBlock].pub
+	if n.topics[TopicSequence].pub != pub {
+		t.Fatal("topics with the same endpoint do not share a publisher")
+	}
+	hashSub := subscribe(t, pub, TopicHashBlock)
+	seqSub := subscribe(t, pub, TopicSequence)
+
+	block := btcutil.NewBlock(chaincfg.MainNetParams.GenesisBlock)
+	tx := btcutil.NewTx(wire.NewMsgTx(wire.TxVersion))
+
+	n.TransactionAdded(tx, 5)
+	n.BlockConnected(block)
+	n.TransactionRemoved(tx, 6)
+	n.BlockDisconnected(block)
+	n.BlockConnected(block)
+
+	blockHash := reversedHash(block.Hash())
+	hashSub.expectMessage(t, TopicHashBlock, blockHash, 0)
+	hashSub.expectMessage(t, TopicHashBlock, blockHash, 1)
+
+	txHash := reversedHash(tx.Hash())
+	added := append(append(txHash, 'A'), 5, 0, 0, 0, 0, 0, 0, 0)
+	removed := append(append([]byte{}, txHash...), 'R', 6, 0, 0, 0, 0, 0,
+		0, 0)
+	seqSub.expectMessage(t, TopicSequence, added, 0)
+	seqSub.expectMessage(t, TopicSequence, append(blockHash, 'C'), 1)
+	seqSub.expectMessage(t, TopicSequence, removed, 2)
+	seqSub.expectMessage(t, TopicSequence, append(blockHash, 'D'), 3)
+	seqSub.expectMessage(t, TopicSequence, append(blockHash, 'C'), 4)
+}
+
+// TestHighWaterMark ensures messages are dropped once a subscriber reaches
+// the high water mark rather than blocking the publisher.
+func TestHighWaterMark(t *testing.T) {
+	pub, err := newPublisher("tcp://127.0.0.1:0", 2)
+	if err != nil {
+		t.Fatalf("newPublisher: unexpected error: %v", err)
+	}
+	defer pub.stop()
+
+	sub := subscribe(t, pub, "topic")
+
+	// Stop the writer from draining the queue by holding every subscriber
+	// queue full before sending.
+	pub.mtx.Lock()
+	for s := range pub.subscribers {
+		for len(s.msgs) < cap(s.msgs) {
+			s.msgs <- [][]byte{[]byte("topic"), {0}}
+		}
+	}
+	pub.mtx.Unlock()
+
+	// Sending must not block regardless of whether the queue has drained.
+	done := make(chan struct{})
+	go func() {
+		for i := 0; i < 100; i++ {
+			pub.send([][]byte{[]byte("topic"), {1}})
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("send blocked at the high water mark")
+	}
+
+	parts := sub.readMessage(t)
+	if string(parts[0]) != "topic" {
+		t.Fatalf("got topic %q, want topic", parts[0])
+	}
+}
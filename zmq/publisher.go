@@ -0,0 +1,298 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zmq
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// handshakeTimeout is the time allowed for a subscriber to complete
+	// the ZMTP handshake.
+	handshakeTimeout = 10 * time.Second
+)
+
+// listenAddress converts a ZeroMQ tcp endpoint such as tcp://127.0.0.1:28332
+// to an address suitable for net.Listen.  A host of * listens on all
+// interfaces.
+func listenAddress(endpoint string) (string, error) {
+	addr := strings.TrimPrefix(endpoint, "tcp://")
+	if addr == endpoint {
+		return "", fmt.Errorf("unsupported ZMQ endpoint %q -- only tcp "+
+			"endpoints are supported", endpoint)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", fmt.Errorf("invalid ZMQ endpoint %q: %v", endpoint,
+			err)
+	}
+	if host == "*" {
+		host = ""
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// subscriber houses the state of a connected SUB socket.
+type subscriber struct {
+	conn net.Conn
+
+	// msgs is the queue of messages waiting to be written to the
+	// subscriber.  Its capacity is the high water mark.
+	msgs chan [][]byte
+
+	mtx    sync.Mutex
+	topics [][]byte
+}
+
+// subscribed returns whether the subscriber has a subscription that is a
+// prefix of the passed topic.
+func (s *subscriber) subscribed(topic []byte) bool {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for _, prefix := range s.topics {
+		if bytes.HasPrefix(topic, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribe adds the passed topic prefix to the subscriptions.
+func (s *subscriber) subscribe(prefix []byte) {
+	s.mtx.Lock()
+	s.topics = append(s.topics, prefix)
+	s.mtx.Unlock()
+}
+
+// unsubscribe removes a single matching subscription for the passed topic
+// prefix.
+func (s *subscriber) unsubscribe(prefix []byte) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	for i, topic := range s.topics {
+		if bytes.Equal(topic, prefix) {
+			s.topics = append(s.topics[:i], s.topics[i+1:]...)
+			return
+		}
+	}
+}
+
+// publisher is a ZeroMQ PUB socket bound to a TCP address.
+type publisher struct {
+	endpoint string
+	hwm      int
+	listener net.Listener
+
+	mtx         sync.Mutex
+	subscribers map[*subscriber]struct{}
+
+	wg   sync.WaitGroup
+	quit chan struct{}
+}
+
+// newPublisher returns a publisher listening on the passed ZeroMQ endpoint.
+// Messages for each subscriber are queued up to the high water mark.
+func newPublisher(endpoint string, hwm int) (*publisher, error) {
+	addr, err := listenAddress(endpoint)
+	if err != nil {
+		return nil, err
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &publisher{
+		endpoint:    endpoint,
+		hwm:         hwm,
+		listener:    listener,
+		subscribers: make(map[*subscriber]struct{}),
+		quit:        make(chan struct{}),
+	}
+	p.wg.Add(1)
+	go p.listenHandler()
+	return p, nil
+}
+
+// listenHandler accepts subscriber connections.  It must be run as a
+// goroutine.
+func (p *publisher) listenHandler() {
+	defer p.wg.Done()
+
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			select {
+			case <-p.quit:
+				return
+			default:
+			}
+
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				continue
+			}
+			log.Errorf("Can't accept ZMQ connection on %s: %v",
+				p.endpoint, err)
+			return
+		}
+
+		p.wg.Add(1)
+		go p.handleConn(conn)
+	}
+}
+
+// handleConn performs the handshake with a new subscriber and then serves it
+// until it disconnects.  It must be run as a goroutine.
+func (p *publisher) handleConn(conn net.Conn) {
+	defer p.wg.Done()
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(handshakeTimeout))
+	reader := bufio.NewReader(conn)
+	socketType, err := handshake(reader, conn, "PUB")
+	if err != nil {
+		log.Debugf("ZMQ handshake with %s failed: %v", conn.RemoteAddr(),
+			err)
+		return
+	}
+	if socketType != "SUB" && socketType != "XSUB" {
+		log.Debugf("Rejecting ZMQ peer %s with incompatible socket "+
+			"type %q", conn.RemoteAddr(), socketType)
+		return
+	}
+	conn.SetDeadline(time.Time{})
+
+	sub := &subscriber{
+		conn: conn,
+		msgs: make(chan [][]byte, p.hwm),
+	}
+	p.mtx.Lock()
+	p.subscribers[sub] = struct{}{}
+	p.mtx.Unlock()
+	log.Debugf("New ZMQ subscriber %s on %s", conn.RemoteAddr(), p.endpoint)
+
+	done := make(chan struct{})
+	p.wg.Add(1)
+	go p.writeHandler(sub, done)
+	p.readHandler(sub, reader)
+
+	p.mtx.Lock()
+	delete(p.subscribers, sub)
+	p.mtx.Unlock()
+	close(done)
+	log.Debugf("ZMQ subscriber %s on %s disconnected", conn.RemoteAddr(),
+		p.endpoint)
+}
+
+// readHandler processes subscriptions sent by the subscriber until it
+// disconnects or the publisher is stopped.
+func (p *publisher) readHandler(sub *subscriber, reader *bufio.Reader) {
+	for {
+		f, err := readFrame(reader, maxSubscriptionLen)
+		if err != nil {
+			return
+		}
+
+		// ZMTP 3.1 peers send subscriptions as commands while ZMTP
+		// 3.0 peers send them as messages whose first byte indicates
+		// whether to subscribe or unsubscribe.
+		if f.isCommand() {
+			cmd, err := parseCommand(f.body)
+			if err != nil {
+				return
+			}
+			switch cmd.name {
+			case "SUBSCRIBE":
+				sub.subscribe(cmd.data)
+			case "CANCEL":
+				sub.unsubscribe(cmd.data)
+			}
+			continue
+		}
+		if f.hasMore() || len(f.body) == 0 {
+			continue
+		}
+		switch f.body[0] {
+		case 1:
+			sub.subscribe(f.body[1:])
+		case 0:
+			sub.unsubscribe(f.body[1:])
+		}
+	}
+}
+
+// writeHandler writes queued messages to the subscriber until done is closed
+// or the publisher is stopped.  It must be run as a goroutine.
+func (p *publisher) writeHandler(sub *subscriber, done chan struct{}) {
+	defer p.wg.Done()
+
+	writer := bufio.NewWriter(sub.conn)
+	for {
+		select {
+		case msg := <-sub.msgs:
+			err := writeMessage(writer, msg)
+			if err == nil && len(sub.msgs) == 0 {
+				err = writer.Flush()
+			}
+			if err != nil {
+				sub.conn.Close()
+				return
+			}
+
+		case <-done:
+			return
+
+		case <-p.quit:
+			sub.conn.Close()
+			return
+		}
+	}
+}
+
+// send queues the passed multipart message for every subscriber subscribed to
+// its topic, which is the first part.  Messages for subscribers which have
+// reached the high water mark are dropped.
+func (p *publisher) send(msg [][]byte) {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	for sub := range p.subscribers {
+		if !sub.subscribed(msg[0]) {
+			continue
+		}
+		select {
+		case sub.msgs <- msg:
+		default:
+			log.Debugf("Dropping ZMQ %s message for subscriber %s "+
+				"which reached the high water mark", msg[0],
+				sub.conn.RemoteAddr())
+		}
+	}
+}
+
+// stop closes the listener and disconnects all subscribers.
+func (p *publisher) stop() {
+	close(p.quit)
+	p.listener.Close()
+
+	p.mtx.Lock()
+	for sub := range p.subscribers {
+		sub.conn.Close()
+	}
+	p.mtx.Unlock()
+
+	p.wg.Wait()
+}
@@ -0,0 +1,288 @@
+// Copyright (c) 2024 The btcsuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zmq
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// greetingLen is the length of the ZMTP 3.0 greeting.
+	greetingLen = 64
+
+	// signatureLen is the length of the signature at the start of the
+	// greeting.
+	signatureLen = 10
+
+	// mechanismLen is the length of the security mechanism field in the
+	// greeting.
+	mechanismLen = 20
+
+	// zmtpMajorVersion and zmtpMinorVersion are the ZMTP protocol version
+	// advertised in the greeting.  Advertising 3.0 ensures subscribers send
+	// subscriptions as messages rather than the 3.1 commands, however both
+	// are understood.
+	zmtpMajorVersion = 3
+	zmtpMinorVersion = 0
+
+	// Frame flags as defined by ZMTP 3.0.
+	flagMore    = 0x01
+	flagLong    = 0x02
+	flagCommand = 0x04
+
+	// maxShortFrameLen is the max body length of a frame with a one byte
+	// size field.
+	maxShortFrameLen = 255
+
+	// maxCommandLen is the max body length of a command frame accepted
+	// from a subscriber.  Subscribers only send small commands.
+	maxCommandLen = 4096
+
+	// maxSubscriptionLen is the max length of a subscription message
+	// accepted from a subscriber.
+	maxSubscriptionLen = 4096
+)
+
+var (
+	// nullMechanism is the NULL security mechanism padded to the length of
+	// the mechanism field.
+	nullMechanism = [mechanismLen]byte{'N', 'U', 'L', 'L'}
+
+	// errFrameTooLarge is returned when a subscriber sends a frame larger
+	// than allowed.
+	errFrameTooLarge = errors.New("frame too large")
+)
+
+// frame is a single ZMTP frame.
+type frame struct {
+	flags byte
+	body  []byte
+}
+
+// isCommand returns whether the frame is a command frame.
+func (f *frame) isCommand() bool {
+	return f.flags&flagCommand != 0
+}
+
+// hasMore returns whether more frames of the same message follow.
+func (f *frame) hasMore() bool {
+	return f.flags&flagMore != 0
+}
+
+// greeting returns the ZMTP 3.0 greeting for the NULL mechanism.
+func greeting() []byte {
+	var g [greetingLen]byte
+	g[0] = 0xff
+	g[signatureLen-1] = 0x7f
+	g[signatureLen] = zmtpMajorVersion
+	g[signatureLen+1] = zmtpMinorVersion
+	copy(g[signatureLen+2:], nullMechanism[:])
+
+	// The as-server field and filler are left zero.
+	return g[:]
+}
+
+// readGreeting reads the greeting of the remote peer and ensures it speaks
+// ZMTP 3.x with the NULL mechanism.
+func readGreeting(r io.Reader) error {
+	var g [greetingLen]byte
+	if _, err := io.ReadFull(r, g[:signatureLen]); err != nil {
+		return err
+	}
+	if g[0] != 0xff || g[signatureLen-1]&0x01 != 0x01 {
+		return errors.New("invalid ZMTP signature")
+	}
+	if _, err := io.ReadFull(r, g[signatureLen:]); err != nil {
+		return err
+	}
+
+	major := g[signatureLen]
+	if major < zmtpMajorVersion {
+		return fmt.Errorf("unsupported ZMTP version %d", major)
+	}
+	mechanism := g[signatureLen+2 : signatureLen+2+mechanismLen]
+	if !bytes.Equal(mechanism, nullMechanism[:]) {
+		return fmt.Errorf("unsupported security mechanism %q",
+			bytes.TrimRight(mechanism, "\x00"))
+	}
+
+	return nil
+}
+
+// writeFrame writes a single frame with the passed flags and body.
+func writeFrame(w io.Writer, flags byte, body []byte) error {
+	var hdr [9]byte
+	hdrLen := 2
+	if len(body) > maxShortFrameLen {
+		hdr[0] = flags | flagLong
+		binary.BigEndian.PutUint64(hdr[1:], uint64(len(body)))
+		hdrLen = 9
+	} else {
+		hdr[0] = flags
+		hdr[1] = byte(len(body))
+	}
+
+	if _, err := w.Write(hdr[:hdrLen]); err != nil {
+		return err
+	}
+	_, err := w.Write(body)
+	return err
+}
+
+// writeMessage writes a multipart message with one frame per part.
+func writeMessage(w io.Writer, parts [][]byte) error {
+	for i, part := range parts {
+		var flags byte
+		if i < len(parts)-1 {
+			flags = flagMore
+		}
+		if err := writeFrame(w, flags, part); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readFrame reads a single frame.  Frames with bodies larger than maxLen are
+// rejected.
+func readFrame(r io.Reader, maxLen uint64) (*frame, error) {
+	var flags [1]byte
+	if _, err := io.ReadFull(r, flags[:]); err != nil {
+		return nil, err
+	}
+
+	var size uint64
+	if flags[0]&flagLong != 0 {
+		var b [8]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		size = binary.BigEndian.Uint64(b[:])
+	} else {
+		var b [1]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		size = uint64(b[0])
+	}
+	if size > maxLen {
+		return nil, errFrameTooLarge
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return &frame{flags: flags[0] &^ flagLong, body: body}, nil
+}
+
+// command is a parsed ZMTP command.
+type command struct {
+	name string
+	data []byte
+}
+
+// parseCommand parses the body of a command frame.
+func parseCommand(body []byte) (*command, error) {
+	if len(body) == 0 || int(body[0]) > len(body)-1 {
+		return nil, errors.New("malformed command")
+	}
+	nameLen := int(body[0])
+	return &command{
+		name: string(body[1 : 1+nameLen]),
+		data: body[1+nameLen:],
+	}, nil
+}
+
+// encodeCommand returns the body of a command frame.
+func encodeCommand(name string, data []byte) []byte {
+	body := make([]byte, 0, 1+len(name)+len(data))
+	body = append(body, byte(len(name)))
+	body = append(body, name...)
+	return append(body, data...)
+}
+
+// encodeProperties encodes the passed metadata properties as used by the
+// READY command.
+func encodeProperties(props map[string]string) []byte {
+	var buf bytes.Buffer
+	for name, value := range props {
+		buf.WriteByte(byte(len(name)))
+		buf.WriteString(name)
+		var valueLen [4]byte
+		binary.BigEndian.PutUint32(valueLen[:], uint32(len(value)))
+		buf.Write(valueLen[:])
+		buf.WriteString(value)
+	}
+	return buf.Bytes()
+}
+
+// parseProperties parses the metadata properties of a READY command.
+func parseProperties(data []byte) (map[string]string, error) {
+	props := make(map[string]string)
+	for len(data) > 0 {
+		nameLen := int(data[0])
+		if len(data) < 1+nameLen+4 {
+			return nil, errors.New("malformed property")
+		}
+		name := string(data[1 : 1+nameLen])
+		data = data[1+nameLen:]
+
+		valueLen := binary.BigEndian.Uint32(data)
+		data = data[4:]
+		if uint64(len(data)) < uint64(valueLen) {
+			return nil, errors.New("malformed property value")
+		}
+		props[name] = string(data[:valueLen])
+		data = data[valueLen:]
+	}
+	return props, nil
+}
+
+// handshake performs the ZMTP 3.0 greeting and NULL mechanism handshake as the
+// passed socket type and returns the socket type of the remote peer.
+func handshake(r io.Reader, w io.Writer, socketType string) (string, error) {
+	if _, err := w.Write(greeting()); err != nil {
+		return "", err
+	}
+	if err := readGreeting(r); err != nil {
+		return "", err
+	}
+
+	props := encodeProperties(map[string]string{"Socket-Type": socketType})
+	err := writeFrame(w, flagCommand, encodeCommand("READY", props))
+	if err != nil {
+		return "", err
+	}
+
+	f, err := readFrame(r, maxCommandLen)
+	if err != nil {
+		return "", err
+	}
+	if !f.isCommand() {
+		return "", errors.New("expected READY command")
+	}
+	cmd, err := parseCommand(f.body)
+	if err != nil {
+		return "", err
+	}
+	switch cmd.name {
+	case "READY":
+	case "ERROR":
+		return "", fmt.Errorf("peer reported error: %q", cmd.data)
+	default:
+		return "", fmt.Errorf("unexpected command %q", cmd.name)
+	}
+
+	remoteProps, err := parseProperties(cmd.data)
+	if err != nil {
+		return "", err
+	}
+	return remoteProps["Socket-Type"], nil
+}